@@ -0,0 +1,205 @@
+// Package applogs reads the Cloud Foundry logs of an application on the foundations of an environment.
+package applogs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// maxLineBytes is the longest log line a followed stream passes on.
+const maxLineBytes = 1024 * 1024
+
+type courierCreator interface {
+	CreateCourier() (I.Courier, error)
+}
+
+// Fetcher logs into each foundation of an environment with its own courier to read the logs of an application.
+type Fetcher struct {
+	CourierCreator courierCreator
+	Log            I.Logger
+}
+
+// Request names the application whose logs are read and the credentials they are read with.
+type Request struct {
+	Environment   S.Environment
+	Org           string
+	Space         string
+	AppName       string
+	Authorization I.Authorization
+}
+
+// Recent writes the last lines of the recent logs of the application on each foundation to output. The logs of each
+// foundation are headed by its URL when the environment has more than one. Nothing is written when the logs of any
+// foundation cannot be read.
+func (f Fetcher) Recent(request Request, lines int, output io.Writer) error {
+	logs := make([][]byte, len(request.Environment.Foundations))
+
+	for i, foundationURL := range request.Environment.Foundations {
+		courier, err := f.login(request, foundationURL)
+		if err != nil {
+			return err
+		}
+
+		out, err := courier.Logs(request.AppName)
+		courier.CleanUp()
+		if err != nil {
+			f.Log.Errorf("could not get the logs of %s on %s", request.AppName, foundationURL)
+			return LogsError{request.AppName, foundationURL, out}
+		}
+
+		logs[i] = tail(out, lines)
+	}
+
+	for i, foundationURL := range request.Environment.Foundations {
+		if len(request.Environment.Foundations) > 1 {
+			fmt.Fprintf(output, "--- %s ---\n", foundationURL)
+		}
+		output.Write(logs[i])
+	}
+
+	return nil
+}
+
+// Follow streams the logs of the application on every foundation to output, a line at a time, until ctx is done.
+// Each line is prefixed by the URL of its foundation when the environment has more than one. It logs into every
+// foundation before anything is written, so an error returned before then leaves output untouched.
+func (f Fetcher) Follow(ctx context.Context, request Request, output io.Writer) error {
+	couriers := []I.Courier{}
+	defer func() {
+		for _, courier := range couriers {
+			courier.CleanUp()
+		}
+	}()
+
+	for _, foundationURL := range request.Environment.Foundations {
+		courier, err := f.login(request, foundationURL)
+		if err != nil {
+			return err
+		}
+		couriers = append(couriers, courier)
+	}
+
+	writer := &lineWriter{output: output}
+	errs := make(chan error, len(couriers))
+
+	for i, courier := range couriers {
+		prefix := ""
+		if len(couriers) > 1 {
+			prefix = "[" + request.Environment.Foundations[i] + "] "
+		}
+
+		go func(courier I.Courier, prefix string) {
+			errs <- f.stream(ctx, courier, request.AppName, prefix, writer)
+		}(courier, prefix)
+	}
+
+	var err error
+	for range couriers {
+		streamErr := <-errs
+		if streamErr != nil && ctx.Err() == nil && err == nil {
+			err = streamErr
+		}
+	}
+
+	return err
+}
+
+// stream copies the logs of one foundation to writer a line at a time, so lines of different foundations are
+// never interleaved.
+func (f Fetcher) stream(ctx context.Context, courier I.Courier, appName, prefix string, writer *lineWriter) error {
+	reader, pipe := io.Pipe()
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+		for scanner.Scan() {
+			writer.writeLine(prefix + scanner.Text())
+		}
+		io.Copy(ioutil.Discard, reader)
+	}()
+
+	err := courier.StreamLogs(ctx, appName, pipe)
+	pipe.Close()
+	<-copied
+
+	return err
+}
+
+// login returns a courier logged into the org and space of the request on foundationURL, after checking that the
+// application exists there. The caller cleans the courier up.
+func (f Fetcher) login(request Request, foundationURL string) (I.Courier, error) {
+	courier, err := f.CourierCreator.CreateCourier()
+	if err != nil {
+		f.Log.Error(err)
+		return nil, state.CourierCreationError{Err: err}
+	}
+
+	output, err := courier.Login(
+		foundationURL,
+		request.Authorization.Username,
+		request.Authorization.Password,
+		request.Org,
+		request.Space,
+		request.Environment.SkipSSL,
+	)
+	if err != nil {
+		courier.CleanUp()
+		f.Log.Errorf("could not login to %s", foundationURL)
+		return nil, state.LoginError{foundationURL, output}
+	}
+
+	if !courier.Exists(request.AppName) {
+		courier.CleanUp()
+		return nil, state.ExistsError{ApplicationName: request.AppName}
+	}
+
+	return courier, nil
+}
+
+// tail returns the last lines of logs.
+func tail(logs []byte, lines int) []byte {
+	trimmed := strings.TrimRight(string(logs), "\n")
+	if trimmed == "" {
+		return nil
+	}
+
+	all := strings.Split(trimmed, "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+
+	buffer := bytes.Buffer{}
+	for _, line := range all {
+		buffer.WriteString(line + "\n")
+	}
+	return buffer.Bytes()
+}
+
+// lineWriter writes whole lines from many streams to output, flushing each one when output can be flushed.
+type lineWriter struct {
+	mutex  sync.Mutex
+	output io.Writer
+}
+
+func (w *lineWriter) writeLine(line string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	io.WriteString(w.output, line+"\n")
+	if flusher, ok := w.output.(interface{ Flush() }); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,13 @@
+package applogs_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestApplogs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Applogs Suite")
+}
@@ -0,0 +1,160 @@
+package applogs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	. "github.com/compozed/deployadactyl/applogs"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/op/go-logging"
+)
+
+type courierCreator struct {
+	couriers []*mocks.Courier
+	created  int
+}
+
+func (c *courierCreator) CreateCourier() (I.Courier, error) {
+	defer func() { c.created++ }()
+	return c.couriers[c.created], nil
+}
+
+var _ = Describe("Fetcher", func() {
+	var (
+		couriers []*mocks.Courier
+		creator  *courierCreator
+		fetcher  Fetcher
+		request  Request
+		output   *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		couriers = []*mocks.Courier{{}, {}}
+		for _, courier := range couriers {
+			courier.ExistsCall.Returns.Bool = true
+		}
+		creator = &courierCreator{couriers: couriers}
+
+		fetcher = Fetcher{
+			CourierCreator: creator,
+			Log:            I.DefaultLogger(&bytes.Buffer{}, logging.DEBUG, "applogs_test"),
+		}
+
+		request = Request{
+			Environment:   S.Environment{Name: "prod", Foundations: []string{"https://api.one.example.com"}, SkipSSL: true},
+			Org:           "org",
+			Space:         "space",
+			AppName:       "app",
+			Authorization: I.Authorization{Username: "user", Password: "password"},
+		}
+		output = &bytes.Buffer{}
+	})
+
+	Describe("Recent", func() {
+		It("logs in with the request and writes the last lines of the recent logs", func() {
+			couriers[0].LogsCall.Returns.Output = []byte("header\n\nline 1\nline 2\nline 3\n")
+
+			Expect(fetcher.Recent(request, 2, output)).To(Succeed())
+
+			Expect(output.String()).To(Equal("line 2\nline 3\n"))
+			Expect(couriers[0].LoginCall.Received.FoundationURL).To(Equal("https://api.one.example.com"))
+			Expect(couriers[0].LoginCall.Received.Username).To(Equal("user"))
+			Expect(couriers[0].LoginCall.Received.Org).To(Equal("org"))
+			Expect(couriers[0].LoginCall.Received.Space).To(Equal("space"))
+			Expect(couriers[0].LoginCall.Received.SkipSSL).To(BeTrue())
+			Expect(couriers[0].LogsCall.Received.AppName).To(Equal("app"))
+		})
+
+		It("heads the logs of each foundation with its URL when there is more than one", func() {
+			request.Environment.Foundations = append(request.Environment.Foundations, "https://api.two.example.com")
+			couriers[0].LogsCall.Returns.Output = []byte("one\n")
+			couriers[1].LogsCall.Returns.Output = []byte("two\n")
+
+			Expect(fetcher.Recent(request, 10, output)).To(Succeed())
+
+			Expect(output.String()).To(Equal("--- https://api.one.example.com ---\none\n--- https://api.two.example.com ---\ntwo\n"))
+		})
+
+		It("returns a LoginError when it cannot log in", func() {
+			couriers[0].LoginCall.Returns.Output = []byte("login output")
+			couriers[0].LoginCall.Returns.Error = errors.New("login error")
+
+			err := fetcher.Recent(request, 10, output)
+
+			Expect(err).To(MatchError(state.LoginError{"https://api.one.example.com", []byte("login output")}))
+			Expect(output.String()).To(BeEmpty())
+		})
+
+		It("returns an ExistsError when the application does not exist", func() {
+			couriers[0].ExistsCall.Returns.Bool = false
+
+			err := fetcher.Recent(request, 10, output)
+
+			Expect(err).To(MatchError(state.ExistsError{ApplicationName: "app"}))
+		})
+
+		It("returns a LogsError when the logs cannot be read", func() {
+			couriers[0].LogsCall.Returns.Output = []byte("logs output")
+			couriers[0].LogsCall.Returns.Error = errors.New("logs error")
+
+			err := fetcher.Recent(request, 10, output)
+
+			Expect(err).To(MatchError(LogsError{"app", "https://api.one.example.com", []byte("logs output")}))
+		})
+	})
+
+	Describe("Follow", func() {
+		It("streams the logs until the stream ends", func() {
+			ctx := context.Background()
+			couriers[0].StreamLogsCall.Write.Output = "line 1\nline 2"
+
+			Expect(fetcher.Follow(ctx, request, output)).To(Succeed())
+
+			Expect(output.String()).To(Equal("line 1\nline 2\n"))
+			Expect(couriers[0].StreamLogsCall.Received.Context).To(Equal(ctx))
+			Expect(couriers[0].StreamLogsCall.Received.AppName).To(Equal("app"))
+		})
+
+		It("prefixes each line with its foundation when there is more than one", func() {
+			request.Environment.Foundations = append(request.Environment.Foundations, "https://api.two.example.com")
+			couriers[0].StreamLogsCall.Write.Output = "one\n"
+			couriers[1].StreamLogsCall.Write.Output = "two\n"
+
+			Expect(fetcher.Follow(context.Background(), request, output)).To(Succeed())
+
+			Expect(output.String()).To(ContainSubstring("[https://api.one.example.com] one\n"))
+			Expect(output.String()).To(ContainSubstring("[https://api.two.example.com] two\n"))
+		})
+
+		It("returns the error of a stream that fails", func() {
+			couriers[0].StreamLogsCall.Returns.Error = errors.New("stream error")
+
+			Expect(fetcher.Follow(context.Background(), request, output)).To(MatchError("stream error"))
+		})
+
+		It("does not return the error of a stream stopped by the context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			couriers[0].StreamLogsCall.Returns.Error = errors.New("signal: killed")
+
+			Expect(fetcher.Follow(ctx, request, output)).To(Succeed())
+		})
+
+		It("writes nothing when it cannot log into every foundation", func() {
+			request.Environment.Foundations = append(request.Environment.Foundations, "https://api.two.example.com")
+			couriers[1].LoginCall.Returns.Error = errors.New("login error")
+
+			err := fetcher.Follow(context.Background(), request, output)
+
+			Expect(err).To(BeAssignableToTypeOf(state.LoginError{}))
+			Expect(couriers[0].StreamLogsCall.Received.AppName).To(BeEmpty())
+			Expect(output.String()).To(BeEmpty())
+		})
+	})
+})
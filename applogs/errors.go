@@ -0,0 +1,13 @@
+package applogs
+
+import "fmt"
+
+type LogsError struct {
+	ApplicationName string
+	FoundationURL   string
+	Out             []byte
+}
+
+func (e LogsError) Error() string {
+	return fmt.Sprintf("cannot get the logs of app %s on %s: %s", e.ApplicationName, e.FoundationURL, string(e.Out))
+}
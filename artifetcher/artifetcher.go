@@ -5,9 +5,13 @@ import (
 	"io"
 	"net"
 	"net/http"
+	neturl "net/url"
 	"time"
 
+	"github.com/compozed/deployadactyl/artifetcher/extractor"
+	"github.com/compozed/deployadactyl/controller/deployer/hostpolicy"
 	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
 	"github.com/spf13/afero"
 )
 
@@ -22,10 +26,36 @@ func NewArtifetcher(fs *afero.Afero, ex I.Extractor, log I.DeploymentLogger) I.F
 }
 
 // Artifetcher fetches artifacts within a file system with an Extractor.
+// If Cache is set and a Checksum is provided, downloaded artifacts are cached.
+// A download that fails with a network error or a 5xx response is retried up to MaxRetries times.
+// The wait between retries starts at RetryBackoff and doubles after each retry.
 type Artifetcher struct {
-	FileSystem *afero.Afero
-	Extractor  I.Extractor
-	Log        I.DeploymentLogger
+	FileSystem   *afero.Afero
+	Extractor    I.Extractor
+	Log          I.DeploymentLogger
+	Cache        *ArtifactCache
+	Checksum     string
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// ContentType is the content type of the artifact. The artifact is extracted by the extractor in Extractors
+	// for it. Zip artifacts, and artifacts without a content type, are extracted by Extractor when Extractors
+	// has none for them.
+	ContentType string
+	Extractors  map[string]I.ArtifactExtractor
+
+	// Sleep waits between retries. Defaults to time.Sleep.
+	Sleep func(time.Duration)
+
+	// HostPolicy is checked on every connection and redirect of a download, so neither a redirect nor a DNS answer
+	// that changed since the request was checked can reach a host it denies. Downloads are not checked when it is nil.
+	HostPolicy *hostpolicy.Policy
+
+	// StagingDirectory is the directory artifacts are downloaded and extracted in. The temp directory of the
+	// operating system is used when it is empty.
+	StagingDirectory string
+
+	metadata S.ArtifactMetadata
 }
 
 // Fetch downloads an artifact located at URL.
@@ -36,68 +66,242 @@ func (a *Artifetcher) Fetch(url, manifest string) (string, error) {
 	a.Log.Info("fetching artifact")
 	a.Log.Debugf("artifact URL: %s", url)
 
-	artifactFile, err := a.FileSystem.TempFile("", "deployadactyl-zip-")
+	artifactExtractor, err := a.artifactExtractor()
+	if err != nil {
+		return "", err
+	}
+
+	artifactFile, err := a.FileSystem.TempFile(a.StagingDirectory, ArtifactFilePrefix)
 	if err != nil {
 		return "", CreateTempFileError{err}
 	}
 	defer artifactFile.Close()
 	defer a.FileSystem.Remove(artifactFile.Name())
 
+	if a.Cache != nil && a.Checksum != "" {
+		hit, err := a.Cache.Fetch(url, a.Checksum, artifactFile, func(writer io.Writer) error {
+			return a.download(url, writer)
+		})
+		if err != nil {
+			return "", err
+		}
+
+		if hit {
+			a.Log.Infof("artifact cache hit: %s", url)
+		} else {
+			a.Log.Infof("artifact cache miss: %s", url)
+		}
+	} else {
+		err = a.download(url, artifactFile)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = a.checkNotEmpty(artifactFile, url)
+	if err != nil {
+		return "", err
+	}
+
+	unzippedPath, err := a.FileSystem.TempDir(a.StagingDirectory, UnzippedDirectoryPrefix)
+	if err != nil {
+		return "", CreateTempDirectoryError{err}
+	}
+
+	extracted := false
+	defer func() {
+		if !extracted {
+			a.removeStaged(unzippedPath)
+		}
+	}()
+
+	err = artifactExtractor.Extract(artifactFile.Name(), unzippedPath, manifest)
+	if err != nil {
+		return "", UnzipError{err}
+
+	}
+
+	extracted = true
+	a.recordMetadata(url, artifactFile, readExtractedFile(a.FileSystem, unzippedPath))
+	a.Log.Debugf("fetched and unzipped to tempdir: %s", unzippedPath)
+	return unzippedPath, nil
+}
+
+// artifactExtractor returns the extractor of the ContentType of the artifact.
+// It returns an UnsupportedArtifactTypeError when there is none.
+func (a *Artifetcher) artifactExtractor() (I.ArtifactExtractor, error) {
+	contentType := extractor.MediaType(a.ContentType)
+
+	if artifactExtractor, ok := a.Extractors[contentType]; ok {
+		return artifactExtractor, nil
+	}
+	if contentType == extractor.ZipContentType {
+		return extractor.ArtifactExtractorFunc(a.Extractor.Unzip), nil
+	}
+
+	return nil, extractor.UnsupportedArtifactTypeError{contentType}
+}
+
+// download writes the artifact at url to writer, retrying failed attempts up to MaxRetries times.
+// Each retried attempt downloads to its own temp file so a partial download never reaches writer.
+func (a *Artifetcher) download(url string, writer io.Writer) error {
+	if a.MaxRetries < 1 {
+		return a.get(url, writer)
+	}
+
+	backoff := a.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := a.getToTempFile(url, writer)
+		if err == nil {
+			return nil
+		}
+
+		if attempt > a.MaxRetries || !retryable(err) {
+			return ArtifactDownloadError{URL: url, Attempts: attempt, Err: err}
+		}
+
+		a.Log.Errorf("attempt %d to download artifact %s failed, retrying in %s: %s", attempt, url, backoff, err)
+		a.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (a *Artifetcher) getToTempFile(url string, writer io.Writer) error {
+	downloadFile, err := a.FileSystem.TempFile(a.StagingDirectory, DownloadFilePrefix)
+	if err != nil {
+		return CreateTempFileError{err}
+	}
+	defer a.FileSystem.Remove(downloadFile.Name())
+	defer downloadFile.Close()
+
+	err = a.get(url, downloadFile)
+	if err != nil {
+		return err
+	}
+
+	_, err = downloadFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return WriteResponseError{err}
+	}
+
+	_, err = io.Copy(writer, downloadFile)
+	if err != nil {
+		return WriteResponseError{err}
+	}
+
+	return nil
+}
+
+func (a *Artifetcher) sleep(d time.Duration) {
+	if a.Sleep != nil {
+		a.Sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// retryable reports whether a failed download may succeed if it is tried again.
+func retryable(err error) bool {
+	switch e := err.(type) {
+	case GetUrlError, ReadResponseError:
+		return true
+	case GetStatusError:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+func (a *Artifetcher) get(url string, writer io.Writer) error {
+	dialer := &net.Dialer{
+		Timeout:   60 * time.Second,
+		KeepAlive: 60 * time.Second,
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   15 * time.Second,
+		ResponseHeaderTimeout: 15 * time.Second,
+		ExpectContinueTimeout: 2 * time.Second,
+	}
 	var client = &http.Client{
-		Timeout: 15 * time.Minute,
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout:   60 * time.Second,
-				KeepAlive: 60 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout:   15 * time.Second,
-			ResponseHeaderTimeout: 15 * time.Second,
-			ExpectContinueTimeout: 2 * time.Second,
-		},
+		Timeout:   15 * time.Minute,
+		Transport: transport,
+	}
+
+	if a.HostPolicy != nil {
+		transport.DialContext = a.HostPolicy.DialContext(dialer)
+		client.CheckRedirect = a.HostPolicy.CheckRedirect
 	}
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return "", FetcherRequestError{err}
+		return FetcherRequestError{err}
 	}
 
 	response, err := client.Do(req)
 	if err != nil {
-		return "", GetUrlError{url, err}
+		if denied, ok := hostNotAllowed(err); ok {
+			return denied
+		}
+		return GetUrlError{url, err}
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return "", GetStatusError{url, response.Status}
+		return GetStatusError{Url: url, Status: response.Status, StatusCode: response.StatusCode}
 	}
 
-	_, err = io.Copy(artifactFile, response.Body)
-	if err != nil {
-		return "", WriteResponseError{err}
+	body := &bodyReader{Reader: response.Body}
+	_, err = io.Copy(writer, body)
+	if body.Err != nil {
+		return ReadResponseError{url, body.Err}
 	}
-
-	unzippedPath, err := a.FileSystem.TempDir("", "deployadactyl-unzipped-")
 	if err != nil {
-		return "", CreateTempDirectoryError{err}
+		return WriteResponseError{err}
 	}
 
-	err = a.Extractor.Unzip(artifactFile.Name(), unzippedPath, manifest)
-	if err != nil {
-		a.FileSystem.RemoveAll(unzippedPath)
-		return "", UnzipError{err}
+	return nil
+}
 
+// hostNotAllowed returns the HostNotAllowedError of a request the HostPolicy stopped, which is not retried.
+func hostNotAllowed(err error) (error, bool) {
+	for {
+		switch e := err.(type) {
+		case hostpolicy.HostNotAllowedError:
+			return e, true
+		case *neturl.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		default:
+			return nil, false
+		}
 	}
+}
 
-	a.Log.Debugf("fetched and unzipped to tempdir: %s", unzippedPath)
-	return unzippedPath, nil
+// bodyReader records read errors so they can be told apart from write errors after a copy.
+type bodyReader struct {
+	Reader io.Reader
+	Err    error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		b.Err = err
+	}
+	return n, err
 }
 
 // FetchZipFromRequest fetches files from a compressed zip file in the request body.
 //
 // Returns a string to the unzipped application path and an error.
 func (a *Artifetcher) FetchZipFromRequest(body io.Reader) (string, string, error) {
+	artifactExtractor, err := a.artifactExtractor()
+	if err != nil {
+		return "", "", err
+	}
 
-	zipFile, err := a.FileSystem.TempFile("", "deployadactyl-")
+	zipFile, err := a.FileSystem.TempFile(a.StagingDirectory, ArtifactFilePrefix)
 	if err != nil {
 		return "", "", CreateTempFileError{err}
 	}
@@ -111,14 +315,25 @@ func (a *Artifetcher) FetchZipFromRequest(body io.Reader) (string, string, error
 		return "", "", WriteResponseError{err}
 	}
 
-	unzippedPath, err := a.FileSystem.TempDir("", "deployadactyl-")
+	err = a.checkNotEmpty(zipFile, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	unzippedPath, err := a.FileSystem.TempDir(a.StagingDirectory, UnzippedDirectoryPrefix)
 	if err != nil {
 		return "", "", CreateTempDirectoryError{err}
 	}
 
-	err = a.Extractor.Unzip(zipFile.Name(), unzippedPath, "")
+	extracted := false
+	defer func() {
+		if !extracted {
+			a.removeStaged(unzippedPath)
+		}
+	}()
+
+	err = artifactExtractor.Extract(zipFile.Name(), unzippedPath, "")
 	if err != nil {
-		a.FileSystem.RemoveAll(unzippedPath)
 		return "", "", UnzipError{err}
 	}
 
@@ -127,6 +342,25 @@ func (a *Artifetcher) FetchZipFromRequest(body io.Reader) (string, string, error
 		return "", "", err
 	}
 
+	extracted = true
+	a.recordMetadata("", zipFile, readExtractedFile(a.FileSystem, unzippedPath))
 	a.Log.Debugf("fetched and unzipped to tempdir %s", unzippedPath)
 	return unzippedPath, string(manifest), nil
 }
+
+// checkNotEmpty returns an EmptyArtifactError when nothing was written to the artifact file, so an empty artifact
+// is not handed to the extractor. The url is empty for an artifact from the request body.
+func (a *Artifetcher) checkNotEmpty(artifactFile afero.File, url string) error {
+	info, err := artifactFile.Stat()
+	if err != nil {
+		return WriteResponseError{err}
+	}
+
+	if info.Size() == 0 {
+		err = EmptyArtifactError{url}
+		a.Log.Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -1,10 +1,20 @@
 package artifetcher_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,6 +23,7 @@ import (
 	"github.com/op/go-logging"
 
 	. "github.com/compozed/deployadactyl/artifetcher"
+	"github.com/compozed/deployadactyl/controller/deployer/hostpolicy"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
 	"github.com/compozed/deployadactyl/interfaces"
@@ -33,7 +44,7 @@ var _ = Describe("Artifetcher", func() {
 		log = interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(GinkgoWriter, logging.DEBUG, "artifetcher_test")}
 		af = &afero.Afero{Fs: afero.NewMemMapFs()}
 		extractor = &mocks.Extractor{}
-		artifetcher = &Artifetcher{af, extractor, log}
+		artifetcher = &Artifetcher{FileSystem: af, Extractor: extractor, Log: log}
 		manifest = "manifest-" + randomizer.StringRunes(10)
 
 		testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -73,6 +84,17 @@ var _ = Describe("Artifetcher", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
+		It("returns an EmptyArtifactError when the artifact is empty", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			artifactURL := strings.Replace(testserver.URL, "http://", "http://user:secret@", 1)
+
+			_, err := artifetcher.Fetch(artifactURL, manifest)
+
+			Expect(err).To(MatchError(EmptyArtifactError{artifactURL}))
+			Expect(err.Error()).ToNot(ContainSubstring("secret"))
+			Expect(extractor.UnzipCall.Received.Source).To(BeEmpty())
+		})
+
 		Context("when extractor fails", func() {
 			It("returns an error", func() {
 				extractor.UnzipCall.Returns.Error = errors.New("unzip call failed")
@@ -84,9 +106,232 @@ var _ = Describe("Artifetcher", func() {
 		})
 	})
 
+	Describe("recording the artifact metadata", func() {
+		It("records the url, checksum, size and fetch time of the artifact", func() {
+			fixture, err := os.Stat("./fixtures/deployadactyl-fixture.jar")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			metadata := artifetcher.Metadata()
+			Expect(metadata.URL).To(Equal(testserver.URL))
+			Expect(metadata.Checksum).To(Equal("f6bdd0c3378276630679e1845601e166d30e7c3546d57059420e01373ee73f43"))
+			Expect(metadata.SizeBytes).To(Equal(fixture.Size()))
+			Expect(metadata.FetchedAt).To(BeTemporally("~", time.Now(), time.Minute))
+			Expect(metadata.GitSHA).To(BeEmpty())
+		})
+
+		It("removes the credentials from the url", func() {
+			_, err := artifetcher.Fetch(strings.Replace(testserver.URL, "http://", "http://user:secret@", 1), "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(artifetcher.Metadata().URL).To(Equal(testserver.URL))
+		})
+
+		It("records the commit of the git.properties of the artifact", func() {
+			artifetcher.ContentType = E.ZipContentType
+			artifetcher.Extractors = map[string]interfaces.ArtifactExtractor{
+				E.ZipContentType: E.ArtifactExtractorFunc(func(source, destination, manifest string) error {
+					properties := "#Generated by Git-Commit-Id-Plugin\ngit.commit.id.abbrev=3f2c1e0\ngit.commit.id.full=3f2c1e0b9a8d7c6b5a4f3e2d1c0b9a8d7c6b5a4f\n"
+					return af.WriteFile(path.Join(destination, "BOOT-INF/classes/git.properties"), []byte(properties), 0644)
+				}),
+			}
+
+			_, err := artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(artifetcher.Metadata().GitSHA).To(Equal("3f2c1e0b9a8d7c6b5a4f3e2d1c0b9a8d7c6b5a4f"))
+		})
+	})
+
+	Describe("fetching a zip file with an artifact cache", func() {
+		var (
+			requests int
+			checksum string
+		)
+
+		BeforeEach(func() {
+			requests = 0
+			checksum = "f6bdd0c3378276630679e1845601e166d30e7c3546d57059420e01373ee73f43"
+
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				http.ServeFile(w, r, "./fixtures/deployadactyl-fixture.jar")
+			}))
+
+			cache, err := NewArtifactCache(af, "/cache", 1024*1024*1024)
+			Expect(err).ToNot(HaveOccurred())
+
+			artifetcher.Cache = cache
+			artifetcher.Checksum = checksum
+		})
+
+		It("only downloads the artifact once", func() {
+			_, err := artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(Equal(1))
+		})
+
+		It("downloads the artifact when no checksum is provided", func() {
+			artifetcher.Checksum = ""
+
+			_, err := artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(Equal(2))
+		})
+
+		It("returns an error when the checksum does not match", func() {
+			artifetcher.Checksum = "bad-checksum"
+
+			_, err := artifetcher.Fetch(testserver.URL, "")
+			Expect(err).To(BeAssignableToTypeOf(ChecksumMismatchError{}))
+		})
+	})
+
+	Describe("retrying a failed download", func() {
+		var (
+			requests int
+			sleeps   []time.Duration
+			fixture  []byte
+		)
+
+		BeforeEach(func() {
+			requests = 0
+			sleeps = nil
+
+			var err error
+			fixture, err = ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+			Expect(err).ToNot(HaveOccurred())
+
+			artifetcher.MaxRetries = 2
+			artifetcher.RetryBackoff = time.Second
+			artifetcher.Sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+		})
+
+		It("retries 5xx responses with a doubling backoff", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests < 3 {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				w.Write(fixture)
+			}))
+
+			_, err := artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(Equal(3))
+			Expect(sleeps).To(Equal([]time.Duration{time.Second, 2 * time.Second}))
+		})
+
+		It("does not retry 4xx responses", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				http.Error(w, "not found", http.StatusNotFound)
+			}))
+
+			_, err := artifetcher.Fetch(testserver.URL, "")
+
+			Expect(requests).To(Equal(1))
+			Expect(err).To(MatchError(ContainSubstring("gave up after attempt 1")))
+			Expect(err.(ArtifactDownloadError).Err).To(BeAssignableToTypeOf(GetStatusError{}))
+		})
+
+		It("returns the url and attempt count when every attempt fails", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+			}))
+
+			_, err := artifetcher.Fetch(testserver.URL, "")
+
+			Expect(requests).To(Equal(3))
+			Expect(err).To(MatchError(ArtifactDownloadError{
+				URL:      testserver.URL,
+				Attempts: 3,
+				Err:      GetStatusError{Url: testserver.URL, Status: "502 Bad Gateway", StatusCode: http.StatusBadGateway},
+			}))
+		})
+
+		It("discards a partial download before retrying", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Content-Length", strconv.Itoa(len(fixture)))
+				if requests == 1 {
+					w.Write(fixture[:len(fixture)/2])
+					conn, _, _ := w.(http.Hijacker).Hijack()
+					conn.Close()
+					return
+				}
+				w.Write(fixture)
+			}))
+
+			cache, err := NewArtifactCache(af, "/cache", 1024*1024*1024)
+			Expect(err).ToNot(HaveOccurred())
+			artifetcher.Cache = cache
+			artifetcher.Checksum = "f6bdd0c3378276630679e1845601e166d30e7c3546d57059420e01373ee73f43"
+
+			_, err = artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(Equal(2))
+		})
+	})
+
+	Describe("fetching with a host policy", func() {
+		BeforeEach(func() {
+			artifetcher.MaxRetries = 2
+			artifetcher.Sleep = func(time.Duration) {}
+		})
+
+		It("fetches from hosts the policy allows", func() {
+			artifetcher.HostPolicy = &hostpolicy.Policy{DeniedCIDRs: []string{"10.0.0.0/8"}}
+
+			_, err := artifetcher.Fetch(testserver.URL, "")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not follow a redirect to a host the policy denies", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+			}))
+			artifetcher.HostPolicy = &hostpolicy.Policy{}
+
+			_, err := artifetcher.Fetch(testserver.URL, "")
+
+			Expect(err.(ArtifactDownloadError).Attempts).To(Equal(1))
+			Expect(err.(ArtifactDownloadError).Err).To(MatchError(hostpolicy.HostNotAllowedError{"169.254.169.254", "it is in the denied range 169.254.0.0/16"}))
+		})
+
+		It("does not connect to a host that resolves to a range the policy denies", func() {
+			artifetcher.HostPolicy = &hostpolicy.Policy{
+				DeniedCIDRs: []string{"127.0.0.0/8"},
+				LookupIP: func(host string) ([]net.IP, error) {
+					return []net.IP{net.ParseIP("127.0.0.1")}, nil
+				},
+			}
+			_, port, err := net.SplitHostPort(testserver.Listener.Addr().String())
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = artifetcher.Fetch("http://artifacts.example.com:"+port+"/artifact.zip", "")
+
+			Expect(err.(ArtifactDownloadError).Err).To(MatchError(hostpolicy.HostNotAllowedError{"artifacts.example.com", "it is in the denied range 127.0.0.0/8"}))
+		})
+	})
+
 	Describe("fetching a zip file from a request", func() {
 		It("returns the path to the unzipped directory and manifest", func() {
-			artifetcher = &Artifetcher{af, E.NewExtractor(log, af), log}
+			artifetcher = &Artifetcher{FileSystem: af, Extractor: E.NewExtractor(log, af), Log: log}
 
 			expectManifest := `---
 applications:
@@ -103,6 +348,15 @@ applications:
 			Expect(manifest).To(ContainSubstring(expectManifest))
 		})
 
+		It("returns an EmptyArtifactError when the request body is empty", func() {
+			path, _, err := artifetcher.FetchZipFromRequest(strings.NewReader(""))
+
+			Expect(err).To(MatchError(EmptyArtifactError{}))
+			Expect(err).To(MatchError("the artifact in the request body is empty"))
+			Expect(path).To(BeEmpty())
+			Expect(extractor.UnzipCall.Received.Source).To(BeEmpty())
+		})
+
 		Context("when extractor fails", func() {
 			It("returns an error", func() {
 				errorMessage := "test extract fail"
@@ -118,4 +372,121 @@ applications:
 			})
 		})
 	})
+
+	Describe("staging a zip file from a request", func() {
+		It("stages the zip with its manifest without extracting it", func() {
+			body, err := os.Open("./fixtures/artifact-with-manifest.jar")
+			Expect(err).ToNot(HaveOccurred())
+
+			stagedPath, manifest, err := artifetcher.StageZipFromRequest(body)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(stagedPath).To(ContainSubstring(StagedDirectoryPrefix))
+			Expect(manifest).To(ContainSubstring("name: artifact-with-manifest"))
+			Expect(af.ReadFile(path.Join(stagedPath, "manifest.yml"))).To(Equal([]byte(manifest)))
+			Expect(af.Exists(path.Join(stagedPath, StagedArtifactFile))).To(BeTrue())
+			Expect(af.Exists(path.Join(stagedPath, "index.html"))).To(BeFalse())
+			Expect(extractor.UnzipCall.Received.Source).To(BeEmpty())
+		})
+
+		It("records the checksum of the zip and the commit of its git.properties", func() {
+			buffer := &bytes.Buffer{}
+			writer := zip.NewWriter(buffer)
+			for name, contents := range map[string]string{
+				"manifest.yml":                    "---\napplications:\n- name: my-app\n",
+				"BOOT-INF/classes/git.properties": "git.commit.id.full=3f2c1e0b9a8d7c6b5a4f3e2d1c0b9a8d7c6b5a4f\n",
+			} {
+				file, err := writer.Create(name)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = file.Write([]byte(contents))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(writer.Close()).To(Succeed())
+			checksum := sha256.Sum256(buffer.Bytes())
+			size := int64(buffer.Len())
+
+			_, _, err := artifetcher.StageZipFromRequest(buffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			metadata := artifetcher.Metadata()
+			Expect(metadata.Checksum).To(Equal(hex.EncodeToString(checksum[:])))
+			Expect(metadata.SizeBytes).To(Equal(size))
+			Expect(metadata.GitSHA).To(Equal("3f2c1e0b9a8d7c6b5a4f3e2d1c0b9a8d7c6b5a4f"))
+		})
+
+		It("returns an EmptyArtifactError when the request body is empty", func() {
+			stagedPath, _, err := artifetcher.StageZipFromRequest(strings.NewReader(""))
+
+			Expect(err).To(MatchError(EmptyArtifactError{}))
+			Expect(stagedPath).To(BeEmpty())
+		})
+
+		It("returns an UnzipError and removes the staging directory when the body is not a zip", func() {
+			stagedPath, _, err := artifetcher.StageZipFromRequest(strings.NewReader("not a zip"))
+
+			Expect(err).To(BeAssignableToTypeOf(UnzipError{}))
+			Expect(stagedPath).To(BeEmpty())
+			Expect(af.ReadDir(os.TempDir())).To(BeEmpty())
+		})
+
+		It("returns an UnsupportedArtifactTypeError for an artifact of another content type", func() {
+			artifetcher.ContentType = "application/x-tar"
+
+			_, _, err := artifetcher.StageZipFromRequest(strings.NewReader("tar"))
+
+			Expect(err).To(MatchError(E.UnsupportedArtifactTypeError{"application/x-tar"}))
+		})
+	})
+
+	Describe("fetching an artifact of another content type", func() {
+		var tarExtractor *mocks.ArtifactExtractor
+
+		BeforeEach(func() {
+			tarExtractor = &mocks.ArtifactExtractor{}
+			artifetcher.Extractors = map[string]interfaces.ArtifactExtractor{"application/x-tar": tarExtractor}
+			artifetcher.ContentType = "application/x-tar"
+		})
+
+		It("extracts the artifact with the extractor of its content type", func() {
+			unzippedPath, err := artifetcher.Fetch(testserver.URL, manifest)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tarExtractor.ExtractCall.Received.Destination).To(Equal(unzippedPath))
+			Expect(tarExtractor.ExtractCall.Received.Manifest).To(Equal(manifest))
+			Expect(extractor.UnzipCall.Received.Source).To(BeEmpty())
+		})
+
+		It("extracts an artifact from a request with the extractor of its content type", func() {
+			body, err := os.Open("./fixtures/bad-deployadactyl-fixture.tar")
+			Expect(err).ToNot(HaveOccurred())
+
+			artifetcher.FetchZipFromRequest(body)
+
+			Expect(tarExtractor.ExtractCall.Called).To(Equal(1))
+			Expect(extractor.UnzipCall.Received.Source).To(BeEmpty())
+		})
+
+		It("extracts zip artifacts with the extractor when none is registered for them", func() {
+			artifetcher.ContentType = "application/zip"
+
+			_, err := artifetcher.Fetch(testserver.URL, manifest)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(extractor.UnzipCall.Received.Manifest).To(Equal(manifest))
+			Expect(tarExtractor.ExtractCall.Called).To(Equal(0))
+		})
+
+		It("returns an UnsupportedArtifactTypeError without downloading when no extractor is registered", func() {
+			downloads := 0
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				downloads++
+			}))
+			artifetcher.ContentType = "application/gzip"
+
+			_, err := artifetcher.Fetch(testserver.URL, manifest)
+
+			Expect(err).To(MatchError(E.UnsupportedArtifactTypeError{"application/gzip"}))
+			Expect(downloads).To(Equal(0))
+		})
+	})
 })
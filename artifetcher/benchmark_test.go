@@ -0,0 +1,95 @@
+package artifetcher_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	. "github.com/compozed/deployadactyl/artifetcher"
+	E "github.com/compozed/deployadactyl/artifetcher/extractor"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+// BenchmarkFetchZipFromRequest and BenchmarkStageZipFromRequest compare extracting a zip push with staging it
+// to be pushed as is, on the disk, for a zip of 200 files of 256KB.
+func BenchmarkFetchZipFromRequest(b *testing.B) {
+	benchmarkZipFromRequest(b, func(artifetcher *Artifetcher, body *bytes.Reader) (string, error) {
+		appPath, _, err := artifetcher.FetchZipFromRequest(body)
+		return appPath, err
+	})
+}
+
+func BenchmarkStageZipFromRequest(b *testing.B) {
+	benchmarkZipFromRequest(b, func(artifetcher *Artifetcher, body *bytes.Reader) (string, error) {
+		appPath, _, err := artifetcher.StageZipFromRequest(body)
+		return appPath, err
+	})
+}
+
+func benchmarkZipFromRequest(b *testing.B, fetch func(*Artifetcher, *bytes.Reader) (string, error)) {
+	stagingDirectory, err := ioutil.TempDir("", "deployadactyl-benchmark-")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(stagingDirectory)
+
+	af := &afero.Afero{Fs: afero.NewOsFs()}
+	log := interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(ioutil.Discard, logging.ERROR, "artifetcher_benchmark")}
+	artifetcher := &Artifetcher{
+		FileSystem:       af,
+		Extractor:        E.NewExtractor(log, af),
+		Log:              log,
+		StagingDirectory: stagingDirectory,
+	}
+
+	artifact := benchmarkZip(b, 200, 256*1024)
+	b.SetBytes(int64(len(artifact)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		appPath, err := fetch(artifetcher, bytes.NewReader(artifact))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		af.RemoveAll(appPath)
+		b.StartTimer()
+	}
+}
+
+// benchmarkZip returns a zip with a manifest and files of random bytes, which do not compress.
+func benchmarkZip(b *testing.B, files, size int) []byte {
+	buffer := &bytes.Buffer{}
+	writer := zip.NewWriter(buffer)
+
+	manifest, err := writer.Create("manifest.yml")
+	if err != nil {
+		b.Fatal(err)
+	}
+	manifest.Write([]byte("---\napplications:\n- name: benchmark\n"))
+
+	contents := make([]byte, size)
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < files; i++ {
+		random.Read(contents)
+
+		file, err := writer.Create(fmt.Sprintf("lib/library-%d.jar", i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		file.Write(contents)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return buffer.Bytes()
+}
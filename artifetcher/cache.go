@@ -0,0 +1,262 @@
+package artifetcher
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// ArtifactCache is an on-disk cache of downloaded artifacts keyed by artifact URL and checksum.
+// Least recently used artifacts are evicted once the total size of the cache exceeds MaxSize.
+type ArtifactCache struct {
+	FileSystem *afero.Afero
+	Directory  string
+	MaxSize    int64
+
+	mutex    sync.Mutex
+	size     int64
+	lru      *list.List
+	entries  map[string]*list.Element
+	inflight map[string]chan struct{}
+}
+
+type cacheEntry struct {
+	key     string
+	path    string
+	size    int64
+	readers int
+}
+
+// NewArtifactCache returns an ArtifactCache rooted at directory.
+// Artifacts cached in the directory by a previous run are kept and evicted as if they had been used in the
+// order they were written. Partial downloads are removed and any other files are left alone.
+func NewArtifactCache(fs *afero.Afero, directory string, maxSize int64) (*ArtifactCache, error) {
+	err := fs.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, CreateCacheDirectoryError{directory, err}
+	}
+
+	cache := &ArtifactCache{
+		FileSystem: fs,
+		Directory:  directory,
+		MaxSize:    maxSize,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+		inflight:   map[string]chan struct{}{},
+	}
+
+	err = cache.load()
+	if err != nil {
+		return nil, CreateCacheDirectoryError{directory, err}
+	}
+
+	return cache, nil
+}
+
+// load indexes the artifacts already in the directory, oldest first, and evicts them down to MaxSize.
+func (c *ArtifactCache) load() error {
+	files, err := c.FileSystem.ReadDir(c.Directory)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		if strings.HasPrefix(file.Name(), downloadPrefix) {
+			c.FileSystem.Remove(path.Join(c.Directory, file.Name()))
+			continue
+		}
+
+		if !isCacheKey(file.Name()) {
+			continue
+		}
+
+		entry := &cacheEntry{key: file.Name(), path: path.Join(c.Directory, file.Name()), size: file.Size()}
+		c.entries[entry.key] = c.lru.PushFront(entry)
+		c.size += entry.size
+	}
+
+	c.evict()
+	return nil
+}
+
+// Fetch writes the artifact identified by url and checksum to writer. If the artifact is not
+// cached, download is called to retrieve it and the result is verified against checksum before
+// it is stored. Concurrent fetches of the same artifact only download it once.
+//
+// Returns true if the artifact was served from the cache.
+func (c *ArtifactCache) Fetch(url, checksum string, writer io.Writer, download func(writer io.Writer) error) (bool, error) {
+	key := cacheKey(url, checksum)
+
+	for {
+		c.mutex.Lock()
+
+		if element, ok := c.entries[key]; ok {
+			entry := c.acquire(element)
+			c.mutex.Unlock()
+
+			return true, c.copyEntry(entry, writer)
+		}
+
+		wait, ok := c.inflight[key]
+		if !ok {
+			break
+		}
+
+		c.mutex.Unlock()
+		<-wait
+	}
+
+	done := make(chan struct{})
+	c.inflight[key] = done
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.inflight, key)
+		close(done)
+		c.mutex.Unlock()
+	}()
+
+	entry, err := c.store(key, checksum, download)
+	if err != nil {
+		return false, err
+	}
+
+	return false, c.copyEntry(entry, writer)
+}
+
+func (c *ArtifactCache) store(key, checksum string, download func(writer io.Writer) error) (*cacheEntry, error) {
+	file, err := c.FileSystem.TempFile(c.Directory, downloadPrefix)
+	if err != nil {
+		return nil, CreateTempFileError{err}
+	}
+	defer file.Close()
+
+	sum := sha256.New()
+	counter := &countingWriter{Hash: sum}
+
+	err = download(io.MultiWriter(file, counter))
+	if err != nil {
+		c.FileSystem.Remove(file.Name())
+		return nil, err
+	}
+
+	actual := hex.EncodeToString(sum.Sum(nil))
+	if !strings.EqualFold(actual, checksum) {
+		c.FileSystem.Remove(file.Name())
+		return nil, ChecksumMismatchError{checksum, actual}
+	}
+
+	entryPath := path.Join(c.Directory, key)
+	err = c.FileSystem.Rename(file.Name(), entryPath)
+	if err != nil {
+		c.FileSystem.Remove(file.Name())
+		return nil, WriteResponseError{err}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := &cacheEntry{key: key, path: entryPath, size: counter.Count, readers: 1}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += entry.size
+	c.evict()
+
+	return entry, nil
+}
+
+// acquire marks an entry as being read so it is not evicted. The caller must hold the mutex.
+func (c *ArtifactCache) acquire(element *list.Element) *cacheEntry {
+	entry := element.Value.(*cacheEntry)
+	entry.readers++
+	c.lru.MoveToFront(element)
+	return entry
+}
+
+func (c *ArtifactCache) release(entry *cacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry.readers--
+	c.evict()
+}
+
+func (c *ArtifactCache) copyEntry(entry *cacheEntry, writer io.Writer) error {
+	defer c.release(entry)
+
+	file, err := c.FileSystem.Open(entry.path)
+	if err != nil {
+		return WriteResponseError{err}
+	}
+	defer file.Close()
+
+	_, err = io.Copy(writer, file)
+	if err != nil {
+		return WriteResponseError{err}
+	}
+
+	return nil
+}
+
+// evict removes least recently used entries that are not being read until the cache
+// fits within MaxSize. The caller must hold the mutex.
+func (c *ArtifactCache) evict() {
+	element := c.lru.Back()
+	for c.size > c.MaxSize && element != nil {
+		previous := element.Prev()
+
+		entry := element.Value.(*cacheEntry)
+		if entry.readers == 0 {
+			c.lru.Remove(element)
+			delete(c.entries, entry.key)
+			c.size -= entry.size
+			c.FileSystem.Remove(entry.path)
+		}
+
+		element = previous
+	}
+}
+
+// downloadPrefix names the temporary files artifacts are downloaded to before they are verified.
+const downloadPrefix = "download-"
+
+func cacheKey(url, checksum string) string {
+	sum := sha256.Sum256([]byte(url + "\n" + strings.ToLower(checksum)))
+	return hex.EncodeToString(sum[:])
+}
+
+// isCacheKey returns whether name is a key returned by cacheKey.
+func isCacheKey(name string) bool {
+	if len(name) != sha256.Size*2 || strings.ToLower(name) != name {
+		return false
+	}
+
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+type countingWriter struct {
+	Hash  hash.Hash
+	Count int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.Count += int64(len(p))
+	return w.Hash.Write(p)
+}
@@ -0,0 +1,235 @@
+package artifetcher_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+
+	. "github.com/compozed/deployadactyl/artifetcher"
+	"github.com/compozed/deployadactyl/randomizer"
+)
+
+var _ = Describe("ArtifactCache", func() {
+	var (
+		af       *afero.Afero
+		cache    *ArtifactCache
+		content  string
+		checksum string
+		url      string
+	)
+
+	download := func(content string, calls *int) func(writer io.Writer) error {
+		return func(writer io.Writer) error {
+			*calls++
+			_, err := io.WriteString(writer, content)
+			return err
+		}
+	}
+
+	sha := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		cache, err = NewArtifactCache(af, "/cache", 10)
+		Expect(err).ToNot(HaveOccurred())
+
+		content = randomizer.StringRunes(5)
+		checksum = sha(content)
+		url = "https://example.com/" + randomizer.StringRunes(10)
+	})
+
+	It("downloads the artifact on a miss and serves it from disk on a hit", func() {
+		calls := 0
+
+		first := &bytes.Buffer{}
+		hit, err := cache.Fetch(url, checksum, first, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hit).To(BeFalse())
+		Expect(first.String()).To(Equal(content))
+
+		second := &bytes.Buffer{}
+		hit, err = cache.Fetch(url, checksum, second, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hit).To(BeTrue())
+		Expect(second.String()).To(Equal(content))
+
+		Expect(calls).To(Equal(1))
+	})
+
+	It("keys artifacts by url and checksum", func() {
+		calls := 0
+		otherContent := randomizer.StringRunes(5)
+
+		_, err := cache.Fetch(url, checksum, &bytes.Buffer{}, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+
+		buffer := &bytes.Buffer{}
+		hit, err := cache.Fetch(url, sha(otherContent), buffer, download(otherContent, &calls))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(hit).To(BeFalse())
+		Expect(buffer.String()).To(Equal(otherContent))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("evicts the least recently used artifact when the cache is full", func() {
+		calls := 0
+		otherURL := "https://example.com/" + randomizer.StringRunes(10)
+		thirdURL := "https://example.com/" + randomizer.StringRunes(10)
+
+		_, err := cache.Fetch(url, checksum, &bytes.Buffer{}, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = cache.Fetch(otherURL, checksum, &bytes.Buffer{}, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+
+		hit, err := cache.Fetch(url, checksum, &bytes.Buffer{}, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hit).To(BeTrue())
+
+		_, err = cache.Fetch(thirdURL, checksum, &bytes.Buffer{}, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+
+		hit, err = cache.Fetch(url, checksum, &bytes.Buffer{}, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hit).To(BeTrue())
+
+		hit, err = cache.Fetch(otherURL, checksum, &bytes.Buffer{}, download(content, &calls))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hit).To(BeFalse())
+	})
+
+	It("only downloads once for concurrent fetches of the same artifact", func() {
+		var (
+			mutex sync.Mutex
+			calls int
+			wg    sync.WaitGroup
+		)
+
+		release := make(chan struct{})
+		slowDownload := func(writer io.Writer) error {
+			mutex.Lock()
+			calls++
+			mutex.Unlock()
+
+			<-release
+			_, err := io.WriteString(writer, content)
+			return err
+		}
+
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				buffer := &bytes.Buffer{}
+				_, err := cache.Fetch(url, checksum, buffer, slowDownload)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(buffer.String()).To(Equal(content))
+			}()
+		}
+
+		close(release)
+		wg.Wait()
+
+		Expect(calls).To(Equal(1))
+	})
+
+	Context("when the download fails", func() {
+		It("returns the error and does not cache the artifact", func() {
+			calls := 0
+
+			_, err := cache.Fetch(url, checksum, &bytes.Buffer{}, func(writer io.Writer) error {
+				return errors.New("download failed")
+			})
+			Expect(err).To(MatchError("download failed"))
+
+			hit, err := cache.Fetch(url, checksum, &bytes.Buffer{}, download(content, &calls))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hit).To(BeFalse())
+		})
+	})
+
+	Context("when the checksum does not match", func() {
+		It("returns a ChecksumMismatchError", func() {
+			calls := 0
+
+			_, err := cache.Fetch(url, "bad-checksum", &bytes.Buffer{}, download(content, &calls))
+
+			Expect(err).To(MatchError(ChecksumMismatchError{"bad-checksum", checksum}))
+		})
+	})
+
+	Context("when the directory is used by a previous run", func() {
+		It("serves the artifacts cached by the previous run", func() {
+			calls := 0
+
+			_, err := cache.Fetch(url, checksum, &bytes.Buffer{}, download(content, &calls))
+			Expect(err).ToNot(HaveOccurred())
+
+			cache, err = NewArtifactCache(af, "/cache", 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			buffer := &bytes.Buffer{}
+			hit, err := cache.Fetch(url, checksum, buffer, download(content, &calls))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(hit).To(BeTrue())
+			Expect(buffer.String()).To(Equal(content))
+			Expect(calls).To(Equal(1))
+		})
+
+		It("removes partial downloads and leaves other files alone", func() {
+			Expect(af.WriteFile("/cache/download-123", []byte("partial"), 0644)).To(Succeed())
+			Expect(af.WriteFile("/cache/notes.txt", []byte("notes"), 0644)).To(Succeed())
+
+			_, err := NewArtifactCache(af, "/cache", 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(af.Exists("/cache/download-123")).To(BeFalse())
+			Expect(af.Exists("/cache/notes.txt")).To(BeTrue())
+		})
+
+		It("evicts the oldest artifacts that do not fit", func() {
+			calls := 0
+			otherURL := "https://example.com/" + randomizer.StringRunes(10)
+
+			_, err := cache.Fetch(url, checksum, &bytes.Buffer{}, download(content, &calls))
+			Expect(err).ToNot(HaveOccurred())
+
+			files, err := af.ReadDir("/cache")
+			Expect(err).ToNot(HaveOccurred())
+			for _, file := range files {
+				written := time.Now().Add(-time.Hour)
+				Expect(af.Chtimes("/cache/"+file.Name(), written, written)).To(Succeed())
+			}
+
+			_, err = cache.Fetch(otherURL, checksum, &bytes.Buffer{}, download(content, &calls))
+			Expect(err).ToNot(HaveOccurred())
+
+			cache, err = NewArtifactCache(af, "/cache", 5)
+			Expect(err).ToNot(HaveOccurred())
+
+			hit, err := cache.Fetch(otherURL, checksum, &bytes.Buffer{}, download(content, &calls))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hit).To(BeTrue())
+
+			hit, err = cache.Fetch(url, checksum, &bytes.Buffer{}, download(content, &calls))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hit).To(BeFalse())
+		})
+	})
+})
@@ -28,14 +28,34 @@ func (e GetUrlError) Error() string {
 }
 
 type GetStatusError struct {
-	Url    string
-	Status string
+	Url        string
+	Status     string
+	StatusCode int
 }
 
 func (e GetStatusError) Error() string {
 	return fmt.Sprintf("cannot GET url: %s: %s", e.Url, e.Status)
 }
 
+type ReadResponseError struct {
+	Url string
+	Err error
+}
+
+func (e ReadResponseError) Error() string {
+	return fmt.Sprintf("cannot read response from url: %s: %s", e.Url, e.Err)
+}
+
+type ArtifactDownloadError struct {
+	URL      string
+	Attempts int
+	Err      error
+}
+
+func (e ArtifactDownloadError) Error() string {
+	return fmt.Sprintf("cannot download artifact %s: gave up after attempt %d: %s", e.URL, e.Attempts, e.Err)
+}
+
 type WriteResponseError struct {
 	Err error
 }
@@ -52,6 +72,26 @@ func (e CreateTempDirectoryError) Error() string {
 	return fmt.Sprintf("cannot create temp directory: %s", e.Err)
 }
 
+type StagingDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e StagingDirectoryError) Error() string {
+	return fmt.Sprintf("cannot prepare staging directory %s: %s", e.Directory, e.Err)
+}
+
+type EmptyArtifactError struct {
+	URL string
+}
+
+func (e EmptyArtifactError) Error() string {
+	if e.URL == "" {
+		return "the artifact in the request body is empty"
+	}
+	return fmt.Sprintf("the artifact at %s is empty", redactURL(e.URL))
+}
+
 type UnzipError struct {
 	Err error
 }
@@ -59,3 +99,21 @@ type UnzipError struct {
 func (e UnzipError) Error() string {
 	return fmt.Sprintf("cannot unzip artifact: %s", e.Err)
 }
+
+type CreateCacheDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e CreateCacheDirectoryError) Error() string {
+	return fmt.Sprintf("cannot create artifact cache directory %s: %s", e.Directory, e.Err)
+}
+
+type ChecksumMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("artifact checksum mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
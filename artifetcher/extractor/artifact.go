@@ -0,0 +1,54 @@
+package extractor
+
+import (
+	"mime"
+	"strings"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/spf13/afero"
+)
+
+// ZipContentType is the content type of zip artifacts. They are extracted by an Extractor unless another
+// ArtifactExtractor is registered for them.
+const ZipContentType = "application/zip"
+
+// ArtifactExtractorConstructor creates the ArtifactExtractor of an artifact content type.
+type ArtifactExtractorConstructor func(log I.DeploymentLogger, fs *afero.Afero) I.ArtifactExtractor
+
+// ArtifactExtractorFunc is an ArtifactExtractor that calls the function.
+type ArtifactExtractorFunc func(source, destination, manifest string) error
+
+// Extract calls f(source, destination, manifest).
+func (f ArtifactExtractorFunc) Extract(source, destination, manifest string) error {
+	return f(source, destination, manifest)
+}
+
+// MediaType returns the content type without its parameters, in lower case.
+// An artifact without a content type is a zip artifact.
+func MediaType(contentType string) string {
+	if contentType == "" {
+		return ZipContentType
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
+
+// Supported reports whether an artifact of the content type can be extracted when there are extractors for
+// contentTypes. Zip artifacts can always be extracted.
+func Supported(contentTypes []string, contentType string) bool {
+	mediaType := MediaType(contentType)
+	if mediaType == ZipContentType {
+		return true
+	}
+
+	for _, supported := range contentTypes {
+		if MediaType(supported) == mediaType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package extractor_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/compozed/deployadactyl/artifetcher/extractor"
+)
+
+var _ = Describe("Artifact extractors", func() {
+	It("returns the media type of a content type", func() {
+		Expect(MediaType("Application/X-Tar; charset=binary")).To(Equal("application/x-tar"))
+		Expect(MediaType("")).To(Equal(ZipContentType))
+	})
+
+	It("can always extract zip artifacts", func() {
+		Expect(Supported(nil, "application/zip")).To(BeTrue())
+		Expect(Supported(nil, "")).To(BeTrue())
+	})
+
+	It("can extract artifacts of the registered content types", func() {
+		Expect(Supported([]string{"application/x-tar"}, "application/x-tar")).To(BeTrue())
+		Expect(Supported([]string{"application/x-tar"}, "application/gzip")).To(BeFalse())
+	})
+
+	It("calls an ArtifactExtractorFunc", func() {
+		var received []string
+		extract := ArtifactExtractorFunc(func(source, destination, manifest string) error {
+			received = []string{source, destination, manifest}
+			return errors.New("extract failed")
+		})
+
+		Expect(extract.Extract("/artifact.tar", "/app", "manifest")).To(MatchError("extract failed"))
+		Expect(received).To(Equal([]string{"/artifact.tar", "/app", "manifest"}))
+	})
+})
@@ -74,3 +74,11 @@ type WriteFileError struct {
 func (e WriteFileError) Error() string {
 	return fmt.Sprintf("cannot write to file: %s: %s", e.SavedLocation, e.Err)
 }
+
+type UnsupportedArtifactTypeError struct {
+	ContentType string
+}
+
+func (e UnsupportedArtifactTypeError) Error() string {
+	return fmt.Sprintf("unsupported artifact type %q: no extractor is registered for it", e.ContentType)
+}
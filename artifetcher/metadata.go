@@ -0,0 +1,103 @@
+package artifetcher
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/spf13/afero"
+)
+
+// GitPropertiesPaths are where the git.properties file of an artifact is looked for, relative to the extracted
+// artifact. It is written by the git-commit-id plugin of Maven and Gradle builds.
+var GitPropertiesPaths = []string{
+	"git.properties",
+	"BOOT-INF/classes/git.properties",
+	"WEB-INF/classes/git.properties",
+}
+
+// gitCommitKeys are the keys of git.properties that hold the commit, the full SHA first.
+var gitCommitKeys = []string{"git.commit.id.full", "git.commit.id"}
+
+// Metadata returns the metadata of the last artifact that was fetched.
+func (a *Artifetcher) Metadata() S.ArtifactMetadata {
+	return a.metadata
+}
+
+// recordMetadata records the URL, SHA-256 and size of the artifact file and the commit of the artifact, whose files
+// are read with readFile. The deploy does not fail when the artifact cannot be read again, it only misses its
+// checksum and size.
+func (a *Artifetcher) recordMetadata(artifactURL string, artifactFile afero.File, readFile func(name string) ([]byte, error)) {
+	a.metadata = S.ArtifactMetadata{
+		URL:       redactURL(artifactURL),
+		FetchedAt: time.Now().UTC(),
+		GitSHA:    gitSHA(readFile),
+	}
+
+	_, err := artifactFile.Seek(0, io.SeekStart)
+	if err != nil {
+		a.Log.Errorf("cannot record the checksum of the artifact: %s", err)
+		return
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, artifactFile)
+	if err != nil {
+		a.Log.Errorf("cannot record the checksum of the artifact: %s", err)
+		return
+	}
+
+	a.metadata.Checksum = hex.EncodeToString(hash.Sum(nil))
+	a.metadata.SizeBytes = size
+	a.Log.Infof("fetched artifact with sha256 %s and %d bytes", a.metadata.Checksum, size)
+}
+
+// gitSHA returns the commit in the first git.properties of the artifact, or an empty string.
+func gitSHA(readFile func(name string) ([]byte, error)) string {
+	for _, gitPropertiesPath := range GitPropertiesPaths {
+		properties, err := readFile(gitPropertiesPath)
+		if err != nil {
+			continue
+		}
+
+		values := map[string]string{}
+		scanner := bufio.NewScanner(bytes.NewReader(properties))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			separator := strings.IndexAny(line, "=:")
+			if separator < 0 {
+				continue
+			}
+			values[strings.TrimSpace(line[:separator])] = strings.TrimSpace(line[separator+1:])
+		}
+
+		for _, key := range gitCommitKeys {
+			if values[key] != "" {
+				return values[key]
+			}
+		}
+	}
+
+	return ""
+}
+
+// redactURL removes the credentials of an artifact URL.
+func redactURL(artifactURL string) string {
+	parsed, err := url.Parse(artifactURL)
+	if err != nil || parsed.User == nil {
+		return artifactURL
+	}
+
+	parsed.User = nil
+	return parsed.String()
+}
@@ -0,0 +1,75 @@
+package artifetcher
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/spf13/afero"
+)
+
+// Prefixes of the names of the files and directories an Artifetcher stages.
+const (
+	ArtifactFilePrefix      = "deployadactyl-zip-"
+	DownloadFilePrefix      = "deployadactyl-download-"
+	UnzippedDirectoryPrefix = "deployadactyl-unzipped-"
+	StagedDirectoryPrefix   = "deployadactyl-staged-"
+)
+
+// StagingPrefixes are the prefixes of the names of everything an Artifetcher stages.
+var StagingPrefixes = []string{ArtifactFilePrefix, DownloadFilePrefix, UnzippedDirectoryPrefix, StagedDirectoryPrefix}
+
+// removeStaged removes a staged directory of a fetch that did not finish, even when it panicked.
+func (a *Artifetcher) removeStaged(directory string) {
+	err := a.FileSystem.RemoveAll(directory)
+	if err != nil {
+		a.Log.Errorf("cannot remove staging directory %s: %s", directory, err)
+		return
+	}
+	a.Log.Debugf("removed staging directory %s", directory)
+}
+
+// SweepStagingDirectory creates the staging directory if needed and removes the files and directories in it whose
+// name has one of the prefixes and that were last modified more than maxAge ago. They are left behind by deploys that
+// never finished, eg: because Deployadactyl crashed. Nothing is removed when maxAge is zero.
+func SweepStagingDirectory(fs *afero.Afero, directory string, maxAge time.Duration, prefixes []string, log I.Logger) error {
+	err := fs.MkdirAll(directory, 0755)
+	if err != nil {
+		return StagingDirectoryError{directory, err}
+	}
+
+	if maxAge <= 0 {
+		return nil
+	}
+
+	infos, err := fs.ReadDir(directory)
+	if err != nil {
+		return StagingDirectoryError{directory, err}
+	}
+
+	for _, info := range infos {
+		if !hasPrefix(info.Name(), prefixes) || time.Since(info.ModTime()) <= maxAge {
+			continue
+		}
+
+		stale := path.Join(directory, info.Name())
+		err = fs.RemoveAll(stale)
+		if err != nil {
+			log.Errorf("cannot remove stale staging path %s: %s", stale, err)
+			continue
+		}
+		log.Infof("removed stale staging path %s last modified %s", stale, info.ModTime().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
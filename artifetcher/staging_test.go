@@ -0,0 +1,64 @@
+package artifetcher_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+
+	. "github.com/compozed/deployadactyl/artifetcher"
+	"github.com/compozed/deployadactyl/interfaces"
+)
+
+var _ = Describe("SweepStagingDirectory", func() {
+	var (
+		af  *afero.Afero
+		log interfaces.Logger
+	)
+
+	stage := func(name string, age time.Duration) {
+		Expect(af.MkdirAll("/staging/"+name, 0755)).To(Succeed())
+		Expect(af.Chtimes("/staging/"+name, time.Now().Add(-age), time.Now().Add(-age))).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		log = interfaces.DefaultLogger(GinkgoWriter, logging.DEBUG, "staging_test")
+	})
+
+	It("creates the staging directory", func() {
+		Expect(SweepStagingDirectory(af, "/staging", time.Hour, StagingPrefixes, log)).To(Succeed())
+
+		Expect(af.DirExists("/staging")).To(BeTrue())
+	})
+
+	It("removes the stale staged paths", func() {
+		stage(UnzippedDirectoryPrefix+"stale", 2*time.Hour)
+		stage(DownloadFilePrefix+"fresh", time.Minute)
+		stage("deployadactyl-cache", 2*time.Hour)
+
+		Expect(SweepStagingDirectory(af, "/staging", time.Hour, StagingPrefixes, log)).To(Succeed())
+
+		Expect(af.Exists("/staging/" + UnzippedDirectoryPrefix + "stale")).To(BeFalse())
+		Expect(af.Exists("/staging/" + DownloadFilePrefix + "fresh")).To(BeTrue())
+		Expect(af.Exists("/staging/deployadactyl-cache")).To(BeTrue())
+	})
+
+	It("removes nothing when the max age is zero", func() {
+		stage(UnzippedDirectoryPrefix+"stale", 2*time.Hour)
+
+		Expect(SweepStagingDirectory(af, "/staging", 0, StagingPrefixes, log)).To(Succeed())
+
+		Expect(af.Exists("/staging/" + UnzippedDirectoryPrefix + "stale")).To(BeTrue())
+	})
+
+	It("returns an error when the staging directory cannot be created", func() {
+		af = &afero.Afero{Fs: afero.NewReadOnlyFs(afero.NewMemMapFs())}
+
+		err := SweepStagingDirectory(af, "/staging", time.Hour, StagingPrefixes, log)
+
+		Expect(err).To(BeAssignableToTypeOf(StagingDirectoryError{}))
+	})
+})
@@ -0,0 +1,112 @@
+package artifetcher
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/compozed/deployadactyl/artifetcher/extractor"
+	"github.com/spf13/afero"
+)
+
+// StagedArtifactFile is the name of the zip in the directory returned by StageZipFromRequest. It is pushed to Cloud
+// Foundry as is, with the manifest.yml next to it.
+const StagedArtifactFile = "artifact.zip"
+
+// StageZipFromRequest writes the zip in the request body to a staging directory as StagedArtifactFile without
+// extracting it. Only its manifest.yml is extracted, next to it, so the manifest can be changed before the push.
+// It saves writing every file of a large artifact to disk and reading it back for the push.
+//
+// Returns the path of the staging directory, the manifest and an error.
+func (a *Artifetcher) StageZipFromRequest(body io.Reader) (string, string, error) {
+	contentType := extractor.MediaType(a.ContentType)
+	if contentType != extractor.ZipContentType {
+		return "", "", extractor.UnsupportedArtifactTypeError{contentType}
+	}
+
+	stagedPath, err := a.FileSystem.TempDir(a.StagingDirectory, StagedDirectoryPrefix)
+	if err != nil {
+		return "", "", CreateTempDirectoryError{err}
+	}
+
+	staged := false
+	defer func() {
+		if !staged {
+			a.removeStaged(stagedPath)
+		}
+	}()
+
+	zipPath := path.Join(stagedPath, StagedArtifactFile)
+	zipFile, err := a.FileSystem.OpenFile(zipPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", "", CreateTempFileError{err}
+	}
+	defer zipFile.Close()
+
+	a.Log.Infof("staging zip file %s", zipPath)
+
+	_, err = io.Copy(zipFile, body)
+	if err != nil {
+		return "", "", WriteResponseError{err}
+	}
+
+	err = a.checkNotEmpty(zipFile, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	info, err := zipFile.Stat()
+	if err != nil {
+		return "", "", WriteResponseError{err}
+	}
+
+	reader, err := zip.NewReader(zipFile, info.Size())
+	if err != nil {
+		return "", "", UnzipError{extractor.OpenZipError{zipPath, err}}
+	}
+
+	manifest, err := readZipEntry(reader, "manifest.yml")
+	if err != nil {
+		return "", "", UnzipError{extractor.ExtractFileError{"manifest.yml", err}}
+	}
+
+	err = a.FileSystem.WriteFile(path.Join(stagedPath, "manifest.yml"), manifest, 0600)
+	if err != nil {
+		return "", "", UnzipError{extractor.WriteFileError{path.Join(stagedPath, "manifest.yml"), err}}
+	}
+
+	staged = true
+	a.recordMetadata("", zipFile, func(name string) ([]byte, error) {
+		return readZipEntry(reader, name)
+	})
+	a.Log.Debugf("staged zip file without extracting it in tempdir %s", stagedPath)
+	return stagedPath, string(manifest), nil
+}
+
+// readZipEntry returns the contents of the file called name in the zip.
+func readZipEntry(reader *zip.Reader, name string) ([]byte, error) {
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+
+		contents, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer contents.Close()
+
+		return ioutil.ReadAll(contents)
+	}
+
+	return nil, os.ErrNotExist
+}
+
+// readExtractedFile returns a function that reads the files of an artifact extracted to directory.
+func readExtractedFile(fs *afero.Afero, directory string) func(name string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		return fs.ReadFile(path.Join(directory, name))
+	}
+}
@@ -2,8 +2,17 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -11,23 +20,432 @@ import (
 	"github.com/compozed/deployadactyl/controller/deployer/error_finder"
 	"github.com/compozed/deployadactyl/geterrors"
 	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/signing"
 	s "github.com/compozed/deployadactyl/structs"
 )
 
-const defaultConfigPath = "./config.yml"
+const (
+	defaultConfigPath              = "./config.yml"
+	defaultArtifactCacheDirectory  = "deployadactyl-artifact-cache"
+	defaultArtifactCacheMaxSizeMB  = 1024
+	defaultDeploymentLogDirectory  = "deployadactyl-deployment-logs"
+	defaultDeploymentLogMaxFiles   = 100
+	defaultCircuitFailures         = 5
+	defaultCircuitCooldownSeconds  = 60
+	defaultProbeTimeoutSeconds     = 300
+	defaultSilentDeployPoolSize    = 4
+	defaultBatchDeployConcurrency  = 4
+	defaultRequestLogMaxBodyBytes  = 1024
+	defaultEventRetryDirectory     = "deployadactyl-event-retries"
+	defaultDeployJournalDirectory  = "deployadactyl-deploy-journal"
+	defaultEventRetryMaxAttempts   = 10
+	defaultEventRetryBackoff       = 5
+	defaultAppLogLines             = 100
+	defaultAppLogMaxLines          = 5000
+	defaultInstanceQuorumPercent   = 100
+	defaultMinHealthyPercent       = 100
+	defaultLatencyTolerance        = 20
+	defaultInstanceTimeoutSeconds  = 120
+	defaultPostDeployTaskSeconds   = 1800
+	defaultWebhookTimeoutSeconds   = 10
+	defaultDownloadBackoffSeconds  = 2
+	defaultTrafficSplitSoakSeconds = 300
+	defaultKafkaRetries            = 3
+	defaultMaxIdleConns            = 100
+	defaultMaxIdleConnsPerHost     = 10
+	defaultIdleConnTimeoutSeconds  = 90
+	defaultDialTimeoutSeconds      = 30
+	defaultTLSHandshakeSeconds     = 10
+	defaultHTTPRequestSeconds      = 30
+	defaultKafkaRetryIntervalMS    = 200
+	defaultStablePeriodSeconds     = 30
+	defaultAPIURLPrefix            = "api.cf"
+	defaultAppsURLPrefix           = "apps"
+	defaultOTelEndpoint            = "localhost:4318"
+	defaultCFCLIPath               = "cf"
+	defaultStagingDirectory        = "deployadactyl-staging"
+	defaultStagingMaxAgeHours      = 24
+	defaultHistoryPruneMinutes     = 10
+	defaultApprovalPollSeconds     = 10
+	defaultApprovalTimeoutSeconds  = 3600
+
+	// DefaultArtifactUploadMaxSizeMB is the maximum size of the artifact of a multipart push when none is configured.
+	DefaultArtifactUploadMaxSizeMB = 1024
+
+	// DefaultMaxOutputKB is the most Cloud Foundry output held for each foundation when none is configured.
+	DefaultMaxOutputKB = 1024
+
+	// DefaultUUIDMaxLength is the longest UUID a request may send when none is configured.
+	DefaultUUIDMaxLength = 36
+
+	// UUIDFormatDefault accepts UUIDs made of letters, digits and hyphens.
+	UUIDFormatDefault = "default"
+	// UUIDFormatRFC4122 accepts only RFC 4122 UUIDs and generates version 4 UUIDs.
+	UUIDFormatRFC4122 = "rfc4122"
+)
 
 // Config is a representation of a config yaml. It can contain multiple Environments.
 type Config struct {
-	Username      string
-	Password      string
-	Environments  map[string]s.Environment
-	Port          int
-	ErrorMatchers []interfaces.ErrorMatcher
+	Username          string
+	Password          string
+	Environments      map[string]s.Environment
+	Port              int
+	ErrorMatchers     []interfaces.ErrorMatcher
+	ArtifactCache     ArtifactCacheConfig
+	UUID              UUIDConfig
+	DeploymentLog     DeploymentLogConfig
+	HealthCheck       HealthCheckConfig
+	Circuit           CircuitBreakerConfig
+	SilentDeploy      SilentDeployConfig
+	BatchDeploy       BatchDeployConfig
+	TLS               TLSConfig
+	ValidationWebhook ValidationWebhookConfig
+	ApprovalGate      ApprovalGateConfig
+	ErrorOutput       ErrorOutputConfig
+	ArtifactDownload  ArtifactDownloadConfig
+	ArtifactUpload    ArtifactUploadConfig
+	ArtifactHosts     ArtifactHostsConfig
+	LogPrefix         LogPrefixConfig
+	RequestTimeout    RequestTimeoutConfig
+	RequestLog        RequestLogConfig
+	EventRetry        EventRetryConfig
+	DeployRecovery    DeployRecoveryConfig
+	AppLogs           AppLogsConfig
+	Kafka             KafkaConfig
+	HTTPClient        HTTPClientConfig
+	OTel              OTelConfig
+	CFCLI             CFCLIConfig
+	Staging           StagingConfig
+	DeploymentHistory DeploymentHistoryConfig
+	EventSigning      EventSigningConfig
+	Profiles          map[string]Profile
+	AppSpecs          map[string]AppSpec
+	// JSONFields renames the fields of JSON push bodies. It maps a field name, like artifact_url, to the
+	// name clients send instead. The field can still be sent with its own name.
+	JSONFields map[string]string
+	// DefaultEnvironment is used for deploys whose URL does not name an environment.
+	DefaultEnvironment string
+	// APIKeys authenticate clients to Deployadactyl itself. Every request must send one of them when any is set.
+	APIKeys []APIKey
+	// MaxConcurrentDeployments is the most deploys that run on the server at the same time, across every
+	// environment. Deploys are not limited when it is zero. With QueueConcurrentDeployments, a deploy over the limit
+	// waits for a place instead of being rejected.
+	MaxConcurrentDeployments   int
+	QueueConcurrentDeployments bool
+}
+
+// ArtifactCacheConfig configures the on-disk cache of downloaded artifacts.
+type ArtifactCacheConfig struct {
+	Enabled   bool
+	Directory string
+	MaxSizeMB int64 `yaml:"max_size_mb"`
+}
+
+// UUIDConfig controls how deployment UUIDs are assigned and which client supplied UUIDs are accepted.
+type UUIDConfig struct {
+	AlwaysGenerate bool `yaml:"always_generate"`
+	Format         string
+	MaxLength      int `yaml:"max_length"`
+}
+
+// DeploymentLogConfig configures the file each deployment's output is written to.
+// Only the newest MaxFiles files are kept. Files older than MaxAgeHours are removed if it is set.
+type DeploymentLogConfig struct {
+	Enabled     bool
+	Directory   string
+	MaxFiles    int `yaml:"max_files"`
+	MaxAgeHours int `yaml:"max_age_hours"`
+}
+
+// HealthCheckConfig configures how failed health checks are retried.
+// An endpoint that cannot be reached is retried separately from one that responds with an unhealthy status.
+type HealthCheckConfig struct {
+	Unreachable HealthCheckPolicy
+	Unhealthy   HealthCheckPolicy
+}
+
+// HealthCheckPolicy is the number of times a failed health check is retried and the seconds to wait between retries.
+type HealthCheckPolicy struct {
+	Retries              int
+	RetryIntervalSeconds int `yaml:"retry_interval_seconds"`
+}
+
+// CircuitBreakerConfig configures the per environment circuit breaker. After FailureThreshold consecutive
+// Cloud Foundry failures, deploys to the environment are rejected for CooldownSeconds.
+type CircuitBreakerConfig struct {
+	Enabled          bool
+	FailureThreshold int `yaml:"failure_threshold"`
+	CooldownSeconds  int `yaml:"cooldown_seconds"`
+}
+
+// SilentDeployConfig configures the number of silent deploys that run in the background at the same time.
+type SilentDeployConfig struct {
+	PoolSize int `yaml:"pool_size"`
+}
+
+// BatchDeployConfig configures the number of applications of a batch deploy that are deployed at the same time.
+type BatchDeployConfig struct {
+	Concurrency int
+}
+
+// TLSConfig configures TLS on the listener. When ClientCAFile is set, client certificates are verified
+// against it. RequireClientCert rejects connections that do not present a valid client certificate.
+type TLSConfig struct {
+	Enabled           bool
+	CertFile          string `yaml:"cert_file"`
+	KeyFile           string `yaml:"key_file"`
+	ClientCAFile      string `yaml:"client_ca_file"`
+	RequireClientCert bool   `yaml:"require_client_cert"`
+}
+
+// ValidationWebhookConfig configures the webhook that must approve each deploy before it starts.
+// When the webhook does not respond within TimeoutSeconds, or cannot be reached, AllowOnTimeout decides whether the
+// deploy continues.
+type ValidationWebhookConfig struct {
+	URL            string
+	TimeoutSeconds int  `yaml:"timeout_seconds"`
+	AllowOnTimeout bool `yaml:"allow_on_timeout"`
+}
+
+// ApprovalGateConfig configures the approval service that must approve the deploys to environments with
+// RequireApproval. The approval is polled every PollIntervalSeconds until TimeoutSeconds have passed.
+type ApprovalGateConfig struct {
+	URL                 string
+	PollIntervalSeconds int `yaml:"poll_interval_seconds"`
+	TimeoutSeconds      int `yaml:"timeout_seconds"`
+}
+
+// ErrorOutputConfig caps the Cloud Foundry output included in the response of a failed deploy.
+// A zero MaxLines or MaxBytes disables that limit.
+type ErrorOutputConfig struct {
+	MaxLines int `yaml:"max_lines"`
+	MaxBytes int `yaml:"max_bytes"`
+}
+
+// ArtifactDownloadConfig configures retries of artifact downloads that fail with a network error or a 5xx
+// response. The wait between retries starts at BackoffSeconds and doubles after each retry.
+type ArtifactDownloadConfig struct {
+	MaxRetries     int `yaml:"max_retries"`
+	BackoffSeconds int `yaml:"backoff_seconds"`
+}
+
+// ArtifactUploadConfig caps the size of the artifact part of a multipart push.
+type ArtifactUploadConfig struct {
+	MaxSizeMB int64 `yaml:"max_size_mb"`
+}
+
+// LogPrefixConfig controls the prefix of the log lines of a deployment. The prefix has the short form of the
+// deployment UUID and, with IncludeApplication, the environment and application.
+// Disabled removes the prefix, for log pipelines that record the UUID as a field.
+type LogPrefixConfig struct {
+	Disabled           bool
+	IncludeApplication bool `yaml:"include_application"`
+}
+
+// RequestTimeoutConfig caps the total time of any request. A request is not limited when Seconds is 0.
+type RequestTimeoutConfig struct {
+	Seconds int
+}
+
+// RequestLogConfig turns on logging the method, path, headers and the first MaxBodyBytes of the body of each deploy
+// and PUT request, for debugging clients. It is verbose and the bodies can hold secrets, so it is off by default.
+type RequestLogConfig struct {
+	Enabled      bool
+	MaxBodyBytes int `yaml:"max_body_bytes"`
+}
+
+// DeployRecoveryConfig turns on recording the phase of each push in a journal in Directory, so the deploys that were
+// interrupted by a restart are cleaned up when the server starts again. Directory should be on storage that outlives
+// the server.
+type DeployRecoveryConfig struct {
+	Enabled   bool
+	Directory string
+}
+
+// EventRetryConfig turns on keeping deploy success and failure events whose emission failed in Directory and
+// emitting them again in the background. An event is retried up to MaxAttempts times, waiting BackoffSeconds before
+// the first retry and twice as long before each next one. Directory should be on storage that outlives the server.
+type EventRetryConfig struct {
+	Enabled        bool
+	Directory      string
+	MaxAttempts    int `yaml:"max_attempts"`
+	BackoffSeconds int `yaml:"backoff_seconds"`
+}
+
+// AppLogsConfig configures the app logs endpoint. It returns the last Lines lines of the recent logs of an
+// application when the request does not ask for a number, and never more than MaxLines.
+type AppLogsConfig struct {
+	Lines    int
+	MaxLines int `yaml:"max_lines"`
+}
+
+// KafkaConfig configures the Kafka topic deploy events are published to. Events are published when Brokers is set.
+// A failed publish is retried Retries times, waiting RetryIntervalMilliseconds between attempts.
+type KafkaConfig struct {
+	Brokers                   []string `yaml:",flow"`
+	Topic                     string
+	Retries                   int
+	RetryIntervalMilliseconds int `yaml:"retry_interval_milliseconds"`
+}
+
+// EventSigningConfig configures the signing of the deploy records sent to the webhooks and Kafka and kept in the
+// deployment history. Records are signed when Algorithm is set. The Key is read from the EVENT_SIGNING_KEY
+// environment variable, never from the config yaml: the shared key of hmac-sha256, or the base64 encoded seed or
+// private key of ed25519.
+type EventSigningConfig struct {
+	Algorithm string
+	KeyID     string `yaml:"key_id"`
+	Key       []byte `yaml:"-"`
+}
+
+// OTelConfig configures the OTLP collector deploy spans are exported to when the server runs with -otel.
+// Spans are sent over HTTP, with TLS unless Insecure is set.
+type OTelConfig struct {
+	Endpoint string
+	Insecure bool
+}
+
+// CFCLIConfig configures the Cloud Foundry CLI binary Deployadactyl runs. Path is the cf on the PATH by default,
+// and the CF_CLI_PATH environment variable overrides it. Deployadactyl does not start when the binary is older than
+// MinVersion, a version like 6.20.0.
+type CFCLIConfig struct {
+	Path       string
+	MinVersion string `yaml:"min_version"`
+}
+
+// StagingConfig configures the directory artifacts are downloaded and extracted in and the CF_HOME directories of the
+// cf commands are created in. When Deployadactyl starts, what deploys staged there and left behind more than
+// MaxAgeHours ago is removed. A negative MaxAgeHours keeps everything.
+type StagingConfig struct {
+	Directory   string
+	MaxAgeHours int `yaml:"max_age_hours"`
+}
+
+// DeploymentHistoryConfig limits the deploys kept in the deployment history of each application to the newest
+// MaxRecordsPerApp and to the ones started in the last MaxAgeHours. The history is pruned every PruneIntervalMinutes.
+// The newest successful deploy of an application is always kept so it can be rolled back to. A zero limit is not
+// enforced.
+type DeploymentHistoryConfig struct {
+	MaxRecordsPerApp     int `yaml:"max_records_per_app"`
+	MaxAgeHours          int `yaml:"max_age_hours"`
+	PruneIntervalMinutes int `yaml:"prune_interval_minutes"`
+}
+
+// ArtifactHostsConfig limits the hosts JSON pushes may fetch their artifacts from. Any host may be used when Allowed
+// is empty. Hosts in DeniedCIDRs, and link-local hosts unless AllowLinkLocal is set, are never used.
+type ArtifactHostsConfig struct {
+	Allowed        []string `yaml:",flow"`
+	DeniedCIDRs    []string `yaml:"denied_cidrs,flow"`
+	AllowLinkLocal bool     `yaml:"allow_link_local"`
+}
+
+// APIKey is a key clients send in the X-API-Key header to authenticate to Deployadactyl. Hash is the hex encoded
+// SHA-256 of the key, so the key itself is not kept in the config. A key with Environments can only be used for
+// requests to those environments. A key with an Org and Space deploys to them when the request path leaves them out.
+type APIKey struct {
+	Name         string
+	Hash         string
+	Environments []string `yaml:",flow"`
+	Org          string
+	Space        string
+}
+
+// HTTPClientConfig configures the connection pool and timeouts of the HTTP clients the deployer and
+// health checker share.
+type HTTPClientConfig struct {
+	MaxIdleConns               int `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost        int `yaml:"max_idle_conns_per_host"`
+	IdleConnTimeoutSeconds     int `yaml:"idle_conn_timeout_seconds"`
+	DialTimeoutSeconds         int `yaml:"dial_timeout_seconds"`
+	TLSHandshakeTimeoutSeconds int `yaml:"tls_handshake_timeout_seconds"`
+	RequestTimeoutSeconds      int `yaml:"request_timeout_seconds"`
+}
+
+// Profile is a named set of deploy settings that a push applies with its profile field.
+// Settings in the push override the ones in the profile.
+type Profile struct {
+	Instances            uint16
+	Memory               string
+	Stack                string
+	HealthCheckEndpoint  string            `yaml:"health_check_endpoint"`
+	PostDeployTask       string            `yaml:"post_deploy_task"`
+	EnvironmentVariables map[string]string `yaml:"environment_variables"`
+	Labels               map[string]string
+}
+
+// Manifest returns a Cloud Foundry manifest with the instances, memory and stack of the profile.
+// It returns an empty string when the profile sets none of them.
+func (p Profile) Manifest() string {
+	application := map[string]interface{}{}
+	if p.Instances > 0 {
+		application["instances"] = int(p.Instances)
+	}
+	if p.Memory != "" {
+		application["memory"] = p.Memory
+	}
+	if p.Stack != "" {
+		application["stack"] = p.Stack
+	}
+
+	if len(application) == 0 {
+		return ""
+	}
+
+	manifest, err := candiedyaml.Marshal(map[string]interface{}{"applications": []interface{}{application}})
+	if err != nil {
+		return ""
+	}
+
+	return string(manifest)
+}
+
+// AppSpec is the canonical spec of an application that a JSON push can reference with its spec field,
+// so the push only has to set what changed. Manifest is a plain YAML manifest.
+type AppSpec struct {
+	ArtifactURL          string `yaml:"artifact_url"`
+	Manifest             string
+	Instances            uint16
+	EnvironmentVariables map[string]string `yaml:"environment_variables"`
 }
 
 type configYaml struct {
 	Environments       []s.Environment            `yaml:",flow"`
 	MatcherDescriptors []s.ErrorMatcherDescriptor `yaml:"error_matchers,flow"`
+	ArtifactCache      ArtifactCacheConfig        `yaml:"artifact_cache"`
+	UUID               UUIDConfig                 `yaml:"uuid"`
+	DeploymentLog      DeploymentLogConfig        `yaml:"deployment_log"`
+	HealthCheck        HealthCheckConfig          `yaml:"health_check"`
+	Circuit            CircuitBreakerConfig       `yaml:"circuit_breaker"`
+	SilentDeploy       SilentDeployConfig         `yaml:"silent_deploy"`
+	BatchDeploy        BatchDeployConfig          `yaml:"batch_deploy"`
+	TLS                TLSConfig                  `yaml:"tls"`
+	ValidationWebhook  ValidationWebhookConfig    `yaml:"validation_webhook"`
+	ErrorOutput        ErrorOutputConfig          `yaml:"error_output"`
+	ArtifactDownload   ArtifactDownloadConfig     `yaml:"artifact_download"`
+	ArtifactUpload     ArtifactUploadConfig       `yaml:"artifact_upload"`
+	ArtifactHosts      ArtifactHostsConfig        `yaml:"artifact_hosts"`
+	DefaultEnvironment string                     `yaml:"default_environment"`
+	LogPrefix          LogPrefixConfig            `yaml:"log_prefix"`
+	RequestTimeout     RequestTimeoutConfig       `yaml:"request_timeout"`
+	RequestLog         RequestLogConfig           `yaml:"request_log"`
+	EventRetry         EventRetryConfig           `yaml:"event_retry"`
+	DeployRecovery     DeployRecoveryConfig       `yaml:"deploy_recovery"`
+	AppLogs            AppLogsConfig              `yaml:"app_logs"`
+	Kafka              KafkaConfig                `yaml:"kafka"`
+	HTTPClient         HTTPClientConfig           `yaml:"http_client"`
+	OTel               OTelConfig                 `yaml:"otel"`
+	CFCLI              CFCLIConfig                `yaml:"cf_cli"`
+	Staging            StagingConfig              `yaml:"staging"`
+	Profiles           map[string]Profile         `yaml:"profiles"`
+	AppSpecs           map[string]AppSpec         `yaml:"app_specs"`
+	JSONFields         map[string]string          `yaml:"json_fields"`
+	APIKeys            []APIKey                   `yaml:"api_keys"`
+	DeploymentHistory  DeploymentHistoryConfig    `yaml:"deployment_history"`
+	ApprovalGate       ApprovalGateConfig         `yaml:"approval_gate"`
+	EventSigning       EventSigningConfig         `yaml:"event_signing"`
+
+	MaxConcurrentDeployments   int  `yaml:"max_concurrent_deployments"`
+	QueueConcurrentDeployments bool `yaml:"queue_concurrent_deployments"`
 }
 
 type foundationYaml struct {
@@ -56,7 +474,112 @@ func Custom(getenv func(string) string, configPath string) (Config, error) {
 		return Config{}, err
 	}
 
-	return createConfig(getenv, environments, errormatchers)
+	config, err := createConfig(getenv, environments, errormatchers)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.ArtifactCache = getArtifactCacheFromConfig(foundationConfig)
+
+	config.DeploymentLog = getDeploymentLogFromConfig(foundationConfig)
+
+	config.HealthCheck = getHealthCheckFromConfig(foundationConfig)
+
+	config.Circuit = getCircuitBreakerFromConfig(foundationConfig)
+
+	config.SilentDeploy = getSilentDeployFromConfig(foundationConfig)
+
+	config.BatchDeploy = getBatchDeployFromConfig(foundationConfig)
+
+	config.ValidationWebhook = getValidationWebhookFromConfig(foundationConfig)
+
+	config.ApprovalGate, err = getApprovalGateFromConfig(foundationConfig, environments)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.ErrorOutput = getErrorOutputFromConfig(foundationConfig)
+
+	config.ArtifactDownload = getArtifactDownloadFromConfig(foundationConfig)
+
+	config.ArtifactUpload = getArtifactUploadFromConfig(foundationConfig)
+
+	config.LogPrefix = foundationConfig.LogPrefix
+
+	config.RequestTimeout = getRequestTimeoutFromConfig(foundationConfig)
+
+	config.RequestLog = getRequestLogFromConfig(foundationConfig)
+
+	config.EventRetry = getEventRetryFromConfig(foundationConfig)
+
+	config.DeployRecovery = getDeployRecoveryFromConfig(foundationConfig)
+
+	config.AppLogs = getAppLogsFromConfig(foundationConfig)
+
+	config.HTTPClient = getHTTPClientFromConfig(foundationConfig)
+
+	config.OTel = getOTelFromConfig(foundationConfig)
+
+	config.Staging = getStagingFromConfig(foundationConfig)
+
+	config.DeploymentHistory = getDeploymentHistoryFromConfig(foundationConfig)
+
+	config.Profiles = foundationConfig.Profiles
+
+	config.AppSpecs = foundationConfig.AppSpecs
+
+	config.DefaultEnvironment, err = getDefaultEnvironmentFromConfig(foundationConfig, environments)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if foundationConfig.MaxConcurrentDeployments < 0 {
+		return Config{}, InvalidMaxConcurrentDeploymentsError{foundationConfig.MaxConcurrentDeployments}
+	}
+	config.MaxConcurrentDeployments = foundationConfig.MaxConcurrentDeployments
+	config.QueueConcurrentDeployments = foundationConfig.QueueConcurrentDeployments
+
+	config.UUID, err = getUUIDFromConfig(foundationConfig)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.TLS, err = getTLSFromConfig(foundationConfig)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.CFCLI, err = getCFCLIFromConfig(getenv, foundationConfig)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Kafka, err = getKafkaFromConfig(foundationConfig)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.ArtifactHosts, err = getArtifactHostsFromConfig(foundationConfig)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.JSONFields, err = getJSONFieldsFromConfig(foundationConfig)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.APIKeys, err = getAPIKeysFromConfig(foundationConfig, environments)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.EventSigning, err = getEventSigningFromConfig(getenv, foundationConfig)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
 }
 
 func createConfig(getenv func(string) string, environments map[string]s.Environment, errormatchers []interfaces.ErrorMatcher) (Config, error) {
@@ -74,6 +597,13 @@ func createConfig(getenv func(string) string, environments map[string]s.Environm
 		return Config{}, err
 	}
 
+	for key, environment := range environments {
+		name := authFallbackVariableName(environment.Name)
+		environment.FallbackUsername = getenv("CF_USERNAME_" + name)
+		environment.FallbackPassword = getenv("CF_PASSWORD_" + name)
+		environments[key] = environment
+	}
+
 	config := Config{
 		Username:      username,
 		Password:      password,
@@ -84,6 +614,42 @@ func createConfig(getenv func(string) string, environments map[string]s.Environm
 	return config, nil
 }
 
+// nonAlphanumeric matches the characters of an environment name replaced in its auth fallback variable names.
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// authFallbackVariableName is the suffix of the environment variables holding the env auth fallback credentials of an
+// environment, eg: CF_USERNAME_PRE_PROD for pre-prod.
+func authFallbackVariableName(environment string) string {
+	return strings.ToUpper(nonAlphanumeric.ReplaceAllString(environment, "_"))
+}
+
+// FallbackAuthorization finds the credentials for a request to an environment without basic auth, trying the sources
+// in its auth fallback order. It returns false when the request is denied.
+func (c Config) FallbackAuthorization(environment s.Environment) (username, password string, ok bool) {
+	sources := environment.AuthFallback
+	if len(sources) == 0 {
+		if environment.Authenticate {
+			return "", "", false
+		}
+		sources = []string{s.AuthFallbackConfig}
+	}
+
+	for _, source := range sources {
+		switch source {
+		case s.AuthFallbackConfig:
+			return c.Username, c.Password, true
+		case s.AuthFallbackEnv:
+			if environment.FallbackUsername != "" && environment.FallbackPassword != "" {
+				return environment.FallbackUsername, environment.FallbackPassword, true
+			}
+		case s.AuthFallbackDeny:
+			return "", "", false
+		}
+	}
+
+	return "", "", false
+}
+
 func getPortFromEnv(getenv func(string) string) (int, error) {
 	envPort := getenv("PORT")
 	if envPort == "" {
@@ -114,6 +680,568 @@ func getErrorMatchersFromConfig(foundationConfig configYaml) []interfaces.ErrorM
 	return matchers
 }
 
+func getArtifactCacheFromConfig(foundationConfig configYaml) ArtifactCacheConfig {
+	artifactCache := foundationConfig.ArtifactCache
+
+	if artifactCache.Directory == "" {
+		artifactCache.Directory = filepath.Join(os.TempDir(), defaultArtifactCacheDirectory)
+	}
+
+	if artifactCache.MaxSizeMB < 1 {
+		artifactCache.MaxSizeMB = defaultArtifactCacheMaxSizeMB
+	}
+
+	return artifactCache
+}
+
+func getDeploymentLogFromConfig(foundationConfig configYaml) DeploymentLogConfig {
+	deploymentLog := foundationConfig.DeploymentLog
+
+	if deploymentLog.Directory == "" {
+		deploymentLog.Directory = filepath.Join(os.TempDir(), defaultDeploymentLogDirectory)
+	}
+
+	if deploymentLog.MaxFiles < 1 {
+		deploymentLog.MaxFiles = defaultDeploymentLogMaxFiles
+	}
+
+	return deploymentLog
+}
+
+func getHealthCheckFromConfig(foundationConfig configYaml) HealthCheckConfig {
+	healthCheck := foundationConfig.HealthCheck
+
+	for _, policy := range []*HealthCheckPolicy{&healthCheck.Unreachable, &healthCheck.Unhealthy} {
+		if policy.Retries < 0 {
+			policy.Retries = 0
+		}
+		if policy.RetryIntervalSeconds < 0 {
+			policy.RetryIntervalSeconds = 0
+		}
+	}
+
+	return healthCheck
+}
+
+func getCircuitBreakerFromConfig(foundationConfig configYaml) CircuitBreakerConfig {
+	circuit := foundationConfig.Circuit
+
+	if circuit.FailureThreshold < 1 {
+		circuit.FailureThreshold = defaultCircuitFailures
+	}
+
+	if circuit.CooldownSeconds < 1 {
+		circuit.CooldownSeconds = defaultCircuitCooldownSeconds
+	}
+
+	return circuit
+}
+
+func getSilentDeployFromConfig(foundationConfig configYaml) SilentDeployConfig {
+	silentDeploy := foundationConfig.SilentDeploy
+
+	if silentDeploy.PoolSize < 1 {
+		silentDeploy.PoolSize = defaultSilentDeployPoolSize
+	}
+
+	return silentDeploy
+}
+
+func getBatchDeployFromConfig(foundationConfig configYaml) BatchDeployConfig {
+	batchDeploy := foundationConfig.BatchDeploy
+
+	if batchDeploy.Concurrency < 1 {
+		batchDeploy.Concurrency = defaultBatchDeployConcurrency
+	}
+
+	return batchDeploy
+}
+
+func getValidationWebhookFromConfig(foundationConfig configYaml) ValidationWebhookConfig {
+	webhook := foundationConfig.ValidationWebhook
+
+	if webhook.TimeoutSeconds < 1 {
+		webhook.TimeoutSeconds = defaultWebhookTimeoutSeconds
+	}
+
+	return webhook
+}
+
+// getApprovalGateFromConfig checks that the approval gate is configured when an environment requires approval.
+func getApprovalGateFromConfig(foundationConfig configYaml, environments map[string]s.Environment) (ApprovalGateConfig, error) {
+	gate := foundationConfig.ApprovalGate
+
+	if gate.URL == "" {
+		for _, environment := range environments {
+			if environment.RequireApproval {
+				return ApprovalGateConfig{}, ApprovalGateRequiredError{environment.Name}
+			}
+		}
+	}
+
+	if gate.PollIntervalSeconds < 1 {
+		gate.PollIntervalSeconds = defaultApprovalPollSeconds
+	}
+
+	if gate.TimeoutSeconds < 1 {
+		gate.TimeoutSeconds = defaultApprovalTimeoutSeconds
+	}
+
+	return gate, nil
+}
+
+func getErrorOutputFromConfig(foundationConfig configYaml) ErrorOutputConfig {
+	errorOutput := foundationConfig.ErrorOutput
+
+	if errorOutput.MaxLines < 0 {
+		errorOutput.MaxLines = 0
+	}
+
+	if errorOutput.MaxBytes < 0 {
+		errorOutput.MaxBytes = 0
+	}
+
+	return errorOutput
+}
+
+func getArtifactDownloadFromConfig(foundationConfig configYaml) ArtifactDownloadConfig {
+	artifactDownload := foundationConfig.ArtifactDownload
+
+	if artifactDownload.MaxRetries < 0 {
+		artifactDownload.MaxRetries = 0
+	}
+
+	if artifactDownload.BackoffSeconds < 1 {
+		artifactDownload.BackoffSeconds = defaultDownloadBackoffSeconds
+	}
+
+	return artifactDownload
+}
+
+func getArtifactUploadFromConfig(foundationConfig configYaml) ArtifactUploadConfig {
+	artifactUpload := foundationConfig.ArtifactUpload
+
+	if artifactUpload.MaxSizeMB < 1 {
+		artifactUpload.MaxSizeMB = DefaultArtifactUploadMaxSizeMB
+	}
+
+	return artifactUpload
+}
+
+func getStagingFromConfig(foundationConfig configYaml) StagingConfig {
+	staging := foundationConfig.Staging
+
+	if staging.Directory == "" {
+		staging.Directory = filepath.Join(os.TempDir(), defaultStagingDirectory)
+	}
+
+	if staging.MaxAgeHours == 0 {
+		staging.MaxAgeHours = defaultStagingMaxAgeHours
+	}
+
+	return staging
+}
+
+func getDeploymentHistoryFromConfig(foundationConfig configYaml) DeploymentHistoryConfig {
+	history := foundationConfig.DeploymentHistory
+
+	if history.MaxRecordsPerApp < 0 {
+		history.MaxRecordsPerApp = 0
+	}
+
+	if history.MaxAgeHours < 0 {
+		history.MaxAgeHours = 0
+	}
+
+	if history.PruneIntervalMinutes < 1 {
+		history.PruneIntervalMinutes = defaultHistoryPruneMinutes
+	}
+
+	return history
+}
+
+func getRequestLogFromConfig(foundationConfig configYaml) RequestLogConfig {
+	requestLog := foundationConfig.RequestLog
+
+	if requestLog.MaxBodyBytes < 1 {
+		requestLog.MaxBodyBytes = defaultRequestLogMaxBodyBytes
+	}
+
+	return requestLog
+}
+
+func getEventRetryFromConfig(foundationConfig configYaml) EventRetryConfig {
+	eventRetry := foundationConfig.EventRetry
+
+	if eventRetry.Directory == "" {
+		eventRetry.Directory = filepath.Join(os.TempDir(), defaultEventRetryDirectory)
+	}
+
+	if eventRetry.MaxAttempts < 1 {
+		eventRetry.MaxAttempts = defaultEventRetryMaxAttempts
+	}
+
+	if eventRetry.BackoffSeconds < 1 {
+		eventRetry.BackoffSeconds = defaultEventRetryBackoff
+	}
+
+	return eventRetry
+}
+
+func getDeployRecoveryFromConfig(foundationConfig configYaml) DeployRecoveryConfig {
+	deployRecovery := foundationConfig.DeployRecovery
+
+	if deployRecovery.Directory == "" {
+		deployRecovery.Directory = filepath.Join(os.TempDir(), defaultDeployJournalDirectory)
+	}
+
+	return deployRecovery
+}
+
+func getAppLogsFromConfig(foundationConfig configYaml) AppLogsConfig {
+	appLogs := foundationConfig.AppLogs
+
+	if appLogs.MaxLines < 1 {
+		appLogs.MaxLines = defaultAppLogMaxLines
+	}
+
+	if appLogs.Lines < 1 {
+		appLogs.Lines = defaultAppLogLines
+	}
+
+	if appLogs.Lines > appLogs.MaxLines {
+		appLogs.Lines = appLogs.MaxLines
+	}
+
+	return appLogs
+}
+
+func getRequestTimeoutFromConfig(foundationConfig configYaml) RequestTimeoutConfig {
+	requestTimeout := foundationConfig.RequestTimeout
+
+	if requestTimeout.Seconds < 0 {
+		requestTimeout.Seconds = 0
+	}
+
+	return requestTimeout
+}
+
+func getOTelFromConfig(foundationConfig configYaml) OTelConfig {
+	otel := foundationConfig.OTel
+
+	if otel.Endpoint == "" {
+		otel.Endpoint = defaultOTelEndpoint
+	}
+
+	return otel
+}
+
+func getHTTPClientFromConfig(foundationConfig configYaml) HTTPClientConfig {
+	httpClient := foundationConfig.HTTPClient
+
+	if httpClient.MaxIdleConns < 1 {
+		httpClient.MaxIdleConns = defaultMaxIdleConns
+	}
+
+	if httpClient.MaxIdleConnsPerHost < 1 {
+		httpClient.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+
+	if httpClient.IdleConnTimeoutSeconds < 1 {
+		httpClient.IdleConnTimeoutSeconds = defaultIdleConnTimeoutSeconds
+	}
+
+	if httpClient.DialTimeoutSeconds < 1 {
+		httpClient.DialTimeoutSeconds = defaultDialTimeoutSeconds
+	}
+
+	if httpClient.TLSHandshakeTimeoutSeconds < 1 {
+		httpClient.TLSHandshakeTimeoutSeconds = defaultTLSHandshakeSeconds
+	}
+
+	if httpClient.RequestTimeoutSeconds < 1 {
+		httpClient.RequestTimeoutSeconds = defaultHTTPRequestSeconds
+	}
+
+	return httpClient
+}
+
+func getDefaultEnvironmentFromConfig(foundationConfig configYaml, environments map[string]s.Environment) (string, error) {
+	name := strings.ToLower(foundationConfig.DefaultEnvironment)
+	if name == "" {
+		return "", nil
+	}
+
+	if _, ok := environments[name]; !ok {
+		return "", DefaultEnvironmentNotFoundError{foundationConfig.DefaultEnvironment}
+	}
+
+	return name, nil
+}
+
+func getTLSFromConfig(foundationConfig configYaml) (TLSConfig, error) {
+	tls := foundationConfig.TLS
+
+	if !tls.Enabled {
+		if tls.ClientCAFile != "" || tls.RequireClientCert {
+			return TLSConfig{}, InvalidTLSConfigError{"client certificates require tls to be enabled"}
+		}
+		return tls, nil
+	}
+
+	if tls.CertFile == "" || tls.KeyFile == "" {
+		return TLSConfig{}, InvalidTLSConfigError{"cert_file and key_file are required"}
+	}
+
+	if tls.RequireClientCert && tls.ClientCAFile == "" {
+		return TLSConfig{}, InvalidTLSConfigError{"client_ca_file is required to verify client certificates"}
+	}
+
+	return tls, nil
+}
+
+func getArtifactHostsFromConfig(foundationConfig configYaml) (ArtifactHostsConfig, error) {
+	for _, cidr := range foundationConfig.ArtifactHosts.DeniedCIDRs {
+		_, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return ArtifactHostsConfig{}, InvalidDeniedCIDRError{cidr}
+		}
+	}
+
+	return foundationConfig.ArtifactHosts, nil
+}
+
+// getAPIKeysFromConfig checks that each API key has a unique name, a SHA-256 hash, only names configured
+// environments and either both or neither of a default org and space. Hashes and environment names are lowercased.
+func getAPIKeysFromConfig(foundationConfig configYaml, environments map[string]s.Environment) ([]APIKey, error) {
+	names := map[string]bool{}
+	apiKeys := make([]APIKey, 0, len(foundationConfig.APIKeys))
+
+	for _, apiKey := range foundationConfig.APIKeys {
+		if apiKey.Name == "" {
+			return nil, InvalidAPIKeyError{apiKey.Name, "a name is required"}
+		}
+		if names[apiKey.Name] {
+			return nil, InvalidAPIKeyError{apiKey.Name, "the name is used by another key"}
+		}
+		names[apiKey.Name] = true
+
+		apiKey.Hash = strings.ToLower(apiKey.Hash)
+		if hash, err := hex.DecodeString(apiKey.Hash); err != nil || len(hash) != sha256.Size {
+			return nil, InvalidAPIKeyError{apiKey.Name, "the hash must be a hex encoded sha256"}
+		}
+
+		scopes := make([]string, 0, len(apiKey.Environments))
+		for _, environment := range apiKey.Environments {
+			if _, ok := environments[strings.ToLower(environment)]; !ok {
+				return nil, InvalidAPIKeyError{apiKey.Name, fmt.Sprintf("environment %s is not configured", environment)}
+			}
+			scopes = append(scopes, strings.ToLower(environment))
+		}
+		apiKey.Environments = scopes
+
+		if (apiKey.Org == "") != (apiKey.Space == "") {
+			return nil, InvalidAPIKeyError{apiKey.Name, "a default org and space must be set together"}
+		}
+
+		if apiKey.Org != "" {
+			err := checkAPIKeyOrgAndSpace(apiKey, environments)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, nil
+}
+
+// checkAPIKeyOrgAndSpace returns an InvalidAPIKeyError when the default org or space of an API key is not allowed
+// in an environment the key can be used for: its environments, or every environment when it has none.
+func checkAPIKeyOrgAndSpace(apiKey APIKey, environments map[string]s.Environment) error {
+	names := apiKey.Environments
+	if len(names) == 0 {
+		for name := range environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		environment := environments[name]
+		if !isAllowed(apiKey.Org, environment.AllowedOrgs) {
+			return InvalidAPIKeyError{apiKey.Name, fmt.Sprintf("default org %s is not allowed in environment %s", apiKey.Org, name)}
+		}
+		if !isAllowed(apiKey.Space, environment.AllowedSpaces) {
+			return InvalidAPIKeyError{apiKey.Name, fmt.Sprintf("default space %s is not allowed in environment %s", apiKey.Space, name)}
+		}
+	}
+
+	return nil
+}
+
+// isAllowed reports whether value is one of allowed, or allowed is empty.
+func isAllowed(value string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getJSONFieldsFromConfig checks that only fields of a JSON push are renamed, and that no two fields are sent
+// with the same name.
+func getJSONFieldsFromConfig(foundationConfig configYaml) (map[string]string, error) {
+	fields := map[string]bool{}
+	for _, field := range jsonFieldNames() {
+		fields[field] = true
+	}
+
+	renamed := make([]string, 0, len(foundationConfig.JSONFields))
+	for field := range foundationConfig.JSONFields {
+		renamed = append(renamed, field)
+	}
+	sort.Strings(renamed)
+
+	names := map[string]string{}
+	for _, field := range renamed {
+		name := foundationConfig.JSONFields[field]
+		if !fields[field] {
+			return nil, InvalidJSONFieldsError{fmt.Sprintf("%s is not a field of a push", field)}
+		}
+		if name == "" {
+			return nil, InvalidJSONFieldsError{fmt.Sprintf("%s has no name", field)}
+		}
+		if fields[name] && name != field {
+			return nil, InvalidJSONFieldsError{fmt.Sprintf("%s cannot be renamed to %s, which is the name of another field", field, name)}
+		}
+		if other, ok := names[name]; ok {
+			return nil, InvalidJSONFieldsError{fmt.Sprintf("%s and %s are both renamed to %s", other, field, name)}
+		}
+		names[name] = field
+	}
+
+	return foundationConfig.JSONFields, nil
+}
+
+// jsonFieldNames returns the names of the fields of a JSON push.
+func jsonFieldNames() []string {
+	var names []string
+
+	deploymentInfo := reflect.TypeOf(s.DeploymentInfo{})
+	for i := 0; i < deploymentInfo.NumField(); i++ {
+		name := strings.Split(deploymentInfo.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func getKafkaFromConfig(foundationConfig configYaml) (KafkaConfig, error) {
+	kafka := foundationConfig.Kafka
+
+	if len(kafka.Brokers) == 0 {
+		return kafka, nil
+	}
+
+	if kafka.Topic == "" {
+		return KafkaConfig{}, InvalidKafkaConfigError{"topic is required"}
+	}
+
+	if kafka.Retries < 1 {
+		kafka.Retries = defaultKafkaRetries
+	}
+
+	if kafka.RetryIntervalMilliseconds < 1 {
+		kafka.RetryIntervalMilliseconds = defaultKafkaRetryIntervalMS
+	}
+
+	return kafka, nil
+}
+
+// cliVersionPattern matches the minimum version of the Cloud Foundry CLI.
+var cliVersionPattern = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+func getCFCLIFromConfig(getenv func(string) string, foundationConfig configYaml) (CFCLIConfig, error) {
+	cli := foundationConfig.CFCLI
+
+	if path := getenv("CF_CLI_PATH"); path != "" {
+		cli.Path = path
+	}
+
+	if cli.Path == "" {
+		cli.Path = defaultCFCLIPath
+	}
+
+	if cli.MinVersion != "" && !cliVersionPattern.MatchString(cli.MinVersion) {
+		return CFCLIConfig{}, InvalidCFCLIConfigError{fmt.Sprintf("min_version %q must be a version like 6.20.0", cli.MinVersion)}
+	}
+
+	return cli, nil
+}
+
+// getEventSigningFromConfig reads the signing key of the deploy records from the EVENT_SIGNING_KEY environment
+// variable and checks that it can sign with it. The key is never part of an error.
+func getEventSigningFromConfig(getenv func(string) string, foundationConfig configYaml) (EventSigningConfig, error) {
+	eventSigning := foundationConfig.EventSigning
+	switch eventSigning.Algorithm {
+	case "":
+		return EventSigningConfig{}, nil
+	case signing.HMACSHA256, signing.Ed25519:
+	default:
+		return EventSigningConfig{}, InvalidEventSigningError{signing.UnknownAlgorithmError{eventSigning.Algorithm}.Error()}
+	}
+
+	key := getenv("EVENT_SIGNING_KEY")
+	if key == "" {
+		return EventSigningConfig{}, InvalidEventSigningError{"the EVENT_SIGNING_KEY environment variable is required"}
+	}
+
+	eventSigning.Key = []byte(key)
+	if eventSigning.Algorithm == signing.Ed25519 {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return EventSigningConfig{}, InvalidEventSigningError{"the EVENT_SIGNING_KEY of ed25519 must be base64 encoded"}
+		}
+		eventSigning.Key = decoded
+	}
+
+	_, err := signing.NewSigner(eventSigning.Algorithm, eventSigning.KeyID, eventSigning.Key)
+	if err != nil {
+		return EventSigningConfig{}, InvalidEventSigningError{err.Error()}
+	}
+
+	return eventSigning, nil
+}
+
+func getUUIDFromConfig(foundationConfig configYaml) (UUIDConfig, error) {
+	uuid := foundationConfig.UUID
+
+	switch strings.ToLower(uuid.Format) {
+	case "", UUIDFormatDefault:
+		uuid.Format = UUIDFormatDefault
+	case UUIDFormatRFC4122:
+		uuid.Format = UUIDFormatRFC4122
+	default:
+		return UUIDConfig{}, InvalidUUIDFormatError{uuid.Format}
+	}
+
+	if uuid.MaxLength < 1 {
+		uuid.MaxLength = DefaultUUIDMaxLength
+	}
+
+	return uuid, nil
+}
+
 func getEnvironmentsFromConfig(foundationConfig configYaml) (map[string]s.Environment, error) {
 
 	if foundationConfig.Environments == nil || len(foundationConfig.Environments) == 0 {
@@ -130,12 +1258,194 @@ func getEnvironmentsFromConfig(foundationConfig configYaml) (map[string]s.Enviro
 			environment.Instances = 1
 		}
 
-		environments[strings.ToLower(environment.Name)] = environment
+		if environment.ProbeTimeoutSeconds < 1 {
+			environment.ProbeTimeoutSeconds = defaultProbeTimeoutSeconds
+		}
+
+		if environment.InstanceQuorumPercent < 1 || environment.InstanceQuorumPercent > 100 {
+			environment.InstanceQuorumPercent = defaultInstanceQuorumPercent
+		}
+
+		if environment.InstanceTimeoutSeconds < 1 {
+			environment.InstanceTimeoutSeconds = defaultInstanceTimeoutSeconds
+		}
+
+		if environment.PostDeployTaskTimeoutSeconds < 1 {
+			environment.PostDeployTaskTimeoutSeconds = defaultPostDeployTaskSeconds
+		}
+
+		if environment.MaxOutputKB < 1 {
+			environment.MaxOutputKB = DefaultMaxOutputKB
+		}
+
+		switch environment.HealthCheckMode {
+		case "":
+			environment.HealthCheckMode = s.HealthCheckEnforce
+		case s.HealthCheckEnforce, s.HealthCheckWarn, s.HealthCheckOff:
+		default:
+			return nil, InvalidHealthCheckModeError{environment.Name, environment.HealthCheckMode}
+		}
+
+		if environment.MinHealthyPercent < 1 || environment.MinHealthyPercent > 100 {
+			environment.MinHealthyPercent = defaultMinHealthyPercent
+		}
+
+		switch environment.HealthCheckCompare {
+		case "":
+			environment.HealthCheckCompare = s.HealthCheckCompareOff
+		case s.HealthCheckCompareOff, s.HealthCheckCompareHealthy, s.HealthCheckCompareLatency:
+		default:
+			return nil, InvalidHealthCheckCompareError{environment.Name, environment.HealthCheckCompare}
+		}
+
+		if environment.HealthCheckLatencyTolerancePercent < 1 {
+			environment.HealthCheckLatencyTolerancePercent = defaultLatencyTolerance
+		}
+
+		if environment.HealthCheckStablePolls > 1 && environment.HealthCheckStableSeconds < 1 {
+			environment.HealthCheckStableSeconds = defaultStablePeriodSeconds
+		}
+
+		switch environment.RouteConflictPolicy {
+		case "":
+			environment.RouteConflictPolicy = s.RouteConflictFail
+		case s.RouteConflictFail, s.RouteConflictSteal, s.RouteConflictSkip:
+		default:
+			return nil, InvalidRouteConflictPolicyError{environment.Name, environment.RouteConflictPolicy}
+		}
+
+		switch environment.OverrideConflictPolicy {
+		case "":
+			environment.OverrideConflictPolicy = s.OverrideConflictWarn
+		case s.OverrideConflictSilent, s.OverrideConflictWarn, s.OverrideConflictReject:
+		default:
+			return nil, InvalidOverrideConflictPolicyError{environment.Name, environment.OverrideConflictPolicy}
+		}
+
+		for _, source := range environment.AuthFallback {
+			switch source {
+			case s.AuthFallbackConfig, s.AuthFallbackEnv, s.AuthFallbackDeny:
+			default:
+				return nil, InvalidAuthFallbackError{environment.Name, source}
+			}
+		}
+
+		for _, handler := range environment.EventHandlers {
+			switch handler {
+			case s.EventHandlerHealthCheck, s.EventHandlerEnvVar, s.EventHandlerRouteMapper:
+			default:
+				return nil, InvalidEventHandlerError{environment.Name, handler}
+			}
+		}
+
+		for _, flag := range environment.PushFlags {
+			if _, _, ok := s.ParsePushFlag(flag); !ok {
+				return nil, InvalidPushFlagError{environment.Name, "push flag", flag}
+			}
+		}
+
+		for _, flag := range environment.AllowedPushFlags {
+			if !s.IsPassthroughPushFlag(flag) {
+				return nil, InvalidPushFlagError{environment.Name, "allowed push flag", flag}
+			}
+		}
+
+		sizes := []struct{ key, size string }{
+			{"max_memory_per_instance", environment.MaxMemoryPerInstance},
+			{"max_disk_per_instance", environment.MaxDiskPerInstance},
+		}
+		for _, limit := range sizes {
+			if _, ok := s.ParseMegabytes(limit.size); limit.size != "" && !ok {
+				return nil, InvalidSizeError{environment.Name, limit.key, limit.size}
+			}
+		}
+
+		err := setURLPrefixDefaults(&environment)
+		if err != nil {
+			return nil, err
+		}
+
+		// Any environment can be traffic split by a push that chooses the traffic-split strategy.
+		err = setTrafficSplitDefaults(&environment)
+		if err != nil {
+			return nil, err
+		}
+
+		defaultManifest, err := readDefaultManifest(environment.DefaultManifest)
+		if err != nil {
+			return nil, DefaultManifestError{environment.Name, err}
+		}
+		environment.DefaultManifest = defaultManifest
+
+		name := strings.ToLower(environment.Name)
+		if _, ok := environments[name]; ok {
+			return nil, DuplicateEnvironmentError{environment.Name}
+		}
+		environments[name] = environment
 	}
 
 	return environments, nil
 }
 
+// urlPrefixPattern matches the host name labels an API or apps URL prefix is made of, eg: api.cf or apps.
+var urlPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+// setURLPrefixDefaults defaults the API and apps URL prefixes of an environment and checks that they are host names.
+func setURLPrefixDefaults(environment *s.Environment) error {
+	if environment.APIURLPrefix == "" {
+		environment.APIURLPrefix = defaultAPIURLPrefix
+	}
+	if environment.AppsURLPrefix == "" {
+		environment.AppsURLPrefix = defaultAppsURLPrefix
+	}
+
+	if !urlPrefixPattern.MatchString(environment.APIURLPrefix) {
+		return InvalidURLPrefixError{environment.Name, "api_url_prefix", environment.APIURLPrefix}
+	}
+	if !urlPrefixPattern.MatchString(environment.AppsURLPrefix) {
+		return InvalidURLPrefixError{environment.Name, "apps_url_prefix", environment.AppsURLPrefix}
+	}
+
+	return nil
+}
+
+// setTrafficSplitDefaults defaults the weights and soak time of traffic splits in an environment.
+// The weights must increase and each must leave some traffic on the existing application.
+func setTrafficSplitDefaults(environment *s.Environment) error {
+	if len(environment.TrafficSplitWeights) == 0 {
+		environment.TrafficSplitWeights = []int{10, 50}
+	}
+
+	previous := 0
+	for _, weight := range environment.TrafficSplitWeights {
+		if weight <= previous || weight >= 100 {
+			return InvalidTrafficSplitWeightsError{environment.Name, environment.TrafficSplitWeights}
+		}
+		previous = weight
+	}
+
+	if environment.TrafficSplitSoakSeconds < 1 {
+		environment.TrafficSplitSoakSeconds = defaultTrafficSplitSoakSeconds
+	}
+
+	return nil
+}
+
+// readDefaultManifest returns the default manifest of an environment. A single line value is
+// treated as the path to a manifest file, anything longer is treated as inline YAML.
+func readDefaultManifest(defaultManifest string) (string, error) {
+	if defaultManifest == "" || strings.Contains(defaultManifest, "\n") {
+		return defaultManifest, nil
+	}
+
+	manifest, err := ioutil.ReadFile(defaultManifest)
+	if err != nil {
+		return "", err
+	}
+
+	return string(manifest), nil
+}
+
 func parseConfig(configPath string) (configYaml, error) {
 	file, err := ioutil.ReadFile(configPath)
 	if err != nil {
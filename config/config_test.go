@@ -1,8 +1,12 @@
 package config_test
 
 import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -12,6 +16,7 @@ import (
 
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/signing"
 )
 
 const (
@@ -95,20 +100,50 @@ var _ = Describe("Config", func() {
 
 		envMap = map[string]S.Environment{
 			"test": {
-				Name:         "Test",
-				Foundations:  []string{"api1.example.com", "api2.example.com"},
-				Domain:       "test.example.com",
-				SkipSSL:      true,
-				Instances:    3,
-				CustomParams: testCustomParams,
+				Name:                               "Test",
+				Foundations:                        []string{"api1.example.com", "api2.example.com"},
+				Domain:                             "test.example.com",
+				SkipSSL:                            true,
+				Instances:                          3,
+				CustomParams:                       testCustomParams,
+				ProbeTimeoutSeconds:                300,
+				InstanceQuorumPercent:              100,
+				InstanceTimeoutSeconds:             120,
+				PostDeployTaskTimeoutSeconds:       1800,
+				MaxOutputKB:                        1024,
+				MinHealthyPercent:                  100,
+				HealthCheckMode:                    S.HealthCheckEnforce,
+				HealthCheckCompare:                 S.HealthCheckCompareOff,
+				HealthCheckLatencyTolerancePercent: 20,
+				RouteConflictPolicy:                S.RouteConflictFail,
+				OverrideConflictPolicy:             S.OverrideConflictWarn,
+				APIURLPrefix:                       "api.cf",
+				AppsURLPrefix:                      "apps",
+				TrafficSplitWeights:                []int{10, 50},
+				TrafficSplitSoakSeconds:            300,
 			},
 			"prod": {
-				Name:         "Prod",
-				Foundations:  []string{"api3.example.com", "api4.example.com"},
-				Domain:       "example.com",
-				SkipSSL:      false,
-				Instances:    1,
-				CustomParams: prodCustomParams,
+				Name:                               "Prod",
+				Foundations:                        []string{"api3.example.com", "api4.example.com"},
+				Domain:                             "example.com",
+				SkipSSL:                            false,
+				Instances:                          1,
+				CustomParams:                       prodCustomParams,
+				ProbeTimeoutSeconds:                300,
+				InstanceQuorumPercent:              100,
+				InstanceTimeoutSeconds:             120,
+				PostDeployTaskTimeoutSeconds:       1800,
+				MaxOutputKB:                        1024,
+				MinHealthyPercent:                  100,
+				HealthCheckMode:                    S.HealthCheckEnforce,
+				HealthCheckCompare:                 S.HealthCheckCompareOff,
+				HealthCheckLatencyTolerancePercent: 20,
+				RouteConflictPolicy:                S.RouteConflictFail,
+				OverrideConflictPolicy:             S.OverrideConflictWarn,
+				APIURLPrefix:                       "api.cf",
+				AppsURLPrefix:                      "apps",
+				TrafficSplitWeights:                []int{10, 50},
+				TrafficSplitSoakSeconds:            300,
 			},
 		}
 
@@ -246,6 +281,2018 @@ environments:
 
 			})
 		})
+
+		Context("when the probe timeout is not set", func() {
+			It("defaults the probe timeout", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  probe_command: ./smoke-test.sh
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				badConfig, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(badConfig.Environments["production"].ProbeCommand).To(Equal("./smoke-test.sh"))
+				Expect(badConfig.Environments["production"].ProbeTimeoutSeconds).To(Equal(300))
+			})
+		})
+
+		Context("when the health check mode is invalid", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  health_check_mode: sometimes
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidHealthCheckModeError{"production", "sometimes"}))
+			})
+		})
+
+		Context("when the health check mode is warn", func() {
+			It("keeps the mode", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  health_check_mode: warn
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				badConfig, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(badConfig.Environments["production"].HealthCheckMode).To(Equal(S.HealthCheckWarn))
+			})
+		})
+
+		Context("when the route conflict policy is invalid", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  route_conflict_policy: share
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidRouteConflictPolicyError{"production", "share"}))
+			})
+		})
+
+		Context("when the override conflict policy is invalid", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  override_conflict_policy: manifest-wins
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidOverrideConflictPolicyError{"production", "manifest-wins"}))
+			})
+		})
+
+		Context("when health check stable polls are set without a period", func() {
+			It("defaults the period", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  health_check_stable_polls: 3
+`
+
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].HealthCheckStablePolls).To(Equal(3))
+				Expect(config.Environments["production"].HealthCheckStableSeconds).To(Equal(30))
+			})
+		})
+
+		Context("when the min healthy percent is out of range", func() {
+			It("defaults it", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  min_healthy_percent: 150
+- name: staging
+  foundations:
+  - api2.example.com
+  min_healthy_percent: 60
+`
+
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].MinHealthyPercent).To(Equal(100))
+				Expect(config.Environments["staging"].MinHealthyPercent).To(Equal(60))
+			})
+		})
+
+		Context("when the health check comparison is not valid", func() {
+			It("returns an error", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  health_check_compare: faster
+`
+
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+				Expect(err).To(MatchError(InvalidHealthCheckCompareError{"production", "faster"}))
+			})
+		})
+
+		Context("when a resource limit is not a size", func() {
+			It("returns an error", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  max_memory_per_instance: 2G
+  max_disk_per_instance: 4096
+`
+
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+				Expect(err).To(MatchError(InvalidSizeError{"production", "max_disk_per_instance", "4096"}))
+			})
+		})
+
+		Context("when the url prefixes are not set", func() {
+			It("defaults them", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+`
+
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].APIURLPrefix).To(Equal("api.cf"))
+				Expect(config.Environments["production"].AppsURLPrefix).To(Equal("apps"))
+			})
+		})
+
+		Context("when the url prefixes are set", func() {
+			It("keeps them", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api.sys.example.com
+  api_url_prefix: api.sys
+  apps_url_prefix: cfapps
+`
+
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].APIURLPrefix).To(Equal("api.sys"))
+				Expect(config.Environments["production"].AppsURLPrefix).To(Equal("cfapps"))
+			})
+		})
+
+		Context("when a url prefix is not a host name", func() {
+			It("returns an error", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  apps_url_prefix: https://apps
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidURLPrefixError{"production", "apps_url_prefix", "https://apps"}))
+			})
+		})
+
+		Context("when the route conflict policy is steal", func() {
+			It("keeps the policy", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  route_conflict_policy: steal
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				badConfig, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(badConfig.Environments["production"].RouteConflictPolicy).To(Equal(S.RouteConflictSteal))
+			})
+		})
+
+		Context("when the auth fallback is invalid", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  auth_fallback: [env, ldap]
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidAuthFallbackError{"production", "ldap"}))
+			})
+		})
+
+		Context("when an event handler is unknown", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  event_handlers: [healthcheck, slack]
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidEventHandlerError{"production", "slack"}))
+			})
+		})
+
+		Context("when a push flag cannot be passed through", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  push_flags: ["-m 512M", "--no-start"]
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidPushFlagError{"production", "push flag", "--no-start"}))
+			})
+		})
+
+		Context("when an allowed push flag cannot be passed through", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  allowed_push_flags: [-m, -p]
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidPushFlagError{"production", "allowed push flag", "-p"}))
+			})
+		})
+
+		Context("when the auth fallback uses env", func() {
+			It("reads the credentials of the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+				env.GetCall.Returns.Values["CF_USERNAME_PRE_PROD"] = "pre-prod-username"
+				env.GetCall.Returns.Values["CF_PASSWORD_PRE_PROD"] = "pre-prod-password"
+
+				testBadConfig := `---
+environments:
+- name: pre-prod
+  foundations:
+  - api1.example.com
+  auth_fallback: [env, deny]
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				badConfig, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				environment := badConfig.Environments["pre-prod"]
+				Expect(environment.AuthFallback).To(Equal([]string{S.AuthFallbackEnv, S.AuthFallbackDeny}))
+				Expect(environment.FallbackUsername).To(Equal("pre-prod-username"))
+				Expect(environment.FallbackPassword).To(Equal("pre-prod-password"))
+			})
+		})
+
+		Context("when traffic split is enabled without weights or soak time", func() {
+			It("defaults them", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  traffic_split: true
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				badConfig, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(badConfig.Environments["production"].TrafficSplitWeights).To(Equal([]int{10, 50}))
+				Expect(badConfig.Environments["production"].TrafficSplitSoakSeconds).To(Equal(300))
+			})
+		})
+
+		Context("when the traffic split weights do not increase", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  traffic_split: true
+  traffic_split_weights: [50, 20]
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidTrafficSplitWeightsError{"production", []int{50, 20}}))
+			})
+		})
+	})
+
+	Context("when the deployment history is configured", func() {
+		It("returns the deployment history config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+deployment_history:
+  max_records_per_app: 5
+  max_age_hours: 72
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DeploymentHistory).To(Equal(DeploymentHistoryConfig{
+				MaxRecordsPerApp:     5,
+				MaxAgeHours:          72,
+				PruneIntervalMinutes: 10,
+			}))
+		})
+	})
+
+	Context("when an environment requires approval", func() {
+		It("returns the approval gate config with the default intervals", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  require_approval: true
+approval_gate:
+  url: https://approvals.example.com/deploys
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].RequireApproval).To(BeTrue())
+			Expect(config.ApprovalGate).To(Equal(ApprovalGateConfig{
+				URL:                 "https://approvals.example.com/deploys",
+				PollIntervalSeconds: 10,
+				TimeoutSeconds:      3600,
+			}))
+		})
+
+		It("returns an error when the approval gate has no url", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  require_approval: true
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(ApprovalGateRequiredError{"production"}))
+		})
+	})
+
+	Context("when deploy records are signed", func() {
+		It("returns the event signing config with the key from the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+			env.GetCall.Returns.Values["EVENT_SIGNING_KEY"] = "secret"
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+event_signing:
+  algorithm: hmac-sha256
+  key_id: key-1
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.EventSigning).To(Equal(EventSigningConfig{
+				Algorithm: "hmac-sha256",
+				KeyID:     "key-1",
+				Key:       []byte("secret"),
+			}))
+		})
+
+		It("decodes the base64 key of ed25519", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+			env.GetCall.Returns.Values["EVENT_SIGNING_KEY"] = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+event_signing:
+  algorithm: ed25519
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.EventSigning.Key).To(Equal(bytes.Repeat([]byte{7}, 32)))
+		})
+
+		It("returns an error for an unknown algorithm", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+event_signing:
+  algorithm: rsa
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidEventSigningError{signing.UnknownAlgorithmError{"rsa"}.Error()}))
+		})
+
+		It("returns an error without a key", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+event_signing:
+  algorithm: hmac-sha256
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidEventSigningError{"the EVENT_SIGNING_KEY environment variable is required"}))
+		})
+
+		It("returns an error without the key for an ed25519 key of the wrong size", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+			env.GetCall.Returns.Values["EVENT_SIGNING_KEY"] = base64.StdEncoding.EncodeToString([]byte("short secret"))
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+event_signing:
+  algorithm: ed25519
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(BeAssignableToTypeOf(InvalidEventSigningError{}))
+			Expect(err.Error()).ToNot(ContainSubstring("short secret"))
+		})
+	})
+
+	Context("when api keys are configured", func() {
+		It("returns the api keys", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: Production
+  foundations:
+  - api1.example.com
+api_keys:
+- name: ci
+  hash: 5E884898DA28047151D0E56F8DC6292773603D0D6AABBDD62A11EF721D1542D8
+- name: team
+  hash: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+  environments: [Production]
+  org: team-org
+  space: team-space
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.APIKeys).To(Equal([]APIKey{
+				{Name: "ci", Hash: "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8", Environments: []string{}},
+				{Name: "team", Hash: "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", Environments: []string{"production"}, Org: "team-org", Space: "team-space"},
+			}))
+		})
+
+		It("returns an error for a hash that is not a sha256", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+api_keys:
+- name: ci
+  hash: my-secret-key
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidAPIKeyError{"ci", "the hash must be a hex encoded sha256"}))
+		})
+
+		It("returns an error for an environment that is not configured", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+api_keys:
+- name: team
+  hash: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+  environments: [staging]
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidAPIKeyError{"team", "environment staging is not configured"}))
+		})
+
+		It("returns an error for a default org without a default space", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+api_keys:
+- name: team
+  hash: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+  org: team-org
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidAPIKeyError{"team", "a default org and space must be set together"}))
+		})
+
+		It("returns an error for a default org that an environment of the key does not allow", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  allowed_orgs: [payments-org]
+- name: staging
+  foundations:
+  - api2.example.com
+api_keys:
+- name: team
+  hash: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+  environments: [production]
+  org: team-org
+  space: team-space
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidAPIKeyError{"team", "default org team-org is not allowed in environment production"}))
+		})
+
+		It("returns an error for a default space that any environment does not allow when the key has no environments", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+- name: staging
+  foundations:
+  - api2.example.com
+  allowed_spaces: [staging-space]
+api_keys:
+- name: team
+  hash: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+  org: team-org
+  space: team-space
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidAPIKeyError{"team", "default space team-space is not allowed in environment staging"}))
+		})
+
+		It("accepts a default org and space that the environments of the key allow", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  allowed_orgs: [team-org]
+  allowed_spaces: [team-space]
+- name: staging
+  foundations:
+  - api2.example.com
+  allowed_orgs: [other-org]
+api_keys:
+- name: team
+  hash: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+  environments: [production]
+  org: team-org
+  space: team-space
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.APIKeys[0].Org).To(Equal("team-org"))
+		})
+	})
+
+	Context("when artifact hosts are configured", func() {
+		It("returns the artifact hosts config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+artifact_hosts:
+  allowed: [artifacts.example.com, "*.repo.example.com"]
+  denied_cidrs: [10.0.0.0/8]
+  allow_link_local: true
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactHosts.Allowed).To(Equal([]string{"artifacts.example.com", "*.repo.example.com"}))
+			Expect(config.ArtifactHosts.DeniedCIDRs).To(Equal([]string{"10.0.0.0/8"}))
+			Expect(config.ArtifactHosts.AllowLinkLocal).To(BeTrue())
+		})
+
+		It("returns an error for a denied range that is not a CIDR", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+artifact_hosts:
+  denied_cidrs: [10.0.0.0]
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidDeniedCIDRError{"10.0.0.0"}))
+		})
+	})
+
+	Context("when an artifact cache is configured", func() {
+		It("returns the artifact cache config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+artifact_cache:
+  enabled: true
+  directory: /tmp/artifacts
+  max_size_mb: 42
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactCache.Enabled).To(BeTrue())
+			Expect(config.ArtifactCache.Directory).To(Equal("/tmp/artifacts"))
+			Expect(config.ArtifactCache.MaxSizeMB).To(Equal(int64(42)))
+		})
+
+		It("is disabled with defaults when not configured", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactCache.Enabled).To(BeFalse())
+			Expect(config.ArtifactCache.Directory).ToNot(BeEmpty())
+			Expect(config.ArtifactCache.MaxSizeMB).To(Equal(int64(1024)))
+		})
+	})
+
+	Context("when deployment logs are configured", func() {
+		It("returns the deployment log config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+deployment_log:
+  enabled: true
+  directory: /tmp/deployments
+  max_files: 10
+  max_age_hours: 24
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DeploymentLog.Enabled).To(BeTrue())
+			Expect(config.DeploymentLog.Directory).To(Equal("/tmp/deployments"))
+			Expect(config.DeploymentLog.MaxFiles).To(Equal(10))
+			Expect(config.DeploymentLog.MaxAgeHours).To(Equal(24))
+		})
+
+		It("is disabled with defaults when not configured", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DeploymentLog.Enabled).To(BeFalse())
+			Expect(config.DeploymentLog.Directory).ToNot(BeEmpty())
+			Expect(config.DeploymentLog.MaxFiles).To(Equal(100))
+			Expect(config.DeploymentLog.MaxAgeHours).To(BeZero())
+		})
+	})
+
+	Context("when health check policies are configured", func() {
+		It("returns the health check config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+health_check:
+  unreachable:
+    retries: 3
+    retry_interval_seconds: 5
+  unhealthy:
+    retries: -1
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.HealthCheck.Unreachable).To(Equal(HealthCheckPolicy{Retries: 3, RetryIntervalSeconds: 5}))
+			Expect(config.HealthCheck.Unhealthy).To(Equal(HealthCheckPolicy{}))
+		})
+
+		It("does not retry when not configured", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.HealthCheck).To(Equal(HealthCheckConfig{}))
+		})
+	})
+
+	Context("when the circuit breaker is configured", func() {
+		It("returns the circuit breaker config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+circuit_breaker:
+  enabled: true
+  failure_threshold: 3
+  cooldown_seconds: 120
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Circuit).To(Equal(CircuitBreakerConfig{Enabled: true, FailureThreshold: 3, CooldownSeconds: 120}))
+		})
+
+		It("is disabled with defaults when not configured", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Circuit).To(Equal(CircuitBreakerConfig{FailureThreshold: 5, CooldownSeconds: 60}))
+		})
+	})
+
+	Context("when an environment has a default manifest", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("keeps an inline manifest", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  default_manifest: |
+    applications:
+    - memory: 1G
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].DefaultManifest).To(Equal("applications:\n- memory: 1G\n"))
+		})
+
+		It("reads the manifest from a file path", func() {
+			manifestPath := customConfigPath + "-manifest.yml"
+			defer os.RemoveAll(manifestPath)
+			Expect(ioutil.WriteFile(manifestPath, []byte("applications:\n- memory: 2G\n"), 0644)).To(Succeed())
+
+			testConfig := fmt.Sprintf(`---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  default_manifest: %s
+`, manifestPath)
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].DefaultManifest).To(Equal("applications:\n- memory: 2G\n"))
+		})
+
+		It("returns an error when the manifest file cannot be read", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  default_manifest: /does/not/exist.yml
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(BeAssignableToTypeOf(DefaultManifestError{}))
+			Expect(err.Error()).To(ContainSubstring("cannot read default manifest for environment production"))
+		})
+	})
+
+	Context("when a validation webhook is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the validation webhook config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+validation_webhook:
+  url: https://policy.example.com/deploys
+  timeout_seconds: 30
+  allow_on_timeout: true
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ValidationWebhook).To(Equal(ValidationWebhookConfig{URL: "https://policy.example.com/deploys", TimeoutSeconds: 30, AllowOnTimeout: true}))
+		})
+
+		It("defaults the timeout", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ValidationWebhook).To(Equal(ValidationWebhookConfig{TimeoutSeconds: 10}))
+		})
+	})
+
+	Context("when error output is capped", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the error output config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+error_output:
+  max_lines: 200
+  max_bytes: 65536
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ErrorOutput).To(Equal(ErrorOutputConfig{MaxLines: 200, MaxBytes: 65536}))
+		})
+
+		It("does not cap the error output by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ErrorOutput).To(Equal(ErrorOutputConfig{}))
+		})
+	})
+
+	Context("when artifact download retries are configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the artifact download config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+artifact_download:
+  max_retries: 3
+  backoff_seconds: 5
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactDownload).To(Equal(ArtifactDownloadConfig{MaxRetries: 3, BackoffSeconds: 5}))
+		})
+
+		It("does not retry by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactDownload).To(Equal(ArtifactDownloadConfig{MaxRetries: 0, BackoffSeconds: 2}))
+		})
+	})
+
+	Context("when an artifact upload limit is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the artifact upload config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+artifact_upload:
+  max_size_mb: 256
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactUpload).To(Equal(ArtifactUploadConfig{MaxSizeMB: 256}))
+		})
+
+		It("limits artifacts to 1024 MB by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactUpload).To(Equal(ArtifactUploadConfig{MaxSizeMB: 1024}))
+		})
+	})
+
+	Context("when a request timeout is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the request timeout config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+request_timeout:
+  seconds: 1800
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.RequestTimeout).To(Equal(RequestTimeoutConfig{Seconds: 1800}))
+		})
+
+		It("does not limit requests by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.RequestTimeout).To(Equal(RequestTimeoutConfig{}))
+		})
+	})
+
+	Context("when the http client is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the http client config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+http_client:
+  max_idle_conns: 200
+  max_idle_conns_per_host: 20
+  idle_conn_timeout_seconds: 120
+  dial_timeout_seconds: 5
+  tls_handshake_timeout_seconds: 3
+  request_timeout_seconds: 45
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.HTTPClient).To(Equal(HTTPClientConfig{
+				MaxIdleConns:               200,
+				MaxIdleConnsPerHost:        20,
+				IdleConnTimeoutSeconds:     120,
+				DialTimeoutSeconds:         5,
+				TLSHandshakeTimeoutSeconds: 3,
+				RequestTimeoutSeconds:      45,
+			}))
+		})
+
+		It("returns the default http client config", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.HTTPClient).To(Equal(HTTPClientConfig{
+				MaxIdleConns:               100,
+				MaxIdleConnsPerHost:        10,
+				IdleConnTimeoutSeconds:     90,
+				DialTimeoutSeconds:         30,
+				TLSHandshakeTimeoutSeconds: 10,
+				RequestTimeoutSeconds:      30,
+			}))
+		})
+	})
+
+	Context("when an otel collector is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the otel config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+otel:
+  endpoint: collector.example.com:4318
+  insecure: true
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.OTel).To(Equal(OTelConfig{Endpoint: "collector.example.com:4318", Insecure: true}))
+		})
+
+		It("exports to a local collector by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.OTel).To(Equal(OTelConfig{Endpoint: "localhost:4318"}))
+		})
+	})
+
+	Context("when the cf cli is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the cf cli config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+cf_cli:
+  path: /opt/cf/bin/cf
+  min_version: 6.20.0
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.CFCLI).To(Equal(CFCLIConfig{Path: "/opt/cf/bin/cf", MinVersion: "6.20.0"}))
+		})
+
+		It("uses CF_CLI_PATH over the configured path", func() {
+			env.GetCall.Returns.Values["CF_CLI_PATH"] = "/usr/local/bin/cf"
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.CFCLI.Path).To(Equal("/usr/local/bin/cf"))
+		})
+
+		It("uses the cf on the PATH without a minimum version by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.CFCLI).To(Equal(CFCLIConfig{Path: "cf"}))
+		})
+
+		It("returns an error when the minimum version is not a version", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+cf_cli:
+  min_version: latest
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidCFCLIConfigError{`min_version "latest" must be a version like 6.20.0`}))
+		})
+	})
+
+	Context("when staging is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the staging config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+staging:
+  directory: /var/deployadactyl/staging
+  max_age_hours: 6
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Staging).To(Equal(StagingConfig{Directory: "/var/deployadactyl/staging", MaxAgeHours: 6}))
+		})
+
+		It("stages in the temp directory and sweeps after a day by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Staging).To(Equal(StagingConfig{Directory: filepath.Join(os.TempDir(), "deployadactyl-staging"), MaxAgeHours: 24}))
+		})
+	})
+
+	Context("when kafka is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the kafka config with the default retries", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+kafka:
+  brokers:
+  - kafka1.example.com:9092
+  - kafka2.example.com:9092
+  topic: deploys
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Kafka).To(Equal(KafkaConfig{
+				Brokers:                   []string{"kafka1.example.com:9092", "kafka2.example.com:9092"},
+				Topic:                     "deploys",
+				Retries:                   3,
+				RetryIntervalMilliseconds: 200,
+			}))
+		})
+
+		It("returns an error when the topic is missing", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+kafka:
+  brokers:
+  - kafka1.example.com:9092
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidKafkaConfigError{"topic is required"}))
+		})
+
+		It("does not publish events by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Kafka.Brokers).To(BeEmpty())
+		})
+	})
+
+	Context("when a default environment is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the default environment", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+default_environment: Production
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DefaultEnvironment).To(Equal("production"))
+		})
+
+		It("returns an error when the default environment is not an environment", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+default_environment: staging
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(DefaultEnvironmentNotFoundError{Environment: "staging"}))
+		})
+
+		It("returns an error when two environments differ only in case", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+- name: Production
+  foundations:
+  - api2.example.com
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(DuplicateEnvironmentError{Environment: "Production"}))
+		})
+
+		It("has no default environment when not configured", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DefaultEnvironment).To(BeEmpty())
+		})
+	})
+
+	Context("when the concurrent deployments are limited", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the most deploys that run at the same time", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+max_concurrent_deployments: 10
+queue_concurrent_deployments: true
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.MaxConcurrentDeployments).To(Equal(10))
+			Expect(config.QueueConcurrentDeployments).To(BeTrue())
+		})
+
+		It("returns an error when the limit is negative", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+max_concurrent_deployments: -1
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidMaxConcurrentDeploymentsError{-1}))
+		})
+
+		It("does not limit deploys when not configured", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.MaxConcurrentDeployments).To(BeZero())
+			Expect(config.QueueConcurrentDeployments).To(BeFalse())
+		})
+	})
+
+	Context("when json fields are renamed", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the json field names", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+json_fields:
+  artifact_url: artifactUrl
+  manifest: manifestYaml
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.JSONFields).To(Equal(map[string]string{"artifact_url": "artifactUrl", "manifest": "manifestYaml"}))
+		})
+
+		It("returns an error when a field is not a field of a push", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+json_fields:
+  artifact_uri: artifactUrl
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidJSONFieldsError{"artifact_uri is not a field of a push"}))
+		})
+
+		It("returns an error when a field is renamed to the name of another field", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+json_fields:
+  manifest: spec
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidJSONFieldsError{"manifest cannot be renamed to spec, which is the name of another field"}))
+		})
+
+		It("returns an error when two fields are renamed to the same name", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+json_fields:
+  artifact_url: url
+  health_check_endpoint: url
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidJSONFieldsError{"artifact_url and health_check_endpoint are both renamed to url"}))
+		})
+
+		It("renames no fields when not configured", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.JSONFields).To(BeEmpty())
+		})
+	})
+
+	Context("when the log prefix is configured", func() {
+		It("returns the log prefix config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+log_prefix:
+  disabled: true
+  include_application: true
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.LogPrefix).To(Equal(LogPrefixConfig{Disabled: true, IncludeApplication: true}))
+		})
+	})
+
+	Context("when profiles are configured", func() {
+		It("returns the profiles", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+profiles:
+  large:
+    instances: 4
+    memory: 2G
+    stack: cflinuxfs4
+    health_check_endpoint: /health
+    environment_variables:
+      LOG_LEVEL: info
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Profiles).To(Equal(map[string]Profile{
+				"large": {
+					Instances:            4,
+					Memory:               "2G",
+					Stack:                "cflinuxfs4",
+					HealthCheckEndpoint:  "/health",
+					EnvironmentVariables: map[string]string{"LOG_LEVEL": "info"},
+				},
+			}))
+			Expect(config.Profiles["large"].Manifest()).To(MatchYAML(`
+applications:
+- instances: 4
+  memory: 2G
+  stack: cflinuxfs4
+`))
+		})
+
+		It("has no profile manifest without instances, memory or stack", func() {
+			Expect(Profile{HealthCheckEndpoint: "/health"}.Manifest()).To(BeEmpty())
+		})
+	})
+
+	Context("when tls is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the tls config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+tls:
+  enabled: true
+  cert_file: /certs/server.crt
+  key_file: /certs/server.key
+  client_ca_file: /certs/clients.pem
+  require_client_cert: true
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.TLS).To(Equal(TLSConfig{
+				Enabled:           true,
+				CertFile:          "/certs/server.crt",
+				KeyFile:           "/certs/server.key",
+				ClientCAFile:      "/certs/clients.pem",
+				RequireClientCert: true,
+			}))
+		})
+
+		It("returns an error when the server certificate is missing", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+tls:
+  enabled: true
+  key_file: /certs/server.key
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidTLSConfigError{"cert_file and key_file are required"}))
+		})
+
+		It("returns an error when client certificates are required without a ca bundle", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+tls:
+  enabled: true
+  cert_file: /certs/server.crt
+  key_file: /certs/server.key
+  require_client_cert: true
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidTLSConfigError{"client_ca_file is required to verify client certificates"}))
+		})
+
+		It("returns an error when client certificates are required without tls", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+tls:
+  require_client_cert: true
+  client_ca_file: /certs/clients.pem
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidTLSConfigError{"client certificates require tls to be enabled"}))
+		})
+	})
+
+	Context("when the silent deploy pool is configured", func() {
+		It("returns the silent deploy config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+silent_deploy:
+  pool_size: 8
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.SilentDeploy).To(Equal(SilentDeployConfig{PoolSize: 8}))
+		})
+
+		It("defaults the pool size when not configured", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.SilentDeploy).To(Equal(SilentDeployConfig{PoolSize: 4}))
+		})
+	})
+
+	Context("when the request log is configured", func() {
+		It("returns the request log config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+request_log:
+  enabled: true
+  max_body_bytes: 256
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.RequestLog).To(Equal(RequestLogConfig{Enabled: true, MaxBodyBytes: 256}))
+		})
+
+		It("is off by default", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.RequestLog).To(Equal(RequestLogConfig{MaxBodyBytes: 1024}))
+		})
+	})
+
+	Context("when the app logs are configured", func() {
+		It("returns the app logs config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+app_logs:
+  lines: 50
+  max_lines: 500
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.AppLogs).To(Equal(AppLogsConfig{Lines: 50, MaxLines: 500}))
+		})
+
+		It("does not return more lines than the maximum by default", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+app_logs:
+  max_lines: 20
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.AppLogs).To(Equal(AppLogsConfig{Lines: 20, MaxLines: 20}))
+		})
+
+		It("has defaults", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.AppLogs).To(Equal(AppLogsConfig{Lines: 100, MaxLines: 5000}))
+		})
+	})
+
+	Context("when event retries are configured", func() {
+		It("returns the event retry config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+event_retry:
+  enabled: true
+  directory: /var/deployadactyl/retries
+  max_attempts: 3
+  backoff_seconds: 30
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.EventRetry).To(Equal(EventRetryConfig{Enabled: true, Directory: "/var/deployadactyl/retries", MaxAttempts: 3, BackoffSeconds: 30}))
+		})
+
+		It("is off by default", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.EventRetry.Enabled).To(BeFalse())
+			Expect(config.EventRetry.Directory).To(HaveSuffix("deployadactyl-event-retries"))
+			Expect(config.EventRetry.MaxAttempts).To(Equal(10))
+			Expect(config.EventRetry.BackoffSeconds).To(Equal(5))
+		})
+	})
+
+	Context("when deploy recovery is configured", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the deploy recovery config", func() {
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+deploy_recovery:
+  enabled: true
+  directory: /var/deployadactyl/journal
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DeployRecovery).To(Equal(DeployRecoveryConfig{Enabled: true, Directory: "/var/deployadactyl/journal"}))
+		})
+
+		It("is off by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DeployRecovery.Enabled).To(BeFalse())
+			Expect(config.DeployRecovery.Directory).To(HaveSuffix("deployadactyl-deploy-journal"))
+		})
+	})
+
+	Context("when the batch deploy concurrency is configured", func() {
+		It("returns the batch deploy config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+batch_deploy:
+  concurrency: 2
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.BatchDeploy).To(Equal(BatchDeployConfig{Concurrency: 2}))
+		})
+
+		It("defaults the concurrency when not configured", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.BatchDeploy).To(Equal(BatchDeployConfig{Concurrency: 4}))
+		})
+	})
+
+	Context("when uuid options are configured", func() {
+		It("returns the uuid config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+uuid:
+  always_generate: true
+  format: RFC4122
+  max_length: 40
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.UUID.AlwaysGenerate).To(BeTrue())
+			Expect(config.UUID.Format).To(Equal(UUIDFormatRFC4122))
+			Expect(config.UUID.MaxLength).To(Equal(40))
+		})
+
+		It("uses the defaults when not configured", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.UUID.AlwaysGenerate).To(BeFalse())
+			Expect(config.UUID.Format).To(Equal(UUIDFormatDefault))
+			Expect(config.UUID.MaxLength).To(Equal(36))
+		})
+
+		It("returns an error for an unknown format", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+uuid:
+  format: guid
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidUUIDFormatError{"guid"}))
+		})
 	})
 
 	Context("when no error matchers are present", func() {
@@ -301,4 +2348,57 @@ error_matchers:
 			Expect(config.ErrorMatchers[1].Descriptor()).To(Equal("another matcher: cd: 34: "))
 		})
 	})
+
+	Describe("FallbackAuthorization", func() {
+		var config Config
+
+		BeforeEach(func() {
+			config = Config{Username: cfUsername, Password: cfPassword}
+		})
+
+		It("uses the config credentials by default", func() {
+			username, password, ok := config.FallbackAuthorization(S.Environment{})
+
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal(cfUsername))
+			Expect(password).To(Equal(cfPassword))
+		})
+
+		It("denies by default when the environment authenticates", func() {
+			_, _, ok := config.FallbackAuthorization(S.Environment{Authenticate: true})
+
+			Expect(ok).To(BeFalse())
+		})
+
+		It("uses the env credentials when they are set", func() {
+			environment := S.Environment{
+				AuthFallback:     []string{S.AuthFallbackEnv, S.AuthFallbackConfig},
+				FallbackUsername: "env-username",
+				FallbackPassword: "env-password",
+			}
+
+			username, password, ok := config.FallbackAuthorization(environment)
+
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal("env-username"))
+			Expect(password).To(Equal("env-password"))
+		})
+
+		It("falls through to the next source when the env credentials are not set", func() {
+			environment := S.Environment{AuthFallback: []string{S.AuthFallbackEnv, S.AuthFallbackConfig}}
+
+			username, _, ok := config.FallbackAuthorization(environment)
+
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal(cfUsername))
+		})
+
+		It("denies when it reaches deny", func() {
+			environment := S.Environment{AuthFallback: []string{S.AuthFallbackEnv, S.AuthFallbackDeny, S.AuthFallbackConfig}}
+
+			_, _, ok := config.FallbackAuthorization(environment)
+
+			Expect(ok).To(BeFalse())
+		})
+	})
 })
@@ -1,6 +1,11 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	s "github.com/compozed/deployadactyl/structs"
+)
 
 type EnvironmentsNotSpecifiedError struct{}
 
@@ -21,3 +26,202 @@ type ParseYamlError struct {
 func (e ParseYamlError) Error() string {
 	return fmt.Sprintf("cannot parse yaml file: %s", e.Err)
 }
+
+type InvalidUUIDFormatError struct {
+	Format string
+}
+
+func (e InvalidUUIDFormatError) Error() string {
+	return fmt.Sprintf("invalid uuid format %s: must be default or rfc4122", e.Format)
+}
+
+type InvalidTLSConfigError struct {
+	Reason string
+}
+
+func (e InvalidTLSConfigError) Error() string {
+	return fmt.Sprintf("invalid tls config: %s", e.Reason)
+}
+
+type InvalidJSONFieldsError struct {
+	Reason string
+}
+
+func (e InvalidJSONFieldsError) Error() string {
+	return fmt.Sprintf("invalid json_fields: %s", e.Reason)
+}
+
+type ApprovalGateRequiredError struct {
+	Environment string
+}
+
+func (e ApprovalGateRequiredError) Error() string {
+	return fmt.Sprintf("environment %s requires approval: approval_gate.url is required", e.Environment)
+}
+
+type InvalidAPIKeyError struct {
+	Name   string
+	Reason string
+}
+
+func (e InvalidAPIKeyError) Error() string {
+	return fmt.Sprintf("invalid api key %s: %s", e.Name, e.Reason)
+}
+
+type InvalidKafkaConfigError struct {
+	Reason string
+}
+
+func (e InvalidKafkaConfigError) Error() string {
+	return fmt.Sprintf("invalid kafka config: %s", e.Reason)
+}
+
+type InvalidEventSigningError struct {
+	Reason string
+}
+
+func (e InvalidEventSigningError) Error() string {
+	return fmt.Sprintf("invalid event signing config: %s", e.Reason)
+}
+
+type InvalidCFCLIConfigError struct {
+	Reason string
+}
+
+func (e InvalidCFCLIConfigError) Error() string {
+	return fmt.Sprintf("invalid cf cli config: %s", e.Reason)
+}
+
+type InvalidDeniedCIDRError struct {
+	CIDR string
+}
+
+func (e InvalidDeniedCIDRError) Error() string {
+	return fmt.Sprintf("invalid artifact_hosts.denied_cidrs range %s: must be a CIDR such as 10.0.0.0/8", e.CIDR)
+}
+
+type DefaultEnvironmentNotFoundError struct {
+	Environment string
+}
+
+func (e DefaultEnvironmentNotFoundError) Error() string {
+	return fmt.Sprintf("default environment %s is not in the environments key", e.Environment)
+}
+
+type DuplicateEnvironmentError struct {
+	Environment string
+}
+
+func (e DuplicateEnvironmentError) Error() string {
+	return fmt.Sprintf("environment %s is configured more than once: names are matched whatever their case", e.Environment)
+}
+
+type DefaultManifestError struct {
+	Environment string
+	Err         error
+}
+
+func (e DefaultManifestError) Error() string {
+	return fmt.Sprintf("cannot read default manifest for environment %s: %s", e.Environment, e.Err)
+}
+
+type InvalidHealthCheckModeError struct {
+	Environment string
+	Mode        string
+}
+
+func (e InvalidHealthCheckModeError) Error() string {
+	return fmt.Sprintf("invalid health check mode %s for environment %s: must be enforce, warn or off", e.Mode, e.Environment)
+}
+
+type InvalidHealthCheckCompareError struct {
+	Environment string
+	Compare     string
+}
+
+func (e InvalidHealthCheckCompareError) Error() string {
+	return fmt.Sprintf("invalid health check comparison %s for environment %s: must be off, healthy or latency", e.Compare, e.Environment)
+}
+
+type InvalidRouteConflictPolicyError struct {
+	Environment string
+	Policy      string
+}
+
+func (e InvalidRouteConflictPolicyError) Error() string {
+	return fmt.Sprintf("invalid route conflict policy %s for environment %s: must be fail, steal or skip", e.Policy, e.Environment)
+}
+
+type InvalidOverrideConflictPolicyError struct {
+	Environment string
+	Policy      string
+}
+
+func (e InvalidOverrideConflictPolicyError) Error() string {
+	return fmt.Sprintf("invalid override conflict policy %s for environment %s: must be silent, warn or reject", e.Policy, e.Environment)
+}
+
+type InvalidAuthFallbackError struct {
+	Environment string
+	Source      string
+}
+
+func (e InvalidAuthFallbackError) Error() string {
+	return fmt.Sprintf("invalid auth fallback %s for environment %s: must be config, env or deny", e.Source, e.Environment)
+}
+
+type InvalidEventHandlerError struct {
+	Environment string
+	Handler     string
+}
+
+func (e InvalidEventHandlerError) Error() string {
+	return fmt.Sprintf("invalid event handler %s for environment %s: must be healthcheck, envvar or routemapper", e.Handler, e.Environment)
+}
+
+type InvalidSizeError struct {
+	Environment string
+	Key         string
+	Size        string
+}
+
+func (e InvalidSizeError) Error() string {
+	return fmt.Sprintf("invalid %s %q for environment %s: must be a size like 512M or 2G", e.Key, e.Size, e.Environment)
+}
+
+type InvalidPushFlagError struct {
+	Environment string
+	Key         string
+	Flag        string
+}
+
+func (e InvalidPushFlagError) Error() string {
+	return fmt.Sprintf("invalid %s %q for environment %s: must be one of %s", e.Key, e.Flag, e.Environment, strings.Join(s.PassthroughPushFlags, ", "))
+}
+
+type InvalidURLPrefixError struct {
+	Environment string
+	Key         string
+	Prefix      string
+}
+
+func (e InvalidURLPrefixError) Error() string {
+	return fmt.Sprintf("invalid %s %s for environment %s: must be dot separated host name labels", e.Key, e.Prefix, e.Environment)
+}
+
+type InvalidTrafficSplitWeightsError struct {
+	Environment string
+	Weights     []int
+}
+
+func (e InvalidTrafficSplitWeightsError) Error() string {
+	return fmt.Sprintf("invalid traffic split weights %v for environment %s: must increase from 1 to 99", e.Weights, e.Environment)
+}
+
+type InvalidMaxConcurrentDeploymentsError struct {
+	Max int
+}
+
+func (e InvalidMaxConcurrentDeploymentsError) Error() string {
+	return fmt.Sprintf("invalid max concurrent deployments %d: must not be negative", e.Max)
+}
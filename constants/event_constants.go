@@ -1,10 +1,14 @@
 package constants
 
 const (
-	DeployStartEvent   = "deploy.start"
-	DeployFinishEvent  = "deploy.finish"
-	DeploySuccessEvent = "deploy.success"
-	DeployFailureEvent = "deploy.failure"
-	PushStartedEvent   = "push.started"
-	PushFinishedEvent  = "push.finished"
+	DeployStartEvent     = "deploy.start"
+	DeployFinishEvent    = "deploy.finish"
+	DeploySuccessEvent   = "deploy.success"
+	DeployFailureEvent   = "deploy.failure"
+	DeployDeleteEvent    = "deploy.delete"
+	DeployWarningEvent   = "deploy.warning"
+	DeployCanceledEvent  = "deploy.canceled"
+	DeploySLOBreachEvent = "deploy.slo_breach"
+	PushStartedEvent     = "push.started"
+	PushFinishedEvent    = "push.finished"
 )
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/constants"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/drain"
+	"github.com/compozed/deployadactyl/diagnostics"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state/push"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminDrainHandler stops the node from accepting deploys. Deploys that are already running are left to finish.
+// The request must authenticate with the credentials Deployadactyl uses for Cloud Foundry.
+func (c *Controller) AdminDrainHandler(g *gin.Context) {
+	c.adminHandler(g, "drain", c.Drain.Drain)
+}
+
+// AdminUndrainHandler lets the node accept deploys again.
+func (c *Controller) AdminUndrainHandler(g *gin.Context) {
+	c.adminHandler(g, "undrain", c.Drain.Undrain)
+}
+
+// AdminStatusHandler returns whether the node is draining and the number of deploys still running.
+func (c *Controller) AdminStatusHandler(g *gin.Context) {
+	c.adminHandler(g, "status", c.Drain.Status)
+}
+
+func (c *Controller) adminHandler(g *gin.Context, action string, handle func() drain.Status) {
+	c.Log.Debugf("admin %s request originated from: %+v", action, g.Request.RemoteAddr)
+
+	if !c.authorizeAdmin(g, action) {
+		return
+	}
+
+	c.writeAdminJSON(g, http.StatusOK, handle())
+}
+
+// AdminDiagnosticsHandler checks the Cloud Foundry API of every configured foundation and the validation webhook,
+// and returns the status and latency of each. It answers 503 when any of them is down.
+// The request must authenticate with the credentials Deployadactyl uses for Cloud Foundry.
+func (c *Controller) AdminDiagnosticsHandler(g *gin.Context) {
+	c.Log.Debugf("admin diagnostics request originated from: %+v", g.Request.RemoteAddr)
+
+	if !c.authorizeAdmin(g, "diagnostics") {
+		return
+	}
+
+	report := c.Diagnostics.Check()
+	for _, result := range report.Dependencies {
+		if result.Status == diagnostics.StatusDown {
+			c.Log.Errorf("%s %s is down: %s", result.Kind, result.URL, result.Error)
+		}
+	}
+
+	statusCode := http.StatusOK
+	if report.Status != diagnostics.StatusOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+	c.writeAdminJSON(g, statusCode, report)
+}
+
+// authorizeAdmin answers 401 and returns false when the admin request does not authenticate with the credentials
+// Deployadactyl uses for Cloud Foundry.
+func (c *Controller) authorizeAdmin(g *gin.Context, action string) bool {
+	auth := getAuthorization(g)
+	if c.validCredentials(auth.Username, auth.Password) {
+		return true
+	}
+
+	c.Log.Errorf("invalid credentials for admin %s request", action)
+	g.Writer.Header().Set("WWW-Authenticate", `Basic realm="deployadactyl"`)
+	g.Writer.WriteHeader(http.StatusUnauthorized)
+	fmt.Fprintln(g.Writer, deployer.InvalidCredentialsError{})
+	return false
+}
+
+func (c *Controller) writeAdminJSON(g *gin.Context, statusCode int, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	g.Writer.Header().Set("Content-Type", "application/json")
+	g.Writer.WriteHeader(statusCode)
+	g.Writer.Write(body)
+}
+
+// AdminReplayHandler emits the recorded events of a past deploy again through the EventManager so that handlers can
+// recover events they missed. Each event is emitted both to the handlers and to the bindings of its typed event,
+// marked as a replay.
+// The request must authenticate with the credentials Deployadactyl uses for Cloud Foundry.
+func (c *Controller) AdminReplayHandler(g *gin.Context) {
+	uuid := g.Param("uuid")
+
+	c.Log.Debugf("admin replay request for deploy %s originated from: %+v", uuid, g.Request.RemoteAddr)
+
+	if !c.authorizeAdmin(g, "replay") {
+		return
+	}
+
+	var events []deployer.HistoryEvent
+	var err error = deployer.DeploymentHistoryNotFoundError{UUID: uuid}
+	if c.History != nil {
+		events, err = c.History.Events(uuid)
+	}
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	for _, event := range events {
+		c.Log.Infof("replaying a %s event of deploy %s", event.Type, uuid)
+		err = c.EventManager.Emit(event.Event())
+		if err == nil {
+			if typed := c.replayedTypedEvent(event); typed != nil {
+				err = c.EventManager.EmitEvent(typed)
+			}
+		}
+		if err != nil {
+			err = deployer.EventError{Type: event.Type, Err: err}
+			c.Log.Error(err)
+			g.Writer.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(g.Writer, err)
+			return
+		}
+	}
+
+	g.Writer.WriteHeader(http.StatusOK)
+	fmt.Fprintf(g.Writer, "replayed %d events of deploy %s\n", len(events), uuid)
+}
+
+// replayedTypedEvent returns the typed event of a recorded deploy event, marked as a replay, with the environment of
+// the deploy and an empty request body and response. It is nil for an event without a typed event.
+func (c *Controller) replayedTypedEvent(event deployer.HistoryEvent) I.IEvent {
+	info := event.DeploymentInfo
+	cf := I.CFContext{
+		Environment:  info.Environment,
+		Organization: info.Org,
+		Space:        info.Space,
+		Application:  info.AppName,
+		SkipSSL:      info.SkipSSL,
+	}
+	environment := c.Config.Environments[info.Environment]
+	log := I.DeploymentLogger{Log: c.Log, UUID: info.UUID}
+
+	switch event.Type {
+	case constants.DeployStartEvent:
+		return push.DeployStartedEvent{
+			CFContext:      cf,
+			ArtifactURL:    info.ArtifactURL,
+			Body:           &bytes.Buffer{},
+			ContentType:    info.ContentType,
+			Environment:    environment,
+			Response:       &bytes.Buffer{},
+			Data:           info.Data,
+			Log:            log,
+			ClientIdentity: info.ClientIdentity,
+			ChangeTicket:   info.ChangeTicket,
+			Reason:         info.Reason,
+			Replay:         true,
+		}
+	case constants.DeploySuccessEvent:
+		return push.DeploySuccessEvent{
+			CFContext:           cf,
+			Body:                &bytes.Buffer{},
+			ContentType:         info.ContentType,
+			Environment:         environment,
+			Response:            &bytes.Buffer{},
+			Data:                info.Data,
+			HealthCheckEndpoint: info.HealthCheckEndpoint,
+			ArtifactURL:         info.ArtifactURL,
+			Artifact:            info.Artifact,
+			Log:                 log,
+			ChangeTicket:        info.ChangeTicket,
+			Reason:              info.Reason,
+			Replay:              true,
+		}
+	case constants.DeployFailureEvent:
+		return push.DeployFailureEvent{
+			CFContext:    cf,
+			Body:         &bytes.Buffer{},
+			ContentType:  info.ContentType,
+			Environment:  environment,
+			Response:     &bytes.Buffer{},
+			Data:         info.Data,
+			Error:        event.Error,
+			Log:          log,
+			ChangeTicket: info.ChangeTicket,
+			Reason:       info.Reason,
+			Replay:       true,
+		}
+	case constants.DeployFinishEvent:
+		return push.DeployFinishedEvent{
+			CFContext:      cf,
+			Body:           &bytes.Buffer{},
+			ContentType:    info.ContentType,
+			Environment:    environment,
+			Response:       &bytes.Buffer{},
+			Data:           info.Data,
+			Log:            log,
+			ClientIdentity: info.ClientIdentity,
+			ChangeTicket:   info.ChangeTicket,
+			Reason:         info.Reason,
+			Replay:         true,
+		}
+	}
+
+	return nil
+}
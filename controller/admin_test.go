@@ -0,0 +1,228 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"errors"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/constants"
+	. "github.com/compozed/deployadactyl/controller"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/drain"
+	"github.com/compozed/deployadactyl/diagnostics"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Admin handlers", func() {
+	var (
+		controller *Controller
+		gate       *drain.Gate
+		history    *deployer.History
+		events     *mocks.EventManager
+		router     *gin.Engine
+		logBuffer  *Buffer
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		router = gin.New()
+
+		logger := I.DefaultLogger(logBuffer, logging.DEBUG, "admin_test")
+		gate = drain.NewGate(logger)
+		history = deployer.NewHistory(10)
+		events = &mocks.EventManager{}
+
+		controller = &Controller{
+			Log:          logger,
+			Config:       config.Config{Username: "admin", Password: "secret"},
+			Drain:        gate,
+			History:      history,
+			EventManager: events,
+		}
+
+		router.POST("/admin/drain", controller.AdminDrainHandler)
+		router.POST("/admin/undrain", controller.AdminUndrainHandler)
+		router.GET("/admin/status", controller.AdminStatusHandler)
+		router.POST("/admin/deployments/:uuid/replay", controller.AdminReplayHandler)
+		router.GET("/admin/diagnostics", controller.AdminDiagnosticsHandler)
+	})
+
+	request := func(method, path, user, pass string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.SetBasicAuth(user, pass)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	status := func(resp *httptest.ResponseRecorder) drain.Status {
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var s drain.Status
+		Expect(json.Unmarshal(resp.Body.Bytes(), &s)).To(Succeed())
+		return s
+	}
+
+	It("drains the node and reports the deploys in flight", func() {
+		Expect(gate.Enter()).To(Succeed())
+
+		Expect(status(request("POST", "/admin/drain", "admin", "secret"))).To(Equal(drain.Status{Draining: true, InFlight: 1}))
+		Expect(gate.Enter()).To(MatchError(drain.DrainingError{}))
+
+		gate.Leave()
+
+		Expect(status(request("GET", "/admin/status", "admin", "secret"))).To(Equal(drain.Status{Draining: true, InFlight: 0}))
+	})
+
+	It("undrains the node", func() {
+		gate.Drain()
+
+		Expect(status(request("POST", "/admin/undrain", "admin", "secret"))).To(Equal(drain.Status{Draining: false, InFlight: 0}))
+		Expect(gate.Enter()).To(Succeed())
+	})
+
+	It("returns unauthorized with invalid credentials", func() {
+		for _, endpoint := range [][]string{{"POST", "/admin/drain"}, {"POST", "/admin/undrain"}, {"GET", "/admin/status"}, {"POST", "/admin/deployments/my-uuid/replay"}, {"GET", "/admin/diagnostics"}} {
+			resp := request(endpoint[0], endpoint[1], "admin", "wrong")
+
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Expect(resp.Header().Get("WWW-Authenticate")).To(Equal(`Basic realm="deployadactyl"`))
+		}
+
+		Expect(gate.Status().Draining).To(BeFalse())
+		Expect(events.EmitCall.TimesCalled).To(Equal(0))
+		Expect(logBuffer).To(Say("invalid credentials for admin drain request"))
+	})
+
+	Describe("diagnosing the dependencies", func() {
+		var (
+			foundation       *httptest.Server
+			foundationStatus int
+		)
+
+		BeforeEach(func() {
+			foundationStatus = http.StatusOK
+			foundation = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(foundationStatus)
+			}))
+
+			controller.Diagnostics = &diagnostics.Checker{Dependencies: []diagnostics.Dependency{
+				{Kind: diagnostics.FoundationDependency, Environment: "production", URL: foundation.URL},
+			}}
+		})
+
+		AfterEach(func() {
+			foundation.Close()
+		})
+
+		report := func(resp *httptest.ResponseRecorder) diagnostics.Report {
+			Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var r diagnostics.Report
+			Expect(json.Unmarshal(resp.Body.Bytes(), &r)).To(Succeed())
+			return r
+		}
+
+		It("reports the status of each dependency", func() {
+			resp := request("GET", "/admin/diagnostics", "admin", "secret")
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			r := report(resp)
+			Expect(r.Status).To(Equal(diagnostics.StatusOK))
+			Expect(r.Dependencies).To(HaveLen(1))
+			Expect(r.Dependencies[0].Environment).To(Equal("production"))
+			Expect(r.Dependencies[0].Status).To(Equal(diagnostics.StatusUp))
+		})
+
+		It("returns http.StatusServiceUnavailable when a dependency is down", func() {
+			foundationStatus = http.StatusBadGateway
+
+			resp := request("GET", "/admin/diagnostics", "admin", "secret")
+
+			Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(report(resp).Dependencies[0].Status).To(Equal(diagnostics.StatusDown))
+			Expect(logBuffer).To(Say("foundation " + foundation.URL + " is down: unexpected response 502 Bad Gateway"))
+		})
+	})
+
+	Describe("replaying a deploy", func() {
+		var info S.DeploymentInfo
+
+		BeforeEach(func() {
+			info = S.DeploymentInfo{UUID: "my-uuid", Environment: "prod", Org: "my-org", Space: "my-space", AppName: "my-app", Reason: "hotfix"}
+			data := &S.DeployEventData{DeploymentInfo: &info, RequestBody: bytes.NewBufferString("the artifact")}
+
+			history.OnEvent(I.Event{Type: constants.DeployStartEvent, Data: data})
+			history.OnEvent(I.Event{Type: constants.DeploySuccessEvent, Data: data})
+			history.OnEvent(I.Event{Type: constants.DeployFinishEvent, Data: data})
+		})
+
+		It("emits the recorded events as replays", func() {
+			resp := request("POST", "/admin/deployments/my-uuid/replay", "admin", "secret")
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(ContainSubstring("replayed 3 events of deploy my-uuid"))
+
+			replayed := &S.DeployEventData{DeploymentInfo: &info, Response: &bytes.Buffer{}, RequestBody: &bytes.Buffer{}}
+			Expect(events.EmitCall.Received.Events).To(Equal([]I.Event{
+				{Type: constants.DeployStartEvent, Data: replayed, Replay: true},
+				{Type: constants.DeploySuccessEvent, Data: replayed, Replay: true},
+				{Type: constants.DeployFinishEvent, Data: replayed, Replay: true},
+			}))
+		})
+
+		It("emits the typed events of the recorded events as replays", func() {
+			request("POST", "/admin/deployments/my-uuid/replay", "admin", "secret")
+
+			Expect(events.EmitEventCall.Received.Events).To(HaveLen(3))
+
+			started, ok := events.EmitEventCall.Received.Events[0].(push.DeployStartedEvent)
+			Expect(ok).To(BeTrue())
+			Expect(started.Replay).To(BeTrue())
+			Expect(started.CFContext).To(Equal(I.CFContext{Environment: "prod", Organization: "my-org", Space: "my-space", Application: "my-app"}))
+			Expect(started.Reason).To(Equal("hotfix"))
+			Expect(started.Body).To(Equal(&bytes.Buffer{}))
+
+			succeeded, ok := events.EmitEventCall.Received.Events[1].(push.DeploySuccessEvent)
+			Expect(ok).To(BeTrue())
+			Expect(succeeded.Replay).To(BeTrue())
+
+			finished, ok := events.EmitEventCall.Received.Events[2].(push.DeployFinishedEvent)
+			Expect(ok).To(BeTrue())
+			Expect(finished.Replay).To(BeTrue())
+		})
+
+		It("returns not found for a deploy without recorded events", func() {
+			resp := request("POST", "/admin/deployments/other-uuid/replay", "admin", "secret")
+
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+			Expect(resp.Body.String()).To(ContainSubstring(deployer.DeploymentHistoryNotFoundError{UUID: "other-uuid"}.Error()))
+			Expect(events.EmitCall.TimesCalled).To(Equal(0))
+		})
+
+		It("returns an error when an event cannot be emitted", func() {
+			events.EmitCall.Returns.Error = []error{nil, errors.New("emit failed")}
+
+			resp := request("POST", "/admin/deployments/my-uuid/replay", "admin", "secret")
+
+			Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+			Expect(resp.Body.String()).To(ContainSubstring("emit failed"))
+			Expect(events.EmitCall.TimesCalled).To(Equal(2))
+		})
+	})
+})
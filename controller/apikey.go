@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader is the header clients send their API key in. The Authorization header is left to the Cloud Foundry
+// credentials.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyContextKey is the key of the authenticated API key in the gin context of a request.
+const apiKeyContextKey = "apiKey"
+
+// APIKeyAuth returns middleware that authenticates clients with the key in the X-API-Key header when API keys are
+// configured. A request without a key or with an unknown key gets a 401.
+//
+// A key limited to environments gets a 403 for a request to another environment or one that does not name an
+// environment, like the status and admin endpoints. A short deploy URL whose first param is not an environment is
+// checked against the default environment. Any other URL that names an unknown environment is checked against that
+// name, so it is rejected.
+func APIKeyAuth(keys []config.APIKey, environments map[string]S.Environment, defaultEnvironment string, log I.Logger) gin.HandlerFunc {
+	hashes := make(map[string]config.APIKey, len(keys))
+	for _, key := range keys {
+		hashes[key.Hash] = key
+	}
+
+	return func(g *gin.Context) {
+		if len(hashes) == 0 {
+			g.Next()
+			return
+		}
+
+		key := g.Request.Header.Get(APIKeyHeader)
+		if key == "" {
+			log.Errorf("request %s %s rejected: no api key", g.Request.Method, g.Request.URL.Path)
+			rejectRequest(g, http.StatusUnauthorized, deployer.APIKeyRequiredError{})
+			return
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		apiKey, ok := hashes[hex.EncodeToString(sum[:])]
+		if !ok {
+			log.Errorf("request %s %s rejected: invalid api key", g.Request.Method, g.Request.URL.Path)
+			rejectRequest(g, http.StatusUnauthorized, deployer.InvalidAPIKeyError{})
+			return
+		}
+
+		if len(apiKey.Environments) > 0 {
+			environment := requestEnvironment(g, environments, defaultEnvironment)
+			if !allowsEnvironment(apiKey, environment) {
+				err := deployer.APIKeyNotAllowedError{apiKey.Name, environment}
+				log.Errorf("request %s %s rejected: %s", g.Request.Method, g.Request.URL.Path, err)
+				rejectRequest(g, http.StatusForbidden, err)
+				return
+			}
+		}
+
+		log.Debugf("request %s %s authenticated with api key %s", g.Request.Method, g.Request.URL.Path, apiKey.Name)
+		g.Set(apiKeyContextKey, apiKey)
+		g.Next()
+	}
+}
+
+// requestAPIKey returns the API key a request was authenticated with. It returns false when no API keys are
+// configured.
+func requestAPIKey(g *gin.Context) (config.APIKey, bool) {
+	value, ok := g.Get(apiKeyContextKey)
+	if !ok {
+		return config.APIKey{}, false
+	}
+	apiKey, ok := value.(config.APIKey)
+	return apiKey, ok
+}
+
+// requestEnvironment returns the environment a request is for, or an empty string when it does not name one. Only a
+// short deploy URL falls back to the default environment when its first param is not an environment.
+func requestEnvironment(g *gin.Context, environments map[string]S.Environment, defaultEnvironment string) string {
+	environment := g.Param("environment")
+	if environment == "" {
+		return environment
+	}
+
+	name, ok := environmentName(environments, environment)
+	if ok {
+		return name
+	}
+	if defaultEnvironment != "" && shortDeployRequest(g) {
+		return defaultEnvironment
+	}
+	return environment
+}
+
+// shortDeployRequest reports whether the request is a push to /v2/deploy/:environment/:org/:space, whose first param
+// is an org when it is not an environment, or to /v2/deploy/:environment, whose only param is then an application.
+func shortDeployRequest(g *gin.Context) bool {
+	if g.Request.Method != http.MethodPost || !strings.HasPrefix(g.Request.URL.Path, "/v2/deploy/") {
+		return false
+	}
+
+	return len(g.Params) == 1 || len(g.Params) == 3
+}
+
+func allowsEnvironment(apiKey config.APIKey, environment string) bool {
+	for _, allowed := range apiKey.Environments {
+		if allowed == environment {
+			return true
+		}
+	}
+	return false
+}
+
+func rejectRequest(g *gin.Context, statusCode int, err error) {
+	g.Writer.WriteHeader(statusCode)
+	fmt.Fprintln(g.Writer, err)
+	g.Abort()
+}
@@ -0,0 +1,174 @@
+package controller_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("APIKeyAuth", func() {
+	var (
+		router       *gin.Engine
+		logger       I.Logger
+		logBuffer    *Buffer
+		keys         []config.APIKey
+		environments map[string]S.Environment
+	)
+
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+
+	request := func(path, key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		logger = I.DefaultLogger(logBuffer, logging.DEBUG, "apikey_test")
+
+		keys = []config.APIKey{
+			{Name: "ci", Hash: hash("ci-key")},
+			{Name: "team", Hash: hash("team-key"), Environments: []string{"dev"}},
+		}
+		environments = map[string]S.Environment{"dev": {Name: "dev"}, "prod": {Name: "prod"}}
+	})
+
+	JustBeforeEach(func() {
+		router = gin.New()
+		router.Use(APIKeyAuth(keys, environments, "prod", logger))
+		handler := func(g *gin.Context) {
+			fmt.Fprint(g.Writer, "deployed")
+		}
+		router.POST("/v2/deploy/:environment/:org/:space/:appName", handler)
+		router.POST("/v2/deploy/:environment/:org/:space", handler)
+		router.POST("/v2/deploy/:environment", handler)
+		router.DELETE("/v2/deploy/:environment/:org/:space/:appName", handler)
+		router.POST("/admin/drain", handler)
+	})
+
+	It("rejects a request without an api key with a 401", func() {
+		resp := request("/v2/deploy/dev/org/space/app", "")
+
+		Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+		Expect(resp.Body.String()).To(ContainSubstring("an api key is required"))
+		Expect(logBuffer).To(Say("rejected: no api key"))
+	})
+
+	It("rejects a request with an unknown api key with a 401", func() {
+		resp := request("/v2/deploy/dev/org/space/app", "other-key")
+
+		Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+		Expect(resp.Body.String()).To(ContainSubstring("invalid api key"))
+	})
+
+	It("lets a request with a valid api key through", func() {
+		resp := request("/v2/deploy/prod/org/space/app", "ci-key")
+
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(Equal("deployed"))
+		Expect(logBuffer).To(Say("authenticated with api key ci"))
+	})
+
+	Context("when the api key is limited to environments", func() {
+		It("lets a request to an allowed environment through", func() {
+			resp := request("/v2/deploy/dev/org/space/app", "team-key")
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+		})
+
+		It("matches the environment whatever its case", func() {
+			resp := request("/v2/deploy/DEV/org/space/app", "team-key")
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+		})
+
+		It("rejects a request to another environment with a 403", func() {
+			resp := request("/v2/deploy/prod/org/space/app", "team-key")
+
+			Expect(resp.Code).To(Equal(http.StatusForbidden))
+			Expect(resp.Body.String()).To(ContainSubstring("api key team is not allowed in environment prod"))
+		})
+
+		It("checks a deploy to the default environment against it", func() {
+			resp := request("/v2/deploy/org/space/app", "team-key")
+
+			Expect(resp.Code).To(Equal(http.StatusForbidden))
+			Expect(resp.Body.String()).To(ContainSubstring("environment prod"))
+		})
+
+		It("checks a deploy of an application to the default environment against it", func() {
+			resp := request("/v2/deploy/app", "team-key")
+
+			Expect(resp.Code).To(Equal(http.StatusForbidden))
+			Expect(resp.Body.String()).To(ContainSubstring("environment prod"))
+		})
+
+		It("rejects a request to an unknown environment with a 403 that names it", func() {
+			resp := request("/v2/deploy/staging/org/space/app", "team-key")
+
+			Expect(resp.Code).To(Equal(http.StatusForbidden))
+			Expect(resp.Body.String()).To(ContainSubstring("api key team is not allowed in environment staging"))
+		})
+
+		Context("to the default environment", func() {
+			BeforeEach(func() {
+				keys = append(keys, config.APIKey{Name: "release", Hash: hash("release-key"), Environments: []string{"prod"}})
+			})
+
+			It("lets a short deploy through", func() {
+				resp := request("/v2/deploy/org/space/app", "release-key")
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+			})
+
+			It("rejects a request to an unknown environment on any other route with a 403", func() {
+				req, _ := http.NewRequest("DELETE", "/v2/deploy/staging/org/space/app", nil)
+				req.Header.Set(APIKeyHeader, "release-key")
+				resp := httptest.NewRecorder()
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusForbidden))
+				Expect(resp.Body.String()).To(ContainSubstring("api key release is not allowed in environment staging"))
+			})
+		})
+
+		It("rejects a request that does not name an environment with a 403", func() {
+			resp := request("/admin/drain", "team-key")
+
+			Expect(resp.Code).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("when no api keys are configured", func() {
+		BeforeEach(func() {
+			keys = nil
+		})
+
+		It("lets every request through", func() {
+			resp := request("/admin/drain", "")
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+		})
+	})
+})
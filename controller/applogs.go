@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/compozed/deployadactyl/applogs"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/state"
+	"github.com/gin-gonic/gin"
+)
+
+// AppLogsHandler returns the last lines of the recent Cloud Foundry logs of an application as text. The lines query
+// param chooses how many. With follow=true the logs are streamed instead until the client disconnects.
+func (c *Controller) AppLogsHandler(g *gin.Context) {
+	cf := getCFContext(g)
+	c.Log.Debugf("app logs request for %s originated from: %+v", cf.Application, g.Request.RemoteAddr)
+
+	err := validateCFContext(cf)
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	environment, ok := c.Config.Environments[cf.Environment]
+	if !ok {
+		g.Writer.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(g.Writer, deployer.EnvironmentNotFoundError{cf.Environment})
+		return
+	}
+
+	lines, follow, err := c.getAppLogsParams(g)
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	auth := getAuthorization(g)
+	if auth.Username == "" && auth.Password == "" {
+		auth.Username, auth.Password, ok = c.Config.FallbackAuthorization(environment)
+		if !ok {
+			g.Writer.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(g.Writer, deployer.BasicAuthError{})
+			return
+		}
+	}
+
+	request := applogs.Request{
+		Environment:   environment,
+		Org:           cf.Organization,
+		Space:         cf.Space,
+		AppName:       cf.Application,
+		Authorization: auth,
+	}
+
+	g.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if follow {
+		c.Log.Infof("following the logs of %s in environment %s", cf.Application, cf.Environment)
+
+		err = c.AppLogs.Follow(g.Request.Context(), request, g.Writer)
+		if err != nil {
+			c.Log.Error(err)
+			if !g.Writer.Written() {
+				g.Writer.WriteHeader(appLogsStatusCode(err))
+			}
+			fmt.Fprintln(g.Writer, err)
+		}
+		return
+	}
+
+	logs := &bytes.Buffer{}
+	err = c.AppLogs.Recent(request, lines, logs)
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(appLogsStatusCode(err))
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	g.Writer.WriteHeader(http.StatusOK)
+	logs.WriteTo(g.Writer)
+}
+
+// getAppLogsParams returns the lines and follow query params of an app logs request. Lines defaults to the lines of
+// the app logs config.
+func (c *Controller) getAppLogsParams(g *gin.Context) (int, bool, error) {
+	lines := c.Config.AppLogs.Lines
+	if value, ok := g.GetQuery("lines"); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > c.Config.AppLogs.MaxLines {
+			return 0, false, deployer.InvalidLogLinesError{value, c.Config.AppLogs.MaxLines}
+		}
+		lines = parsed
+	}
+
+	follow := false
+	if value, ok := g.GetQuery("follow"); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return 0, false, deployer.InvalidFollowError{value}
+		}
+		follow = parsed
+	}
+
+	return lines, follow, nil
+}
+
+func appLogsStatusCode(err error) int {
+	switch err.(type) {
+	case state.LoginError:
+		return http.StatusBadRequest
+	case state.ExistsError:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
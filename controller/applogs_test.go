@@ -0,0 +1,157 @@
+package controller_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/compozed/deployadactyl/applogs"
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+type appLogsCourierCreator struct {
+	courier *mocks.Courier
+}
+
+func (c appLogsCourierCreator) CreateCourier() (I.Courier, error) {
+	return c.courier, nil
+}
+
+var _ = Describe("AppLogsHandler", func() {
+	var (
+		controller *Controller
+		courier    *mocks.Courier
+		router     *gin.Engine
+		logBuffer  *Buffer
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		router = gin.New()
+		courier = &mocks.Courier{}
+		courier.ExistsCall.Returns.Bool = true
+		courier.LogsCall.Returns.Output = []byte("line 1\nline 2\nline 3\n")
+
+		log := I.DefaultLogger(logBuffer, logging.DEBUG, "applogs_test")
+		controller = &Controller{
+			Log: log,
+			Config: config.Config{
+				Username: "admin",
+				Password: "secret",
+				Environments: map[string]S.Environment{
+					"prod": {Name: "prod", Foundations: []string{"https://api.example.com"}},
+				},
+				AppLogs: config.AppLogsConfig{Lines: 2, MaxLines: 100},
+			},
+			AppLogs: applogs.Fetcher{CourierCreator: appLogsCourierCreator{courier}, Log: log},
+		}
+
+		router.GET("/v2/deploy/:environment/:org/:space/:appName/applogs", controller.AppLogsHandler)
+	})
+
+	getLogs := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	It("returns the configured number of lines of the recent logs as text", func() {
+		resp := getLogs("/v2/deploy/prod/org/space/myApp/applogs")
+
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+		Expect(resp.Body.String()).To(Equal("line 2\nline 3\n"))
+		Expect(courier.LoginCall.Received.Username).To(Equal("admin"))
+		Expect(courier.LoginCall.Received.Org).To(Equal("org"))
+		Expect(courier.LogsCall.Received.AppName).To(Equal("myApp"))
+	})
+
+	It("returns the number of lines the request asks for", func() {
+		resp := getLogs("/v2/deploy/prod/org/space/myApp/applogs?lines=3")
+
+		Expect(resp.Body.String()).To(Equal("line 1\nline 2\nline 3\n"))
+	})
+
+	It("logs in with the basic auth of the request", func() {
+		req, err := http.NewRequest("GET", "/v2/deploy/prod/org/space/myApp/applogs", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.SetBasicAuth("user", "password")
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		Expect(courier.LoginCall.Received.Username).To(Equal("user"))
+		Expect(courier.LoginCall.Received.Password).To(Equal("password"))
+	})
+
+	It("streams the logs when following", func() {
+		courier.StreamLogsCall.Write.Output = "streamed 1\nstreamed 2\n"
+
+		resp := getLogs("/v2/deploy/prod/org/space/myApp/applogs?follow=true")
+
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(Equal("streamed 1\nstreamed 2\n"))
+		Expect(courier.StreamLogsCall.Received.AppName).To(Equal("myApp"))
+		Eventually(logBuffer).Should(Say("following the logs of myApp in environment prod"))
+	})
+
+	It("returns http.StatusBadRequest for lines that are not a number in range", func() {
+		for _, lines := range []string{"many", "0", "101"} {
+			resp := getLogs("/v2/deploy/prod/org/space/myApp/applogs?lines=" + lines)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(ContainSubstring("must be a number from 1 to 100"))
+		}
+	})
+
+	It("returns http.StatusBadRequest for an invalid follow", func() {
+		resp := getLogs("/v2/deploy/prod/org/space/myApp/applogs?follow=sometimes")
+
+		Expect(resp.Code).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body.String()).To(ContainSubstring(`invalid follow "sometimes"`))
+	})
+
+	It("returns http.StatusNotFound for an unknown environment", func() {
+		resp := getLogs("/v2/deploy/staging/org/space/myApp/applogs")
+
+		Expect(resp.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns http.StatusNotFound when the application does not exist", func() {
+		courier.ExistsCall.Returns.Bool = false
+
+		resp := getLogs("/v2/deploy/prod/org/space/myApp/applogs")
+
+		Expect(resp.Code).To(Equal(http.StatusNotFound))
+		Expect(resp.Body.String()).To(ContainSubstring("app myApp doesn't exist"))
+	})
+
+	It("returns http.StatusBadRequest when it cannot log in", func() {
+		courier.LoginCall.Returns.Error = errors.New("login error")
+
+		resp := getLogs("/v2/deploy/prod/org/space/myApp/applogs?follow=true")
+
+		Expect(resp.Code).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body.String()).To(ContainSubstring("cannot login to https://api.example.com"))
+	})
+
+	It("returns http.StatusUnauthorized when the environment has no credentials for the request", func() {
+		controller.Config.Environments["prod"] = S.Environment{Name: "prod", Authenticate: true, Foundations: []string{"https://api.example.com"}}
+
+		resp := getLogs("/v2/deploy/prod/org/space/myApp/applogs")
+
+		Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+		Expect(courier.LoginCall.TimesCalled).To(Equal(0))
+	})
+})
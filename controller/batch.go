@@ -0,0 +1,527 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state/push"
+	"github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+)
+
+// Modes of a batch deploy.
+const (
+	// BatchBestEffort deploys each application on its own. The applications that succeed stay deployed.
+	BatchBestEffort = "best-effort"
+	// BatchAllOrNothing pushes each application as a manual cutover candidate. The candidates are promoted once
+	// every application was pushed, otherwise they are deleted. When a promotion fails, the applications already
+	// promoted are rolled back, so it needs an environment that keeps replaced applications for a delete delay.
+	BatchAllOrNothing = "all-or-nothing"
+)
+
+// BatchStatusPartial is the status of a best effort batch deploy in which only some of the applications succeeded.
+const BatchStatusPartial = "partial"
+
+// Statuses of the applications of a batch deploy.
+const (
+	BatchApplicationNotPushed  = "not pushed"
+	BatchApplicationPending    = "waiting for promotion"
+	BatchApplicationFailed     = "failed"
+	BatchApplicationRolledBack = "rolled back"
+	BatchApplicationDeployed   = "deployed"
+)
+
+// batchApplicationField is the field of an app spec that names its application.
+const batchApplicationField = "application"
+
+// BatchResult is the body of the response to a batch deploy.
+type BatchResult struct {
+	Status        string                   `json:"status"`
+	Mode          string                   `json:"mode"`
+	FailurePolicy string                   `json:"failure_policy"`
+	Applications  []BatchApplicationResult `json:"applications"`
+
+	// Succeeded, Failed, Skipped and RolledBack name the applications by what happened to them. Skipped are the
+	// applications that were not pushed because of the fail-fast failure policy.
+	Succeeded  []string `json:"succeeded,omitempty"`
+	Failed     []string `json:"failed,omitempty"`
+	Skipped    []string `json:"skipped,omitempty"`
+	RolledBack []string `json:"rolled_back,omitempty"`
+}
+
+// BatchApplicationResult is what happened to one application of a batch deploy. Output is the deploy output.
+type BatchApplicationResult struct {
+	Application string `json:"application"`
+	UUID        string `json:"uuid,omitempty"`
+	Status      string `json:"status"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Output      string `json:"output,omitempty"`
+}
+
+// batchApplication is an application of a batch deploy with the JSON push body of its app spec.
+type batchApplication struct {
+	body []byte
+	log  I.DeploymentLogger
+
+	// manualCutover is set when the app spec asks for a manual cutover, so its candidate is not promoted.
+	manualCutover bool
+	// candidate is set when the application is pushed as a candidate, for a manual cutover or all-or-nothing mode.
+	candidate bool
+
+	result BatchApplicationResult
+}
+
+// BatchDeploymentHandler deploys the applications of a JSON array of app specs to /v2/deploy-batch/:environment/:org/:space.
+// An app spec is the JSON body of a push with the name of its application in application. The applications are
+// deployed BatchDeploy.Concurrency at a time in the mode of the mode query param, best-effort by default. The
+// failurePolicy query param decides whether the applications are still pushed after one fails. It is continue
+// by default in best-effort mode and fail-fast in all-or-nothing mode. Like a push, the body must be
+// application/json and the skipSSL query param overrides the skip_ssl of the environment.
+func (c *Controller) BatchDeploymentHandler(g *gin.Context) {
+	log, err := c.deploymentLogger(g.Request.Header.Get(UUIDHeader), getCFContext(g))
+	if err != nil {
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+	trackRequest(g, log.UUID, ReadingRequestPhase)
+	log.Debugf("batch deploy request originated from: %+v", g.Request.RemoteAddr)
+	c.logRequest(g, log)
+
+	if !c.allowChange(g, log) {
+		return
+	}
+
+	deploymentType, _, err := getDeploymentType(g, c.ArtifactTypes)
+	if err != nil || !deploymentType.JSON {
+		err = deployer.UnsupportedBatchMediaTypeError{g.Request.Header.Get("Content-Type")}
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusUnsupportedMediaType)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	authorization := getAuthorization(g)
+
+	skipSSL, err := c.getSkipSSL(g, authorization)
+	if err != nil {
+		log.Error(err)
+
+		statusCode := http.StatusBadRequest
+		if _, ok := err.(deployer.SkipSSLOverrideForbiddenError); ok {
+			statusCode = http.StatusForbidden
+		}
+		g.Writer.WriteHeader(statusCode)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	mode := g.Query("mode")
+	if mode == "" {
+		mode = BatchBestEffort
+	}
+
+	environment, ok := c.Config.Environments[getCFContext(g).Environment]
+	if ok && mode == BatchAllOrNothing && environment.DeleteDelaySeconds <= 0 {
+		err = deployer.AllOrNothingWithoutDeleteDelayError{environment.Name}
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	failurePolicy, err := getFailurePolicy(g)
+	if err != nil {
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+	if failurePolicy == "" {
+		failurePolicy = structs.FailurePolicyContinue
+		if mode == BatchAllOrNothing {
+			failurePolicy = structs.FailurePolicyFailFast
+		}
+	}
+
+	leave, ok := c.enterDrain(g, log)
+	if !ok {
+		return
+	}
+	defer leave()
+
+	bodyBuffer, _ := ioutil.ReadAll(g.Request.Body)
+	g.Request.Body.Close()
+
+	applications, err := c.getBatchApplications(bodyBuffer, mode)
+	if err != nil {
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	template := I.Deployment{
+		Authorization:  authorization,
+		CFContext:      getCFContext(g),
+		Type:           deploymentType,
+		NoCache:        g.Query("noCache") == "true",
+		ClientIdentity: getClientIdentity(g),
+		ChangeTicket:   g.Request.Header.Get(ChangeTicketHeader),
+		Reason:         g.Request.Header.Get(ReasonHeader),
+		SkipSSL:        skipSSL,
+		Context:        g.Request.Context(),
+	}
+
+	log.Infof("deploying %d applications in %s mode with the %s failure policy", len(applications), mode, failurePolicy)
+	trackRequest(g, log.UUID, DeployingPhase)
+	c.deployBatch(template, failurePolicy, applications)
+
+	if mode == BatchAllOrNothing {
+		c.finishAllOrNothing(template, applications)
+	}
+
+	result, statusCode := batchResult(mode, failurePolicy, applications)
+	log.Infof("batch deploy %s", result.Status)
+
+	body, _ := json.Marshal(result)
+
+	g.Writer.Header().Set("Content-Type", "application/json")
+	g.Writer.WriteHeader(statusCode)
+	g.Writer.Write(body)
+}
+
+// getBatchApplications reads the app specs of a batch deploy. In all-or-nothing mode, manual_cutover is turned on
+// in each of them.
+func (c *Controller) getBatchApplications(body []byte, mode string) ([]*batchApplication, error) {
+	if mode != BatchBestEffort && mode != BatchAllOrNothing {
+		return nil, deployer.InvalidBatchModeError{mode}
+	}
+
+	var specs []map[string]json.RawMessage
+	err := json.Unmarshal(body, &specs)
+	if err != nil {
+		return nil, deployer.InvalidBatchError{fmt.Sprintf("the body must be a JSON array of app specs: %s", err)}
+	}
+	if len(specs) == 0 {
+		return nil, deployer.InvalidBatchError{"there are no app specs"}
+	}
+
+	manualCutoverField := c.Config.JSONFields["manual_cutover"]
+	if manualCutoverField == "" {
+		manualCutoverField = "manual_cutover"
+	}
+
+	applications := make([]*batchApplication, 0, len(specs))
+	names := map[string]bool{}
+	for i, spec := range specs {
+		var name string
+		json.Unmarshal(spec[batchApplicationField], &name)
+		if name == "" {
+			return nil, deployer.InvalidBatchError{fmt.Sprintf("app spec %d has no application", i)}
+		}
+		if names[name] {
+			return nil, deployer.InvalidBatchError{fmt.Sprintf("application %s is in more than one app spec", name)}
+		}
+		names[name] = true
+		delete(spec, batchApplicationField)
+
+		application := &batchApplication{result: BatchApplicationResult{Application: name}}
+		json.Unmarshal(spec[manualCutoverField], &application.manualCutover)
+		if _, ok := spec[manualCutoverField]; !ok {
+			json.Unmarshal(spec["manual_cutover"], &application.manualCutover)
+		}
+
+		application.candidate = application.manualCutover
+		if mode == BatchAllOrNothing {
+			spec[manualCutoverField] = json.RawMessage("true")
+			application.candidate = true
+		}
+
+		application.body, _ = json.Marshal(spec)
+		applications = append(applications, application)
+	}
+
+	return applications, nil
+}
+
+// deployBatch deploys the applications in order, BatchDeploy.Concurrency at a time. With the fail-fast failure
+// policy, the applications that have not started when one fails are not pushed.
+func (c *Controller) deployBatch(template I.Deployment, failurePolicy string, applications []*batchApplication) {
+	concurrency := c.Config.BatchDeploy.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		failed bool
+		mutex  sync.Mutex
+		wg     sync.WaitGroup
+	)
+
+	next := make(chan *batchApplication)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for application := range next {
+				mutex.Lock()
+				skip := failed && failurePolicy == structs.FailurePolicyFailFast
+				mutex.Unlock()
+
+				if skip {
+					application.result.Status = BatchApplicationNotPushed
+					continue
+				}
+
+				c.deployBatchApplication(template, application)
+
+				if application.result.Status == BatchApplicationFailed {
+					mutex.Lock()
+					failed = true
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, application := range applications {
+		next <- application
+	}
+	close(next)
+	wg.Wait()
+}
+
+// deployBatchApplication deploys one application of a batch deploy with its own deployment UUID and log. Like a
+// push, it holds a place in the Limiter of concurrent deployments while it runs, and fails with a 429 without one.
+func (c *Controller) deployBatchApplication(template I.Deployment, application *batchApplication) {
+	deployment := template
+	deployment.CFContext.Application = application.result.Application
+	deployment.Body = &application.body
+
+	log, err := c.deploymentLogger("", deployment.CFContext)
+	if err != nil {
+		application.fail(http.StatusBadRequest, err)
+		return
+	}
+	application.log = log
+	application.result.UUID = log.UUID
+
+	err = c.checkCooldown(deployment.CFContext)
+	if err != nil {
+		log.Error(err)
+		application.fail(http.StatusTooManyRequests, err)
+		return
+	}
+
+	release, err := c.acquirePlace(deployment.Context)
+	if err != nil {
+		log.Error(err)
+		application.fail(http.StatusTooManyRequests, err)
+		return
+	}
+	defer release()
+
+	span := c.Tracer.StartDeploy(log.UUID, deployment.CFContext)
+	logFile := c.openDeploymentLog(&application.log)
+	response := &bytes.Buffer{}
+
+	deployResponse := c.PushControllerFactory(application.log).RunDeployment(&deployment, response)
+
+	closeDeploymentLog(logFile, response)
+	span.EndWithStatus(deployResponse.StatusCode)
+	application.result.Output = response.String()
+
+	if deployResponse.Error != nil {
+		application.fail(deployResponse.StatusCode, deployResponse.Error)
+		return
+	}
+
+	application.result.StatusCode = deployResponse.StatusCode
+	application.result.Status = BatchApplicationDeployed
+	if application.candidate || c.Config.Environments[deployment.CFContext.Environment].ManualCutover {
+		application.result.Status = BatchApplicationPending
+	}
+}
+
+// finishAllOrNothing promotes the candidates of an all-or-nothing batch deploy when every application was pushed,
+// which includes the health check of its candidate. Candidates of applications that asked for a manual cutover are
+// left waiting for promotion. When any application failed, including a failed promotion, the applications already
+// promoted are rolled back and the candidates that were not promoted are deleted, with the candidate whose promotion
+// failed.
+func (c *Controller) finishAllOrNothing(template I.Deployment, applications []*batchApplication) {
+	environment := c.Config.Environments[template.CFContext.Environment]
+
+	var (
+		promoted        = []*batchApplication{}
+		failedPromotion *batchApplication
+	)
+	if !batchFailed(applications) {
+		for _, application := range applications {
+			if application.result.Status != BatchApplicationPending || application.manualCutover || environment.ManualCutover {
+				continue
+			}
+
+			c.promoteBatchCandidate(template, application)
+			if application.result.Status == BatchApplicationFailed {
+				failedPromotion = application
+				break
+			}
+			promoted = append(promoted, application)
+		}
+	}
+
+	if !batchFailed(applications) {
+		return
+	}
+
+	for _, application := range promoted {
+		c.rollbackBatchApplication(template, application)
+	}
+
+	if failedPromotion != nil {
+		err := c.deleteBatchCandidate(template, failedPromotion)
+		if err != nil {
+			failedPromotion.result.Error += "; " + err.Error()
+		}
+	}
+
+	for _, application := range applications {
+		if application.result.Status != BatchApplicationPending {
+			continue
+		}
+
+		err := c.deleteBatchCandidate(template, application)
+		if err != nil {
+			application.result.Error = err.Error()
+			continue
+		}
+		application.result.Status = BatchApplicationRolledBack
+	}
+}
+
+// promoteBatchCandidate promotes the candidate of an application of an all-or-nothing batch deploy.
+func (c *Controller) promoteBatchCandidate(template I.Deployment, application *batchApplication) {
+	deployment := template
+	deployment.CFContext.Application = application.result.Application
+	response := &bytes.Buffer{}
+
+	deployResponse := c.PushControllerFactory(application.log).PromoteDeployment(&deployment, application.log.UUID, response)
+	application.result.Output += response.String()
+
+	if deployResponse.Error != nil {
+		application.fail(deployResponse.StatusCode, deployResponse.Error)
+		return
+	}
+
+	application.result.Status = BatchApplicationDeployed
+}
+
+// rollbackBatchApplication brings back the application replaced by the promotion of an application of an
+// all-or-nothing batch deploy. An application that cannot be rolled back, such as one deployed for the first time,
+// stays deployed.
+func (c *Controller) rollbackBatchApplication(template I.Deployment, application *batchApplication) {
+	deployment := template
+	deployment.CFContext.Application = application.result.Application
+	response := &bytes.Buffer{}
+
+	application.log.Infof("rolling back %s", application.result.Application)
+	deployResponse := c.PushControllerFactory(application.log).RollbackDeployment(&deployment, application.log.UUID, response)
+	application.result.Output += response.String()
+
+	if deployResponse.Error != nil {
+		application.log.Errorf("could not roll back %s: %s", application.result.Application, deployResponse.Error)
+		application.result.Error = fmt.Sprintf("could not roll back %s: %s", application.result.Application, deployResponse.Error)
+		return
+	}
+
+	application.result.Status = BatchApplicationRolledBack
+}
+
+// deleteBatchCandidate deletes the candidate of an application of an all-or-nothing batch deploy, with its route.
+// It returns an error when the candidate cannot be deleted.
+func (c *Controller) deleteBatchCandidate(template I.Deployment, application *batchApplication) error {
+	candidate := application.result.Application + push.TemporaryNameSuffix + application.log.UUID
+
+	deployment := template
+	deployment.CFContext.Application = candidate
+	response := &bytes.Buffer{}
+
+	application.log.Infof("deleting candidate %s", candidate)
+	deployResponse := c.DeleteControllerFactory(application.log).DeleteDeployment(&deployment, structs.DeleteOptions{Routes: true}, nil, response)
+	application.result.Output += response.String()
+
+	if deployResponse.Error != nil {
+		err := fmt.Errorf("could not delete candidate %s: %s", candidate, deployResponse.Error)
+		application.log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// fail marks the application as failed with the status code and error of its deploy.
+func (a *batchApplication) fail(statusCode int, err error) {
+	a.result.Status = BatchApplicationFailed
+	a.result.StatusCode = statusCode
+	a.result.Error = err.Error()
+}
+
+// batchFailed reports whether any application of a batch deploy failed.
+func batchFailed(applications []*batchApplication) bool {
+	for _, application := range applications {
+		if application.result.Status == BatchApplicationFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// batchResult returns the result of a batch deploy and its status code. A batch deploy in which every application
+// succeeded returns http.StatusOK and a partial best effort batch deploy http.StatusMultiStatus. A failed batch
+// deploy returns the status code of its first failed application.
+func batchResult(mode, failurePolicy string, applications []*batchApplication) (BatchResult, int) {
+	result := BatchResult{Status: deployer.DeploymentSucceeded, Mode: mode, FailurePolicy: failurePolicy}
+
+	statusCode := 0
+	for _, application := range applications {
+		result.Applications = append(result.Applications, application.result)
+
+		name := application.result.Application
+		switch application.result.Status {
+		case BatchApplicationDeployed, BatchApplicationPending:
+			result.Succeeded = append(result.Succeeded, name)
+		case BatchApplicationFailed:
+			result.Failed = append(result.Failed, name)
+			if statusCode == 0 {
+				statusCode = application.result.StatusCode
+			}
+		case BatchApplicationNotPushed:
+			result.Skipped = append(result.Skipped, name)
+		case BatchApplicationRolledBack:
+			result.RolledBack = append(result.RolledBack, name)
+		}
+	}
+
+	switch {
+	case !batchFailed(applications):
+		return result, http.StatusOK
+	case mode == BatchBestEffort && len(result.Succeeded) > 0:
+		result.Status = BatchStatusPartial
+		return result, http.StatusMultiStatus
+	}
+
+	result.Status = deployer.DeploymentFailed
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	return result, statusCode
+}
@@ -0,0 +1,422 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller"
+	"github.com/compozed/deployadactyl/controller/deployer/drain"
+	"github.com/compozed/deployadactyl/controller/deployer/limiter"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("BatchDeploymentHandler", func() {
+	var (
+		controller       *Controller
+		pushController   *mocks.PushController
+		deleteController *mocks.DeleteController
+		router           *gin.Engine
+		logBuffer        *Buffer
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		router = gin.New()
+		pushController = &mocks.PushController{}
+		deleteController = &mocks.DeleteController{}
+
+		pushController.RunDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusOK}
+		pushController.PromoteDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusOK}
+		pushController.RollbackDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusOK}
+		deleteController.DeleteDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusOK}
+
+		controller = &Controller{
+			Log: I.DefaultLogger(logBuffer, logging.DEBUG, "batch_test"),
+			Config: config.Config{
+				Environments: map[string]S.Environment{"prod": {Name: "prod", DeleteDelaySeconds: 600}},
+				BatchDeploy:  config.BatchDeployConfig{Concurrency: 1},
+			},
+			PushControllerFactory: func(log I.DeploymentLogger) I.PushController {
+				return pushController
+			},
+			DeleteControllerFactory: func(log I.DeploymentLogger) I.DeleteController {
+				return deleteController
+			},
+		}
+
+		router.POST("/v2/deploy-batch/:environment/:org/:space", controller.BatchDeploymentHandler)
+	})
+
+	contentType := "application/json"
+
+	deployBatch := func(query, body string) (*httptest.ResponseRecorder, BatchResult) {
+		req, err := http.NewRequest("POST", "/v2/deploy-batch/prod/org/space"+query, bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", contentType)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		result := BatchResult{}
+		json.Unmarshal(resp.Body.Bytes(), &result)
+		return resp, result
+	}
+
+	specs := `[{"application": "api", "artifact_url": "https://example.com/api.jar"}, {"application": "web", "artifact_url": "https://example.com/web.jar"}]`
+
+	It("deploys each application with its own app spec", func() {
+		resp, result := deployBatch("", specs)
+
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(result.Status).To(Equal("succeeded"))
+		Expect(result.Mode).To(Equal(BatchBestEffort))
+		Expect(pushController.RunDeploymentCall.Received.Applications).To(Equal([]string{"api", "web"}))
+
+		Expect(result.Applications).To(HaveLen(2))
+		Expect(result.Applications[0].Application).To(Equal("api"))
+		Expect(result.Applications[0].Status).To(Equal(BatchApplicationDeployed))
+		Expect(result.Applications[0].UUID).ToNot(BeEmpty())
+		Expect(result.Applications[1].Application).To(Equal("web"))
+		Expect(result.Applications[1].Status).To(Equal(BatchApplicationDeployed))
+
+		deployment := pushController.RunDeploymentCall.Received.Deployment
+		Expect(deployment.Type.JSON).To(BeTrue())
+		Expect(deployment.CFContext.Environment).To(Equal("prod"))
+		Expect(string(*deployment.Body)).To(MatchJSON(`{"artifact_url": "https://example.com/web.jar"}`))
+	})
+
+	Context("when some applications fail in best-effort mode", func() {
+		It("reports a partial success", func() {
+			pushController.RunDeploymentCall.Returns.DeployResponses = map[string]I.DeployResponse{
+				"api": {StatusCode: http.StatusInternalServerError, Error: errors.New("push failed")},
+			}
+
+			resp, result := deployBatch("?mode=best-effort", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusMultiStatus))
+			Expect(result.Status).To(Equal(BatchStatusPartial))
+			Expect(result.Applications[0].Status).To(Equal(BatchApplicationFailed))
+			Expect(result.Applications[0].StatusCode).To(Equal(http.StatusInternalServerError))
+			Expect(result.Applications[0].Error).To(Equal("push failed"))
+			Expect(result.Applications[1].Status).To(Equal(BatchApplicationDeployed))
+			Expect(deleteController.DeleteDeploymentCall.Called).To(BeFalse())
+
+			Expect(result.FailurePolicy).To(Equal("continue"))
+			Expect(result.Succeeded).To(Equal([]string{"web"}))
+			Expect(result.Failed).To(Equal([]string{"api"}))
+			Expect(result.Skipped).To(BeEmpty())
+		})
+
+		It("does not push the rest with the fail-fast failure policy", func() {
+			pushController.RunDeploymentCall.Returns.DeployResponses = map[string]I.DeployResponse{
+				"web": {StatusCode: http.StatusInternalServerError, Error: errors.New("push failed")},
+			}
+
+			resp, result := deployBatch("?failurePolicy=fail-fast", `[{"application": "api"}, {"application": "web"}, {"application": "worker"}]`)
+
+			Expect(resp.Code).To(Equal(http.StatusMultiStatus))
+			Expect(result.Status).To(Equal(BatchStatusPartial))
+			Expect(result.FailurePolicy).To(Equal("fail-fast"))
+			Expect(pushController.RunDeploymentCall.Received.Applications).To(Equal([]string{"api", "web"}))
+
+			Expect(result.Succeeded).To(Equal([]string{"api"}))
+			Expect(result.Failed).To(Equal([]string{"web"}))
+			Expect(result.Skipped).To(Equal([]string{"worker"}))
+		})
+	})
+
+	Context("when every application fails", func() {
+		It("returns the status code of the first failed application", func() {
+			pushController.RunDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusBadRequest, Error: errors.New("bad request")}
+
+			resp, result := deployBatch("", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(result.Status).To(Equal("failed"))
+		})
+	})
+
+	Context("in all-or-nothing mode", func() {
+		It("pushes candidates and promotes them when every application was pushed", func() {
+			resp, result := deployBatch("?mode=all-or-nothing", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(result.Status).To(Equal("succeeded"))
+			Expect(string(*pushController.RunDeploymentCall.Received.Deployment.Body)).To(MatchJSON(`{"artifact_url": "https://example.com/web.jar", "manual_cutover": true}`))
+			Expect(pushController.PromoteDeploymentCall.TimesCalled).To(Equal(2))
+			Expect(pushController.PromoteDeploymentCall.Received.UUID).To(Equal(result.Applications[1].UUID))
+			Expect(result.Applications[0].Status).To(Equal(BatchApplicationDeployed))
+			Expect(result.Applications[1].Status).To(Equal(BatchApplicationDeployed))
+		})
+
+		It("deletes the candidates and does not push the rest when an application fails", func() {
+			pushController.RunDeploymentCall.Returns.DeployResponses = map[string]I.DeployResponse{
+				"web": {StatusCode: http.StatusInternalServerError, Error: errors.New("push failed")},
+			}
+
+			resp, result := deployBatch("?mode=all-or-nothing", `[{"application": "api"}, {"application": "web"}, {"application": "worker"}]`)
+
+			Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+			Expect(result.Status).To(Equal("failed"))
+			Expect(pushController.RunDeploymentCall.Received.Applications).To(Equal([]string{"api", "web"}))
+			Expect(pushController.PromoteDeploymentCall.Called).To(BeFalse())
+
+			Expect(result.Applications[0].Status).To(Equal(BatchApplicationRolledBack))
+			Expect(result.Applications[1].Status).To(Equal(BatchApplicationFailed))
+			Expect(result.Applications[2].Status).To(Equal(BatchApplicationNotPushed))
+			Expect(result.FailurePolicy).To(Equal("fail-fast"))
+			Expect(result.RolledBack).To(Equal([]string{"api"}))
+			Expect(result.Failed).To(Equal([]string{"web"}))
+			Expect(result.Skipped).To(Equal([]string{"worker"}))
+
+			Expect(deleteController.DeleteDeploymentCall.Received.Deployment.CFContext.Application).To(Equal("api-new-build-" + result.Applications[0].UUID))
+			Expect(deleteController.DeleteDeploymentCall.Received.Options.Routes).To(BeTrue())
+			Eventually(logBuffer).Should(Say("deleting candidate api-new-build-"))
+		})
+
+		It("pushes the rest before deleting the candidates with the continue failure policy", func() {
+			pushController.RunDeploymentCall.Returns.DeployResponses = map[string]I.DeployResponse{
+				"api": {StatusCode: http.StatusInternalServerError, Error: errors.New("push failed")},
+			}
+
+			resp, result := deployBatch("?mode=all-or-nothing&failurePolicy=continue", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+			Expect(pushController.RunDeploymentCall.Received.Applications).To(Equal([]string{"api", "web"}))
+			Expect(result.Failed).To(Equal([]string{"api"}))
+			Expect(result.RolledBack).To(Equal([]string{"web"}))
+		})
+
+		It("rolls back the promoted applications and deletes the other candidates when a promotion fails", func() {
+			pushController.PromoteDeploymentCall.Returns.DeployResponses = map[string]I.DeployResponse{
+				"web": {StatusCode: http.StatusInternalServerError, Error: errors.New("promotion failed")},
+			}
+
+			resp, result := deployBatch("?mode=all-or-nothing", `[{"application": "api"}, {"application": "web"}, {"application": "worker"}]`)
+
+			Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+			Expect(result.Status).To(Equal("failed"))
+			Expect(pushController.PromoteDeploymentCall.Received.Applications).To(Equal([]string{"api", "web"}))
+			Expect(pushController.RollbackDeploymentCall.Received.Applications).To(Equal([]string{"api"}))
+			Expect(pushController.RollbackDeploymentCall.Received.UUID).To(Equal(result.Applications[0].UUID))
+			Expect(deleteController.DeleteDeploymentCall.Received.Applications).To(Equal([]string{
+				"web-new-build-" + result.Applications[1].UUID,
+				"worker-new-build-" + result.Applications[2].UUID,
+			}))
+
+			Expect(result.Failed).To(Equal([]string{"web"}))
+			Expect(result.Applications[1].Error).To(Equal("promotion failed"))
+			Expect(result.RolledBack).To(Equal([]string{"api", "worker"}))
+		})
+
+		It("reports the candidate whose promotion failed when it cannot be deleted", func() {
+			pushController.PromoteDeploymentCall.Returns.DeployResponses = map[string]I.DeployResponse{
+				"web": {StatusCode: http.StatusInternalServerError, Error: errors.New("promotion failed")},
+			}
+			deleteController.DeleteDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusInternalServerError, Error: errors.New("delete failed")}
+
+			_, result := deployBatch("?mode=all-or-nothing", specs)
+
+			Expect(deleteController.DeleteDeploymentCall.Received.Applications).To(Equal([]string{"web-new-build-" + result.Applications[1].UUID}))
+			Expect(result.Applications[1].Status).To(Equal(BatchApplicationFailed))
+			Expect(result.Applications[1].Error).To(Equal("promotion failed; could not delete candidate web-new-build-" + result.Applications[1].UUID + ": delete failed"))
+		})
+
+		It("reports the promoted applications that cannot be rolled back", func() {
+			pushController.PromoteDeploymentCall.Returns.DeployResponses = map[string]I.DeployResponse{
+				"web": {StatusCode: http.StatusInternalServerError, Error: errors.New("promotion failed")},
+			}
+			pushController.RollbackDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusNotFound, Error: errors.New("no replaced application")}
+
+			_, result := deployBatch("?mode=all-or-nothing", specs)
+
+			Expect(result.Status).To(Equal("failed"))
+			Expect(result.Applications[0].Status).To(Equal(BatchApplicationDeployed))
+			Expect(result.Applications[0].Error).To(Equal("could not roll back api: no replaced application"))
+		})
+
+		It("rejects an environment that deletes replaced applications right away", func() {
+			controller.Config.Environments["prod"] = S.Environment{Name: "prod"}
+
+			resp, _ := deployBatch("?mode=all-or-nothing", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(ContainSubstring("it has no delete_delay_seconds"))
+			Expect(pushController.RunDeploymentCall.Called).To(BeFalse())
+		})
+
+		It("leaves the candidates of applications that ask for a manual cutover waiting for promotion", func() {
+			_, result := deployBatch("?mode=all-or-nothing", `[{"application": "api", "manual_cutover": true}, {"application": "web"}]`)
+
+			Expect(pushController.PromoteDeploymentCall.TimesCalled).To(Equal(1))
+			Expect(result.Applications[0].Status).To(Equal(BatchApplicationPending))
+			Expect(result.Applications[1].Status).To(Equal(BatchApplicationDeployed))
+		})
+	})
+
+	It("passes the skipSSL override of a request with the deployer credentials", func() {
+		controller.Config.Username = "username"
+		controller.Config.Password = "password"
+
+		req, err := http.NewRequest("POST", "/v2/deploy-batch/prod/org/space?skipSSL=true", bytes.NewBufferString(specs))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("username", "password")
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(*pushController.RunDeploymentCall.Received.Deployment.SkipSSL).To(BeTrue())
+	})
+
+	Context("when the request is invalid", func() {
+		AfterEach(func() {
+			contentType = "application/json"
+		})
+
+		It("rejects a body that is not application/json", func() {
+			contentType = "application/zip"
+
+			resp, _ := deployBatch("", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusUnsupportedMediaType))
+			Expect(resp.Body.String()).To(ContainSubstring("a batch deploy must be application/json"))
+			Expect(pushController.RunDeploymentCall.Called).To(BeFalse())
+		})
+
+		It("rejects a skipSSL override without the deployer credentials", func() {
+			resp, _ := deployBatch("?skipSSL=true", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusForbidden))
+			Expect(pushController.RunDeploymentCall.Called).To(BeFalse())
+		})
+
+		It("rejects an unknown mode", func() {
+			resp, _ := deployBatch("?mode=some", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(ContainSubstring(`invalid batch mode "some"`))
+			Expect(pushController.RunDeploymentCall.Called).To(BeFalse())
+		})
+
+		It("rejects an unknown failure policy", func() {
+			resp, _ := deployBatch("?failurePolicy=some", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(ContainSubstring(`invalid failurePolicy "some"`))
+			Expect(pushController.RunDeploymentCall.Called).To(BeFalse())
+		})
+
+		It("rejects a body that is not an array of app specs", func() {
+			resp, _ := deployBatch("", `{"application": "api"}`)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(ContainSubstring("the body must be a JSON array of app specs"))
+		})
+
+		It("rejects an app spec without an application", func() {
+			resp, _ := deployBatch("", `[{"application": "api"}, {"artifact_url": "https://example.com/web.jar"}]`)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(ContainSubstring("app spec 1 has no application"))
+			Expect(pushController.RunDeploymentCall.Called).To(BeFalse())
+		})
+
+		It("rejects an application in more than one app spec", func() {
+			resp, _ := deployBatch("", `[{"application": "api"}, {"application": "api"}]`)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(ContainSubstring("application api is in more than one app spec"))
+		})
+	})
+
+	It("returns http.StatusServiceUnavailable without pushing while the node is draining", func() {
+		gate := drain.NewGate(controller.Log)
+		controller.Drain = gate
+		gate.Drain()
+
+		resp, _ := deployBatch("", specs)
+
+		Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(resp.Body.String()).To(ContainSubstring(drain.DrainingError{}.Error()))
+		Expect(pushController.RunDeploymentCall.Called).To(BeFalse())
+	})
+
+	Context("when the concurrent deploys are limited", func() {
+		var (
+			deploys *limiter.Limiter
+			counter *inFlightPushController
+		)
+
+		BeforeEach(func() {
+			deploys = limiter.NewLimiter(2)
+			controller.Limiter = deploys
+			controller.Config.BatchDeploy.Concurrency = 4
+
+			counter = &inFlightPushController{PushController: pushController, limiter: deploys}
+			controller.PushControllerFactory = func(log I.DeploymentLogger) I.PushController {
+				return counter
+			}
+		})
+
+		It("holds a place for each application it pushes", func() {
+			controller.Config.QueueConcurrentDeployments = true
+
+			resp, result := deployBatch("", `[{"application": "api"}, {"application": "web"}, {"application": "worker"}, {"application": "cron"}]`)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(result.Succeeded).To(HaveLen(4))
+			Expect(counter.maxInFlight).To(Equal(2))
+			Expect(deploys.Status()).To(Equal(limiter.Status{MaxConcurrentDeployments: 2}))
+		})
+
+		It("fails the applications that get no place with http.StatusTooManyRequests", func() {
+			Expect(deploys.Acquire()).To(Succeed())
+			Expect(deploys.Acquire()).To(Succeed())
+
+			resp, result := deployBatch("", specs)
+
+			Expect(resp.Code).To(Equal(http.StatusTooManyRequests))
+			Expect(result.Failed).To(Equal([]string{"api", "web"}))
+			Expect(result.Applications[0].StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(result.Applications[0].Error).To(Equal(limiter.TooManyDeploymentsError{2}.Error()))
+			Expect(counter.calls).To(Equal(0))
+		})
+	})
+})
+
+// inFlightPushController records the most deploys in flight in the limiter while it pushes.
+type inFlightPushController struct {
+	*mocks.PushController
+	limiter *limiter.Limiter
+
+	mutex       sync.Mutex
+	calls       int
+	maxInFlight int
+}
+
+func (c *inFlightPushController) RunDeployment(deployment *I.Deployment, response *bytes.Buffer) I.DeployResponse {
+	c.mutex.Lock()
+	c.calls++
+	if inFlight := c.limiter.Status().InFlight; inFlight > c.maxInFlight {
+		c.maxInFlight = inFlight
+	}
+	c.mutex.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+	return I.DeployResponse{StatusCode: http.StatusOK}
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/gin-gonic/gin"
+)
+
+// CancelDeploymentHandler cancels a running deploy. The deploy is rolled back on every foundation and its candidate
+// applications are deleted. A deploy can no longer be canceled once it has finished pushing to every foundation.
+// The request must authenticate with the credentials Deployadactyl uses for Cloud Foundry.
+func (c *Controller) CancelDeploymentHandler(g *gin.Context) {
+	cf := getCFContext(g)
+	uuid := g.Param("uuid")
+
+	c.Log.Debugf("cancel request for deploy %s of %s originated from: %+v", uuid, cf.Application, g.Request.RemoteAddr)
+
+	auth := getAuthorization(g)
+	if !c.validCredentials(auth.Username, auth.Password) {
+		c.Log.Errorf("invalid credentials for cancel request of deploy %s", uuid)
+		g.Writer.Header().Set("WWW-Authenticate", `Basic realm="deployadactyl"`)
+		g.Writer.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(g.Writer, deployer.InvalidCredentialsError{})
+		return
+	}
+
+	var err error = deployer.DeployNotFoundError{UUID: uuid, AppName: cf.Application}
+	if c.Cancellations != nil {
+		err = c.Cancellations.Cancel(uuid, cf.Environment, cf.Organization, cf.Space, cf.Application)
+	}
+
+	switch err.(type) {
+	case nil:
+		c.Log.Infof("canceling deploy %s of %s", uuid, cf.Application)
+		g.Writer.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(g.Writer, "deploy %s of %s is being canceled\n", uuid, cf.Application)
+	case deployer.DeployNotCancelableError:
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusConflict)
+		fmt.Fprintln(g.Writer, err)
+	default:
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(g.Writer, err)
+	}
+}
@@ -0,0 +1,89 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("CancelDeploymentHandler", func() {
+	var (
+		controller    *Controller
+		cancellations *deployer.Cancellations
+		router        *gin.Engine
+		logBuffer     *Buffer
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		router = gin.New()
+		cancellations = deployer.NewCancellations()
+
+		controller = &Controller{
+			Log:           I.DefaultLogger(logBuffer, logging.DEBUG, "cancel_test"),
+			Config:        config.Config{Username: "admin", Password: "secret"},
+			Cancellations: cancellations,
+		}
+
+		router.POST("/v2/deploy/:environment/:org/:space/:appName/cancel/:uuid", controller.CancelDeploymentHandler)
+	})
+
+	cancel := func(uuid, user, pass string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/v2/deploy/prod/org/space/myApp/cancel/"+uuid, nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.SetBasicAuth(user, pass)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	It("cancels a running deploy", func() {
+		cancellation := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		cancellation.Add()
+
+		resp := cancel("my-uuid", "admin", "secret")
+
+		Expect(resp.Code).To(Equal(http.StatusAccepted))
+		Expect(resp.Body.String()).To(ContainSubstring("deploy my-uuid of myApp is being canceled"))
+		Expect(cancellation.Canceled()).To(BeTrue())
+		Eventually(logBuffer).Should(Say("canceling deploy my-uuid of myApp"))
+	})
+
+	It("returns http.StatusNotFound when the deploy is not running", func() {
+		resp := cancel("my-uuid", "admin", "secret")
+
+		Expect(resp.Code).To(Equal(http.StatusNotFound))
+		Expect(resp.Body.String()).To(ContainSubstring("no deploy of myApp with UUID my-uuid is running"))
+	})
+
+	It("returns http.StatusConflict when the deploy can no longer be canceled", func() {
+		cancellation := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		cancellation.Add()
+		cancellation.Done()
+
+		resp := cancel("my-uuid", "admin", "secret")
+
+		Expect(resp.Code).To(Equal(http.StatusConflict))
+		Expect(cancellation.Canceled()).To(BeFalse())
+	})
+
+	It("rejects invalid credentials", func() {
+		cancellation := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		cancellation.Add()
+
+		resp := cancel("my-uuid", "admin", "wrong")
+
+		Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+		Expect(cancellation.Canceled()).To(BeFalse())
+	})
+})
@@ -3,83 +3,327 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 
 	"encoding/json"
 	I "github.com/compozed/deployadactyl/interfaces"
 
+	"github.com/compozed/deployadactyl/applogs"
+	"github.com/compozed/deployadactyl/artifetcher/extractor"
 	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/circuitbreaker"
+	"github.com/compozed/deployadactyl/controller/deployer/drain"
+	"github.com/compozed/deployadactyl/controller/deployer/limiter"
+	"github.com/compozed/deployadactyl/deploymentlog"
+	"github.com/compozed/deployadactyl/diagnostics"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/tracing"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"regexp"
+	"strconv"
+	"time"
 )
 
+// UUIDHeader is the request header a client can use to supply the deployment UUID.
+const UUIDHeader = "X-Deployment-UUID"
+
+// ChangeTicketHeader is the request header a client can use to supply the change ticket of a deploy.
+const ChangeTicketHeader = "X-Change-Ticket"
+
+// ReasonHeader is the request header a client can use to supply the reason for a deploy.
+const ReasonHeader = "X-Deploy-Reason"
+
 type PushControllerFactory func(log I.DeploymentLogger) I.PushController
 type StartControllerFactory func(log I.DeploymentLogger) I.StartController
 type StopControllerFactory func(log I.DeploymentLogger) I.StopController
+type ScaleControllerFactory func(log I.DeploymentLogger) I.ScaleController
+type RestartControllerFactory func(log I.DeploymentLogger) I.RestartController
+type DeleteControllerFactory func(log I.DeploymentLogger) I.DeleteController
 
 // Controller is used to determine the type of request and process it accordingly.
 type Controller struct {
-	Log                    I.Logger
-	PushControllerFactory  PushControllerFactory
-	StartControllerFactory StartControllerFactory
-	StopControllerFactory  StopControllerFactory
-	Config                 config.Config
-	EventManager           I.EventManager
-	ErrorFinder            I.ErrorFinder
+	Log                      I.Logger
+	PushControllerFactory    PushControllerFactory
+	StartControllerFactory   StartControllerFactory
+	StopControllerFactory    StopControllerFactory
+	ScaleControllerFactory   ScaleControllerFactory
+	RestartControllerFactory RestartControllerFactory
+	DeleteControllerFactory  DeleteControllerFactory
+	Config                   config.Config
+	EventManager             I.EventManager
+	ErrorFinder              I.ErrorFinder
+	DeploymentLogSink        *deploymentlog.Sink
+	CircuitBreaker           *circuitbreaker.Breaker
+	Drain                    *drain.Gate
+	Limiter                  *limiter.Limiter
+	Cancellations            *deployer.Cancellations
+	History                  *deployer.History
+	Cooldowns                *deployer.Cooldowns
+	SLOBreaches              *deployer.SLOBreaches
+	Tracer                   *tracing.Tracer
+	Diagnostics              *diagnostics.Checker
+	AppLogs                  applogs.Fetcher
+
+	// ArtifactTypes are the content types, besides application/zip, of artifacts a ZIP push may send.
+	ArtifactTypes []string
 }
 
+var (
+	uuidPattern    = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	rfc4122Pattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[1-5][0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
+)
+
 type PutRequest struct {
 	State string                 `json:"state"`
 	Data  map[string]interface{} `json:"data"`
+
+	// UUID names the deploy to promote or roll back. The deployment UUID of the request is used when it is empty.
+	UUID string `json:"uuid"`
+}
+
+type PatchRequest struct {
+	Instances            *uint16                `json:"instances"`
+	Memory               string                 `json:"memory"`
+	EnvironmentVariables map[string]string      `json:"environment_variables"`
+	Data                 map[string]interface{} `json:"data"`
 }
 
 // Deprecated - wrapper for PushController.RunDeployment
 func (c *Controller) RunDeployment(deployment *I.Deployment, response *bytes.Buffer) I.DeployResponse {
-	uuid := randomizer.StringRunes(10)
-	log := I.DeploymentLogger{Log: c.Log, UUID: uuid}
-	return c.PushControllerFactory(log).RunDeployment(deployment, response)
+	log, err := c.deploymentLogger(deployment.UUID, deployment.CFContext)
+	if err != nil {
+		return I.DeployResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err,
+		}
+	}
+
+	err = c.checkReadOnly(deployment.CFContext)
+	if err != nil {
+		log.Error(err)
+		return I.DeployResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      err,
+		}
+	}
+
+	if c.Drain != nil {
+		err = c.Drain.Enter()
+		if err != nil {
+			log.Error(err)
+			return I.DeployResponse{
+				StatusCode: http.StatusServiceUnavailable,
+				Error:      err,
+			}
+		}
+		defer c.Drain.Leave()
+	}
+
+	ctx := deployment.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	release, err := c.acquirePlace(ctx)
+	if err != nil {
+		log.Error(err)
+		return I.DeployResponse{
+			StatusCode: http.StatusTooManyRequests,
+			Error:      err,
+		}
+	}
+	defer release()
+
+	logFile := c.openDeploymentLog(&log)
+	deployResponse := c.PushControllerFactory(log).RunDeployment(deployment, response)
+	closeDeploymentLog(logFile, response)
+
+	return deployResponse
 }
 
 // RunDeploymentViaHttp checks the request content type and passes it to the Deployer.
 func (c *Controller) RunDeploymentViaHttp(g *gin.Context) {
-	uuid := randomizer.StringRunes(10)
-	log := I.DeploymentLogger{Log: c.Log, UUID: uuid}
-	log.Debugf("Request originated from: %+v", g.Request.RemoteAddr)
+	start := time.Now()
 
-	cfContext := I.CFContext{
-		Environment:  g.Param("environment"),
-		Organization: g.Param("org"),
-		Space:        g.Param("space"),
-		Application:  g.Param("appName"),
+	log, err := c.deploymentLogger(g.Request.Header.Get(UUIDHeader), getCFContext(g))
+	if err != nil {
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
 	}
+	trackRequest(g, log.UUID, ReadingRequestPhase)
+	c.logRequest(g, log)
 
-	user, pwd, _ := g.Request.BasicAuth()
-	authorization := I.Authorization{
-		Username: user,
-		Password: pwd,
+	span := c.Tracer.StartDeploy(log.UUID, getCFContext(g))
+	streamedStatus := 0
+	defer func() {
+		if streamedStatus != 0 {
+			span.EndWithStatus(streamedStatus)
+			return
+		}
+		span.EndWithStatus(g.Writer.Status())
+	}()
+
+	if !c.allowChange(g, log) {
+		return
+	}
+
+	deploymentType, artifactType, err := getDeploymentType(g, c.ArtifactTypes)
+	if err != nil {
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusUnsupportedMediaType)
+		fmt.Fprintln(g.Writer, err)
+		return
 	}
 
-	deploymentType := I.DeploymentType{
-		JSON: g.Request.Header.Get("Content-Type") == "application/json",
-		ZIP:  g.Request.Header.Get("Content-Type") == "application/zip",
+	err = c.checkCooldown(getCFContext(g))
+	if err != nil {
+		log.Error(err)
+		retryAfter := int64(err.(deployer.DeployCooldownError).Remaining / time.Second)
+		g.Writer.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+		g.Writer.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintln(g.Writer, err)
+		return
 	}
+
+	authorization := getAuthorization(g)
+
+	skipSSL, err := c.getSkipSSL(g, authorization)
+	if err != nil {
+		log.Error(err)
+
+		statusCode := http.StatusBadRequest
+		if _, ok := err.(deployer.SkipSSLOverrideForbiddenError); ok {
+			statusCode = http.StatusForbidden
+		}
+		g.Writer.WriteHeader(statusCode)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	failurePolicy, err := getFailurePolicy(g)
+	if err != nil {
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	format, err := getFormat(g)
+	if err != nil {
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	leave, ok := c.enterDrain(g, log)
+	if !ok {
+		return
+	}
+	defer leave()
+
+	release, ok := c.acquireDeployment(g, log)
+	if !ok {
+		return
+	}
+	defer release()
+
+	logFile := c.openDeploymentLog(&log)
+	log.Debugf("Request originated from: %+v", g.Request.RemoteAddr)
+
+	cfContext := getCFContext(g)
+
 	response := &bytes.Buffer{}
 
 	deployment := I.Deployment{
-		Authorization: authorization,
-		CFContext:     cfContext,
-		Type:          deploymentType,
+		Authorization:  authorization,
+		CFContext:      cfContext,
+		Type:           deploymentType,
+		NoCache:        g.Query("noCache") == "true",
+		ClientIdentity: getClientIdentity(g),
+		ChangeTicket:   g.Request.Header.Get(ChangeTicketHeader),
+		Reason:         g.Request.Header.Get(ReasonHeader),
+
+		AllowNameMismatch: g.Query("allowNameMismatch") == "true",
+		SkipSSL:           skipSSL,
+		ArtifactType:      artifactType,
+		FailurePolicy:     failurePolicy,
+		Context:           g.Request.Context(),
+	}
+
+	if deploymentType.Multipart {
+		manifest, artifact, err := readMultipart(g, c.Config.ArtifactUpload.MaxSizeMB)
+		g.Request.Body.Close()
+		if err != nil {
+			log.Error(err)
+			closeDeploymentLog(logFile, response)
+
+			statusCode := http.StatusBadRequest
+			switch err.(type) {
+			case deployer.ArtifactTooLargeError, deployer.ManifestTooLargeError:
+				statusCode = http.StatusRequestEntityTooLarge
+			}
+			g.Writer.WriteHeader(statusCode)
+			fmt.Fprintln(g.Writer, err)
+			return
+		}
+		deployment.Manifest = manifest
+		deployment.Body = &artifact
+	} else {
+		bodyBuffer, _ := ioutil.ReadAll(g.Request.Body)
+		g.Request.Body.Close()
+		deployment.Body = &bodyBuffer
+	}
+
+	trackRequest(g, log.UUID, DeployingPhase)
+
+	// An NDJSON deploy sends its headers and start event before the deploy, so its result carries the status code.
+	// Its output is sent while the deploy runs.
+	if format == FormatNDJSON {
+		stream := startNDJSON(g, log.UUID, cfContext)
+		deployment.Output = stream
+		deployResponse := c.PushControllerFactory(log).RunDeployment(&deployment, response)
+		closeDeploymentLog(logFile, response)
+		streamedStatus = stream.finish(log.UUID, deployResponse, time.Since(start))
+		return
+	}
+
+	// A plain text deploy that waits for an approval is streamed from then on, so the client sees that it waits.
+	stream := &textStream{writer: g.Writer}
+	if !waitForResult(g) {
+		deployment.StreamOutput = func() io.Writer {
+			return stream.start(log.UUID, cfContext.Environment, response)
+		}
 	}
-	bodyBuffer, _ := ioutil.ReadAll(g.Request.Body)
-	g.Request.Body.Close()
-	deployment.Body = &bodyBuffer
 
 	deployResponse := c.PushControllerFactory(log).RunDeployment(&deployment, response)
+	duration := time.Since(start)
+	setDeployHeaders(g, log.UUID, cfContext.Environment, deployResponse, duration)
+
+	if waitForResult(g) {
+		closeDeploymentLog(logFile, response)
+		writeDeployResult(g, log.UUID, deployResponse, duration)
+		return
+	}
+
+	// The output of a streamed deploy was already sent, and its status is sent in the trailers set above.
+	if stream.isStarted() {
+		if deployResponse.Error != nil {
+			fmt.Fprintf(io.MultiWriter(response, stream), "cannot deploy application: %s\n", deployResponse.Error)
+		}
+		closeDeploymentLog(logFile, response)
+		return
+	}
 
 	defer io.Copy(g.Writer, response)
+	defer closeDeploymentLog(logFile, response)
 
 	if deployResponse.Error != nil {
 		g.Writer.WriteHeader(deployResponse.StatusCode)
@@ -90,37 +334,111 @@ func (c *Controller) RunDeploymentViaHttp(g *gin.Context) {
 	g.Writer.WriteHeader(deployResponse.StatusCode)
 }
 
+// RunShortDeploymentViaHttp handles deploy URLs with three params.
+//
+// When the first param names an environment, the URL is /v2/deploy/:environment/:org/:space and every
+// application in the manifest is deployed. Otherwise the URL is /v2/deploy/:org/:space/:appName and the
+// application is deployed to the configured DefaultEnvironment. Its org, space and application arrive in
+// the environment, org and space params and are moved to the right names.
+//
+// An environment name always wins, whatever its case, so an org that shares the name of an environment is
+// deployed to with the full /v2/deploy/:environment/:org/:space/:appName URL.
+func (c *Controller) RunShortDeploymentViaHttp(g *gin.Context) {
+	if name, ok := environmentName(c.Config.Environments, g.Param("environment")); ok {
+		c.Log.Debugf("deploy URL %s names environment %s, deploying every application in the manifest", g.Request.URL.Path, name)
+		g.Params = gin.Params{
+			{Key: "environment", Value: name},
+			{Key: "org", Value: g.Param("org")},
+			{Key: "space", Value: g.Param("space")},
+		}
+		c.RunDeploymentViaHttp(g)
+		return
+	}
+
+	if c.Config.DefaultEnvironment == "" {
+		c.Log.Error(deployer.DefaultEnvironmentNotConfiguredError{})
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, deployer.DefaultEnvironmentNotConfiguredError{})
+		return
+	}
+
+	g.Params = gin.Params{
+		{Key: "environment", Value: c.Config.DefaultEnvironment},
+		{Key: "org", Value: g.Param("environment")},
+		{Key: "space", Value: g.Param("org")},
+		{Key: "appName", Value: g.Param("space")},
+	}
+
+	c.RunDeploymentViaHttp(g)
+}
+
+// RunDefaultOrgSpaceDeploymentViaHttp deploys to the default org and space of the API key of the request. The
+// route is either /v2/deploy/:environment/:org, where :org is the application, or /v2/deploy/:environment, where
+// :environment is the application deployed to the default environment. Its params are named to match v2ENDPOINT.
+func (c *Controller) RunDefaultOrgSpaceDeploymentViaHttp(g *gin.Context) {
+	apiKey, _ := requestAPIKey(g)
+	if apiKey.Org == "" {
+		err := deployer.DefaultOrgSpaceNotConfiguredError{apiKey.Name}
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	environment, appName := g.Param("environment"), g.Param("org")
+	if appName == "" {
+		if c.Config.DefaultEnvironment == "" {
+			c.Log.Error(deployer.DefaultEnvironmentNotConfiguredError{})
+			g.Writer.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(g.Writer, deployer.DefaultEnvironmentNotConfiguredError{})
+			return
+		}
+		environment, appName = c.Config.DefaultEnvironment, g.Param("environment")
+	}
+
+	g.Params = gin.Params{
+		{Key: "environment", Value: environment},
+		{Key: "org", Value: apiKey.Org},
+		{Key: "space", Value: apiKey.Space},
+		{Key: "appName", Value: appName},
+	}
+
+	c.RunDeploymentViaHttp(g)
+}
+
 func (c *Controller) PutRequestHandler(g *gin.Context) {
-	uuid := randomizer.StringRunes(10)
-	log := I.DeploymentLogger{Log: c.Log, UUID: uuid}
+	log, err := c.deploymentLogger(g.Request.Header.Get(UUIDHeader), getCFContext(g))
+	if err != nil {
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+	trackRequest(g, log.UUID, ReadingRequestPhase)
 	log.Debugf("PUT Request originated from: %+v", g.Request.RemoteAddr)
+	c.logRequest(g, log)
 
-	cfContext := I.CFContext{
-		Environment:  g.Param("environment"),
-		Organization: g.Param("org"),
-		Space:        g.Param("space"),
-		Application:  g.Param("appName"),
+	if !c.allowChange(g, log) {
+		return
 	}
 
+	cfContext := getCFContext(g)
+
 	response := &bytes.Buffer{}
 	defer io.Copy(g.Writer, response)
 
-	user, pwd, _ := g.Request.BasicAuth()
-	authorization := I.Authorization{
-		Username: user,
-		Password: pwd,
-	}
+	authorization := getAuthorization(g)
 
 	deployment := I.Deployment{
-		Authorization: authorization,
-		CFContext:     cfContext,
+		Authorization:  authorization,
+		CFContext:      cfContext,
+		ClientIdentity: getClientIdentity(g),
 	}
 
 	bodyBuffer, _ := ioutil.ReadAll(g.Request.Body)
 	g.Request.Body.Close()
 
 	putRequest := &PutRequest{}
-	err := json.Unmarshal(bodyBuffer, putRequest)
+	err = json.Unmarshal(bodyBuffer, putRequest)
 	if err != nil {
 		response.Write([]byte("Invalid request body."))
 		g.Writer.WriteHeader(http.StatusBadRequest)
@@ -129,10 +447,27 @@ func (c *Controller) PutRequestHandler(g *gin.Context) {
 
 	var deployResponse I.DeployResponse
 
+	trackRequest(g, log.UUID, ChangingStatePhase)
 	if putRequest.State == "stopped" {
 		deployResponse = c.StopControllerFactory(log).StopDeployment(&deployment, putRequest.Data, response)
 	} else if putRequest.State == "started" {
 		deployResponse = c.StartControllerFactory(log).StartDeployment(&deployment, putRequest.Data, response)
+	} else if putRequest.State == "restaged" {
+		deployResponse = c.RestartControllerFactory(log).RestageDeployment(&deployment, putRequest.Data, response)
+	} else if putRequest.State == "restarted-instances" {
+		deployResponse = c.RestartControllerFactory(log).RestartDeployment(&deployment, putRequest.Data, response)
+	} else if putRequest.State == "promoted" {
+		uuid := putRequest.UUID
+		if uuid == "" {
+			uuid = log.UUID
+		}
+		deployResponse = c.PushControllerFactory(log).PromoteDeployment(&deployment, uuid, response)
+	} else if putRequest.State == "rolled-back" {
+		uuid := putRequest.UUID
+		if uuid == "" {
+			uuid = log.UUID
+		}
+		deployResponse = c.PushControllerFactory(log).RollbackDeployment(&deployment, uuid, response)
 	} else {
 		response.Write([]byte("Unknown requested state: " + putRequest.State))
 		deployResponse = I.DeployResponse{
@@ -142,3 +477,359 @@ func (c *Controller) PutRequestHandler(g *gin.Context) {
 
 	g.Writer.WriteHeader(deployResponse.StatusCode)
 }
+
+// PatchRequestHandler applies a partial update to a running application without a blue green deploy.
+func (c *Controller) PatchRequestHandler(g *gin.Context) {
+	log, err := c.deploymentLogger(g.Request.Header.Get(UUIDHeader), getCFContext(g))
+	if err != nil {
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+	trackRequest(g, log.UUID, ReadingRequestPhase)
+	log.Debugf("PATCH Request originated from: %+v", g.Request.RemoteAddr)
+
+	if !c.allowChange(g, log) {
+		return
+	}
+
+	response := &bytes.Buffer{}
+	defer io.Copy(g.Writer, response)
+
+	deployment := I.Deployment{
+		Authorization:  getAuthorization(g),
+		CFContext:      getCFContext(g),
+		ClientIdentity: getClientIdentity(g),
+	}
+
+	bodyBuffer, _ := ioutil.ReadAll(g.Request.Body)
+	g.Request.Body.Close()
+
+	patchRequest := &PatchRequest{}
+	err = json.Unmarshal(bodyBuffer, patchRequest)
+	if err != nil {
+		response.Write([]byte("Invalid request body."))
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	spec := structs.ScaleSpec{
+		Instances:            patchRequest.Instances,
+		Memory:               patchRequest.Memory,
+		EnvironmentVariables: patchRequest.EnvironmentVariables,
+	}
+	if spec.IsEmpty() {
+		response.Write([]byte("Request body must contain instances, memory or environment_variables."))
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	trackRequest(g, log.UUID, UpdatingPhase)
+	deployResponse := c.ScaleControllerFactory(log).ScaleDeployment(&deployment, spec, patchRequest.Data, response)
+
+	if deployResponse.Error != nil {
+		fmt.Fprintf(response, "cannot scale application: %s\n", deployResponse.Error)
+	}
+
+	g.Writer.WriteHeader(deployResponse.StatusCode)
+}
+
+// DeleteRequestHandler deletes an application from every foundation in the environment.
+// The deleteRoutes and deleteServices query parameters also delete the routes mapped to the
+// application and the services that are only bound to it.
+func (c *Controller) DeleteRequestHandler(g *gin.Context) {
+	log, err := c.deploymentLogger(g.Request.Header.Get(UUIDHeader), getCFContext(g))
+	if err != nil {
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+	trackRequest(g, log.UUID, ReadingRequestPhase)
+	log.Debugf("DELETE Request originated from: %+v", g.Request.RemoteAddr)
+	c.logRequest(g, log)
+
+	if !c.allowChange(g, log) {
+		return
+	}
+
+	response := &bytes.Buffer{}
+	defer io.Copy(g.Writer, response)
+
+	deployment := I.Deployment{
+		Authorization:  getAuthorization(g),
+		CFContext:      getCFContext(g),
+		ClientIdentity: getClientIdentity(g),
+	}
+
+	options := structs.DeleteOptions{
+		Routes:   g.Query("deleteRoutes") == "true",
+		Services: g.Query("deleteServices") == "true",
+	}
+
+	trackRequest(g, log.UUID, ChangingStatePhase)
+	deployResponse := c.DeleteControllerFactory(log).DeleteDeployment(&deployment, options, nil, response)
+
+	if deployResponse.Error != nil {
+		fmt.Fprintf(response, "cannot delete application: %s\n", deployResponse.Error)
+	}
+
+	g.Writer.WriteHeader(deployResponse.StatusCode)
+}
+
+// deploymentLogger returns a DeploymentLogger for the requested UUID. A UUID is generated
+// if none was requested or the config says to always generate one. It returns an error when the names of
+// the request path are not valid Cloud Foundry names.
+func (c *Controller) deploymentLogger(requested string, cf I.CFContext) (I.DeploymentLogger, error) {
+	err := validateCFContext(cf)
+	if err != nil {
+		c.Log.Errorf("rejected request: %s", err)
+		return I.DeploymentLogger{}, err
+	}
+
+	uuid, err := c.resolveUUID(requested)
+	if err != nil {
+		c.Log.Errorf("rejected request: %s", err)
+		return I.DeploymentLogger{}, err
+	}
+
+	prefix := I.DeploymentPrefix(uuid, "", "")
+	if c.Config.LogPrefix.IncludeApplication {
+		prefix = I.DeploymentPrefix(uuid, cf.Environment, cf.Application)
+	}
+
+	return I.DeploymentLogger{
+		Log:      c.Log,
+		UUID:     uuid,
+		Prefix:   prefix,
+		NoPrefix: c.Config.LogPrefix.Disabled,
+	}, nil
+}
+
+func (c *Controller) resolveUUID(requested string) (string, error) {
+	uuidConfig := c.Config.UUID
+
+	if requested == "" || uuidConfig.AlwaysGenerate {
+		if uuidConfig.Format == config.UUIDFormatRFC4122 {
+			return randomizer.UUID(), nil
+		}
+		return randomizer.StringRunes(10), nil
+	}
+
+	maxLength := uuidConfig.MaxLength
+	if maxLength < 1 {
+		maxLength = config.DefaultUUIDMaxLength
+	}
+	if len(requested) > maxLength {
+		return "", deployer.InvalidUUIDError{UUID: requested, Reason: fmt.Sprintf("must be at most %d characters", maxLength)}
+	}
+
+	if uuidConfig.Format == config.UUIDFormatRFC4122 {
+		if !rfc4122Pattern.MatchString(requested) {
+			return "", deployer.InvalidUUIDError{UUID: requested, Reason: "must be an RFC 4122 UUID"}
+		}
+		return requested, nil
+	}
+
+	if !uuidPattern.MatchString(requested) {
+		return "", deployer.InvalidUUIDError{UUID: requested, Reason: "must contain only letters, digits and hyphens"}
+	}
+
+	return requested, nil
+}
+
+// openDeploymentLog sends the logs of a deployment to its own log file when a DeploymentLogSink is configured.
+func (c *Controller) openDeploymentLog(log *I.DeploymentLogger) *deploymentlog.File {
+	if c.DeploymentLogSink == nil {
+		return nil
+	}
+
+	logFile := c.DeploymentLogSink.Open(log.UUID)
+	log.Log = logFile.Logger(log.Log)
+
+	return logFile
+}
+
+// closeDeploymentLog writes the deployment output to the log file and closes it.
+func closeDeploymentLog(logFile *deploymentlog.File, response *bytes.Buffer) {
+	if logFile == nil {
+		return
+	}
+
+	logFile.Write(response.Bytes())
+	logFile.Close()
+}
+
+func getCFContext(g *gin.Context) I.CFContext {
+	return I.CFContext{
+		Environment:  g.Param("environment"),
+		Organization: g.Param("org"),
+		Space:        g.Param("space"),
+		Application:  g.Param("appName"),
+	}
+}
+
+// getSkipSSL returns the skipSSL query param of the request, or nil when it is not set. Because it can turn off
+// TLS verification, only a request with the credentials Deployadactyl uses for Cloud Foundry may set it.
+func (c *Controller) getSkipSSL(g *gin.Context, auth I.Authorization) (*bool, error) {
+	value, ok := g.GetQuery("skipSSL")
+	if !ok {
+		return nil, nil
+	}
+
+	skipSSL, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, deployer.InvalidSkipSSLError{value}
+	}
+
+	if !c.validCredentials(auth.Username, auth.Password) {
+		return nil, deployer.SkipSSLOverrideForbiddenError{}
+	}
+
+	return &skipSSL, nil
+}
+
+// getFailurePolicy returns the failurePolicy query param of the request, or the empty string when it is not set.
+func getFailurePolicy(g *gin.Context) (string, error) {
+	failurePolicy := g.Query("failurePolicy")
+	switch failurePolicy {
+	case "", structs.FailurePolicyFailFast, structs.FailurePolicyContinue:
+		return failurePolicy, nil
+	}
+
+	return "", deployer.InvalidFailurePolicyError{failurePolicy}
+}
+
+// checkReadOnly returns a ReadOnlyEnvironmentError when the environment is read only.
+func (c *Controller) checkReadOnly(cf I.CFContext) error {
+	if c.Config.Environments[cf.Environment].ReadOnly {
+		return deployer.ReadOnlyEnvironmentError{cf.Environment}
+	}
+	return nil
+}
+
+// allowChange responds with http.StatusForbidden and returns false when the request would change an application
+// in a read only environment.
+func (c *Controller) allowChange(g *gin.Context, log I.DeploymentLogger) bool {
+	err := c.checkReadOnly(getCFContext(g))
+	if err == nil {
+		return true
+	}
+
+	log.Error(err)
+	g.Writer.WriteHeader(http.StatusForbidden)
+	fmt.Fprintln(g.Writer, err)
+	return false
+}
+
+// enterDrain counts a push as in flight in the Drain gate for the whole request, so a node is not restarted while
+// it waits for a place, for approval or for its deploy. A push to a draining node is answered with a 503 before
+// its body is read. It returns the func that stops counting the push, or false when the push was answered.
+func (c *Controller) enterDrain(g *gin.Context, log I.DeploymentLogger) (func(), bool) {
+	if c.Drain == nil {
+		return func() {}, true
+	}
+
+	err := c.Drain.Enter()
+	if err != nil {
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(g.Writer, err)
+		return nil, false
+	}
+
+	return c.Drain.Leave, true
+}
+
+// acquireDeployment holds a place in the Limiter of concurrent deployments for a push before its body is read. A push
+// over the limit is answered with a 429, or waits for a place with QueueConcurrentDeployments until its request is
+// done. It returns the func that frees the place, or false when the push was answered.
+func (c *Controller) acquireDeployment(g *gin.Context, log I.DeploymentLogger) (func(), bool) {
+	release, err := c.acquirePlace(g.Request.Context())
+	if err != nil {
+		log.Error(err)
+		g.Writer.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintln(g.Writer, err)
+		return nil, false
+	}
+
+	return release, true
+}
+
+// acquirePlace holds a place in the Limiter of concurrent deployments, waiting for one until ctx is done with
+// QueueConcurrentDeployments. It returns the func that frees the place, or the error of the Limiter.
+func (c *Controller) acquirePlace(ctx context.Context) (func(), error) {
+	if c.Limiter == nil {
+		return func() {}, nil
+	}
+
+	var err error
+	if c.Config.QueueConcurrentDeployments {
+		err = c.Limiter.Wait(ctx)
+	} else {
+		err = c.Limiter.Acquire()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Limiter.Release, nil
+}
+
+// checkCooldown returns a DeployCooldownError when the application was deployed successfully more recently than
+// the MinTimeBetweenDeploysSeconds of its environment. The remaining time is rounded up to whole seconds.
+func (c *Controller) checkCooldown(cf I.CFContext) error {
+	environment, ok := c.Config.Environments[cf.Environment]
+	if c.Cooldowns == nil || !ok || environment.MinTimeBetweenDeploysSeconds < 1 || cf.Application == "" {
+		return nil
+	}
+
+	cooldown := time.Duration(environment.MinTimeBetweenDeploysSeconds) * time.Second
+	remaining := c.Cooldowns.Remaining(cf.Environment, cf.Organization, cf.Space, cf.Application, cooldown)
+	if remaining == 0 {
+		return nil
+	}
+
+	remaining = (remaining + time.Second - 1) / time.Second * time.Second
+	return deployer.DeployCooldownError{cf.Application, cooldown, remaining}
+}
+
+// getDeploymentType returns the type of a deploy from the Content-Type of the request, ignoring its parameters.
+// A request with an artifact of application/zip or one of the artifactTypes is a ZIP deploy, and its media type
+// is returned as the type of the artifact.
+// It returns an UnsupportedMediaTypeError for any other type than those, application/json and multipart/form-data.
+func getDeploymentType(g *gin.Context, artifactTypes []string) (I.DeploymentType, string, error) {
+	contentType := g.Request.Header.Get("Content-Type")
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return I.DeploymentType{}, "", deployer.UnsupportedMediaTypeError{contentType}
+	}
+
+	switch {
+	case mediaType == "application/json":
+		return I.DeploymentType{JSON: true}, "", nil
+	case mediaType == "multipart/form-data":
+		return I.DeploymentType{Multipart: true}, "", nil
+	case extractor.Supported(artifactTypes, mediaType):
+		return I.DeploymentType{ZIP: true}, mediaType, nil
+	default:
+		return I.DeploymentType{}, "", deployer.UnsupportedMediaTypeError{contentType}
+	}
+}
+
+func getAuthorization(g *gin.Context) I.Authorization {
+	user, pwd, _ := g.Request.BasicAuth()
+	return I.Authorization{
+		Username: user,
+		Password: pwd,
+	}
+}
+
+// getClientIdentity returns the common name of the verified client certificate, or an empty string
+// when the request did not present one.
+func getClientIdentity(g *gin.Context) string {
+	if g.Request.TLS == nil || len(g.Request.TLS.VerifiedChains) == 0 || len(g.Request.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return g.Request.TLS.VerifiedChains[0][0].Subject.CommonName
+}
@@ -0,0 +1,212 @@
+// Package approval holds deploys until an external approval service approves them.
+package approval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// RequestTimeout limits each call to the approval service.
+const RequestTimeout = 30 * time.Second
+
+// Statuses of an approval returned by the approval service.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+)
+
+// Request is the summary of a deploy that is posted to the approval service.
+type Request struct {
+	UUID         string                 `json:"uuid"`
+	Environment  string                 `json:"environment"`
+	Org          string                 `json:"org"`
+	Space        string                 `json:"space"`
+	AppName      string                 `json:"app_name"`
+	ArtifactURL  string                 `json:"artifact_url"`
+	Username     string                 `json:"username"`
+	ChangeTicket string                 `json:"change_ticket"`
+	Reason       string                 `json:"reason"`
+	Data         map[string]interface{} `json:"data"`
+}
+
+// Response is the JSON body of the approval service, when the approval is requested and each time it is polled.
+// The response to the request has the StatusURL the approval is polled at, relative to the URL of the service or
+// absolute.
+type Response struct {
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	StatusURL string `json:"status_url"`
+}
+
+// Gate posts a summary of each deploy to an approval service, then polls the approval every PollInterval until
+// it is approved or denied, for up to Timeout.
+type Gate struct {
+	URL          string
+	PollInterval time.Duration
+	Timeout      time.Duration
+	Client       *http.Client
+
+	// Signer signs each request when it is set.
+	Signer I.DeploySigner
+}
+
+// NewGate returns a Gate that posts to url.
+func NewGate(url string, pollInterval, timeout time.Duration) Gate {
+	return Gate{
+		URL:          url,
+		PollInterval: pollInterval,
+		Timeout:      timeout,
+		Client:       &http.Client{Timeout: RequestTimeout},
+	}
+}
+
+// Approve requests the approval of the deploy and waits for it, writing the status of the approval to output.
+//
+// Returns a DeniedError when the service denies the deploy and a TimedOutError when it is still pending after
+// Timeout. Returns a CanceledError when ctx is done first, and a GateError or an InvalidResponseError when the
+// service cannot be called.
+func (g Gate) Approve(ctx context.Context, deployEventData S.DeployEventData, output io.Writer) error {
+	info := deployEventData.DeploymentInfo
+	request := Request{
+		UUID:         info.UUID,
+		Environment:  info.Environment,
+		Org:          info.Org,
+		Space:        info.Space,
+		AppName:      info.AppName,
+		ArtifactURL:  info.ArtifactURL,
+		Username:     info.Username,
+		ChangeTicket: info.ChangeTicket,
+		Reason:       info.Reason,
+		Data:         info.Data,
+	}
+
+	body, err := signing.Marshal(&request, g.Signer)
+	if err != nil {
+		return GateError{err}
+	}
+
+	deadline := time.Now().Add(g.Timeout)
+
+	response, err := g.call(ctx, "POST", g.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	statusURL, err := g.statusURL(response)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(output, "awaiting approval of deploy %s for up to %s\n", info.UUID, g.Timeout)
+
+	for {
+		switch response.Status {
+		case StatusApproved:
+			fmt.Fprintf(output, "deploy approved%s\n", suffix(response.Message))
+			return nil
+		case StatusDenied:
+			message := response.Message
+			if message == "" {
+				message = "denied by the approval service"
+			}
+			return DeniedError{message}
+		case StatusPending:
+		default:
+			return InvalidResponseError{fmt.Sprintf("unknown status %q", response.Status)}
+		}
+
+		if !time.Now().Add(g.PollInterval).Before(deadline) {
+			return TimedOutError{g.Timeout}
+		}
+		select {
+		case <-time.After(g.PollInterval):
+		case <-ctx.Done():
+			return CanceledError{ctx.Err()}
+		}
+
+		response, err = g.call(ctx, "GET", statusURL, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "awaiting approval: %s%s\n", response.Status, suffix(response.Message))
+	}
+}
+
+// statusURL resolves the status URL of the response to the request against the URL of the service. It is only
+// required while the approval is pending.
+func (g Gate) statusURL(response Response) (string, error) {
+	if response.Status != StatusPending {
+		return "", nil
+	}
+	if response.StatusURL == "" {
+		return "", InvalidResponseError{"a pending approval has no status_url"}
+	}
+
+	base, err := url.Parse(g.URL)
+	if err != nil {
+		return "", GateError{err}
+	}
+	reference, err := url.Parse(response.StatusURL)
+	if err != nil {
+		return "", InvalidResponseError{fmt.Sprintf("invalid status_url %q", response.StatusURL)}
+	}
+
+	return base.ResolveReference(reference).String(), nil
+}
+
+// call calls the service with a request that is canceled with ctx. It returns a CanceledError when ctx is done
+// before the service responds.
+func (g Gate) call(ctx context.Context, method, url string, body io.Reader) (Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return Response{}, GateError{err}
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.Client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Response{}, CanceledError{ctx.Err()}
+		}
+		return Response{}, GateError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Response{}, GateError{fmt.Errorf("%s %s: %s", method, url, resp.Status)}
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, GateError{err}
+	}
+
+	var response Response
+	err = json.Unmarshal(respBody, &response)
+	if err != nil {
+		return Response{}, InvalidResponseError{err.Error()}
+	}
+
+	return response, nil
+}
+
+func suffix(message string) string {
+	if message == "" {
+		return ""
+	}
+	return ": " + message
+}
@@ -0,0 +1,201 @@
+package approval_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/approval"
+	"github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+var _ = Describe("Gate", func() {
+	var (
+		server          *httptest.Server
+		mutex           sync.Mutex
+		received        Request
+		body            []byte
+		polls           int
+		statuses        []string
+		deployEventData S.DeployEventData
+		output          *bytes.Buffer
+		gate            Gate
+	)
+
+	BeforeEach(func() {
+		received = Request{}
+		polls = 0
+		statuses = []string{StatusPending}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if r.Method == "POST" {
+				body, _ = ioutil.ReadAll(r.Body)
+				json.Unmarshal(body, &received)
+				fmt.Fprintf(w, `{"status": %q, "status_url": "/approvals/42"}`, statuses[0])
+				return
+			}
+
+			Expect(r.URL.Path).To(Equal("/approvals/42"))
+			polls++
+			status := statuses[len(statuses)-1]
+			if polls < len(statuses) {
+				status = statuses[polls]
+			}
+			fmt.Fprintf(w, `{"status": %q, "message": "by jane"}`, status)
+		}))
+
+		deployEventData = S.DeployEventData{
+			DeploymentInfo: &S.DeploymentInfo{
+				UUID:         "the-uuid",
+				Environment:  "production",
+				Org:          "the-org",
+				Space:        "the-space",
+				AppName:      "the-app",
+				Username:     "the-user",
+				Password:     "the-password",
+				ChangeTicket: "CHG0001",
+			},
+		}
+
+		output = &bytes.Buffer{}
+		gate = NewGate(server.URL+"/approvals", 10*time.Millisecond, time.Second)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("posts a summary of the deploy without the password", func() {
+		statuses = []string{StatusApproved}
+
+		Expect(gate.Approve(context.Background(), deployEventData, output)).To(Succeed())
+
+		Expect(received.UUID).To(Equal("the-uuid"))
+		Expect(received.Environment).To(Equal("production"))
+		Expect(received.AppName).To(Equal("the-app"))
+		Expect(received.Username).To(Equal("the-user"))
+		Expect(received.ChangeTicket).To(Equal("CHG0001"))
+		Expect(polls).To(Equal(0))
+	})
+
+	It("signs the summary with the signer", func() {
+		statuses = []string{StatusApproved}
+		gate.Signer, _ = signing.NewSigner(signing.HMACSHA256, "key-1", []byte("secret"))
+
+		Expect(gate.Approve(context.Background(), deployEventData, output)).To(Succeed())
+
+		envelope := signing.Envelope{}
+		Expect(json.Unmarshal(body, &envelope)).To(Succeed())
+		Expect(envelope.Signature.KeyID).To(Equal("key-1"))
+		Expect(signing.Verify(envelope.Signature, envelope.Payload, []byte("secret"))).To(BeTrue())
+
+		summary := Request{}
+		Expect(json.Unmarshal(envelope.Payload, &summary)).To(Succeed())
+		Expect(summary.UUID).To(Equal("the-uuid"))
+	})
+
+	It("polls the status url until the deploy is approved", func() {
+		statuses = []string{StatusPending, StatusPending, StatusApproved}
+
+		Expect(gate.Approve(context.Background(), deployEventData, output)).To(Succeed())
+
+		Expect(polls).To(Equal(2))
+		Expect(output.String()).To(ContainSubstring("awaiting approval of deploy the-uuid for up to 1s"))
+		Expect(output.String()).To(ContainSubstring("awaiting approval: pending: by jane"))
+		Expect(output.String()).To(ContainSubstring("awaiting approval: approved: by jane"))
+	})
+
+	It("returns a DeniedError when the deploy is denied", func() {
+		statuses = []string{StatusPending, StatusDenied}
+
+		err := gate.Approve(context.Background(), deployEventData, output)
+
+		Expect(err).To(MatchError(DeniedError{"by jane"}))
+	})
+
+	It("returns a TimedOutError when the deploy is still pending after the timeout", func() {
+		gate.Timeout = 50 * time.Millisecond
+
+		err := gate.Approve(context.Background(), deployEventData, output)
+
+		Expect(err).To(MatchError(TimedOutError{50 * time.Millisecond}))
+		Expect(polls).To(BeNumerically(">", 0))
+	})
+
+	It("stops polling and returns a CanceledError when the context is canceled", func() {
+		gate.Timeout = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		err := gate.Approve(ctx, deployEventData, output)
+
+		Expect(err).To(MatchError(CanceledError{context.Canceled}))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+
+		mutex.Lock()
+		pollsAtCancel := polls
+		mutex.Unlock()
+		time.Sleep(50 * time.Millisecond)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(polls).To(Equal(pollsAtCancel))
+	})
+
+	It("returns a CanceledError when the deadline of the context passes while the approval is pending", func() {
+		gate.Timeout = time.Hour
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := gate.Approve(ctx, deployEventData, output)
+
+		Expect(err).To(MatchError(CanceledError{context.DeadlineExceeded}))
+	})
+
+	It("returns a CanceledError when the context is canceled during a call to the approval service", func() {
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer slow.Close()
+		defer close(release)
+
+		gate = NewGate(slow.URL, 10*time.Millisecond, time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := gate.Approve(ctx, deployEventData, output)
+
+		Expect(err).To(MatchError(CanceledError{context.DeadlineExceeded}))
+	})
+
+	It("returns an InvalidResponseError for an unknown status", func() {
+		statuses = []string{"maybe"}
+
+		err := gate.Approve(context.Background(), deployEventData, output)
+
+		Expect(err).To(MatchError(InvalidResponseError{`unknown status "maybe"`}))
+	})
+
+	It("returns a GateError when the approval service fails", func() {
+		server.Close()
+
+		err := gate.Approve(context.Background(), deployEventData, output)
+
+		Expect(err).To(BeAssignableToTypeOf(GateError{}))
+	})
+})
@@ -0,0 +1,46 @@
+package approval
+
+import (
+	"fmt"
+	"time"
+)
+
+type DeniedError struct {
+	Message string
+}
+
+func (e DeniedError) Error() string {
+	return fmt.Sprintf("deploy denied: %s", e.Message)
+}
+
+type TimedOutError struct {
+	Timeout time.Duration
+}
+
+func (e TimedOutError) Error() string {
+	return fmt.Sprintf("deploy was not approved within %s", e.Timeout)
+}
+
+type CanceledError struct {
+	Err error
+}
+
+func (e CanceledError) Error() string {
+	return fmt.Sprintf("stopped awaiting approval: %s", e.Err)
+}
+
+type GateError struct {
+	Err error
+}
+
+func (e GateError) Error() string {
+	return fmt.Sprintf("cannot call approval service: %s", e.Err)
+}
+
+type InvalidResponseError struct {
+	Reason string
+}
+
+func (e InvalidResponseError) Error() string {
+	return fmt.Sprintf("invalid response from approval service: %s", e.Reason)
+}
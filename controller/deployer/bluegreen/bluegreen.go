@@ -2,7 +2,6 @@
 package bluegreen
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"strings"
@@ -21,10 +20,10 @@ type BlueGreen struct {
 func (bg BlueGreen) Execute(actionCreator I.ActionCreator, environment S.Environment, response io.ReadWriter) error {
 
 	actors := make([]actor, len(environment.Foundations))
-	buffers := make([]*bytes.Buffer, len(environment.Foundations))
+	buffers := make([]*OutputBuffer, len(environment.Foundations))
 
 	for i, foundationURL := range environment.Foundations {
-		buffers[i] = &bytes.Buffer{}
+		buffers[i] = NewOutputBuffer(environment.MaxOutputKB * 1024)
 
 		action, err := actionCreator.Create(environment, buffers[i], foundationURL)
 		if err != nil {
@@ -2,10 +2,15 @@
 package courier
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 
 	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 type CourierConstructor func(executor I.Executor) I.Courier
@@ -33,6 +38,69 @@ func (c Courier) Login(foundationURL, username, password, org, space string, ski
 	return c.Executor.Execute("login", "-a", foundationURL, "-u", username, "-p", password, "-o", org, "-s", space, s)
 }
 
+// Authenticate sets the Cloud Foundry API endpoint and authenticates without targeting an org or space.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) Authenticate(foundationURL, username, password string, skipSSL bool) ([]byte, error) {
+	args := []string{"api", foundationURL}
+	if skipSSL {
+		args = append(args, "--skip-ssl-validation")
+	}
+
+	output, err := c.Executor.Execute(args...)
+	if err != nil {
+		return output, err
+	}
+
+	authOutput, err := c.Executor.Execute("auth", username, password)
+	return append(output, authOutput...), err
+}
+
+// Target targets the org and space after Authenticate.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) Target(org, space string) ([]byte, error) {
+	return c.Executor.Execute("target", "-o", org, "-s", space)
+}
+
+// OrgExists checks to see whether the org exists already.
+//
+// Returns true if the org exists.
+func (c Courier) OrgExists(org string) bool {
+	_, err := c.Executor.Execute("org", org)
+	return err == nil
+}
+
+// SpaceExists checks to see whether the space exists in the org by targeting it.
+//
+// Returns true if the space exists.
+func (c Courier) SpaceExists(org, space string) bool {
+	_, err := c.Executor.Execute("target", "-o", org, "-s", space)
+	return err == nil
+}
+
+// CreateOrg runs the Cloud Foundry create-org command.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) CreateOrg(org string) ([]byte, error) {
+	return c.Executor.Execute("create-org", org)
+}
+
+// CreateSpace runs the Cloud Foundry create-space command.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) CreateSpace(org, space string) ([]byte, error) {
+	return c.Executor.Execute("create-space", space, "-o", org)
+}
+
+// SetSpaceIsolationSegment runs the Cloud Foundry set-space-isolation-segment command. Applications of the space
+// run in the isolation segment once they are started again.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) SetSpaceIsolationSegment(space, segment string) ([]byte, error) {
+	return c.Executor.Execute("set-space-isolation-segment", space, segment)
+}
+
 func (c Courier) CreateService(service, plan, name string) ([]byte, error) {
 	return c.Executor.Execute("create-service", service, plan, name)
 }
@@ -53,6 +121,97 @@ func (c Courier) Restage(appName string) ([]byte, error) {
 	return c.Executor.Execute("restage", appName)
 }
 
+func (c Courier) Restart(appName string) ([]byte, error) {
+	return c.Executor.Execute("restart", appName)
+}
+
+// Scale runs the Cloud Foundry scale command. Empty instances or memory are left unchanged.
+// Returns the combined standard output and standard error.
+func (c Courier) Scale(appName, instances, memory string) ([]byte, error) {
+	args := []string{"scale", appName}
+	if instances != "" {
+		args = append(args, "-i", instances)
+	}
+	if memory != "" {
+		args = append(args, "-m", memory)
+	}
+
+	return c.Executor.Execute(append(args, "-f")...)
+}
+
+// SetEnv runs the Cloud Foundry set-env command.
+// Returns the combined standard output and standard error.
+func (c Courier) SetEnv(appName, name, value string) ([]byte, error) {
+	return c.Executor.Execute("set-env", appName, name, value)
+}
+
+// SetLabels runs the Cloud Foundry set-label command to apply metadata labels to the application.
+// Returns the combined standard output and standard error.
+func (c Courier) SetLabels(appName string, labels map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := []string{"set-label", "app", appName}
+	for _, key := range keys {
+		args = append(args, key+"="+labels[key])
+	}
+
+	return c.Executor.Execute(args...)
+}
+
+// SetFeature enables or disables an app feature, eg: ssh or revisions.
+// SSH is set with the Cloud Foundry enable-ssh and disable-ssh commands and other features through the v3 API.
+// Returns the combined standard output and standard error.
+func (c Courier) SetFeature(appName, feature string, enabled bool) ([]byte, error) {
+	if feature == "ssh" {
+		if enabled {
+			return c.Executor.Execute("enable-ssh", appName)
+		}
+		return c.Executor.Execute("disable-ssh", appName)
+	}
+
+	guid, err := c.AppGUID(appName)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+
+	body := fmt.Sprintf(`{"enabled":%t}`, enabled)
+	return c.Executor.Execute("curl", fmt.Sprintf("/v3/apps/%s/features/%s", guid, feature), "-X", "PATCH", "-d", body, "--fail")
+}
+
+// RunTask runs the Cloud Foundry run-task command. The task runs asynchronously;
+// use TaskState to wait for it to finish.
+// Returns the combined standard output and standard error.
+func (c Courier) RunTask(appName, command, taskName string) ([]byte, error) {
+	return c.Executor.Execute("run-task", appName, command, "--name", taskName)
+}
+
+// TaskState returns the state of the most recent task named taskName, eg: RUNNING, SUCCEEDED or FAILED.
+func (c Courier) TaskState(appName, taskName string) (string, error) {
+	output, err := c.Executor.Execute("tasks", appName)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err, output)
+	}
+
+	header := false
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if !header {
+			header = len(fields) >= 3 && fields[0] == "id" && fields[1] == "name" && fields[2] == "state"
+			continue
+		}
+
+		if len(fields) >= 3 && fields[1] == taskName {
+			return fields[2], nil
+		}
+	}
+
+	return "", fmt.Errorf("task %s not found for %s", taskName, appName)
+}
+
 func (c Courier) Start(appName string) ([]byte, error) {
 	return c.Executor.Execute("start", appName)
 }
@@ -67,11 +226,53 @@ func (c Courier) Delete(appName string) ([]byte, error) {
 	return c.Executor.Execute("delete", appName, "-f")
 }
 
-// Push runs the Cloud Foundry push command.
+// DeleteWithRoutes runs the Cloud Foundry delete command and also deletes the routes mapped to the application.
+// Returns the combined standard output and standard error.
+func (c Courier) DeleteWithRoutes(appName string) ([]byte, error) {
+	return c.Executor.Execute("delete", appName, "-f", "-r")
+}
+
+// Push runs the Cloud Foundry push command. The start command of the application is overridden
+// when command is not empty, and its buildpacks when buildpacks is not empty. The flags are added last.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) Push(appName, appLocation, hostname string, instances uint16, command string, buildpacks, flags []string) ([]byte, error) {
+	args := withStartCommand([]string{"push", appName, "-i", fmt.Sprint(instances), "-n", hostname}, command)
+	return c.Executor.ExecuteInDirectory(appLocation, withPushFlags(withBuildpacks(args, buildpacks), flags)...)
+}
+
+// PushWithManifest runs the Cloud Foundry push command with the manifest file in the application directory.
+// The start command of the application is overridden when command is not empty, and its buildpacks
+// when buildpacks is not empty. The flags are added last.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Push(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
-	return c.Executor.ExecuteInDirectory(appLocation, "push", appName, "-i", fmt.Sprint(instances), "-n", hostname)
+func (c Courier) PushWithManifest(appName, appLocation, hostname, manifestFile string, instances uint16, command string, buildpacks, flags []string) ([]byte, error) {
+	args := withStartCommand([]string{"push", appName, "-f", manifestFile, "-i", fmt.Sprint(instances), "-n", hostname}, command)
+	return c.Executor.ExecuteInDirectory(appLocation, withPushFlags(withBuildpacks(args, buildpacks), flags)...)
+}
+
+// withStartCommand adds the start command flag to the push arguments when command is not empty.
+func withStartCommand(args []string, command string) []string {
+	if command == "" {
+		return args
+	}
+	return append(args, "-c", command)
+}
+
+// withBuildpacks adds a buildpack flag to the push arguments for each buildpack, in order.
+func withBuildpacks(args []string, buildpacks []string) []string {
+	for _, buildpack := range buildpacks {
+		args = append(args, "-b", buildpack)
+	}
+	return args
+}
+
+// withPushFlags adds each push flag, eg: "-m 512M", to the push arguments as its name and its value, in order.
+func withPushFlags(args []string, flags []string) []string {
+	for _, flag := range flags {
+		args = append(args, strings.Fields(flag)...)
+	}
+	return args
 }
 
 // Rename runs the Cloud Foundry rename command.
@@ -121,6 +322,12 @@ func (c Courier) Logs(appName string) ([]byte, error) {
 	return logs, err
 }
 
+// StreamLogs runs the Cloud Foundry logs command without --recent, writing the logs of the application to output
+// as they arrive until ctx is done.
+func (c Courier) StreamLogs(ctx context.Context, appName string, output io.Writer) error {
+	return c.Executor.Stream(ctx, output, "logs", appName)
+}
+
 // Cups runs the Cloud Foundry CUPS command to create user provided
 // services.
 //
@@ -134,6 +341,202 @@ func (c Courier) Uups(appName string, body string) ([]byte, error) {
 	return c.Executor.Execute("uups", appName, "-p", body)
 }
 
+// InstanceStates returns the state of each instance of the application, eg: "running" or "crashed".
+func (c Courier) InstanceStates(appName string) ([]string, error) {
+	output, err := c.Executor.Execute("app", appName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, output)
+	}
+
+	var states []string
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		states = append(states, strings.ToLower(fields[1]))
+	}
+
+	return states, nil
+}
+
+// AppGUID returns the Cloud Foundry GUID of the application.
+func (c Courier) AppGUID(appName string) (string, error) {
+	output, err := c.Executor.Execute("app", appName, "--guid")
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err, output)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RouteGUID returns the Cloud Foundry GUID of the route hostname.domain.
+func (c Courier) RouteGUID(domain, hostname string) (string, error) {
+	route, err := c.findRoute(domain, hostname)
+	if err != nil {
+		return "", err
+	}
+	if route == nil {
+		return "", fmt.Errorf("route %s.%s not found", hostname, domain)
+	}
+
+	return route.GUID, nil
+}
+
+// RouteApps returns the names of the applications the route hostname.domain is mapped to.
+// It returns none when the route does not exist.
+func (c Courier) RouteApps(domain, hostname string) ([]string, error) {
+	route, err := c.findRoute(domain, hostname)
+	if err != nil || route == nil || len(route.Destinations) == 0 {
+		return nil, err
+	}
+
+	guids := make([]string, 0, len(route.Destinations))
+	for _, destination := range route.Destinations {
+		guids = append(guids, destination.App.GUID)
+	}
+
+	output, err := c.Executor.Execute("curl", "/v3/apps?guids="+strings.Join(guids, ","), "--fail")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, output)
+	}
+
+	var apps struct {
+		Resources []struct {
+			Name string `json:"name"`
+		} `json:"resources"`
+	}
+	err = json.Unmarshal(output, &apps)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(apps.Resources))
+	for _, app := range apps.Resources {
+		names = append(names, app.Name)
+	}
+
+	return names, nil
+}
+
+type route struct {
+	GUID         string `json:"guid"`
+	URL          string `json:"url"`
+	Destinations []struct {
+		App struct {
+			GUID string `json:"guid"`
+		} `json:"app"`
+	} `json:"destinations"`
+}
+
+// findRoute returns the route hostname.domain, or nil when it does not exist.
+func (c Courier) findRoute(domain, hostname string) (*route, error) {
+	output, err := c.Executor.Execute("curl", "/v3/routes?hosts="+hostname, "--fail")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, output)
+	}
+
+	var routes struct {
+		Resources []route `json:"resources"`
+	}
+	err = json.Unmarshal(output, &routes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range routes.Resources {
+		if r.URL == hostname+"."+domain {
+			return &r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// SpaceByGUID returns the Cloud Foundry space with the GUID and the org it belongs to.
+func (c Courier) SpaceByGUID(spaceGUID string) (S.Space, error) {
+	output, err := c.Executor.Execute("curl", "/v3/spaces/"+spaceGUID+"?include=organization", "--fail")
+	if err != nil {
+		return S.Space{}, fmt.Errorf("%s: %s", err, output)
+	}
+
+	var space struct {
+		GUID          string `json:"guid"`
+		Name          string `json:"name"`
+		Relationships struct {
+			Organization struct {
+				Data struct {
+					GUID string `json:"guid"`
+				} `json:"data"`
+			} `json:"organization"`
+		} `json:"relationships"`
+		Included struct {
+			Organizations []struct {
+				GUID string `json:"guid"`
+				Name string `json:"name"`
+			} `json:"organizations"`
+		} `json:"included"`
+	}
+	err = json.Unmarshal(output, &space)
+	if err != nil {
+		return S.Space{}, err
+	}
+
+	result := S.Space{GUID: space.GUID, Name: space.Name, OrgGUID: space.Relationships.Organization.Data.GUID}
+	for _, org := range space.Included.Organizations {
+		if org.GUID == result.OrgGUID {
+			result.OrgName = org.Name
+		}
+	}
+
+	return result, nil
+}
+
+// SetRouteWeights replaces the destinations of the route with the applications in weights, keyed by
+// application GUID. Each application receives its weight as a percentage of the route's traffic.
+// Returns the combined standard output and standard error.
+func (c Courier) SetRouteWeights(routeGUID string, weights map[string]int) ([]byte, error) {
+	guids := make([]string, 0, len(weights))
+	for guid := range weights {
+		guids = append(guids, guid)
+	}
+	sort.Strings(guids)
+
+	destinations := make([]string, 0, len(guids))
+	for _, guid := range guids {
+		destinations = append(destinations, fmt.Sprintf(`{"app":{"guid":"%s"},"weight":%d}`, guid, weights[guid]))
+	}
+
+	body := fmt.Sprintf(`{"destinations":[%s]}`, strings.Join(destinations, ","))
+	return c.Executor.Execute("curl", fmt.Sprintf("/v3/routes/%s/destinations", routeGUID), "-X", "PATCH", "-d", body, "--fail")
+}
+
+// Routes returns the routes mapped to the application, eg: "myapp.example.com".
+func (c Courier) Routes(appName string) ([]string, error) {
+	output, err := c.Executor.Execute("app", appName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, output)
+	}
+
+	var routes []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "routes:") {
+			continue
+		}
+
+		for _, route := range strings.Split(strings.TrimPrefix(line, "routes:"), ",") {
+			if route = strings.TrimSpace(route); route != "" {
+				routes = append(routes, route)
+			}
+		}
+		break
+	}
+
+	return routes, nil
+}
+
 // Exists checks to see whether the application name exists already.
 //
 // Returns true if the application exists.
@@ -156,6 +559,45 @@ func (c Courier) Domains() ([]string, error) {
 	return domains, err
 }
 
+// OrphanedServices returns the services in the current space that are bound only to appName.
+// These services are orphaned once the application is deleted.
+func (c Courier) OrphanedServices(appName string) ([]string, error) {
+	output, err := c.Executor.Execute("services")
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		services    []string
+		boundApps   = -1
+		lastOpStart = -1
+	)
+	for _, line := range strings.Split(string(output), "\n") {
+		if boundApps == -1 {
+			if strings.HasPrefix(line, "name") && strings.Contains(line, "bound apps") {
+				boundApps = strings.Index(line, "bound apps")
+				lastOpStart = strings.Index(line, "last operation")
+			}
+			continue
+		}
+
+		if len(line) <= boundApps {
+			continue
+		}
+
+		apps := line[boundApps:]
+		if lastOpStart > boundApps && len(line) > lastOpStart {
+			apps = line[boundApps:lastOpStart]
+		}
+
+		if strings.TrimSpace(apps) == appName {
+			services = append(services, strings.Fields(line)[0])
+		}
+	}
+
+	return services, nil
+}
+
 // CleanUp removes the temporary directory created by the Executor.
 func (c Courier) CleanUp() error {
 	return c.Executor.CleanUp()
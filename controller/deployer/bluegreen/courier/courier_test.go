@@ -1,13 +1,18 @@
 package courier_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier"
 	"math/rand"
+	"strings"
 
 	"github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -75,6 +80,239 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("authenticating", func() {
+		It("should authenticate without targeting an org or space", func() {
+			var (
+				foundationURL = "foundationURL-" + randomizer.StringRunes(10)
+				password      = "password-" + randomizer.StringRunes(10)
+				user          = "user-" + randomizer.StringRunes(10)
+			)
+
+			expectedArgs := []string{"auth", user, password}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.Authenticate(foundationURL, user, password, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output + output))
+		})
+
+		It("should not authenticate when the api cannot be set", func() {
+			foundationURL := "foundationURL-" + randomizer.StringRunes(10)
+
+			expectedArgs := []string{"api", foundationURL, "--skip-ssl-validation"}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = errors.New("api error")
+
+			out, err := courier.Authenticate(foundationURL, "user", "password", true)
+			Expect(err).To(MatchError("api error"))
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("targeting", func() {
+		It("should send a valid Cloud Foundry target command", func() {
+			org := "org-" + randomizer.StringRunes(10)
+			space := "space-" + randomizer.StringRunes(10)
+			expectedArgs := []string{"target", "-o", org, "-s", space}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.Target(org, space)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("creating an org", func() {
+		It("should send a valid Cloud Foundry create-org command", func() {
+			org := "org-" + randomizer.StringRunes(10)
+			expectedArgs := []string{"create-org", org}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.CreateOrg(org)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("creating a space", func() {
+		It("should send a valid Cloud Foundry create-space command", func() {
+			var (
+				org   = "org-" + randomizer.StringRunes(10)
+				space = "space-" + randomizer.StringRunes(10)
+			)
+			expectedArgs := []string{"create-space", space, "-o", org}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.CreateSpace(org, space)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("setting the isolation segment of a space", func() {
+		It("should send a valid Cloud Foundry set-space-isolation-segment command", func() {
+			var (
+				space   = "space-" + randomizer.StringRunes(10)
+				segment = "segment-" + randomizer.StringRunes(10)
+			)
+			expectedArgs := []string{"set-space-isolation-segment", space, segment}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.SetSpaceIsolationSegment(space, segment)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("checking for an existing org", func() {
+		It("should return true when the org exists", func() {
+			org := "org-" + randomizer.StringRunes(10)
+
+			Expect(courier.OrgExists(org)).To(BeTrue())
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"org", org}))
+		})
+
+		It("should return false when the org does not exist", func() {
+			executor.ExecuteCall.Returns.Error = errors.New("org not found")
+
+			Expect(courier.OrgExists("org")).To(BeFalse())
+		})
+	})
+
+	Describe("checking for an existing space", func() {
+		It("should return true when the space can be targeted", func() {
+			var (
+				org   = "org-" + randomizer.StringRunes(10)
+				space = "space-" + randomizer.StringRunes(10)
+			)
+
+			Expect(courier.SpaceExists(org, space)).To(BeTrue())
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"target", "-o", org, "-s", space}))
+		})
+
+		It("should return false when the space cannot be targeted", func() {
+			executor.ExecuteCall.Returns.Error = errors.New("space not found")
+
+			Expect(courier.SpaceExists("org", "space")).To(BeFalse())
+		})
+	})
+
+	Describe("setting labels on an app", func() {
+		It("should send a valid Cloud Foundry set-label command", func() {
+			expectedArgs := []string{"set-label", "app", appName, "build=42", "git-sha=abc123"}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.SetLabels(appName, map[string]string{"git-sha": "abc123", "build": "42"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("setting an app feature", func() {
+		It("enables ssh with the enable-ssh command", func() {
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.SetFeature(appName, "ssh", true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"enable-ssh", appName}))
+			Expect(string(out)).To(Equal(output))
+		})
+
+		It("disables ssh with the disable-ssh command", func() {
+			_, err := courier.SetFeature(appName, "ssh", false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"disable-ssh", appName}))
+		})
+
+		It("sets other features through the v3 api", func() {
+			executor.ExecuteCall.Returns.Output = []byte("app-guid")
+
+			_, err := courier.SetFeature(appName, "revisions", true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"curl", "/v3/apps/app-guid/features/revisions", "-X", "PATCH", "-d", `{"enabled":true}`, "--fail"}))
+		})
+
+		It("returns an error when the guid cannot be found", func() {
+			executor.ExecuteCall.Returns.Output = []byte("app not found")
+			executor.ExecuteCall.Returns.Error = errors.New("app error")
+
+			_, err := courier.SetFeature(appName, "revisions", false)
+			Expect(err).To(MatchError("app error: app not found"))
+		})
+	})
+
+	Describe("running a task", func() {
+		It("should send a valid Cloud Foundry run-task command", func() {
+			expectedArgs := []string{"run-task", appName, "rake db:migrate", "--name", "post-deploy-1"}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.RunTask(appName, "rake db:migrate", "post-deploy-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("getting the state of a task", func() {
+		It("returns the state of the named task", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Getting tasks for app myapp in org org / space space as user...
+OK
+
+id   name            state       start time                      command
+3    post-deploy-2   RUNNING     Wed, 10 Feb 2021 15:04:05 UTC   rake db:migrate
+2    post-deploy-1   SUCCEEDED   Tue, 09 Feb 2021 15:04:05 UTC   rake db:migrate
+`)
+
+			taskState, err := courier.TaskState(appName, "post-deploy-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"tasks", appName}))
+			Expect(taskState).To(Equal("SUCCEEDED"))
+		})
+
+		It("returns an error when the task is not found", func() {
+			executor.ExecuteCall.Returns.Output = []byte("id   name   state   start time   command\n")
+
+			_, err := courier.TaskState(appName, "post-deploy-1")
+			Expect(err).To(MatchError(fmt.Sprintf("task post-deploy-1 not found for %s", appName)))
+		})
+
+		It("returns an error when the tasks cannot be listed", func() {
+			executor.ExecuteCall.Returns.Output = []byte("tasks output")
+			executor.ExecuteCall.Returns.Error = errors.New("tasks error")
+
+			_, err := courier.TaskState(appName, "post-deploy-1")
+			Expect(err).To(MatchError("tasks error: tasks output"))
+		})
+	})
+
 	Describe("starting an app", func() {
 		It("should send a valid Cloud Foundry start command", func() {
 			expectedArgs := []string{"start", appName}
@@ -120,6 +358,21 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("deleting an app with its routes", func() {
+		It("should get a valid Cloud Foundry delete command", func() {
+			expectedArgs := []string{"delete", appName, "-f", "-r"}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.DeleteWithRoutes(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
 	Describe("pushing an application", func() {
 		It("should get a valid Cloud Foundry push command", func() {
 			var (
@@ -131,9 +384,108 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
 			executor.ExecuteInDirectoryCall.Returns.Error = nil
 
-			out, err := courier.Push(appName, appLocation, hostname, instances)
+			out, err := courier.Push(appName, appLocation, hostname, instances, "", nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("pushing an application with a start command", func() {
+		It("should override the start command", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"push", appName, "-i", "2", "-n", hostname, "-c", "bin/start --worker"}
+			)
+
+			_, err := courier.Push(appName, appLocation, hostname, 2, "bin/start --worker", nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+		})
+
+		It("should override the start command when pushing with a manifest file", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"push", appName, "-f", "manifest.yml", "-i", "2", "-n", hostname, "-c", "bin/start --worker"}
+			)
+
+			_, err := courier.PushWithManifest(appName, appLocation, hostname, "manifest.yml", 2, "bin/start --worker", nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+		})
+	})
+
+	Describe("pushing an application with buildpacks", func() {
+		It("should pass each buildpack in order", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"push", appName, "-i", "2", "-n", hostname, "-b", "nodejs_buildpack", "-b", "java_buildpack"}
+			)
+
+			_, err := courier.Push(appName, appLocation, hostname, 2, "", []string{"nodejs_buildpack", "java_buildpack"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+		})
+
+		It("should pass each buildpack after the start command when pushing with a manifest file", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"push", appName, "-f", "manifest.yml", "-i", "2", "-n", hostname, "-c", "bin/start", "-b", "java_buildpack"}
+			)
+
+			_, err := courier.PushWithManifest(appName, appLocation, hostname, "manifest.yml", 2, "bin/start", []string{"java_buildpack"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+		})
+	})
+
+	Describe("pushing an application with push flags", func() {
+		It("should pass each push flag and its value after the buildpacks", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"push", appName, "-i", "2", "-n", hostname, "-b", "java_buildpack", "-m", "512M", "-t", "180"}
+			)
+
+			_, err := courier.Push(appName, appLocation, hostname, 2, "", []string{"java_buildpack"}, []string{"-m 512M", "-t 180"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+		})
+
+		It("should pass each push flag when pushing with a manifest file", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"push", appName, "-f", "manifest.yml", "-i", "2", "-n", hostname, "--health-check-type", "port"}
+			)
+
+			_, err := courier.PushWithManifest(appName, appLocation, hostname, "manifest.yml", 2, "", nil, []string{"--health-check-type port"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+		})
+	})
+
+	Describe("pushing an application with a manifest file", func() {
+		It("should get a valid Cloud Foundry push command", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				manifestFile = "manifest-" + randomizer.StringRunes(10) + ".yml"
+				instances    = uint16(rand.Uint32())
+				expectedArgs = []string{"push", appName, "-f", manifestFile, "-i", fmt.Sprint(instances), "-n", hostname}
+			)
+
+			executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
+			executor.ExecuteInDirectoryCall.Returns.Error = nil
+
+			out, err := courier.PushWithManifest(appName, appLocation, hostname, manifestFile, instances, "", nil, nil)
 			Expect(err).ToNot(HaveOccurred())
 
+			Expect(executor.ExecuteInDirectoryCall.Received.AppLocation).To(Equal(appLocation))
 			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
 		})
@@ -256,6 +608,19 @@ var _ = Describe("Courier", func() {
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
 		})
+
+		It("should stream the Cloud Foundry logs", func() {
+			ctx := context.Background()
+			buffer := &bytes.Buffer{}
+			executor.StreamCall.Write.Output = output
+
+			err := courier.StreamLogs(ctx, appName, buffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamCall.Received.Context).To(Equal(ctx))
+			Expect(executor.StreamCall.Received.Args).To(Equal([]string{"logs", appName}))
+			Expect(buffer.String()).To(Equal(output))
+		})
 	})
 
 	Describe("checking for an existing app", func() {
@@ -385,6 +750,71 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("restart an app", func() {
+		It("should get a valid Cloud Foundry restart command", func() {
+			var (
+				appName      = "appName-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"restart", appName}
+			)
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.Restart(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("scaling an app", func() {
+		It("should get a valid Cloud Foundry scale command", func() {
+			var (
+				appName      = "appName-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"scale", appName, "-i", "3", "-m", "1G", "-f"}
+			)
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.Scale(appName, "3", "1G")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+
+		It("should leave out empty instances and memory", func() {
+			var (
+				appName      = "appName-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"scale", appName, "-i", "2", "-f"}
+			)
+
+			courier.Scale(appName, "2", "")
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+		})
+	})
+
+	Describe("setting an environment variable", func() {
+		It("should get a valid Cloud Foundry set-env command", func() {
+			var (
+				appName      = "appName-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"set-env", appName, "MY_VAR", "my value"}
+			)
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.SetEnv(appName, "MY_VAR", "my value")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
 	Describe("updating user provided services", func() {
 		It("should get a valid Cloud Foundry Uups command", func() {
 			var (
@@ -403,6 +833,186 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("getting the instance states of an app", func() {
+		It("returns the state of each instance", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Showing health and status for app myapp in org org / space space as user...
+
+name:              myapp
+requested state:   started
+routes:            myapp.example.com
+instances:         2/3
+
+     state      since                  cpu    memory         disk           details
+#0   running    2021-02-10T15:04:05Z   0.3%   120M of 1G     150M of 1G
+#1   crashed    2021-02-10T15:04:05Z   0.0%   0 of 1G        0 of 1G
+#2   running    2021-02-10T15:04:05Z   0.2%   110M of 1G     150M of 1G
+`)
+
+			states, err := courier.InstanceStates(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"app", appName}))
+			Expect(states).To(Equal([]string{"running", "crashed", "running"}))
+		})
+
+		It("returns an error when the app cannot be read", func() {
+			executor.ExecuteCall.Returns.Output = []byte("app output")
+			executor.ExecuteCall.Returns.Error = errors.New("app error")
+
+			_, err := courier.InstanceStates(appName)
+			Expect(err).To(MatchError("app error: app output"))
+		})
+	})
+
+	Describe("getting the guid of an app", func() {
+		It("returns the guid", func() {
+			executor.ExecuteCall.Returns.Output = []byte("1f2e3d4c-5b6a-7980-a1b2-c3d4e5f6a7b8\n")
+
+			guid, err := courier.AppGUID(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"app", appName, "--guid"}))
+			Expect(guid).To(Equal("1f2e3d4c-5b6a-7980-a1b2-c3d4e5f6a7b8"))
+		})
+
+		It("returns an error when the app cannot be read", func() {
+			executor.ExecuteCall.Returns.Output = []byte("app output")
+			executor.ExecuteCall.Returns.Error = errors.New("app error")
+
+			_, err := courier.AppGUID(appName)
+			Expect(err).To(MatchError("app error: app output"))
+		})
+	})
+
+	Describe("getting the guid of a route", func() {
+		It("returns the guid of the route with the domain", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`{"resources":[
+				{"guid":"route-guid-1","url":"myapp.other.example.com"},
+				{"guid":"route-guid-2","url":"myapp.apps.example.com"}
+			]}`)
+
+			guid, err := courier.RouteGUID("apps.example.com", "myapp")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"curl", "/v3/routes?hosts=myapp", "--fail"}))
+			Expect(guid).To(Equal("route-guid-2"))
+		})
+
+		It("returns an error when the route is not found", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`{"resources":[]}`)
+
+			_, err := courier.RouteGUID("apps.example.com", "myapp")
+			Expect(err).To(MatchError("route myapp.apps.example.com not found"))
+		})
+	})
+
+	Describe("getting the applications of a route", func() {
+		It("returns the names of the applications the route is mapped to", func() {
+			executor.ExecuteCall.Returns.Outputs = [][]byte{
+				[]byte(`{"resources":[
+					{"guid":"route-guid-1","url":"myapp.other.example.com","destinations":[]},
+					{"guid":"route-guid-2","url":"myapp.apps.example.com","destinations":[{"app":{"guid":"app-guid-1"}},{"app":{"guid":"app-guid-2"}}]}
+				]}`),
+				[]byte(`{"resources":[{"guid":"app-guid-1","name":"myapp"},{"guid":"app-guid-2","name":"other-app"}]}`),
+			}
+
+			apps, err := courier.RouteApps("apps.example.com", "myapp")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.AllArgs).To(Equal([][]string{
+				{"curl", "/v3/routes?hosts=myapp", "--fail"},
+				{"curl", "/v3/apps?guids=app-guid-1,app-guid-2", "--fail"},
+			}))
+			Expect(apps).To(Equal([]string{"myapp", "other-app"}))
+		})
+
+		It("returns no applications when the route does not exist", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`{"resources":[]}`)
+
+			apps, err := courier.RouteApps("apps.example.com", "myapp")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(apps).To(BeEmpty())
+			Expect(executor.ExecuteCall.TimesCalled).To(Equal(1))
+		})
+	})
+
+	Describe("getting a space by its GUID", func() {
+		It("returns the space and its org", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`{
+				"guid": "space-guid",
+				"name": "my-space",
+				"relationships": {"organization": {"data": {"guid": "org-guid"}}},
+				"included": {"organizations": [{"guid": "org-guid", "name": "my-org"}]}
+			}`)
+
+			space, err := courier.SpaceByGUID("space-guid")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"curl", "/v3/spaces/space-guid?include=organization", "--fail"}))
+			Expect(space).To(Equal(S.Space{GUID: "space-guid", Name: "my-space", OrgGUID: "org-guid", OrgName: "my-org"}))
+		})
+
+		It("returns an error when the space cannot be found", func() {
+			executor.ExecuteCall.Returns.Output = []byte("The requested URL returned error: 404")
+			executor.ExecuteCall.Returns.Error = errors.New("exit status 22")
+
+			_, err := courier.SpaceByGUID("space-guid")
+			Expect(err).To(MatchError("exit status 22: The requested URL returned error: 404"))
+		})
+	})
+
+	Describe("setting the weights of a route", func() {
+		It("replaces the destinations of the route in guid order", func() {
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.SetRouteWeights("route-guid", map[string]int{"new-guid": 10, "existing-guid": 90})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{
+				"curl", "/v3/routes/route-guid/destinations", "-X", "PATCH",
+				"-d", `{"destinations":[{"app":{"guid":"existing-guid"},"weight":90},{"app":{"guid":"new-guid"},"weight":10}]}`,
+				"--fail",
+			}))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("getting the routes of an app", func() {
+		It("returns the routes from the app summary", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Showing health and status for app myapp in org org / space space as user...
+
+name:              myapp
+requested state:   started
+routes:            myapp.example.com, myapp.example.org/path
+last uploaded:     Wed 10 Feb 15:04:05 UTC 2021
+`)
+
+			routes, err := courier.Routes(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"app", appName}))
+			Expect(routes).To(Equal([]string{"myapp.example.com", "myapp.example.org/path"}))
+		})
+
+		It("returns no routes when the app has none", func() {
+			executor.ExecuteCall.Returns.Output = []byte("name:   myapp\nroutes:\n")
+
+			routes, err := courier.Routes(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(routes).To(BeEmpty())
+		})
+
+		It("returns an error when the app cannot be read", func() {
+			executor.ExecuteCall.Returns.Output = []byte("app output")
+			executor.ExecuteCall.Returns.Error = errors.New("app error")
+
+			_, err := courier.Routes(appName)
+			Expect(err).To(MatchError("app error: app output"))
+		})
+	})
+
 	Describe("getting the list of domains", func() {
 		It("gets a valid domains command", func() {
 			expectedArgs := []string{"domains"}
@@ -420,6 +1030,37 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("getting orphaned services", func() {
+		It("should return the services only bound to the app", func() {
+			expectedArgs := []string{"services"}
+
+			executor.ExecuteCall.Returns.Output = []byte(strings.Join([]string{
+				"Getting services in org org / space space as user...",
+				"OK",
+				"",
+				"name       service         plan    bound apps             last operation",
+				"only-mine  p-mysql         100mb   " + fmt.Sprintf("%-23s", appName) + "create succeeded",
+				"shared     p-mysql         100mb   " + fmt.Sprintf("%-23s", appName+", other") + "create succeeded",
+				"ups        user-provided           " + fmt.Sprintf("%-23s", appName) + "",
+				"unbound    p-redis         small                          create succeeded",
+			}, "\n"))
+			executor.ExecuteCall.Returns.Error = nil
+
+			services, err := courier.OrphanedServices(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(services).To(Equal([]string{"only-mine", "ups"}))
+		})
+
+		It("should return an error when services fails", func() {
+			executor.ExecuteCall.Returns.Error = errors.New("services failed")
+
+			_, err := courier.OrphanedServices(appName)
+			Expect(err).To(MatchError("services failed"))
+		})
+	})
+
 	Describe("cleaning up executor directories", func() {
 		It("should be successful", func() {
 			executor.CleanUpCall.Returns.Error = nil
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+type CLINotFoundError struct {
+	Binary string
+	Err    error
+}
+
+func (e CLINotFoundError) Error() string {
+	return fmt.Sprintf("cannot run the cf cli %s: %s: set cf_cli.path or CF_CLI_PATH to the cf binary", e.Binary, e.Err)
+}
+
+type UnknownCLIVersionError struct {
+	Binary string
+	Output string
+}
+
+func (e UnknownCLIVersionError) Error() string {
+	return fmt.Sprintf("cannot find the version of the cf cli %s in %q", e.Binary, e.Output)
+}
+
+type CLIVersionTooOldError struct {
+	Binary     string
+	Version    string
+	MinVersion string
+}
+
+func (e CLIVersionTooOldError) Error() string {
+	return fmt.Sprintf("the cf cli %s is version %s, but at least %s is required", e.Binary, e.Version, e.MinVersion)
+}
+
+// CFCommandError is a cf command that failed. Command is the command with its secrets redacted, and Stderr its
+// standard error without the standard output.
+type CFCommandError struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e CFCommandError) Error() string {
+	message := fmt.Sprintf("cf %s failed with exit code %d: %s", e.Command, e.ExitCode, e.Hint())
+	if e.Stderr != "" {
+		message += ": " + e.Stderr
+	}
+	return message
+}
+
+// Hint describes what to do about the exit code of the command.
+func (e CFCommandError) Hint() string {
+	if _, ok := e.Err.(*exec.ExitError); !ok {
+		return fmt.Sprintf("the cf cli could not be run: %s", e.Err)
+	}
+
+	if hint, ok := exitCodeHints[e.ExitCode]; ok {
+		return hint
+	}
+	return "check the Cloud Foundry output for the reason"
+}
+
+// exitCodeHints are the hints for the common exit codes of cf commands.
+var exitCodeHints = map[int]string{
+	-1:  "the command was killed before it finished, it may have been canceled or timed out",
+	1:   "the command was rejected by Cloud Foundry, check the Cloud Foundry output for the reason",
+	126: "the cf cli is not executable, check the permissions of cf_cli.path",
+	127: "the cf cli or a program it runs was not found, set cf_cli.path or CF_CLI_PATH to the cf binary",
+	137: "the command was killed, the host may be out of memory",
+}
+
+func newCFCommandError(args []string, stderr []byte, err error) CFCommandError {
+	exitCode := -1
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	}
+
+	return CFCommandError{
+		Command:  redactArgs(args),
+		ExitCode: exitCode,
+		Stderr:   strings.TrimSpace(string(stderr)),
+		Err:      err,
+	}
+}
+
+// redactArgs joins the args of a cf command with the passwords of login and auth, the credentials of user provided
+// services and the value of set-env redacted.
+func redactArgs(args []string) string {
+	redacted := make([]string, 0, len(args))
+	for i, arg := range args {
+		switch {
+		case arg == "":
+			continue
+		case i > 0 && args[i-1] == "-p":
+			arg = S.RedactedValue
+		case i == 2 && args[0] == "auth":
+			arg = S.RedactedValue
+		case i == 3 && args[0] == "set-env":
+			arg = S.RedactedValue
+		}
+		redacted = append(redacted, arg)
+	}
+
+	return strings.Join(redacted, " ")
+}
@@ -2,23 +2,39 @@
 package executor
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/spf13/afero"
 )
 
-// New returns a new Executor struct.
-func New(fileSystem *afero.Afero) (Executor, error) {
-	tempDir, err := fileSystem.TempDir("", "deployadactyl-executor-")
+// DefaultBinary is the Cloud Foundry CLI binary found on the PATH.
+const DefaultBinary = "cf"
+
+// HomeDirectoryPrefix is the prefix of the name of the CF_HOME directory of an Executor.
+const HomeDirectoryPrefix = "deployadactyl-executor-"
+
+// New returns a new Executor struct that runs binary, or DefaultBinary when it is empty. Its CF_HOME is a new
+// directory in stagingDirectory, or in the temp directory of the operating system when it is empty.
+func New(fileSystem *afero.Afero, binary, stagingDirectory string) (Executor, error) {
+	tempDir, err := fileSystem.TempDir(stagingDirectory, HomeDirectoryPrefix)
 	if err != nil {
 		return Executor{}, err
 	}
 
+	if binary == "" {
+		binary = DefaultBinary
+	}
+
 	return Executor{
 		fileSystem: fileSystem,
 		tempDir:    tempDir,
+		binary:     binary,
 	}, nil
 }
 
@@ -26,25 +42,44 @@ func New(fileSystem *afero.Afero) (Executor, error) {
 type Executor struct {
 	tempDir    string
 	fileSystem *afero.Afero
+	binary     string
 }
 
 // Execute takes a slice of string args and runs them together against the cf command on the Cloud Foundry binary.
 //
-// Returns the combined standard output and standard error.
+// Returns the combined standard output and standard error. A failed command returns a CFCommandError.
 func (e Executor) Execute(args ...string) ([]byte, error) {
-	command := exec.Command("cf", args...)
+	command := exec.Command(e.binary, args...)
 	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
-	return command.CombinedOutput()
+	return run(command, args)
 }
 
 // ExecuteInDirectory does the same thing as Execute does, but does it in a specific directory.
 //
-// Returns the combined standard output and standard error.
+// Returns the combined standard output and standard error. A failed command returns a CFCommandError.
 func (e Executor) ExecuteInDirectory(directory string, args ...string) ([]byte, error) {
-	command := exec.Command("cf", args...)
+	command := exec.Command(e.binary, args...)
 	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
 	command.Dir = directory
-	return command.CombinedOutput()
+	return run(command, args)
+}
+
+// Stream does the same thing as Execute does, but writes the standard output and standard error to output as the
+// command writes them. The command is killed when ctx is done.
+func (e Executor) Stream(ctx context.Context, output io.Writer, args ...string) error {
+	command := exec.CommandContext(ctx, e.binary, args...)
+	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
+
+	combined := &lockedWriter{writer: output}
+	stderr := &bytes.Buffer{}
+	command.Stdout = combined
+	command.Stderr = io.MultiWriter(combined, stderr)
+
+	err := command.Run()
+	if err != nil {
+		return newCFCommandError(args, stderr.Bytes(), err)
+	}
+	return nil
 }
 
 // CleanUp removes the temporary directory of the Executor.
@@ -52,6 +87,35 @@ func (e Executor) CleanUp() error {
 	return e.fileSystem.RemoveAll(e.tempDir)
 }
 
+// run runs the command and returns its combined standard output and standard error. The standard error is also kept
+// on its own for the CFCommandError of a failed command.
+func run(command *exec.Cmd, args []string) ([]byte, error) {
+	output := &bytes.Buffer{}
+	combined := &lockedWriter{writer: output}
+	stderr := &bytes.Buffer{}
+	command.Stdout = combined
+	command.Stderr = io.MultiWriter(combined, stderr)
+
+	err := command.Run()
+	if err != nil {
+		return output.Bytes(), newCFCommandError(args, stderr.Bytes(), err)
+	}
+	return output.Bytes(), nil
+}
+
+// lockedWriter lets the standard output and standard error of a command be written to the same writer at once.
+type lockedWriter struct {
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.writer.Write(p)
+}
+
 func setEnv(env []string, key, value string) []string {
 	keyValuePair := key + "=" + value
 
@@ -0,0 +1,87 @@
+package executor_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Executor", func() {
+	var (
+		dir      string
+		executor Executor
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "executor-test-")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(executor.CleanUp()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	fakeCLI := func(script string) {
+		binary := filepath.Join(dir, "cf")
+		Expect(ioutil.WriteFile(binary, []byte("#!/bin/sh\n"+script+"\n"), 0755)).To(Succeed())
+
+		var err error
+		executor, err = New(&afero.Afero{Fs: afero.NewOsFs()}, binary, dir)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("returns the combined output of the command", func() {
+		fakeCLI("echo out; echo err >&2")
+
+		output, err := executor.Execute("apps")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(output)).To(ContainSubstring("out"))
+		Expect(string(output)).To(ContainSubstring("err"))
+	})
+
+	Context("when the command fails", func() {
+		It("returns a CFCommandError with the exit code and standard error", func() {
+			fakeCLI("echo out; echo 'App my-app not found' >&2; exit 1")
+
+			output, err := executor.Execute("app", "my-app")
+
+			Expect(string(output)).To(ContainSubstring("out"))
+			commandErr, ok := err.(CFCommandError)
+			Expect(ok).To(BeTrue())
+			Expect(commandErr.Command).To(Equal("app my-app"))
+			Expect(commandErr.ExitCode).To(Equal(1))
+			Expect(commandErr.Stderr).To(Equal("App my-app not found"))
+			Expect(commandErr.Error()).To(Equal("cf app my-app failed with exit code 1: the command was rejected by Cloud Foundry, check the Cloud Foundry output for the reason: App my-app not found"))
+		})
+
+		It("redacts the secrets of the command", func() {
+			fakeCLI("exit 1")
+
+			_, err := executor.Execute("login", "-a", "https://api.example.com", "-u", "user", "-p", "s3cr3t", "-o", "org", "-s", "space", "")
+			Expect(err.(CFCommandError).Command).To(Equal("login -a https://api.example.com -u user -p [REDACTED] -o org -s space"))
+
+			_, err = executor.Execute("auth", "user", "s3cr3t")
+			Expect(err.(CFCommandError).Command).To(Equal("auth user [REDACTED]"))
+
+			_, err = executor.Execute("set-env", "my-app", "API_TOKEN", "s3cr3t")
+			Expect(err.(CFCommandError).Command).To(Equal("set-env my-app API_TOKEN [REDACTED]"))
+		})
+
+		It("describes a common exit code", func() {
+			fakeCLI("exit 127")
+
+			_, err := executor.ExecuteInDirectory(dir, "push", "my-app")
+
+			Expect(err.(CFCommandError).ExitCode).To(Equal(127))
+			Expect(err.(CFCommandError).Hint()).To(ContainSubstring("set cf_cli.path or CF_CLI_PATH"))
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionPattern finds the version in the output of cf version, eg: cf version 6.20.0+25b1961-2016-06-29.
+var versionPattern = regexp.MustCompile(`version (\d+(?:\.\d+)*)`)
+
+// CheckVersion runs cf version with the Cloud Foundry CLI binary and returns its version. It returns an error when
+// the binary cannot be found or run, or is older than minVersion. Any version is accepted when minVersion is empty.
+func CheckVersion(binary, minVersion string) (string, error) {
+	if binary == "" {
+		binary = DefaultBinary
+	}
+
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return "", CLINotFoundError{binary, err}
+	}
+
+	out, err := exec.Command(path, "version").CombinedOutput()
+	if err != nil {
+		return "", CLINotFoundError{binary, err}
+	}
+
+	match := versionPattern.FindStringSubmatch(string(out))
+	if match == nil {
+		return "", UnknownCLIVersionError{binary, strings.TrimSpace(string(out))}
+	}
+	version := match[1]
+
+	if minVersion != "" && compareVersions(version, minVersion) < 0 {
+		return version, CLIVersionTooOldError{binary, version, minVersion}
+	}
+
+	return version, nil
+}
+
+// compareVersions compares dotted versions part by part. A missing part is 0, so 6.20 is 6.20.0.
+func compareVersions(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart := versionPart(aParts, i)
+		bPart := versionPart(bParts, i)
+
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+
+	part, _ := strconv.Atoi(parts[i])
+	return part
+}
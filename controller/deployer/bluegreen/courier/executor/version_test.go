@@ -0,0 +1,72 @@
+package executor_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CheckVersion", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "executor-test-")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	fakeCLI := func(output string) string {
+		binary := filepath.Join(dir, "cf")
+		script := "#!/bin/sh\necho '" + output + "'\n"
+		Expect(ioutil.WriteFile(binary, []byte(script), 0755)).To(Succeed())
+		return binary
+	}
+
+	It("returns the version of the cf cli", func() {
+		binary := fakeCLI("cf version 6.20.0+25b1961-2016-06-29")
+
+		version, err := CheckVersion(binary, "6.20")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(Equal("6.20.0"))
+	})
+
+	It("returns an error when the cf cli is older than the minimum version", func() {
+		binary := fakeCLI("cf version 6.20.0+25b1961-2016-06-29")
+
+		_, err := CheckVersion(binary, "6.53.0")
+
+		Expect(err).To(MatchError(CLIVersionTooOldError{binary, "6.20.0", "6.53.0"}))
+	})
+
+	It("compares the parts of the versions as numbers", func() {
+		binary := fakeCLI("cf version 6.100.0")
+
+		_, err := CheckVersion(binary, "6.53.0")
+
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("returns an error when the output has no version", func() {
+		binary := fakeCLI("command not recognized")
+
+		_, err := CheckVersion(binary, "")
+
+		Expect(err).To(MatchError(UnknownCLIVersionError{binary, "command not recognized"}))
+	})
+
+	It("returns an error when the cf cli cannot be found", func() {
+		_, err := CheckVersion(filepath.Join(dir, "missing"), "")
+
+		Expect(err).To(BeAssignableToTypeOf(CLINotFoundError{}))
+		Expect(err.Error()).To(ContainSubstring("set cf_cli.path or CF_CLI_PATH"))
+	})
+})
@@ -185,3 +185,117 @@ func (e RollbackStartError) Error() string {
 
 	return fmt.Sprintf("start failed: %s: rollback failed: %s", startErrs, rollbackStartErrors)
 }
+
+type FinishScaleError struct {
+	FinishScaleErrors []error
+}
+
+func (e FinishScaleError) Error() string {
+	finishScaleErrors := makeErrorString(e.FinishScaleErrors)
+
+	return fmt.Sprintf("finish scale failed: %s", finishScaleErrors)
+}
+
+type ScaleError struct {
+	Errors []error
+}
+
+func (e ScaleError) Error() string {
+	errs := makeErrorString(e.Errors)
+	return fmt.Sprintf("scale failed: %s", errs)
+}
+
+func (e ScaleError) Code() string {
+	return "ScaleError"
+}
+
+type RollbackScaleError struct {
+	ScaleErrors    []error
+	RollbackErrors []error
+}
+
+func (e RollbackScaleError) Error() string {
+	var (
+		scaleErrs           = makeErrorString(e.ScaleErrors)
+		rollbackScaleErrors = makeErrorString(e.RollbackErrors)
+	)
+
+	return fmt.Sprintf("scale failed: %s: rollback failed: %s", scaleErrs, rollbackScaleErrors)
+}
+
+type FinishRestartError struct {
+	Operation           string
+	FinishRestartErrors []error
+}
+
+func (e FinishRestartError) Error() string {
+	finishRestartErrors := makeErrorString(e.FinishRestartErrors)
+
+	return fmt.Sprintf("finish %s failed: %s", e.Operation, finishRestartErrors)
+}
+
+type RestartError struct {
+	Operation string
+	Errors    []error
+}
+
+func (e RestartError) Error() string {
+	errs := makeErrorString(e.Errors)
+	return fmt.Sprintf("%s failed: %s", e.Operation, errs)
+}
+
+func (e RestartError) Code() string {
+	return "RestartError"
+}
+
+type RollbackRestartError struct {
+	Operation      string
+	RestartErrors  []error
+	RollbackErrors []error
+}
+
+func (e RollbackRestartError) Error() string {
+	var (
+		restartErrs           = makeErrorString(e.RestartErrors)
+		rollbackRestartErrors = makeErrorString(e.RollbackErrors)
+	)
+
+	return fmt.Sprintf("%s failed: %s: rollback failed: %s", e.Operation, restartErrs, rollbackRestartErrors)
+}
+
+type FinishDeleteError struct {
+	FinishDeleteErrors []error
+}
+
+func (e FinishDeleteError) Error() string {
+	finishDeleteErrors := makeErrorString(e.FinishDeleteErrors)
+
+	return fmt.Sprintf("finish delete failed: %s", finishDeleteErrors)
+}
+
+type DeleteError struct {
+	Errors []error
+}
+
+func (e DeleteError) Error() string {
+	errs := makeErrorString(e.Errors)
+	return fmt.Sprintf("delete failed: %s", errs)
+}
+
+func (e DeleteError) Code() string {
+	return "DeleteError"
+}
+
+type RollbackDeleteError struct {
+	DeleteErrors   []error
+	RollbackErrors []error
+}
+
+func (e RollbackDeleteError) Error() string {
+	var (
+		deleteErrs           = makeErrorString(e.DeleteErrors)
+		rollbackDeleteErrors = makeErrorString(e.RollbackErrors)
+	)
+
+	return fmt.Sprintf("delete failed: %s: rollback failed: %s", deleteErrs, rollbackDeleteErrors)
+}
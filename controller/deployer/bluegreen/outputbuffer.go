@@ -0,0 +1,130 @@
+package bluegreen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/compozed/deployadactyl/config"
+)
+
+// OutputBuffer holds the Cloud Foundry output of a single foundation.
+//
+// Progress lines that do not fit in MaxBytes are dropped and replaced with a marker that counts them. Essential
+// lines, the ones that report a failure or an error, are always kept even when the buffer is full. A line that grows
+// past MaxBytes before it ends, such as a progress bar redrawn with carriage returns, is dropped as a whole. A MaxBytes
+// below 1 bounds the buffer to config.DefaultMaxOutputKB.
+type OutputBuffer struct {
+	MaxBytes int
+
+	mutex   sync.Mutex
+	buffer  bytes.Buffer
+	partial []byte
+	dropped int
+
+	// truncating is set while the rest of a line that grew past MaxBytes is dropped.
+	truncating bool
+}
+
+// NewOutputBuffer returns an OutputBuffer bounded to maxBytes.
+func NewOutputBuffer(maxBytes int) *OutputBuffer {
+	return &OutputBuffer{MaxBytes: maxBytes}
+}
+
+// Write adds the complete lines of p to the buffer. An incomplete last line is held until it is completed, unless
+// it grows past MaxBytes.
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	data := append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if !b.truncating {
+			b.writeLine(data[:i+1])
+		}
+		b.truncating = false
+		data = data[i+1:]
+	}
+
+	if !b.truncating && len(data) > b.maxBytes() {
+		b.dropped++
+		b.truncating = true
+	}
+	if b.truncating {
+		data = nil
+	}
+	b.partial = append([]byte{}, data...)
+
+	return len(p), nil
+}
+
+// Read drains the buffer.
+func (b *OutputBuffer) Read(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buffer.Read(p)
+}
+
+// WriteTo writes everything left in the buffer to w, including an incomplete last line.
+func (b *OutputBuffer) WriteTo(w io.Writer) (int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.partial) > 0 {
+		b.writeLine(b.partial)
+		b.partial = nil
+	}
+	b.writeMarker()
+
+	return b.buffer.WriteTo(w)
+}
+
+// Len returns the number of bytes held in the buffer.
+func (b *OutputBuffer) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buffer.Len()
+}
+
+func (b *OutputBuffer) maxBytes() int {
+	if b.MaxBytes < 1 {
+		return config.DefaultMaxOutputKB * 1024
+	}
+	return b.MaxBytes
+}
+
+func (b *OutputBuffer) writeLine(line []byte) {
+	if !essentialLine(string(line)) && b.buffer.Len()+len(line) > b.maxBytes() {
+		b.dropped++
+		return
+	}
+
+	b.writeMarker()
+	b.buffer.Write(line)
+}
+
+func (b *OutputBuffer) writeMarker() {
+	if b.dropped == 0 {
+		return
+	}
+
+	if b.dropped == 1 {
+		fmt.Fprintln(&b.buffer, "... 1 line of output dropped")
+	} else {
+		fmt.Fprintf(&b.buffer, "... %d lines of output dropped\n", b.dropped)
+	}
+	b.dropped = 0
+}
+
+// essentialLine reports whether a line of Cloud Foundry output reports a failure or an error.
+func essentialLine(line string) bool {
+	return strings.Contains(line, "FAILED") || strings.Contains(strings.ToLower(line), "error")
+}
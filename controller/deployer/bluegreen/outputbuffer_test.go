@@ -0,0 +1,134 @@
+package bluegreen_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OutputBuffer", func() {
+	It("is bounded to the default max_output_kb without MaxBytes", func() {
+		buffer := bluegreen.NewOutputBuffer(0)
+		output := strings.Repeat("uploading droplet\n", 1000)
+
+		fmt.Fprint(buffer, output)
+		fmt.Fprint(buffer, strings.Repeat("x", config.DefaultMaxOutputKB*1024)+"\n")
+
+		result := &bytes.Buffer{}
+		buffer.WriteTo(result)
+		Expect(result.String()).To(Equal(output + "... 1 line of output dropped\n"))
+	})
+
+	It("drops a line that grows past MaxBytes before it ends", func() {
+		buffer := bluegreen.NewOutputBuffer(20)
+
+		fmt.Fprint(buffer, "staging app\n")
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(buffer, "\rdownloading %d%%", i)
+		}
+		fmt.Fprint(buffer, "\rdownloaded\nFAILED\n")
+
+		result := &bytes.Buffer{}
+		buffer.WriteTo(result)
+		Expect(result.String()).To(Equal("staging app\n... 1 line of output dropped\nFAILED\n"))
+	})
+
+	It("drops an unfinished line that grows past MaxBytes", func() {
+		buffer := bluegreen.NewOutputBuffer(20)
+
+		fmt.Fprint(buffer, strings.Repeat("=", 1000))
+
+		Expect(buffer.Len()).To(Equal(0))
+		result := &bytes.Buffer{}
+		buffer.WriteTo(result)
+		Expect(result.String()).To(Equal("... 1 line of output dropped\n"))
+	})
+
+	It("drops progress lines that do not fit and counts them", func() {
+		buffer := bluegreen.NewOutputBuffer(20)
+
+		fmt.Fprint(buffer, "staging app\nuploading droplet\nstarting app\n")
+
+		result := &bytes.Buffer{}
+		buffer.WriteTo(result)
+		Expect(result.String()).To(Equal("staging app\n... 2 lines of output dropped\n"))
+	})
+
+	It("always keeps essential lines", func() {
+		buffer := bluegreen.NewOutputBuffer(20)
+
+		fmt.Fprint(buffer, "staging app\nuploading droplet\nFAILED\nError staging application\nstarting app\n")
+
+		result := &bytes.Buffer{}
+		buffer.WriteTo(result)
+		Expect(result.String()).To(Equal("staging app\n... 1 line of output dropped\nFAILED\nError staging application\n... 1 line of output dropped\n"))
+	})
+
+	It("joins lines that are written in pieces", func() {
+		buffer := bluegreen.NewOutputBuffer(100)
+
+		fmt.Fprint(buffer, "staging ")
+		fmt.Fprint(buffer, "app\nFAI")
+		fmt.Fprint(buffer, "LED")
+
+		result := &bytes.Buffer{}
+		buffer.WriteTo(result)
+		Expect(result.String()).To(Equal("staging app\nFAILED"))
+	})
+
+	It("stays bounded when it is read while it is written", func() {
+		const maxBytes = 1024
+		buffer := bluegreen.NewOutputBuffer(maxBytes)
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+
+			for i := 0; i < 2000; i++ {
+				fmt.Fprintf(buffer, "progress line %d\n", i)
+				if i%500 == 0 {
+					fmt.Fprintf(buffer, "FAILED at line %d\n", i)
+				}
+			}
+		}()
+
+		received := &bytes.Buffer{}
+		chunk := make([]byte, 64)
+		largest := 0
+		reading := true
+		for reading {
+			select {
+			case <-done:
+				reading = false
+			default:
+			}
+
+			if length := buffer.Len(); length > largest {
+				largest = length
+			}
+
+			n, err := buffer.Read(chunk)
+			received.Write(chunk[:n])
+			if err != nil && err != io.EOF {
+				Fail(err.Error())
+			}
+			time.Sleep(time.Millisecond)
+		}
+		buffer.WriteTo(received)
+
+		Expect(largest).To(BeNumerically("<=", maxBytes+200))
+		Expect(received.String()).To(ContainSubstring("lines of output dropped"))
+		for _, line := range []string{"FAILED at line 0", "FAILED at line 500", "FAILED at line 1000", "FAILED at line 1500"} {
+			Expect(received.String()).To(ContainSubstring(line))
+		}
+		Expect(received.String()).To(ContainSubstring("progress line 0\n"))
+	})
+})
@@ -0,0 +1,175 @@
+package deployer
+
+import (
+	"sync"
+)
+
+// Cancellation lets a running deploy be canceled until every foundation has finished pushing.
+// Once they all have, the deploy is committed and can no longer be canceled.
+//
+// The methods of a nil Cancellation report a deploy that is never canceled.
+type Cancellation struct {
+	mutex     sync.Mutex
+	pending   int
+	canceled  bool
+	committed bool
+	onCancel  func()
+}
+
+// Add records a foundation, or an application on a foundation, that has started pushing.
+func (c *Cancellation) Add() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pending++
+}
+
+// Done records a push that has finished. It returns false when the deploy was canceled.
+// The deploy is committed once every push is done.
+func (c *Cancellation) Done() bool {
+	if c == nil {
+		return true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.canceled {
+		return false
+	}
+
+	c.pending--
+	if c.pending <= 0 {
+		c.committed = true
+	}
+
+	return true
+}
+
+// Canceled reports whether the deploy was canceled.
+func (c *Cancellation) Canceled() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.canceled
+}
+
+// Cancel cancels the deploy. It returns false when the deploy is committed or was already canceled.
+func (c *Cancellation) Cancel() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mutex.Lock()
+	if c.canceled || c.committed {
+		c.mutex.Unlock()
+		return false
+	}
+	c.canceled = true
+	onCancel := c.onCancel
+	c.mutex.Unlock()
+
+	if onCancel != nil {
+		onCancel()
+	}
+	return true
+}
+
+// OnCancel sets a function that is called when the deploy is canceled, so a deploy that waits can stop waiting.
+func (c *Cancellation) OnCancel(onCancel func()) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.onCancel = onCancel
+}
+
+// Cancellations tracks the running deploys that can be canceled by their UUID.
+type Cancellations struct {
+	running map[string]cancelable
+	mutex   sync.Mutex
+}
+
+type cancelable struct {
+	cfContext    string
+	cancellation *Cancellation
+}
+
+// NewCancellations returns Cancellations without any running deploy.
+func NewCancellations() *Cancellations {
+	return &Cancellations{running: map[string]cancelable{}}
+}
+
+// Register records a running deploy of the application and returns its Cancellation. It returns nil, a Cancellation
+// that is never canceled, when a deploy with the same UUID is already running, so the deploy that registered the UUID
+// first stays the one that is canceled.
+func (c *Cancellations) Register(uuid, environment, org, space, appName string) *Cancellation {
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.running[uuid]; ok {
+		return nil
+	}
+
+	cancellation := &Cancellation{}
+	c.running[uuid] = cancelable{cfContextKey(environment, org, space, appName), cancellation}
+
+	return cancellation
+}
+
+// Cancel cancels the running deploy of the application with the UUID.
+// It returns a DeployNotFoundError when there is no such deploy and a DeployNotCancelableError
+// when the deploy was already canceled or has finished pushing to every foundation.
+func (c *Cancellations) Cancel(uuid, environment, org, space, appName string) error {
+	if c == nil {
+		return DeployNotFoundError{UUID: uuid, AppName: appName}
+	}
+
+	c.mutex.Lock()
+	running, ok := c.running[uuid]
+	c.mutex.Unlock()
+
+	if !ok || running.cfContext != cfContextKey(environment, org, space, appName) {
+		return DeployNotFoundError{UUID: uuid, AppName: appName}
+	}
+
+	if !running.cancellation.Cancel() {
+		return DeployNotCancelableError{UUID: uuid}
+	}
+
+	return nil
+}
+
+// Remove forgets the deploy with the UUID once it has finished. Only the deploy that registered the cancellation
+// can remove it.
+func (c *Cancellations) Remove(uuid string, cancellation *Cancellation) {
+	if c == nil || cancellation == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.running[uuid].cancellation == cancellation {
+		delete(c.running, uuid)
+	}
+}
+
+func cfContextKey(environment, org, space, appName string) string {
+	return environment + "/" + org + "/" + space + "/" + appName
+}
@@ -0,0 +1,104 @@
+package deployer_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/compozed/deployadactyl/controller/deployer"
+)
+
+var _ = Describe("Cancellations", func() {
+	var cancellations *Cancellations
+
+	BeforeEach(func() {
+		cancellations = NewCancellations()
+	})
+
+	It("cancels a running deploy", func() {
+		cancellation := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		cancellation.Add()
+
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).To(Succeed())
+
+		Expect(cancellation.Canceled()).To(BeTrue())
+		Expect(cancellation.Done()).To(BeFalse())
+	})
+
+	It("calls the function set with OnCancel once when the deploy is canceled", func() {
+		calls := 0
+		cancellation := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		cancellation.OnCancel(func() { calls++ })
+
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).To(Succeed())
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).ToNot(Succeed())
+
+		Expect(calls).To(Equal(1))
+	})
+
+	It("does not cancel a deploy twice", func() {
+		cancellations.Register("my-uuid", "prod", "org", "space", "myApp").Add()
+
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).To(Succeed())
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).To(MatchError(DeployNotCancelableError{UUID: "my-uuid"}))
+	})
+
+	It("does not cancel a deploy that has finished pushing to every foundation", func() {
+		cancellation := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		cancellation.Add()
+		cancellation.Add()
+
+		Expect(cancellation.Done()).To(BeTrue())
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).To(Succeed())
+
+		cancellation = cancellations.Register("other-uuid", "prod", "org", "space", "myApp")
+		cancellation.Add()
+		cancellation.Add()
+
+		Expect(cancellation.Done()).To(BeTrue())
+		Expect(cancellation.Done()).To(BeTrue())
+		Expect(cancellations.Cancel("other-uuid", "prod", "org", "space", "myApp")).To(MatchError(DeployNotCancelableError{UUID: "other-uuid"}))
+		Expect(cancellation.Canceled()).To(BeFalse())
+	})
+
+	It("does not find a deploy of another application", func() {
+		cancellations.Register("my-uuid", "prod", "org", "space", "myApp").Add()
+
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "otherApp")).To(MatchError(DeployNotFoundError{UUID: "my-uuid", AppName: "otherApp"}))
+	})
+
+	It("does not find a deploy that was removed", func() {
+		cancellation := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		cancellation.Add()
+		cancellations.Remove("my-uuid", cancellation)
+
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).To(MatchError(DeployNotFoundError{UUID: "my-uuid", AppName: "myApp"}))
+	})
+
+	It("keeps the first deploy of a UUID that is registered twice", func() {
+		first := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		first.Add()
+
+		second := cancellations.Register("my-uuid", "prod", "org", "space", "myApp")
+		Expect(second).To(BeNil())
+
+		cancellations.Remove("my-uuid", second)
+
+		Expect(cancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).To(Succeed())
+		Expect(first.Canceled()).To(BeTrue())
+	})
+
+	It("does not find a deploy in nil Cancellations", func() {
+		var nilCancellations *Cancellations
+
+		Expect(nilCancellations.Cancel("my-uuid", "prod", "org", "space", "myApp")).To(MatchError(DeployNotFoundError{UUID: "my-uuid", AppName: "myApp"}))
+	})
+
+	It("never cancels a nil Cancellation", func() {
+		var cancellation *Cancellation
+
+		cancellation.Add()
+		Expect(cancellation.Done()).To(BeTrue())
+		Expect(cancellation.Canceled()).To(BeFalse())
+		Expect(cancellation.Cancel()).To(BeFalse())
+	})
+})
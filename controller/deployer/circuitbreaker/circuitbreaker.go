@@ -0,0 +1,147 @@
+// Package circuitbreaker stops deploys to an environment whose Cloud Foundry foundations keep failing.
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+const (
+	// Closed lets deploys through.
+	Closed = "closed"
+	// Open rejects deploys until the cooldown has passed.
+	Open = "open"
+	// HalfOpen lets a single deploy through to test whether the foundations have recovered.
+	HalfOpen = "half-open"
+)
+
+// Status is the state of the circuit for a single environment.
+type Status struct {
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenedAt            time.Time `json:"opened_at"`
+}
+
+type circuit struct {
+	Status
+	trial bool
+}
+
+// Breaker keeps a circuit per environment. A circuit opens after Threshold consecutive
+// failures and stays open for Cooldown before a single trial deploy is let through.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	Log       I.Logger
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewBreaker returns a Breaker with every circuit closed.
+func NewBreaker(threshold int, cooldown time.Duration, log I.Logger) *Breaker {
+	return &Breaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		Log:       log,
+		Now:       time.Now,
+		circuits:  map[string]*circuit{},
+	}
+}
+
+// Allow returns a CircuitOpenError if deploys to the environment should be short-circuited.
+// Once the cooldown has passed the circuit half-opens and only the first caller is allowed through.
+func (b *Breaker) Allow(environment string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.circuit(environment)
+
+	switch c.State {
+	case Open:
+		elapsed := b.now().Sub(c.OpenedAt)
+		if elapsed < b.Cooldown {
+			return CircuitOpenError{environment, b.Cooldown - elapsed}
+		}
+
+		b.transition(environment, c, HalfOpen)
+		c.trial = true
+		return nil
+	case HalfOpen:
+		if c.trial {
+			return CircuitOpenError{environment, 0}
+		}
+		c.trial = true
+	}
+
+	return nil
+}
+
+// Record updates the circuit with the outcome of a deploy that was allowed through.
+// failed should only be true when the deploy failed because of the Cloud Foundry foundations.
+func (b *Breaker) Record(environment string, failed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.circuit(environment)
+	c.trial = false
+
+	if !failed {
+		c.ConsecutiveFailures = 0
+		c.OpenedAt = time.Time{}
+		if c.State != Closed {
+			b.transition(environment, c, Closed)
+		}
+		return
+	}
+
+	c.ConsecutiveFailures++
+	if c.State == HalfOpen || (c.State == Closed && c.ConsecutiveFailures >= b.Threshold) {
+		c.OpenedAt = b.now()
+		b.transition(environment, c, Open)
+	}
+}
+
+// Statuses returns the status of every environment that has been deployed to.
+func (b *Breaker) Statuses() map[string]Status {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	statuses := make(map[string]Status, len(b.circuits))
+	for environment, c := range b.circuits {
+		statuses[environment] = c.Status
+	}
+
+	return statuses
+}
+
+func (b *Breaker) circuit(environment string) *circuit {
+	if b.circuits == nil {
+		b.circuits = map[string]*circuit{}
+	}
+
+	c, ok := b.circuits[environment]
+	if !ok {
+		c = &circuit{Status: Status{State: Closed}}
+		b.circuits[environment] = c
+	}
+
+	return c
+}
+
+func (b *Breaker) transition(environment string, c *circuit, state string) {
+	b.Log.Infof("circuit for environment %s changed from %s to %s after %d consecutive failures", environment, c.State, state, c.ConsecutiveFailures)
+	c.State = state
+}
+
+func (b *Breaker) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,101 @@
+package circuitbreaker_test
+
+import (
+	"time"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/circuitbreaker"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/randomizer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Breaker", func() {
+	var (
+		breaker     *Breaker
+		logBuffer   *Buffer
+		environment string
+		now         time.Time
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		environment = "environment-" + randomizer.StringRunes(10)
+		now = time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		breaker = NewBreaker(2, time.Minute, I.DefaultLogger(logBuffer, logging.DEBUG, "circuitbreaker_test"))
+		breaker.Now = func() time.Time { return now }
+	})
+
+	fail := func() {
+		Expect(breaker.Allow(environment)).To(Succeed())
+		breaker.Record(environment, true)
+	}
+
+	It("allows deploys while the circuit is closed", func() {
+		fail()
+
+		Expect(breaker.Allow(environment)).To(Succeed())
+		Expect(breaker.Statuses()[environment]).To(Equal(Status{State: Closed, ConsecutiveFailures: 1}))
+	})
+
+	It("opens after the threshold of consecutive failures", func() {
+		fail()
+		fail()
+
+		Expect(breaker.Allow(environment)).To(MatchError(CircuitOpenError{environment, time.Minute}))
+		Expect(breaker.Statuses()[environment]).To(Equal(Status{State: Open, ConsecutiveFailures: 2, OpenedAt: now}))
+		Eventually(logBuffer).Should(Say("circuit for environment %s changed from closed to open after 2 consecutive failures", environment))
+	})
+
+	It("resets the consecutive failures after a success", func() {
+		fail()
+		Expect(breaker.Allow(environment)).To(Succeed())
+		breaker.Record(environment, false)
+		fail()
+
+		Expect(breaker.Allow(environment)).To(Succeed())
+	})
+
+	It("keeps circuits for each environment separately", func() {
+		fail()
+		fail()
+
+		Expect(breaker.Allow("other-environment")).To(Succeed())
+	})
+
+	Context("when the cooldown has passed", func() {
+		BeforeEach(func() {
+			fail()
+			fail()
+			now = now.Add(time.Minute)
+		})
+
+		It("half-opens and lets a single trial deploy through", func() {
+			Expect(breaker.Allow(environment)).To(Succeed())
+			Expect(breaker.Allow(environment)).To(MatchError(CircuitOpenError{environment, 0}))
+
+			Expect(breaker.Statuses()[environment].State).To(Equal(HalfOpen))
+			Eventually(logBuffer).Should(Say("changed from open to half-open"))
+		})
+
+		It("closes when the trial deploy succeeds", func() {
+			Expect(breaker.Allow(environment)).To(Succeed())
+			breaker.Record(environment, false)
+
+			Expect(breaker.Allow(environment)).To(Succeed())
+			Expect(breaker.Statuses()[environment]).To(Equal(Status{State: Closed}))
+			Eventually(logBuffer).Should(Say("changed from half-open to closed"))
+		})
+
+		It("opens again when the trial deploy fails", func() {
+			Expect(breaker.Allow(environment)).To(Succeed())
+			breaker.Record(environment, true)
+
+			Expect(breaker.Allow(environment)).To(MatchError(CircuitOpenError{environment, time.Minute}))
+			Expect(breaker.Statuses()[environment].OpenedAt).To(Equal(now))
+		})
+	})
+})
@@ -0,0 +1,47 @@
+package circuitbreaker
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/controller/deployer/prechecker"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// Deployer wraps a Deployer and short-circuits deploys to environments whose circuit is open.
+type Deployer struct {
+	Deployer I.Deployer
+	Breaker  *Breaker
+	Log      I.DeploymentLogger
+}
+
+// Deploy returns http.StatusServiceUnavailable without deploying when the circuit for the environment is open.
+// Otherwise it deploys and records whether the deploy failed because of the Cloud Foundry foundations.
+func (d Deployer) Deploy(deploymentInfo *S.DeploymentInfo, env S.Environment, actionCreator I.ActionCreator, response io.ReadWriter) *I.DeployResponse {
+	err := d.Breaker.Allow(env.Name)
+	if err != nil {
+		d.Log.Error(err)
+		return &I.DeployResponse{
+			StatusCode:     http.StatusServiceUnavailable,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	deployResponse := d.Deployer.Deploy(deploymentInfo, env, actionCreator, response)
+	d.Breaker.Record(env.Name, IsFoundationError(deployResponse.Error))
+
+	return deployResponse
+}
+
+// IsFoundationError reports whether a deploy failed because the Cloud Foundry foundations
+// could not be reached or logged into, rather than because of the application.
+func IsFoundationError(err error) bool {
+	switch err.(type) {
+	case prechecker.InvalidGetRequestError, prechecker.FoundationUnavailableError, bluegreen.LoginError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package circuitbreaker_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	. "github.com/compozed/deployadactyl/controller/deployer/circuitbreaker"
+	"github.com/compozed/deployadactyl/controller/deployer/prechecker"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Deployer", func() {
+	var (
+		deployer       Deployer
+		wrapped        *mocks.Deployer
+		breaker        *Breaker
+		logBuffer      *Buffer
+		environment    S.Environment
+		deploymentInfo *S.DeploymentInfo
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		log := I.DeploymentLogger{Log: I.DefaultLogger(logBuffer, logging.DEBUG, "circuitbreaker_deployer_test")}
+
+		wrapped = &mocks.Deployer{}
+		breaker = NewBreaker(1, time.Minute, log.Log)
+		environment = S.Environment{Name: "production"}
+		deploymentInfo = &S.DeploymentInfo{}
+
+		deployer = Deployer{Deployer: wrapped, Breaker: breaker, Log: log}
+	})
+
+	It("deploys while the circuit is closed", func() {
+		wrapped.DeployCall.Returns.StatusCode = http.StatusOK
+
+		deployResponse := deployer.Deploy(deploymentInfo, environment, &mocks.PushManager{}, &bytes.Buffer{})
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusOK))
+		Expect(wrapped.DeployCall.Called).To(Equal(1))
+	})
+
+	It("opens the circuit after a foundation failure and rejects deploys with http.StatusServiceUnavailable", func() {
+		wrapped.DeployCall.Returns.Error = bluegreen.LoginError{LoginErrors: []error{errors.New("login failed")}}
+		deployer.Deploy(deploymentInfo, environment, &mocks.PushManager{}, &bytes.Buffer{})
+
+		deployResponse := deployer.Deploy(deploymentInfo, environment, &mocks.PushManager{}, &bytes.Buffer{})
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(deployResponse.Error).To(BeAssignableToTypeOf(CircuitOpenError{}))
+		Expect(deployResponse.Error.Error()).To(ContainSubstring("circuit open"))
+		Expect(deployResponse.DeploymentInfo).To(Equal(deploymentInfo))
+		Expect(wrapped.DeployCall.Called).To(Equal(1))
+		Eventually(logBuffer).Should(Say("circuit open for environment production"))
+	})
+
+	It("does not count application failures", func() {
+		wrapped.DeployCall.Returns.Error = errors.New("push failed")
+		deployer.Deploy(deploymentInfo, environment, &mocks.PushManager{}, &bytes.Buffer{})
+
+		deployer.Deploy(deploymentInfo, environment, &mocks.PushManager{}, &bytes.Buffer{})
+
+		Expect(wrapped.DeployCall.Called).To(Equal(2))
+	})
+
+	Describe("IsFoundationError", func() {
+		It("is true for prechecker and login errors", func() {
+			Expect(IsFoundationError(prechecker.FoundationUnavailableError{})).To(BeTrue())
+			Expect(IsFoundationError(prechecker.InvalidGetRequestError{})).To(BeTrue())
+			Expect(IsFoundationError(bluegreen.LoginError{})).To(BeTrue())
+		})
+
+		It("is false for other errors", func() {
+			Expect(IsFoundationError(nil)).To(BeFalse())
+			Expect(IsFoundationError(prechecker.NoFoundationsConfiguredError{})).To(BeFalse())
+			Expect(IsFoundationError(bluegreen.PushError{})).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,15 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"time"
+)
+
+type CircuitOpenError struct {
+	Environment string
+	RetryAfter  time.Duration
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for environment %s: too many consecutive Cloud Foundry failures, retry in %s", e.Environment, e.RetryAfter)
+}
@@ -0,0 +1,71 @@
+package deployer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/constants"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// Cooldowns records when each application was last deployed successfully, by its environment, org, space and
+// name, so that another deploy of the application can be held off until the cooldown of its environment has passed.
+//
+// The times are kept in memory only. They are lost when deployadactyl restarts, so the first deploy of an
+// application after a restart is never held off.
+//
+// Cooldowns is a Handler and must be added to the EventManager for the deploy.success event.
+type Cooldowns struct {
+	deploys map[string]time.Time
+	mutex   sync.Mutex
+}
+
+// NewCooldowns returns Cooldowns that have not recorded any deploys.
+func NewCooldowns() *Cooldowns {
+	return &Cooldowns{deploys: map[string]time.Time{}}
+}
+
+// OnEvent records the time of a successful deploy. Replayed events and deploys of every application in a
+// manifest are not recorded.
+func (c *Cooldowns) OnEvent(event I.Event) error {
+	if event.Type != constants.DeploySuccessEvent || event.Replay {
+		return nil
+	}
+
+	data, ok := event.Data.(*S.DeployEventData)
+	if !ok || data.DeploymentInfo == nil || data.DeploymentInfo.AppName == "" {
+		return nil
+	}
+	info := data.DeploymentInfo
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.deploys[cooldownKey(info.Environment, info.Org, info.Space, info.AppName)] = time.Now()
+
+	return nil
+}
+
+// Remaining returns how long a deploy of the application must wait until cooldown has passed since its last
+// successful deploy. It returns zero when the application may be deployed.
+func (c *Cooldowns) Remaining(environment, org, space, appName string, cooldown time.Duration) time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	last, ok := c.deploys[cooldownKey(environment, org, space, appName)]
+	if !ok {
+		return 0
+	}
+
+	remaining := cooldown - time.Since(last)
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+func cooldownKey(environment, org, space, appName string) string {
+	return environment + "/" + org + "/" + space + "/" + appName
+}
@@ -0,0 +1,65 @@
+package deployer_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/compozed/deployadactyl/constants"
+	. "github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+var _ = Describe("Cooldowns", func() {
+	var cooldowns *Cooldowns
+
+	event := func(eventType, appName string) I.Event {
+		return I.Event{Type: eventType, Data: &S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{
+			Environment: "my-env",
+			Org:         "my-org",
+			Space:       "my-space",
+			AppName:     appName,
+		}}}
+	}
+
+	BeforeEach(func() {
+		cooldowns = NewCooldowns()
+	})
+
+	It("holds off a deploy until the cooldown has passed since the last successful deploy", func() {
+		Expect(cooldowns.OnEvent(event(constants.DeploySuccessEvent, "my-app"))).To(Succeed())
+
+		remaining := cooldowns.Remaining("my-env", "my-org", "my-space", "my-app", time.Minute)
+
+		Expect(remaining).To(BeNumerically(">", 59*time.Second))
+		Expect(remaining).To(BeNumerically("<=", time.Minute))
+	})
+
+	It("does not hold off a deploy once the cooldown has passed", func() {
+		cooldowns.OnEvent(event(constants.DeploySuccessEvent, "my-app"))
+
+		Eventually(func() time.Duration {
+			return cooldowns.Remaining("my-env", "my-org", "my-space", "my-app", 10*time.Millisecond)
+		}).Should(BeZero())
+	})
+
+	It("does not hold off applications that were never deployed", func() {
+		cooldowns.OnEvent(event(constants.DeploySuccessEvent, "my-app"))
+
+		Expect(cooldowns.Remaining("my-env", "my-org", "my-space", "other-app", time.Minute)).To(BeZero())
+		Expect(cooldowns.Remaining("other-env", "my-org", "my-space", "my-app", time.Minute)).To(BeZero())
+	})
+
+	It("does not record failed or replayed deploys", func() {
+		cooldowns.OnEvent(event(constants.DeployFailureEvent, "failed-app"))
+
+		replayed := event(constants.DeploySuccessEvent, "replayed-app")
+		replayed.Replay = true
+		cooldowns.OnEvent(replayed)
+
+		Expect(cooldowns.Remaining("my-env", "my-org", "my-space", "failed-app", time.Minute)).To(BeZero())
+		Expect(cooldowns.Remaining("my-env", "my-org", "my-space", "replayed-app", time.Minute)).To(BeZero())
+	})
+})
@@ -11,6 +11,7 @@ import (
 	"os"
 
 	"encoding/base64"
+	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/config"
 	I "github.com/compozed/deployadactyl/interfaces"
 	S "github.com/compozed/deployadactyl/structs"
@@ -34,6 +35,8 @@ AppName:      %s`
 )
 
 type SilentDeployer struct {
+	// Client sends the silent deploy. A client that skips TLS verification is created for each deploy when it is nil.
+	Client I.Client
 }
 
 func (d SilentDeployer) Deploy(deploymentInfo *S.DeploymentInfo, env S.Environment, actionCreator I.ActionCreator, response io.ReadWriter) *I.DeployResponse {
@@ -44,24 +47,29 @@ func (d SilentDeployer) Deploy(deploymentInfo *S.DeploymentInfo, env S.Environme
 	if err != nil {
 		log.Println(fmt.Sprintf("Silent deployer request err: %s", err))
 		deployResponse.Error = err
+		return deployResponse
 	}
 	usernamePassword := base64.StdEncoding.EncodeToString([]byte(deploymentInfo.Username + ":" + deploymentInfo.Password))
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("Accept", "application/json")
 	request.Header.Set("Authorization", usernamePassword)
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	client := d.Client
+	if client == nil {
+		client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
 	}
 
-	client := &http.Client{Transport: tr}
-
 	resp, err := client.Do(request)
 	if err != nil {
 		log.Println(fmt.Sprintf("Silent deployer response err: %s", err))
-		deployResponse.StatusCode = resp.StatusCode
 		deployResponse.Error = err
+		return deployResponse
 	}
+	resp.Body.Close()
 
 	deployResponse.StatusCode = resp.StatusCode
 	deployResponse.Error = err
@@ -97,6 +105,14 @@ func (d Deployer) Deploy(deploymentInfo *S.DeploymentInfo, env S.Environment, ac
 	err = actionCreator.SetUp()
 	if err != nil {
 		deployResponse.StatusCode = http.StatusInternalServerError
+		switch err.(type) {
+		case TooManyInstancesError, ResourceLimitError, ManifestOverrideError, artifetcher.EmptyArtifactError:
+			deployResponse.StatusCode = http.StatusBadRequest
+		case ScanFailedError:
+			deployResponse.StatusCode = http.StatusUnprocessableEntity
+		case RetirementNotFoundError:
+			deployResponse.StatusCode = http.StatusNotFound
+		}
 		deployResponse.Error = err
 		return deployResponse
 	}
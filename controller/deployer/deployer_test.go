@@ -14,6 +14,7 @@ import (
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
 
+	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/interfaces"
@@ -360,6 +361,50 @@ var _ = Describe("Deployer", func() {
 					Expect(deployResponse.StatusCode).To(Equal(http.StatusInternalServerError))
 				})
 			})
+
+			Context("when the manifest asks for too many instances", func() {
+				It("returns http.StatusBadRequest", func() {
+					pusherCreator.SetUpCall.Returns.Err = TooManyInstancesError{"myApp", 5000, 10}
+
+					deployResponse := deployer.Deploy(&deploymentInfo, S.Environment{}, pusherCreator, response)
+
+					Expect(deployResponse.Error).To(MatchError("cannot deploy 5000 instances of myApp: the environment allows at most 10"))
+					Expect(deployResponse.StatusCode).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("when the artifact is empty", func() {
+				It("returns http.StatusBadRequest", func() {
+					pusherCreator.SetUpCall.Returns.Err = artifetcher.EmptyArtifactError{"https://example.com/app.zip"}
+
+					deployResponse := deployer.Deploy(&deploymentInfo, S.Environment{}, pusherCreator, response)
+
+					Expect(deployResponse.Error).To(MatchError("the artifact at https://example.com/app.zip is empty"))
+					Expect(deployResponse.StatusCode).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("when the request overrides the manifest in an environment that rejects overrides", func() {
+				It("returns http.StatusBadRequest", func() {
+					pusherCreator.SetUpCall.Returns.Err = ManifestOverrideError{"my-app", []string{"memory 1G instead of 512M"}}
+
+					deployResponse := deployer.Deploy(&deploymentInfo, S.Environment{}, pusherCreator, response)
+
+					Expect(deployResponse.Error).To(MatchError("cannot deploy my-app: the request overrides its manifest: memory 1G instead of 512M"))
+					Expect(deployResponse.StatusCode).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("when the artifact scan has findings", func() {
+				It("returns http.StatusUnprocessableEntity", func() {
+					pusherCreator.SetUpCall.Returns.Err = ScanFailedError{[]string{"AWS secret key in config/app.yml"}}
+
+					deployResponse := deployer.Deploy(&deploymentInfo, S.Environment{}, pusherCreator, response)
+
+					Expect(deployResponse.Error).To(MatchError("the artifact scan failed: AWS secret key in config/app.yml"))
+					Expect(deployResponse.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+				})
+			})
 		})
 	})
 
@@ -0,0 +1,33 @@
+package drain
+
+import (
+	"io"
+	"net/http"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// Deployer wraps a Deployer and rejects deploys while the node is draining.
+type Deployer struct {
+	Deployer I.Deployer
+	Gate     *Gate
+	Log      I.DeploymentLogger
+}
+
+// Deploy returns http.StatusServiceUnavailable without deploying when the Gate is draining.
+// Otherwise the deploy is counted as in flight until it finishes.
+func (d Deployer) Deploy(deploymentInfo *S.DeploymentInfo, env S.Environment, actionCreator I.ActionCreator, response io.ReadWriter) *I.DeployResponse {
+	err := d.Gate.Enter()
+	if err != nil {
+		d.Log.Error(err)
+		return &I.DeployResponse{
+			StatusCode:     http.StatusServiceUnavailable,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+	defer d.Gate.Leave()
+
+	return d.Deployer.Deploy(deploymentInfo, env, actionCreator, response)
+}
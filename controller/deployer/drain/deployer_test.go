@@ -0,0 +1,70 @@
+package drain_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/drain"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+type gateCheckingDeployer struct {
+	gate     *Gate
+	inFlight int
+}
+
+func (d *gateCheckingDeployer) Deploy(deploymentInfo *S.DeploymentInfo, env S.Environment, actionCreator I.ActionCreator, response io.ReadWriter) *I.DeployResponse {
+	d.inFlight = d.gate.Status().InFlight
+	return &I.DeployResponse{StatusCode: http.StatusOK}
+}
+
+var _ = Describe("Deployer", func() {
+	var (
+		deployer       Deployer
+		gate           *Gate
+		logBuffer      *Buffer
+		deploymentInfo *S.DeploymentInfo
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		log := I.DeploymentLogger{Log: I.DefaultLogger(logBuffer, logging.DEBUG, "drain_deployer_test")}
+
+		gate = NewGate(log.Log)
+		deploymentInfo = &S.DeploymentInfo{}
+
+		deployer = Deployer{Deployer: &mocks.Deployer{}, Gate: gate, Log: log}
+	})
+
+	It("counts the deploy as in flight while it runs", func() {
+		wrapped := &gateCheckingDeployer{gate: gate}
+		deployer.Deployer = wrapped
+
+		deployResponse := deployer.Deploy(deploymentInfo, S.Environment{}, &mocks.PushManager{}, &bytes.Buffer{})
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusOK))
+		Expect(wrapped.inFlight).To(Equal(1))
+		Expect(gate.Status().InFlight).To(Equal(0))
+	})
+
+	It("rejects deploys with http.StatusServiceUnavailable while draining", func() {
+		wrapped := &mocks.Deployer{}
+		deployer.Deployer = wrapped
+		gate.Drain()
+
+		deployResponse := deployer.Deploy(deploymentInfo, S.Environment{}, &mocks.PushManager{}, &bytes.Buffer{})
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(deployResponse.Error).To(MatchError(DrainingError{}))
+		Expect(deployResponse.DeploymentInfo).To(Equal(deploymentInfo))
+		Expect(wrapped.DeployCall.Called).To(Equal(0))
+		Expect(logBuffer).To(Say("node is draining"))
+	})
+})
@@ -0,0 +1,88 @@
+// Package drain stops a node from accepting deploys so it can be restarted without losing any.
+package drain
+
+import (
+	"sync"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// Status reports whether the node is draining and how many deploys are still running.
+type Status struct {
+	Draining bool `json:"draining"`
+	InFlight int  `json:"in_flight"`
+}
+
+// Gate counts the deploys running on the node. While it is draining new deploys are rejected
+// and the running ones are left to finish.
+type Gate struct {
+	Log I.Logger
+
+	mutex    sync.Mutex
+	draining bool
+	inFlight int
+}
+
+// NewGate returns a Gate that accepts deploys.
+func NewGate(log I.Logger) *Gate {
+	return &Gate{Log: log}
+}
+
+// Enter records the start of a deploy. It returns a DrainingError if the node is draining.
+func (g *Gate) Enter() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.draining {
+		return DrainingError{}
+	}
+
+	g.inFlight++
+	return nil
+}
+
+// Leave records the end of a deploy started with Enter.
+func (g *Gate) Leave() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.inFlight--
+}
+
+// Drain stops the Gate from accepting deploys.
+func (g *Gate) Drain() Status {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.draining {
+		g.Log.Infof("draining: new deploys are rejected, %d deploys in flight", g.inFlight)
+	}
+	g.draining = true
+
+	return g.status()
+}
+
+// Undrain lets the Gate accept deploys again.
+func (g *Gate) Undrain() Status {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.draining {
+		g.Log.Info("undrained: accepting deploys")
+	}
+	g.draining = false
+
+	return g.status()
+}
+
+// Status returns whether the Gate is draining and the number of deploys in flight.
+func (g *Gate) Status() Status {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.status()
+}
+
+func (g *Gate) status() Status {
+	return Status{Draining: g.draining, InFlight: g.inFlight}
+}
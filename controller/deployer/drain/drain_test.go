@@ -0,0 +1,50 @@
+package drain_test
+
+import (
+	. "github.com/compozed/deployadactyl/controller/deployer/drain"
+	I "github.com/compozed/deployadactyl/interfaces"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Gate", func() {
+	var (
+		gate      *Gate
+		logBuffer *Buffer
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		gate = NewGate(I.DefaultLogger(logBuffer, logging.DEBUG, "drain_test"))
+	})
+
+	It("counts deploys in flight", func() {
+		Expect(gate.Enter()).To(Succeed())
+		Expect(gate.Enter()).To(Succeed())
+		gate.Leave()
+
+		Expect(gate.Status()).To(Equal(Status{Draining: false, InFlight: 1}))
+	})
+
+	It("rejects deploys while draining and lets running ones finish", func() {
+		Expect(gate.Enter()).To(Succeed())
+
+		Expect(gate.Drain()).To(Equal(Status{Draining: true, InFlight: 1}))
+		Expect(gate.Enter()).To(MatchError(DrainingError{}))
+		Expect(logBuffer).To(Say("draining: new deploys are rejected, 1 deploys in flight"))
+
+		gate.Leave()
+
+		Expect(gate.Status()).To(Equal(Status{Draining: true, InFlight: 0}))
+	})
+
+	It("accepts deploys again after it is undrained", func() {
+		gate.Drain()
+
+		Expect(gate.Undrain()).To(Equal(Status{Draining: false, InFlight: 0}))
+		Expect(gate.Enter()).To(Succeed())
+		Expect(logBuffer).To(Say("undrained: accepting deploys"))
+	})
+})
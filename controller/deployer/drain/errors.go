@@ -0,0 +1,7 @@
+package drain
+
+type DrainingError struct{}
+
+func (e DrainingError) Error() string {
+	return "node is draining: deploys are not accepted"
+}
@@ -1,6 +1,10 @@
 package deployer
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type BasicAuthError struct{}
 
@@ -22,6 +26,101 @@ func (e InvalidContentTypeError) Error() string {
 	return "must be application/json or application/zip"
 }
 
+type UnsupportedMediaTypeError struct {
+	ContentType string
+}
+
+func (e UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported content type %q: must be application/json, application/zip or multipart/form-data", e.ContentType)
+}
+
+type InvalidMultipartError struct {
+	Err error
+}
+
+func (e InvalidMultipartError) Error() string {
+	return fmt.Sprintf("cannot read multipart body: %s", e.Err)
+}
+
+type MissingMultipartPartError struct {
+	Part string
+}
+
+func (e MissingMultipartPartError) Error() string {
+	return fmt.Sprintf("multipart body is missing the %s part", e.Part)
+}
+
+type DuplicateMultipartPartError struct {
+	Part string
+}
+
+func (e DuplicateMultipartPartError) Error() string {
+	return fmt.Sprintf("multipart body has more than one %s part", e.Part)
+}
+
+type ManifestTooLargeError struct {
+	MaxSizeKB int64
+}
+
+func (e ManifestTooLargeError) Error() string {
+	return fmt.Sprintf("manifest part is larger than the limit of %d KB", e.MaxSizeKB)
+}
+
+type ArtifactTooLargeError struct {
+	MaxSizeMB int64
+}
+
+func (e ArtifactTooLargeError) Error() string {
+	return fmt.Sprintf("artifact part is larger than the limit of %d MB", e.MaxSizeMB)
+}
+
+type InvalidSkipSSLError struct {
+	Value string
+}
+
+func (e InvalidSkipSSLError) Error() string {
+	return fmt.Sprintf("invalid skipSSL %q: must be true or false", e.Value)
+}
+
+type InvalidFailurePolicyError struct {
+	Value string
+}
+
+func (e InvalidFailurePolicyError) Error() string {
+	return fmt.Sprintf("invalid failurePolicy %q: must be fail-fast or continue", e.Value)
+}
+
+type InvalidFormatError struct {
+	Value string
+}
+
+func (e InvalidFormatError) Error() string {
+	return fmt.Sprintf("invalid format %q: must be text or ndjson", e.Value)
+}
+
+type InvalidLogLinesError struct {
+	Value    string
+	MaxLines int
+}
+
+func (e InvalidLogLinesError) Error() string {
+	return fmt.Sprintf("invalid lines %q: must be a number from 1 to %d", e.Value, e.MaxLines)
+}
+
+type InvalidFollowError struct {
+	Value string
+}
+
+func (e InvalidFollowError) Error() string {
+	return fmt.Sprintf("invalid follow %q: must be true or false", e.Value)
+}
+
+type SkipSSLOverrideForbiddenError struct{}
+
+func (e SkipSSLOverrideForbiddenError) Error() string {
+	return "skipSSL can only be overridden with the credentials of deployadactyl"
+}
+
 type EventError struct {
 	Type string
 	Err  error
@@ -38,3 +137,439 @@ type EnvironmentNotFoundError struct {
 func (e EnvironmentNotFoundError) Error() string {
 	return fmt.Sprintf("environment not found: %s", e.Environment)
 }
+
+type DefaultEnvironmentNotConfiguredError struct{}
+
+func (e DefaultEnvironmentNotConfiguredError) Error() string {
+	return "no default environment is configured: the environment must be part of the url"
+}
+
+type DefaultOrgSpaceNotConfiguredError struct {
+	APIKey string
+}
+
+func (e DefaultOrgSpaceNotConfiguredError) Error() string {
+	if e.APIKey == "" {
+		return "no default org and space: the request must use an api key with a default org and space or name them in the url"
+	}
+	return fmt.Sprintf("api key %s has no default org and space: the org and space must be part of the url", e.APIKey)
+}
+
+type InvalidRequestBodyError struct {
+	Err error
+}
+
+func (e InvalidRequestBodyError) Error() string {
+	return fmt.Sprintf("invalid request body: %s", e.Err)
+}
+
+type MissingParameterError struct {
+	Err error
+}
+
+func (e MissingParameterError) Error() string {
+	return e.Err.Error()
+}
+
+type InvalidUUIDError struct {
+	UUID   string
+	Reason string
+}
+
+func (e InvalidUUIDError) Error() string {
+	return fmt.Sprintf("invalid uuid %q: %s", e.UUID, e.Reason)
+}
+
+type InvalidPathNameError struct {
+	Segment string
+	Name    string
+	Reason  string
+}
+
+func (e InvalidPathNameError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %s", e.Segment, e.Name, e.Reason)
+}
+
+type InvalidLabelError struct {
+	Key    string
+	Reason string
+}
+
+func (e InvalidLabelError) Error() string {
+	return fmt.Sprintf("invalid label %q: %s", e.Key, e.Reason)
+}
+
+type InvalidEnvNameError struct {
+	Name string
+}
+
+func (e InvalidEnvNameError) Error() string {
+	return fmt.Sprintf("invalid environment variable name %q: must be letters, digits and underscores and not begin with a digit", e.Name)
+}
+
+type UnsupportedFeatureError struct {
+	Feature   string
+	Supported []string
+}
+
+func (e UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("unsupported app feature %q, supported features are: %s", e.Feature, strings.Join(e.Supported, ", "))
+}
+
+type PendingPromotionNotFoundError struct {
+	UUID    string
+	AppName string
+}
+
+func (e PendingPromotionNotFoundError) Error() string {
+	return fmt.Sprintf("no deploy of %s with UUID %s is waiting for promotion", e.AppName, e.UUID)
+}
+
+type RetirementNotFoundError struct {
+	UUID    string
+	AppName string
+}
+
+func (e RetirementNotFoundError) Error() string {
+	return fmt.Sprintf("no application of %s replaced by the deploy with UUID %s is waiting for deletion", e.AppName, e.UUID)
+}
+
+type DeployNotFoundError struct {
+	UUID    string
+	AppName string
+}
+
+func (e DeployNotFoundError) Error() string {
+	return fmt.Sprintf("no deploy of %s with UUID %s is running", e.AppName, e.UUID)
+}
+
+type DeployNotCancelableError struct {
+	UUID string
+}
+
+func (e DeployNotCancelableError) Error() string {
+	return fmt.Sprintf("deploy %s can no longer be canceled: it was already canceled or has finished pushing", e.UUID)
+}
+
+type CommandNotSupportedError struct{}
+
+func (e CommandNotSupportedError) Error() string {
+	return "a start command cannot be set when pushing every application of a manifest"
+}
+
+type BuildpacksNotSupportedError struct{}
+
+func (e BuildpacksNotSupportedError) Error() string {
+	return "buildpacks cannot be set when pushing every application of a manifest"
+}
+
+type PushFlagsNotSupportedError struct{}
+
+func (e PushFlagsNotSupportedError) Error() string {
+	return "push flags cannot be set when pushing every application of a manifest"
+}
+
+type InvalidPushFlagError struct {
+	Flag string
+}
+
+func (e InvalidPushFlagError) Error() string {
+	return fmt.Sprintf("invalid push flag %q: must be a flag and its value, eg: -m 512M", e.Flag)
+}
+
+type PushFlagNotAllowedError struct {
+	Flag        string
+	Environment string
+}
+
+func (e PushFlagNotAllowedError) Error() string {
+	return fmt.Sprintf("push flag %s is not allowed in environment %s", e.Flag, e.Environment)
+}
+
+type IsolationSegmentNotAllowedError struct {
+	Segment     string
+	Environment string
+}
+
+func (e IsolationSegmentNotAllowedError) Error() string {
+	return fmt.Sprintf("isolation segment %s is not allowed in environment %s", e.Segment, e.Environment)
+}
+
+type BuildpackNotAllowedError struct {
+	Buildpack   string
+	Environment string
+}
+
+func (e BuildpackNotAllowedError) Error() string {
+	return fmt.Sprintf("buildpack %s is not allowed in environment %s", e.Buildpack, e.Environment)
+}
+
+type OrgNotAllowedError struct {
+	Org         string
+	Environment string
+}
+
+func (e OrgNotAllowedError) Error() string {
+	return fmt.Sprintf("org %s is not allowed in environment %s", e.Org, e.Environment)
+}
+
+type SpaceNotAllowedError struct {
+	Space       string
+	Environment string
+}
+
+func (e SpaceNotAllowedError) Error() string {
+	return fmt.Sprintf("space %s is not allowed in environment %s", e.Space, e.Environment)
+}
+
+type InvalidGUIDError struct {
+	Field string
+	GUID  string
+}
+
+func (e InvalidGUIDError) Error() string {
+	return fmt.Sprintf("%s %s is not a valid Cloud Foundry GUID", e.Field, e.GUID)
+}
+
+type OrgGUIDWithoutSpaceGUIDError struct{}
+
+func (e OrgGUIDWithoutSpaceGUIDError) Error() string {
+	return "org_guid can only be used with space_guid"
+}
+
+type NameWithoutSpaceGUIDError struct {
+	Name string
+}
+
+func (e NameWithoutSpaceGUIDError) Error() string {
+	return fmt.Sprintf("an org or space named %s can only be used with space_guid", e.Name)
+}
+
+type SpaceGUIDNotSupportedError struct {
+	Environment string
+}
+
+func (e SpaceGUIDNotSupportedError) Error() string {
+	return fmt.Sprintf("space_guid cannot be used in environment %s: a space GUID names a space on a single foundation", e.Environment)
+}
+
+type ChangeTicketRequiredError struct {
+	Environment string
+}
+
+func (e ChangeTicketRequiredError) Error() string {
+	return fmt.Sprintf("environment %s requires a change ticket: set change_ticket in the request body or the X-Change-Ticket header", e.Environment)
+}
+
+type ReadOnlyEnvironmentError struct {
+	Environment string
+}
+
+func (e ReadOnlyEnvironmentError) Error() string {
+	return fmt.Sprintf("environment %s is read only: its applications cannot be deployed, changed or deleted", e.Environment)
+}
+
+type CustomParamsRequiredError struct {
+	Environment string
+	Missing     []string
+}
+
+func (e CustomParamsRequiredError) Error() string {
+	return fmt.Sprintf("environment %s requires the custom params %s: set them in CustomParams of the request body", e.Environment, strings.Join(e.Missing, ", "))
+}
+
+type ReasonRequiredError struct {
+	Environment string
+}
+
+func (e ReasonRequiredError) Error() string {
+	return fmt.Sprintf("environment %s requires a reason for the deploy: set reason in the request body or the X-Deploy-Reason header", e.Environment)
+}
+
+type UnknownStrategyError struct {
+	Strategy string
+}
+
+func (e UnknownStrategyError) Error() string {
+	return fmt.Sprintf("unknown strategy %s: must be bluegreen or traffic-split", e.Strategy)
+}
+
+type AppNameMismatchError struct {
+	AppName      string
+	ManifestApps []string
+}
+
+func (e AppNameMismatchError) Error() string {
+	return fmt.Sprintf("the manifest declares %s but the request deploys %s: fix the manifest or add ?allowNameMismatch=true to the request", strings.Join(e.ManifestApps, ", "), e.AppName)
+}
+
+type ManualCutoverNotSupportedError struct{}
+
+func (e ManualCutoverNotSupportedError) Error() string {
+	return "manual cutover is not supported when pushing every application of a manifest"
+}
+
+type ProbeNotAllowedError struct {
+	Environment string
+}
+
+func (e ProbeNotAllowedError) Error() string {
+	return fmt.Sprintf("probe commands are not allowed in requests to environment %s", e.Environment)
+}
+
+type SilentDeployPoolClosedError struct{}
+
+func (e SilentDeployPoolClosedError) Error() string {
+	return "silent deploys are no longer accepted because the server is shutting down"
+}
+
+type InvalidCredentialsError struct{}
+
+func (e InvalidCredentialsError) Error() string {
+	return "invalid credentials"
+}
+
+type APIKeyRequiredError struct{}
+
+func (e APIKeyRequiredError) Error() string {
+	return "an api key is required in the X-API-Key header"
+}
+
+type InvalidAPIKeyError struct{}
+
+func (e InvalidAPIKeyError) Error() string {
+	return "invalid api key"
+}
+
+type APIKeyNotAllowedError struct {
+	Name        string
+	Environment string
+}
+
+func (e APIKeyNotAllowedError) Error() string {
+	if e.Environment == "" {
+		return fmt.Sprintf("api key %s is limited to environments and cannot be used for this request", e.Name)
+	}
+	return fmt.Sprintf("api key %s is not allowed in environment %s", e.Name, e.Environment)
+}
+
+type ApprovalGateNotConfiguredError struct {
+	Environment string
+}
+
+func (e ApprovalGateNotConfiguredError) Error() string {
+	return fmt.Sprintf("environment %s requires approval but no approval gate is configured", e.Environment)
+}
+
+type ProfileNotFoundError struct {
+	Profile string
+}
+
+func (e ProfileNotFoundError) Error() string {
+	return fmt.Sprintf("profile %s is not in the profiles key", e.Profile)
+}
+
+type AppSpecNotFoundError struct {
+	Spec string
+}
+
+func (e AppSpecNotFoundError) Error() string {
+	return fmt.Sprintf("app spec %s is not in the app_specs key", e.Spec)
+}
+
+type AppSpecManifestError struct {
+	Spec string
+	Err  error
+}
+
+func (e AppSpecManifestError) Error() string {
+	return fmt.Sprintf("cannot merge the manifest of the push over app spec %s: %s", e.Spec, e.Err)
+}
+
+type DeployCooldownError struct {
+	ApplicationName string
+	Cooldown        time.Duration
+	Remaining       time.Duration
+}
+
+func (e DeployCooldownError) Error() string {
+	return fmt.Sprintf("%s was deployed less than %s ago, retry in %s", e.ApplicationName, e.Cooldown, e.Remaining)
+}
+
+type TooManyInstancesError struct {
+	ApplicationName string
+	Requested       uint16
+	Allowed         uint16
+}
+
+func (e TooManyInstancesError) Error() string {
+	return fmt.Sprintf("cannot deploy %d instances of %s: the environment allows at most %d", e.Requested, e.ApplicationName, e.Allowed)
+}
+
+type ResourceLimitError struct {
+	ApplicationName string
+	Resource        string
+	Requested       string
+	Allowed         string
+}
+
+func (e ResourceLimitError) Error() string {
+	return fmt.Sprintf("cannot deploy %s with %s of %s per instance: the environment allows at most %s", e.ApplicationName, e.Requested, e.Resource, e.Allowed)
+}
+
+type ManifestOverrideError struct {
+	ApplicationName string
+	Overrides       []string
+}
+
+func (e ManifestOverrideError) Error() string {
+	return fmt.Sprintf("cannot deploy %s: the request overrides its manifest: %s", e.ApplicationName, strings.Join(e.Overrides, "; "))
+}
+
+type ScanFailedError struct {
+	Findings []string
+}
+
+func (e ScanFailedError) Error() string {
+	return fmt.Sprintf("the artifact scan failed: %s", strings.Join(e.Findings, "; "))
+}
+
+type DeploymentHistoryNotFoundError struct {
+	UUID string
+}
+
+func (e DeploymentHistoryNotFoundError) Error() string {
+	return fmt.Sprintf("no events were recorded for deploy %s", e.UUID)
+}
+
+type InvalidBatchModeError struct {
+	Mode string
+}
+
+func (e InvalidBatchModeError) Error() string {
+	return fmt.Sprintf("invalid batch mode %q: must be best-effort or all-or-nothing", e.Mode)
+}
+
+type UnsupportedBatchMediaTypeError struct {
+	ContentType string
+}
+
+func (e UnsupportedBatchMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported content type %q: a batch deploy must be application/json", e.ContentType)
+}
+
+type AllOrNothingWithoutDeleteDelayError struct {
+	Environment string
+}
+
+func (e AllOrNothingWithoutDeleteDelayError) Error() string {
+	return fmt.Sprintf("cannot deploy in all-or-nothing mode to environment %s: it has no delete_delay_seconds, so promoted applications cannot be rolled back", e.Environment)
+}
+
+type InvalidBatchError struct {
+	Reason string
+}
+
+func (e InvalidBatchError) Error() string {
+	return fmt.Sprintf("invalid batch deploy: %s", e.Reason)
+}
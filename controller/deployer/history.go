@@ -0,0 +1,344 @@
+package deployer
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/constants"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// HistoryEvents are the deploy events recorded by a History.
+var HistoryEvents = []string{
+	constants.DeployStartEvent,
+	constants.DeploySuccessEvent,
+	constants.DeployFailureEvent,
+	constants.DeployFinishEvent,
+}
+
+// Statuses of a deploy in a History.
+const (
+	DeploymentRunning   = "running"
+	DeploymentSucceeded = "succeeded"
+	DeploymentFailed    = "failed"
+)
+
+// History records the deploy events of the most recent deploys by their UUID so they can be replayed.
+// Once it holds size deploys, the oldest deploy is forgotten when a new one starts.
+//
+// History is a Handler and must be added to the EventManager for each of the HistoryEvents.
+type History struct {
+	// Signer signs the record of the latest event of each deploy when it is set.
+	Signer I.DeploySigner
+
+	size     int
+	order    []string
+	events   map[string][]HistoryEvent
+	recorded map[string]historyRecord
+	signed   map[string]json.RawMessage
+	mutex    sync.Mutex
+}
+
+// historyRecord is the application of a deploy in a History and when its first event was recorded.
+type historyRecord struct {
+	app string
+	at  time.Time
+}
+
+// HistoryEvent is what a History keeps of a deploy event to replay it. The deployment info is kept without the
+// request body and the credentials of the deploy, and the response of the deploy is not kept.
+type HistoryEvent struct {
+	Type           string
+	Error          error
+	DeploymentInfo S.DeploymentInfo
+}
+
+// newHistoryEvent returns the HistoryEvent of a deploy event with deployment info.
+func newHistoryEvent(event I.Event) HistoryEvent {
+	info := *event.Data.(*S.DeployEventData).DeploymentInfo
+	info.Body = nil
+	info.Username = ""
+	info.Password = ""
+
+	return HistoryEvent{Type: event.Type, Error: event.Error, DeploymentInfo: info}
+}
+
+// Event returns the deploy event to replay, with an empty request body and response.
+func (e HistoryEvent) Event() I.Event {
+	info := e.DeploymentInfo
+
+	return I.Event{
+		Type:   e.Type,
+		Error:  e.Error,
+		Replay: true,
+		Data: &S.DeployEventData{
+			Response:       &bytes.Buffer{},
+			RequestBody:    &bytes.Buffer{},
+			DeploymentInfo: &info,
+		},
+	}
+}
+
+// NewHistory returns a History that remembers the events of the last size deploys.
+func NewHistory(size int) *History {
+	return &History{size: size, events: map[string][]HistoryEvent{}, recorded: map[string]historyRecord{}, signed: map[string]json.RawMessage{}}
+}
+
+// OnEvent records an event of a deploy. Replayed events and events without a deployment UUID are not recorded.
+func (h *History) OnEvent(event I.Event) error {
+	if event.Replay {
+		return nil
+	}
+
+	uuid := eventUUID(event)
+	if uuid == "" {
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.events[uuid]; !ok {
+		h.order = append(h.order, uuid)
+		h.recorded[uuid] = historyRecord{app: eventApp(event), at: time.Now()}
+		if len(h.order) > h.size {
+			delete(h.events, h.order[0])
+			delete(h.recorded, h.order[0])
+			delete(h.signed, h.order[0])
+			h.order = h.order[1:]
+		}
+	}
+	h.events[uuid] = append(h.events[uuid], newHistoryEvent(event))
+
+	if data, ok := event.Data.(*S.DeployEventData); ok && h.Signer != nil && data.DeploymentInfo != nil {
+		record := S.NewDeployRecord(event.Type, false, event.Error, *data.DeploymentInfo)
+		value, err := signing.Marshal(record, h.Signer)
+		if err == nil {
+			h.signed[uuid] = value
+		}
+	}
+
+	return nil
+}
+
+// Record returns the signed record of the latest event of the deploy with the UUID that has deployment info. It is
+// nil when the History does not sign records. It returns a DeploymentHistoryNotFoundError when no events were
+// recorded for the deploy.
+func (h *History) Record(uuid string) (json.RawMessage, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.events[uuid]; !ok {
+		return nil, DeploymentHistoryNotFoundError{UUID: uuid}
+	}
+
+	return h.signed[uuid], nil
+}
+
+// Events returns the recorded events of the deploy with the UUID in the order they were emitted.
+// It returns a DeploymentHistoryNotFoundError when no events were recorded for the deploy.
+func (h *History) Events(uuid string) ([]HistoryEvent, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	events, ok := h.events[uuid]
+	if !ok {
+		return nil, DeploymentHistoryNotFoundError{UUID: uuid}
+	}
+
+	return append([]HistoryEvent{}, events...), nil
+}
+
+// Status returns the status of the deploy with the UUID: running until a success or failure event is recorded.
+// It returns a DeploymentHistoryNotFoundError when no events were recorded for the deploy.
+func (h *History) Status(uuid string) (string, error) {
+	events, err := h.Events(uuid)
+	if err != nil {
+		return "", err
+	}
+
+	return historyStatus(events), nil
+}
+
+// Reason returns the reason given for the deploy with the UUID. It is empty when the deploy was not given one.
+// It returns a DeploymentHistoryNotFoundError when no events were recorded for the deploy.
+func (h *History) Reason(uuid string) (string, error) {
+	events, err := h.Events(uuid)
+	if err != nil {
+		return "", err
+	}
+
+	for _, event := range events {
+		if event.DeploymentInfo.Reason != "" {
+			return event.DeploymentInfo.Reason, nil
+		}
+	}
+
+	return "", nil
+}
+
+// Artifact returns the metadata of the artifact the deploy with the UUID fetched. It is nil when the deploy did not
+// fetch its artifact. It returns a DeploymentHistoryNotFoundError when no events were recorded for the deploy.
+func (h *History) Artifact(uuid string) (*S.ArtifactMetadata, error) {
+	events, err := h.Events(uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if event.DeploymentInfo.Artifact != nil {
+			return event.DeploymentInfo.Artifact, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Prune forgets the deploys of each application beyond its newest retention.MaxRecordsPerApp deploys and the
+// deploys recorded longer than retention.MaxAge before now. The newest successful deploy of each application,
+// which a rollback needs, and running deploys are always kept. A zero limit is not enforced.
+// It returns how many deploys were pruned and how many are left.
+func (h *History) Prune(retention HistoryRetention, now time.Time) (int, int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	apps := map[string][]string{}
+	for _, uuid := range h.order {
+		app := h.recorded[uuid].app
+		apps[app] = append(apps[app], uuid)
+	}
+
+	pruned := map[string]bool{}
+	for _, uuids := range apps {
+		newestSuccess := ""
+		for _, uuid := range uuids {
+			if historyStatus(h.events[uuid]) == DeploymentSucceeded {
+				newestSuccess = uuid
+			}
+		}
+
+		for i, uuid := range uuids {
+			if uuid == newestSuccess || historyStatus(h.events[uuid]) == DeploymentRunning {
+				continue
+			}
+
+			tooMany := retention.MaxRecordsPerApp > 0 && len(uuids)-i > retention.MaxRecordsPerApp
+			tooOld := retention.MaxAge > 0 && now.Sub(h.recorded[uuid].at) > retention.MaxAge
+			if tooMany || tooOld {
+				pruned[uuid] = true
+			}
+		}
+	}
+
+	if len(pruned) == 0 {
+		return 0, len(h.order)
+	}
+
+	order := make([]string, 0, len(h.order)-len(pruned))
+	for _, uuid := range h.order {
+		if pruned[uuid] {
+			delete(h.events, uuid)
+			delete(h.recorded, uuid)
+			delete(h.signed, uuid)
+			continue
+		}
+		order = append(order, uuid)
+	}
+	h.order = order
+
+	return len(pruned), len(h.order)
+}
+
+// historyStatus returns the status of a deploy from its events: running until a success or failure event.
+func historyStatus(events []HistoryEvent) string {
+	status := DeploymentRunning
+	for _, event := range events {
+		switch event.Type {
+		case constants.DeploySuccessEvent:
+			status = DeploymentSucceeded
+		case constants.DeployFailureEvent:
+			status = DeploymentFailed
+		}
+	}
+
+	return status
+}
+
+// eventApp returns the environment, org, space and name of the application of a deploy event.
+func eventApp(event I.Event) string {
+	info := event.Data.(*S.DeployEventData).DeploymentInfo
+	return path.Join(info.Environment, info.Org, info.Space, info.AppName)
+}
+
+func eventUUID(event I.Event) string {
+	data, ok := event.Data.(*S.DeployEventData)
+	if !ok || data.DeploymentInfo == nil {
+		return ""
+	}
+	return data.DeploymentInfo.UUID
+}
+
+// HistoryRetention limits the deploys a History keeps for each application. A zero limit is not enforced.
+type HistoryRetention struct {
+	MaxRecordsPerApp int
+	MaxAge           time.Duration
+}
+
+// HistoryPruner prunes a History with its Retention every Interval in the background and logs what it pruned.
+type HistoryPruner struct {
+	History   *History
+	Retention HistoryRetention
+	Interval  time.Duration
+	Log       I.Logger
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Prune prunes the History now.
+func (p *HistoryPruner) Prune() {
+	pruned, left := p.History.Prune(p.Retention, time.Now())
+	if pruned == 0 {
+		p.Log.Debugf("no deploys to prune from the deployment history, %d left", left)
+		return
+	}
+
+	p.Log.Infof("pruned %d deploys from the deployment history, %d left", pruned, left)
+}
+
+// Start prunes the History every Interval in the background until Stop is called.
+func (p *HistoryPruner) Start() {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.Prune()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops pruning in the background and waits for a prune in progress.
+func (p *HistoryPruner) Stop() {
+	if p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	<-p.done
+}
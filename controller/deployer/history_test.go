@@ -0,0 +1,252 @@
+package deployer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+
+	"github.com/compozed/deployadactyl/constants"
+	. "github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+var _ = Describe("History", func() {
+	var history *History
+
+	event := func(eventType, uuid string) I.Event {
+		return I.Event{Type: eventType, Data: &S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{UUID: uuid}}}
+	}
+
+	BeforeEach(func() {
+		history = NewHistory(2)
+	})
+
+	It("returns the events of a deploy in the order they were emitted", func() {
+		Expect(history.OnEvent(event(constants.DeployStartEvent, "my-uuid"))).To(Succeed())
+		Expect(history.OnEvent(event(constants.DeployStartEvent, "other-uuid"))).To(Succeed())
+		Expect(history.OnEvent(event(constants.DeploySuccessEvent, "my-uuid"))).To(Succeed())
+		Expect(history.OnEvent(event(constants.DeployFinishEvent, "my-uuid"))).To(Succeed())
+
+		events, err := history.Events("my-uuid")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]HistoryEvent{
+			{Type: constants.DeployStartEvent, DeploymentInfo: S.DeploymentInfo{UUID: "my-uuid"}},
+			{Type: constants.DeploySuccessEvent, DeploymentInfo: S.DeploymentInfo{UUID: "my-uuid"}},
+			{Type: constants.DeployFinishEvent, DeploymentInfo: S.DeploymentInfo{UUID: "my-uuid"}},
+		}))
+	})
+
+	It("does not keep the request body, the credentials or the response of a deploy", func() {
+		history.OnEvent(I.Event{Type: constants.DeployFailureEvent, Error: errors.New("push failed"), Data: &S.DeployEventData{
+			Response:    bytes.NewBufferString("the output"),
+			RequestBody: bytes.NewBufferString("the artifact"),
+			DeploymentInfo: &S.DeploymentInfo{
+				UUID: "my-uuid", AppName: "my-app", Username: "user", Password: "the-password", Body: bytes.NewBufferString("the artifact"),
+			},
+		}})
+
+		events, err := history.Events("my-uuid")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]HistoryEvent{
+			{Type: constants.DeployFailureEvent, Error: errors.New("push failed"), DeploymentInfo: S.DeploymentInfo{UUID: "my-uuid", AppName: "my-app"}},
+		}))
+	})
+
+	It("replays a recorded event with an empty request body and response", func() {
+		recorded := HistoryEvent{Type: constants.DeploySuccessEvent, DeploymentInfo: S.DeploymentInfo{UUID: "my-uuid"}}
+
+		event := recorded.Event()
+
+		Expect(event.Type).To(Equal(constants.DeploySuccessEvent))
+		Expect(event.Replay).To(BeTrue())
+		data := event.Data.(*S.DeployEventData)
+		Expect(*data.DeploymentInfo).To(Equal(S.DeploymentInfo{UUID: "my-uuid"}))
+		Expect(data.Response).To(Equal(&bytes.Buffer{}))
+		Expect(data.RequestBody).To(Equal(&bytes.Buffer{}))
+	})
+
+	It("returns the status of a deploy", func() {
+		history.OnEvent(event(constants.DeployStartEvent, "running-uuid"))
+		history.OnEvent(event(constants.DeployStartEvent, "failed-uuid"))
+		history.OnEvent(event(constants.DeployFailureEvent, "failed-uuid"))
+		history.OnEvent(event(constants.DeployFinishEvent, "failed-uuid"))
+
+		Expect(history.Status("running-uuid")).To(Equal(DeploymentRunning))
+		Expect(history.Status("failed-uuid")).To(Equal(DeploymentFailed))
+
+		_, err := history.Status("unknown-uuid")
+		Expect(err).To(MatchError(DeploymentHistoryNotFoundError{UUID: "unknown-uuid"}))
+	})
+
+	It("returns the reason for a deploy", func() {
+		started := event(constants.DeployStartEvent, "my-uuid")
+		started.Data.(*S.DeployEventData).DeploymentInfo.Reason = "hotfix for checkout errors"
+		history.OnEvent(started)
+		history.OnEvent(event(constants.DeployStartEvent, "other-uuid"))
+
+		Expect(history.Reason("my-uuid")).To(Equal("hotfix for checkout errors"))
+		Expect(history.Reason("other-uuid")).To(BeEmpty())
+
+		_, err := history.Reason("unknown-uuid")
+		Expect(err).To(MatchError(DeploymentHistoryNotFoundError{UUID: "unknown-uuid"}))
+	})
+
+	It("signs the record of the latest event of a deploy with the signer", func() {
+		history.Signer, _ = signing.NewSigner(signing.HMACSHA256, "key-1", []byte("secret"))
+		history.OnEvent(event(constants.DeployStartEvent, "my-uuid"))
+		history.OnEvent(event(constants.DeploySuccessEvent, "my-uuid"))
+
+		value, err := history.Record("my-uuid")
+		Expect(err).ToNot(HaveOccurred())
+
+		envelope := signing.Envelope{}
+		Expect(json.Unmarshal(value, &envelope)).To(Succeed())
+		Expect(signing.Verify(envelope.Signature, envelope.Payload, []byte("secret"))).To(BeTrue())
+
+		record := S.DeployRecord{}
+		Expect(json.Unmarshal(envelope.Payload, &record)).To(Succeed())
+		Expect(record.Type).To(Equal(constants.DeploySuccessEvent))
+
+		_, err = history.Record("unknown-uuid")
+		Expect(err).To(MatchError(DeploymentHistoryNotFoundError{UUID: "unknown-uuid"}))
+	})
+
+	It("does not keep a record of a deploy without a signer", func() {
+		history.OnEvent(event(constants.DeployStartEvent, "my-uuid"))
+
+		Expect(history.Record("my-uuid")).To(BeNil())
+	})
+
+	It("does not record replayed events", func() {
+		replayed := event(constants.DeployStartEvent, "my-uuid")
+		replayed.Replay = true
+
+		Expect(history.OnEvent(replayed)).To(Succeed())
+
+		_, err := history.Events("my-uuid")
+		Expect(err).To(MatchError(DeploymentHistoryNotFoundError{UUID: "my-uuid"}))
+	})
+
+	It("does not record events without a deployment UUID", func() {
+		Expect(history.OnEvent(I.Event{Type: constants.DeployStartEvent, Data: &S.DeployEventData{}})).To(Succeed())
+		Expect(history.OnEvent(event(constants.DeployStartEvent, ""))).To(Succeed())
+
+		_, err := history.Events("")
+		Expect(err).To(MatchError(DeploymentHistoryNotFoundError{UUID: ""}))
+	})
+
+	It("forgets the oldest deploy when it is full", func() {
+		history.OnEvent(event(constants.DeployStartEvent, "first-uuid"))
+		history.OnEvent(event(constants.DeployStartEvent, "second-uuid"))
+		history.OnEvent(event(constants.DeployStartEvent, "third-uuid"))
+
+		_, err := history.Events("first-uuid")
+		Expect(err).To(MatchError(DeploymentHistoryNotFoundError{UUID: "first-uuid"}))
+
+		for _, uuid := range []string{"second-uuid", "third-uuid"} {
+			events, err := history.Events(uuid)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+		}
+	})
+
+	Describe("pruning", func() {
+		appEvent := func(eventType, uuid, appName string) I.Event {
+			return I.Event{Type: eventType, Data: &S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{
+				UUID: uuid, Environment: "prod", Org: "my-org", Space: "my-space", AppName: appName,
+			}}}
+		}
+
+		deploy := func(uuid, appName, result string) {
+			history.OnEvent(appEvent(constants.DeployStartEvent, uuid, appName))
+			if result != "" {
+				history.OnEvent(appEvent(result, uuid, appName))
+			}
+		}
+
+		recorded := func(uuid string) bool {
+			_, err := history.Events(uuid)
+			return err == nil
+		}
+
+		BeforeEach(func() {
+			history = NewHistory(100)
+		})
+
+		It("keeps only the newest deploys of each application", func() {
+			deploy("first-uuid", "my-app", constants.DeployFailureEvent)
+			deploy("second-uuid", "my-app", constants.DeployFailureEvent)
+			deploy("other-uuid", "other-app", constants.DeployFailureEvent)
+			deploy("third-uuid", "my-app", constants.DeployFailureEvent)
+
+			pruned, left := history.Prune(HistoryRetention{MaxRecordsPerApp: 2}, time.Now())
+
+			Expect(pruned).To(Equal(1))
+			Expect(left).To(Equal(3))
+			Expect(recorded("first-uuid")).To(BeFalse())
+			Expect(recorded("second-uuid")).To(BeTrue())
+			Expect(recorded("third-uuid")).To(BeTrue())
+			Expect(recorded("other-uuid")).To(BeTrue())
+		})
+
+		It("forgets deploys older than the max age", func() {
+			deploy("first-uuid", "my-app", constants.DeployFailureEvent)
+			deploy("second-uuid", "my-app", constants.DeployFailureEvent)
+
+			pruned, left := history.Prune(HistoryRetention{MaxAge: time.Hour}, time.Now().Add(2*time.Hour))
+
+			Expect(pruned).To(Equal(2))
+			Expect(left).To(Equal(0))
+		})
+
+		It("always keeps the newest successful deploy of an application", func() {
+			deploy("old-success-uuid", "my-app", constants.DeploySuccessEvent)
+			deploy("success-uuid", "my-app", constants.DeploySuccessEvent)
+			deploy("first-failure-uuid", "my-app", constants.DeployFailureEvent)
+			deploy("second-failure-uuid", "my-app", constants.DeployFailureEvent)
+
+			pruned, _ := history.Prune(HistoryRetention{MaxRecordsPerApp: 1, MaxAge: time.Hour}, time.Now().Add(2*time.Hour))
+
+			Expect(pruned).To(Equal(3))
+			Expect(recorded("success-uuid")).To(BeTrue())
+			Expect(history.Status("success-uuid")).To(Equal(DeploymentSucceeded))
+		})
+
+		It("keeps running deploys", func() {
+			deploy("running-uuid", "my-app", "")
+			deploy("failed-uuid", "my-app", constants.DeployFailureEvent)
+
+			pruned, _ := history.Prune(HistoryRetention{MaxAge: time.Hour}, time.Now().Add(2*time.Hour))
+
+			Expect(pruned).To(Equal(1))
+			Expect(recorded("running-uuid")).To(BeTrue())
+		})
+
+		It("logs how many deploys were pruned", func() {
+			logBuffer := NewBuffer()
+			deploy("first-uuid", "my-app", constants.DeployFailureEvent)
+			deploy("second-uuid", "my-app", constants.DeployFailureEvent)
+
+			pruner := &HistoryPruner{
+				History:   history,
+				Retention: HistoryRetention{MaxRecordsPerApp: 1},
+				Interval:  time.Millisecond,
+				Log:       I.DefaultLogger(logBuffer, logging.DEBUG, "history_test"),
+			}
+			pruner.Start()
+			defer pruner.Stop()
+
+			Eventually(logBuffer).Should(Say("pruned 1 deploys from the deployment history, 1 left"))
+		})
+	})
+})
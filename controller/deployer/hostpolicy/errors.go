@@ -0,0 +1,12 @@
+package hostpolicy
+
+import "fmt"
+
+type HostNotAllowedError struct {
+	Host   string
+	Reason string
+}
+
+func (e HostNotAllowedError) Error() string {
+	return fmt.Sprintf("cannot fetch artifact from %s: %s", e.Host, e.Reason)
+}
@@ -0,0 +1,179 @@
+// Package hostpolicy decides which hosts the artifacts of JSON pushes may be fetched from.
+package hostpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LinkLocalCIDRs are the link-local ranges, which hold the metadata services of cloud providers.
+// They are denied unless a Policy allows them.
+var LinkLocalCIDRs = []string{"169.254.0.0/16", "fe80::/10", "fd00:ec2::254/128"}
+
+// Policy checks the host of an artifact URL before the artifact is fetched.
+type Policy struct {
+	// AllowedHosts are the host names artifacts may be fetched from. A name starting with *. allows every
+	// subdomain of the rest of the name. Any host is allowed when it is empty.
+	AllowedHosts []string
+
+	// DeniedCIDRs are the IP ranges artifacts may not be fetched from.
+	DeniedCIDRs []string
+
+	// AllowLinkLocal allows fetching from the LinkLocalCIDRs.
+	AllowLinkLocal bool
+
+	// LookupIP resolves host names to check their addresses against the denied ranges. Defaults to net.LookupIP.
+	LookupIP func(host string) ([]net.IP, error)
+}
+
+// Check returns a HostNotAllowedError when the host of the artifact URL is not allowed, is in a denied range
+// or resolves to an address in a denied range. Hosts that cannot be resolved are denied when the policy has
+// DeniedCIDRs, and left for the fetch to report otherwise. URLs without a host are left for the fetch to report.
+//
+// The host is resolved again when the artifact is fetched. Fetches that must not reach a denied range whatever
+// the DNS server answers the second time connect with DialContext.
+func (p Policy) Check(artifactURL string) error {
+	parsed, err := url.Parse(artifactURL)
+	if err != nil || parsed.Hostname() == "" {
+		return nil
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	if !p.allowed(host) {
+		return HostNotAllowedError{host, "it is not an allowed artifact host"}
+	}
+
+	denied := p.deniedNetworks()
+	if len(denied) == 0 {
+		return nil
+	}
+
+	ips, err := p.resolve(host)
+	if err != nil {
+		if len(p.DeniedCIDRs) > 0 {
+			return HostNotAllowedError{host, "it cannot be resolved"}
+		}
+		return nil
+	}
+
+	return checkIPs(host, ips, denied)
+}
+
+// CheckRedirect checks each redirect of a fetch like Check, so a redirect cannot lead the fetch to a host the
+// policy denies. It stops after 10 redirects like the default of http.Client. Use it as the CheckRedirect of
+// the http.Client that fetches artifacts.
+func (p Policy) CheckRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	return p.Check(request.URL.String())
+}
+
+// DialContext returns a function that dials with dialer, for the DialContext of the http.Transport that fetches
+// artifacts. It resolves the host itself and connects to the addresses it checked against the denied ranges, so
+// the connection cannot reach a denied range through a DNS answer that changed since Check.
+func (p Policy) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		denied := p.deniedNetworks()
+		if len(denied) == 0 {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		host = strings.ToLower(host)
+
+		ips, err := p.resolve(host)
+		if err != nil {
+			return nil, HostNotAllowedError{host, "it cannot be resolved"}
+		}
+
+		err = checkIPs(host, ips, denied)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range ips {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+
+		return nil, err
+	}
+}
+
+// resolve returns the addresses of host, which may be an IP address.
+func (p Policy) resolve(host string) ([]net.IP, error) {
+	ip := net.ParseIP(host)
+	if ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	lookupIP := p.LookupIP
+	if lookupIP == nil {
+		lookupIP = net.LookupIP
+	}
+
+	ips, err := lookupIP(host)
+	if err == nil && len(ips) == 0 {
+		err = errors.New("no addresses")
+	}
+
+	return ips, err
+}
+
+func checkIPs(host string, ips []net.IP, denied []*net.IPNet) error {
+	for _, ip := range ips {
+		for _, network := range denied {
+			if network.Contains(ip) {
+				return HostNotAllowedError{host, "it is in the denied range " + network.String()}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p Policy) allowed(host string) bool {
+	if len(p.AllowedHosts) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deniedNetworks returns the denied ranges. Ranges that cannot be parsed are skipped; they are rejected when the
+// config is loaded.
+func (p Policy) deniedNetworks() []*net.IPNet {
+	cidrs := p.DeniedCIDRs
+	if !p.AllowLinkLocal {
+		cidrs = append(append([]string{}, cidrs...), LinkLocalCIDRs...)
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil {
+			networks = append(networks, network)
+		}
+	}
+
+	return networks
+}
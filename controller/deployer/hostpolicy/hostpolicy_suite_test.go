@@ -0,0 +1,13 @@
+package hostpolicy_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHostpolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hostpolicy Suite")
+}
@@ -0,0 +1,141 @@
+package hostpolicy_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/hostpolicy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Policy", func() {
+	var (
+		policy   Policy
+		resolved map[string][]net.IP
+	)
+
+	BeforeEach(func() {
+		resolved = map[string][]net.IP{
+			"artifacts.example.com": {net.ParseIP("10.0.0.5")},
+			"metadata.example.com":  {net.ParseIP("169.254.169.254")},
+		}
+		policy = Policy{
+			LookupIP: func(host string) ([]net.IP, error) {
+				ips, ok := resolved[host]
+				if !ok {
+					return nil, errors.New("no such host")
+				}
+				return ips, nil
+			},
+		}
+	})
+
+	It("allows any host that is not link-local by default", func() {
+		Expect(policy.Check("https://artifacts.example.com/my-app.zip")).To(Succeed())
+	})
+
+	It("denies link-local addresses by default", func() {
+		Expect(policy.Check("http://169.254.169.254/latest/meta-data")).To(MatchError(HostNotAllowedError{"169.254.169.254", "it is in the denied range 169.254.0.0/16"}))
+		Expect(policy.Check("http://[fe80::1]/artifact.zip")).To(HaveOccurred())
+	})
+
+	It("denies host names that resolve to link-local addresses", func() {
+		Expect(policy.Check("http://metadata.example.com/latest")).To(MatchError(HostNotAllowedError{"metadata.example.com", "it is in the denied range 169.254.0.0/16"}))
+	})
+
+	It("allows link-local addresses when the policy allows them", func() {
+		policy.AllowLinkLocal = true
+
+		Expect(policy.Check("http://169.254.169.254/artifact.zip")).To(Succeed())
+	})
+
+	It("denies hosts in the denied ranges", func() {
+		policy.DeniedCIDRs = []string{"10.0.0.0/8"}
+
+		Expect(policy.Check("https://artifacts.example.com/my-app.zip")).To(MatchError(HostNotAllowedError{"artifacts.example.com", "it is in the denied range 10.0.0.0/8"}))
+		Expect(policy.Check("https://10.1.2.3/my-app.zip")).To(HaveOccurred())
+	})
+
+	It("allows only the allowed hosts when there are any", func() {
+		policy.AllowedHosts = []string{"artifacts.example.com", "*.repo.example.com"}
+
+		Expect(policy.Check("https://ARTIFACTS.example.com:8443/my-app.zip")).To(Succeed())
+		Expect(policy.Check("https://maven.repo.example.com/my-app.zip")).To(Succeed())
+		Expect(policy.Check("https://repo.example.com/my-app.zip")).To(MatchError(HostNotAllowedError{"repo.example.com", "it is not an allowed artifact host"}))
+		Expect(policy.Check("https://evil.com/my-app.zip")).To(HaveOccurred())
+	})
+
+	It("leaves urls without a host and hosts that cannot be resolved to the fetch", func() {
+		Expect(policy.Check("artifact")).To(Succeed())
+		Expect(policy.Check("https://unknown.example.com/my-app.zip")).To(Succeed())
+	})
+
+	It("denies hosts that cannot be resolved when there are denied ranges", func() {
+		policy.DeniedCIDRs = []string{"10.0.0.0/8"}
+
+		Expect(policy.Check("https://unknown.example.com/my-app.zip")).To(MatchError(HostNotAllowedError{"unknown.example.com", "it cannot be resolved"}))
+	})
+
+	Describe("CheckRedirect", func() {
+		It("checks the url of the redirect", func() {
+			request, err := http.NewRequest("GET", "http://metadata.example.com/latest", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(policy.CheckRedirect(request, nil)).To(MatchError(HostNotAllowedError{"metadata.example.com", "it is in the denied range 169.254.0.0/16"}))
+		})
+
+		It("stops after 10 redirects", func() {
+			request, err := http.NewRequest("GET", "https://artifacts.example.com/my-app.zip", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(policy.CheckRedirect(request, make([]*http.Request, 9))).To(Succeed())
+			Expect(policy.CheckRedirect(request, make([]*http.Request, 10))).To(MatchError("stopped after 10 redirects"))
+		})
+	})
+
+	Describe("DialContext", func() {
+		var listener net.Listener
+
+		BeforeEach(func() {
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		dial := func(host string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(listener.Addr().String())
+			Expect(err).ToNot(HaveOccurred())
+
+			return policy.DialContext(&net.Dialer{})(context.Background(), "tcp", net.JoinHostPort(host, port))
+		}
+
+		It("connects to the address it resolved", func() {
+			resolved["artifacts.example.com"] = []net.IP{net.ParseIP("127.0.0.1")}
+
+			conn, err := dial("artifacts.example.com")
+			Expect(err).ToNot(HaveOccurred())
+			conn.Close()
+		})
+
+		It("does not connect to a host that resolves to a denied range", func() {
+			resolved["artifacts.example.com"] = []net.IP{net.ParseIP("127.0.0.1")}
+			policy.DeniedCIDRs = []string{"127.0.0.0/8"}
+
+			_, err := dial("artifacts.example.com")
+			Expect(err).To(MatchError(HostNotAllowedError{"artifacts.example.com", "it is in the denied range 127.0.0.0/8"}))
+		})
+
+		It("does not connect to a host that cannot be resolved", func() {
+			_, err := dial("unknown.example.com")
+			Expect(err).To(MatchError(HostNotAllowedError{"unknown.example.com", "it cannot be resolved"}))
+		})
+	})
+})
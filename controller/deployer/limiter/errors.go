@@ -0,0 +1,11 @@
+package limiter
+
+import "fmt"
+
+type TooManyDeploymentsError struct {
+	Max int
+}
+
+func (e TooManyDeploymentsError) Error() string {
+	return fmt.Sprintf("too many deploys: %d deploys are already running on this server, try again later", e.Max)
+}
@@ -0,0 +1,95 @@
+// Package limiter caps the number of deploys running on the node at the same time, across every environment.
+package limiter
+
+import (
+	"context"
+	"sync"
+)
+
+// Status reports the most deploys that may run at the same time, how many are running and how many are waiting for
+// a place.
+type Status struct {
+	MaxConcurrentDeployments int `json:"max_concurrent_deployments"`
+	InFlight                 int `json:"in_flight"`
+	Queued                   int `json:"queued"`
+}
+
+// Limiter is a semaphore on the deploys running on the node. It lets at most Max deploys in at the same time.
+// Deploys are not limited when Max is zero, but they are still counted.
+type Limiter struct {
+	Max int
+
+	mutex    sync.Mutex
+	inFlight int
+	queued   int
+	released chan struct{}
+}
+
+// NewLimiter returns a Limiter that lets at most max deploys in at the same time.
+func NewLimiter(max int) *Limiter {
+	return &Limiter{Max: max}
+}
+
+// Acquire records the start of a deploy. It returns a TooManyDeploymentsError if Max deploys are already running.
+func (l *Limiter) Acquire() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.Max > 0 && l.inFlight >= l.Max {
+		return TooManyDeploymentsError{l.Max}
+	}
+
+	l.inFlight++
+	return nil
+}
+
+// Wait records the start of a deploy like Acquire, but waits for a place while Max deploys are already running.
+// It returns a TooManyDeploymentsError if ctx is done before a place is free.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mutex.Lock()
+	for l.Max > 0 && l.inFlight >= l.Max {
+		if l.released == nil {
+			l.released = make(chan struct{})
+		}
+		released := l.released
+		l.queued++
+		l.mutex.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			l.mutex.Lock()
+			l.queued--
+			l.mutex.Unlock()
+			return TooManyDeploymentsError{l.Max}
+		}
+
+		l.mutex.Lock()
+		l.queued--
+	}
+	defer l.mutex.Unlock()
+
+	l.inFlight++
+	return nil
+}
+
+// Release records the end of a deploy started with Acquire.
+func (l *Limiter) Release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inFlight--
+	if l.released != nil {
+		close(l.released)
+		l.released = nil
+	}
+}
+
+// Status returns the most deploys that may run at the same time, the number of deploys in flight and the number of
+// deploys waiting for a place.
+func (l *Limiter) Status() Status {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return Status{MaxConcurrentDeployments: l.Max, InFlight: l.inFlight, Queued: l.queued}
+}
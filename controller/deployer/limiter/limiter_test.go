@@ -0,0 +1,83 @@
+package limiter_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/limiter"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiter", func() {
+	It("counts deploys in flight", func() {
+		limiter := NewLimiter(3)
+
+		Expect(limiter.Acquire()).To(Succeed())
+		Expect(limiter.Acquire()).To(Succeed())
+		limiter.Release()
+
+		Expect(limiter.Status()).To(Equal(Status{MaxConcurrentDeployments: 3, InFlight: 1}))
+	})
+
+	It("rejects deploys while the most deploys are running and accepts them again once one finishes", func() {
+		limiter := NewLimiter(2)
+
+		Expect(limiter.Acquire()).To(Succeed())
+		Expect(limiter.Acquire()).To(Succeed())
+		Expect(limiter.Acquire()).To(MatchError(TooManyDeploymentsError{2}))
+		Expect(limiter.Status().InFlight).To(Equal(2))
+
+		limiter.Release()
+
+		Expect(limiter.Acquire()).To(Succeed())
+	})
+
+	It("does not limit deploys when the maximum is zero", func() {
+		limiter := NewLimiter(0)
+
+		for i := 0; i < 100; i++ {
+			Expect(limiter.Acquire()).To(Succeed())
+		}
+
+		Expect(limiter.Status()).To(Equal(Status{MaxConcurrentDeployments: 0, InFlight: 100}))
+	})
+
+	Describe("waiting for a place", func() {
+		It("waits until a running deploy finishes", func() {
+			limiter := NewLimiter(1)
+			Expect(limiter.Acquire()).To(Succeed())
+
+			acquired := make(chan error, 1)
+			go func() {
+				acquired <- limiter.Wait(context.Background())
+			}()
+
+			Eventually(func() int { return limiter.Status().Queued }).Should(Equal(1))
+			Consistently(acquired).ShouldNot(Receive())
+
+			limiter.Release()
+
+			Eventually(acquired).Should(Receive(BeNil()))
+			Expect(limiter.Status()).To(Equal(Status{MaxConcurrentDeployments: 1, InFlight: 1, Queued: 0}))
+		})
+
+		It("gives up when the context is done", func() {
+			limiter := NewLimiter(1)
+			Expect(limiter.Acquire()).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			Expect(limiter.Wait(ctx)).To(MatchError(TooManyDeploymentsError{1}))
+			Expect(limiter.Status()).To(Equal(Status{MaxConcurrentDeployments: 1, InFlight: 1, Queued: 0}))
+		})
+
+		It("does not wait when there is a place", func() {
+			limiter := NewLimiter(1)
+
+			Expect(limiter.Wait(context.Background())).To(Succeed())
+			Expect(limiter.Status().InFlight).To(Equal(1))
+		})
+	})
+})
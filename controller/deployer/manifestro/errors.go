@@ -0,0 +1,11 @@
+package manifestro
+
+import "fmt"
+
+type ApplicationNotFoundError struct {
+	Application string
+}
+
+func (e ApplicationNotFoundError) Error() string {
+	return fmt.Sprintf("application %s is not in the manifest", e.Application)
+}
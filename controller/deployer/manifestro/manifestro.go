@@ -1,6 +1,9 @@
 package manifestro
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/cloudfoundry-incubator/candiedyaml"
 )
 
@@ -24,3 +27,226 @@ func GetInstances(manifest string) *uint16 {
 
 	return m.Applications[0].Instances
 }
+
+type resourcesYaml struct {
+	Applications []struct {
+		Memory    string
+		DiskQuota string `yaml:"disk_quota"`
+	}
+}
+
+// GetResources reads a Cloud Foundry manifest as a string and returns the memory and disk quota of each instance
+// of its first application, if there are any.
+func GetResources(manifest string) (memory, disk string) {
+	var m resourcesYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil || len(m.Applications) == 0 {
+		return "", ""
+	}
+
+	return m.Applications[0].Memory, m.Applications[0].DiskQuota
+}
+
+// GetSetting reads a Cloud Foundry manifest as a string and returns the value of a setting of its first
+// application, such as its memory or command. A list, such as its buildpacks, is joined with commas.
+//
+// Returns an empty string if the manifest cannot be read or its first application does not have the setting.
+func GetSetting(manifest, key string) string {
+	content, err := unmarshalManifest(manifest)
+	if err != nil {
+		return ""
+	}
+
+	applications, _ := content["applications"].([]interface{})
+	if len(applications) == 0 {
+		return ""
+	}
+	application, _ := applications[0].(map[interface{}]interface{})
+
+	switch value := application[key].(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, ", ")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Merge lays the override manifest over the base manifest and returns the result.
+//
+// Maps are merged key by key, with values from override winning. Lists and scalars from override
+// replace the ones in base. Each application in override is merged over the first application in base,
+// so base acts as a template for every application. If override has no applications, the applications
+// from base are used.
+func Merge(base, override string) (string, error) {
+	baseManifest, err := unmarshalManifest(base)
+	if err != nil {
+		return "", err
+	}
+
+	overrideManifest, err := unmarshalManifest(override)
+	if err != nil {
+		return "", err
+	}
+
+	baseApplications, _ := baseManifest["applications"].([]interface{})
+	overrideApplications, _ := overrideManifest["applications"].([]interface{})
+
+	merged := mergeMaps(baseManifest, overrideManifest)
+
+	if len(baseApplications) > 0 && len(overrideApplications) > 0 {
+		template, _ := baseApplications[0].(map[interface{}]interface{})
+
+		applications := make([]interface{}, 0, len(overrideApplications))
+		for _, application := range overrideApplications {
+			if application, ok := application.(map[interface{}]interface{}); ok {
+				applications = append(applications, mergeMaps(template, application))
+				continue
+			}
+			applications = append(applications, application)
+		}
+		merged["applications"] = applications
+	}
+
+	result, err := candiedyaml.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+// GetApplicationNames reads a Cloud Foundry manifest as a string and returns the names of its applications,
+// in the order they are declared. Applications without a name are skipped.
+//
+// Returns nil if the manifest cannot be read or declares no named applications.
+func GetApplicationNames(manifest string) []string {
+	content, err := unmarshalManifest(manifest)
+	if err != nil {
+		return nil
+	}
+
+	applications, _ := content["applications"].([]interface{})
+
+	var names []string
+	for _, application := range applications {
+		application, _ := application.(map[interface{}]interface{})
+		if name, ok := application["name"].(string); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// GetApplication returns a manifest that only has the named application of the given manifest.
+// Keys outside of applications are kept.
+//
+// Returns an ApplicationNotFoundError if the manifest has no application with the name.
+func GetApplication(manifest, name string) (string, error) {
+	content, err := unmarshalManifest(manifest)
+	if err != nil {
+		return "", err
+	}
+
+	applications, _ := content["applications"].([]interface{})
+	for _, application := range applications {
+		application, _ := application.(map[interface{}]interface{})
+		if application["name"] != name {
+			continue
+		}
+
+		content["applications"] = []interface{}{application}
+
+		result, err := candiedyaml.Marshal(content)
+		if err != nil {
+			return "", err
+		}
+
+		return string(result), nil
+	}
+
+	return "", ApplicationNotFoundError{name}
+}
+
+// SetEnv sets the environment variables on every application of a Cloud Foundry manifest, replacing
+// variables of the same name that the manifest already sets.
+func SetEnv(manifest string, env map[string]string) (string, error) {
+	content, err := unmarshalManifest(manifest)
+	if err != nil {
+		return "", err
+	}
+
+	applications, _ := content["applications"].([]interface{})
+	for _, application := range applications {
+		application, ok := application.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		vars, _ := application["env"].(map[interface{}]interface{})
+		if vars == nil {
+			vars = map[interface{}]interface{}{}
+		}
+		for name, value := range env {
+			vars[name] = value
+		}
+		application["env"] = vars
+	}
+
+	return Format(content)
+}
+
+// Parse reads a Cloud Foundry manifest as a string and returns its content.
+// An empty manifest has no content.
+func Parse(manifest string) (map[interface{}]interface{}, error) {
+	return unmarshalManifest(manifest)
+}
+
+// Format returns the content of a Cloud Foundry manifest as a string.
+func Format(content map[interface{}]interface{}) (string, error) {
+	result, err := candiedyaml.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+func unmarshalManifest(manifest string) (map[interface{}]interface{}, error) {
+	content := map[interface{}]interface{}{}
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &content)
+	if err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func mergeMaps(base, override map[interface{}]interface{}) map[interface{}]interface{} {
+	merged := make(map[interface{}]interface{}, len(base)+len(override))
+
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range override {
+		baseValue, baseIsMap := merged[key].(map[interface{}]interface{})
+		overrideValue, overrideIsMap := value.(map[interface{}]interface{})
+
+		if baseIsMap && overrideIsMap {
+			merged[key] = mergeMaps(baseValue, overrideValue)
+			continue
+		}
+		merged[key] = value
+	}
+
+	return merged
+}
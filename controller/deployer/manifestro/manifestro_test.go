@@ -110,4 +110,302 @@ applications:
 			})
 		})
 	})
+
+	Describe("GetResources", func() {
+		It("returns the memory and disk quota of the first application", func() {
+			manifest := `
+applications:
+- name: example
+  memory: 512M
+  disk_quota: 2G
+- name: other
+  memory: 4G`
+
+			memory, disk := GetResources(manifest)
+
+			Expect(memory).To(Equal("512M"))
+			Expect(disk).To(Equal("2G"))
+		})
+
+		It("returns empty sizes when the manifest does not set them", func() {
+			memory, disk := GetResources("bork")
+
+			Expect(memory).To(BeEmpty())
+			Expect(disk).To(BeEmpty())
+		})
+	})
+
+	Describe("GetSetting", func() {
+		manifest := `
+applications:
+- name: example
+  memory: 512M
+  timeout: 120
+  buildpacks:
+  - nodejs_buildpack
+  - java_buildpack
+- name: other
+  command: bin/other`
+
+		It("returns a setting of the first application", func() {
+			Expect(GetSetting(manifest, "memory")).To(Equal("512M"))
+			Expect(GetSetting(manifest, "timeout")).To(Equal("120"))
+		})
+
+		It("joins a list with commas", func() {
+			Expect(GetSetting(manifest, "buildpacks")).To(Equal("nodejs_buildpack, java_buildpack"))
+		})
+
+		It("returns an empty string when the first application does not have the setting", func() {
+			Expect(GetSetting(manifest, "command")).To(BeEmpty())
+			Expect(GetSetting("bork: [", "memory")).To(BeEmpty())
+		})
+	})
+
+	Describe("Merge", func() {
+		base := `---
+applications:
+- memory: 1G
+  buildpack: java_buildpack
+  env:
+    LOG_LEVEL: info
+    REGION: east
+  services:
+  - logging
+  - metrics
+`
+
+		It("uses the override for scalar keys", func() {
+			result, err := Merge(base, `
+applications:
+- name: example
+  memory: 2G
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result).To(MatchYAML(`
+applications:
+- name: example
+  memory: 2G
+  buildpack: java_buildpack
+  env:
+    LOG_LEVEL: info
+    REGION: east
+  services:
+  - logging
+  - metrics
+`))
+		})
+
+		It("merges nested maps key by key", func() {
+			result, err := Merge(base, `
+applications:
+- name: example
+  env:
+    REGION: west
+    FEATURE: enabled
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result).To(MatchYAML(`
+applications:
+- name: example
+  memory: 1G
+  buildpack: java_buildpack
+  env:
+    LOG_LEVEL: info
+    REGION: west
+    FEATURE: enabled
+  services:
+  - logging
+  - metrics
+`))
+		})
+
+		It("replaces lists instead of appending to them", func() {
+			result, err := Merge(base, `
+applications:
+- name: example
+  services:
+  - database
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result).To(MatchYAML(`
+applications:
+- name: example
+  memory: 1G
+  buildpack: java_buildpack
+  env:
+    LOG_LEVEL: info
+    REGION: east
+  services:
+  - database
+`))
+		})
+
+		It("applies the first base application to every override application", func() {
+			result, err := Merge(base, `
+applications:
+- name: web
+- name: worker
+  memory: 512M
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result).To(MatchYAML(`
+applications:
+- name: web
+  memory: 1G
+  buildpack: java_buildpack
+  env:
+    LOG_LEVEL: info
+    REGION: east
+  services:
+  - logging
+  - metrics
+- name: worker
+  memory: 512M
+  buildpack: java_buildpack
+  env:
+    LOG_LEVEL: info
+    REGION: east
+  services:
+  - logging
+  - metrics
+`))
+		})
+
+		It("merges top level keys outside of applications", func() {
+			result, err := Merge(`
+applications:
+- memory: 1G
+env:
+  SHARED: base
+`, `
+env:
+  OWN: override
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result).To(MatchYAML(`
+applications:
+- memory: 1G
+env:
+  SHARED: base
+  OWN: override
+`))
+		})
+
+		It("returns an error when a manifest is not a map", func() {
+			_, err := Merge(base, "- bork")
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetApplicationNames", func() {
+		It("returns the names of the applications in order", func() {
+			manifest := `---
+applications:
+- name: frontend
+- path: ./unnamed
+- name: backend
+`
+
+			Expect(GetApplicationNames(manifest)).To(Equal([]string{"frontend", "backend"}))
+		})
+
+		It("returns nil when there are no applications", func() {
+			Expect(GetApplicationNames("---\ninstances: 2\n")).To(BeNil())
+			Expect(GetApplicationNames("bork")).To(BeNil())
+		})
+	})
+
+	Describe("GetApplication", func() {
+		manifest := `---
+buildpack: java_buildpack
+applications:
+- name: frontend
+  path: ./frontend
+  instances: 2
+- name: backend
+  path: ./backend
+`
+
+		It("returns a manifest with only the named application", func() {
+			result, err := GetApplication(manifest, "backend")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result).To(MatchYAML(`
+buildpack: java_buildpack
+applications:
+- name: backend
+  path: ./backend
+`))
+		})
+
+		It("returns an error when the application is not in the manifest", func() {
+			_, err := GetApplication(manifest, "worker")
+
+			Expect(err).To(MatchError(ApplicationNotFoundError{"worker"}))
+		})
+	})
+
+	Describe("SetEnv", func() {
+		It("sets the variables on every application over the ones of the manifest", func() {
+			manifest := `---
+applications:
+- name: frontend
+  env:
+    LOG_LEVEL: debug
+    REGION: east
+- name: backend
+`
+
+			result, err := SetEnv(manifest, map[string]string{"LOG_LEVEL": "info", "TOKEN": "secret"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result).To(MatchYAML(`
+applications:
+- name: frontend
+  env:
+    LOG_LEVEL: info
+    REGION: east
+    TOKEN: secret
+- name: backend
+  env:
+    LOG_LEVEL: info
+    TOKEN: secret
+`))
+		})
+
+		It("returns an error when the manifest cannot be read", func() {
+			_, err := SetEnv("applications: [", map[string]string{"TOKEN": "secret"})
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Parse and Format", func() {
+		It("round trips a manifest", func() {
+			content, err := Parse(`---
+applications:
+- name: my-app
+  instances: 2
+`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content["applications"]).To(HaveLen(1))
+
+			manifest, err := Format(content)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest).To(MatchYAML("applications:\n- name: my-app\n  instances: 2\n"))
+		})
+
+		It("returns an error for a manifest that cannot be read", func() {
+			_, err := Parse("applications: [")
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
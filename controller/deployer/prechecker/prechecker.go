@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/httpclient"
 	I "github.com/compozed/deployadactyl/interfaces"
 	S "github.com/compozed/deployadactyl/structs"
 	"github.com/go-errors/errors"
@@ -58,9 +59,15 @@ func NewPrechecker(eventManager I.EventManager) I.Prechecker {
 	}
 }
 
+// precheckTimeout is how long a foundation has to respond to a precheck.
+const precheckTimeout = 15 * time.Second
+
 // Prechecker has an eventmanager used to manage event if prechecks fail.
 type Prechecker struct {
 	EventManager I.EventManager
+
+	// Clients are the shared HTTP clients. A client that skips TLS verification is created for each check when it is nil.
+	Clients *httpclient.Clients
 }
 
 // AssertAllFoundationsUp will send a request to each Cloud Foundry instance and check that the response status code is 200 OK.
@@ -81,15 +88,10 @@ func (p Prechecker) AssertAllFoundationsUp(environment S.Environment) error {
 		return NoFoundationsConfiguredError{}
 	}
 
-	insecureClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-			ResponseHeaderTimeout: 15 * time.Second,
-		},
-	}
+	client := p.client(environment.SkipSSL)
 
 	for _, foundationURL := range environment.Foundations {
-		resp, err := insecureClient.Get(fmt.Sprintf("%s/v2/info", foundationURL))
+		resp, err := client.Get(fmt.Sprintf("%s/v2/info", foundationURL))
 		if err != nil {
 			return InvalidGetRequestError{foundationURL, err}
 		}
@@ -110,3 +112,20 @@ func (p Prechecker) AssertAllFoundationsUp(environment S.Environment) error {
 
 	return nil
 }
+
+// client returns a client for the foundations of an environment that gives up on a foundation that does not
+// respond within the precheck timeout.
+func (p Prechecker) client(skipSSL bool) *http.Client {
+	if p.Clients == nil {
+		return &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
+				ResponseHeaderTimeout: precheckTimeout,
+			},
+		}
+	}
+
+	client := *p.Clients.Client(skipSSL)
+	client.Timeout = precheckTimeout
+	return &client
+}
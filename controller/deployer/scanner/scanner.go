@@ -0,0 +1,22 @@
+// Package scanner scans the artifact of an application before it is pushed.
+package scanner
+
+import (
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+type ArtifactScannerConstructor func(log I.DeploymentLogger) I.ArtifactScanner
+
+// NoopScanner finds nothing in artifacts. It is used when no ArtifactScanner is provided.
+type NoopScanner struct{}
+
+// NewNoopScanner returns a NoopScanner.
+func NewNoopScanner(log I.DeploymentLogger) I.ArtifactScanner {
+	return NoopScanner{}
+}
+
+// Scan returns an empty result.
+func (s NoopScanner) Scan(path string, deploymentInfo S.DeploymentInfo) (I.ScanResult, error) {
+	return I.ScanResult{}, nil
+}
@@ -0,0 +1,18 @@
+package scanner_test
+
+import (
+	. "github.com/compozed/deployadactyl/controller/deployer/scanner"
+	"github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NoopScanner", func() {
+	It("finds nothing", func() {
+		result, err := NewNoopScanner(interfaces.DeploymentLogger{}).Scan("/tmp/app", S.DeploymentInfo{AppName: "my-app"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(interfaces.ScanResult{}))
+	})
+})
@@ -0,0 +1,163 @@
+package deployer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/compozed/deployadactyl/eventmanager"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/go-errors/errors"
+)
+
+type eventBinding struct {
+	etype   reflect.Type
+	handler func(event interface{}) error
+}
+
+func (b eventBinding) Accepts(event interface{}) bool {
+	return reflect.TypeOf(event) == b.etype
+}
+
+func (b eventBinding) Emit(event interface{}) error {
+	return b.handler(event)
+}
+
+// SilentDeployFinishedEvent is emitted after a silent deploy run by the SilentDeployPool finishes.
+type SilentDeployFinishedEvent struct {
+	DeploymentInfo *S.DeploymentInfo
+	Environment    S.Environment
+	StatusCode     int
+	Error          error
+	Response       io.Reader
+}
+
+func (e SilentDeployFinishedEvent) Name() string {
+	return "SilentDeployFinishedEvent"
+}
+
+func NewSilentDeployFinishedEventBinding(handler func(event SilentDeployFinishedEvent) error) I.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(SilentDeployFinishedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(SilentDeployFinishedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type silentDeploy struct {
+	deploymentInfo *S.DeploymentInfo
+	environment    S.Environment
+	actionCreator  I.ActionCreator
+}
+
+// SilentDeployPool runs silent deploys in the background on a fixed number of workers so they do not
+// hold up the response of the primary deploy. When every worker is busy, Deploy blocks until one is free.
+type SilentDeployPool struct {
+	Deployer     I.Deployer
+	EventManager I.EventManager
+	Log          I.Logger
+
+	jobs    chan silentDeploy
+	mutex   sync.RWMutex
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// NewSilentDeployPool starts size workers that run silent deploys with the given deployer.
+func NewSilentDeployPool(deployer I.Deployer, size int, eventManager I.EventManager, log I.Logger) *SilentDeployPool {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &SilentDeployPool{
+		Deployer:     deployer,
+		EventManager: eventManager,
+		Log:          log,
+		jobs:         make(chan silentDeploy),
+	}
+
+	pool.workers.Add(size)
+	for i := 0; i < size; i++ {
+		go pool.work()
+	}
+
+	return pool
+}
+
+// Deploy hands the silent deploy to a worker and returns without waiting for it to finish. The worker deploys a
+// copy of deploymentInfo, so the Body of deploymentInfo and any actionCreator must belong to the silent deploy alone.
+//
+// Returns http.StatusAccepted, or a SilentDeployPoolClosedError after the pool has been drained.
+func (p *SilentDeployPool) Deploy(deploymentInfo *S.DeploymentInfo, env S.Environment, actionCreator I.ActionCreator, response io.ReadWriter) *I.DeployResponse {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if p.closed {
+		return &I.DeployResponse{
+			StatusCode:     http.StatusServiceUnavailable,
+			Error:          SilentDeployPoolClosedError{},
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	p.jobs <- silentDeploy{deploymentInfo.Copy(), env, actionCreator}
+
+	return &I.DeployResponse{
+		StatusCode:     http.StatusAccepted,
+		DeploymentInfo: deploymentInfo,
+	}
+}
+
+// Drain stops the pool from accepting silent deploys and waits for the queued ones to finish.
+func (p *SilentDeployPool) Drain() {
+	p.mutex.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mutex.Unlock()
+
+	p.workers.Wait()
+}
+
+func (p *SilentDeployPool) work() {
+	defer p.workers.Done()
+
+	for job := range p.jobs {
+		p.run(job)
+	}
+}
+
+func (p *SilentDeployPool) run(job silentDeploy) {
+	response := &bytes.Buffer{}
+
+	p.Log.Infof("starting silent deploy of %s with UUID %s", job.deploymentInfo.AppName, job.deploymentInfo.UUID)
+
+	deployResponse := p.Deployer.Deploy(job.deploymentInfo, job.environment, job.actionCreator, response)
+
+	if deployResponse.Error != nil {
+		p.Log.Errorf("silent deploy of %s with UUID %s failed: %s", job.deploymentInfo.AppName, job.deploymentInfo.UUID, deployResponse.Error)
+	} else {
+		p.Log.Infof("silent deploy of %s with UUID %s finished with status %d", job.deploymentInfo.AppName, job.deploymentInfo.UUID, deployResponse.StatusCode)
+	}
+
+	err := p.EventManager.EmitEvent(SilentDeployFinishedEvent{
+		DeploymentInfo: job.deploymentInfo,
+		Environment:    job.environment,
+		StatusCode:     deployResponse.StatusCode,
+		Error:          deployResponse.Error,
+		Response:       response,
+	})
+	if err != nil {
+		p.Log.Errorf("could not emit silent deploy finished event: %s", err)
+	}
+}
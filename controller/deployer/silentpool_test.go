@@ -0,0 +1,149 @@
+package deployer_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+
+	. "github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// blockingDeployer blocks every deploy until it is released.
+type blockingDeployer struct {
+	started chan string
+	release chan struct{}
+}
+
+func (d blockingDeployer) Deploy(deploymentInfo *S.DeploymentInfo, env S.Environment, actionCreator interfaces.ActionCreator, response io.ReadWriter) *interfaces.DeployResponse {
+	d.started <- deploymentInfo.AppName
+	<-d.release
+	return &interfaces.DeployResponse{StatusCode: http.StatusOK}
+}
+
+var _ = Describe("SilentDeployPool", func() {
+	var (
+		deployer     *mocks.Deployer
+		eventManager *mocks.EventManager
+		logBuffer    *Buffer
+		log          interfaces.Logger
+	)
+
+	BeforeEach(func() {
+		deployer = &mocks.Deployer{}
+		eventManager = &mocks.EventManager{}
+		logBuffer = NewBuffer()
+		log = interfaces.DefaultLogger(logBuffer, logging.DEBUG, "silentpool_test")
+	})
+
+	It("runs the deploy in the background and returns accepted", func() {
+		deployer.DeployCall.Returns.StatusCode = http.StatusOK
+		pool := NewSilentDeployPool(deployer, 1, eventManager, log)
+
+		deployResponse := pool.Deploy(&S.DeploymentInfo{AppName: "my-app", UUID: "uuid"}, S.Environment{Name: "test"}, nil, &bytes.Buffer{})
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(deployResponse.Error).ToNot(HaveOccurred())
+
+		pool.Drain()
+
+		Expect(deployer.DeployCall.Called).To(Equal(1))
+		Expect(deployer.DeployCall.Received.DeploymentInfo.AppName).To(Equal("my-app"))
+		Expect(deployer.DeployCall.Received.Env.Name).To(Equal("test"))
+		Expect(logBuffer).To(Say("silent deploy of my-app with UUID uuid finished with status 200"))
+	})
+
+	It("emits a silent deploy finished event", func() {
+		deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+		deployer.DeployCall.Returns.Error = errors.New("deploy failed")
+		pool := NewSilentDeployPool(deployer, 1, eventManager, log)
+
+		pool.Deploy(&S.DeploymentInfo{AppName: "my-app", UUID: "uuid"}, S.Environment{}, nil, &bytes.Buffer{})
+		pool.Drain()
+
+		Expect(eventManager.EmitEventCall.Received.Events).To(HaveLen(1))
+		event := eventManager.EmitEventCall.Received.Events[0].(SilentDeployFinishedEvent)
+		Expect(event.DeploymentInfo.AppName).To(Equal("my-app"))
+		Expect(event.StatusCode).To(Equal(http.StatusInternalServerError))
+		Expect(event.Error).To(MatchError("deploy failed"))
+		Expect(logBuffer).To(Say("silent deploy of my-app with UUID uuid failed: deploy failed"))
+	})
+
+	It("deploys a copy that shares no maps with the deploy it was given", func() {
+		pool := NewSilentDeployPool(deployer, 1, eventManager, log)
+		deploymentInfo := &S.DeploymentInfo{
+			AppName:              "my-app",
+			EnvironmentVariables: map[string]string{"NAME": "value"},
+			Data:                 map[string]interface{}{"nested": map[string]interface{}{"key": "value"}},
+		}
+
+		pool.Deploy(deploymentInfo, S.Environment{}, nil, &bytes.Buffer{})
+		pool.Drain()
+
+		received := deployer.DeployCall.Received.DeploymentInfo
+		Expect(received).ToNot(BeIdenticalTo(deploymentInfo))
+		Expect(received).To(Equal(deploymentInfo))
+
+		deploymentInfo.EnvironmentVariables["NAME"] = "changed"
+		deploymentInfo.Data["nested"].(map[string]interface{})["key"] = "changed"
+		Expect(received.EnvironmentVariables["NAME"]).To(Equal("value"))
+		Expect(received.Data["nested"]).To(Equal(map[string]interface{}{"key": "value"}))
+	})
+
+	It("blocks new deploys while every worker is busy", func() {
+		blocking := blockingDeployer{started: make(chan string, 2), release: make(chan struct{})}
+		pool := NewSilentDeployPool(blocking, 1, eventManager, log)
+
+		pool.Deploy(&S.DeploymentInfo{AppName: "first"}, S.Environment{}, nil, &bytes.Buffer{})
+		Eventually(blocking.started).Should(Receive(Equal("first")))
+
+		accepted := make(chan bool)
+		go func() {
+			pool.Deploy(&S.DeploymentInfo{AppName: "second"}, S.Environment{}, nil, &bytes.Buffer{})
+			accepted <- true
+		}()
+		Consistently(accepted).ShouldNot(Receive())
+
+		blocking.release <- struct{}{}
+		Eventually(accepted).Should(Receive())
+		Eventually(blocking.started).Should(Receive(Equal("second")))
+
+		blocking.release <- struct{}{}
+		pool.Drain()
+	})
+
+	It("waits for running deploys when drained", func() {
+		blocking := blockingDeployer{started: make(chan string, 1), release: make(chan struct{})}
+		pool := NewSilentDeployPool(blocking, 1, eventManager, log)
+
+		pool.Deploy(&S.DeploymentInfo{AppName: "first"}, S.Environment{}, nil, &bytes.Buffer{})
+		Eventually(blocking.started).Should(Receive())
+
+		drained := make(chan bool)
+		go func() {
+			pool.Drain()
+			drained <- true
+		}()
+		Consistently(drained).ShouldNot(Receive())
+
+		blocking.release <- struct{}{}
+		Eventually(drained).Should(Receive())
+	})
+
+	It("rejects deploys after it has been drained", func() {
+		pool := NewSilentDeployPool(deployer, 1, eventManager, log)
+		pool.Drain()
+
+		deployResponse := pool.Deploy(&S.DeploymentInfo{}, S.Environment{}, nil, &bytes.Buffer{})
+
+		Expect(deployResponse.Error).To(MatchError(SilentDeployPoolClosedError{}))
+		Expect(deployer.DeployCall.Called).To(Equal(0))
+	})
+})
@@ -0,0 +1,54 @@
+package deployer
+
+import (
+	"sync"
+
+	"github.com/compozed/deployadactyl/constants"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// SLOBreaches counts the deploys of each environment that took longer than its deploy SLO, so alerting can watch
+// the counts for slow deploys. The counts are kept in memory only and start over when deployadactyl restarts.
+//
+// SLOBreaches is a Handler and must be added to the EventManager for the deploy.slo_breach event.
+type SLOBreaches struct {
+	counts map[string]int
+	mutex  sync.Mutex
+}
+
+// NewSLOBreaches returns SLOBreaches that have not counted any breaches.
+func NewSLOBreaches() *SLOBreaches {
+	return &SLOBreaches{counts: map[string]int{}}
+}
+
+// OnEvent counts a breach of the deploy SLO by the environment of the deploy. Replayed events are not counted.
+func (s *SLOBreaches) OnEvent(event I.Event) error {
+	if event.Type != constants.DeploySLOBreachEvent || event.Replay {
+		return nil
+	}
+
+	data, ok := event.Data.(*S.DeploySLOBreach)
+	if !ok || data.DeploymentInfo == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counts[data.DeploymentInfo.Environment]++
+
+	return nil
+}
+
+// Counts returns the number of breaches of each environment that has had any.
+func (s *SLOBreaches) Counts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]int, len(s.counts))
+	for environment, count := range s.counts {
+		counts[environment] = count
+	}
+	return counts
+}
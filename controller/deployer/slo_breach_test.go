@@ -0,0 +1,47 @@
+package deployer_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/compozed/deployadactyl/constants"
+	. "github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+var _ = Describe("SLOBreaches", func() {
+	var breaches *SLOBreaches
+
+	event := func(environment string) I.Event {
+		return I.Event{Type: constants.DeploySLOBreachEvent, Data: &S.DeploySLOBreach{
+			DeploymentInfo: &S.DeploymentInfo{Environment: environment},
+			Duration:       11 * time.Minute,
+			Target:         10 * time.Minute,
+		}}
+	}
+
+	BeforeEach(func() {
+		breaches = NewSLOBreaches()
+	})
+
+	It("counts the breaches of each environment", func() {
+		Expect(breaches.OnEvent(event("prod"))).To(Succeed())
+		Expect(breaches.OnEvent(event("prod"))).To(Succeed())
+		Expect(breaches.OnEvent(event("dev"))).To(Succeed())
+
+		Expect(breaches.Counts()).To(Equal(map[string]int{"prod": 2, "dev": 1}))
+	})
+
+	It("does not count replayed events or other events", func() {
+		replayed := event("prod")
+		replayed.Replay = true
+
+		breaches.OnEvent(replayed)
+		breaches.OnEvent(I.Event{Type: constants.DeploySuccessEvent, Data: &S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{Environment: "prod"}}})
+
+		Expect(breaches.Counts()).To(BeEmpty())
+	})
+})
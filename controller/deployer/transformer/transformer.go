@@ -0,0 +1,22 @@
+// Package transformer changes the manifest of an application before it is pushed.
+package transformer
+
+import (
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+type ManifestTransformerConstructor func(log I.DeploymentLogger) I.ManifestTransformer
+
+// NoopTransformer leaves manifests as they are. It is used when no ManifestTransformer is provided.
+type NoopTransformer struct{}
+
+// NewNoopTransformer returns a NoopTransformer.
+func NewNoopTransformer(log I.DeploymentLogger) I.ManifestTransformer {
+	return NoopTransformer{}
+}
+
+// Transform returns the manifest unchanged.
+func (t NoopTransformer) Transform(manifest map[interface{}]interface{}, deploymentInfo S.DeploymentInfo) (map[interface{}]interface{}, error) {
+	return manifest, nil
+}
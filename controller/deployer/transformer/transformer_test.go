@@ -0,0 +1,24 @@
+package transformer_test
+
+import (
+	. "github.com/compozed/deployadactyl/controller/deployer/transformer"
+	"github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NoopTransformer", func() {
+	It("returns the manifest unchanged", func() {
+		manifest := map[interface{}]interface{}{
+			"applications": []interface{}{
+				map[interface{}]interface{}{"name": "my-app", "instances": 2},
+			},
+		}
+
+		transformed, err := NewNoopTransformer(interfaces.DeploymentLogger{}).Transform(manifest, S.DeploymentInfo{AppName: "my-app"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(transformed).To(Equal(manifest))
+	})
+})
@@ -0,0 +1,19 @@
+package validator
+
+import "fmt"
+
+type DeniedError struct {
+	Message string
+}
+
+func (e DeniedError) Error() string {
+	return fmt.Sprintf("deploy denied: %s", e.Message)
+}
+
+type WebhookError struct {
+	Err error
+}
+
+func (e WebhookError) Error() string {
+	return fmt.Sprintf("cannot call validation webhook: %s", e.Err)
+}
@@ -0,0 +1,141 @@
+// Package validator asks an external policy service to approve a deploy before it starts.
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// maxMessageLength limits how much of a webhook response is used as the deny message.
+const maxMessageLength = 1024
+
+// Request is the summary of a deploy that is posted to the validation webhook.
+type Request struct {
+	UUID           string                 `json:"uuid"`
+	Environment    string                 `json:"environment"`
+	Org            string                 `json:"org"`
+	Space          string                 `json:"space"`
+	AppName        string                 `json:"app_name"`
+	ArtifactURL    string                 `json:"artifact_url"`
+	ContentType    string                 `json:"content_type"`
+	Username       string                 `json:"username"`
+	ClientIdentity string                 `json:"client_identity"`
+	ChangeTicket   string                 `json:"change_ticket"`
+	Reason         string                 `json:"reason"`
+	Data           map[string]interface{} `json:"data"`
+}
+
+// Response is the optional JSON body returned by the validation webhook.
+// A 2xx response denies the deploy only when allow is explicitly false.
+type Response struct {
+	Allow   *bool  `json:"allow"`
+	Message string `json:"message"`
+}
+
+// Validator posts a summary of each deploy to a webhook and waits for it to approve the deploy.
+type Validator struct {
+	URL            string
+	Timeout        time.Duration
+	AllowOnTimeout bool
+	Client         *http.Client
+
+	// Signer signs each request when it is set.
+	Signer I.DeploySigner
+}
+
+// NewValidator returns a Validator that posts to url and gives up after timeout.
+func NewValidator(url string, timeout time.Duration, allowOnTimeout bool) Validator {
+	return Validator{
+		URL:            url,
+		Timeout:        timeout,
+		AllowOnTimeout: allowOnTimeout,
+		Client:         &http.Client{Timeout: timeout},
+	}
+}
+
+// Validate posts the deploy summary to the webhook.
+//
+// Returns a DeniedError when the webhook responds with a non-2xx status or denies the deploy,
+// or when it times out and AllowOnTimeout is false. Returns a WebhookError when the webhook cannot be reached and
+// AllowOnTimeout is false; the deploy is allowed when it is true, as when the webhook times out.
+func (v Validator) Validate(deployEventData S.DeployEventData) error {
+	info := deployEventData.DeploymentInfo
+
+	request := Request{
+		UUID:           info.UUID,
+		Environment:    info.Environment,
+		Org:            info.Org,
+		Space:          info.Space,
+		AppName:        info.AppName,
+		ArtifactURL:    info.ArtifactURL,
+		ContentType:    info.ContentType,
+		Username:       info.Username,
+		ClientIdentity: info.ClientIdentity,
+		ChangeTicket:   info.ChangeTicket,
+		Reason:         info.Reason,
+		Data:           info.Data,
+	}
+
+	body, err := signing.Marshal(&request, v.Signer)
+	if err != nil {
+		return err
+	}
+
+	resp, err := v.Client.Post(v.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		if v.AllowOnTimeout {
+			return nil
+		}
+		if isTimeout(err) {
+			return DeniedError{"validation webhook timed out after " + v.Timeout.String()}
+		}
+		return WebhookError{err}
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+
+	var response Response
+	json.Unmarshal(respBody, &response)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return DeniedError{message(response, respBody, resp.Status)}
+	}
+
+	if response.Allow != nil && !*response.Allow {
+		return DeniedError{message(response, respBody, "denied by validation webhook")}
+	}
+
+	return nil
+}
+
+func message(response Response, body []byte, fallback string) string {
+	if response.Message != "" {
+		return response.Message
+	}
+
+	if response.Allow == nil {
+		if trimmed := strings.TrimSpace(string(body)); trimmed != "" {
+			if len(trimmed) > maxMessageLength {
+				trimmed = trimmed[:maxMessageLength]
+			}
+			return trimmed
+		}
+	}
+
+	return fallback
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,176 @@
+package validator_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/validator"
+	"github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+var _ = Describe("Validator", func() {
+	var (
+		server          *httptest.Server
+		handler         http.HandlerFunc
+		received        Request
+		body            []byte
+		deployEventData S.DeployEventData
+		validator       Validator
+	)
+
+	BeforeEach(func() {
+		received = Request{}
+		handler = func(w http.ResponseWriter, r *http.Request) {}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &received)
+			handler(w, r)
+		}))
+
+		deployEventData = S.DeployEventData{
+			DeploymentInfo: &S.DeploymentInfo{
+				UUID:           "the-uuid",
+				Environment:    "production",
+				Org:            "the-org",
+				Space:          "the-space",
+				AppName:        "the-app",
+				ArtifactURL:    "https://artifacts.example.com/app.jar",
+				ContentType:    "JSON",
+				Username:       "the-user",
+				Password:       "the-password",
+				ClientIdentity: "ci-pipeline",
+				ChangeTicket:   "CHG0001",
+				Reason:         "hotfix for checkout errors",
+				Data:           map[string]interface{}{"ticket": "CHG123"},
+			},
+		}
+
+		validator = NewValidator(server.URL, time.Second, false)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("posts a summary of the deploy without the password", func() {
+		Expect(validator.Validate(deployEventData)).To(Succeed())
+
+		Expect(received).To(Equal(Request{
+			UUID:           "the-uuid",
+			Environment:    "production",
+			Org:            "the-org",
+			Space:          "the-space",
+			AppName:        "the-app",
+			ArtifactURL:    "https://artifacts.example.com/app.jar",
+			ContentType:    "JSON",
+			Username:       "the-user",
+			ClientIdentity: "ci-pipeline",
+			ChangeTicket:   "CHG0001",
+			Reason:         "hotfix for checkout errors",
+			Data:           map[string]interface{}{"ticket": "CHG123"},
+		}))
+	})
+
+	It("signs the summary with the signer", func() {
+		validator.Signer, _ = signing.NewSigner(signing.HMACSHA256, "key-1", []byte("secret"))
+
+		Expect(validator.Validate(deployEventData)).To(Succeed())
+
+		envelope := signing.Envelope{}
+		Expect(json.Unmarshal(body, &envelope)).To(Succeed())
+		Expect(envelope.Signature.KeyID).To(Equal("key-1"))
+		Expect(signing.Verify(envelope.Signature, envelope.Payload, []byte("secret"))).To(BeTrue())
+
+		summary := Request{}
+		Expect(json.Unmarshal(envelope.Payload, &summary)).To(Succeed())
+		Expect(summary.UUID).To(Equal("the-uuid"))
+	})
+
+	It("allows the deploy when the webhook explicitly allows it", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"allow": true}`)
+		}
+
+		Expect(validator.Validate(deployEventData)).To(Succeed())
+	})
+
+	It("denies the deploy when the webhook responds with a non-2xx status", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message": "no change ticket"}`)
+		}
+
+		Expect(validator.Validate(deployEventData)).To(MatchError(DeniedError{"no change ticket"}))
+	})
+
+	It("uses the response body as the message when it is not JSON", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "outside of the deploy window\n")
+		}
+
+		Expect(validator.Validate(deployEventData)).To(MatchError(DeniedError{"outside of the deploy window"}))
+	})
+
+	It("uses the status when the response has no body", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}
+
+		Expect(validator.Validate(deployEventData)).To(MatchError(DeniedError{"403 Forbidden"}))
+	})
+
+	It("denies the deploy when the webhook denies it with a 2xx status", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"allow": false, "message": "app is frozen"}`)
+		}
+
+		Expect(validator.Validate(deployEventData)).To(MatchError(DeniedError{"app is frozen"}))
+	})
+
+	Context("when the webhook times out", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+			}
+		})
+
+		It("denies the deploy by default", func() {
+			validator = NewValidator(server.URL, 50*time.Millisecond, false)
+
+			Expect(validator.Validate(deployEventData)).To(MatchError(DeniedError{"validation webhook timed out after 50ms"}))
+		})
+
+		It("allows the deploy when configured to", func() {
+			validator = NewValidator(server.URL, 50*time.Millisecond, true)
+
+			Expect(validator.Validate(deployEventData)).To(Succeed())
+		})
+	})
+
+	Context("when the webhook cannot be reached", func() {
+		It("returns a webhook error by default", func() {
+			server.Close()
+
+			err := validator.Validate(deployEventData)
+
+			Expect(err).To(BeAssignableToTypeOf(WebhookError{}))
+		})
+
+		It("allows the deploy when configured to allow it on timeout", func() {
+			server.Close()
+			validator = NewValidator(server.URL, time.Second, true)
+
+			Expect(validator.Validate(deployEventData)).To(Succeed())
+		})
+	})
+})
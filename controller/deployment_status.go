@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+)
+
+// DeploymentStatus is the body of a request for the status of a deploy.
+type DeploymentStatus struct {
+	UUID     string              `json:"uuid"`
+	Status   string              `json:"status"`
+	Reason   string              `json:"reason,omitempty"`
+	Artifact *S.ArtifactMetadata `json:"artifact,omitempty"`
+	// Record is the signed record of the latest event of the deploy, when deploy records are signed.
+	Record json.RawMessage `json:"record,omitempty"`
+}
+
+// deploymentStatusURL returns the path of the status of the deploy with the UUID.
+func deploymentStatusURL(uuid string) string {
+	return "/v2/deployments/" + uuid
+}
+
+// DeploymentStatusHandler returns whether a recent deploy is running, succeeded or failed, the reason given for it,
+// the metadata of the artifact it fetched and its signed record.
+// Deploys that were not recorded in the History, such as ones rejected before they started, are not found.
+func (c *Controller) DeploymentStatusHandler(g *gin.Context) {
+	uuid := g.Param("uuid")
+
+	var status, reason string
+	var artifact *S.ArtifactMetadata
+	var record json.RawMessage
+	var err error = deployer.DeploymentHistoryNotFoundError{UUID: uuid}
+	if c.History != nil {
+		status, err = c.History.Status(uuid)
+	}
+	if err == nil {
+		reason, err = c.History.Reason(uuid)
+	}
+	if err == nil {
+		artifact, err = c.History.Artifact(uuid)
+	}
+	if err == nil {
+		record, err = c.History.Record(uuid)
+	}
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	body, err := json.Marshal(DeploymentStatus{UUID: uuid, Status: status, Reason: reason, Artifact: artifact, Record: record})
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	g.Writer.Header().Set("Content-Type", "application/json")
+	g.Writer.WriteHeader(http.StatusOK)
+	g.Writer.Write(body)
+}
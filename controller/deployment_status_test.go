@@ -0,0 +1,125 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/compozed/deployadactyl/constants"
+	. "github.com/compozed/deployadactyl/controller"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("DeploymentStatusHandler", func() {
+	var (
+		controller *Controller
+		history    *deployer.History
+		router     *gin.Engine
+		resp       *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		history = deployer.NewHistory(10)
+		resp = httptest.NewRecorder()
+		router = gin.New()
+
+		controller = &Controller{
+			Log:     I.DefaultLogger(NewBuffer(), logging.DEBUG, "deployment_status_test"),
+			History: history,
+		}
+
+		router.GET("/v2/deployments/:uuid", controller.DeploymentStatusHandler)
+	})
+
+	record := func(eventType, uuid string) {
+		history.OnEvent(I.Event{Type: eventType, Data: &S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{UUID: uuid}}})
+	}
+
+	It("returns the status of a deploy", func() {
+		record(constants.DeployStartEvent, "my-uuid")
+		record(constants.DeploySuccessEvent, "my-uuid")
+
+		req, err := http.NewRequest("GET", "/v2/deployments/my-uuid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusOK))
+
+		var status DeploymentStatus
+		Expect(json.Unmarshal(resp.Body.Bytes(), &status)).To(Succeed())
+		Expect(status).To(Equal(DeploymentStatus{UUID: "my-uuid", Status: deployer.DeploymentSucceeded}))
+	})
+
+	It("returns the reason for a deploy", func() {
+		history.OnEvent(I.Event{Type: constants.DeployStartEvent, Data: &S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{UUID: "my-uuid", Reason: "hotfix for checkout errors"}}})
+
+		req, err := http.NewRequest("GET", "/v2/deployments/my-uuid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		router.ServeHTTP(resp, req)
+
+		var status DeploymentStatus
+		Expect(json.Unmarshal(resp.Body.Bytes(), &status)).To(Succeed())
+		Expect(status).To(Equal(DeploymentStatus{UUID: "my-uuid", Status: deployer.DeploymentRunning, Reason: "hotfix for checkout errors"}))
+	})
+
+	It("returns the artifact a deploy fetched", func() {
+		artifact := &S.ArtifactMetadata{
+			URL:       "https://example.com/my-artifact.jar",
+			Checksum:  "f6bdd0c3378276630679e1845601e166d30e7c3546d57059420e01373ee73f43",
+			SizeBytes: 2048,
+			FetchedAt: time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+		}
+		history.OnEvent(I.Event{Type: constants.DeploySuccessEvent, Data: &S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{UUID: "my-uuid", Artifact: artifact}}})
+
+		req, err := http.NewRequest("GET", "/v2/deployments/my-uuid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		router.ServeHTTP(resp, req)
+
+		var status DeploymentStatus
+		Expect(json.Unmarshal(resp.Body.Bytes(), &status)).To(Succeed())
+		Expect(status.Artifact).To(Equal(artifact))
+	})
+
+	It("returns the signed record of a deploy when deploy records are signed", func() {
+		history.Signer, _ = signing.NewSigner(signing.HMACSHA256, "key-1", []byte("secret"))
+		record(constants.DeploySuccessEvent, "my-uuid")
+
+		req, err := http.NewRequest("GET", "/v2/deployments/my-uuid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		router.ServeHTTP(resp, req)
+
+		var status DeploymentStatus
+		Expect(json.Unmarshal(resp.Body.Bytes(), &status)).To(Succeed())
+
+		envelope := signing.Envelope{}
+		Expect(json.Unmarshal(status.Record, &envelope)).To(Succeed())
+		Expect(envelope.Signature.KeyID).To(Equal("key-1"))
+
+		signed := S.DeployRecord{}
+		Expect(json.Unmarshal(envelope.Payload, &signed)).To(Succeed())
+		Expect(signed.DeploymentInfo.UUID).To(Equal("my-uuid"))
+	})
+
+	It("returns http.StatusNotFound for an unknown deploy", func() {
+		req, err := http.NewRequest("GET", "/v2/deployments/unknown-uuid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusNotFound))
+		Expect(resp.Body.String()).To(ContainSubstring("unknown-uuid"))
+	})
+})
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"strings"
+
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+)
+
+// CanonicalEnvironment returns middleware for routes whose environment param names an environment. Environments
+// are configured under lower case names, so the param is lowered when it names one whatever its case, and
+// /v2/deploy/Prod/... deploys to prod. A param that names no environment is left as it was sent, as the short
+// deploy URL treats it as an org.
+func CanonicalEnvironment(environments map[string]S.Environment) gin.HandlerFunc {
+	return func(g *gin.Context) {
+		for i, param := range g.Params {
+			if param.Key != "environment" {
+				continue
+			}
+
+			if name, ok := environmentName(environments, param.Value); ok {
+				g.Params[i].Value = name
+			}
+		}
+
+		g.Next()
+	}
+}
+
+// environmentName returns the configured name of the environment a name refers to, ignoring case.
+func environmentName(environments map[string]S.Environment, name string) (string, bool) {
+	if _, ok := environments[name]; ok {
+		return name, true
+	}
+
+	name = strings.ToLower(name)
+	_, ok := environments[name]
+	return name, ok
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/candiedyaml"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+)
+
+// RedactedValue replaces secrets in the resolved environment config.
+const RedactedValue = "[REDACTED]"
+
+// secretKeyWords mark settings and custom params whose values are redacted.
+var secretKeyWords = []string{"password", "secret", "token", "credential", "key"}
+
+// EnvironmentConfigHandler returns the resolved configuration of an environment with secrets redacted.
+// The request must authenticate with the credentials Deployadactyl uses for Cloud Foundry.
+func (c *Controller) EnvironmentConfigHandler(g *gin.Context) {
+	c.Log.Debugf("environment config request originated from: %+v", g.Request.RemoteAddr)
+
+	auth := getAuthorization(g)
+	if !c.validCredentials(auth.Username, auth.Password) {
+		c.Log.Errorf("invalid credentials for environment config request")
+		g.Writer.Header().Set("WWW-Authenticate", `Basic realm="deployadactyl"`)
+		g.Writer.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(g.Writer, deployer.InvalidCredentialsError{})
+		return
+	}
+
+	name := g.Param("environment")
+	environment, ok := c.Config.Environments[name]
+	if !ok {
+		g.Writer.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(g.Writer, deployer.EnvironmentNotFoundError{name})
+		return
+	}
+
+	body, err := resolvedConfig(environment)
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	g.Writer.Header().Set("Content-Type", "application/json")
+	g.Writer.WriteHeader(http.StatusOK)
+	g.Writer.Write(body)
+}
+
+// resolvedConfig encodes the settings of environment as json, named as in the config file, with secrets redacted.
+// The credentials an environment falls back to are never included.
+func resolvedConfig(environment S.Environment) ([]byte, error) {
+	data, err := candiedyaml.Marshal(environment)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]interface{}{}
+	err = candiedyaml.Unmarshal(data, &settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// Settings without a yaml name are encoded by their field name, which the config file spells in lower case.
+	named := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		named[strings.ToLower(key)] = value
+	}
+
+	return json.Marshal(redactParams(named))
+}
+
+func (c *Controller) validCredentials(username, password string) bool {
+	if c.Config.Username == "" || username == "" {
+		return false
+	}
+
+	validUsername := subtle.ConstantTimeCompare([]byte(username), []byte(c.Config.Username)) == 1
+	validPassword := subtle.ConstantTimeCompare([]byte(password), []byte(c.Config.Password)) == 1
+
+	return validUsername && validPassword
+}
+
+// redactParams returns a copy of params with secret values redacted. Nested yaml maps are
+// converted to string keyed maps so they can be encoded as json.
+func redactParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+
+	redacted := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		redacted[key] = redactParam(key, value)
+	}
+
+	return redacted
+}
+
+func redactParam(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return redactParams(v)
+	case map[interface{}]interface{}:
+		params := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			params[fmt.Sprint(k)] = nested
+		}
+		return redactParams(params)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, nested := range v {
+			values[i] = redactParam(key, nested)
+		}
+		return values
+	}
+
+	if isSecretKey(key) {
+		return RedactedValue
+	}
+
+	return value
+}
+
+// isSecretKey reports whether the name of a value marks it as a secret.
+func isSecretKey(key string) bool {
+	lowerKey := strings.ToLower(key)
+	for _, word := range secretKeyWords {
+		if strings.Contains(lowerKey, word) {
+			return true
+		}
+	}
+
+	return false
+}
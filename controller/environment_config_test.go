@@ -0,0 +1,159 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("EnvironmentConfigHandler", func() {
+	var (
+		controller  *Controller
+		router      *gin.Engine
+		resp        *httptest.ResponseRecorder
+		logBuffer   *Buffer
+		environment string
+		username    string
+		password    string
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		resp = httptest.NewRecorder()
+		router = gin.New()
+
+		environment = "environment-" + randomizer.StringRunes(10)
+		username = "username-" + randomizer.StringRunes(10)
+		password = "password-" + randomizer.StringRunes(10)
+
+		controller = &Controller{
+			Log: I.DefaultLogger(logBuffer, logging.DEBUG, "environment_config_test"),
+			Config: config.Config{
+				Username: username,
+				Password: password,
+				Environments: map[string]S.Environment{
+					environment: {
+						Name:             environment,
+						Domain:           "example.com",
+						Foundations:      []string{"https://api.foundation-1.example.com"},
+						SkipSSL:          true,
+						Instances:        2,
+						EnableRollback:   true,
+						HealthCheckMode:  S.HealthCheckWarn,
+						AuthFallback:     []string{S.AuthFallbackEnv},
+						FallbackUsername: "fallback-user",
+						FallbackPassword: "fallback-password",
+						CustomParams: map[string]interface{}{
+							"team":      "dinosaurs",
+							"api_token": "abc123",
+							"database": map[interface{}]interface{}{
+								"host":     "db.example.com",
+								"password": "hunter2",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		router.GET("/v2/environments/:environment/config", controller.EnvironmentConfigHandler)
+	})
+
+	request := func(env, user, pass string) {
+		req, err := http.NewRequest("GET", "/v2/environments/"+env+"/config", nil)
+		Expect(err).ToNot(HaveOccurred())
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+
+		router.ServeHTTP(resp, req)
+	}
+
+	Context("when the credentials are valid", func() {
+		It("returns the resolved environment config", func() {
+			request(environment, username, password)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			environmentConfig := map[string]interface{}{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &environmentConfig)).To(Succeed())
+
+			Expect(environmentConfig["name"]).To(Equal(environment))
+			Expect(environmentConfig["domain"]).To(Equal("example.com"))
+			Expect(environmentConfig["foundations"]).To(Equal([]interface{}{"https://api.foundation-1.example.com"}))
+			Expect(environmentConfig["skip_ssl"]).To(Equal(true))
+			Expect(environmentConfig["instances"]).To(Equal(float64(2)))
+			Expect(environmentConfig["rollback_enabled"]).To(Equal(true))
+			Expect(environmentConfig["health_check_mode"]).To(Equal(S.HealthCheckWarn))
+			Expect(environmentConfig["auth_fallback"]).To(Equal([]interface{}{S.AuthFallbackEnv}))
+		})
+
+		It("does not return the credentials the environment falls back to", func() {
+			request(environment, username, password)
+
+			Expect(resp.Body.String()).ToNot(ContainSubstring(username))
+			Expect(resp.Body.String()).ToNot(ContainSubstring("fallback-user"))
+			Expect(resp.Body.String()).ToNot(ContainSubstring("fallback-password"))
+		})
+
+		It("redacts secrets", func() {
+			request(environment, username, password)
+
+			Expect(resp.Body.String()).ToNot(ContainSubstring(password))
+			Expect(resp.Body.String()).ToNot(ContainSubstring("abc123"))
+			Expect(resp.Body.String()).ToNot(ContainSubstring("hunter2"))
+
+			environmentConfig := map[string]interface{}{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &environmentConfig)).To(Succeed())
+
+			Expect(environmentConfig["custom_params"]).To(Equal(map[string]interface{}{
+				"team":      "dinosaurs",
+				"api_token": RedactedValue,
+				"database": map[string]interface{}{
+					"host":     "db.example.com",
+					"password": RedactedValue,
+				},
+			}))
+		})
+	})
+
+	Context("when the environment does not exist", func() {
+		It("returns http.StatusNotFound", func() {
+			request("unknown", username, password)
+
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+			Expect(resp.Body.String()).To(ContainSubstring("environment not found: unknown"))
+		})
+	})
+
+	Context("when the credentials are missing", func() {
+		It("returns http.StatusUnauthorized", func() {
+			request(environment, "", "")
+
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Expect(resp.Body.String()).To(ContainSubstring("invalid credentials"))
+			Expect(resp.Body.String()).ToNot(ContainSubstring(environment))
+		})
+	})
+
+	Context("when the credentials are wrong", func() {
+		It("returns http.StatusUnauthorized", func() {
+			request(environment, username, "wrong")
+
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Eventually(logBuffer).Should(Say("invalid credentials for environment config request"))
+		})
+	})
+})
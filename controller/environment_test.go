@@ -0,0 +1,40 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/compozed/deployadactyl/controller"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CanonicalEnvironment", func() {
+	var router *gin.Engine
+
+	request := func(path string) string {
+		req, _ := http.NewRequest("POST", path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Body.String()
+	}
+
+	BeforeEach(func() {
+		router = gin.New()
+		environments := map[string]S.Environment{"prod": {Name: "Prod"}}
+		router.POST("/v2/deploy/:environment/:org/:space", CanonicalEnvironment(environments), func(g *gin.Context) {
+			fmt.Fprintf(g.Writer, "%s/%s/%s", g.Param("environment"), g.Param("org"), g.Param("space"))
+		})
+	})
+
+	It("names the environment as it is configured whatever the case of the url", func() {
+		Expect(request("/v2/deploy/PROD/Org/Space")).To(Equal("prod/Org/Space"))
+	})
+
+	It("leaves a param that names no environment as it was sent", func() {
+		Expect(request("/v2/deploy/My-Org/Space/App")).To(Equal("My-Org/Space/App"))
+	})
+})
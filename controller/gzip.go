@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gzip returns middleware that compresses the response with gzip when the request has an Accept-Encoding that
+// accepts it. Whatever was compressed is flushed to the client every flushInterval, and whenever the handler
+// flushes, so a streamed response keeps arriving while it is written. It is not flushed periodically when
+// flushInterval is not positive.
+func Gzip(flushInterval time.Duration) gin.HandlerFunc {
+	return func(g *gin.Context) {
+		if !acceptsGzip(g.Request.Header.Get("Accept-Encoding")) {
+			g.Next()
+			return
+		}
+
+		header := g.Writer.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+
+		writer := &gzipWriter{ResponseWriter: g.Writer}
+		writer.gzip = gzip.NewWriter(writer.ResponseWriter)
+		g.Writer = writer
+		defer func() { g.Writer = writer.ResponseWriter }()
+
+		if flushInterval > 0 {
+			stop := make(chan struct{})
+			defer close(stop)
+			go writer.flushEvery(flushInterval, stop)
+		}
+
+		g.Next()
+
+		writer.close()
+	}
+}
+
+// acceptsGzip returns true when an Accept-Encoding header accepts gzip, without a q of zero.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, encoding := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(encoding, ";")
+		if strings.ToLower(strings.TrimSpace(parts[0])) != "gzip" {
+			continue
+		}
+
+		for _, param := range parts[1:] {
+			param = strings.Replace(strings.TrimSpace(param), " ", "", -1)
+			if param == "q=0" || strings.HasPrefix(param, "q=0.") && strings.Trim(param[len("q=0."):], "0") == "" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter compresses everything the handler writes. The gzip writer is shared with the periodic flush,
+// so every use of it holds the mutex.
+type gzipWriter struct {
+	gin.ResponseWriter
+	gzip   *gzip.Writer
+	mutex  sync.Mutex
+	dirty  bool
+	closed bool
+}
+
+// WriteHeader drops the Content-Length of the handler, which is the length before compression.
+func (w *gzipWriter) WriteHeader(code int) {
+	w.ResponseWriter.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return 0, http.ErrBodyNotAllowed
+	}
+	w.ResponseWriter.Header().Del("Content-Length")
+	w.dirty = true
+	return w.gzip.Write(data)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush writes whatever was compressed so far to the client as a complete chunk of the gzip stream.
+func (w *gzipWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.flush()
+}
+
+func (w *gzipWriter) flush() {
+	if w.closed {
+		return
+	}
+	w.gzip.Flush()
+	w.ResponseWriter.Flush()
+	w.dirty = false
+}
+
+func (w *gzipWriter) flushEvery(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.mutex.Lock()
+			if w.dirty {
+				w.flush()
+			}
+			w.mutex.Unlock()
+		}
+	}
+}
+
+// close ends the gzip stream. A handler that did not write a body still gets an empty gzip stream so the
+// Content-Encoding holds.
+func (w *gzipWriter) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return
+	}
+	w.gzip.Close()
+	w.closed = true
+}
@@ -0,0 +1,67 @@
+package controller_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/compozed/deployadactyl/controller"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gzip", func() {
+	var router *gin.Engine
+
+	decompress := func(body []byte) string {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		decompressed, err := ioutil.ReadAll(reader)
+		Expect(err).ToNot(HaveOccurred())
+		return string(decompressed)
+	}
+
+	BeforeEach(func() {
+		router = gin.New()
+		router.POST("/deploy", Gzip(10*time.Millisecond), func(g *gin.Context) {
+			g.Writer.Header().Set("Content-Length", "24")
+			g.Writer.WriteHeader(http.StatusCreated)
+			fmt.Fprint(g.Writer, "pushing app\n")
+			g.Writer.Flush()
+			time.Sleep(30 * time.Millisecond)
+			fmt.Fprint(g.Writer, "app started\n")
+		})
+	})
+
+	It("compresses the response when the request accepts gzip", func() {
+		req, _ := http.NewRequest("POST", "/deploy", nil)
+		req.Header.Set("Accept-Encoding", "deflate, gzip;q=0.8")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusCreated))
+		Expect(resp.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(resp.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+		Expect(resp.Header().Get("Content-Length")).To(BeEmpty())
+		Expect(resp.Flushed).To(BeTrue())
+		Expect(decompress(resp.Body.Bytes())).To(Equal("pushing app\napp started\n"))
+	})
+
+	It("does not compress the response when the request does not accept gzip", func() {
+		req, _ := http.NewRequest("POST", "/deploy", nil)
+		req.Header.Set("Accept-Encoding", "gzip;q=0, br")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(resp.Body.String()).To(Equal("pushing app\napp started\n"))
+	})
+})
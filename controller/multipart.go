@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/gin-gonic/gin"
+)
+
+// Parts of a multipart push.
+const (
+	ManifestPart = "manifest"
+	ArtifactPart = "artifact"
+)
+
+// maxManifestSizeKB is the maximum size of the manifest part of a multipart push.
+const maxManifestSizeKB = 1024
+
+// readMultipart reads the manifest and artifact parts of a multipart/form-data push. Other parts are ignored.
+// A part that is sent twice returns a DuplicateMultipartPartError. The manifest part is not read past
+// maxManifestSizeKB, and the artifact part is not read past maxSizeMB; a larger part returns a ManifestTooLargeError
+// or an ArtifactTooLargeError. config.DefaultArtifactUploadMaxSizeMB is used when maxSizeMB is not positive.
+func readMultipart(g *gin.Context, maxSizeMB int64) (string, []byte, error) {
+	reader, err := g.Request.MultipartReader()
+	if err != nil {
+		return "", nil, deployer.InvalidMultipartError{err}
+	}
+
+	var (
+		manifest    []byte
+		artifact    []byte
+		hasManifest bool
+		hasArtifact bool
+		tooLarge    bool
+	)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, deployer.InvalidMultipartError{err}
+		}
+
+		switch part.FormName() {
+		case ManifestPart:
+			if hasManifest {
+				return "", nil, deployer.DuplicateMultipartPartError{ManifestPart}
+			}
+			manifest, tooLarge, err = readPart(part, maxManifestSizeKB*1024)
+			if err != nil {
+				return "", nil, err
+			}
+			if tooLarge {
+				return "", nil, deployer.ManifestTooLargeError{maxManifestSizeKB}
+			}
+			hasManifest = true
+		case ArtifactPart:
+			if hasArtifact {
+				return "", nil, deployer.DuplicateMultipartPartError{ArtifactPart}
+			}
+			if maxSizeMB < 1 {
+				maxSizeMB = config.DefaultArtifactUploadMaxSizeMB
+			}
+			artifact, tooLarge, err = readPart(part, maxSizeMB*1024*1024)
+			if err != nil {
+				return "", nil, err
+			}
+			if tooLarge {
+				return "", nil, deployer.ArtifactTooLargeError{maxSizeMB}
+			}
+			hasArtifact = true
+		}
+		part.Close()
+	}
+
+	if !hasManifest {
+		return "", nil, deployer.MissingMultipartPartError{ManifestPart}
+	}
+	if !hasArtifact {
+		return "", nil, deployer.MissingMultipartPartError{ArtifactPart}
+	}
+
+	return string(manifest), artifact, nil
+}
+
+// readPart reads a part of at most maxSize bytes. It returns true when the part is larger than that.
+func readPart(part io.Reader, maxSize int64) ([]byte, bool, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(part, maxSize+1))
+	if err != nil {
+		return nil, false, deployer.InvalidMultipartError{err}
+	}
+	if int64(len(body)) > maxSize {
+		return nil, true, nil
+	}
+
+	return body, false, nil
+}
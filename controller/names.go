@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// maxPathNameLength is the longest name Cloud Foundry accepts for an org, space or application.
+const maxPathNameLength = 255
+
+var pathNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+( +[A-Za-z0-9._-]+)*$`)
+
+// validateCFContext checks the environment, org, space and application names of the request path against the
+// naming rules of Cloud Foundry, so a bad name is rejected before anything is done on a foundation. The names
+// arrive decoded, so an encoded space is part of the name. Names missing from the route are not checked.
+func validateCFContext(cf I.CFContext) error {
+	segments := []struct {
+		name  string
+		value string
+	}{
+		{"environment", cf.Environment},
+		{"org", cf.Organization},
+		{"space", cf.Space},
+		{"appName", cf.Application},
+	}
+
+	for _, segment := range segments {
+		if segment.value == "" {
+			continue
+		}
+
+		if utf8.RuneCountInString(segment.value) > maxPathNameLength {
+			return deployer.InvalidPathNameError{segment.name, segment.value, fmt.Sprintf("must be at most %d characters", maxPathNameLength)}
+		}
+
+		if !pathNamePattern.MatchString(segment.value) {
+			return deployer.InvalidPathNameError{segment.name, segment.value, "must contain only letters, digits, '.', '-', '_' and spaces between words"}
+		}
+	}
+
+	return nil
+}
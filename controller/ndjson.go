@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// Formats of the output of a deploy, chosen with the format query param.
+const (
+	// FormatText returns the output of the deploy as plain text.
+	FormatText = "text"
+	// FormatNDJSON returns the output of the deploy as newline delimited JSON, one StreamEvent per line.
+	FormatNDJSON = "ndjson"
+)
+
+// Types of the events of an NDJSON deploy.
+const (
+	// StreamEventStart is the first event, written before the deploy starts.
+	StreamEventStart = "start"
+	// StreamEventOutput is a line of the output of the deploy.
+	StreamEventOutput = "output"
+	// StreamEventResult is the last event, written when the deploy finished.
+	StreamEventResult = "result"
+)
+
+// StreamEvent is a line of an NDJSON deploy.
+type StreamEvent struct {
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// StreamResult is the data of the result event of an NDJSON deploy. The status code of the response is always
+// http.StatusOK because it is sent before the deploy starts, so the status code of the deploy is part of its result.
+type StreamResult struct {
+	DeployResult
+	StatusCode int `json:"status_code"`
+}
+
+// getFormat returns the format query param of the request.
+func getFormat(g *gin.Context) (string, error) {
+	format := g.Query("format")
+	switch format {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatNDJSON:
+		return format, nil
+	}
+
+	return "", deployer.InvalidFormatError{format}
+}
+
+// ndjsonWriter writes the events of an NDJSON deploy to the response, flushing each one. It is the Output of the
+// deployment, so the output of the deploy is sent while it runs.
+type ndjsonWriter struct {
+	writer gin.ResponseWriter
+
+	mutex sync.Mutex
+	// partial is the start of an output line that was written without its newline.
+	partial bytes.Buffer
+}
+
+// startNDJSON sends the headers of an NDJSON deploy and its start event.
+func startNDJSON(g *gin.Context, uuid string, cfContext I.CFContext) *ndjsonWriter {
+	g.Writer.Header().Set("Content-Type", "application/x-ndjson")
+	g.Writer.WriteHeader(http.StatusOK)
+
+	w := &ndjsonWriter{writer: g.Writer}
+	w.write(StreamEventStart, "deploying "+cfContext.Application+" to "+cfContext.Environment, map[string]string{
+		"uuid":        uuid,
+		"environment": cfContext.Environment,
+		"org":         cfContext.Organization,
+		"space":       cfContext.Space,
+		"application": cfContext.Application,
+	})
+
+	return w
+}
+
+// Write writes an output event for each line of the output of the deploy as soon as the line is complete. The
+// start of a line is held until the rest of it is written or the deploy finishes.
+func (w *ndjsonWriter) Write(output []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.partial.Write(output)
+	for {
+		end := bytes.IndexByte(w.partial.Bytes(), '\n')
+		if end < 0 {
+			break
+		}
+
+		line := string(w.partial.Next(end + 1))
+		w.write(StreamEventOutput, strings.TrimSuffix(line[:end], "\r"), nil)
+	}
+
+	return len(output), nil
+}
+
+// finish writes an output event for the last line of the output of the deploy when it has no newline, then the
+// result event of the deploy. It returns the status code of the deploy.
+func (w *ndjsonWriter) finish(uuid string, deployResponse I.DeployResponse, duration time.Duration) int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.partial.Len() > 0 {
+		w.write(StreamEventOutput, strings.TrimSuffix(w.partial.String(), "\r"), nil)
+		w.partial.Reset()
+	}
+
+	result := StreamResult{
+		DeployResult: DeployResult{
+			Status:   deployer.DeploymentSucceeded,
+			UUID:     uuid,
+			Duration: duration.String(),
+		},
+		StatusCode: deployResponse.StatusCode,
+	}
+	if result.StatusCode == 0 {
+		result.StatusCode = http.StatusOK
+	}
+
+	message := "deploy succeeded"
+	if deployResponse.Error != nil {
+		result.Status = deployer.DeploymentFailed
+		result.Errors = []string{deployResponse.Error.Error()}
+		message = "cannot deploy application: " + deployResponse.Error.Error()
+	} else if deployResponse.DeploymentInfo != nil {
+		result.Artifact = deployResponse.DeploymentInfo.Artifact
+	}
+
+	w.write(StreamEventResult, message, result)
+
+	return result.StatusCode
+}
+
+func (w *ndjsonWriter) write(eventType, message string, data interface{}) {
+	line, _ := json.Marshal(StreamEvent{
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Message:   message,
+		Data:      data,
+	})
+
+	w.writer.Write(append(line, '\n'))
+	w.writer.Flush()
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// redactedHeaders are the request headers that are always redacted in the request log.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// envBodyKeys are the keys of a JSON request body whose maps of environment variables are logged with every value redacted.
+var envBodyKeys = []string{"env", "environment_variables"}
+
+// manifestBodyKey is the key of a JSON request body whose manifest, which can set environment variables, is always redacted.
+const manifestBodyKey = "manifest"
+
+// maxParsedBodyBytes is the longest JSON body the request log parses to redact it. A longer body is logged by its size only.
+const maxParsedBodyBytes = 64 * 1024
+
+// logRequest logs the method, path, headers and body of a request with the UUID of its deploy when RequestLog is
+// enabled. Credentials and headers whose names mark a secret are redacted.
+//
+// A JSON body is logged with its environment variables, manifest and the values whose keys mark a secret redacted,
+// cut to RequestLog.MaxBodyBytes. Other bodies, such as artifacts and multipart forms, are logged by their size only.
+// The body is only peeked at, so the handler still reads all of it.
+func (c *Controller) logRequest(g *gin.Context, log I.DeploymentLogger) {
+	if !c.Config.RequestLog.Enabled {
+		return
+	}
+
+	body := ": none"
+	if g.Request.Body != nil {
+		if strings.HasPrefix(g.Request.Header.Get("Content-Type"), "application/json") {
+			reader := bufio.NewReaderSize(g.Request.Body, maxParsedBodyBytes+1)
+			peeked, _ := reader.Peek(maxParsedBodyBytes + 1)
+			g.Request.Body = peekedBody{reader, g.Request.Body}
+
+			body = redactJSONBody(peeked, c.Config.RequestLog.MaxBodyBytes)
+		} else {
+			body = describeBodySize(g.Request.ContentLength)
+		}
+	}
+
+	log.Infof("request of deploy %s: %s %s headers: %s body%s", log.UUID, g.Request.Method, g.Request.URL.RequestURI(), redactHeaders(g.Request.Header), body)
+}
+
+// redactJSONBody formats the first maxBytes of a JSON body with its secrets redacted.
+// A body that is too long to parse, or that is not valid JSON, is formatted by its size only.
+func redactJSONBody(body []byte, maxBytes int) string {
+	if len(body) > maxParsedBodyBytes {
+		return fmt.Sprintf(": more than %d bytes of JSON, not logged", maxParsedBodyBytes)
+	}
+
+	var value interface{}
+	err := json.Unmarshal(body, &value)
+	if err != nil {
+		return fmt.Sprintf(": %d bytes of invalid JSON, not logged", len(body))
+	}
+
+	redacted, err := json.Marshal(redactBodyValue("", value))
+	if err != nil {
+		return fmt.Sprintf(": %d bytes of JSON, not logged", len(body))
+	}
+
+	if len(redacted) > maxBytes {
+		return fmt.Sprintf(" (truncated): %q", redacted[:maxBytes])
+	}
+
+	return fmt.Sprintf(": %q", redacted)
+}
+
+// redactBodyValue returns a copy of a value of a JSON body with its secrets redacted.
+func redactBodyValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			if isEnvBodyKey(k) {
+				redacted[k] = redactEnvValues(nested)
+			} else {
+				redacted[k] = redactBodyValue(k, nested)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, nested := range v {
+			redacted[i] = redactBodyValue(key, nested)
+		}
+		return redacted
+	}
+
+	if isSecretKey(key) || strings.ToLower(key) == manifestBodyKey {
+		return RedactedValue
+	}
+
+	return value
+}
+
+// redactEnvValues redacts every value of a map of environment variables, keeping their names.
+func redactEnvValues(value interface{}) interface{} {
+	env, ok := value.(map[string]interface{})
+	if !ok {
+		return RedactedValue
+	}
+
+	redacted := make(map[string]interface{}, len(env))
+	for name := range env {
+		redacted[name] = RedactedValue
+	}
+
+	return redacted
+}
+
+func isEnvBodyKey(key string) bool {
+	for _, envKey := range envBodyKeys {
+		if strings.ToLower(key) == envKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeBodySize formats the size of a body that is not logged.
+func describeBodySize(contentLength int64) string {
+	if contentLength < 0 {
+		return ": of unknown size, not logged"
+	}
+
+	return fmt.Sprintf(": %d bytes, not logged", contentLength)
+}
+
+// redactHeaders formats request headers sorted by name, with the values of credentials and secrets redacted.
+func redactHeaders(header http.Header) string {
+	headers := make([]string, 0, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if isSecretKey(name) {
+			value = RedactedValue
+		}
+		for _, redacted := range redactedHeaders {
+			if http.CanonicalHeaderKey(name) == redacted {
+				value = RedactedValue
+			}
+		}
+
+		headers = append(headers, name+": "+value)
+	}
+	sort.Strings(headers)
+
+	return "[" + strings.Join(headers, "; ") + "]"
+}
+
+// peekedBody reads a request body through the reader that peeked at it and closes the original body.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
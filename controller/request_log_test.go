@@ -0,0 +1,135 @@
+package controller_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("request logging", func() {
+	var (
+		controller     *Controller
+		pushController *mocks.PushController
+		router         *gin.Engine
+		logBuffer      *Buffer
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		router = gin.New()
+		pushController = &mocks.PushController{}
+		pushController.RunDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusOK}
+
+		controller = &Controller{
+			Log: I.DefaultLogger(logBuffer, logging.DEBUG, "request_log_test"),
+			PushControllerFactory: func(log I.DeploymentLogger) I.PushController {
+				return pushController
+			},
+		}
+
+		router.POST("/v3/apps/:environment/:org/:space/:appName", controller.RunDeploymentViaHttp)
+	})
+
+	deployWithContentType := func(contentType, body string) {
+		req, err := http.NewRequest("POST", "/v3/apps/prod/org/space/myApp?noCache=true", bytes.NewBufferString(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.SetBasicAuth("user", "the-password")
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Api-Key", "the-key")
+		req.Header.Set(UUIDHeader, "the-uuid")
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	deploy := func(body string) {
+		deployWithContentType("application/json", body)
+	}
+
+	Context("when the request log is enabled", func() {
+		BeforeEach(func() {
+			controller.Config.RequestLog = config.RequestLogConfig{Enabled: true, MaxBodyBytes: 16}
+		})
+
+		It("logs the request with its credentials and secrets redacted", func() {
+			deploy(`{"artifact_url": "https://example.com/my_artifact.jar"}`)
+
+			Eventually(logBuffer).Should(Say(`request of deploy the-uuid: POST /v3/apps/prod/org/space/myApp\?noCache=true headers: \[Authorization: \[REDACTED\]; Content-Type: application/json; X-Api-Key: \[REDACTED\]; X-Deployment-Uuid: the-uuid\] body \(truncated\): "{\\"artifact_url\\":"`))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("the-password"))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("the-key"))
+		})
+
+		It("leaves the whole body for the deploy", func() {
+			deploy(`{"artifact_url": "https://example.com/my_artifact.jar"}`)
+
+			Expect(string(*pushController.RunDeploymentCall.Received.Deployment.Body)).To(Equal(`{"artifact_url": "https://example.com/my_artifact.jar"}`))
+		})
+
+		It("does not mark a short body as truncated", func() {
+			deploy(`{}`)
+
+			Eventually(logBuffer).Should(Say(`body: "{}"`))
+		})
+
+		It("redacts the environment variables, the manifest and the secrets of a JSON body", func() {
+			controller.Config.RequestLog.MaxBodyBytes = 1024
+
+			deploy(`{"environment_variables": {"DB_URL": "postgres://db"}, "env": {"TOKEN": "the-token"}, "manifest": "the-manifest", "data": {"api_password": "the-secret", "team": "the-team"}}`)
+
+			Eventually(logBuffer).Should(Say(`body: "{\\"data\\":{\\"api_password\\":\\"\[REDACTED\]\\",\\"team\\":\\"the-team\\"},\\"env\\":{\\"TOKEN\\":\\"\[REDACTED\]\\"},\\"environment_variables\\":{\\"DB_URL\\":\\"\[REDACTED\]\\"},\\"manifest\\":\\"\[REDACTED\]\\"}"`))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("postgres://db"))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("the-token"))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("the-manifest"))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("the-secret"))
+		})
+
+		It("does not log a body that is not valid JSON", func() {
+			deploy(`{"env": {"TOKEN": "the-token"`)
+
+			Eventually(logBuffer).Should(Say(`body: 29 bytes of invalid JSON, not logged`))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("the-token"))
+		})
+
+		It("logs a delete request", func() {
+			controller.DeleteControllerFactory = func(log I.DeploymentLogger) I.DeleteController {
+				return &mocks.DeleteController{}
+			}
+			router.DELETE("/v2/deploy/:environment/:org/:space/:appName", controller.DeleteRequestHandler)
+
+			req, err := http.NewRequest("DELETE", "/v2/deploy/prod/org/space/myApp?deleteRoutes=true", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.SetBasicAuth("user", "the-password")
+			req.Header.Set(UUIDHeader, "the-uuid")
+
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			Eventually(logBuffer).Should(Say(`request of deploy the-uuid: DELETE /v2/deploy/prod/org/space/myApp\?deleteRoutes=true headers: \[Authorization: \[REDACTED\]; X-Deployment-Uuid: the-uuid\] body: none`))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("the-password"))
+		})
+
+		It("logs only the size of a body that is not JSON", func() {
+			deployWithContentType("application/zip", "the-artifact")
+
+			Eventually(logBuffer).Should(Say(`body: 12 bytes, not logged`))
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("the-artifact"))
+			Expect(string(*pushController.RunDeploymentCall.Received.Deployment.Body)).To(Equal("the-artifact"))
+		})
+	})
+
+	Context("when the request log is not enabled", func() {
+		It("does not log the request", func() {
+			deploy(`{"artifact_url": "https://example.com/my_artifact.jar"}`)
+
+			Expect(logBuffer.Contents()).ToNot(ContainSubstring("request of deploy"))
+		})
+	})
+})
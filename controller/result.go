@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+)
+
+// Response headers with the metadata of a finished deploy.
+const (
+	DeployUUIDHeader        = "X-Deploy-UUID"
+	DeployStatusHeader      = "X-Deploy-Status"
+	DeployEnvironmentHeader = "X-Deploy-Environment"
+	DeployDurationHeader    = "X-Deploy-Duration-Ms"
+
+	DeployArtifactURLHeader       = "X-Deploy-Artifact-URL"
+	DeployArtifactChecksumHeader  = "X-Deploy-Artifact-Checksum"
+	DeployArtifactSizeHeader      = "X-Deploy-Artifact-Size"
+	DeployArtifactFetchedAtHeader = "X-Deploy-Artifact-Fetched-At"
+	DeployArtifactGitSHAHeader    = "X-Deploy-Artifact-Git-SHA"
+)
+
+// DeployResult is the body of a deploy requested with wait=true and stream=false, in place of the deploy output.
+type DeployResult struct {
+	Status   string              `json:"status"`
+	UUID     string              `json:"uuid"`
+	Errors   []string            `json:"errors,omitempty"`
+	Duration string              `json:"duration"`
+	Artifact *S.ArtifactMetadata `json:"artifact,omitempty"`
+}
+
+// waitForResult returns true when the request asks for only the result of the deploy, without its output.
+func waitForResult(g *gin.Context) bool {
+	return g.Query("wait") == "true" && g.Query("stream") == "false"
+}
+
+// setDeployHeaders sets the deploy headers of a finished deploy, whether it succeeded or failed.
+// The UUID and environment of the deployment info are used when the push controller returned it.
+// The artifact headers are only set on a deploy that succeeded.
+func setDeployHeaders(g *gin.Context, uuid, environment string, deployResponse I.DeployResponse, duration time.Duration) {
+	if info := deployResponse.DeploymentInfo; info != nil {
+		if info.UUID != "" {
+			uuid = info.UUID
+		}
+		if info.Environment != "" {
+			environment = info.Environment
+		}
+	}
+
+	status := deployer.DeploymentSucceeded
+	if deployResponse.Error != nil {
+		status = deployer.DeploymentFailed
+	}
+
+	header := g.Writer.Header()
+	header.Set(DeployUUIDHeader, uuid)
+	header.Set(DeployStatusHeader, status)
+	header.Set(DeployEnvironmentHeader, environment)
+	header.Set(DeployDurationHeader, strconv.FormatInt(int64(duration/time.Millisecond), 10))
+
+	if info := deployResponse.DeploymentInfo; status == deployer.DeploymentSucceeded && info != nil && info.Artifact != nil {
+		setArtifactHeaders(header, *info.Artifact)
+	}
+}
+
+func setArtifactHeaders(header http.Header, artifact S.ArtifactMetadata) {
+	if artifact.URL != "" {
+		header.Set(DeployArtifactURLHeader, artifact.URL)
+	}
+	if artifact.Checksum != "" {
+		header.Set(DeployArtifactChecksumHeader, artifact.Checksum)
+		header.Set(DeployArtifactSizeHeader, strconv.FormatInt(artifact.SizeBytes, 10))
+	}
+	header.Set(DeployArtifactFetchedAtHeader, artifact.FetchedAt.Format(time.RFC3339))
+	if artifact.GitSHA != "" {
+		header.Set(DeployArtifactGitSHAHeader, artifact.GitSHA)
+	}
+}
+
+// writeDeployResult writes the result of a finished deploy as JSON with the status code of the deploy.
+func writeDeployResult(g *gin.Context, uuid string, deployResponse I.DeployResponse, duration time.Duration) {
+	result := DeployResult{
+		Status:   deployer.DeploymentSucceeded,
+		UUID:     uuid,
+		Duration: duration.String(),
+	}
+	if deployResponse.Error != nil {
+		result.Status = deployer.DeploymentFailed
+		result.Errors = []string{deployResponse.Error.Error()}
+	} else if deployResponse.DeploymentInfo != nil {
+		result.Artifact = deployResponse.DeploymentInfo.Artifact
+	}
+
+	statusCode := deployResponse.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	body, _ := json.Marshal(result)
+
+	g.Writer.Header().Set("Content-Type", "application/json")
+	g.Writer.WriteHeader(statusCode)
+	g.Writer.Write(body)
+}
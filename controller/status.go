@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/controller/deployer/circuitbreaker"
+	"github.com/compozed/deployadactyl/controller/deployer/limiter"
+	"github.com/gin-gonic/gin"
+)
+
+// Status reports the state of Deployadactyl.
+type Status struct {
+	CircuitBreakerEnabled bool                             `json:"circuit_breaker_enabled"`
+	CircuitBreakers       map[string]circuitbreaker.Status `json:"circuit_breakers"`
+	SLOBreaches           map[string]int                   `json:"slo_breaches"`
+	Deployments           limiter.Status                   `json:"deployments"`
+}
+
+// StatusHandler returns the state of the circuit breaker for each environment that has been deployed to, the
+// number of deploys of each environment that took longer than its deploy SLO and the deploys running on the server.
+func (c *Controller) StatusHandler(g *gin.Context) {
+	status := Status{CircuitBreakers: map[string]circuitbreaker.Status{}, SLOBreaches: map[string]int{}}
+	if c.CircuitBreaker != nil {
+		status.CircuitBreakerEnabled = true
+		status.CircuitBreakers = c.CircuitBreaker.Statuses()
+	}
+	if c.SLOBreaches != nil {
+		status.SLOBreaches = c.SLOBreaches.Counts()
+	}
+	if c.Limiter != nil {
+		status.Deployments = c.Limiter.Status()
+	}
+
+	body, err := json.Marshal(status)
+	if err != nil {
+		c.Log.Error(err)
+		g.Writer.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(g.Writer, err)
+		return
+	}
+
+	g.Writer.Header().Set("Content-Type", "application/json")
+	g.Writer.WriteHeader(http.StatusOK)
+	g.Writer.Write(body)
+}
@@ -0,0 +1,100 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/compozed/deployadactyl/constants"
+	. "github.com/compozed/deployadactyl/controller"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/circuitbreaker"
+	"github.com/compozed/deployadactyl/controller/deployer/limiter"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("StatusHandler", func() {
+	var (
+		controller *Controller
+		router     *gin.Engine
+		resp       *httptest.ResponseRecorder
+		logger     I.Logger
+	)
+
+	BeforeEach(func() {
+		logger = I.DefaultLogger(NewBuffer(), logging.DEBUG, "status_test")
+		resp = httptest.NewRecorder()
+		router = gin.New()
+
+		controller = &Controller{Log: logger}
+
+		router.GET("/v2/status", controller.StatusHandler)
+	})
+
+	getStatus := func() Status {
+		req, err := http.NewRequest("GET", "/v2/status", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		router.ServeHTTP(resp, req)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+
+		var status Status
+		Expect(json.Unmarshal(resp.Body.Bytes(), &status)).To(Succeed())
+		return status
+	}
+
+	Context("when the circuit breaker is disabled", func() {
+		It("reports it as disabled", func() {
+			status := getStatus()
+
+			Expect(status.CircuitBreakerEnabled).To(BeFalse())
+			Expect(status.CircuitBreakers).To(BeEmpty())
+		})
+	})
+
+	Context("when the circuit breaker is enabled", func() {
+		It("returns the state of each environment's circuit", func() {
+			breaker := circuitbreaker.NewBreaker(1, time.Minute, logger)
+			breaker.Allow("production")
+			breaker.Record("production", true)
+			breaker.Allow("preproduction")
+			breaker.Record("preproduction", false)
+			controller.CircuitBreaker = breaker
+
+			status := getStatus()
+
+			Expect(status.CircuitBreakerEnabled).To(BeTrue())
+			Expect(status.CircuitBreakers["production"].State).To(Equal(circuitbreaker.Open))
+			Expect(status.CircuitBreakers["production"].ConsecutiveFailures).To(Equal(1))
+			Expect(status.CircuitBreakers["preproduction"].State).To(Equal(circuitbreaker.Closed))
+		})
+	})
+
+	It("returns the number of deploy SLO breaches of each environment", func() {
+		breaches := deployer.NewSLOBreaches()
+		breaches.OnEvent(I.Event{Type: constants.DeploySLOBreachEvent, Data: &S.DeploySLOBreach{DeploymentInfo: &S.DeploymentInfo{Environment: "production"}}})
+		controller.SLOBreaches = breaches
+
+		status := getStatus()
+
+		Expect(status.SLOBreaches).To(Equal(map[string]int{"production": 1}))
+	})
+
+	It("returns the deploys running on the server and the most that may run at the same time", func() {
+		deploys := limiter.NewLimiter(10)
+		Expect(deploys.Acquire()).To(Succeed())
+		Expect(deploys.Acquire()).To(Succeed())
+		controller.Limiter = deploys
+
+		status := getStatus()
+
+		Expect(status.Deployments).To(Equal(limiter.Status{MaxConcurrentDeployments: 10, InFlight: 2}))
+	})
+})
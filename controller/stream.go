@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// textStream sends the plain text output of a deploy to the response as it is written, once the deploy starts to
+// wait for an approval. Its headers are sent when it starts, so the status and duration of the deploy follow its
+// output as trailers.
+type textStream struct {
+	writer gin.ResponseWriter
+
+	mutex   sync.Mutex
+	started bool
+}
+
+// start sends the headers of the deploy and the output written to response so far. It returns the writer the
+// rest of the output is sent to.
+func (s *textStream) start(uuid, environment string, response *bytes.Buffer) io.Writer {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	header := s.writer.Header()
+	header.Set(DeployUUIDHeader, uuid)
+	header.Set(DeployEnvironmentHeader, environment)
+	header.Set("Trailer", strings.Join([]string{DeployStatusHeader, DeployDurationHeader}, ", "))
+	s.writer.WriteHeader(http.StatusOK)
+
+	s.writer.Write(response.Bytes())
+	s.writer.Flush()
+	s.started = true
+
+	return s
+}
+
+// Write sends the output to the response and flushes it.
+func (s *textStream) Write(output []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n, err := s.writer.Write(output)
+	s.writer.Flush()
+	return n, err
+}
+
+// isStarted returns true when the output of the deploy is streamed.
+func (s *textStream) isStarted() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.started
+}
@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// Phases of a request reported when it times out.
+const (
+	ReadingRequestPhase = "reading request"
+	DeployingPhase      = "deploying"
+	ChangingStatePhase  = "changing state"
+	UpdatingPhase       = "updating"
+)
+
+const requestTrackerKey = "deployadactyl.requestTracker"
+
+// RequestTimeoutResponse is the body of the 504 written when a request runs past the request timeout.
+// UUID and Phase are empty when the handler did not record them before the timeout. StatusURL points to the
+// status of the deploy, which keeps running, when the request timed out while deploying.
+type RequestTimeoutResponse struct {
+	Error     string `json:"error"`
+	UUID      string `json:"uuid,omitempty"`
+	Phase     string `json:"phase,omitempty"`
+	StatusURL string `json:"status_url,omitempty"`
+}
+
+// RequestTimeout returns middleware that gives every request a context with a deadline of timeout.
+// When a request runs past it, a 504 with a RequestTimeoutResponse is written and anything the handler
+// writes afterwards is discarded. A request is not limited when timeout is not positive.
+//
+// A handler stops when it honours the context of its request; a push is canceled and rolled back unless it has
+// already finished pushing to every foundation. The middleware waits for the handler to return so its gin.Context
+// is not reused while it is still running; the client has its response as soon as the deadline passes. A handler
+// that panics gets a 500, since it does not run on the goroutine of gin.Recovery.
+func RequestTimeout(timeout time.Duration, log I.Logger) gin.HandlerFunc {
+	return func(g *gin.Context) {
+		if timeout <= 0 {
+			g.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(g.Request.Context(), timeout)
+		defer cancel()
+
+		tracker := &requestTracker{}
+		g.Set(requestTrackerKey, tracker)
+		g.Request = g.Request.WithContext(ctx)
+
+		writer := &timeoutWriter{ResponseWriter: g.Writer, header: http.Header{}}
+		g.Writer = writer
+		defer func() { g.Writer = writer.ResponseWriter }()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("request %s %s panicked: %v\n%s", g.Request.Method, g.Request.URL.Path, r, debug.Stack())
+					g.AbortWithStatus(http.StatusInternalServerError)
+				}
+			}()
+			g.Next()
+		}()
+
+		select {
+		case <-done:
+			writer.finish()
+		case <-ctx.Done():
+			uuid, phase := tracker.get()
+			log.Errorf("request %s %s timed out after %s: uuid: %s phase: %s", g.Request.Method, g.Request.URL.Path, timeout, uuid, phase)
+
+			response := RequestTimeoutResponse{
+				Error: fmt.Sprintf("request timed out after %s", timeout),
+				UUID:  uuid,
+				Phase: phase,
+			}
+			if uuid != "" && phase == DeployingPhase {
+				response.StatusURL = deploymentStatusURL(uuid)
+			}
+			writer.timeout(response)
+			<-done
+		}
+	}
+}
+
+// trackRequest records the deployment UUID and phase of a request so they can be reported if it times out.
+func trackRequest(g *gin.Context, uuid, phase string) {
+	value, ok := g.Get(requestTrackerKey)
+	if !ok {
+		return
+	}
+	value.(*requestTracker).set(uuid, phase)
+}
+
+type requestTracker struct {
+	mutex sync.Mutex
+	uuid  string
+	phase string
+}
+
+func (t *requestTracker) set(uuid, phase string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.uuid = uuid
+	t.phase = phase
+}
+
+func (t *requestTracker) get() (string, string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.uuid, t.phase
+}
+
+// timeoutWriter keeps the headers of the handler apart from the response until it writes, so the 504 can be
+// written while the handler is still running. Once the request has timed out every write is discarded.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	header    http.Header
+	mutex     sync.Mutex
+	committed bool
+	timedOut  bool
+}
+
+// Header returns the headers of the response once they are written, so the handler can still set its trailers.
+func (w *timeoutWriter) Header() http.Header {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.committed {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.timedOut {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.timedOut {
+		return
+	}
+	w.commit()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.timedOut {
+		return len(data), nil
+	}
+	w.commit()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.timedOut {
+		return
+	}
+	w.commit()
+	w.ResponseWriter.Flush()
+}
+
+// finish copies the headers of a handler that returned without writing a body.
+func (w *timeoutWriter) finish() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.commit()
+}
+
+// timeout writes the 504 unless the handler already started its response, and discards any later write.
+func (w *timeoutWriter) timeout(response RequestTimeoutResponse) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.timedOut = true
+	if w.committed {
+		return
+	}
+	w.committed = true
+
+	body, _ := json.Marshal(response)
+
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Set("Connection", "close")
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+}
+
+func (w *timeoutWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+
+	header := w.ResponseWriter.Header()
+	for key, values := range w.header {
+		header[key] = values
+	}
+}
@@ -0,0 +1,184 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/compozed/deployadactyl/controller"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("RequestTimeout", func() {
+	var (
+		router    *gin.Engine
+		logger    I.Logger
+		logBuffer *Buffer
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		logger = I.DefaultLogger(logBuffer, logging.DEBUG, "timeout_test")
+
+		router = gin.New()
+		router.Use(RequestTimeout(50*time.Millisecond, logger))
+	})
+
+	It("passes through a request that finishes in time", func() {
+		router.GET("/fast", func(g *gin.Context) {
+			g.Writer.Header().Set("X-Example", "fast")
+			g.Writer.WriteHeader(http.StatusCreated)
+			fmt.Fprint(g.Writer, "done")
+		})
+
+		req, _ := http.NewRequest("GET", "/fast", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusCreated))
+		Expect(resp.Header().Get("X-Example")).To(Equal("fast"))
+		Expect(resp.Body.String()).To(Equal("done"))
+	})
+
+	It("passes through the trailers a handler sets after it writes", func() {
+		router.GET("/trailer", func(g *gin.Context) {
+			g.Writer.Header().Set("Trailer", "X-Example")
+			fmt.Fprint(g.Writer, "done")
+			g.Writer.Header().Set("X-Example", "trailer")
+		})
+
+		req, _ := http.NewRequest("GET", "/trailer", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Body.String()).To(Equal("done"))
+		Expect(resp.Result().Trailer.Get("X-Example")).To(Equal("trailer"))
+	})
+
+	It("returns a 500 when the handler panics", func() {
+		router.GET("/panic", func(g *gin.Context) {
+			panic("broken handler")
+		})
+
+		req, _ := http.NewRequest("GET", "/panic", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+		Eventually(logBuffer).Should(Say("request GET /panic panicked: broken handler"))
+	})
+
+	It("passes a context to the handler that is done when the request times out", func() {
+		router.GET("/slow", func(g *gin.Context) {
+			<-g.Request.Context().Done()
+			Expect(g.Request.Context().Err()).To(Equal(context.DeadlineExceeded))
+		})
+
+		req, _ := http.NewRequest("GET", "/slow", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusGatewayTimeout))
+	})
+
+	It("returns a 504 naming the UUID, phase and status of a deploy that runs past the timeout", func() {
+		pushController := &mocks.PushController{}
+		pushController.RunDeploymentCall.Writes = "deployed"
+		pushController.RunDeploymentCall.Delay = 200 * time.Millisecond
+		pushController.RunDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusOK}
+
+		controller := &Controller{
+			Log: logger,
+			PushControllerFactory: func(log I.DeploymentLogger) I.PushController {
+				return pushController
+			},
+		}
+		router.POST("/v3/apps/:environment/:org/:space/:appName", controller.RunDeploymentViaHttp)
+
+		req, _ := http.NewRequest("POST", "/v3/apps/prod/org/space/myApp", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(UUIDHeader, "my-uuid")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusGatewayTimeout))
+		Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var body RequestTimeoutResponse
+		Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+		Expect(body).To(Equal(RequestTimeoutResponse{
+			Error:     "request timed out after 50ms",
+			UUID:      "my-uuid",
+			Phase:     DeployingPhase,
+			StatusURL: "/v2/deployments/my-uuid",
+		}))
+		Expect(resp.Body.String()).ToNot(ContainSubstring("deployed"))
+		Expect(logBuffer).To(Say("request POST /v3/apps/prod/org/space/myApp timed out after 50ms"))
+	})
+
+	It("returns a 504 naming the UUID and phase of a delete that runs past the timeout", func() {
+		deleteController := &mocks.DeleteController{}
+		deleteController.DeleteDeploymentCall.Delay = 200 * time.Millisecond
+		deleteController.DeleteDeploymentCall.Returns.DeployResponse = I.DeployResponse{StatusCode: http.StatusOK}
+
+		controller := &Controller{
+			Log: logger,
+			DeleteControllerFactory: func(log I.DeploymentLogger) I.DeleteController {
+				return deleteController
+			},
+		}
+		router.DELETE("/v2/deploy/:environment/:org/:space/:appName", controller.DeleteRequestHandler)
+
+		req, _ := http.NewRequest("DELETE", "/v2/deploy/prod/org/space/myApp", nil)
+		req.Header.Set(UUIDHeader, "my-uuid")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusGatewayTimeout))
+
+		var body RequestTimeoutResponse
+		Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+		Expect(body.UUID).To(Equal("my-uuid"))
+		Expect(body.Phase).To(Equal(ChangingStatePhase))
+	})
+
+	It("cancels the context of a request that runs past the timeout", func() {
+		canceled := false
+		router.GET("/slow", func(g *gin.Context) {
+			<-g.Request.Context().Done()
+			canceled = true
+		})
+
+		req, _ := http.NewRequest("GET", "/slow", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusGatewayTimeout))
+		Expect(canceled).To(BeTrue())
+	})
+
+	It("does not limit requests when the timeout is not positive", func() {
+		router = gin.New()
+		router.Use(RequestTimeout(0, logger))
+		router.GET("/slow", func(g *gin.Context) {
+			time.Sleep(100 * time.Millisecond)
+			g.Writer.WriteHeader(http.StatusOK)
+		})
+
+		req, _ := http.NewRequest("GET", "/slow", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusOK))
+	})
+})
@@ -2,29 +2,52 @@
 package creator
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"github.com/compozed/deployadactyl/applogs"
 	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/artifetcher/extractor"
 	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/constants"
 	"github.com/compozed/deployadactyl/controller"
 	"github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	"github.com/compozed/deployadactyl/state/push"
 
+	"github.com/compozed/deployadactyl/controller/deployer/approval"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
+	"github.com/compozed/deployadactyl/controller/deployer/circuitbreaker"
+	"github.com/compozed/deployadactyl/controller/deployer/drain"
 	"github.com/compozed/deployadactyl/controller/deployer/error_finder"
+	"github.com/compozed/deployadactyl/controller/deployer/hostpolicy"
+	"github.com/compozed/deployadactyl/controller/deployer/limiter"
 	"github.com/compozed/deployadactyl/controller/deployer/prechecker"
+	"github.com/compozed/deployadactyl/controller/deployer/scanner"
+	"github.com/compozed/deployadactyl/controller/deployer/transformer"
+	"github.com/compozed/deployadactyl/controller/deployer/validator"
+	"github.com/compozed/deployadactyl/deploymentlog"
+	"github.com/compozed/deployadactyl/diagnostics"
 	"github.com/compozed/deployadactyl/eventmanager"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/envvar"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/healthchecker"
+	"github.com/compozed/deployadactyl/eventmanager/handlers/kafka"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/routemapper"
+	"github.com/compozed/deployadactyl/eventmanager/retry"
+	"github.com/compozed/deployadactyl/httpclient"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/prober"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/signing"
+	"github.com/compozed/deployadactyl/state/restart"
+	"github.com/compozed/deployadactyl/state/scale"
 	"github.com/compozed/deployadactyl/state/start"
 	"github.com/compozed/deployadactyl/state/stop"
+	"github.com/compozed/deployadactyl/state/undeploy"
 	"github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
@@ -33,32 +56,91 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
+	"sort"
+	"time"
 )
 
 // ENDPOINT is used by the handler to define the deployment endpoint.
 const v2ENDPOINT = "/v2/deploy/:environment/:org/:space/:appName"
+
+// v2ShortENDPOINT is either /v2/deploy/:environment/:org/:space or /v2/deploy/:org/:space/:appName.
+// Its params are named to match v2ENDPOINT.
+const v2ShortENDPOINT = "/v2/deploy/:environment/:org/:space"
+
+// v2DefaultOrgSpaceENDPOINT is either /v2/deploy/:environment/:appName or /v2/deploy/:appName, deployed to the
+// default org and space of the API key. v2DefaultOrgSpaceShortENDPOINT is the second form.
+const v2DefaultOrgSpaceENDPOINT = "/v2/deploy/:environment/:org"
+const v2DefaultOrgSpaceShortENDPOINT = "/v2/deploy/:environment"
 const ENDPOINT = "/v3/apps/:environment/:org/:space/:appName"
+const v2EnvironmentConfigEndpoint = "/v2/environments/:environment/config"
+const v2StatusEndpoint = "/v2/status"
+const v2DeploymentStatusEndpoint = "/v2/deployments/:uuid"
+const v2CancelEndpoint = "/v2/deploy/:environment/:org/:space/:appName/cancel/:uuid"
+const v2BatchEndpoint = "/v2/deploy-batch/:environment/:org/:space"
+const v2AppLogsEndpoint = "/v2/deploy/:environment/:org/:space/:appName/applogs"
+const adminDrainEndpoint = "/admin/drain"
+const adminUndrainEndpoint = "/admin/undrain"
+const adminStatusEndpoint = "/admin/status"
+const adminReplayEndpoint = "/admin/deployments/:uuid/replay"
+const adminDiagnosticsEndpoint = "/admin/diagnostics"
+
+// deploymentHistorySize is the number of past deploys whose events can be replayed.
+const deploymentHistorySize = 100
+
+// gzipFlushInterval is how often the compressed output of a deploy is flushed to the client.
+const gzipFlushInterval = time.Second
 
 type CreatorModuleProvider struct {
-	NewCourier         courier.CourierConstructor
-	NewPrechecker      prechecker.PrecheckerConstructor
-	NewFetcher         artifetcher.ArtifetcherConstructor
-	NewExtractor       extractor.ExtractorConstructor
-	NewEventManager    eventmanager.EventManagerConstructor
-	NewPushController  push.PushControllerConstructor
-	NewStartController start.StartControllerConstructor
-	NewStopController  stop.StopControllerConstructor
+	NewCourier           courier.CourierConstructor
+	NewPrechecker        prechecker.PrecheckerConstructor
+	NewFetcher           artifetcher.ArtifetcherConstructor
+	NewExtractor         extractor.ExtractorConstructor
+	NewEventManager      eventmanager.EventManagerConstructor
+	NewPushController    push.PushControllerConstructor
+	NewStartController   start.StartControllerConstructor
+	NewStopController    stop.StopControllerConstructor
+	NewScaleController   scale.ScaleControllerConstructor
+	NewRestartController restart.RestartControllerConstructor
+	NewDeleteController  undeploy.DeleteControllerConstructor
+
+	// NewManifestTransformer creates the transformer that changes manifests before they are pushed.
+	NewManifestTransformer transformer.ManifestTransformerConstructor
+
+	// NewArtifactScanner creates the scanner that checks artifacts before they are pushed.
+	NewArtifactScanner scanner.ArtifactScannerConstructor
+
+	// NewArtifactExtractors create the extractors of artifacts of other content types than application/zip,
+	// such as application/x-tar, by content type. An extractor for application/zip replaces NewExtractor.
+	NewArtifactExtractors map[string]extractor.ArtifactExtractorConstructor
 }
 
 // Creator has a config, eventManager, logger and writer for creating dependencies.
 type Creator struct {
-	config       config.Config
-	eventManager I.EventManager
-	logger       I.Logger
-	writer       io.Writer
-	fileSystem   *afero.Afero
-	provider     CreatorModuleProvider
+	config        config.Config
+	eventManager  I.EventManager
+	logger        I.Logger
+	writer        io.Writer
+	fileSystem    *afero.Afero
+	provider      CreatorModuleProvider
+	artifactCache *artifetcher.ArtifactCache
+	logSink       *deploymentlog.Sink
+	breaker       *circuitbreaker.Breaker
+	silentPool    *deployer.SilentDeployPool
+	drain         *drain.Gate
+	limiter       *limiter.Limiter
+	promotions    *push.Promotions
+	retirements   *push.Retirements
+	cancellations *deployer.Cancellations
+	history       *deployer.History
+	historyPruner *deployer.HistoryPruner
+	cooldowns     *deployer.Cooldowns
+	sloBreaches   *deployer.SLOBreaches
+	kafkaHandler  *kafka.Handler
+	eventRetries  *retry.Queue
+	journal       *push.Journal
+	httpClients   *httpclient.Clients
+	tracer        *tracing.Tracer
+	signer        I.DeploySigner
 }
 
 // Default returns a default Creator and an Error.
@@ -89,13 +171,41 @@ func Custom(level string, configFilename string, provider CreatorModuleProvider)
 func (c Creator) CreateControllerHandler(controller I.Controller) *gin.Engine {
 
 	r := gin.New()
+	// Routes are matched on the path as it was sent, so an encoded name such as my%2Fapp stays in its own
+	// segment. The params are decoded before the controller sees them.
+	r.UseRawPath = true
 	r.Use(gin.Recovery())
 	r.Use(gin.LoggerWithWriter(c.createWriter()))
 	r.Use(gin.ErrorLogger())
+	r.Use(c.createRequestTimeout())
+	r.Use(c.createAPIKeyAuth())
+
+	// The output of a deploy can be large, so it is compressed for clients that accept gzip.
+	compress := c.createGzip()
 
-	r.POST(v2ENDPOINT, controller.RunDeploymentViaHttp)
-	r.POST(ENDPOINT, controller.RunDeploymentViaHttp)
-	r.PUT(ENDPOINT, controller.PutRequestHandler)
+	// Environment names are matched whatever their case. The param of v2DefaultOrgSpaceShortENDPOINT is an
+	// application, so its case is kept.
+	environment := c.createCanonicalEnvironment()
+
+	r.POST(v2ENDPOINT, environment, compress, controller.RunDeploymentViaHttp)
+	r.POST(v2ShortENDPOINT, environment, compress, controller.RunShortDeploymentViaHttp)
+	r.POST(v2DefaultOrgSpaceENDPOINT, environment, compress, controller.RunDefaultOrgSpaceDeploymentViaHttp)
+	r.POST(v2DefaultOrgSpaceShortENDPOINT, compress, controller.RunDefaultOrgSpaceDeploymentViaHttp)
+	r.POST(ENDPOINT, environment, compress, controller.RunDeploymentViaHttp)
+	r.PUT(ENDPOINT, environment, controller.PutRequestHandler)
+	r.PATCH(v2ENDPOINT, environment, controller.PatchRequestHandler)
+	r.DELETE(v2ENDPOINT, environment, controller.DeleteRequestHandler)
+	r.GET(v2EnvironmentConfigEndpoint, environment, controller.EnvironmentConfigHandler)
+	r.GET(v2StatusEndpoint, controller.StatusHandler)
+	r.GET(v2DeploymentStatusEndpoint, controller.DeploymentStatusHandler)
+	r.POST(v2CancelEndpoint, environment, controller.CancelDeploymentHandler)
+	r.POST(v2BatchEndpoint, environment, compress, controller.BatchDeploymentHandler)
+	r.GET(v2AppLogsEndpoint, environment, controller.AppLogsHandler)
+	r.POST(adminDrainEndpoint, controller.AdminDrainHandler)
+	r.POST(adminUndrainEndpoint, controller.AdminUndrainHandler)
+	r.GET(adminStatusEndpoint, controller.AdminStatusHandler)
+	r.POST(adminReplayEndpoint, controller.AdminReplayHandler)
+	r.GET(adminDiagnosticsEndpoint, controller.AdminDiagnosticsHandler)
 
 	return r
 }
@@ -110,12 +220,57 @@ func (c Creator) CreateListener() net.Listener {
 	if err != nil {
 		log.Fatal(err)
 	}
-	return ls
+
+	if !c.config.TLS.Enabled {
+		return ls
+	}
+
+	tlsConfig, err := c.createTLSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return tls.NewListener(ls, tlsConfig)
+}
+
+// createTLSConfig loads the server certificate and, when a client CA bundle is configured,
+// verifies client certificates against it.
+func (c Creator) createTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.config.TLS.CertFile, c.config.TLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load tls certificate: %s", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.config.TLS.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caBundle, err := c.fileSystem.ReadFile(c.config.TLS.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read client ca bundle: %s", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("no certificates found in client ca bundle %s", c.config.TLS.ClientCAFile)
+	}
+
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if c.config.TLS.RequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
 }
 
 // CreateCourier returns a courier with an executor.
 func (c Creator) CreateCourier() (I.Courier, error) {
-	ex, err := executor.New(c.CreateFileSystem())
+	ex, err := executor.New(c.CreateFileSystem(), c.config.CFCLI.Path, c.config.Staging.Directory)
 	if err != nil {
 		return nil, err
 	}
@@ -146,34 +301,82 @@ func (c Creator) CreateFileSystem() *afero.Afero {
 	return c.fileSystem
 }
 
-// CreateHTTPClient return an http client.
-func (c Creator) CreateHTTPClient() *http.Client {
-	insecureClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}
-
-	return insecureClient
+// CreateHTTPClient returns the shared http client that verifies TLS certificates, or skips the verification
+// with skipSSL.
+func (c Creator) CreateHTTPClient(skipSSL bool) *http.Client {
+	return c.httpClients.Client(skipSSL)
 }
 
 func (c Creator) CreateController() I.Controller {
 	return &controller.Controller{
-		Log: c.logger,
-		PushControllerFactory:  c.CreatePushController,
-		StopControllerFactory:  c.CreateStopController,
-		StartControllerFactory: c.CreateStartController,
-		Config:                 c.CreateConfig(),
-		EventManager:           c.CreateEventManager(),
-		ErrorFinder:            c.createErrorFinder(),
+		Log:                      c.logger,
+		PushControllerFactory:    c.CreatePushController,
+		StopControllerFactory:    c.CreateStopController,
+		StartControllerFactory:   c.CreateStartController,
+		ScaleControllerFactory:   c.CreateScaleController,
+		RestartControllerFactory: c.CreateRestartController,
+		DeleteControllerFactory:  c.CreateDeleteController,
+		Config:                   c.CreateConfig(),
+		EventManager:             c.CreateEventManager(),
+		ErrorFinder:              c.createErrorFinder(),
+		DeploymentLogSink:        c.logSink,
+		CircuitBreaker:           c.breaker,
+		Drain:                    c.drain,
+		Limiter:                  c.limiter,
+		Cancellations:            c.cancellations,
+		History:                  c.history,
+		Cooldowns:                c.cooldowns,
+		SLOBreaches:              c.sloBreaches,
+		Tracer:                   c.tracer,
+		Diagnostics:              c.createDiagnostics(),
+		AppLogs:                  applogs.Fetcher{CourierCreator: c, Log: c.logger},
+		ArtifactTypes:            c.artifactTypes(),
 	}
 }
 
+// createDiagnostics returns a Checker of the Cloud Foundry API of every foundation, by environment name,
+// and of the validation webhook when one is configured.
+func (c Creator) createDiagnostics() *diagnostics.Checker {
+	names := make([]string, 0, len(c.config.Environments))
+	for name := range c.config.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checker := &diagnostics.Checker{Clients: c.httpClients}
+	for _, name := range names {
+		environment := c.config.Environments[name]
+		for _, foundation := range environment.Foundations {
+			checker.Dependencies = append(checker.Dependencies, diagnostics.Dependency{
+				Kind:        diagnostics.FoundationDependency,
+				Environment: name,
+				URL:         foundation,
+				SkipSSL:     environment.SkipSSL,
+			})
+		}
+	}
+
+	if c.config.ValidationWebhook.URL != "" {
+		checker.Dependencies = append(checker.Dependencies, diagnostics.Dependency{
+			Kind: diagnostics.ValidationWebhookDependency,
+			URL:  c.config.ValidationWebhook.URL,
+		})
+	}
+
+	return checker
+}
+
 func (c Creator) CreatePushController(log I.DeploymentLogger) I.PushController {
 	if c.provider.NewPushController != nil {
-		return c.provider.NewPushController(log, c.createDeployer(log), c.createSilentDeployer(), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
+		return c.provider.NewPushController(log, c.createDeployer(log), c.createSilentDeployer(), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c, c.createDeployValidator())
 	}
-	return push.NewPushController(log, c.createDeployer(log), c.createSilentDeployer(), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
+	pushController := push.NewPushController(log, c.createDeployer(log), c.createSilentDeployer(), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c, c.createDeployValidator()).(*push.PushController)
+	pushController.Tracer = c.tracer
+	pushController.Approver = c.createDeployApprover()
+	pushController.EventRetries = c.eventRetries
+	pushController.ArtifactTypes = c.artifactTypes()
+	pushController.Cancellations = c.cancellations
+	return pushController
 }
 
 func (c Creator) CreateStopController(log I.DeploymentLogger) I.StopController {
@@ -190,8 +393,29 @@ func (c Creator) CreateStartController(log I.DeploymentLogger) I.StartController
 	return start.NewStartController(log, c.createDeployer(log), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
 }
 
+func (c Creator) CreateScaleController(log I.DeploymentLogger) I.ScaleController {
+	if c.provider.NewScaleController != nil {
+		return c.provider.NewScaleController(log, c.createDeployer(log), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
+	}
+	return scale.NewScaleController(log, c.createDeployer(log), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
+}
+
+func (c Creator) CreateRestartController(log I.DeploymentLogger) I.RestartController {
+	if c.provider.NewRestartController != nil {
+		return c.provider.NewRestartController(log, c.createDeployer(log), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
+	}
+	return restart.NewRestartController(log, c.createDeployer(log), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
+}
+
+func (c Creator) CreateDeleteController(log I.DeploymentLogger) I.DeleteController {
+	if c.provider.NewDeleteController != nil {
+		return c.provider.NewDeleteController(log, c.createDeployer(log), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
+	}
+	return undeploy.NewDeleteController(log, c.createDeployer(log), c.CreateConfig(), c.CreateEventManager(), c.createErrorFinder(), c)
+}
+
 func (c Creator) createDeployer(log I.DeploymentLogger) I.Deployer {
-	return deployer.Deployer{
+	d := deployer.Deployer{
 		Config:       c.CreateConfig(),
 		BlueGreener:  c.createBlueGreener(log),
 		Prechecker:   c.createPrechecker(),
@@ -200,6 +424,13 @@ func (c Creator) createDeployer(log I.DeploymentLogger) I.Deployer {
 		ErrorFinder:  c.createErrorFinder(),
 		Log:          log,
 	}
+
+	var wrapped I.Deployer = d
+	if c.breaker != nil {
+		wrapped = circuitbreaker.Deployer{Deployer: wrapped, Breaker: c.breaker, Log: log}
+	}
+
+	return wrapped
 }
 
 func (c Creator) PushManager(log I.DeploymentLogger, deployEventData structs.DeployEventData, cf I.CFContext, auth I.Authorization, env structs.Environment, envVars map[string]string) I.ActionCreator {
@@ -207,14 +438,62 @@ func (c Creator) PushManager(log I.DeploymentLogger, deployEventData structs.Dep
 		CourierCreator:       c,
 		EventManager:         c.CreateEventManager(),
 		Logger:               log,
-		Fetcher:              c.createFetcher(log),
+		Fetcher:              c.createFetcher(log, deployEventData.DeploymentInfo),
 		DeployEventData:      deployEventData,
 		FileSystemCleaner:    c.CreateFileSystem(),
+		ManifestWriter:       c.CreateFileSystem(),
 		CFContext:            cf,
 		Auth:                 auth,
 		Environment:          env,
 		EnvironmentVariables: envVars,
+		Prober:               prober.Prober{},
+		ManifestTransformer:  c.createManifestTransformer(log),
+		ArtifactScanner:      c.createArtifactScanner(log),
+		Promotions:           c.promotions,
+		Retirements:          c.retirements,
+		Cancellations:        c.cancellations,
+		Tracer:               c.tracer,
+		Journal:              c.journal,
+	}
+}
+
+// PromoteManager returns the manager that completes the manual cutover of the deploy with the UUID in deployEventData.
+// It returns an error when no deploy of that application is waiting for promotion.
+func (c Creator) PromoteManager(log I.DeploymentLogger, deployEventData structs.DeployEventData) (I.ActionCreator, error) {
+	info := deployEventData.DeploymentInfo
+
+	pending, ok := c.promotions.Get(info.UUID)
+	if !ok || pending.Environment != info.Environment || pending.Org != info.Org || pending.Space != info.Space || pending.AppName != info.AppName {
+		return nil, deployer.PendingPromotionNotFoundError{UUID: info.UUID, AppName: info.AppName}
 	}
+
+	return push.PromoteManager{
+		CourierCreator:  c,
+		EventManager:    c.CreateEventManager(),
+		Logger:          log,
+		DeployEventData: deployEventData,
+		Promotions:      c.promotions,
+		Retirements:     c.retirements,
+	}, nil
+}
+
+// RollbackManager returns the manager that brings back the application replaced by the deploy with the UUID in
+// deployEventData. It returns an error when no application replaced by that deploy is waiting for deletion.
+func (c Creator) RollbackManager(log I.DeploymentLogger, deployEventData structs.DeployEventData) (I.ActionCreator, error) {
+	info := deployEventData.DeploymentInfo
+
+	retired, ok := c.retirements.Get(info.UUID, info.AppName)
+	if !ok || retired.Environment != info.Environment || retired.Org != info.Org || retired.Space != info.Space {
+		return nil, deployer.RetirementNotFoundError{UUID: info.UUID, AppName: info.AppName}
+	}
+
+	return &push.RollbackManager{
+		CourierCreator:  c,
+		EventManager:    c.CreateEventManager(),
+		Logger:          log,
+		DeployEventData: deployEventData,
+		Retirements:     c.retirements,
+	}, nil
 }
 
 func (c Creator) StopManager(log I.DeploymentLogger, deployEventData structs.DeployEventData) I.ActionCreator {
@@ -236,15 +515,65 @@ func (c Creator) StartManager(log I.DeploymentLogger, deployEventData structs.De
 	}
 }
 
+func (c Creator) ScaleManager(log I.DeploymentLogger, deployEventData structs.DeployEventData, spec structs.ScaleSpec) I.ActionCreator {
+	return scale.ScaleManager{
+		CourierCreator:  c,
+		EventManager:    c.CreateEventManager(),
+		Logger:          log,
+		DeployEventData: deployEventData,
+		Spec:            spec,
+	}
+}
+
+func (c Creator) RestartManager(log I.DeploymentLogger, deployEventData structs.DeployEventData, restage bool) I.ActionCreator {
+	return restart.RestartManager{
+		CourierCreator:  c,
+		EventManager:    c.CreateEventManager(),
+		Logger:          log,
+		DeployEventData: deployEventData,
+		Restage:         restage,
+	}
+}
+
+func (c Creator) DeleteManager(log I.DeploymentLogger, deployEventData structs.DeployEventData, options structs.DeleteOptions) I.ActionCreator {
+	return undeploy.DeleteManager{
+		CourierCreator:  c,
+		EventManager:    c.CreateEventManager(),
+		Logger:          log,
+		DeployEventData: deployEventData,
+		Options:         options,
+	}
+}
+
 func (c Creator) CreateEnvVarHandler() envvar.Envvarhandler {
 	return envvar.Envvarhandler{FileSystem: c.CreateFileSystem()}
 }
 
 func (c Creator) CreateHealthChecker() healthchecker.HealthChecker {
+	healthCheck := c.config.HealthCheck
+
+	urlPrefixes := map[string]healthchecker.URLPrefixes{}
+	for name, environment := range c.config.Environments {
+		if environment.APIURLPrefix != "" && environment.AppsURLPrefix != "" {
+			urlPrefixes[name] = healthchecker.URLPrefixes{environment.APIURLPrefix, environment.AppsURLPrefix}
+		}
+	}
+
 	return healthchecker.HealthChecker{
-		OldURL: "api.cf",
-		NewURL: "apps",
-		Client: c.CreateHTTPClient(),
+		OldURL:                 "api.cf",
+		NewURL:                 "apps",
+		EnvironmentURLPrefixes: urlPrefixes,
+		UnreachablePolicy: healthchecker.Policy{
+			Retries:       healthCheck.Unreachable.Retries,
+			RetryInterval: time.Duration(healthCheck.Unreachable.RetryIntervalSeconds) * time.Second,
+		},
+		UnhealthyPolicy: healthchecker.Policy{
+			Retries:       healthCheck.Unhealthy.Retries,
+			RetryInterval: time.Duration(healthCheck.Unhealthy.RetryIntervalSeconds) * time.Second,
+		},
+		EventManager:   c.CreateEventManager(),
+		Client:         c.CreateHTTPClient(false),
+		InsecureClient: c.CreateHTTPClient(true),
 	}
 }
 
@@ -254,8 +583,137 @@ func (c Creator) CreateRouteMapper() routemapper.RouteMapper {
 	}
 }
 
+func (c Creator) createDeployValidator() I.DeployValidator {
+	if c.config.ValidationWebhook.URL == "" {
+		return nil
+	}
+
+	timeout := time.Duration(c.config.ValidationWebhook.TimeoutSeconds) * time.Second
+	deployValidator := validator.NewValidator(c.config.ValidationWebhook.URL, timeout, c.config.ValidationWebhook.AllowOnTimeout)
+	deployValidator.Signer = c.signer
+	return deployValidator
+}
+
+func (c Creator) createDeployApprover() I.DeployApprover {
+	if c.config.ApprovalGate.URL == "" {
+		return nil
+	}
+
+	pollInterval := time.Duration(c.config.ApprovalGate.PollIntervalSeconds) * time.Second
+	timeout := time.Duration(c.config.ApprovalGate.TimeoutSeconds) * time.Second
+	gate := approval.NewGate(c.config.ApprovalGate.URL, pollInterval, timeout)
+	gate.Signer = c.signer
+	return gate
+}
+
 func (c Creator) createSilentDeployer() I.Deployer {
-	return deployer.SilentDeployer{}
+	return c.silentPool
+}
+
+// DrainSilentDeploys stops accepting silent deploys and waits for the running ones to finish.
+func (c Creator) DrainSilentDeploys() {
+	c.silentPool.Drain()
+}
+
+// CloseKafkaProducer publishes the deploy events still being sent to Kafka and closes the connection.
+// It does nothing when Kafka is not configured.
+func (c Creator) CloseKafkaProducer() {
+	if c.kafkaHandler == nil {
+		return
+	}
+
+	c.logger.Infof("closing the kafka producer")
+	err := c.kafkaHandler.Close()
+	if err != nil {
+		c.logger.Errorf("could not close the kafka producer: %s", err)
+	}
+}
+
+// RecoverInterruptedDeploys cleans up after the deploys that a previous run left unfinished when it stopped, and marks
+// them successful or failed. It does nothing when deploy recovery is off.
+func (c Creator) RecoverInterruptedDeploys() {
+	if c.journal == nil {
+		return
+	}
+
+	cfg := c.CreateConfig()
+	recovery := push.Recovery{
+		Journal:        c.journal,
+		CourierCreator: c,
+		EventManager:   c.CreateEventManager(),
+		Log:            c.logger,
+		Credentials: func(environment string) (string, string, bool) {
+			env, ok := cfg.Environments[environment]
+			if !ok {
+				return "", "", false
+			}
+			return cfg.FallbackAuthorization(env)
+		},
+	}
+	recovery.Recover()
+}
+
+// StartEventRetries starts emitting the failed deploy events kept in the event retry directory again, including the
+// ones left by a previous run. It does nothing when event retries are off.
+func (c Creator) StartEventRetries() {
+	if c.eventRetries == nil {
+		return
+	}
+
+	c.eventRetries.Start()
+}
+
+// StopEventRetries stops emitting failed deploy events again. The events left are retried on the next start.
+// It does nothing when event retries are off.
+func (c Creator) StopEventRetries() {
+	if c.eventRetries == nil {
+		return
+	}
+
+	c.eventRetries.Stop()
+}
+
+// StartHistoryPruning prunes the deployment history in the background. It does nothing when the history has no
+// retention limits.
+func (c Creator) StartHistoryPruning() {
+	if c.historyPruner == nil {
+		return
+	}
+
+	c.historyPruner.Start()
+}
+
+// StopHistoryPruning stops pruning the deployment history. It does nothing when the history has no retention limits.
+func (c Creator) StopHistoryPruning() {
+	if c.historyPruner == nil {
+		return
+	}
+
+	c.historyPruner.Stop()
+}
+
+// EnableTracing exports OpenTelemetry spans of the deploys to the collector configured in otel.
+func (c Creator) EnableTracing() error {
+	err := c.tracer.Enable(c.config.OTel.Endpoint, c.config.OTel.Insecure, c.logger)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Infof("exporting deploy spans to %s", c.config.OTel.Endpoint)
+	return nil
+}
+
+// ShutdownTracing exports the spans still waiting to be sent. It does nothing when tracing is not enabled.
+func (c Creator) ShutdownTracing(ctx context.Context) {
+	if !c.tracer.Enabled() {
+		return
+	}
+
+	c.logger.Infof("exporting the remaining deploy spans")
+	err := c.tracer.Shutdown(ctx)
+	if err != nil {
+		c.logger.Errorf("could not export the remaining deploy spans: %s", err)
+	}
 }
 
 func (c Creator) createExtractor(log I.DeploymentLogger) I.Extractor {
@@ -265,11 +723,72 @@ func (c Creator) createExtractor(log I.DeploymentLogger) I.Extractor {
 	return extractor.NewExtractor(log, c.CreateFileSystem())
 }
 
-func (c Creator) createFetcher(log I.DeploymentLogger) I.Fetcher {
+// createArtifactExtractors returns the extractor of each artifact content type.
+func (c Creator) createArtifactExtractors(log I.DeploymentLogger) map[string]I.ArtifactExtractor {
+	extractors := map[string]I.ArtifactExtractor{
+		extractor.ZipContentType: extractor.ArtifactExtractorFunc(c.createExtractor(log).Unzip),
+	}
+	for contentType, newArtifactExtractor := range c.provider.NewArtifactExtractors {
+		extractors[extractor.MediaType(contentType)] = newArtifactExtractor(log, c.CreateFileSystem())
+	}
+	return extractors
+}
+
+// artifactTypes returns the content types of artifacts with an extractor in the CreatorModuleProvider.
+func (c Creator) artifactTypes() []string {
+	contentTypes := make([]string, 0, len(c.provider.NewArtifactExtractors))
+	for contentType := range c.provider.NewArtifactExtractors {
+		contentTypes = append(contentTypes, extractor.MediaType(contentType))
+	}
+	sort.Strings(contentTypes)
+	return contentTypes
+}
+
+func (c Creator) createFetcher(log I.DeploymentLogger, deploymentInfo *structs.DeploymentInfo) I.Fetcher {
 	if c.provider.NewFetcher != nil {
 		return c.provider.NewFetcher(c.CreateFileSystem(), c.createExtractor(log), log)
 	}
-	return artifetcher.NewArtifetcher(c.CreateFileSystem(), c.createExtractor(log), log)
+
+	artifactDownload := c.CreateConfig().ArtifactDownload
+	artifactHosts := c.CreateConfig().ArtifactHosts
+
+	fetcher := &artifetcher.Artifetcher{
+		FileSystem:   c.CreateFileSystem(),
+		Extractor:    c.createExtractor(log),
+		Log:          log,
+		MaxRetries:   artifactDownload.MaxRetries,
+		RetryBackoff: time.Duration(artifactDownload.BackoffSeconds) * time.Second,
+		ContentType:  deploymentInfo.ArtifactType,
+		Extractors:   c.createArtifactExtractors(log),
+
+		StagingDirectory: c.CreateConfig().Staging.Directory,
+		HostPolicy: &hostpolicy.Policy{
+			AllowedHosts:   artifactHosts.Allowed,
+			DeniedCIDRs:    artifactHosts.DeniedCIDRs,
+			AllowLinkLocal: artifactHosts.AllowLinkLocal,
+		},
+	}
+
+	if c.artifactCache != nil && !deploymentInfo.NoCache {
+		fetcher.Cache = c.artifactCache
+		fetcher.Checksum = deploymentInfo.ArtifactChecksum
+	}
+
+	return fetcher
+}
+
+func (c Creator) createManifestTransformer(log I.DeploymentLogger) I.ManifestTransformer {
+	if c.provider.NewManifestTransformer != nil {
+		return c.provider.NewManifestTransformer(log)
+	}
+	return transformer.NewNoopTransformer(log)
+}
+
+func (c Creator) createArtifactScanner(log I.DeploymentLogger) I.ArtifactScanner {
+	if c.provider.NewArtifactScanner != nil {
+		return c.provider.NewArtifactScanner(log)
+	}
+	return scanner.NewNoopScanner(log)
 }
 
 func (c Creator) createRandomizer() I.Randomizer {
@@ -280,7 +799,26 @@ func (c Creator) createPrechecker() I.Prechecker {
 	if c.provider.NewPrechecker != nil {
 		return c.provider.NewPrechecker(c.CreateEventManager())
 	}
-	return prechecker.NewPrechecker(c.CreateEventManager())
+	return prechecker.Prechecker{
+		EventManager: c.CreateEventManager(),
+		Clients:      c.httpClients,
+	}
+}
+
+func (c Creator) createRequestTimeout() gin.HandlerFunc {
+	return controller.RequestTimeout(time.Duration(c.config.RequestTimeout.Seconds)*time.Second, c.logger)
+}
+
+func (c Creator) createAPIKeyAuth() gin.HandlerFunc {
+	return controller.APIKeyAuth(c.config.APIKeys, c.config.Environments, c.config.DefaultEnvironment, c.logger)
+}
+
+func (c Creator) createCanonicalEnvironment() gin.HandlerFunc {
+	return controller.CanonicalEnvironment(c.config.Environments)
+}
+
+func (c Creator) createGzip() gin.HandlerFunc {
+	return controller.Gzip(gzipFlushInterval)
 }
 
 func (c Creator) createWriter() io.Writer {
@@ -300,7 +838,7 @@ func (c Creator) createErrorFinder() I.ErrorFinder {
 }
 
 func createCreator(l logging.Level, cfg config.Config, provider CreatorModuleProvider) (Creator, error) {
-	err := ensureCLI()
+	err := ensureCLI(cfg.CFCLI)
 	if err != nil {
 		return Creator{}, err
 	}
@@ -312,20 +850,180 @@ func createCreator(l logging.Level, cfg config.Config, provider CreatorModulePro
 	} else {
 		eventManager = eventmanager.NewEventManager(logger)
 	}
+	if em, ok := eventManager.(*eventmanager.EventManager); ok {
+		em.Environments = cfg.Environments
+	}
+
+	fileSystem := &afero.Afero{Fs: afero.NewOsFs()}
+
+	stagingPrefixes := append([]string{executor.HomeDirectoryPrefix}, artifetcher.StagingPrefixes...)
+	stagingMaxAge := time.Duration(cfg.Staging.MaxAgeHours) * time.Hour
+	err = artifetcher.SweepStagingDirectory(fileSystem, cfg.Staging.Directory, stagingMaxAge, stagingPrefixes, logger)
+	if err != nil {
+		return Creator{}, err
+	}
+	logger.Infof("staging artifacts in %s", cfg.Staging.Directory)
+
+	var artifactCache *artifetcher.ArtifactCache
+	if cfg.ArtifactCache.Enabled {
+		artifactCache, err = artifetcher.NewArtifactCache(fileSystem, cfg.ArtifactCache.Directory, cfg.ArtifactCache.MaxSizeMB*1024*1024)
+		if err != nil {
+			return Creator{}, err
+		}
+		logger.Infof("artifact cache enabled in %s", cfg.ArtifactCache.Directory)
+	}
+
+	var logSink *deploymentlog.Sink
+	if cfg.DeploymentLog.Enabled {
+		maxAge := time.Duration(cfg.DeploymentLog.MaxAgeHours) * time.Hour
+		logSink, err = deploymentlog.NewSink(fileSystem, cfg.DeploymentLog.Directory, cfg.DeploymentLog.MaxFiles, maxAge, logger)
+		if err != nil {
+			return Creator{}, err
+		}
+		logger.Infof("deployment logs enabled in %s", cfg.DeploymentLog.Directory)
+	}
+
+	var breaker *circuitbreaker.Breaker
+	if cfg.Circuit.Enabled {
+		cooldown := time.Duration(cfg.Circuit.CooldownSeconds) * time.Second
+		breaker = circuitbreaker.NewBreaker(cfg.Circuit.FailureThreshold, cooldown, logger)
+		logger.Infof("circuit breaker enabled after %d consecutive failures", cfg.Circuit.FailureThreshold)
+	}
+
+	var signer I.DeploySigner
+	if cfg.EventSigning.Algorithm != "" {
+		signer, err = signing.NewSigner(cfg.EventSigning.Algorithm, cfg.EventSigning.KeyID, cfg.EventSigning.Key)
+		if err != nil {
+			return Creator{}, err
+		}
+		if ed25519Signer, ok := signer.(signing.Ed25519Signer); ok {
+			logger.Infof("signing deploy records with %s key %s, public key %s", cfg.EventSigning.Algorithm, cfg.EventSigning.KeyID, ed25519Signer.PublicKey())
+		} else {
+			logger.Infof("signing deploy records with %s key %s", cfg.EventSigning.Algorithm, cfg.EventSigning.KeyID)
+		}
+	}
+
+	history := deployer.NewHistory(deploymentHistorySize)
+	history.Signer = signer
+	for _, eventType := range deployer.HistoryEvents {
+		eventManager.AddHandler(history, eventType)
+	}
+
+	var historyPruner *deployer.HistoryPruner
+	if cfg.DeploymentHistory.MaxRecordsPerApp > 0 || cfg.DeploymentHistory.MaxAgeHours > 0 {
+		historyPruner = &deployer.HistoryPruner{
+			History: history,
+			Retention: deployer.HistoryRetention{
+				MaxRecordsPerApp: cfg.DeploymentHistory.MaxRecordsPerApp,
+				MaxAge:           time.Duration(cfg.DeploymentHistory.MaxAgeHours) * time.Hour,
+			},
+			Interval: time.Duration(cfg.DeploymentHistory.PruneIntervalMinutes) * time.Minute,
+			Log:      logger,
+		}
+		logger.Infof("pruning the deployment history every %d minutes", cfg.DeploymentHistory.PruneIntervalMinutes)
+	}
 
-	return Creator{
+	cooldowns := deployer.NewCooldowns()
+	eventManager.AddHandler(cooldowns, constants.DeploySuccessEvent)
+
+	if cfg.MaxConcurrentDeployments > 0 {
+		logger.Infof("running at most %d deploys at the same time", cfg.MaxConcurrentDeployments)
+	}
+
+	sloBreaches := deployer.NewSLOBreaches()
+	eventManager.AddHandler(sloBreaches, constants.DeploySLOBreachEvent)
+
+	var kafkaHandler *kafka.Handler
+	if len(cfg.Kafka.Brokers) > 0 {
+		kafkaHandler = &kafka.Handler{
+			Producer:      kafka.NewProducer(cfg.Kafka.Brokers),
+			Topic:         cfg.Kafka.Topic,
+			Retries:       cfg.Kafka.Retries,
+			RetryInterval: time.Duration(cfg.Kafka.RetryIntervalMilliseconds) * time.Millisecond,
+			Log:           logger,
+			Signer:        signer,
+		}
+		for _, eventType := range kafka.Events {
+			eventManager.AddHandler(kafkaHandler, eventType)
+		}
+		logger.Infof("publishing deploy events to kafka topic %s", cfg.Kafka.Topic)
+	}
+
+	var eventRetries *retry.Queue
+	if cfg.EventRetry.Enabled {
+		backoff := time.Duration(cfg.EventRetry.BackoffSeconds) * time.Second
+		eventRetries, err = retry.NewQueue(fileSystem, cfg.EventRetry.Directory, eventManager, cfg.EventRetry.MaxAttempts, backoff, logger)
+		if err != nil {
+			return Creator{}, err
+		}
+		logger.Infof("retrying failed deploy events from %s", cfg.EventRetry.Directory)
+	}
+
+	var journal *push.Journal
+	if cfg.DeployRecovery.Enabled {
+		journal, err = push.NewJournal(fileSystem, cfg.DeployRecovery.Directory, logger)
+		if err != nil {
+			return Creator{}, err
+		}
+		logger.Infof("recording the phases of deploys in %s", cfg.DeployRecovery.Directory)
+	}
+
+	httpClients := httpclient.NewClients(httpclient.Settings{
+		MaxIdleConns:        cfg.HTTPClient.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.HTTPClient.MaxIdleConnsPerHost,
+		IdleConnTimeout:     time.Duration(cfg.HTTPClient.IdleConnTimeoutSeconds) * time.Second,
+		DialTimeout:         time.Duration(cfg.HTTPClient.DialTimeoutSeconds) * time.Second,
+		TLSHandshakeTimeout: time.Duration(cfg.HTTPClient.TLSHandshakeTimeoutSeconds) * time.Second,
+		RequestTimeout:      time.Duration(cfg.HTTPClient.RequestTimeoutSeconds) * time.Second,
+	})
+
+	// The pushes are counted by the controller for their whole request. The silent deploys run in the background,
+	// so the drain gate counts each one while it runs.
+	drainGate := drain.NewGate(logger)
+	silentDeployer := drain.Deployer{
+		Deployer: deployer.SilentDeployer{Client: httpClients.Client(true)},
+		Gate:     drainGate,
+		Log:      I.DeploymentLogger{Log: logger},
+	}
+	silentPool := deployer.NewSilentDeployPool(silentDeployer, cfg.SilentDeploy.PoolSize, eventManager, logger)
+
+	creator := Creator{
 		cfg,
 		eventManager,
 		logger,
 		os.Stdout,
-		&afero.Afero{Fs: afero.NewOsFs()},
+		fileSystem,
 		provider,
-	}, nil
+		artifactCache,
+		logSink,
+		breaker,
+		silentPool,
+		drainGate,
+		limiter.NewLimiter(cfg.MaxConcurrentDeployments),
+		push.NewPromotions(),
+		nil,
+		deployer.NewCancellations(),
+		history,
+		historyPruner,
+		cooldowns,
+		sloBreaches,
+		kafkaHandler,
+		eventRetries,
+		journal,
+		httpClients,
+		tracing.NewTracer(),
+		signer,
+	}
+	creator.retirements = push.NewRetirements(creator, logger)
+
+	return creator, nil
 
 }
 
-func ensureCLI() error {
-	_, err := exec.LookPath("cf")
+// ensureCLI fails when the Cloud Foundry CLI cannot be run or is older than its minimum version, so Deployadactyl
+// does not start without a CLI it can deploy with.
+func ensureCLI(cli config.CFCLIConfig) error {
+	_, err := executor.CheckVersion(cli.Path, cli.MinVersion)
 	return err
 }
 
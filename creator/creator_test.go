@@ -3,6 +3,8 @@ package creator
 import (
 	"os"
 
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"runtime"
@@ -57,4 +59,16 @@ var _ = Describe("Custom creator", func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(Equal("missing environment variables: CF_USERNAME, CF_PASSWORD"))
 	})
+
+	It("fails when the cf cli is older than its minimum version", func() {
+		err := ensureCLI(config.CFCLIConfig{Path: "cf", MinVersion: "99.0.0"})
+
+		Expect(err).To(BeAssignableToTypeOf(executor.CLIVersionTooOldError{}))
+	})
+
+	It("fails when the cf cli cannot be found", func() {
+		err := ensureCLI(config.CFCLIConfig{Path: "/does/not/exist/cf"})
+
+		Expect(err).To(BeAssignableToTypeOf(executor.CLINotFoundError{}))
+	})
 })
@@ -0,0 +1,30 @@
+package deploymentlog
+
+import "fmt"
+
+type CreateDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e CreateDirectoryError) Error() string {
+	return fmt.Sprintf("cannot create deployment log directory %s: %s", e.Directory, e.Err)
+}
+
+type CreateFileError struct {
+	Path string
+	Err  error
+}
+
+func (e CreateFileError) Error() string {
+	return fmt.Sprintf("cannot create deployment log %s: %s", e.Path, e.Err)
+}
+
+type WriteError struct {
+	Path string
+	Err  error
+}
+
+func (e WriteError) Error() string {
+	return fmt.Sprintf("cannot write deployment log %s: %s", e.Path, e.Err)
+}
@@ -0,0 +1,185 @@
+// Package deploymentlog persists the output of each deployment to a file named by its UUID.
+package deploymentlog
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/spf13/afero"
+)
+
+const fileExtension = ".log"
+
+// Sink creates deployment log files in Directory. Files beyond the newest MaxFiles or older
+// than MaxAge are removed whenever a new file is opened. A zero MaxFiles or MaxAge disables
+// that limit.
+type Sink struct {
+	FileSystem *afero.Afero
+	Directory  string
+	MaxFiles   int
+	MaxAge     time.Duration
+	Log        I.Logger
+}
+
+// NewSink returns a Sink that writes to directory, creating it if needed.
+func NewSink(fs *afero.Afero, directory string, maxFiles int, maxAge time.Duration, log I.Logger) (*Sink, error) {
+	err := fs.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, CreateDirectoryError{directory, err}
+	}
+
+	return &Sink{
+		FileSystem: fs,
+		Directory:  directory,
+		MaxFiles:   maxFiles,
+		MaxAge:     maxAge,
+		Log:        log,
+	}, nil
+}
+
+// FilePath returns the path of the log file for uuid in directory.
+func FilePath(directory, uuid string) string {
+	return path.Join(directory, uuid+fileExtension)
+}
+
+// Open creates the log file for uuid. If the file cannot be created the error is logged and
+// the returned File discards everything written to it.
+func (s *Sink) Open(uuid string) *File {
+	filePath := FilePath(s.Directory, uuid)
+
+	file, err := s.FileSystem.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		s.Log.Errorf("%s", CreateFileError{filePath, err})
+		return &File{log: s.Log, failed: true}
+	}
+
+	s.prune(filePath)
+
+	return &File{file: file, log: s.Log}
+}
+
+func (s *Sink) prune(current string) {
+	infos, err := s.FileSystem.ReadDir(s.Directory)
+	if err != nil {
+		s.Log.Errorf("cannot read deployment log directory %s: %s", s.Directory, err)
+		return
+	}
+
+	var files []os.FileInfo
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), fileExtension) && path.Join(s.Directory, info.Name()) != current {
+			files = append(files, info)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+
+	for i, info := range files {
+		expired := s.MaxAge > 0 && time.Since(info.ModTime()) > s.MaxAge
+		overLimit := s.MaxFiles > 0 && i+1 >= s.MaxFiles
+
+		if expired || overLimit {
+			err = s.FileSystem.Remove(path.Join(s.Directory, info.Name()))
+			if err != nil {
+				s.Log.Errorf("cannot remove deployment log %s: %s", info.Name(), err)
+			}
+		}
+	}
+}
+
+// File is a deployment log file. Writes never fail. The first write error is logged and
+// nothing more is written to the file.
+type File struct {
+	file   afero.File
+	log    I.Logger
+	mutex  sync.Mutex
+	failed bool
+}
+
+// Write writes p to the file and always reports success.
+func (f *File) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.failed {
+		return len(p), nil
+	}
+
+	_, err := f.file.Write(p)
+	if err != nil {
+		f.failed = true
+		f.log.Errorf("%s", WriteError{f.file.Name(), err})
+	}
+
+	return len(p), nil
+}
+
+// Logger returns a Logger that logs to log and also writes each message to the file.
+func (f *File) Logger(log I.Logger) I.Logger {
+	return fileLogger{Log: log, File: f}
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+type fileLogger struct {
+	Log  I.Logger
+	File *File
+}
+
+func (l fileLogger) write(level string, message string) {
+	fmt.Fprintf(l.File, "%s %s ▶ %s\n", time.Now().Format("2006/01/02 15:04:05"), level, message)
+}
+
+// sprint joins args with spaces the same way the go-logging Logger does.
+func sprint(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+func (l fileLogger) Error(args ...interface{}) {
+	l.Log.Error(args...)
+	l.write("ERRO", sprint(args...))
+}
+
+func (l fileLogger) Errorf(str string, args ...interface{}) {
+	l.Log.Errorf(str, args...)
+	l.write("ERRO", fmt.Sprintf(str, args...))
+}
+
+func (l fileLogger) Debug(args ...interface{}) {
+	l.Log.Debug(args...)
+	l.write("DEBU", sprint(args...))
+}
+
+func (l fileLogger) Debugf(str string, args ...interface{}) {
+	l.Log.Debugf(str, args...)
+	l.write("DEBU", fmt.Sprintf(str, args...))
+}
+
+func (l fileLogger) Info(args ...interface{}) {
+	l.Log.Info(args...)
+	l.write("INFO", sprint(args...))
+}
+
+func (l fileLogger) Infof(str string, args ...interface{}) {
+	l.Log.Infof(str, args...)
+	l.write("INFO", fmt.Sprintf(str, args...))
+}
+
+func (l fileLogger) Fatal(args ...interface{}) {
+	l.write("CRIT", sprint(args...))
+	l.Log.Fatal(args...)
+}
@@ -0,0 +1,146 @@
+package deploymentlog_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+
+	. "github.com/compozed/deployadactyl/deploymentlog"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/randomizer"
+)
+
+type failingFs struct {
+	afero.Fs
+}
+
+func (f failingFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	file, err := f.Fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return failingFile{file}, nil
+}
+
+type failingFile struct {
+	afero.File
+}
+
+func (f failingFile) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+var _ = Describe("Sink", func() {
+	var (
+		af        *afero.Afero
+		sink      *Sink
+		logBuffer *Buffer
+		log       I.Logger
+		uuid      string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		logBuffer = NewBuffer()
+		log = I.DefaultLogger(logBuffer, logging.DEBUG, "deploymentlog_test")
+		uuid = randomizer.StringRunes(10)
+
+		sink, err = NewSink(af, "/logs", 3, 0, log)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("creates the directory", func() {
+		exists, err := af.DirExists("/logs")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeTrue())
+	})
+
+	It("writes output to a file named by the uuid", func() {
+		file := sink.Open(uuid)
+		file.Write([]byte("deploy output"))
+		Expect(file.Close()).To(Succeed())
+
+		contents, err := af.ReadFile("/logs/" + uuid + ".log")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal("deploy output"))
+	})
+
+	It("writes log messages to the file and the logger", func() {
+		file := sink.Open(uuid)
+		I.DeploymentLogger{Log: file.Logger(log), UUID: uuid}.Infof("pushing %s", "myApp")
+		file.Close()
+
+		contents, _ := af.ReadFile("/logs/" + uuid + ".log")
+		Expect(string(contents)).To(MatchRegexp(`INFO ▶ ` + uuid + ` pushing myApp\n$`))
+		Eventually(logBuffer).Should(Say(uuid + " pushing myApp"))
+	})
+
+	It("keeps only the newest files", func() {
+		for i := 0; i < 3; i++ {
+			name := "/logs/old-" + randomizer.StringRunes(5) + ".log"
+			af.WriteFile(name, []byte("old"), 0644)
+			af.Chtimes(name, time.Now(), time.Now().Add(-time.Duration(i+1)*time.Hour))
+		}
+
+		sink.Open(uuid).Close()
+
+		infos, err := af.ReadDir("/logs")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(infos).To(HaveLen(3))
+
+		exists, _ := af.Exists("/logs/" + uuid + ".log")
+		Expect(exists).To(BeTrue())
+	})
+
+	It("removes files older than the max age", func() {
+		sink.MaxFiles = 0
+		sink.MaxAge = time.Hour
+
+		af.WriteFile("/logs/recent.log", []byte("recent"), 0644)
+		af.WriteFile("/logs/expired.log", []byte("expired"), 0644)
+		af.Chtimes("/logs/expired.log", time.Now(), time.Now().Add(-2*time.Hour))
+
+		sink.Open(uuid).Close()
+
+		Expect(af.Exists("/logs/recent.log")).To(BeTrue())
+		Expect(af.Exists("/logs/expired.log")).To(BeFalse())
+	})
+
+	Context("when the file cannot be written", func() {
+		It("logs the error and does not fail", func() {
+			sink.FileSystem = &afero.Afero{Fs: failingFs{af.Fs}}
+
+			file := sink.Open(uuid)
+
+			n, err := file.Write([]byte("deploy output"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(len("deploy output")))
+
+			file.Write([]byte("more output"))
+
+			Eventually(logBuffer).Should(Say("cannot write deployment log /logs/" + uuid + ".log: disk full"))
+			Consistently(logBuffer).ShouldNot(Say("cannot write deployment log"))
+		})
+	})
+
+	Context("when the file cannot be created", func() {
+		It("logs the error and discards the output", func() {
+			sink.FileSystem = &afero.Afero{Fs: afero.NewReadOnlyFs(af.Fs)}
+
+			file := sink.Open(uuid)
+
+			_, err := file.Write([]byte("deploy output"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+			Eventually(logBuffer).Should(Say("cannot create deployment log"))
+		})
+	})
+})
@@ -0,0 +1,142 @@
+// Package diagnostics checks the dependencies of Deployadactyl so on-call can see what is degraded without
+// attempting a deploy.
+package diagnostics
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/httpclient"
+)
+
+// Kinds of dependencies.
+const (
+	FoundationDependency        = "foundation"
+	ValidationWebhookDependency = "validation_webhook"
+)
+
+// Statuses of a dependency and of a Report.
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+)
+
+// DefaultTimeout is how long a dependency has to respond when the Checker has no Timeout.
+const DefaultTimeout = 10 * time.Second
+
+// Dependency is something a deploy needs to reach.
+// The API of a foundation is up when its /v2/info answers 200 OK; a webhook is up when it answers at all.
+type Dependency struct {
+	Kind        string
+	Environment string
+	URL         string
+	SkipSSL     bool
+}
+
+// Result is the status of a Dependency and how long it took to answer.
+type Result struct {
+	Kind        string `json:"kind"`
+	Environment string `json:"environment,omitempty"`
+	URL         string `json:"url"`
+	Status      string `json:"status"`
+	LatencyMS   int64  `json:"latency_ms"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Report is the result of checking every Dependency. Its Status is degraded when any of them is down.
+type Report struct {
+	Status       string   `json:"status"`
+	Dependencies []Result `json:"dependencies"`
+}
+
+// Checker checks its Dependencies at the same time.
+type Checker struct {
+	Dependencies []Dependency
+	Timeout      time.Duration
+
+	// Clients are the shared HTTP clients. A client is created for each check when it is nil.
+	Clients *httpclient.Clients
+}
+
+// Check checks every Dependency and returns their results in the order of the Dependencies.
+func (c *Checker) Check() Report {
+	report := Report{Status: StatusOK, Dependencies: []Result{}}
+	if c == nil {
+		return report
+	}
+
+	results := make([]Result, len(c.Dependencies))
+
+	wg := sync.WaitGroup{}
+	for i, dependency := range c.Dependencies {
+		wg.Add(1)
+		go func(i int, dependency Dependency) {
+			defer wg.Done()
+			results[i] = c.check(dependency)
+		}(i, dependency)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Status == StatusDown {
+			report.Status = StatusDegraded
+		}
+	}
+	report.Dependencies = results
+
+	return report
+}
+
+func (c *Checker) check(dependency Dependency) Result {
+	result := Result{
+		Kind:        dependency.Kind,
+		Environment: dependency.Environment,
+		URL:         dependency.URL,
+		Status:      StatusUp,
+	}
+
+	url := dependency.URL
+	if dependency.Kind == FoundationDependency {
+		url = fmt.Sprintf("%s/v2/info", dependency.URL)
+	}
+
+	start := time.Now()
+	resp, err := c.client(dependency.SkipSSL).Get(url)
+	result.LatencyMS = int64(time.Since(start) / time.Millisecond)
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+
+	if dependency.Kind == FoundationDependency && resp.StatusCode != http.StatusOK {
+		result.Status = StatusDown
+		result.Error = fmt.Sprintf("unexpected response %s", resp.Status)
+	}
+
+	return result
+}
+
+func (c *Checker) client(skipSSL bool) *http.Client {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	if c.Clients == nil {
+		return &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: skipSSL}},
+		}
+	}
+
+	client := *c.Clients.Client(skipSSL)
+	client.Timeout = timeout
+	return &client
+}
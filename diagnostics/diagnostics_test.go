@@ -0,0 +1,86 @@
+package diagnostics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/compozed/deployadactyl/diagnostics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		foundation *httptest.Server
+		webhook    *httptest.Server
+		status     int
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+
+		foundation = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v2/info" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(status)
+		}))
+
+		webhook = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}))
+	})
+
+	AfterEach(func() {
+		foundation.Close()
+		webhook.Close()
+	})
+
+	It("reports every dependency as up", func() {
+		checker := &Checker{Dependencies: []Dependency{
+			{Kind: FoundationDependency, Environment: "production", URL: foundation.URL},
+			{Kind: ValidationWebhookDependency, URL: webhook.URL},
+		}}
+
+		report := checker.Check()
+
+		Expect(report.Status).To(Equal(StatusOK))
+		Expect(report.Dependencies).To(HaveLen(2))
+		Expect(report.Dependencies[0].Kind).To(Equal(FoundationDependency))
+		Expect(report.Dependencies[0].Environment).To(Equal("production"))
+		Expect(report.Dependencies[0].Status).To(Equal(StatusUp))
+		Expect(report.Dependencies[0].LatencyMS).To(BeNumerically(">=", 0))
+		Expect(report.Dependencies[1].Kind).To(Equal(ValidationWebhookDependency))
+		Expect(report.Dependencies[1].Status).To(Equal(StatusUp))
+	})
+
+	It("reports a foundation that does not answer 200 OK as down", func() {
+		status = http.StatusServiceUnavailable
+		checker := &Checker{Dependencies: []Dependency{{Kind: FoundationDependency, URL: foundation.URL}}}
+
+		report := checker.Check()
+
+		Expect(report.Status).To(Equal(StatusDegraded))
+		Expect(report.Dependencies[0].Status).To(Equal(StatusDown))
+		Expect(report.Dependencies[0].Error).To(Equal("unexpected response 503 Service Unavailable"))
+	})
+
+	It("reports a dependency that cannot be reached as down", func() {
+		webhook.Close()
+		checker := &Checker{Dependencies: []Dependency{{Kind: ValidationWebhookDependency, URL: webhook.URL}}}
+
+		report := checker.Check()
+
+		Expect(report.Status).To(Equal(StatusDegraded))
+		Expect(report.Dependencies[0].Status).To(Equal(StatusDown))
+		Expect(report.Dependencies[0].Error).ToNot(BeEmpty())
+	})
+
+	It("reports no dependencies when the checker is nil", func() {
+		var checker *Checker
+
+		Expect(checker.Check()).To(Equal(Report{Status: StatusOK, Dependencies: []Result{}}))
+	})
+})
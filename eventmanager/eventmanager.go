@@ -2,7 +2,10 @@
 package eventmanager
 
 import (
+	"reflect"
+
 	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
 	"github.com/go-errors/errors"
 )
 
@@ -20,6 +23,15 @@ type EventManagerConstructor func(log I.Logger) I.EventManager
 type EventManager struct {
 	Bindings []I.Binding
 	Log      I.Logger
+
+	// Environments decide which named bindings are emitted the events of their deploys.
+	Environments map[string]S.Environment
+}
+
+// namedBinding is a binding of an event handler that environments turn on by its name.
+type namedBinding struct {
+	I.Binding
+	name string
 }
 
 type legacyEventBinding struct {
@@ -72,9 +84,15 @@ func (e *EventManager) AddBinding(binding I.Binding) {
 	e.Bindings = append(e.Bindings, binding)
 }
 
+// AddNamedBinding adds the binding of the event handler with the name. It is only emitted the events of deploys
+// to environments whose event handlers include the name, or that do not set their event handlers.
+func (e *EventManager) AddNamedBinding(name string, binding I.Binding) {
+	e.Bindings = append(e.Bindings, namedBinding{binding, name})
+}
+
 func (e EventManager) EmitEvent(event I.IEvent) error {
 	for _, binding := range e.Bindings {
-		if binding.Accepts(event) {
+		if binding.Accepts(event) && e.handles(binding, event) {
 			err := binding.Emit(event)
 			if err != nil {
 				return err
@@ -83,3 +101,63 @@ func (e EventManager) EmitEvent(event I.IEvent) error {
 	}
 	return nil
 }
+
+// handles reports whether the binding is turned on in the environment of the event. Only named bindings can be
+// turned off, and they are on for an event whose environment is not known.
+func (e EventManager) handles(binding I.Binding, event interface{}) bool {
+	named, ok := binding.(namedBinding)
+	if !ok {
+		return true
+	}
+
+	environment, ok := e.Environments[eventEnvironment(event)]
+	if !ok || environment.EventHandlers == nil {
+		return true
+	}
+
+	for _, handler := range environment.EventHandlers {
+		if handler == named.name {
+			return true
+		}
+	}
+
+	e.Log.Debugf("skipping the %s handler in environment %s", named.name, environment.Name)
+	return false
+}
+
+// eventEnvironment returns the environment of the deploy of an event, from the deployment info of a legacy event
+// or the CFContext of any other event.
+func eventEnvironment(event interface{}) string {
+	if legacy, ok := event.(I.Event); ok {
+		switch data := legacy.Data.(type) {
+		case *S.DeployEventData:
+			if data.DeploymentInfo != nil {
+				return data.DeploymentInfo.Environment
+			}
+		case S.DeployEventData:
+			if data.DeploymentInfo != nil {
+				return data.DeploymentInfo.Environment
+			}
+		case S.PushEventData:
+			if data.DeploymentInfo != nil {
+				return data.DeploymentInfo.Environment
+			}
+		default:
+			return eventEnvironment(legacy.Data)
+		}
+		return ""
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(event))
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+
+	field := value.FieldByName("CFContext")
+	if !field.IsValid() || !field.CanInterface() {
+		return ""
+	}
+
+	cfContext, _ := field.Interface().(I.CFContext)
+	return cfContext.Environment
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
 	"github.com/compozed/deployadactyl/state/stop"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 var _ = Describe("Events", func() {
@@ -162,4 +163,68 @@ var _ = Describe("Events", func() {
 		})
 
 	})
+
+	Context("when a binding is named", func() {
+		var (
+			binding      *mocks.EventBinding
+			eventManager *EventManager
+		)
+
+		BeforeEach(func() {
+			binding = &mocks.EventBinding{}
+			binding.AcceptsCall.Returns.Bool = true
+
+			eventManager = &EventManager{
+				Log: log,
+				Environments: map[string]S.Environment{
+					"prod": {Name: "prod", EventHandlers: []string{S.EventHandlerHealthCheck}},
+					"dev":  {Name: "dev", EventHandlers: []string{S.EventHandlerEnvVar}},
+					"test": {Name: "test"},
+				},
+			}
+		})
+
+		It("emits events of environments that turn the handler on", func() {
+			eventManager.AddNamedBinding(S.EventHandlerHealthCheck, binding)
+
+			event := stop.StopStartedEvent{CFContext: I.CFContext{Environment: "prod"}}
+			Expect(eventManager.EmitEvent(event)).To(Succeed())
+
+			Expect(binding.EmitCall.Received.Event).To(Equal(event))
+		})
+
+		It("does not emit events of environments that do not turn the handler on", func() {
+			eventManager.AddNamedBinding(S.EventHandlerHealthCheck, binding)
+
+			Expect(eventManager.EmitEvent(stop.StopStartedEvent{CFContext: I.CFContext{Environment: "dev"}})).To(Succeed())
+
+			Expect(binding.EmitCall.Called.Bool).To(BeFalse())
+			Eventually(logBuffer).Should(gbytes.Say("skipping the healthcheck handler in environment dev"))
+		})
+
+		It("emits events of environments that do not set their event handlers", func() {
+			eventManager.AddNamedBinding(S.EventHandlerHealthCheck, binding)
+
+			Expect(eventManager.EmitEvent(stop.StopStartedEvent{CFContext: I.CFContext{Environment: "test"}})).To(Succeed())
+
+			Expect(binding.EmitCall.Called.Bool).To(BeTrue())
+		})
+
+		It("uses the environment of the deployment info of a legacy event", func() {
+			eventManager.AddNamedBinding(S.EventHandlerEnvVar, binding)
+
+			event := I.Event{Type: eventType, Data: &S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{Environment: "prod"}}}
+			Expect(eventManager.EmitEvent(event)).To(Succeed())
+
+			Expect(binding.EmitCall.Called.Bool).To(BeFalse())
+		})
+
+		It("always emits to bindings that are not named", func() {
+			eventManager.AddBinding(binding)
+
+			Expect(eventManager.EmitEvent(stop.StopStartedEvent{CFContext: I.CFContext{Environment: "dev"}})).To(Succeed())
+
+			Expect(binding.EmitCall.Called.Bool).To(BeTrue())
+		})
+	})
 })
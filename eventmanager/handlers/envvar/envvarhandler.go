@@ -13,7 +13,8 @@ type Envvarhandler struct {
 
 func (handler Envvarhandler) ArtifactRetrievalSuccessEventHandler(event push.ArtifactRetrievalSuccessEvent) error {
 
-	event.Log.Debugf("Environment Variable Handler Processing Event => %+v", event)
+	// The event is not logged as it is: it holds the credentials and the values of the environment variables.
+	event.Log.Debugf("Environment Variable Handler Processing Event for %s", event.CFContext.Application)
 
 	if event.EnvironmentVariables == nil || len(event.EnvironmentVariables) == 0 {
 		event.Log.Info("No Deployment Info or Environment Variables to process!")
@@ -23,6 +23,47 @@ health check failed:
 	)
 }
 
+type AbortedError struct {
+	Err error
+}
+
+func (e AbortedError) Error() string {
+	return fmt.Sprintf("health check aborted because a health check on another foundation failed, after: %s", e.Err)
+}
+
+type InstancesUnhealthyError struct {
+	Unhealthy int
+	Results   []Result
+}
+
+func (e InstancesUnhealthyError) Error() string {
+	message := fmt.Sprintf("health check failed on %d of %d instances:", e.Unhealthy, len(e.Results))
+	for _, result := range e.Results {
+		message += fmt.Sprintf("\n  %s", result)
+	}
+
+	return message
+}
+
+type UnstableError struct {
+	Successes int
+	Polls     int
+	Err       error
+}
+
+func (e UnstableError) Error() string {
+	return fmt.Sprintf("health check was not stable: %d of %d consecutive healthy polls before %s", e.Successes, e.Polls, e.Err)
+}
+
+type ComparisonError struct {
+	AppName string
+	Reason  string
+}
+
+func (e ComparisonError) Error() string {
+	return fmt.Sprintf("health check failed: the new application is less healthy than %s: %s", e.AppName, e.Reason)
+}
+
 type MapRouteError struct {
 	AppName string
 	Domain  string
@@ -46,7 +87,7 @@ type ClientError struct {
 }
 
 func (e ClientError) Error() string {
-	return fmt.Sprintf("could not perform GET request: %s", e.Err.Error())
+	return fmt.Sprintf("health check endpoint could not be reached: could not perform GET request: %s", e.Err.Error())
 }
 
 type LoginError struct {
@@ -2,15 +2,92 @@ package healthchecker
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	C "github.com/compozed/deployadactyl/constants"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
+// BaselineHostPrefix is prepended to the temporary application name to build the temporary route of the application
+// replaced by a deploy, when the health of the new application is compared with it.
+const BaselineHostPrefix = "baseline-"
+
+// InstanceHeader routes a request to a single instance of an application, as "guid:index".
+const InstanceHeader = "X-CF-APP-INSTANCE"
+
+// maxBodySize is the most of the body of a health check response that is read. A body that is read to its end lets
+// the connection go back to the pool of the client. A longer body closes the connection instead.
+const maxBodySize = 64 * 1024
+
+// Result is the outcome of checking the health check endpoint of an application.
+type Result struct {
+	// Instance is the index of the instance that was checked, or -1 when the check went through the route of the application.
+	Instance   int
+	URL        string
+	StatusCode int
+	Latency    time.Duration
+	Err        error
+}
+
+func (r Result) String() string {
+	target := r.URL
+	if r.Instance >= 0 {
+		target = fmt.Sprintf("instance %d %s", r.Instance, r.URL)
+	}
+
+	latency := r.Latency.Round(time.Millisecond)
+	if clientErr, ok := r.Err.(ClientError); ok {
+		return fmt.Sprintf("%s: unreachable after %s: %s", target, latency, clientErr.Err)
+	}
+
+	return fmt.Sprintf("%s: %d in %s", target, r.StatusCode, latency)
+}
+
+// writeResults writes the result of every health check to the response, and how many instances were healthy
+// when they were checked on their own.
+func writeResults(response io.Writer, results []Result, minHealthyPercent int) {
+	fmt.Fprintln(response, "Health check results:")
+	healthy := 0
+	for _, result := range results {
+		fmt.Fprintf(response, "  %s\n", result)
+		if result.Err == nil {
+			healthy++
+		}
+	}
+
+	if len(results) > 1 {
+		fmt.Fprintf(response, "Healthy instances: %d of %d, at least %d%% required\n", healthy, len(results), healthyPercent(minHealthyPercent))
+	}
+}
+
+// healthyPercent returns the minimum healthy percent of an environment, or 100 when it is not between 1 and 100.
+func healthyPercent(minHealthyPercent int) int {
+	if minHealthyPercent < 1 || minHealthyPercent > 100 {
+		return 100
+	}
+	return minHealthyPercent
+}
+
+// Policy controls how many times a failed health check is retried and how long to wait between attempts.
+type Policy struct {
+	Retries       int
+	RetryInterval time.Duration
+}
+
+// URLPrefixes are the OldURL and NewURL of an environment.
+type URLPrefixes struct {
+	OldURL string
+	NewURL string
+}
+
 // HealthChecker will check an endpoint for a http.StatusOK
 type HealthChecker struct {
 	// OldURL is the prepend on the foundationURL to replace in order to build the
@@ -22,12 +99,33 @@ type HealthChecker struct {
 	// Eg: "cfapps"
 	NewURL string
 
+	// EnvironmentURLPrefixes replace OldURL and NewURL for the environments they name.
+	EnvironmentURLPrefixes map[string]URLPrefixes
+
 	//SilentDeployURL represents any other url that doesn't match cfapps
 	SilentDeployURL         string
 	SilentDeployEnvironment string
 
+	// UnreachablePolicy is used when the endpoint could not be reached, eg: a network, DNS or timeout error.
+	UnreachablePolicy Policy
+
+	// UnhealthyPolicy is used when the endpoint was reached but did not return http.StatusOK.
+	UnhealthyPolicy Policy
+
+	// Sleep waits between retries. Defaults to time.Sleep.
+	Sleep func(time.Duration)
+
+	// EventManager emits a deploy warning when a health check fails in the warn health check mode.
+	EventManager I.EventManager
+
 	Client  I.Client
 	Courier I.Courier
+
+	// InsecureClient skips TLS verification. It is used instead of Client for environments with skip_ssl.
+	InsecureClient I.Client
+
+	// abort is the Abort of the event being handled. Retries and stabilization polls stop once it is closed.
+	abort <-chan struct{}
 }
 
 func (h HealthChecker) PushFinishedEventHandler(event push.PushFinishedEvent) error {
@@ -41,19 +139,29 @@ func (h HealthChecker) PushFinishedEventHandler(event push.PushFinishedEvent) er
 		return nil
 	}
 
+	if event.HealthCheckMode == S.HealthCheckOff {
+		event.Log.Infof("skipping the health check of %s because the health check mode is %s", event.TempAppWithUUID, S.HealthCheckOff)
+		return nil
+	}
+
 	h.Courier = event.Courier
+	h.abort = event.Abort
+	if event.SkipSSL && h.InsecureClient != nil {
+		h.Client = h.InsecureClient
+	}
 
 	event.Log.Debugf("starting health check")
 
+	prefixes := h.urlPrefixes(event.CFContext.Environment)
 	if event.CFContext.Environment != h.SilentDeployEnvironment {
-		newFoundationURL = strings.Replace(event.FoundationURL, h.OldURL, h.NewURL, 1)
-		domain = regexp.MustCompile(fmt.Sprintf("%s.*", h.NewURL)).FindString(newFoundationURL)
+		newFoundationURL = strings.Replace(event.FoundationURL, prefixes.OldURL, prefixes.NewURL, 1)
+		domain = regexp.MustCompile(fmt.Sprintf("%s.*", regexp.QuoteMeta(prefixes.NewURL))).FindString(newFoundationURL)
 	} else {
-		newFoundationURL = strings.Replace(event.FoundationURL, h.OldURL, h.SilentDeployURL, 1)
+		newFoundationURL = strings.Replace(event.FoundationURL, prefixes.OldURL, h.SilentDeployURL, 1)
 		domain = regexp.MustCompile(fmt.Sprintf("%s.*", h.SilentDeployURL)).FindString(newFoundationURL)
 	}
 
-	err := h.mapTemporaryRoute(event.TempAppWithUUID, domain, event.Log)
+	err := h.mapTemporaryRoute(event.TempAppWithUUID, event.TempAppWithUUID, domain, event.Log)
 	if err != nil {
 		return err
 	}
@@ -62,43 +170,351 @@ func (h HealthChecker) PushFinishedEventHandler(event push.PushFinishedEvent) er
 	defer h.deleteTemporaryRoute(event.TempAppWithUUID, domain, event.Log)
 	defer h.unmapTemporaryRoute(event.TempAppWithUUID, domain, event.Log)
 
-	newFoundationURL = strings.Replace(newFoundationURL, h.NewURL, fmt.Sprintf("%s.%s", event.TempAppWithUUID, h.NewURL), 1)
+	appsURL := newFoundationURL
+	newFoundationURL = strings.Replace(newFoundationURL, prefixes.NewURL, fmt.Sprintf("%s.%s", event.TempAppWithUUID, prefixes.NewURL), 1)
 
-	return h.Check(newFoundationURL, event.HealthCheckEndpoint, event.Log)
+	results, err := h.checkInstances(event.TempAppWithUUID, newFoundationURL, event.HealthCheckEndpoint, event.MinHealthyPercent, event.Log)
+	if event.Response != nil {
+		writeResults(event.Response, results, event.MinHealthyPercent)
+	}
+
+	if err == nil && event.HealthCheckStablePolls > 1 {
+		period := time.Duration(event.HealthCheckStableSeconds) * time.Second
+		var successes int
+		successes, err = h.stabilize(newFoundationURL, event.HealthCheckEndpoint, event.HealthCheckStablePolls, period, event.Log)
+		if event.Response != nil {
+			fmt.Fprintf(event.Response, "Health check stabilization: %d of %d consecutive healthy polls\n", successes, event.HealthCheckStablePolls)
+		}
+	}
+
+	if err == nil && event.HealthCheckCompare != "" && event.HealthCheckCompare != S.HealthCheckCompareOff {
+		err = h.compare(event, results, strings.Replace(appsURL, prefixes.NewURL, fmt.Sprintf("%s%s.%s", BaselineHostPrefix, event.TempAppWithUUID, prefixes.NewURL), 1), domain)
+	}
+
+	if err != nil && event.HealthCheckMode == S.HealthCheckWarn {
+		return h.warn(event, err)
+	}
+
+	return err
+}
+
+// urlPrefixes returns the URL prefixes of the environment, or OldURL and NewURL when it has none.
+func (h HealthChecker) urlPrefixes(environment string) URLPrefixes {
+	if prefixes, ok := h.EnvironmentURLPrefixes[environment]; ok {
+		return prefixes
+	}
+	return URLPrefixes{h.OldURL, h.NewURL}
+}
+
+// warn reports a failed health check in the response and emits a deploy warning instead of failing the deploy.
+func (h HealthChecker) warn(event push.PushFinishedEvent, err error) error {
+	warning := fmt.Sprintf("health check of %s failed: %s", event.TempAppWithUUID, err)
+	event.Log.Errorf("%s, continuing because the health check mode is %s", warning, S.HealthCheckWarn)
+
+	if event.Response != nil {
+		fmt.Fprintf(event.Response, "WARNING: %s\n", warning)
+	}
+
+	if h.EventManager == nil {
+		return nil
+	}
+
+	warningEvent := push.DeployWarningEvent{
+		CFContext:       event.CFContext,
+		Auth:            event.Auth,
+		Response:        event.Response,
+		FoundationURL:   event.FoundationURL,
+		TempAppWithUUID: event.TempAppWithUUID,
+		Warning:         warning,
+		Data:            event.Data,
+		Log:             event.Log,
+	}
+
+	event.Log.Debugf("emitting a %s event", C.DeployWarningEvent)
+	err = h.EventManager.Emit(I.Event{Type: C.DeployWarningEvent, Data: warningEvent})
+	if err != nil {
+		return err
+	}
+
+	return h.EventManager.EmitEvent(warningEvent)
 }
 
 // Check takes a url and endpoint. It does an http.Get to get the response
 // status and returns an error if it is not http.StatusOK.
+//
+// A ClientError is returned when the endpoint could not be reached and a
+// HealthCheckError when it responded with an unhealthy status. Each kind of
+// failure is retried according to its own Policy.
 func (h HealthChecker) Check(url, endpoint string, log I.DeploymentLogger) error {
+	_, err := h.check(url, endpoint, "", log)
+	return err
+}
+
+// checkInstances checks every instance of the application on its own when it has more than one instance,
+// so a single bad instance can be told apart from a failure of every instance. Otherwise the endpoint is
+// checked through the route of the application.
+//
+// Returns an InstancesUnhealthyError with the result of every instance when fewer than minHealthyPercent of them
+// are healthy. Every instance must be healthy when minHealthyPercent is zero.
+func (h HealthChecker) checkInstances(appName, url, endpoint string, minHealthyPercent int, log I.DeploymentLogger) ([]Result, error) {
+	states, err := h.Courier.InstanceStates(appName)
+	if err != nil || len(states) < 2 {
+		result, err := h.check(url, endpoint, "", log)
+		return []Result{result}, err
+	}
+
+	guid, err := h.Courier.AppGUID(appName)
+	if err != nil {
+		log.Errorf("could not get the guid of %s, checking it through its route: %s", appName, err)
+		result, err := h.check(url, endpoint, "", log)
+		return []Result{result}, err
+	}
+
+	log.Debugf("checking each of the %d instances of %s", len(states), appName)
+
+	var (
+		results   []Result
+		unhealthy int
+	)
+	for i := range states {
+		result, err := h.check(url, endpoint, fmt.Sprintf("%s:%d", guid, i), log)
+		result.Instance = i
+		results = append(results, result)
+
+		if err != nil {
+			unhealthy++
+		}
+	}
+
+	minHealthyPercent = healthyPercent(minHealthyPercent)
+	required := (len(states)*minHealthyPercent + 99) / 100
+	healthy := len(states) - unhealthy
+
+	if healthy < required {
+		return results, InstancesUnhealthyError{unhealthy, results}
+	}
+	if unhealthy > 0 {
+		log.Infof("%d of %d instances of %s are healthy, at least %d%% are required", healthy, len(states), appName, minHealthyPercent)
+	}
+
+	return results, nil
+}
+
+// compare health checks the application replaced by the deploy through a temporary route of its own, and returns
+// a ComparisonError when the new application is less healthy according to the health check comparison of the
+// event. Nothing is compared on the first deploy of an application.
+func (h HealthChecker) compare(event push.PushFinishedEvent, results []Result, url, domain string) error {
+	appName := event.CFContext.Application
+	if !h.Courier.Exists(appName) {
+		event.Log.Infof("not comparing the health check of %s because %s does not exist", event.TempAppWithUUID, appName)
+		return nil
+	}
+
+	hostname := BaselineHostPrefix + event.TempAppWithUUID
+	err := h.mapTemporaryRoute(appName, hostname, domain, event.Log)
+	if err != nil {
+		return err
+	}
+	defer h.deleteTemporaryRoute(hostname, domain, event.Log)
+	defer h.unmapRoute(appName, hostname, domain, event.Log)
+
+	event.Log.Infof("comparing the health check of %s with %s", event.TempAppWithUUID, appName)
+	baseline, _ := h.checkInstances(appName, url, event.HealthCheckEndpoint, 0, event.Log)
+
+	newHealth, oldHealth := summarize(results), summarize(baseline)
+	if event.Response != nil {
+		fmt.Fprintf(event.Response, "Health check comparison with %s:\n", appName)
+		for _, result := range baseline {
+			fmt.Fprintf(event.Response, "  %s\n", result)
+		}
+		fmt.Fprintf(event.Response, "  new application: %s\n", newHealth)
+		fmt.Fprintf(event.Response, "  replaced application: %s\n", oldHealth)
+	}
+
+	if newHealth.Healthy*oldHealth.Total < oldHealth.Healthy*newHealth.Total {
+		return ComparisonError{appName, fmt.Sprintf("%d of %d instances are healthy, %d of %d before", newHealth.Healthy, newHealth.Total, oldHealth.Healthy, oldHealth.Total)}
+	}
+
+	if event.HealthCheckCompare == S.HealthCheckCompareLatency && newHealth.Healthy > 0 && oldHealth.Healthy > 0 {
+		tolerance := event.HealthCheckLatencyTolerancePercent
+		if newHealth.Latency*100 > oldHealth.Latency*time.Duration(100+tolerance) {
+			return ComparisonError{appName, fmt.Sprintf("the median latency is %s, more than %d%% over %s before", newHealth.Latency, tolerance, oldHealth.Latency)}
+		}
+	}
+
+	event.Log.Infof("%s is at least as healthy as %s", event.TempAppWithUUID, appName)
+	return nil
+}
+
+// health is how many of the instances of an application are healthy and the median latency of the healthy ones,
+// rounded to the millisecond so that differences below a millisecond are not compared.
+type health struct {
+	Healthy int
+	Total   int
+	Latency time.Duration
+}
+
+func (h health) String() string {
+	if h.Healthy == 0 {
+		return fmt.Sprintf("%d of %d healthy", h.Healthy, h.Total)
+	}
+	return fmt.Sprintf("%d of %d healthy, median latency %s", h.Healthy, h.Total, h.Latency)
+}
+
+func summarize(results []Result) health {
+	var latencies []time.Duration
+	for _, result := range results {
+		if result.Err == nil {
+			latencies = append(latencies, result.Latency)
+		}
+	}
+
+	summary := health{Healthy: len(latencies), Total: len(results)}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		summary.Latency = latencies[len(latencies)/2].Round(time.Millisecond)
+	}
+
+	return summary
+}
+
+// stabilize polls the endpoint until it has been healthy for polls consecutive checks spread evenly over period.
+// The health check that passed counts as the first poll. The polls are not retried, so an application that
+// crashes while warming up fails with an UnstableError instead of passing on a single success.
+//
+// Returns the number of consecutive healthy polls.
+func (h HealthChecker) stabilize(url, endpoint string, polls int, period time.Duration, log I.DeploymentLogger) (int, error) {
+	interval := period / time.Duration(polls-1)
+	log.Infof("waiting for %d consecutive healthy polls over %s", polls, period)
+
+	successes := 1
+	for successes < polls {
+		if !h.wait(interval) {
+			return successes, AbortedError{fmt.Errorf("%d of %d consecutive healthy polls", successes, polls)}
+		}
+
+		_, err := h.get(url, endpoint, "", log)
+		if err != nil {
+			return successes, UnstableError{successes, polls, err}
+		}
+		successes++
+	}
+
+	log.Infof("health check stable after %d consecutive healthy polls", successes)
+	return successes, nil
+}
+
+// check checks the endpoint, retrying each kind of failure according to its own Policy.
+// When instance is set the request is routed to that instance with the X-CF-APP-INSTANCE header.
+//
+// Returns the result of the last attempt.
+func (h HealthChecker) check(url, endpoint, instance string, log I.DeploymentLogger) (Result, error) {
+	var unreachableRetries, unhealthyRetries int
+
+	for {
+		result, err := h.get(url, endpoint, instance, log)
+
+		var (
+			policy  Policy
+			retries *int
+		)
+		switch err.(type) {
+		case ClientError:
+			policy, retries = h.UnreachablePolicy, &unreachableRetries
+		case HealthCheckError:
+			policy, retries = h.UnhealthyPolicy, &unhealthyRetries
+		default:
+			return result, err
+		}
+
+		if *retries >= policy.Retries {
+			return result, err
+		}
+		*retries++
+
+		log.Infof("retrying health check in %s (retry %d of %d)", policy.RetryInterval, *retries, policy.Retries)
+		if !h.wait(policy.RetryInterval) {
+			log.Errorf("health check aborted before retry %d", *retries)
+			return result, AbortedError{err}
+		}
+	}
+}
+
+func (h HealthChecker) get(url, endpoint, instance string, log I.DeploymentLogger) (Result, error) {
 	trimmedEndpoint := strings.TrimPrefix(endpoint, "/")
+	result := Result{URL: fmt.Sprintf("%s/%s", url, trimmedEndpoint), Instance: -1}
 
 	log.Debugf("checking route %s%s", url, endpoint)
 
-	resp, err := h.Client.Get(fmt.Sprintf("%s/%s", url, trimmedEndpoint))
+	start := time.Now()
+	resp, err := h.request(result.URL, instance)
+	result.Latency = time.Since(start)
 	if err != nil {
 		log.Error(ClientError{err})
-		return ClientError{err}
+		result.Err = ClientError{err}
+		return result, result.Err
 	}
+	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodySize))
 		log.Errorf("health check failed for %s/%s", url, trimmedEndpoint)
-		return HealthCheckError{resp.StatusCode, endpoint, body}
+		result.Err = HealthCheckError{resp.StatusCode, endpoint, body}
+		return result, result.Err
 	}
 
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxBodySize))
+
 	log.Infof("health check successful for %s%s", url, endpoint)
-	return nil
+	return result, nil
 }
 
-func (h HealthChecker) mapTemporaryRoute(tempAppWithUUID, domain string, log I.DeploymentLogger) error {
-	log.Debugf("mapping temporary route %s.%s", tempAppWithUUID, domain)
+// request gets the url, from the instance when it is set.
+func (h HealthChecker) request(url, instance string) (*http.Response, error) {
+	if instance == "" {
+		return h.Client.Get(url)
+	}
 
-	out, err := h.Courier.MapRoute(tempAppWithUUID, domain, tempAppWithUUID)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(InstanceHeader, instance)
+
+	return h.Client.Do(req)
+}
+
+// wait waits for d between attempts. It returns false when the health check was aborted first.
+func (h HealthChecker) wait(d time.Duration) bool {
+	select {
+	case <-h.abort:
+		return false
+	default:
+	}
+
+	if h.Sleep != nil {
+		h.Sleep(d)
+		return true
+	}
+
+	select {
+	case <-h.abort:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func (h HealthChecker) mapTemporaryRoute(appName, hostname, domain string, log I.DeploymentLogger) error {
+	log.Debugf("mapping temporary route %s.%s", hostname, domain)
+
+	out, err := h.Courier.MapRoute(appName, domain, hostname)
 	if err != nil {
 		log.Errorf("failed to map temporary route: %s", out)
-		return MapRouteError{tempAppWithUUID, domain}
+		return MapRouteError{hostname, domain}
 	}
-	log.Infof("mapped temporary route %s.%s", tempAppWithUUID, domain)
+	log.Infof("mapped temporary route %s.%s", hostname, domain)
 
 	return nil
 }
@@ -118,14 +534,18 @@ func (h HealthChecker) deleteTemporaryRoute(tempAppWithUUID, domain string, log
 }
 
 func (h HealthChecker) unmapTemporaryRoute(tempAppWithUUID, domain string, log I.DeploymentLogger) {
-	log.Debugf("unmapping temporary route %s.%s", tempAppWithUUID, domain)
+	h.unmapRoute(tempAppWithUUID, tempAppWithUUID, domain, log)
+
+	log.Infof("finished health check")
+}
+
+func (h HealthChecker) unmapRoute(appName, hostname, domain string, log I.DeploymentLogger) {
+	log.Debugf("unmapping temporary route %s.%s", hostname, domain)
 
-	out, err := h.Courier.UnmapRoute(tempAppWithUUID, domain, tempAppWithUUID)
+	out, err := h.Courier.UnmapRoute(appName, domain, hostname)
 	if err != nil {
 		log.Errorf("failed to unmap temporary route: %s", out)
 	} else {
-		log.Infof("unmapped temporary route %s.%s", tempAppWithUUID, domain)
+		log.Infof("unmapped temporary route %s.%s", hostname, domain)
 	}
-
-	log.Infof("finished health check")
 }
@@ -3,7 +3,12 @@ package healthchecker_test
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
 
 	. "github.com/compozed/deployadactyl/eventmanager/handlers/healthchecker"
 	"github.com/compozed/deployadactyl/mocks"
@@ -13,8 +18,10 @@ import (
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 
+	C "github.com/compozed/deployadactyl/constants"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
 	"github.com/op/go-logging"
 )
 
@@ -116,6 +123,51 @@ var _ = Describe("Healthchecker", func() {
 					Expect(client.GetCall.Received.URL).To(Equal(fmt.Sprintf("https://%s.%s%s", randomAppName, randomDomain, randomEndpoint)))
 				})
 
+				It("uses the url prefixes of the environment", func() {
+					healthchecker.EnvironmentURLPrefixes = map[string]URLPrefixes{
+						randomEnvironment: {OldURL: "api.sys", NewURL: "cfapps"},
+					}
+					ievent.FoundationURL = strings.Replace(randomFoundationURL, "api.cf", "api.sys", 1)
+					domain := strings.Replace(randomDomain, "apps", "cfapps", 1)
+
+					healthchecker.PushFinishedEventHandler(ievent)
+
+					Expect(courier.MapRouteCall.Received.Domain[0]).To(Equal(domain))
+					Expect(client.GetCall.Received.URL).To(Equal(fmt.Sprintf("https://%s.%s%s", randomAppName, domain, randomEndpoint)))
+				})
+
+				It("uses the default url prefixes for other environments", func() {
+					healthchecker.EnvironmentURLPrefixes = map[string]URLPrefixes{
+						"other-environment": {OldURL: "api.sys", NewURL: "cfapps"},
+					}
+
+					healthchecker.PushFinishedEventHandler(ievent)
+
+					Expect(client.GetCall.Received.URL).To(Equal(fmt.Sprintf("https://%s.%s%s", randomAppName, randomDomain, randomEndpoint)))
+				})
+
+				It("checks the endpoint with the insecure client when the environment skips ssl", func() {
+					insecureClient := &mocks.Client{}
+					insecureClient.GetCall.Returns.Response = http.Response{StatusCode: http.StatusOK, Body: NewBuffer()}
+					healthchecker.InsecureClient = insecureClient
+					ievent.SkipSSL = true
+
+					Expect(healthchecker.PushFinishedEventHandler(ievent)).To(Succeed())
+
+					Expect(insecureClient.GetCall.Received.URL).To(Equal(fmt.Sprintf("https://%s.%s%s", randomAppName, randomDomain, randomEndpoint)))
+					Expect(client.GetCall.Received.URL).To(BeEmpty())
+				})
+
+				It("checks the endpoint with the client when the environment does not skip ssl", func() {
+					insecureClient := &mocks.Client{}
+					healthchecker.InsecureClient = insecureClient
+
+					healthchecker.PushFinishedEventHandler(ievent)
+
+					Expect(client.GetCall.Received.URL).ToNot(BeEmpty())
+					Expect(insecureClient.GetCall.Received.URL).To(BeEmpty())
+				})
+
 				It("unmaps the temporary route", func() {
 					healthchecker.PushFinishedEventHandler(ievent)
 
@@ -217,6 +269,64 @@ var _ = Describe("Healthchecker", func() {
 			})
 		})
 
+		Context("when the health check mode is warn", func() {
+			var eventManager *mocks.EventManager
+
+			BeforeEach(func() {
+				eventManager = &mocks.EventManager{}
+				healthchecker.EventManager = eventManager
+				ievent.HealthCheckMode = S.HealthCheckWarn
+				ievent.Response = NewBuffer()
+				client.GetCall.Returns.Response = http.Response{
+					StatusCode: http.StatusNotFound,
+					Body:       NewBuffer(),
+				}
+			})
+
+			It("does not fail the deploy", func() {
+				Expect(healthchecker.PushFinishedEventHandler(ievent)).To(Succeed())
+			})
+
+			It("writes a warning to the response", func() {
+				healthchecker.PushFinishedEventHandler(ievent)
+
+				Eventually(ievent.Response).Should(Say("WARNING: health check of %s failed", randomAppName))
+			})
+
+			It("emits a deploy warning", func() {
+				healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal(C.DeployWarningEvent))
+				warning := eventManager.EmitEventCall.Received.Events[0].(push.DeployWarningEvent)
+				Expect(warning.TempAppWithUUID).To(Equal(randomAppName))
+				Expect(warning.Warning).To(ContainSubstring("health check of %s failed", randomAppName))
+			})
+
+			It("returns an error when the warning cannot be emitted", func() {
+				eventManager.EmitEventCall.Returns.Error = []error{errors.New("emit error")}
+
+				Expect(healthchecker.PushFinishedEventHandler(ievent)).To(MatchError("emit error"))
+			})
+
+			It("does not emit a warning when the application is healthy", func() {
+				client.GetCall.Returns.Response = http.Response{StatusCode: http.StatusOK, Body: NewBuffer()}
+
+				Expect(healthchecker.PushFinishedEventHandler(ievent)).To(Succeed())
+				Expect(eventManager.EmitEventCall.Received.Events).To(BeEmpty())
+			})
+		})
+
+		Context("when the health check mode is off", func() {
+			It("skips the health check", func() {
+				ievent.HealthCheckMode = S.HealthCheckOff
+
+				Expect(healthchecker.PushFinishedEventHandler(ievent)).To(Succeed())
+
+				Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+				Expect(client.GetCall.Received.URL).To(BeEmpty())
+			})
+		})
+
 		Context("when mapping the temporary route fails", func() {
 			It("returns an error", func() {
 				courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("map route output"))
@@ -249,6 +359,311 @@ var _ = Describe("Healthchecker", func() {
 			})
 		})
 
+		Context("when retry policies are configured", func() {
+			var sleeps []time.Duration
+
+			BeforeEach(func() {
+				sleeps = nil
+				healthchecker.Sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+				healthchecker.UnreachablePolicy = Policy{Retries: 2, RetryInterval: 5 * time.Second}
+				healthchecker.UnhealthyPolicy = Policy{Retries: 1, RetryInterval: time.Second}
+			})
+
+			It("retries an unreachable endpoint with the unreachable policy", func() {
+				client.GetCall.Returns.Error = errors.New("no such host")
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).To(MatchError(ClientError{errors.New("no such host")}))
+				Expect(sleeps).To(Equal([]time.Duration{5 * time.Second, 5 * time.Second}))
+				Eventually(logBuffer).Should(Say("retrying health check in 5s \\(retry 1 of 2\\)"))
+				Eventually(logBuffer).Should(Say("retrying health check in 5s \\(retry 2 of 2\\)"))
+			})
+
+			It("retries an unhealthy endpoint with the unhealthy policy", func() {
+				client.GetCall.Returns.Response = http.Response{
+					StatusCode: http.StatusInternalServerError,
+					Body:       NewBuffer(),
+				}
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).To(MatchError(HealthCheckError{http.StatusInternalServerError, randomEndpoint, []byte{}}))
+				Expect(sleeps).To(Equal([]time.Duration{time.Second}))
+			})
+
+			It("does not retry an unhealthy endpoint when only network errors are retried", func() {
+				healthchecker.UnhealthyPolicy = Policy{}
+				client.GetCall.Returns.Response = http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       NewBuffer(),
+				}
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).To(MatchError(HealthCheckError{http.StatusServiceUnavailable, randomEndpoint, []byte{}}))
+				Expect(sleeps).To(BeEmpty())
+			})
+
+			It("stops retrying once the health check is aborted", func() {
+				abort := make(chan struct{})
+				close(abort)
+				ievent.Abort = abort
+				client.GetCall.Returns.Error = errors.New("no such host")
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).To(MatchError(AbortedError{ClientError{errors.New("no such host")}}))
+				Expect(sleeps).To(BeEmpty())
+				Eventually(logBuffer).Should(Say("health check aborted before retry 1"))
+			})
+		})
+
+		Context("when the environment has a stabilization period", func() {
+			var (
+				sleeps   []time.Duration
+				response *Buffer
+			)
+
+			BeforeEach(func() {
+				sleeps = nil
+				response = NewBuffer()
+				healthchecker.Sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+				ievent.Response = response
+				ievent.HealthCheckStablePolls = 4
+				ievent.HealthCheckStableSeconds = 30
+			})
+
+			It("passes after the consecutive healthy polls spread over the period", func() {
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(client.GetCall.TimesCalled).To(Equal(4))
+				Expect(sleeps).To(Equal([]time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second}))
+				Eventually(response).Should(Say("Health check stabilization: 4 of 4 consecutive healthy polls"))
+			})
+
+			It("fails when the application becomes unhealthy during the period", func() {
+				healthy := http.Response{StatusCode: http.StatusOK, Body: NewBuffer()}
+				crashed := http.Response{StatusCode: http.StatusBadGateway, Body: NewBuffer()}
+				client.GetCall.Returns.Responses = []http.Response{healthy, healthy, crashed}
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).To(MatchError(UnstableError{2, 4, HealthCheckError{http.StatusBadGateway, randomEndpoint, []byte{}}}))
+				Expect(client.GetCall.TimesCalled).To(Equal(3))
+				Eventually(response).Should(Say("Health check stabilization: 2 of 4 consecutive healthy polls"))
+			})
+
+			It("does not poll again when the stabilization period is not configured", func() {
+				ievent.HealthCheckStablePolls = 0
+
+				Expect(healthchecker.PushFinishedEventHandler(ievent)).To(Succeed())
+
+				Expect(client.GetCall.TimesCalled).To(Equal(1))
+				Expect(sleeps).To(BeEmpty())
+			})
+		})
+
+		Context("when the application has more than one instance", func() {
+			var (
+				response    *Buffer
+				instanceURL string
+			)
+
+			BeforeEach(func() {
+				response = NewBuffer()
+				ievent.Response = response
+				instanceURL = fmt.Sprintf("https://%s.%s%s", randomAppName, randomDomain, randomEndpoint)
+
+				courier.InstanceStatesCall.Returns.States = [][]string{{"running", "running", "running"}}
+				courier.AppGUIDCall.Returns.GUID = "app-guid"
+
+				client.DoCall.Returns.Responses = map[string]http.Response{
+					instanceURL + " app-guid:0": {StatusCode: http.StatusOK},
+					instanceURL + " app-guid:1": {StatusCode: http.StatusOK},
+					instanceURL + " app-guid:2": {StatusCode: http.StatusOK},
+				}
+			})
+
+			It("checks each instance on its own", func() {
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(courier.AppGUIDCall.Received.AppName).To(Equal(randomAppName))
+				Expect(client.DoCall.TimesCalled).To(Equal(3))
+				for i, req := range client.DoCall.Received.Requests {
+					Expect(req.URL.String()).To(Equal(instanceURL))
+					Expect(req.Header.Get(InstanceHeader)).To(Equal(fmt.Sprintf("app-guid:%d", i)))
+				}
+			})
+
+			It("writes the result of each instance to the response", func() {
+				healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(response).To(Say("Health check results:"))
+				Expect(response).To(Say(`instance 0 %s: 200 in \d+`, instanceURL))
+				Expect(response).To(Say(`instance 1 %s: 200 in \d+`, instanceURL))
+				Expect(response).To(Say(`instance 2 %s: 200 in \d+`, instanceURL))
+			})
+
+			It("reports every instance when one of them is unhealthy", func() {
+				client.DoCall.Returns.Responses[instanceURL+" app-guid:1"] = http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       NewBuffer(),
+				}
+				client.DoCall.Returns.Errors = map[string]error{instanceURL + " app-guid:2": errors.New("connection refused")}
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).To(BeAssignableToTypeOf(InstancesUnhealthyError{}))
+				Expect(err.(InstancesUnhealthyError).Unhealthy).To(Equal(2))
+				Expect(err.(InstancesUnhealthyError).Results).To(HaveLen(3))
+				Expect(err.Error()).To(HavePrefix("health check failed on 2 of 3 instances:"))
+				Expect(err.Error()).To(MatchRegexp(`instance 1 %s: 503 in \d+`, instanceURL))
+				Expect(err.Error()).To(MatchRegexp(`instance 2 %s: unreachable after \d+.*: connection refused`, instanceURL))
+
+				Expect(response).To(Say(`instance 0 %s: 200`, instanceURL))
+				Expect(response).To(Say(`instance 1 %s: 503`, instanceURL))
+				Expect(response).To(Say(`instance 2 %s: unreachable`, instanceURL))
+			})
+
+			It("reports how many instances are healthy", func() {
+				healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(response).To(Say("Healthy instances: 3 of 3, at least 100% required"))
+			})
+
+			It("succeeds when at least the minimum healthy percent of the instances are healthy", func() {
+				ievent.MinHealthyPercent = 60
+				client.DoCall.Returns.Errors = map[string]error{instanceURL + " app-guid:2": errors.New("connection refused")}
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).To(Say("Healthy instances: 2 of 3, at least 60% required"))
+			})
+
+			It("fails when fewer than the minimum healthy percent of the instances are healthy", func() {
+				ievent.MinHealthyPercent = 70
+				client.DoCall.Returns.Errors = map[string]error{instanceURL + " app-guid:2": errors.New("connection refused")}
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).To(BeAssignableToTypeOf(InstancesUnhealthyError{}))
+				Expect(response).To(Say("Healthy instances: 2 of 3, at least 70% required"))
+			})
+
+			It("checks through the route when the guid cannot be found", func() {
+				courier.AppGUIDCall.Returns.Error = errors.New("app not found")
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(client.DoCall.TimesCalled).To(Equal(0))
+				Expect(client.GetCall.Received.URL).To(Equal(instanceURL))
+				Expect(response).To(Say(`%s: 200 in \d+`, instanceURL))
+			})
+		})
+
+		Context("when the health check is compared with the replaced application", func() {
+			var (
+				response    *Buffer
+				oldAppName  string
+				newURL      string
+				baselineURL string
+			)
+
+			BeforeEach(func() {
+				response = NewBuffer()
+				oldAppName = "oldAppName-" + randomizer.StringRunes(10)
+				newURL = fmt.Sprintf("https://%s.%s%s", randomAppName, randomDomain, randomEndpoint)
+				baselineURL = fmt.Sprintf("https://%s%s.%s%s", BaselineHostPrefix, randomAppName, randomDomain, randomEndpoint)
+
+				ievent.Response = response
+				ievent.CFContext.Application = oldAppName
+				ievent.HealthCheckCompare = S.HealthCheckCompareHealthy
+				ievent.HealthCheckLatencyTolerancePercent = 20
+				ievent.MinHealthyPercent = 50
+
+				courier.ExistsCall.Returns.Bool = true
+				courier.InstanceStatesCall.Returns.States = [][]string{{"running", "running"}}
+				courier.AppGUIDCall.Returns.GUIDs = map[string]string{randomAppName: "new-guid", oldAppName: "old-guid"}
+
+				client.DoCall.Returns.Responses = map[string]http.Response{
+					newURL + " new-guid:0":      {StatusCode: http.StatusOK},
+					newURL + " new-guid:1":      {StatusCode: http.StatusOK},
+					baselineURL + " old-guid:0": {StatusCode: http.StatusOK},
+					baselineURL + " old-guid:1": {StatusCode: http.StatusOK},
+				}
+			})
+
+			It("checks the replaced application through a temporary route", func() {
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{randomAppName, oldAppName}))
+				Expect(courier.MapRouteCall.Received.Hostname).To(Equal([]string{randomAppName, BaselineHostPrefix + randomAppName}))
+				Expect(courier.AppGUIDCall.Received.AppName).To(Equal(oldAppName))
+				Expect(client.DoCall.TimesCalled).To(Equal(4))
+			})
+
+			It("writes both results to the response", func() {
+				healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(response).To(Say("Health check comparison with %s:", oldAppName))
+				Expect(response).To(Say(`instance 0 %s: 200 in \d+`, baselineURL))
+				Expect(response).To(Say("new application: 2 of 2 healthy, median latency"))
+				Expect(response).To(Say("replaced application: 2 of 2 healthy, median latency"))
+			})
+
+			It("fails when the new application is less healthy than the replaced application", func() {
+				client.DoCall.Returns.Errors = map[string]error{newURL + " new-guid:1": errors.New("connection refused")}
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).To(MatchError(ComparisonError{oldAppName, "1 of 2 instances are healthy, 2 of 2 before"}))
+			})
+
+			It("succeeds when the replaced application is not healthier", func() {
+				client.DoCall.Returns.Errors = map[string]error{
+					newURL + " new-guid:1":      errors.New("connection refused"),
+					baselineURL + " old-guid:0": errors.New("connection refused"),
+				}
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("compares the latency when the latency comparison is chosen", func() {
+				ievent.HealthCheckCompare = S.HealthCheckCompareLatency
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(logBuffer).To(Say("%s is at least as healthy as %s", randomAppName, oldAppName))
+			})
+
+			It("does not compare on the first deploy of the application", func() {
+				courier.ExistsCall.Returns.Bool = false
+
+				err := healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{randomAppName}))
+				Expect(client.DoCall.TimesCalled).To(Equal(2))
+			})
+
+			It("does not compare when the comparison is off", func() {
+				ievent.HealthCheckCompare = S.HealthCheckCompareOff
+
+				healthchecker.PushFinishedEventHandler(ievent)
+
+				Expect(client.DoCall.TimesCalled).To(Equal(2))
+			})
+		})
+
 		Context("when a health check endpoint is not provided", func() {
 			It("returns nil", func() {
 				ievent = push.PushFinishedEvent{
@@ -300,4 +715,46 @@ var _ = Describe("Healthchecker", func() {
 			})
 		})
 	})
+
+	Describe("connections", func() {
+		It("reuses the connections of the client for healthy and unhealthy responses", func() {
+			var (
+				mutex       sync.Mutex
+				connections int
+				requests    int
+			)
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				requests++
+				healthy := requests%2 == 0
+				mutex.Unlock()
+
+				if !healthy {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+				fmt.Fprint(w, strings.Repeat("status ", 100))
+			}))
+			server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					mutex.Lock()
+					connections++
+					mutex.Unlock()
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			healthchecker.Client = &http.Client{Transport: &http.Transport{}}
+			log := I.DeploymentLogger{Log: I.DefaultLogger(logBuffer, logging.DEBUG, "healthchecker_test")}
+
+			for i := 0; i < 6; i++ {
+				healthchecker.Check(server.URL, "/health", log)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			Expect(requests).To(Equal(6))
+			Expect(connections).To(Equal(1))
+		})
+	})
 })
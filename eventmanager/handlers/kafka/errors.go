@@ -0,0 +1,26 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PublishError struct {
+	EventType string
+	UUID      string
+	Topic     string
+	Err       error
+}
+
+func (e PublishError) Error() string {
+	return fmt.Sprintf("could not publish %s event of deploy %s to %s: %s", e.EventType, e.UUID, e.Topic, e.Err)
+}
+
+type ConnectionError struct {
+	Brokers []string
+	Err     error
+}
+
+func (e ConnectionError) Error() string {
+	return fmt.Sprintf("could not connect to kafka brokers %s: %s", strings.Join(e.Brokers, ", "), e.Err)
+}
@@ -0,0 +1,80 @@
+// Package kafka publishes deploy events to a Kafka topic.
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/compozed/deployadactyl/constants"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// Events are the deploy events published by a Handler.
+var Events = []string{
+	constants.DeployStartEvent,
+	constants.DeploySuccessEvent,
+	constants.DeployFailureEvent,
+	constants.DeployFinishEvent,
+}
+
+// Message is the JSON published for each deploy event. A signed Message is the payload of a signing.Envelope.
+type Message = S.DeployRecord
+
+// Handler publishes the deploy events it receives to a Kafka topic, keyed by org/space/app so the events
+// of an application stay in order. A failed publish is retried and then logged; it never fails the deploy.
+//
+// Handler must be added to the EventManager for each of the Events and closed when the server shuts down.
+type Handler struct {
+	Producer      I.KafkaProducer
+	Topic         string
+	Retries       int
+	RetryInterval time.Duration
+	Log           I.Logger
+
+	// Signer signs each message when it is set.
+	Signer I.DeploySigner
+}
+
+// OnEvent publishes a deploy event. Events without deployment info are ignored.
+func (h Handler) OnEvent(event I.Event) error {
+	data, ok := event.Data.(*S.DeployEventData)
+	if !ok || data.DeploymentInfo == nil {
+		return nil
+	}
+
+	message := S.NewDeployRecord(event.Type, event.Replay, event.Error, *data.DeploymentInfo)
+	info := message.DeploymentInfo
+
+	value, err := signing.Marshal(message, h.Signer)
+	if err != nil {
+		h.Log.Errorf("could not serialize %s event of deploy %s: %s", event.Type, info.UUID, err)
+		return nil
+	}
+
+	key := []byte(fmt.Sprintf("%s/%s/%s", info.Org, info.Space, info.AppName))
+
+	for attempt := 0; ; attempt++ {
+		err = h.Producer.Publish(h.Topic, key, value)
+		if err == nil {
+			h.Log.Debugf("published %s event of deploy %s to %s", event.Type, info.UUID, h.Topic)
+			return nil
+		}
+
+		if attempt >= h.Retries {
+			break
+		}
+
+		h.Log.Errorf("could not publish %s event of deploy %s to %s, retrying: %s", event.Type, info.UUID, h.Topic, err)
+		time.Sleep(h.RetryInterval)
+	}
+
+	h.Log.Error(PublishError{event.Type, info.UUID, h.Topic, err})
+	return nil
+}
+
+// Close flushes the messages that are still being published and closes the producer.
+func (h Handler) Close() error {
+	return h.Producer.Close()
+}
@@ -0,0 +1,142 @@
+package kafka_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	. "github.com/compozed/deployadactyl/eventmanager/handlers/kafka"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/signing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+
+	C "github.com/compozed/deployadactyl/constants"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Kafka", func() {
+	var (
+		producer  *mocks.KafkaProducer
+		logBuffer *Buffer
+		handler   Handler
+		event     I.Event
+	)
+
+	BeforeEach(func() {
+		producer = &mocks.KafkaProducer{}
+		logBuffer = NewBuffer()
+
+		handler = Handler{
+			Producer: producer,
+			Topic:    "deploys",
+			Retries:  2,
+			Log:      I.DefaultLogger(logBuffer, logging.DEBUG, "kafka_test"),
+		}
+
+		event = I.Event{
+			Type: C.DeploySuccessEvent,
+			Data: &S.DeployEventData{
+				DeploymentInfo: &S.DeploymentInfo{
+					Username: "username",
+					Password: "password",
+					Org:      "org",
+					Space:    "space",
+					AppName:  "app",
+					UUID:     "uuid",
+				},
+			},
+		}
+	})
+
+	It("publishes the event to the topic keyed by org, space and app", func() {
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		Expect(producer.PublishCall.TimesCalled).To(Equal(1))
+		Expect(producer.PublishCall.Received.Topic).To(Equal("deploys"))
+		Expect(string(producer.PublishCall.Received.Key)).To(Equal("org/space/app"))
+
+		message := Message{}
+		Expect(json.Unmarshal(producer.PublishCall.Received.Value, &message)).To(Succeed())
+		Expect(message.Type).To(Equal(C.DeploySuccessEvent))
+		Expect(message.DeploymentInfo.UUID).To(Equal("uuid"))
+	})
+
+	It("does not publish the credentials of the deploy", func() {
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		Expect(string(producer.PublishCall.Received.Value)).ToNot(ContainSubstring("password"))
+		Expect(string(producer.PublishCall.Received.Value)).ToNot(ContainSubstring("username"))
+	})
+
+	It("redacts the values of the environment variables of the push", func() {
+		event.Data.(*S.DeployEventData).DeploymentInfo.Env = map[string]string{"API_TOKEN": "secret-token"}
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		message := Message{}
+		Expect(json.Unmarshal(producer.PublishCall.Received.Value, &message)).To(Succeed())
+		Expect(message.DeploymentInfo.Env).To(Equal(map[string]string{"API_TOKEN": S.RedactedValue}))
+		Expect(event.Data.(*S.DeployEventData).DeploymentInfo.Env["API_TOKEN"]).To(Equal("secret-token"))
+	})
+
+	It("publishes the error of a failed deploy", func() {
+		event.Type = C.DeployFailureEvent
+		event.Error = errors.New("push failed")
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		message := Message{}
+		Expect(json.Unmarshal(producer.PublishCall.Received.Value, &message)).To(Succeed())
+		Expect(message.Error).To(Equal("push failed"))
+	})
+
+	It("signs the message with the signer", func() {
+		handler.Signer, _ = signing.NewSigner(signing.HMACSHA256, "key-1", []byte("secret"))
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		envelope := signing.Envelope{}
+		Expect(json.Unmarshal(producer.PublishCall.Received.Value, &envelope)).To(Succeed())
+		Expect(envelope.Signature.KeyID).To(Equal("key-1"))
+		Expect(signing.Verify(envelope.Signature, envelope.Payload, []byte("secret"))).To(BeTrue())
+
+		message := Message{}
+		Expect(json.Unmarshal(envelope.Payload, &message)).To(Succeed())
+		Expect(message.Type).To(Equal(event.Type))
+	})
+
+	It("retries a failed publish", func() {
+		producer.PublishCall.Returns.Errors = []error{errors.New("broker unavailable")}
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		Expect(producer.PublishCall.TimesCalled).To(Equal(2))
+		Expect(logBuffer).To(Say("retrying: broker unavailable"))
+	})
+
+	It("logs the error without failing the deploy when every retry fails", func() {
+		publishErr := errors.New("broker unavailable")
+		producer.PublishCall.Returns.Errors = []error{publishErr, publishErr, publishErr}
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		Expect(producer.PublishCall.TimesCalled).To(Equal(3))
+		Expect(logBuffer).To(Say(PublishError{C.DeploySuccessEvent, "uuid", "deploys", publishErr}.Error()))
+	})
+
+	It("ignores events without deployment info", func() {
+		Expect(handler.OnEvent(I.Event{Type: C.DeployStartEvent, Data: "data"})).To(Succeed())
+
+		Expect(producer.PublishCall.TimesCalled).To(BeZero())
+	})
+
+	It("closes the producer", func() {
+		Expect(handler.Close()).To(Succeed())
+
+		Expect(producer.CloseCall.Called).To(BeTrue())
+	})
+})
@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// Producer is a KafkaProducer that publishes each message synchronously to the brokers.
+// It connects on the first publish, and again on the next publish when connecting fails,
+// so brokers that are down when the server starts do not stop it.
+type Producer struct {
+	brokers  []string
+	producer sarama.SyncProducer
+	mutex    sync.Mutex
+}
+
+// NewProducer returns a Producer for the brokers.
+func NewProducer(brokers []string) *Producer {
+	return &Producer{brokers: brokers}
+}
+
+// Publish sends a message to the topic and waits for every in-sync replica to acknowledge it.
+func (p *Producer) Publish(topic string, key, value []byte) error {
+	producer, err := p.connect()
+	if err != nil {
+		return err
+	}
+
+	_, _, err = producer.SendMessage(&sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.ByteEncoder(key),
+		Value: sarama.ByteEncoder(value),
+	})
+	return err
+}
+
+// Close waits for the messages being sent and closes the connection to the brokers.
+func (p *Producer) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.producer == nil {
+		return nil
+	}
+
+	err := p.producer.Close()
+	p.producer = nil
+	return err
+}
+
+func (p *Producer) connect() (sarama.SyncProducer, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.producer != nil {
+		return p.producer, nil
+	}
+
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(p.brokers, config)
+	if err != nil {
+		return nil, ConnectionError{p.brokers, err}
+	}
+
+	p.producer = producer
+	return producer, nil
+}
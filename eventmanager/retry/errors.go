@@ -0,0 +1,21 @@
+package retry
+
+import "fmt"
+
+type CreateDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e CreateDirectoryError) Error() string {
+	return fmt.Sprintf("cannot create event retry directory %s: %s", e.Directory, e.Err)
+}
+
+type WriteEntryError struct {
+	Path string
+	Err  error
+}
+
+func (e WriteEntryError) Error() string {
+	return fmt.Sprintf("cannot write event retry %s: %s", e.Path, e.Err)
+}
@@ -0,0 +1,280 @@
+// Package retry keeps deploy events whose emission failed in a directory and emits them again in the background,
+// so a handler that is down for a while still receives them after a restart of Deployadactyl.
+//
+// Events are delivered at least once. A retried event is emitted to every handler again, including the handlers
+// that already received it.
+package retry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/constants"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/spf13/afero"
+)
+
+const fileExtension = ".json"
+
+// pollInterval is how often a started Queue looks for events that are due.
+const pollInterval = 5 * time.Second
+
+// maxBackoff caps the wait between the retries of an event.
+const maxBackoff = time.Hour
+
+// Events are the deploy events whose failed emissions are retried.
+var Events = []string{
+	constants.DeploySuccessEvent,
+	constants.DeployFailureEvent,
+}
+
+// Entry is a failed event emission kept in the queue. Error is the error of the deploy and LastError the error of
+// the last emission. The credentials of the deploy and the values of its Env are never kept.
+type Entry struct {
+	Type           string           `json:"type"`
+	Error          string           `json:"error,omitempty"`
+	DeploymentInfo S.DeploymentInfo `json:"deployment_info"`
+	Attempts       int              `json:"attempts"`
+	NextAttempt    time.Time        `json:"next_attempt"`
+	LastError      string           `json:"last_error"`
+}
+
+// Queue keeps failed event emissions in Directory and emits them again through the EventManager. An event is
+// retried up to MaxAttempts times, waiting Backoff before the first retry and twice as long before each next one.
+type Queue struct {
+	FileSystem   *afero.Afero
+	Directory    string
+	EventManager I.EventManager
+	MaxAttempts  int
+	Backoff      time.Duration
+	Log          I.Logger
+
+	mutex sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewQueue returns a Queue that keeps its events in directory, creating it if needed.
+func NewQueue(fs *afero.Afero, directory string, eventManager I.EventManager, maxAttempts int, backoff time.Duration, log I.Logger) (*Queue, error) {
+	err := fs.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, CreateDirectoryError{directory, err}
+	}
+
+	return &Queue{
+		FileSystem:   fs,
+		Directory:    directory,
+		EventManager: eventManager,
+		MaxAttempts:  maxAttempts,
+		Backoff:      backoff,
+		Log:          log,
+	}, nil
+}
+
+// Add keeps an event whose emission failed with err to be retried. Only the Events with deployment info are kept.
+func (q *Queue) Add(event I.Event, err error) {
+	if !isRetried(event.Type) {
+		return
+	}
+
+	data, ok := event.Data.(*S.DeployEventData)
+	if !ok || data.DeploymentInfo == nil {
+		return
+	}
+
+	info := *data.DeploymentInfo
+	info.Username = ""
+	info.Password = ""
+	info.Body = nil
+	info.Env = S.RedactEnv(info.Env)
+
+	entry := Entry{
+		Type:           event.Type,
+		DeploymentInfo: info,
+		NextAttempt:    time.Now().Add(q.Backoff),
+		LastError:      err.Error(),
+	}
+	if event.Error != nil {
+		entry.Error = event.Error.Error()
+	}
+
+	filePath := path.Join(q.Directory, info.UUID+"-"+randomizer.StringRunes(10)+fileExtension)
+
+	err = q.write(filePath, entry)
+	if err != nil {
+		q.Log.Error(err)
+		return
+	}
+
+	q.Log.Infof("the %s event of deploy %s will be retried in %s", event.Type, info.UUID, q.Backoff)
+}
+
+// Retry emits the events whose next attempt is due. An event that is emitted is removed from the queue. One that
+// fails again is retried later, until it has been retried MaxAttempts times, after which it is dropped.
+func (q *Queue) Retry() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	infos, err := q.FileSystem.ReadDir(q.Directory)
+	if err != nil {
+		q.Log.Errorf("cannot read event retry directory %s: %s", q.Directory, err)
+		return
+	}
+
+	now := time.Now()
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), fileExtension) {
+			continue
+		}
+
+		filePath := path.Join(q.Directory, info.Name())
+		entry, err := q.read(filePath)
+		if err != nil {
+			q.Log.Errorf("cannot read event retry %s: %s", filePath, err)
+			continue
+		}
+
+		if entry.NextAttempt.After(now) {
+			continue
+		}
+
+		q.retry(filePath, entry)
+	}
+}
+
+// Start retries the events that are due in the background until Stop is called.
+func (q *Queue) Start() {
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+
+	go func() {
+		defer close(q.done)
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				q.Retry()
+			case <-q.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops retrying in the background and waits for a retry in progress. The events left in the queue are
+// retried once it is started again.
+func (q *Queue) Stop() {
+	if q.stop == nil {
+		return
+	}
+
+	close(q.stop)
+	<-q.done
+}
+
+func (q *Queue) retry(filePath string, entry Entry) {
+	uuid := entry.DeploymentInfo.UUID
+	entry.Attempts++
+
+	err := q.EventManager.Emit(entry.event())
+	if err == nil {
+		q.Log.Infof("retried the %s event of deploy %s", entry.Type, uuid)
+		q.FileSystem.Remove(filePath)
+		return
+	}
+
+	if entry.Attempts >= q.MaxAttempts {
+		q.Log.Errorf("giving up on the %s event of deploy %s after %d retries: %s", entry.Type, uuid, entry.Attempts, err)
+		q.FileSystem.Remove(filePath)
+		return
+	}
+
+	wait := backoff(q.Backoff, entry.Attempts)
+	entry.NextAttempt = time.Now().Add(wait)
+	entry.LastError = err.Error()
+	q.Log.Errorf("could not retry the %s event of deploy %s, retrying in %s: %s", entry.Type, uuid, wait, err)
+
+	err = q.write(filePath, entry)
+	if err != nil {
+		q.Log.Error(err)
+	}
+}
+
+// write replaces the entry at filePath through a temporary file, so a crash never leaves half an entry behind.
+func (q *Queue) write(filePath string, entry Entry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return WriteEntryError{filePath, err}
+	}
+
+	temporaryPath := filePath + ".tmp"
+	err = q.FileSystem.WriteFile(temporaryPath, body, 0600)
+	if err != nil {
+		return WriteEntryError{filePath, err}
+	}
+
+	err = q.FileSystem.Rename(temporaryPath, filePath)
+	if err != nil {
+		return WriteEntryError{filePath, err}
+	}
+
+	return nil
+}
+
+func (q *Queue) read(filePath string) (Entry, error) {
+	entry := Entry{}
+
+	body, err := q.FileSystem.ReadFile(filePath)
+	if err != nil {
+		return entry, err
+	}
+
+	err = json.Unmarshal(body, &entry)
+	return entry, err
+}
+
+// event rebuilds the event of the entry. Its deploy output is not kept, so it has an empty Response.
+func (e Entry) event() I.Event {
+	info := e.DeploymentInfo
+
+	event := I.Event{
+		Type: e.Type,
+		Data: &S.DeployEventData{Response: &bytes.Buffer{}, DeploymentInfo: &info},
+	}
+	if e.Error != "" {
+		event.Error = errors.New(e.Error)
+	}
+
+	return event
+}
+
+// backoff doubles the wait before the first retry for each retry that failed, up to maxBackoff.
+func backoff(wait time.Duration, attempts int) time.Duration {
+	for i := 0; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+
+	return wait
+}
+
+func isRetried(eventType string) bool {
+	for _, retried := range Events {
+		if eventType == retried {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package retry_test
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/compozed/deployadactyl/constants"
+	. "github.com/compozed/deployadactyl/eventmanager/retry"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Queue", func() {
+	var (
+		af           *afero.Afero
+		eventManager *mocks.EventManager
+		logBuffer    *Buffer
+		queue        *Queue
+		event        I.Event
+	)
+
+	BeforeEach(func() {
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		eventManager = &mocks.EventManager{}
+		logBuffer = NewBuffer()
+
+		var err error
+		queue, err = NewQueue(af, "retries", eventManager, 3, 0, I.DefaultLogger(logBuffer, logging.DEBUG, "retry_test"))
+		Expect(err).ToNot(HaveOccurred())
+
+		event = I.Event{
+			Type: constants.DeploySuccessEvent,
+			Data: &S.DeployEventData{
+				Response: &bytes.Buffer{},
+				DeploymentInfo: &S.DeploymentInfo{
+					UUID:     "some-uuid",
+					AppName:  "some-app",
+					Username: "some-username",
+					Password: "some-password",
+					Env:      map[string]string{"TOKEN": "some-token"},
+				},
+			},
+		}
+	})
+
+	entries := func() []string {
+		infos, err := af.ReadDir("retries")
+		Expect(err).ToNot(HaveOccurred())
+
+		names := []string{}
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		return names
+	}
+
+	It("keeps failed emissions without the credentials or Env values of the deploy", func() {
+		queue.Add(event, errors.New("handler is down"))
+
+		Expect(entries()).To(HaveLen(1))
+		body, err := af.ReadFile("retries/" + entries()[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(string(body)).To(ContainSubstring("some-app"))
+		Expect(string(body)).To(ContainSubstring("handler is down"))
+		Expect(string(body)).ToNot(ContainSubstring("some-password"))
+		Expect(string(body)).ToNot(ContainSubstring("some-username"))
+		Expect(string(body)).ToNot(ContainSubstring("some-token"))
+	})
+
+	It("ignores events that are not retried", func() {
+		event.Type = constants.DeployStartEvent
+
+		queue.Add(event, errors.New("handler is down"))
+
+		Expect(entries()).To(BeEmpty())
+	})
+
+	It("emits the event again and removes it once it is emitted", func() {
+		event.Type = constants.DeployFailureEvent
+		event.Error = errors.New("push failed")
+		queue.Add(event, errors.New("handler is down"))
+
+		queue.Retry()
+
+		Expect(eventManager.EmitCall.TimesCalled).To(Equal(1))
+		retried := eventManager.EmitCall.Received.Events[0]
+		Expect(retried.Type).To(Equal(constants.DeployFailureEvent))
+		Expect(retried.Error).To(MatchError("push failed"))
+		Expect(retried.Data.(*S.DeployEventData).DeploymentInfo.AppName).To(Equal("some-app"))
+		Expect(entries()).To(BeEmpty())
+		Eventually(logBuffer).Should(Say("retried the deploy.failure event of deploy some-uuid"))
+	})
+
+	It("does not emit events before their backoff", func() {
+		queue.Backoff = time.Minute
+		queue.Add(event, errors.New("handler is down"))
+
+		queue.Retry()
+
+		Expect(eventManager.EmitCall.TimesCalled).To(Equal(0))
+		Expect(entries()).To(HaveLen(1))
+	})
+
+	It("keeps the event with a doubled backoff when the emission fails again", func() {
+		queue.Add(event, errors.New("handler is down"))
+		queue.Backoff = time.Minute
+		eventManager.EmitCall.Returns.Error = []error{errors.New("still down")}
+
+		queue.Retry()
+		queue.Retry()
+
+		Expect(eventManager.EmitCall.TimesCalled).To(Equal(1))
+		Expect(entries()).To(HaveLen(1))
+		Eventually(logBuffer).Should(Say("retrying in 2m0s: still down"))
+	})
+
+	It("drops the event after the maximum number of attempts", func() {
+		queue.Add(event, errors.New("handler is down"))
+		eventManager.EmitCall.Returns.Error = []error{errors.New("down"), errors.New("down"), errors.New("down")}
+
+		queue.Retry()
+		queue.Retry()
+		Expect(entries()).To(HaveLen(1))
+
+		queue.Retry()
+
+		Expect(eventManager.EmitCall.TimesCalled).To(Equal(3))
+		Expect(entries()).To(BeEmpty())
+		Eventually(logBuffer).Should(Say("giving up on the deploy.success event of deploy some-uuid after 3 retries"))
+	})
+})
@@ -0,0 +1,64 @@
+// Package httpclient creates the pooled HTTP clients shared by the deployer and the health checker.
+package httpclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Settings configure the connection pool and the timeouts of the clients.
+type Settings struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+
+	// RequestTimeout limits each request, from the dial to the end of the body, and the wait for the headers of
+	// its response. Requests are not limited when it is zero.
+	RequestTimeout time.Duration
+}
+
+// Clients holds a client that verifies TLS certificates and one that skips the verification, for environments
+// with skip_ssl. Each client keeps its own pool of connections that is shared by everything using it, so
+// connections are reused across deploys instead of being opened for every request.
+type Clients struct {
+	secure   *http.Client
+	insecure *http.Client
+}
+
+// NewClients returns Clients whose transports are configured with the settings.
+func NewClients(settings Settings) *Clients {
+	return &Clients{
+		secure:   &http.Client{Transport: newTransport(settings, false), Timeout: settings.RequestTimeout},
+		insecure: &http.Client{Transport: newTransport(settings, true), Timeout: settings.RequestTimeout},
+	}
+}
+
+// Client returns the client that skips TLS verification when skipSSL is set and the one that verifies it otherwise.
+func (c *Clients) Client(skipSSL bool) *http.Client {
+	if skipSSL {
+		return c.insecure
+	}
+	return c.secure
+}
+
+func newTransport(settings Settings, skipSSL bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   settings.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          settings.MaxIdleConns,
+		MaxIdleConnsPerHost:   settings.MaxIdleConnsPerHost,
+		IdleConnTimeout:       settings.IdleConnTimeout,
+		TLSHandshakeTimeout:   settings.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: settings.RequestTimeout,
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: skipSSL},
+	}
+}
@@ -0,0 +1,91 @@
+package httpclient_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/compozed/deployadactyl/httpclient"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Httpclient", func() {
+	var (
+		settings Settings
+		clients  *Clients
+		server   *httptest.Server
+	)
+
+	BeforeEach(func() {
+		settings = Settings{
+			MaxIdleConns:        50,
+			MaxIdleConnsPerHost: 5,
+			IdleConnTimeout:     60 * time.Second,
+			DialTimeout:         5 * time.Second,
+			TLSHandshakeTimeout: 3 * time.Second,
+			RequestTimeout:      time.Second,
+		}
+		clients = NewClients(settings)
+
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("configures the pool and timeouts of both clients", func() {
+		for _, skipSSL := range []bool{true, false} {
+			transport := clients.Client(skipSSL).Transport.(*http.Transport)
+
+			Expect(transport.MaxIdleConns).To(Equal(50))
+			Expect(transport.MaxIdleConnsPerHost).To(Equal(5))
+			Expect(transport.IdleConnTimeout).To(Equal(60 * time.Second))
+			Expect(transport.TLSHandshakeTimeout).To(Equal(3 * time.Second))
+			Expect(transport.ResponseHeaderTimeout).To(Equal(time.Second))
+			Expect(clients.Client(skipSSL).Timeout).To(Equal(time.Second))
+			Expect(transport.TLSClientConfig.InsecureSkipVerify).To(Equal(skipSSL))
+		}
+	})
+
+	It("times out a request whose response does not come", func() {
+		release := make(chan struct{})
+		hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer hanging.Close()
+		defer close(release)
+
+		settings.RequestTimeout = 50 * time.Millisecond
+		clients = NewClients(settings)
+
+		start := time.Now()
+		_, err := clients.Client(false).Get(hanging.URL)
+
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	It("returns the same client every time", func() {
+		Expect(clients.Client(true)).To(BeIdenticalTo(clients.Client(true)))
+		Expect(clients.Client(false)).To(BeIdenticalTo(clients.Client(false)))
+	})
+
+	It("skips TLS verification with skipSSL", func() {
+		resp, err := clients.Client(true).Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("verifies TLS certificates without skipSSL", func() {
+		_, err := clients.Client(false).Get(server.URL)
+
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,15 @@
+package interfaces
+
+import "github.com/compozed/deployadactyl/structs"
+
+// ArtifactScanner interface.
+type ArtifactScanner interface {
+	Scan(path string, deploymentInfo structs.DeploymentInfo) (ScanResult, error)
+}
+
+// ScanResult is what an ArtifactScanner found in an artifact. Any Findings stop the deploy.
+// Warnings are written to the output of the deploy.
+type ScanResult struct {
+	Findings []string
+	Warnings []string
+}
@@ -2,12 +2,17 @@ package interfaces
 
 import (
 	"bytes"
+	"context"
+	"io"
+
 	"github.com/gin-gonic/gin"
 )
 
 type DeploymentType struct {
 	JSON bool
 	ZIP  bool
+	// Multipart is a multipart/form-data push. Body holds its artifact part and Manifest its manifest part.
+	Multipart bool
 }
 
 type Deployment struct {
@@ -15,6 +20,31 @@ type Deployment struct {
 	Type          DeploymentType
 	Authorization Authorization
 	CFContext     CFContext
+	NoCache       bool
+	UUID          string
+	// ClientIdentity is the common name of the verified client certificate, if one was presented.
+	ClientIdentity string
+	// ChangeTicket is the change ticket ID from the request header, if one was sent.
+	ChangeTicket string
+	// Reason is the reason for the deploy from the request header, if one was sent.
+	Reason string
+	// AllowNameMismatch lets the manifest of a JSON push name a different application than the URL.
+	AllowNameMismatch bool
+	// SkipSSL overrides the skip_ssl of the environment for this deploy when it is set.
+	SkipSSL *bool
+	// Manifest is the manifest part of a multipart push.
+	Manifest string
+	// ArtifactType is the content type of the artifact in the body of a ZIP push, such as application/zip.
+	ArtifactType string
+	// FailurePolicy is the failure policy of a push of every application in the manifest, fail-fast when it is empty.
+	FailurePolicy string
+	// Output, when it is set, is sent the output of a push as it is written to the response.
+	Output io.Writer
+	// StreamOutput, when it is set and Output is not, is called when a push starts to wait for approval;
+	// the output of the push is sent to the writer it returns from then on.
+	StreamOutput func() io.Writer
+	// Context, when it is set, cancels a push that is still running when its deadline passes.
+	Context context.Context
 }
 
 type Authorization struct {
@@ -35,5 +65,35 @@ type Controller interface {
 
 	RunDeploymentViaHttp(g *gin.Context)
 
+	RunShortDeploymentViaHttp(g *gin.Context)
+
+	RunDefaultOrgSpaceDeploymentViaHttp(g *gin.Context)
+
 	PutRequestHandler(g *gin.Context)
+
+	PatchRequestHandler(g *gin.Context)
+
+	DeleteRequestHandler(g *gin.Context)
+
+	EnvironmentConfigHandler(g *gin.Context)
+
+	StatusHandler(g *gin.Context)
+
+	DeploymentStatusHandler(g *gin.Context)
+
+	AdminDrainHandler(g *gin.Context)
+
+	AdminUndrainHandler(g *gin.Context)
+
+	AdminStatusHandler(g *gin.Context)
+
+	AdminReplayHandler(g *gin.Context)
+
+	AdminDiagnosticsHandler(g *gin.Context)
+
+	CancelDeploymentHandler(g *gin.Context)
+
+	BatchDeploymentHandler(g *gin.Context)
+
+	AppLogsHandler(g *gin.Context)
 }
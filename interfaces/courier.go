@@ -1,10 +1,26 @@
 package interfaces
 
+import (
+	"context"
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
 // Courier interface.
 type Courier interface {
 	Login(foundationURL, username, password, org, space string, skipSSL bool) ([]byte, error)
+	Authenticate(foundationURL, username, password string, skipSSL bool) ([]byte, error)
+	Target(org, space string) ([]byte, error)
+	OrgExists(org string) bool
+	SpaceExists(org, space string) bool
+	CreateOrg(org string) ([]byte, error)
+	CreateSpace(org, space string) ([]byte, error)
+	SetSpaceIsolationSegment(space, segment string) ([]byte, error)
 	Delete(appName string) ([]byte, error)
-	Push(appName, appLocation, hostname string, instances uint16) ([]byte, error)
+	DeleteWithRoutes(appName string) ([]byte, error)
+	Push(appName, appLocation, hostname string, instances uint16, command string, buildpacks, flags []string) ([]byte, error)
+	PushWithManifest(appName, appLocation, hostname, manifestFile string, instances uint16, command string, buildpacks, flags []string) ([]byte, error)
 	Rename(oldName, newName string) ([]byte, error)
 	MapRoute(appName, domain, hostname string) ([]byte, error)
 	MapRouteWithPath(appName, domain, hostname, path string) ([]byte, error)
@@ -18,10 +34,26 @@ type Courier interface {
 	Start(appName string) ([]byte, error)
 	Stop(appName string) ([]byte, error)
 	Restage(appName string) ([]byte, error)
+	Restart(appName string) ([]byte, error)
+	Scale(appName, instances, memory string) ([]byte, error)
+	SetEnv(appName, name, value string) ([]byte, error)
+	SetLabels(appName string, labels map[string]string) ([]byte, error)
+	SetFeature(appName, feature string, enabled bool) ([]byte, error)
+	RunTask(appName, command, taskName string) ([]byte, error)
+	TaskState(appName, taskName string) (string, error)
 	Logs(appName string) ([]byte, error)
+	StreamLogs(ctx context.Context, appName string, output io.Writer) error
 	Exists(appName string) bool
+	Routes(appName string) ([]string, error)
+	RouteGUID(domain, hostname string) (string, error)
+	RouteApps(domain, hostname string) ([]string, error)
+	SpaceByGUID(spaceGUID string) (S.Space, error)
+	SetRouteWeights(routeGUID string, weights map[string]int) ([]byte, error)
+	InstanceStates(appName string) ([]string, error)
+	AppGUID(appName string) (string, error)
 	Cups(appName string, body string) ([]byte, error)
 	Uups(appName string, body string) ([]byte, error)
 	Domains() ([]string, error)
+	OrphanedServices(appName string) ([]string, error)
 	CleanUp() error
 }
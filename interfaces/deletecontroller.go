@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"bytes"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type DeleteManagerFactory interface {
+	DeleteManager(log DeploymentLogger, deployEventData structs.DeployEventData, options structs.DeleteOptions) ActionCreator
+}
+
+type DeleteController interface {
+	DeleteDeployment(deployment *Deployment, options structs.DeleteOptions, data map[string]interface{}, response *bytes.Buffer) (deployResponse DeployResponse)
+}
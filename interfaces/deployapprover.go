@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+	"io"
+
+	"github.com/compozed/deployadactyl/structs"
+)
+
+// DeployApprover interface.
+type DeployApprover interface {
+	Approve(ctx context.Context, deployEventData structs.DeployEventData, output io.Writer) error
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/compozed/deployadactyl/structs"
+
+// DeploySigner signs the serialized deploy records sent to the consumers of the deploy events.
+type DeploySigner interface {
+	Sign(payload []byte) structs.Signature
+}
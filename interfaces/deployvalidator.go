@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/compozed/deployadactyl/structs"
+
+// DeployValidator interface.
+type DeployValidator interface {
+	Validate(deployEventData structs.DeployEventData) error
+}
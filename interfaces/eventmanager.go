@@ -4,6 +4,10 @@ type Event struct {
 	Type  string
 	Data  interface{}
 	Error error
+
+	// Replay is true when the event was recorded earlier and is emitted again.
+	// Handlers that must not act twice on the same deploy can ignore replayed events.
+	Replay bool
 }
 
 func (e Event) Name() string {
@@ -21,6 +25,7 @@ type EventManager interface {
 	Emit(event Event) error
 	EmitEvent(event IEvent) error
 	AddBinding(binding Binding)
+	AddNamedBinding(name string, binding Binding)
 }
 
 type IEvent interface {
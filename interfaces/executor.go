@@ -1,8 +1,14 @@
 package interfaces
 
+import (
+	"context"
+	"io"
+)
+
 // Executor interface.
 type Executor interface {
 	Execute(args ...string) ([]byte, error)
 	ExecuteInDirectory(directory string, args ...string) ([]byte, error)
+	Stream(ctx context.Context, output io.Writer, args ...string) error
 	CleanUp() error
 }
@@ -4,3 +4,9 @@ package interfaces
 type Extractor interface {
 	Unzip(source, destination, manifest string) error
 }
+
+// ArtifactExtractor extracts an artifact of one content type, such as application/zip, from source into
+// destination. When manifest is not empty it is written to manifest.yml in destination.
+type ArtifactExtractor interface {
+	Extract(source, destination, manifest string) error
+}
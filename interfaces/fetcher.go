@@ -2,10 +2,14 @@ package interfaces
 
 import (
 	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 // Fetcher interface.
 type Fetcher interface {
 	Fetch(url, manifest string) (string, error)
 	FetchZipFromRequest(body io.Reader) (string, string, error)
+	StageZipFromRequest(body io.Reader) (string, string, error)
+	Metadata() S.ArtifactMetadata
 }
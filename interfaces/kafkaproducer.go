@@ -0,0 +1,7 @@
+package interfaces
+
+// KafkaProducer publishes messages to Kafka topics.
+type KafkaProducer interface {
+	Publish(topic string, key, value []byte) error
+	Close() error
+}
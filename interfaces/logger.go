@@ -34,39 +34,84 @@ func DefaultLogger(out io.Writer, level logging.Level, module string) Logger {
 	return log
 }
 
+// ShortUUIDLength is the number of characters of the deployment UUID used in log prefixes.
+const ShortUUIDLength = 8
+
+// DeploymentPrefix returns the prefix for the log lines of a deployment. It has the short form of the
+// UUID and, when they are given, the environment and application.
+func DeploymentPrefix(uuid, environment, application string) string {
+	if len(uuid) > ShortUUIDLength {
+		uuid = uuid[:ShortUUIDLength]
+	}
+
+	switch {
+	case environment == "" && application == "":
+		return "[" + uuid + "]"
+	case application == "":
+		return "[" + uuid + " " + environment + "]"
+	default:
+		return "[" + uuid + " " + environment + "/" + application + "]"
+	}
+}
+
+// DeploymentLogger writes log lines prefixed with the deployment they belong to, so the lines of
+// concurrent deployments can be told apart.
 type DeploymentLogger struct {
-	Log Logger
+	Log  Logger
 	UUID string
+
+	// Prefix is written before every log message. The UUID is used when it is empty.
+	Prefix string
+
+	// NoPrefix writes log messages without a prefix, for log pipelines that record the UUID as a field.
+	NoPrefix bool
+}
+
+func (l DeploymentLogger) prefix() string {
+	if l.Prefix != "" {
+		return l.Prefix
+	}
+	return l.UUID
+}
+
+func (l DeploymentLogger) prepend(args []interface{}) []interface{} {
+	if l.NoPrefix {
+		return args
+	}
+	return append([]interface{}{l.prefix()}, args...)
+}
+
+func (l DeploymentLogger) format(str string) string {
+	if l.NoPrefix {
+		return str
+	}
+	return l.prefix() + " " + str
 }
 
 func (l DeploymentLogger) Error(args ...interface{}) {
-	args = append([]interface{}{l.UUID}, args...)
-	l.Log.Error(args...)
+	l.Log.Error(l.prepend(args)...)
 }
 
 func (l DeploymentLogger) Errorf(str string, args ...interface{}) {
-	l.Log.Errorf(l.UUID+" "+str, args...)
+	l.Log.Errorf(l.format(str), args...)
 }
 
 func (l DeploymentLogger) Debug(args ...interface{}) {
-	args = append([]interface{}{l.UUID}, args...)
-	l.Log.Debug(args...)
+	l.Log.Debug(l.prepend(args)...)
 }
 
 func (l DeploymentLogger) Debugf(str string, args ...interface{}) {
-	l.Log.Debugf(l.UUID+" "+str, args...)
+	l.Log.Debugf(l.format(str), args...)
 }
 
 func (l DeploymentLogger) Info(args ...interface{}) {
-	args = append([]interface{}{l.UUID}, args...)
-	l.Log.Info(args...)
+	l.Log.Info(l.prepend(args)...)
 }
 
 func (l DeploymentLogger) Infof(str string, args ...interface{}) {
-	l.Log.Infof(l.UUID+" "+str, args...)
+	l.Log.Infof(l.format(str), args...)
 }
 
 func (l DeploymentLogger) Fatal(args ...interface{}) {
-	args = append([]interface{}{l.UUID}, args...)
-	l.Log.Fatal(args...)
+	l.Log.Fatal(l.prepend(args)...)
 }
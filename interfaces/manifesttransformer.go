@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/compozed/deployadactyl/structs"
+
+// ManifestTransformer interface.
+type ManifestTransformer interface {
+	Transform(manifest map[interface{}]interface{}, deploymentInfo structs.DeploymentInfo) (map[interface{}]interface{}, error)
+}
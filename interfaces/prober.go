@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"io"
+	"time"
+)
+
+// Prober interface.
+type Prober interface {
+	Probe(command string, variables map[string]string, timeout time.Duration, out io.Writer) error
+}
@@ -7,8 +7,12 @@ import (
 
 type PushManagerFactory interface {
 	PushManager(log DeploymentLogger, deployEventData structs.DeployEventData, cfContext CFContext, auth Authorization, env structs.Environment, envVars map[string]string) ActionCreator
+	PromoteManager(log DeploymentLogger, deployEventData structs.DeployEventData) (ActionCreator, error)
+	RollbackManager(log DeploymentLogger, deployEventData structs.DeployEventData) (ActionCreator, error)
 }
 
 type PushController interface {
 	RunDeployment(deployment *Deployment, response *bytes.Buffer) (deployResponse DeployResponse)
+	PromoteDeployment(deployment *Deployment, uuid string, response *bytes.Buffer) DeployResponse
+	RollbackDeployment(deployment *Deployment, uuid string, response *bytes.Buffer) DeployResponse
 }
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"bytes"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type RestartManagerFactory interface {
+	RestartManager(log DeploymentLogger, deployEventData structs.DeployEventData, restage bool) ActionCreator
+}
+
+type RestartController interface {
+	RestageDeployment(deployment *Deployment, data map[string]interface{}, response *bytes.Buffer) (deployResponse DeployResponse)
+	RestartDeployment(deployment *Deployment, data map[string]interface{}, response *bytes.Buffer) (deployResponse DeployResponse)
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"bytes"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type ScaleManagerFactory interface {
+	ScaleManager(log DeploymentLogger, deployEventData structs.DeployEventData, spec structs.ScaleSpec) ActionCreator
+}
+
+type ScaleController interface {
+	ScaleDeployment(deployment *Deployment, spec structs.ScaleSpec, data map[string]interface{}, response *bytes.Buffer) (deployResponse DeployResponse)
+}
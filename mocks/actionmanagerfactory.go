@@ -21,6 +21,28 @@ type PushManagerFactory struct {
 			ActionCreator interfaces.ActionCreator
 		}
 	}
+	PromoteManagerCall struct {
+		Called   bool
+		Received struct {
+			Log             interfaces.DeploymentLogger
+			DeployEventData structs.DeployEventData
+		}
+		Returns struct {
+			ActionCreator interfaces.ActionCreator
+			Error         error
+		}
+	}
+	RollbackManagerCall struct {
+		Called   bool
+		Received struct {
+			Log             interfaces.DeploymentLogger
+			DeployEventData structs.DeployEventData
+		}
+		Returns struct {
+			ActionCreator interfaces.ActionCreator
+			Error         error
+		}
+	}
 }
 
 // CreatePusher mock method.
@@ -37,6 +59,22 @@ func (p *PushManagerFactory) PushManager(log interfaces.DeploymentLogger, deploy
 	return p.PushManagerCall.Returns.ActionCreator
 }
 
+func (p *PushManagerFactory) PromoteManager(log interfaces.DeploymentLogger, deployEventData structs.DeployEventData) (interfaces.ActionCreator, error) {
+	p.PromoteManagerCall.Called = true
+	p.PromoteManagerCall.Received.Log = log
+	p.PromoteManagerCall.Received.DeployEventData = deployEventData
+
+	return p.PromoteManagerCall.Returns.ActionCreator, p.PromoteManagerCall.Returns.Error
+}
+
+func (p *PushManagerFactory) RollbackManager(log interfaces.DeploymentLogger, deployEventData structs.DeployEventData) (interfaces.ActionCreator, error) {
+	p.RollbackManagerCall.Called = true
+	p.RollbackManagerCall.Received.Log = log
+	p.RollbackManagerCall.Received.DeployEventData = deployEventData
+
+	return p.RollbackManagerCall.Returns.ActionCreator, p.RollbackManagerCall.Returns.Error
+}
+
 type StopManagerFactory struct {
 	StopManagerCall struct {
 		Called   bool
@@ -78,3 +116,72 @@ func (t *StartManagerFactory) StartManager(log interfaces.DeploymentLogger, Depl
 
 	return t.StartManagerCall.Returns.ActionCreater
 }
+
+type ScaleManagerFactory struct {
+	ScaleManagerCall struct {
+		Called   bool
+		Received struct {
+			Log             interfaces.DeploymentLogger
+			DeployEventData structs.DeployEventData
+			Spec            structs.ScaleSpec
+		}
+		Returns struct {
+			ActionCreater interfaces.ActionCreator
+		}
+	}
+}
+
+func (s *ScaleManagerFactory) ScaleManager(log interfaces.DeploymentLogger, deployEventData structs.DeployEventData, spec structs.ScaleSpec) interfaces.ActionCreator {
+	s.ScaleManagerCall.Called = true
+	s.ScaleManagerCall.Received.Log = log
+	s.ScaleManagerCall.Received.DeployEventData = deployEventData
+	s.ScaleManagerCall.Received.Spec = spec
+
+	return s.ScaleManagerCall.Returns.ActionCreater
+}
+
+type RestartManagerFactory struct {
+	RestartManagerCall struct {
+		Called   bool
+		Received struct {
+			Log             interfaces.DeploymentLogger
+			DeployEventData structs.DeployEventData
+			Restage         bool
+		}
+		Returns struct {
+			ActionCreater interfaces.ActionCreator
+		}
+	}
+}
+
+func (r *RestartManagerFactory) RestartManager(log interfaces.DeploymentLogger, deployEventData structs.DeployEventData, restage bool) interfaces.ActionCreator {
+	r.RestartManagerCall.Called = true
+	r.RestartManagerCall.Received.Log = log
+	r.RestartManagerCall.Received.DeployEventData = deployEventData
+	r.RestartManagerCall.Received.Restage = restage
+
+	return r.RestartManagerCall.Returns.ActionCreater
+}
+
+type DeleteManagerFactory struct {
+	DeleteManagerCall struct {
+		Called   bool
+		Received struct {
+			Log             interfaces.DeploymentLogger
+			DeployEventData structs.DeployEventData
+			Options         structs.DeleteOptions
+		}
+		Returns struct {
+			ActionCreater interfaces.ActionCreator
+		}
+	}
+}
+
+func (d *DeleteManagerFactory) DeleteManager(log interfaces.DeploymentLogger, deployEventData structs.DeployEventData, options structs.DeleteOptions) interfaces.ActionCreator {
+	d.DeleteManagerCall.Called = true
+	d.DeleteManagerCall.Received.Log = log
+	d.DeleteManagerCall.Received.DeployEventData = deployEventData
+	d.DeleteManagerCall.Received.Options = options
+
+	return d.DeleteManagerCall.Returns.ActionCreater
+}
@@ -0,0 +1,27 @@
+package mocks
+
+// ArtifactExtractor handmade mock for tests.
+type ArtifactExtractor struct {
+	ExtractCall struct {
+		Called   int
+		Received struct {
+			Source      string
+			Destination string
+			Manifest    string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+// Extract mock method.
+func (e *ArtifactExtractor) Extract(source, destination, manifest string) error {
+	e.ExtractCall.Called++
+
+	e.ExtractCall.Received.Source = source
+	e.ExtractCall.Received.Destination = destination
+	e.ExtractCall.Received.Manifest = manifest
+
+	return e.ExtractCall.Returns.Error
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// ArtifactScanner handmade mock for tests.
+type ArtifactScanner struct {
+	ScanCall struct {
+		TimesCalled int
+		Received    struct {
+			Path           string
+			DeploymentInfo S.DeploymentInfo
+		}
+		Returns struct {
+			Result I.ScanResult
+			Error  error
+		}
+	}
+}
+
+// Scan mock method.
+func (s *ArtifactScanner) Scan(path string, deploymentInfo S.DeploymentInfo) (I.ScanResult, error) {
+	s.ScanCall.TimesCalled++
+	s.ScanCall.Received.Path = path
+	s.ScanCall.Received.DeploymentInfo = deploymentInfo
+
+	return s.ScanCall.Returns.Result, s.ScanCall.Returns.Error
+}
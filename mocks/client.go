@@ -1,23 +1,72 @@
 package mocks
 
-import "net/http"
+import (
+	"io/ioutil"
+	"net/http"
+)
 
 // Client handmade mock for tests.
 type Client struct {
 	GetCall struct {
-		Received struct {
+		TimesCalled int
+		Received    struct {
 			URL string
 		}
 		Returns struct {
 			Response http.Response
 			Error    error
+
+			// Responses are returned by the first calls, one per call, in place of Response.
+			Responses []http.Response
+		}
+	}
+
+	DoCall struct {
+		TimesCalled int
+		Received    struct {
+			Requests []*http.Request
+		}
+		Returns struct {
+			// Responses and Errors are keyed by the URL of the request followed by its X-CF-APP-INSTANCE header.
+			Responses map[string]http.Response
+			Errors    map[string]error
 		}
 	}
 }
 
 // Get mock method.
 func (c *Client) Get(url string) (*http.Response, error) {
+	defer func() { c.GetCall.TimesCalled++ }()
+
 	c.GetCall.Received.URL = url
 
-	return &c.GetCall.Returns.Response, c.GetCall.Returns.Error
+	if c.GetCall.TimesCalled < len(c.GetCall.Returns.Responses) {
+		return response(c.GetCall.Returns.Responses[c.GetCall.TimesCalled]), c.GetCall.Returns.Error
+	}
+
+	return response(c.GetCall.Returns.Response), c.GetCall.Returns.Error
+}
+
+// Do mock method.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	c.DoCall.TimesCalled++
+	c.DoCall.Received.Requests = append(c.DoCall.Received.Requests, req)
+
+	key := req.URL.String() + " " + req.Header.Get("X-CF-APP-INSTANCE")
+	if err := c.DoCall.Returns.Errors[key]; err != nil {
+		return nil, err
+	}
+
+	return response(c.DoCall.Returns.Responses[key]), nil
+}
+
+// response returns a copy of the response whose body, like the body of a real response, is never nil. Closing the
+// body leaves the body of the response in the mock open, so it can be returned again.
+func response(r http.Response) *http.Response {
+	if r.Body == nil {
+		r.Body = http.NoBody
+	} else {
+		r.Body = ioutil.NopCloser(r.Body)
+	}
+	return &r
 }
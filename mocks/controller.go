@@ -27,12 +27,103 @@ type Controller struct {
 			Context *gin.Context
 		}
 	}
+	RunShortDeploymentViaHttpCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	RunDefaultOrgSpaceDeploymentViaHttpCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
 	PutRequestHandlerCall struct {
 		Called   bool
 		Received struct {
 			Context *gin.Context
 		}
 	}
+	PatchRequestHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	DeleteRequestHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	EnvironmentConfigHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	StatusHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	DeploymentStatusHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	AdminDrainHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	AdminUndrainHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	AdminStatusHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	AdminReplayHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	AdminDiagnosticsHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	CancelDeploymentHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+	BatchDeploymentHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
+
+	AppLogsHandlerCall struct {
+		Called   bool
+		Received struct {
+			Context *gin.Context
+		}
+	}
 }
 
 func (c *Controller) RunDeployment(deployment *I.Deployment, response *bytes.Buffer) I.DeployResponse {
@@ -52,8 +143,98 @@ func (c *Controller) RunDeploymentViaHttp(g *gin.Context) {
 	c.RunDeploymentViaHttpCall.Received.Context = g
 }
 
+func (c *Controller) RunShortDeploymentViaHttp(g *gin.Context) {
+	c.RunShortDeploymentViaHttpCall.Called = true
+
+	c.RunShortDeploymentViaHttpCall.Received.Context = g
+}
+
+func (c *Controller) RunDefaultOrgSpaceDeploymentViaHttp(g *gin.Context) {
+	c.RunDefaultOrgSpaceDeploymentViaHttpCall.Called = true
+
+	c.RunDefaultOrgSpaceDeploymentViaHttpCall.Received.Context = g
+}
+
 func (c *Controller) PutRequestHandler(g *gin.Context) {
 	c.PutRequestHandlerCall.Called = true
 
 	c.PutRequestHandlerCall.Received.Context = g
 }
+
+func (c *Controller) PatchRequestHandler(g *gin.Context) {
+	c.PatchRequestHandlerCall.Called = true
+
+	c.PatchRequestHandlerCall.Received.Context = g
+}
+
+func (c *Controller) DeleteRequestHandler(g *gin.Context) {
+	c.DeleteRequestHandlerCall.Called = true
+
+	c.DeleteRequestHandlerCall.Received.Context = g
+}
+
+func (c *Controller) EnvironmentConfigHandler(g *gin.Context) {
+	c.EnvironmentConfigHandlerCall.Called = true
+
+	c.EnvironmentConfigHandlerCall.Received.Context = g
+}
+
+func (c *Controller) StatusHandler(g *gin.Context) {
+	c.StatusHandlerCall.Called = true
+
+	c.StatusHandlerCall.Received.Context = g
+}
+
+func (c *Controller) AdminDrainHandler(g *gin.Context) {
+	c.AdminDrainHandlerCall.Called = true
+
+	c.AdminDrainHandlerCall.Received.Context = g
+}
+
+func (c *Controller) AdminUndrainHandler(g *gin.Context) {
+	c.AdminUndrainHandlerCall.Called = true
+
+	c.AdminUndrainHandlerCall.Received.Context = g
+}
+
+func (c *Controller) AdminStatusHandler(g *gin.Context) {
+	c.AdminStatusHandlerCall.Called = true
+
+	c.AdminStatusHandlerCall.Received.Context = g
+}
+
+func (c *Controller) DeploymentStatusHandler(g *gin.Context) {
+	c.DeploymentStatusHandlerCall.Called = true
+
+	c.DeploymentStatusHandlerCall.Received.Context = g
+}
+
+func (c *Controller) AdminReplayHandler(g *gin.Context) {
+	c.AdminReplayHandlerCall.Called = true
+
+	c.AdminReplayHandlerCall.Received.Context = g
+}
+
+func (c *Controller) AdminDiagnosticsHandler(g *gin.Context) {
+	c.AdminDiagnosticsHandlerCall.Called = true
+
+	c.AdminDiagnosticsHandlerCall.Received.Context = g
+}
+
+func (c *Controller) CancelDeploymentHandler(g *gin.Context) {
+	c.CancelDeploymentHandlerCall.Called = true
+
+	c.CancelDeploymentHandlerCall.Received.Context = g
+}
+
+func (c *Controller) BatchDeploymentHandler(g *gin.Context) {
+	c.BatchDeploymentHandlerCall.Called = true
+
+	c.BatchDeploymentHandlerCall.Received.Context = g
+}
+
+func (c *Controller) AppLogsHandler(g *gin.Context) {
+	c.AppLogsHandlerCall.Called = true
+
+	c.AppLogsHandlerCall.Received.Context = g
+}
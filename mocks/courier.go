@@ -1,10 +1,19 @@
 package mocks
 
+import (
+	"context"
+	"io"
+	"time"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
 // Courier handmade mock for tests.
 type Courier struct {
 	TimesCourierCalled int
 	LoginCall          struct {
-		Received struct {
+		TimesCalled int
+		Received    struct {
 			FoundationURL string
 			Username      string
 			Password      string
@@ -18,6 +27,210 @@ type Courier struct {
 		}
 	}
 
+	AuthenticateCall struct {
+		Received struct {
+			FoundationURL string
+			Username      string
+			Password      string
+			SkipSSL       bool
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	TargetCall struct {
+		TimesCalled int
+		Received    struct {
+			Org   string
+			Space string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	OrgExistsCall struct {
+		Received struct {
+			Org string
+		}
+		Returns struct {
+			Bool bool
+		}
+	}
+
+	SpaceExistsCall struct {
+		Received struct {
+			Org   string
+			Space string
+		}
+		Returns struct {
+			Bool bool
+		}
+	}
+
+	CreateOrgCall struct {
+		TimesCalled int
+		Received    struct {
+			Org string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	CreateSpaceCall struct {
+		TimesCalled int
+		Received    struct {
+			Org   string
+			Space string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	SetSpaceIsolationSegmentCall struct {
+		TimesCalled int
+		Received    struct {
+			Space   string
+			Segment string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	SetLabelsCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName string
+			Labels  map[string]string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	SetFeatureCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName  []string
+			Features []string
+			Enabled  []bool
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	RunTaskCall struct {
+		Received struct {
+			AppName  string
+			Command  string
+			TaskName string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	TaskStateCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName  string
+			TaskName string
+		}
+		Returns struct {
+			States []string
+			Error  error
+		}
+	}
+
+	RoutesCall struct {
+		Received struct {
+			AppName string
+		}
+		Returns struct {
+			Routes []string
+			Error  error
+		}
+	}
+
+	InstanceStatesCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName string
+		}
+		Returns struct {
+			States [][]string
+			Error  error
+		}
+	}
+
+	AppGUIDCall struct {
+		Received struct {
+			AppName string
+		}
+		Returns struct {
+			GUID  string
+			GUIDs map[string]string
+			Error error
+		}
+	}
+
+	RouteGUIDCall struct {
+		Received struct {
+			Domain   string
+			Hostname string
+		}
+		Returns struct {
+			GUID  string
+			Error error
+		}
+	}
+
+	RouteAppsCall struct {
+		Received struct {
+			Domain   string
+			Hostname string
+		}
+		Returns struct {
+			Apps  []string
+			Error error
+		}
+	}
+
+	SpaceByGUIDCall struct {
+		Received struct {
+			SpaceGUID string
+		}
+		Returns struct {
+			Space S.Space
+			Error error
+		}
+	}
+
+	SetRouteWeightsCall struct {
+		TimesCalled int
+		Received    struct {
+			RouteGUID []string
+			Weights   []map[string]int
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
 	StartCall struct {
 		Received struct {
 			AppName string
@@ -28,6 +241,51 @@ type Courier struct {
 		}
 	}
 
+	RestartCall struct {
+		Received struct {
+			AppName string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	RestageCall struct {
+		Received struct {
+			AppName string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	ScaleCall struct {
+		Received struct {
+			AppName   string
+			Instances string
+			Memory    string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	SetEnvCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName string
+			Names   []string
+			Values  []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
 	StopCall struct {
 		Received struct {
 			AppName string
@@ -48,12 +306,19 @@ type Courier struct {
 		}
 	}
 
-	PushCall struct {
+	DeleteWithRoutesCall struct {
 		Received struct {
-			AppName   string
-			AppPath   string
-			Hostname  string
-			Instances uint16
+			AppName string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	DeleteServiceCall struct {
+		Received struct {
+			ServiceNames []string
 		}
 		Returns struct {
 			Output []byte
@@ -61,6 +326,57 @@ type Courier struct {
 		}
 	}
 
+	OrphanedServicesCall struct {
+		Received struct {
+			AppName string
+		}
+		Returns struct {
+			Services []string
+			Error    error
+		}
+	}
+
+	PushCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName    string
+			AppPath    string
+			Hostname   string
+			Instances  uint16
+			Command    string
+			Buildpacks []string
+			Flags      []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+
+			// Outputs and Errors are returned by the first calls, one per call, in place of Output and Error.
+			Outputs [][]byte
+			Errors  []error
+		}
+		// Delay is how long Push takes before it returns.
+		Delay time.Duration
+	}
+
+	PushWithManifestCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName      []string
+			AppPath      []string
+			Hostname     []string
+			ManifestFile []string
+			Instances    []uint16
+			Command      []string
+			Buildpacks   [][]string
+			Flags        [][]string
+		}
+		Returns struct {
+			Output []byte
+			Error  map[string]error
+		}
+	}
+
 	RenameCall struct {
 		Received struct {
 			AppName          string
@@ -70,6 +386,8 @@ type Courier struct {
 			Output []byte
 			Error  error
 		}
+		// Delay is how long Rename takes before it returns.
+		Delay time.Duration
 	}
 
 	LogsCall struct {
@@ -82,6 +400,19 @@ type Courier struct {
 		}
 	}
 
+	StreamLogsCall struct {
+		Received struct {
+			Context context.Context
+			AppName string
+		}
+		Write struct {
+			Output string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
 	MapRouteWithPathCall struct {
 		TimesCalled int
 		Received    struct {
@@ -185,6 +516,8 @@ type Courier struct {
 
 // Login mock method.
 func (c *Courier) Login(foundationURL, username, password, org, space string, skipSSL bool) ([]byte, error) {
+	defer func() { c.LoginCall.TimesCalled++ }()
+
 	c.LoginCall.Received.FoundationURL = foundationURL
 	c.LoginCall.Received.Username = username
 	c.LoginCall.Received.Password = password
@@ -195,12 +528,141 @@ func (c *Courier) Login(foundationURL, username, password, org, space string, sk
 	return c.LoginCall.Returns.Output, c.LoginCall.Returns.Error
 }
 
+// Authenticate mock method.
+func (c *Courier) Authenticate(foundationURL, username, password string, skipSSL bool) ([]byte, error) {
+	c.AuthenticateCall.Received.FoundationURL = foundationURL
+	c.AuthenticateCall.Received.Username = username
+	c.AuthenticateCall.Received.Password = password
+	c.AuthenticateCall.Received.SkipSSL = skipSSL
+
+	return c.AuthenticateCall.Returns.Output, c.AuthenticateCall.Returns.Error
+}
+
+// Target mock method.
+func (c *Courier) Target(org, space string) ([]byte, error) {
+	c.TargetCall.TimesCalled++
+	c.TargetCall.Received.Org = org
+	c.TargetCall.Received.Space = space
+
+	return c.TargetCall.Returns.Output, c.TargetCall.Returns.Error
+}
+
+// OrgExists mock method.
+func (c *Courier) OrgExists(org string) bool {
+	c.OrgExistsCall.Received.Org = org
+
+	return c.OrgExistsCall.Returns.Bool
+}
+
+// SpaceExists mock method.
+func (c *Courier) SpaceExists(org, space string) bool {
+	c.SpaceExistsCall.Received.Org = org
+	c.SpaceExistsCall.Received.Space = space
+
+	return c.SpaceExistsCall.Returns.Bool
+}
+
+// CreateOrg mock method.
+func (c *Courier) CreateOrg(org string) ([]byte, error) {
+	c.CreateOrgCall.TimesCalled++
+	c.CreateOrgCall.Received.Org = org
+
+	return c.CreateOrgCall.Returns.Output, c.CreateOrgCall.Returns.Error
+}
+
+// CreateSpace mock method.
+func (c *Courier) CreateSpace(org, space string) ([]byte, error) {
+	c.CreateSpaceCall.TimesCalled++
+	c.CreateSpaceCall.Received.Org = org
+	c.CreateSpaceCall.Received.Space = space
+
+	return c.CreateSpaceCall.Returns.Output, c.CreateSpaceCall.Returns.Error
+}
+
+// SetSpaceIsolationSegment mock method.
+func (c *Courier) SetSpaceIsolationSegment(space, segment string) ([]byte, error) {
+	c.SetSpaceIsolationSegmentCall.TimesCalled++
+	c.SetSpaceIsolationSegmentCall.Received.Space = space
+	c.SetSpaceIsolationSegmentCall.Received.Segment = segment
+
+	return c.SetSpaceIsolationSegmentCall.Returns.Output, c.SetSpaceIsolationSegmentCall.Returns.Error
+}
+
+// SetLabels mock method.
+func (c *Courier) SetLabels(appName string, labels map[string]string) ([]byte, error) {
+	c.SetLabelsCall.TimesCalled++
+	c.SetLabelsCall.Received.AppName = appName
+	c.SetLabelsCall.Received.Labels = labels
+
+	return c.SetLabelsCall.Returns.Output, c.SetLabelsCall.Returns.Error
+}
+
+// SetFeature mock method.
+func (c *Courier) SetFeature(appName, feature string, enabled bool) ([]byte, error) {
+	c.SetFeatureCall.TimesCalled++
+	c.SetFeatureCall.Received.AppName = append(c.SetFeatureCall.Received.AppName, appName)
+	c.SetFeatureCall.Received.Features = append(c.SetFeatureCall.Received.Features, feature)
+	c.SetFeatureCall.Received.Enabled = append(c.SetFeatureCall.Received.Enabled, enabled)
+
+	return c.SetFeatureCall.Returns.Output, c.SetFeatureCall.Returns.Error
+}
+
+// RunTask mock method.
+func (c *Courier) RunTask(appName, command, taskName string) ([]byte, error) {
+	c.RunTaskCall.Received.AppName = appName
+	c.RunTaskCall.Received.Command = command
+	c.RunTaskCall.Received.TaskName = taskName
+
+	return c.RunTaskCall.Returns.Output, c.RunTaskCall.Returns.Error
+}
+
+// TaskState mock method. Returns the next state in States, repeating the last one.
+func (c *Courier) TaskState(appName, taskName string) (string, error) {
+	defer func() { c.TaskStateCall.TimesCalled++ }()
+
+	c.TaskStateCall.Received.AppName = appName
+	c.TaskStateCall.Received.TaskName = taskName
+
+	states := c.TaskStateCall.Returns.States
+	if len(states) == 0 {
+		return "", c.TaskStateCall.Returns.Error
+	}
+	if c.TaskStateCall.TimesCalled < len(states) {
+		return states[c.TaskStateCall.TimesCalled], c.TaskStateCall.Returns.Error
+	}
+
+	return states[len(states)-1], c.TaskStateCall.Returns.Error
+}
+
 func (c *Courier) Start(appName string) ([]byte, error) {
 	c.StartCall.Received.AppName = appName
 
 	return c.StartCall.Returns.Output, c.StartCall.Returns.Error
 }
 
+func (c *Courier) Restart(appName string) ([]byte, error) {
+	c.RestartCall.Received.AppName = appName
+
+	return c.RestartCall.Returns.Output, c.RestartCall.Returns.Error
+}
+
+func (c *Courier) Scale(appName, instances, memory string) ([]byte, error) {
+	c.ScaleCall.Received.AppName = appName
+	c.ScaleCall.Received.Instances = instances
+	c.ScaleCall.Received.Memory = memory
+
+	return c.ScaleCall.Returns.Output, c.ScaleCall.Returns.Error
+}
+
+func (c *Courier) SetEnv(appName, name, value string) ([]byte, error) {
+	c.SetEnvCall.TimesCalled++
+	c.SetEnvCall.Received.AppName = appName
+	c.SetEnvCall.Received.Names = append(c.SetEnvCall.Received.Names, name)
+	c.SetEnvCall.Received.Values = append(c.SetEnvCall.Received.Values, value)
+
+	return c.SetEnvCall.Returns.Output, c.SetEnvCall.Returns.Error
+}
+
 func (c *Courier) Stop(appName string) ([]byte, error) {
 	c.StopCall.Received.AppName = appName
 
@@ -214,21 +676,68 @@ func (c *Courier) Delete(appName string) ([]byte, error) {
 	return c.DeleteCall.Returns.Output, c.DeleteCall.Returns.Error
 }
 
+// DeleteWithRoutes mock method.
+func (c *Courier) DeleteWithRoutes(appName string) ([]byte, error) {
+	c.DeleteWithRoutesCall.Received.AppName = appName
+
+	return c.DeleteWithRoutesCall.Returns.Output, c.DeleteWithRoutesCall.Returns.Error
+}
+
+// OrphanedServices mock method.
+func (c *Courier) OrphanedServices(appName string) ([]string, error) {
+	c.OrphanedServicesCall.Received.AppName = appName
+
+	return c.OrphanedServicesCall.Returns.Services, c.OrphanedServicesCall.Returns.Error
+}
+
 // Push mock method.
-func (c *Courier) Push(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
+func (c *Courier) Push(appName, appLocation, hostname string, instances uint16, command string, buildpacks, flags []string) ([]byte, error) {
 	c.PushCall.Received.AppName = appName
 	c.PushCall.Received.AppPath = appLocation
 	c.PushCall.Received.Hostname = hostname
 	c.PushCall.Received.Instances = instances
+	c.PushCall.Received.Command = command
+	c.PushCall.Received.Buildpacks = buildpacks
+	c.PushCall.Received.Flags = flags
+
+	if c.PushCall.Delay > 0 {
+		time.Sleep(c.PushCall.Delay)
+	}
+
+	defer func() { c.PushCall.TimesCalled++ }()
+
+	if c.PushCall.TimesCalled < len(c.PushCall.Returns.Errors) {
+		return c.PushCall.Returns.Outputs[c.PushCall.TimesCalled], c.PushCall.Returns.Errors[c.PushCall.TimesCalled]
+	}
 
 	return c.PushCall.Returns.Output, c.PushCall.Returns.Error
 }
 
+// PushWithManifest mock method. Returns the error for the hostname in Returns.Error, if there is one.
+func (c *Courier) PushWithManifest(appName, appLocation, hostname, manifestFile string, instances uint16, command string, buildpacks, flags []string) ([]byte, error) {
+	defer func() { c.PushWithManifestCall.TimesCalled++ }()
+
+	c.PushWithManifestCall.Received.AppName = append(c.PushWithManifestCall.Received.AppName, appName)
+	c.PushWithManifestCall.Received.AppPath = append(c.PushWithManifestCall.Received.AppPath, appLocation)
+	c.PushWithManifestCall.Received.Hostname = append(c.PushWithManifestCall.Received.Hostname, hostname)
+	c.PushWithManifestCall.Received.ManifestFile = append(c.PushWithManifestCall.Received.ManifestFile, manifestFile)
+	c.PushWithManifestCall.Received.Instances = append(c.PushWithManifestCall.Received.Instances, instances)
+	c.PushWithManifestCall.Received.Command = append(c.PushWithManifestCall.Received.Command, command)
+	c.PushWithManifestCall.Received.Buildpacks = append(c.PushWithManifestCall.Received.Buildpacks, buildpacks)
+	c.PushWithManifestCall.Received.Flags = append(c.PushWithManifestCall.Received.Flags, flags)
+
+	return c.PushWithManifestCall.Returns.Output, c.PushWithManifestCall.Returns.Error[hostname]
+}
+
 // Rename mock method.
 func (c *Courier) Rename(appName, newAppName string) ([]byte, error) {
 	c.RenameCall.Received.AppName = appName
 	c.RenameCall.Received.AppNameVenerable = newAppName
 
+	if c.RenameCall.Delay > 0 {
+		time.Sleep(c.RenameCall.Delay)
+	}
+
 	return c.RenameCall.Returns.Output, c.RenameCall.Returns.Error
 }
 
@@ -305,6 +814,83 @@ func (c *Courier) Logs(appName string) ([]byte, error) {
 	return c.LogsCall.Returns.Output, c.LogsCall.Returns.Error
 }
 
+// StreamLogs mock method.
+func (c *Courier) StreamLogs(ctx context.Context, appName string, output io.Writer) error {
+	c.StreamLogsCall.Received.Context = ctx
+	c.StreamLogsCall.Received.AppName = appName
+
+	io.WriteString(output, c.StreamLogsCall.Write.Output)
+
+	return c.StreamLogsCall.Returns.Error
+}
+
+// Routes mock method.
+func (c *Courier) Routes(appName string) ([]string, error) {
+	c.RoutesCall.Received.AppName = appName
+
+	return c.RoutesCall.Returns.Routes, c.RoutesCall.Returns.Error
+}
+
+// InstanceStates mock method. Returns the next states in States, repeating the last ones.
+func (c *Courier) InstanceStates(appName string) ([]string, error) {
+	defer func() { c.InstanceStatesCall.TimesCalled++ }()
+
+	c.InstanceStatesCall.Received.AppName = appName
+
+	states := c.InstanceStatesCall.Returns.States
+	if len(states) == 0 {
+		return nil, c.InstanceStatesCall.Returns.Error
+	}
+	if c.InstanceStatesCall.TimesCalled < len(states) {
+		return states[c.InstanceStatesCall.TimesCalled], c.InstanceStatesCall.Returns.Error
+	}
+
+	return states[len(states)-1], c.InstanceStatesCall.Returns.Error
+}
+
+// AppGUID mock method. Returns the GUID for the application in Returns.GUIDs, if there is one.
+func (c *Courier) AppGUID(appName string) (string, error) {
+	c.AppGUIDCall.Received.AppName = appName
+
+	if guid, ok := c.AppGUIDCall.Returns.GUIDs[appName]; ok {
+		return guid, c.AppGUIDCall.Returns.Error
+	}
+
+	return c.AppGUIDCall.Returns.GUID, c.AppGUIDCall.Returns.Error
+}
+
+// RouteGUID mock method.
+func (c *Courier) RouteGUID(domain, hostname string) (string, error) {
+	c.RouteGUIDCall.Received.Domain = domain
+	c.RouteGUIDCall.Received.Hostname = hostname
+
+	return c.RouteGUIDCall.Returns.GUID, c.RouteGUIDCall.Returns.Error
+}
+
+// RouteApps mock method.
+func (c *Courier) RouteApps(domain, hostname string) ([]string, error) {
+	c.RouteAppsCall.Received.Domain = domain
+	c.RouteAppsCall.Received.Hostname = hostname
+
+	return c.RouteAppsCall.Returns.Apps, c.RouteAppsCall.Returns.Error
+}
+
+// SpaceByGUID mock method.
+func (c *Courier) SpaceByGUID(spaceGUID string) (S.Space, error) {
+	c.SpaceByGUIDCall.Received.SpaceGUID = spaceGUID
+
+	return c.SpaceByGUIDCall.Returns.Space, c.SpaceByGUIDCall.Returns.Error
+}
+
+// SetRouteWeights mock method.
+func (c *Courier) SetRouteWeights(routeGUID string, weights map[string]int) ([]byte, error) {
+	c.SetRouteWeightsCall.TimesCalled++
+	c.SetRouteWeightsCall.Received.RouteGUID = append(c.SetRouteWeightsCall.Received.RouteGUID, routeGUID)
+	c.SetRouteWeightsCall.Received.Weights = append(c.SetRouteWeightsCall.Received.Weights, weights)
+
+	return c.SetRouteWeightsCall.Returns.Output, c.SetRouteWeightsCall.Returns.Error
+}
+
 // Exists mock method.
 func (c *Courier) Exists(appName string) bool {
 	c.ExistsCall.Received.AppName = appName
@@ -348,11 +934,15 @@ func (c *Courier) UnbindService(appName, serviceName string) ([]byte, error) {
 }
 
 func (c *Courier) DeleteService(serviceName string) ([]byte, error) {
-	panic("Mock not implemented.")
+	c.DeleteServiceCall.Received.ServiceNames = append(c.DeleteServiceCall.Received.ServiceNames, serviceName)
+
+	return c.DeleteServiceCall.Returns.Output, c.DeleteServiceCall.Returns.Error
 }
 
 func (c *Courier) Restage(appName string) ([]byte, error) {
-	panic("Mock not implemented.")
+	c.RestageCall.Received.AppName = appName
+
+	return c.RestageCall.Returns.Output, c.RestageCall.Returns.Error
 }
 
 // CleanUp mock method.
@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"bytes"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/structs"
+	"time"
+)
+
+type DeleteController struct {
+	DeleteDeploymentCall struct {
+		Received struct {
+			Deployment *interfaces.Deployment
+			Options    structs.DeleteOptions
+			Data       map[string]interface{}
+			Response   *bytes.Buffer
+			// Applications are the applications of every call, in order.
+			Applications []string
+		}
+		Returns struct {
+			DeployResponse interfaces.DeployResponse
+		}
+		Writes string
+		Called bool
+		// Delay is how long DeleteDeployment takes before it returns.
+		Delay time.Duration
+	}
+}
+
+func (c *DeleteController) DeleteDeployment(deployment *interfaces.Deployment, options structs.DeleteOptions, data map[string]interface{}, response *bytes.Buffer) (deployResponse interfaces.DeployResponse) {
+	c.DeleteDeploymentCall.Called = true
+	c.DeleteDeploymentCall.Received.Deployment = deployment
+	c.DeleteDeploymentCall.Received.Options = options
+	c.DeleteDeploymentCall.Received.Data = data
+	c.DeleteDeploymentCall.Received.Response = response
+	c.DeleteDeploymentCall.Received.Applications = append(c.DeleteDeploymentCall.Received.Applications, deployment.CFContext.Application)
+
+	if c.DeleteDeploymentCall.Writes != "" {
+		response.Write([]byte(c.DeleteDeploymentCall.Writes))
+	}
+
+	if c.DeleteDeploymentCall.Delay > 0 {
+		time.Sleep(c.DeleteDeploymentCall.Delay)
+	}
+
+	return c.DeleteDeploymentCall.Returns.DeployResponse
+}
@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"context"
+	"io"
+
+	"github.com/compozed/deployadactyl/structs"
+)
+
+// DeployApprover handmade mock for tests.
+type DeployApprover struct {
+	ApproveCall struct {
+		TimesCalled int
+		Received    struct {
+			Context         context.Context
+			DeployEventData structs.DeployEventData
+		}
+		Write struct {
+			Output string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+// Approve mock method.
+func (a *DeployApprover) Approve(ctx context.Context, deployEventData structs.DeployEventData, output io.Writer) error {
+	a.ApproveCall.TimesCalled++
+	a.ApproveCall.Received.Context = ctx
+	a.ApproveCall.Received.DeployEventData = deployEventData
+
+	io.WriteString(output, a.ApproveCall.Write.Output)
+
+	return a.ApproveCall.Returns.Error
+}
@@ -0,0 +1,24 @@
+package mocks
+
+import "github.com/compozed/deployadactyl/structs"
+
+// DeployValidator handmade mock for tests.
+type DeployValidator struct {
+	ValidateCall struct {
+		TimesCalled int
+		Received    struct {
+			DeployEventData structs.DeployEventData
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+// Validate mock method.
+func (v *DeployValidator) Validate(deployEventData structs.DeployEventData) error {
+	v.ValidateCall.TimesCalled++
+	v.ValidateCall.Received.DeployEventData = deployEventData
+
+	return v.ValidateCall.Returns.Error
+}
@@ -69,3 +69,5 @@ func (e *EventManager) EmitEvent(event I.IEvent) error {
 }
 
 func (e *EventManager) AddBinding(binding I.Binding) {}
+
+func (e *EventManager) AddNamedBinding(name string, binding I.Binding) {}
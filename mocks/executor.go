@@ -1,14 +1,24 @@
 package mocks
 
+import (
+	"context"
+	"io"
+)
+
 // Executor handmade mock for tests.
 type Executor struct {
 	ExecuteCall struct {
-		Received struct {
+		TimesCalled int
+		Received    struct {
 			Args []string
+			// AllArgs are the arguments of every call, in order.
+			AllArgs [][]string
 		}
 		Returns struct {
 			Output []byte
 			Error  error
+			// Outputs are returned in order by successive calls, before Output is used.
+			Outputs [][]byte
 		}
 	}
 
@@ -23,6 +33,19 @@ type Executor struct {
 		}
 	}
 
+	StreamCall struct {
+		Received struct {
+			Context context.Context
+			Args    []string
+		}
+		Write struct {
+			Output string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
 	CleanUpCall struct {
 		Returns struct {
 			Error error
@@ -32,7 +55,14 @@ type Executor struct {
 
 // Execute mock method.
 func (e *Executor) Execute(args ...string) ([]byte, error) {
+	defer func() { e.ExecuteCall.TimesCalled++ }()
+
 	e.ExecuteCall.Received.Args = args
+	e.ExecuteCall.Received.AllArgs = append(e.ExecuteCall.Received.AllArgs, args)
+
+	if len(e.ExecuteCall.Returns.Outputs) > e.ExecuteCall.TimesCalled {
+		return e.ExecuteCall.Returns.Outputs[e.ExecuteCall.TimesCalled], e.ExecuteCall.Returns.Error
+	}
 
 	return e.ExecuteCall.Returns.Output, e.ExecuteCall.Returns.Error
 }
@@ -45,6 +75,16 @@ func (e *Executor) ExecuteInDirectory(appLocation string, args ...string) ([]byt
 	return e.ExecuteInDirectoryCall.Returns.Output, e.ExecuteInDirectoryCall.Returns.Error
 }
 
+// Stream mock method.
+func (e *Executor) Stream(ctx context.Context, output io.Writer, args ...string) error {
+	e.StreamCall.Received.Context = ctx
+	e.StreamCall.Received.Args = args
+
+	io.WriteString(output, e.StreamCall.Write.Output)
+
+	return e.StreamCall.Returns.Error
+}
+
 // CleanUp mock method.
 func (e *Executor) CleanUp() error {
 	return e.CleanUpCall.Returns.Error
@@ -2,6 +2,8 @@ package mocks
 
 import (
 	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 // Fetcher handmade mock for tests.
@@ -27,6 +29,23 @@ type Fetcher struct {
 			Error   error
 		}
 	}
+
+	StageZipCall struct {
+		Received struct {
+			Request io.Reader
+		}
+		Returns struct {
+			AppPath  string
+			Manifest string
+			Error    error
+		}
+	}
+
+	MetadataCall struct {
+		Returns struct {
+			Metadata S.ArtifactMetadata
+		}
+	}
 }
 
 // Fetch mock method.
@@ -43,3 +62,15 @@ func (f *Fetcher) FetchZipFromRequest(body io.Reader) (string, string, error) {
 
 	return f.FetchFromZipCall.Returns.AppPath, f.FetchFromZipCall.Returns.Manifest, f.FetchFromZipCall.Returns.Error
 }
+
+// StageZipFromRequest mock method.
+func (f *Fetcher) StageZipFromRequest(body io.Reader) (string, string, error) {
+	f.StageZipCall.Received.Request = body
+
+	return f.StageZipCall.Returns.AppPath, f.StageZipCall.Returns.Manifest, f.StageZipCall.Returns.Error
+}
+
+// Metadata mock method.
+func (f *Fetcher) Metadata() S.ArtifactMetadata {
+	return f.MetadataCall.Returns.Metadata
+}
@@ -0,0 +1,44 @@
+package mocks
+
+// KafkaProducer handmade mock for tests.
+type KafkaProducer struct {
+	PublishCall struct {
+		TimesCalled int
+		Received    struct {
+			Topic string
+			Key   []byte
+			Value []byte
+		}
+		Returns struct {
+			Errors []error
+		}
+	}
+	CloseCall struct {
+		Called  bool
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+// Publish mock method.
+func (k *KafkaProducer) Publish(topic string, key, value []byte) error {
+	defer func() { k.PublishCall.TimesCalled++ }()
+
+	k.PublishCall.Received.Topic = topic
+	k.PublishCall.Received.Key = key
+	k.PublishCall.Received.Value = value
+
+	if len(k.PublishCall.Returns.Errors) > k.PublishCall.TimesCalled {
+		return k.PublishCall.Returns.Errors[k.PublishCall.TimesCalled]
+	}
+
+	return nil
+}
+
+// Close mock method.
+func (k *KafkaProducer) Close() error {
+	k.CloseCall.Called = true
+
+	return k.CloseCall.Returns.Error
+}
@@ -0,0 +1,27 @@
+package mocks
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// ManifestTransformer handmade mock for tests.
+type ManifestTransformer struct {
+	TransformCall struct {
+		TimesCalled int
+		Received    struct {
+			Manifest       map[interface{}]interface{}
+			DeploymentInfo S.DeploymentInfo
+		}
+		Returns struct {
+			Manifest map[interface{}]interface{}
+			Error    error
+		}
+	}
+}
+
+// Transform mock method.
+func (t *ManifestTransformer) Transform(manifest map[interface{}]interface{}, deploymentInfo S.DeploymentInfo) (map[interface{}]interface{}, error) {
+	t.TransformCall.TimesCalled++
+	t.TransformCall.Received.Manifest = manifest
+	t.TransformCall.Received.DeploymentInfo = deploymentInfo
+
+	return t.TransformCall.Returns.Manifest, t.TransformCall.Returns.Error
+}
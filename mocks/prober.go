@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"io"
+	"time"
+)
+
+// Prober handmade mock for tests.
+type Prober struct {
+	ProbeCall struct {
+		TimesCalled int
+		Received    struct {
+			Command   string
+			Variables map[string]string
+			Timeout   time.Duration
+		}
+		Write struct {
+			Output string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+// Probe mock method.
+func (p *Prober) Probe(command string, variables map[string]string, timeout time.Duration, out io.Writer) error {
+	p.ProbeCall.TimesCalled++
+	p.ProbeCall.Received.Command = command
+	p.ProbeCall.Received.Variables = variables
+	p.ProbeCall.Received.Timeout = timeout
+
+	io.WriteString(out, p.ProbeCall.Write.Output)
+
+	return p.ProbeCall.Returns.Error
+}
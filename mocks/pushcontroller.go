@@ -2,31 +2,106 @@ package mocks
 
 import (
 	"bytes"
+	"time"
+
 	"github.com/compozed/deployadactyl/interfaces"
 )
 
 type PushController struct {
 	RunDeploymentCall struct {
 		Received struct {
-			Deployment *interfaces.Deployment
-			Response   *bytes.Buffer
+			Deployment   *interfaces.Deployment
+			Response     *bytes.Buffer
+			Applications []string
+		}
+		Returns struct {
+			DeployResponse interfaces.DeployResponse
+			// DeployResponses are returned in place of DeployResponse for the applications they name.
+			DeployResponses map[string]interfaces.DeployResponse
+		}
+		Writes      string
+		Called      bool
+		TimesCalled int
+		// Delay is how long RunDeployment takes before it returns.
+		Delay time.Duration
+	}
+	PromoteDeploymentCall struct {
+		Received struct {
+			Deployment   *interfaces.Deployment
+			UUID         string
+			Response     *bytes.Buffer
+			Applications []string
+		}
+		Returns struct {
+			DeployResponse interfaces.DeployResponse
+			// DeployResponses are returned in place of DeployResponse for the applications they name.
+			DeployResponses map[string]interfaces.DeployResponse
+		}
+		Called      bool
+		TimesCalled int
+	}
+	RollbackDeploymentCall struct {
+		Received struct {
+			Deployment   *interfaces.Deployment
+			UUID         string
+			Response     *bytes.Buffer
+			Applications []string
 		}
 		Returns struct {
 			DeployResponse interfaces.DeployResponse
 		}
-		Writes string
-		Called bool
+		Called      bool
+		TimesCalled int
 	}
 }
 
 func (c *PushController) RunDeployment(deployment *interfaces.Deployment, response *bytes.Buffer) (deployResponse interfaces.DeployResponse) {
 	c.RunDeploymentCall.Called = true
+	c.RunDeploymentCall.TimesCalled++
 	c.RunDeploymentCall.Received.Deployment = deployment
 	c.RunDeploymentCall.Received.Response = response
+	c.RunDeploymentCall.Received.Applications = append(c.RunDeploymentCall.Received.Applications, deployment.CFContext.Application)
 
 	if c.RunDeploymentCall.Writes != "" {
 		response.Write([]byte(c.RunDeploymentCall.Writes))
+		if deployment.Output != nil {
+			deployment.Output.Write([]byte(c.RunDeploymentCall.Writes))
+		}
+	}
+
+	if c.RunDeploymentCall.Delay > 0 {
+		time.Sleep(c.RunDeploymentCall.Delay)
+	}
+
+	if deployResponse, ok := c.RunDeploymentCall.Returns.DeployResponses[deployment.CFContext.Application]; ok {
+		return deployResponse
 	}
 
 	return c.RunDeploymentCall.Returns.DeployResponse
 }
+
+func (c *PushController) PromoteDeployment(deployment *interfaces.Deployment, uuid string, response *bytes.Buffer) interfaces.DeployResponse {
+	c.PromoteDeploymentCall.Called = true
+	c.PromoteDeploymentCall.TimesCalled++
+	c.PromoteDeploymentCall.Received.Deployment = deployment
+	c.PromoteDeploymentCall.Received.UUID = uuid
+	c.PromoteDeploymentCall.Received.Response = response
+	c.PromoteDeploymentCall.Received.Applications = append(c.PromoteDeploymentCall.Received.Applications, deployment.CFContext.Application)
+
+	if deployResponse, ok := c.PromoteDeploymentCall.Returns.DeployResponses[deployment.CFContext.Application]; ok {
+		return deployResponse
+	}
+
+	return c.PromoteDeploymentCall.Returns.DeployResponse
+}
+
+func (c *PushController) RollbackDeployment(deployment *interfaces.Deployment, uuid string, response *bytes.Buffer) interfaces.DeployResponse {
+	c.RollbackDeploymentCall.Called = true
+	c.RollbackDeploymentCall.TimesCalled++
+	c.RollbackDeploymentCall.Received.Deployment = deployment
+	c.RollbackDeploymentCall.Received.UUID = uuid
+	c.RollbackDeploymentCall.Received.Response = response
+	c.RollbackDeploymentCall.Received.Applications = append(c.RollbackDeploymentCall.Received.Applications, deployment.CFContext.Application)
+
+	return c.RollbackDeploymentCall.Returns.DeployResponse
+}
@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"bytes"
+	"github.com/compozed/deployadactyl/interfaces"
+)
+
+type RestartController struct {
+	RestageDeploymentCall struct {
+		Received struct {
+			Deployment *interfaces.Deployment
+			Data       map[string]interface{}
+			Response   *bytes.Buffer
+		}
+		Returns struct {
+			DeployResponse interfaces.DeployResponse
+		}
+		Writes string
+		Called bool
+	}
+	RestartDeploymentCall struct {
+		Received struct {
+			Deployment *interfaces.Deployment
+			Data       map[string]interface{}
+			Response   *bytes.Buffer
+		}
+		Returns struct {
+			DeployResponse interfaces.DeployResponse
+		}
+		Writes string
+		Called bool
+	}
+}
+
+func (c *RestartController) RestageDeployment(deployment *interfaces.Deployment, data map[string]interface{}, response *bytes.Buffer) (deployResponse interfaces.DeployResponse) {
+	c.RestageDeploymentCall.Called = true
+	c.RestageDeploymentCall.Received.Deployment = deployment
+	c.RestageDeploymentCall.Received.Data = data
+	c.RestageDeploymentCall.Received.Response = response
+
+	if c.RestageDeploymentCall.Writes != "" {
+		response.Write([]byte(c.RestageDeploymentCall.Writes))
+	}
+
+	return c.RestageDeploymentCall.Returns.DeployResponse
+}
+
+func (c *RestartController) RestartDeployment(deployment *interfaces.Deployment, data map[string]interface{}, response *bytes.Buffer) (deployResponse interfaces.DeployResponse) {
+	c.RestartDeploymentCall.Called = true
+	c.RestartDeploymentCall.Received.Deployment = deployment
+	c.RestartDeploymentCall.Received.Data = data
+	c.RestartDeploymentCall.Received.Response = response
+
+	if c.RestartDeploymentCall.Writes != "" {
+		response.Write([]byte(c.RestartDeploymentCall.Writes))
+	}
+
+	return c.RestartDeploymentCall.Returns.DeployResponse
+}
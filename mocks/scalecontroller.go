@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"bytes"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type ScaleController struct {
+	ScaleDeploymentCall struct {
+		Received struct {
+			Deployment *interfaces.Deployment
+			Spec       structs.ScaleSpec
+			Data       map[string]interface{}
+			Response   *bytes.Buffer
+		}
+		Returns struct {
+			DeployResponse interfaces.DeployResponse
+		}
+		Writes string
+		Called bool
+	}
+}
+
+func (c *ScaleController) ScaleDeployment(deployment *interfaces.Deployment, spec structs.ScaleSpec, data map[string]interface{}, response *bytes.Buffer) (deployResponse interfaces.DeployResponse) {
+	c.ScaleDeploymentCall.Called = true
+	c.ScaleDeploymentCall.Received.Deployment = deployment
+	c.ScaleDeploymentCall.Received.Spec = spec
+	c.ScaleDeploymentCall.Received.Data = data
+	c.ScaleDeploymentCall.Received.Response = response
+
+	if c.ScaleDeploymentCall.Writes != "" {
+		response.Write([]byte(c.ScaleDeploymentCall.Writes))
+	}
+
+	return c.ScaleDeploymentCall.Returns.DeployResponse
+}
@@ -0,0 +1,24 @@
+package prober
+
+import (
+	"fmt"
+	"time"
+)
+
+type TimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("probe %q did not finish within %s", e.Command, e.Timeout)
+}
+
+type ExitError struct {
+	Command string
+	Err     error
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("probe %q failed: %s", e.Command, e.Err)
+}
@@ -0,0 +1,48 @@
+// Package prober runs the commands that decide whether a newly pushed application works.
+package prober
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+)
+
+// Prober runs probe commands with the shell.
+type Prober struct{}
+
+// Probe runs the command with the variables added to its environment. The combined standard
+// output and standard error are written to out as the command runs.
+//
+// Returns a TimeoutError if the command does not finish within the timeout and an ExitError if it fails.
+func (p Prober) Probe(command string, variables map[string]string, timeout time.Duration, out io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = os.Environ()
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd.Env = append(cmd.Env, name+"="+variables[name])
+	}
+
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return TimeoutError{command, timeout}
+	}
+	if err != nil {
+		return ExitError{command, err}
+	}
+
+	return nil
+}
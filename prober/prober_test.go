@@ -0,0 +1,50 @@
+package prober_test
+
+import (
+	"time"
+
+	. "github.com/compozed/deployadactyl/prober"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Prober", func() {
+	var (
+		prober Prober
+		out    *Buffer
+	)
+
+	BeforeEach(func() {
+		prober = Prober{}
+		out = NewBuffer()
+	})
+
+	It("writes the output of the command", func() {
+		Expect(prober.Probe("echo smoke tests passed; echo warning >&2", nil, time.Minute, out)).To(Succeed())
+
+		Eventually(out).Should(Say("smoke tests passed"))
+		Eventually(out).Should(Say("warning"))
+	})
+
+	It("passes the variables to the command", func() {
+		variables := map[string]string{"PROBE_APP_URL": "https://example.com", "PROBE_UUID": "1234"}
+
+		Expect(prober.Probe("echo $PROBE_APP_URL $PROBE_UUID", variables, time.Minute, out)).To(Succeed())
+
+		Eventually(out).Should(Say("https://example.com 1234"))
+	})
+
+	It("returns an ExitError when the command exits non-zero", func() {
+		err := prober.Probe("exit 3", nil, time.Minute, out)
+
+		Expect(err).To(BeAssignableToTypeOf(ExitError{}))
+		Expect(err.Error()).To(ContainSubstring(`probe "exit 3" failed: exit status 3`))
+	})
+
+	It("returns a TimeoutError when the command does not finish in time", func() {
+		err := prober.Probe("sleep 5", nil, 100*time.Millisecond, out)
+
+		Expect(err).To(MatchError(TimeoutError{"sleep 5", 100 * time.Millisecond}))
+	})
+})
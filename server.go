@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/compozed/deployadactyl/creator"
 	"github.com/compozed/deployadactyl/state/push"
+	"github.com/compozed/deployadactyl/structs"
 	"github.com/op/go-logging"
 	"github.com/compozed/deployadactyl/interfaces"
 )
@@ -16,6 +21,7 @@ const (
 	defaultConfigFilePath = "./config.yml"
 	defaultLogLevel       = "DEBUG"
 	logLevelEnvVarName    = "DEPLOYADACTYL_LOGLEVEL"
+	shutdownTimeout       = 30 * time.Second
 )
 
 func main() {
@@ -23,6 +29,7 @@ func main() {
 		config               = flag.String("config", defaultConfigFilePath, "location of the config file")
 		envVarHandlerEnabled = flag.Bool("env", false, "enable environment variable handling")
 		routeMapperEnabled   = flag.Bool("route-mapper", false, "enables route mapper to map additional routes from a manifest")
+		otelEnabled          = flag.Bool("otel", false, "export OpenTelemetry spans of deploys to the configured collector")
 	)
 	flag.Parse()
 
@@ -49,18 +56,29 @@ func main() {
 	if *envVarHandlerEnabled {
 		envVarHandler := c.CreateEnvVarHandler()
 		log.Infof("registering environment variable event handler")
-		em.AddBinding(push.NewArtifactRetrievalSuccessEventBinding(envVarHandler.ArtifactRetrievalSuccessEventHandler))
+		em.AddNamedBinding(structs.EventHandlerEnvVar, push.NewArtifactRetrievalSuccessEventBinding(envVarHandler.ArtifactRetrievalSuccessEventHandler))
 	}
 
 	healthHandler := c.CreateHealthChecker()
 	log.Infof("registering health check handler")
-	em.AddBinding(push.NewPushFinishedEventBinding(healthHandler.PushFinishedEventHandler))
+	em.AddNamedBinding(structs.EventHandlerHealthCheck, push.NewPushFinishedEventBinding(healthHandler.PushFinishedEventHandler))
 
 	if *routeMapperEnabled {
 		routeMapper := c.CreateRouteMapper()
 
 		log.Infof("registering health check handler")
-		em.AddBinding(push.NewPushFinishedEventBinding(routeMapper.PushFinishedEventHandler))
+		em.AddNamedBinding(structs.EventHandlerRouteMapper, push.NewPushFinishedEventBinding(routeMapper.PushFinishedEventHandler))
+	}
+
+	c.RecoverInterruptedDeploys()
+	c.StartEventRetries()
+	c.StartHistoryPruning()
+
+	if *otelEnabled {
+		err = c.EnableTracing()
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	l := c.CreateListener()
@@ -70,8 +88,40 @@ func main() {
 
 	log.Infof("Listening on Port %d", c.CreateConfig().Port)
 
-	err = http.Serve(l, deploy)
-	if err != nil {
+	server := &http.Server{Handler: deploy}
+	shutdown := make(chan struct{})
+
+	go func() {
+		defer close(shutdown)
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+
+		log.Infof("shutting down")
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := server.Shutdown(ctx)
+		if err != nil {
+			log.Errorf("could not shut down the server: %s", err)
+		}
+	}()
+
+	err = server.Serve(l)
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
+	<-shutdown
+
+	log.Infof("waiting for silent deploys to finish")
+	c.DrainSilentDeploys()
+	c.StopEventRetries()
+	c.StopHistoryPruning()
+	c.CloseKafkaProducer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	c.ShutdownTracing(ctx)
 }
@@ -125,7 +125,7 @@ applications:
 		Expect(err).ToNot(HaveOccurred())
 		jsonBuffer := bytes.NewBuffer(j)
 
-		requestURL := fmt.Sprintf("%s/v3/apps/%s/%s/%s/%s", deployadactylServer.URL, ENVIRONMENTNAME, org, space, appName)
+		requestURL := fmt.Sprintf("%s/v3/apps/%s/%s/%s/%s?allowNameMismatch=true", deployadactylServer.URL, ENVIRONMENTNAME, org, space, appName)
 		req, err := http.NewRequest("POST", requestURL, jsonBuffer)
 		Expect(err).ToNot(HaveOccurred())
 
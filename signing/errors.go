@@ -0,0 +1,20 @@
+package signing
+
+import "fmt"
+
+type UnknownAlgorithmError struct {
+	Algorithm string
+}
+
+func (e UnknownAlgorithmError) Error() string {
+	return fmt.Sprintf("unknown signing algorithm %q: must be %s or %s", e.Algorithm, HMACSHA256, Ed25519)
+}
+
+type InvalidKeyError struct {
+	Algorithm string
+	Reason    string
+}
+
+func (e InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid %s signing key: %s", e.Algorithm, e.Reason)
+}
@@ -0,0 +1,113 @@
+// Package signing signs the deploy records sent to webhooks and Kafka and kept in the deployment history, so their
+// consumers can verify that a record was sent by Deployadactyl and not changed since.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// Signing algorithms.
+const (
+	// HMACSHA256 signs with an HMAC-SHA256 of a key shared with the consumers.
+	HMACSHA256 = "hmac-sha256"
+	// Ed25519 signs with an Ed25519 private key. The consumers verify with its public key.
+	Ed25519 = "ed25519"
+)
+
+// HMACSigner signs deploy records with an HMAC-SHA256.
+type HMACSigner struct {
+	KeyID string
+	key   []byte
+}
+
+// Sign returns the HMAC-SHA256 of the payload.
+func (s HMACSigner) Sign(payload []byte) S.Signature {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+
+	return S.Signature{Algorithm: HMACSHA256, KeyID: s.KeyID, Value: base64.StdEncoding.EncodeToString(mac.Sum(nil))}
+}
+
+// Ed25519Signer signs deploy records with an Ed25519 private key.
+type Ed25519Signer struct {
+	KeyID string
+	key   ed25519.PrivateKey
+}
+
+// Sign returns the Ed25519 signature of the payload.
+func (s Ed25519Signer) Sign(payload []byte) S.Signature {
+	return S.Signature{Algorithm: Ed25519, KeyID: s.KeyID, Value: base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload))}
+}
+
+// PublicKey returns the base64 encoded public key the consumers verify the signatures with.
+func (s Ed25519Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// NewSigner returns the signer of the algorithm. An Ed25519 key is either a 32 byte seed or a 64 byte private key.
+//
+// Returns an UnknownAlgorithmError or an InvalidKeyError. The key is never part of the error.
+func NewSigner(algorithm, keyID string, key []byte) (I.DeploySigner, error) {
+	switch algorithm {
+	case HMACSHA256:
+		if len(key) == 0 {
+			return nil, InvalidKeyError{algorithm, "the key is empty"}
+		}
+		return HMACSigner{KeyID: keyID, key: key}, nil
+	case Ed25519:
+		switch len(key) {
+		case ed25519.SeedSize:
+			return Ed25519Signer{KeyID: keyID, key: ed25519.NewKeyFromSeed(key)}, nil
+		case ed25519.PrivateKeySize:
+			return Ed25519Signer{KeyID: keyID, key: ed25519.PrivateKey(key)}, nil
+		}
+		return nil, InvalidKeyError{algorithm, "the key must be a 32 byte seed or a 64 byte private key"}
+	}
+
+	return nil, UnknownAlgorithmError{algorithm}
+}
+
+// Envelope carries a signed record. Payload is the exact JSON that was signed, so a consumer verifies the signature
+// on the raw bytes of the payload without serializing the record again.
+type Envelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature S.Signature     `json:"signature"`
+}
+
+// Marshal serializes a record as JSON. With a signer, the JSON is signed and wrapped in an Envelope with its
+// signature.
+func Marshal(record interface{}, signer I.DeploySigner) ([]byte, error) {
+	value, err := json.Marshal(record)
+	if err != nil || signer == nil {
+		return value, err
+	}
+
+	return json.Marshal(Envelope{Payload: value, Signature: signer.Sign(value)})
+}
+
+// Verify returns true when the signature is a valid signature of the payload. The key is the shared key of an
+// HMAC-SHA256 signature or the public key of an Ed25519 signature.
+func Verify(signature S.Signature, payload, key []byte) bool {
+	value, err := base64.StdEncoding.DecodeString(signature.Value)
+	if err != nil {
+		return false
+	}
+
+	switch signature.Algorithm {
+	case HMACSHA256:
+		mac := hmac.New(sha256.New, key)
+		mac.Write(payload)
+		return hmac.Equal(value, mac.Sum(nil))
+	case Ed25519:
+		return len(key) == ed25519.PublicKeySize && ed25519.Verify(ed25519.PublicKey(key), payload, value)
+	}
+
+	return false
+}
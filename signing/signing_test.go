@@ -0,0 +1,139 @@
+package signing_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+
+	. "github.com/compozed/deployadactyl/signing"
+	S "github.com/compozed/deployadactyl/structs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signing", func() {
+	var (
+		payload []byte
+		seed    []byte
+	)
+
+	BeforeEach(func() {
+		payload = []byte(`{"type":"deploy.success"}`)
+		seed = bytes.Repeat([]byte{7}, ed25519.SeedSize)
+	})
+
+	Describe("NewSigner", func() {
+		It("signs with an HMAC-SHA256 of the key", func() {
+			signer, err := NewSigner(HMACSHA256, "key-1", []byte("secret"))
+			Expect(err).ToNot(HaveOccurred())
+
+			signature := signer.Sign(payload)
+
+			Expect(signature.Algorithm).To(Equal(HMACSHA256))
+			Expect(signature.KeyID).To(Equal("key-1"))
+			Expect(Verify(signature, payload, []byte("secret"))).To(BeTrue())
+			Expect(Verify(signature, payload, []byte("other secret"))).To(BeFalse())
+			Expect(Verify(signature, []byte(`{"type":"deploy.failure"}`), []byte("secret"))).To(BeFalse())
+		})
+
+		It("signs with an Ed25519 key from a seed", func() {
+			signer, err := NewSigner(Ed25519, "key-2", seed)
+			Expect(err).ToNot(HaveOccurred())
+
+			signature := signer.Sign(payload)
+			publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+
+			Expect(signature.Algorithm).To(Equal(Ed25519))
+			Expect(signature.KeyID).To(Equal("key-2"))
+			Expect(Verify(signature, payload, publicKey)).To(BeTrue())
+			Expect(Verify(signature, []byte(`{"type":"deploy.failure"}`), publicKey)).To(BeFalse())
+		})
+
+		It("signs with an Ed25519 private key", func() {
+			signer, err := NewSigner(Ed25519, "key-2", ed25519.NewKeyFromSeed(seed))
+			Expect(err).ToNot(HaveOccurred())
+
+			fromSeed, _ := NewSigner(Ed25519, "key-2", seed)
+
+			Expect(signer.Sign(payload)).To(Equal(fromSeed.Sign(payload)))
+		})
+
+		It("returns the public key of an Ed25519 signer", func() {
+			signer, _ := NewSigner(Ed25519, "key-2", seed)
+			publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+
+			Expect(signer.(Ed25519Signer).PublicKey()).To(Equal(base64.StdEncoding.EncodeToString(publicKey)))
+		})
+
+		It("returns an error for an unknown algorithm", func() {
+			_, err := NewSigner("rsa", "key-1", []byte("secret"))
+
+			Expect(err).To(MatchError(UnknownAlgorithmError{"rsa"}))
+		})
+
+		It("returns an error for an empty HMAC key", func() {
+			_, err := NewSigner(HMACSHA256, "key-1", nil)
+
+			Expect(err).To(MatchError(InvalidKeyError{HMACSHA256, "the key is empty"}))
+		})
+
+		It("returns an error for an Ed25519 key of the wrong size", func() {
+			_, err := NewSigner(Ed25519, "key-2", []byte("secret"))
+
+			Expect(err).To(MatchError(InvalidKeyError{Ed25519, "the key must be a 32 byte seed or a 64 byte private key"}))
+			Expect(err.Error()).ToNot(ContainSubstring("secret"))
+		})
+	})
+
+	Describe("Marshal", func() {
+		var record S.DeployRecord
+
+		BeforeEach(func() {
+			record = S.NewDeployRecord("deploy.success", false, nil, S.DeploymentInfo{AppName: "app", Password: "hunter2"})
+		})
+
+		It("does not sign without a signer", func() {
+			body, err := Marshal(record, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			unsigned, _ := json.Marshal(record)
+			Expect(body).To(Equal(unsigned))
+			Expect(string(body)).ToNot(ContainSubstring("hunter2"))
+		})
+
+		It("wraps the record in an envelope with the signature of its exact JSON", func() {
+			signer, _ := NewSigner(HMACSHA256, "key-1", []byte("secret"))
+
+			body, err := Marshal(record, signer)
+			Expect(err).ToNot(HaveOccurred())
+
+			var envelope Envelope
+			Expect(json.Unmarshal(body, &envelope)).To(Succeed())
+			Expect(envelope.Signature.KeyID).To(Equal("key-1"))
+
+			unsigned, _ := json.Marshal(record)
+			Expect([]byte(envelope.Payload)).To(Equal(unsigned))
+			Expect(Verify(envelope.Signature, envelope.Payload, []byte("secret"))).To(BeTrue())
+		})
+
+		It("signs the payload as it is sent", func() {
+			record.DeploymentInfo.AppName = "<app> & co"
+			signer, _ := NewSigner(HMACSHA256, "key-1", []byte("secret"))
+
+			body, err := Marshal(record, signer)
+			Expect(err).ToNot(HaveOccurred())
+
+			var raw struct {
+				Payload json.RawMessage `json:"payload"`
+			}
+			Expect(json.Unmarshal(body, &raw)).To(Succeed())
+			Expect(bytes.Contains(body, raw.Payload)).To(BeTrue())
+
+			var envelope Envelope
+			json.Unmarshal(body, &envelope)
+			Expect(Verify(envelope.Signature, raw.Payload, []byte("secret"))).To(BeTrue())
+		})
+	})
+})
@@ -1,6 +1,12 @@
 package state
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/compozed/deployadactyl/structs"
+)
 
 type CloudFoundryGetLogsError struct {
 	CfTaskErr error
@@ -20,6 +26,15 @@ func (e DeleteApplicationError) Error() string {
 	return fmt.Sprintf("cannot delete %s: %s", e.ApplicationName, string(e.Out))
 }
 
+type AuthExpiredError struct {
+	FoundationURL string
+	Out           []byte
+}
+
+func (e AuthExpiredError) Error() string {
+	return fmt.Sprintf("cloud foundry token for %s expired and logging in again failed: %s", e.FoundationURL, string(e.Out))
+}
+
 type LoginError struct {
 	FoundationURL string
 	Out           []byte
@@ -29,6 +44,158 @@ func (e LoginError) Error() string {
 	return fmt.Sprintf("cannot login to %s: %s", e.FoundationURL, string(e.Out))
 }
 
+type TargetError struct {
+	Org   string
+	Space string
+	Out   []byte
+}
+
+func (e TargetError) Error() string {
+	return fmt.Sprintf("cannot target space %s in org %s: %s", e.Space, e.Org, string(e.Out))
+}
+
+type SpaceGUIDError struct {
+	SpaceGUID string
+	Err       error
+}
+
+func (e SpaceGUIDError) Error() string {
+	return fmt.Sprintf("cannot find space %s: %s", e.SpaceGUID, e.Err)
+}
+
+type SpaceGUIDConflictError struct {
+	SpaceGUID string
+	Org       string
+	Space     string
+	Found     structs.Space
+}
+
+func (e SpaceGUIDConflictError) Error() string {
+	return fmt.Sprintf("space %s is %s in org %s (org GUID %s), not %s in org %s", e.SpaceGUID, e.Found.Name, e.Found.OrgName, e.Found.OrgGUID, e.Space, e.Org)
+}
+
+type CreateOrgError struct {
+	Org string
+	Out []byte
+}
+
+func (e CreateOrgError) Error() string {
+	return fmt.Sprintf("cannot create org %s: %s", e.Org, string(e.Out))
+}
+
+type CreateSpaceError struct {
+	Org   string
+	Space string
+	Out   []byte
+}
+
+func (e CreateSpaceError) Error() string {
+	return fmt.Sprintf("cannot create space %s in org %s: %s", e.Space, e.Org, string(e.Out))
+}
+
+type SetIsolationSegmentError struct {
+	Space   string
+	Segment string
+	Out     []byte
+}
+
+func (e SetIsolationSegmentError) Error() string {
+	return fmt.Sprintf("cannot set the isolation segment of space %s to %s: %s", e.Space, e.Segment, string(e.Out))
+}
+
+type RouteAppsError struct {
+	Route string
+	Err   error
+}
+
+func (e RouteAppsError) Error() string {
+	return fmt.Sprintf("cannot find the applications mapped to %s: %s", e.Route, e.Err)
+}
+
+type RouteConflictError struct {
+	Route string
+	Apps  []string
+}
+
+func (e RouteConflictError) Error() string {
+	return fmt.Sprintf("route %s is already mapped to %s: set route_conflict_policy to steal or skip to deploy anyway", e.Route, strings.Join(e.Apps, ", "))
+}
+
+type SetLabelsError struct {
+	ApplicationName string
+	Out             []byte
+}
+
+func (e SetLabelsError) Error() string {
+	return fmt.Sprintf("cannot set labels on %s: %s", e.ApplicationName, string(e.Out))
+}
+
+type RunTaskError struct {
+	ApplicationName string
+	Out             []byte
+}
+
+func (e RunTaskError) Error() string {
+	return fmt.Sprintf("cannot run task on %s: %s", e.ApplicationName, string(e.Out))
+}
+
+type TaskStateError struct {
+	TaskName string
+	Err      error
+}
+
+func (e TaskStateError) Error() string {
+	return fmt.Sprintf("cannot get state of task %s: %s", e.TaskName, e.Err)
+}
+
+type TaskFailedError struct {
+	TaskName string
+	State    string
+}
+
+func (e TaskFailedError) Error() string {
+	return fmt.Sprintf("task %s did not succeed: %s", e.TaskName, e.State)
+}
+
+type TaskTimeoutError struct {
+	TaskName string
+	Timeout  time.Duration
+}
+
+func (e TaskTimeoutError) Error() string {
+	return fmt.Sprintf("task %s did not finish within %s", e.TaskName, e.Timeout)
+}
+
+type InstanceStateError struct {
+	ApplicationName string
+	Err             error
+}
+
+func (e InstanceStateError) Error() string {
+	return fmt.Sprintf("could not get the instance states of %s: %s", e.ApplicationName, e.Err)
+}
+
+type InstancesNotRunningError struct {
+	ApplicationName string
+	Running         int
+	Expected        int
+	Required        int
+	Timeout         time.Duration
+}
+
+func (e InstancesNotRunningError) Error() string {
+	return fmt.Sprintf("%d of %d instances of %s were running after %s, %d required", e.Running, e.Expected, e.ApplicationName, e.Timeout, e.Required)
+}
+
+type ProbeError struct {
+	ApplicationName string
+	Err             error
+}
+
+func (e ProbeError) Error() string {
+	return fmt.Sprintf("probe failed for %s: %s", e.ApplicationName, e.Err)
+}
+
 type RenameError struct {
 	ApplicationName string
 	Out             []byte
@@ -52,6 +219,49 @@ func (e MapRouteError) Error() string {
 	return fmt.Sprintf("map route failed: %s", string(e.Out))
 }
 
+type DeployCanceledError struct {
+	ApplicationName string
+}
+
+func (e DeployCanceledError) Error() string {
+	return fmt.Sprintf("the deploy of %s was canceled", e.ApplicationName)
+}
+
+type CandidateNotFoundError struct {
+	ApplicationName string
+	FoundationURL   string
+}
+
+func (e CandidateNotFoundError) Error() string {
+	return fmt.Sprintf("candidate %s does not exist on %s", e.ApplicationName, e.FoundationURL)
+}
+
+type VenerableNotFoundError struct {
+	ApplicationName string
+	FoundationURL   string
+}
+
+func (e VenerableNotFoundError) Error() string {
+	return fmt.Sprintf("replaced application %s does not exist on %s", e.ApplicationName, e.FoundationURL)
+}
+
+type RoutesError struct {
+	ApplicationName string
+	Err             error
+}
+
+func (e RoutesError) Error() string {
+	return fmt.Sprintf("cannot get the routes of %s: %s", e.ApplicationName, e.Err)
+}
+
+type NoTemporaryRouteError struct {
+	ApplicationName string
+}
+
+func (e NoTemporaryRouteError) Error() string {
+	return fmt.Sprintf("candidate %s has no temporary route", e.ApplicationName)
+}
+
 type UnmapRouteError struct {
 	ApplicationName string
 	Out             []byte
@@ -81,6 +291,78 @@ func (e ManifestError) Error() string {
 	return "manifest decoding error"
 }
 
+type DefaultManifestError struct {
+	Err error
+}
+
+func (e DefaultManifestError) Error() string {
+	return fmt.Sprintf("cannot apply the default manifest: %s", e.Err)
+}
+
+type RequestEnvError struct {
+	Err error
+}
+
+func (e RequestEnvError) Error() string {
+	return fmt.Sprintf("cannot set the environment variables of the push in the manifest: %s", e.Err)
+}
+
+type NoApplicationsError struct{}
+
+func (e NoApplicationsError) Error() string {
+	return "no application name in the url and no named applications in the manifest"
+}
+
+type ApplicationManifestError struct {
+	ApplicationName string
+	Err             error
+}
+
+func (e ApplicationManifestError) Error() string {
+	return fmt.Sprintf("cannot write the manifest of %s: %s", e.ApplicationName, e.Err)
+}
+
+type ApplicationPushError struct {
+	ApplicationName string
+	Err             error
+}
+
+func (e ApplicationPushError) Error() string {
+	return fmt.Sprintf("%s: %s", e.ApplicationName, e.Err)
+}
+
+type ApplicationsFailedError struct {
+	ApplicationNames []string
+}
+
+func (e ApplicationsFailedError) Error() string {
+	return fmt.Sprintf("applications failed: %s", strings.Join(e.ApplicationNames, ", "))
+}
+
+type ProfileManifestError struct {
+	Err error
+}
+
+func (e ProfileManifestError) Error() string {
+	return fmt.Sprintf("cannot apply the profile manifest: %s", e.Err)
+}
+
+type ManifestTransformError struct {
+	Err error
+}
+
+func (e ManifestTransformError) Error() string {
+	return fmt.Sprintf("the manifest transformer failed: %s", e.Err)
+}
+
+type ArtifactScanError struct {
+	Err error
+}
+
+func (e ArtifactScanError) Error() string {
+	return fmt.Sprintf("the artifact scanner failed: %s", e.Err)
+}
+
 type UnzippingError struct {
 	Err error
 }
@@ -122,3 +404,142 @@ type ExistsError struct {
 func (e ExistsError) Error() string {
 	return fmt.Sprintf("app %s doesn't exist", e.ApplicationName)
 }
+
+type ScaleError struct {
+	ApplicationName string
+	Out             []byte
+}
+
+func (e ScaleError) Error() string {
+	return fmt.Sprintf("cannot scale %s: %s", e.ApplicationName, string(e.Out))
+}
+
+type SetEnvError struct {
+	ApplicationName string
+	Name            string
+	Out             []byte
+}
+
+func (e SetEnvError) Error() string {
+	return fmt.Sprintf("cannot set environment variable %s on %s: %s", e.Name, e.ApplicationName, string(e.Out))
+}
+
+type SetFeatureError struct {
+	ApplicationName string
+	Feature         string
+	Out             []byte
+}
+
+func (e SetFeatureError) Error() string {
+	return fmt.Sprintf("cannot set app feature %s on %s: %s", e.Feature, e.ApplicationName, string(e.Out))
+}
+
+type RestartError struct {
+	ApplicationName string
+	Out             []byte
+}
+
+func (e RestartError) Error() string {
+	return fmt.Sprintf("cannot restart %s: %s", e.ApplicationName, string(e.Out))
+}
+
+type RestageError struct {
+	ApplicationName string
+	Out             []byte
+}
+
+func (e RestageError) Error() string {
+	return fmt.Sprintf("cannot restage %s: %s", e.ApplicationName, string(e.Out))
+}
+
+type DeleteServiceError struct {
+	ServiceName string
+	Out         []byte
+}
+
+func (e DeleteServiceError) Error() string {
+	return fmt.Sprintf("cannot delete service %s: %s", e.ServiceName, string(e.Out))
+}
+
+type OrphanedServicesError struct {
+	ApplicationName string
+	Err             error
+}
+
+func (e OrphanedServicesError) Error() string {
+	return fmt.Sprintf("cannot find services bound to %s: %s", e.ApplicationName, e.Err)
+}
+
+type RouteWeightsError struct {
+	Route string
+	Err   error
+}
+
+func (e RouteWeightsError) Error() string {
+	return fmt.Sprintf("cannot set the weights of route %s: %s", e.Route, e.Err)
+}
+
+type TrafficSplitDegradedError struct {
+	ApplicationName string
+	Weight          int
+	Running         int
+	Expected        int
+}
+
+func (e TrafficSplitDegradedError) Error() string {
+	return fmt.Sprintf("%d of %d instances of %s were running while it received %d%% of the traffic: traffic was returned to the existing application", e.Running, e.Expected, e.ApplicationName, e.Weight)
+}
+
+type HealthCheckAbortedError struct {
+	FoundationURL string
+}
+
+func (e HealthCheckAbortedError) Error() string {
+	return fmt.Sprintf("health check on %s aborted because a health check on another foundation failed", e.FoundationURL)
+}
+
+type PhaseTimeoutError struct {
+	Phase   string
+	Timeout time.Duration
+}
+
+func (e PhaseTimeoutError) Error() string {
+	return fmt.Sprintf("the %s phase did not finish within %s", e.Phase, e.Timeout)
+}
+
+type JournalDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e JournalDirectoryError) Error() string {
+	return fmt.Sprintf("cannot use deploy journal directory %s: %s", e.Directory, e.Err)
+}
+
+type JournalWriteError struct {
+	Path string
+	Err  error
+}
+
+func (e JournalWriteError) Error() string {
+	return fmt.Sprintf("cannot write checkpoint %s: %s", e.Path, e.Err)
+}
+
+type InterruptedDeployError struct {
+	UUID  string
+	Phase string
+}
+
+func (e InterruptedDeployError) Error() string {
+	return fmt.Sprintf("deploy %s was interrupted by a restart of Deployadactyl during the %s phase", e.UUID, e.Phase)
+}
+
+type RecoveryError struct {
+	UUID          string
+	FoundationURL string
+	Reason        string
+}
+
+func (e RecoveryError) Error() string {
+	return fmt.Sprintf("cannot recover deploy %s on %s: %s", e.UUID, e.FoundationURL, e.Reason)
+}
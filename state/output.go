@@ -0,0 +1,51 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/deploymentlog"
+)
+
+// TruncateOutput keeps the first lines of output that fit in the limits of errorOutput and replaces the rest
+// with a marker. When deployment logs are enabled the marker points to the log file of uuid, which has
+// the full output.
+func TruncateOutput(output string, errorOutput config.ErrorOutputConfig, deploymentLog config.DeploymentLogConfig, uuid string) string {
+	lines := strings.SplitAfter(output, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	kept := len(lines)
+	if errorOutput.MaxLines > 0 && kept > errorOutput.MaxLines {
+		kept = errorOutput.MaxLines
+	}
+
+	if errorOutput.MaxBytes > 0 {
+		size := 0
+		for i := 0; i < kept; i++ {
+			size += len(lines[i])
+			if size > errorOutput.MaxBytes {
+				kept = i
+				break
+			}
+		}
+	}
+
+	if kept == len(lines) {
+		return output
+	}
+
+	truncated := strings.Join(lines[:kept], "")
+	if truncated != "" && !strings.HasSuffix(truncated, "\n") {
+		truncated += "\n"
+	}
+
+	truncated += fmt.Sprintf("... %d more lines truncated\n", len(lines)-kept)
+	if deploymentLog.Enabled {
+		truncated += fmt.Sprintf("the full output is in %s\n", deploymentlog.FilePath(deploymentLog.Directory, uuid))
+	}
+
+	return truncated
+}
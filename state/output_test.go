@@ -0,0 +1,54 @@
+package state_test
+
+import (
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/state"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TruncateOutput", func() {
+	var (
+		output        string
+		deploymentLog config.DeploymentLogConfig
+	)
+
+	BeforeEach(func() {
+		output = "line 1\nline 2\nline 3\nline 4\n"
+		deploymentLog = config.DeploymentLogConfig{}
+	})
+
+	It("returns the output unchanged without limits", func() {
+		Expect(TruncateOutput(output, config.ErrorOutputConfig{}, deploymentLog, "uuid")).To(Equal(output))
+	})
+
+	It("returns the output unchanged when it fits in the limits", func() {
+		Expect(TruncateOutput(output, config.ErrorOutputConfig{MaxLines: 4, MaxBytes: 28}, deploymentLog, "uuid")).To(Equal(output))
+	})
+
+	It("keeps the first MaxLines lines", func() {
+		truncated := TruncateOutput(output, config.ErrorOutputConfig{MaxLines: 1}, deploymentLog, "uuid")
+
+		Expect(truncated).To(Equal("line 1\n... 3 more lines truncated\n"))
+	})
+
+	It("keeps the whole lines that fit in MaxBytes", func() {
+		truncated := TruncateOutput(output, config.ErrorOutputConfig{MaxBytes: 20}, deploymentLog, "uuid")
+
+		Expect(truncated).To(Equal("line 1\nline 2\n... 2 more lines truncated\n"))
+	})
+
+	It("counts a last line without a newline", func() {
+		truncated := TruncateOutput("line 1\nline 2", config.ErrorOutputConfig{MaxLines: 1}, deploymentLog, "uuid")
+
+		Expect(truncated).To(Equal("line 1\n... 1 more lines truncated\n"))
+	})
+
+	It("points to the deployment log file when deployment logs are enabled", func() {
+		deploymentLog = config.DeploymentLogConfig{Enabled: true, Directory: "/var/log/deployments"}
+
+		truncated := TruncateOutput(output, config.ErrorOutputConfig{MaxLines: 3}, deploymentLog, "the-uuid")
+
+		Expect(truncated).To(Equal("line 1\nline 2\nline 3\n... 1 more lines truncated\nthe full output is in /var/log/deployments/the-uuid.log\n"))
+	})
+})
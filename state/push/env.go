@@ -0,0 +1,57 @@
+package push
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	"github.com/compozed/deployadactyl/state"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnv checks that the environment variables of a push have names a shell accepts.
+func validateEnv(env map[string]string) error {
+	for _, name := range sortedEnvNames(env) {
+		if !envNamePattern.MatchString(name) {
+			return deployer.InvalidEnvNameError{name}
+		}
+	}
+
+	return nil
+}
+
+// withRequestEnv sets the environment variables of the push on every application of the manifest.
+// A manifest with only the application is created when the push has none.
+//
+// Only the names of the variables are logged.
+func (a *PushManager) withRequestEnv(manifest string) (string, error) {
+	env := a.DeployEventData.DeploymentInfo.Env
+	if len(env) == 0 {
+		return manifest, nil
+	}
+
+	if manifest == "" {
+		manifest = "---\napplications:\n- name: " + a.DeployEventData.DeploymentInfo.AppName + "\n"
+	}
+
+	a.Logger.Infof("setting environment variables %s from the push", strings.Join(sortedEnvNames(env), ", "))
+	result, err := manifestro.SetEnv(manifest, env)
+	if err != nil {
+		return "", state.RequestEnvError{err}
+	}
+
+	return result, nil
+}
+
+func sortedEnvNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
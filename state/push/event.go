@@ -7,6 +7,7 @@ import (
 	"github.com/compozed/deployadactyl/structs"
 	"io"
 	"reflect"
+	"time"
 )
 
 type eventBinding struct {
@@ -23,15 +24,21 @@ func (b eventBinding) Emit(event interface{}) error {
 }
 
 type DeployStartedEvent struct {
-	CFContext   interfaces.CFContext
-	ArtifactURL string
-	Body        io.Reader
-	ContentType string
-	Environment structs.Environment
-	Auth        interfaces.Authorization
-	Response    io.ReadWriter
-	Data        map[string]interface{}
-	Log         interfaces.DeploymentLogger
+	CFContext      interfaces.CFContext
+	ArtifactURL    string
+	Body           io.Reader
+	ContentType    string
+	Environment    structs.Environment
+	Auth           interfaces.Authorization
+	Response       io.ReadWriter
+	Data           map[string]interface{}
+	Log            interfaces.DeploymentLogger
+	ClientIdentity string
+	ChangeTicket   string
+	Reason         string
+
+	// Replay is true when the event was recorded earlier and is emitted again.
+	Replay bool
 }
 
 func (d DeployStartedEvent) Name() string {
@@ -53,14 +60,20 @@ func NewDeployStartEventBinding(handler func(event DeployStartedEvent) error) in
 }
 
 type DeployFinishedEvent struct {
-	CFContext   interfaces.CFContext
-	Body        io.Reader
-	ContentType string
-	Environment structs.Environment
-	Auth        interfaces.Authorization
-	Response    io.ReadWriter
-	Data        map[string]interface{}
-	Log         interfaces.DeploymentLogger
+	CFContext      interfaces.CFContext
+	Body           io.Reader
+	ContentType    string
+	Environment    structs.Environment
+	Auth           interfaces.Authorization
+	Response       io.ReadWriter
+	Data           map[string]interface{}
+	Log            interfaces.DeploymentLogger
+	ClientIdentity string
+	ChangeTicket   string
+	Reason         string
+
+	// Replay is true when the event was recorded earlier and is emitted again.
+	Replay bool
 }
 
 func (d DeployFinishedEvent) Name() string {
@@ -91,7 +104,13 @@ type DeploySuccessEvent struct {
 	Data                map[string]interface{}
 	HealthCheckEndpoint string
 	ArtifactURL         string
+	Artifact            *structs.ArtifactMetadata
 	Log                 interfaces.DeploymentLogger
+	ChangeTicket        string
+	Reason              string
+
+	// Replay is true when the event was recorded earlier and is emitted again.
+	Replay bool
 }
 
 func (d DeploySuccessEvent) Name() string {
@@ -113,15 +132,20 @@ func NewDeploySuccessEventBinding(handler func(event DeploySuccessEvent) error)
 }
 
 type DeployFailureEvent struct {
-	CFContext   interfaces.CFContext
-	Body        io.Reader
-	ContentType string
-	Environment structs.Environment
-	Auth        interfaces.Authorization
-	Response    io.ReadWriter
-	Data        map[string]interface{}
-	Error       error
-	Log         interfaces.DeploymentLogger
+	CFContext    interfaces.CFContext
+	Body         io.Reader
+	ContentType  string
+	Environment  structs.Environment
+	Auth         interfaces.Authorization
+	Response     io.ReadWriter
+	Data         map[string]interface{}
+	Error        error
+	Log          interfaces.DeploymentLogger
+	ChangeTicket string
+	Reason       string
+
+	// Replay is true when the event was recorded earlier and is emitted again.
+	Replay bool
 }
 
 func (d DeployFailureEvent) Name() string {
@@ -185,7 +209,26 @@ type PushFinishedEvent struct {
 	Data                map[string]interface{}
 	Courier             interfaces.Courier
 	HealthCheckEndpoint string
+	HealthCheckMode     string
+	SkipSSL             bool
 	Log                 interfaces.DeploymentLogger
+
+	// HealthCheckStablePolls and HealthCheckStableSeconds are the stabilization period of the environment.
+	HealthCheckStablePolls   int
+	HealthCheckStableSeconds int
+
+	// MinHealthyPercent is the percentage of the instances that must pass the health check. Every instance must
+	// pass when it is zero.
+	MinHealthyPercent int
+
+	// HealthCheckCompare compares the health of the new application with the application it replaces.
+	// HealthCheckLatencyTolerancePercent is how much slower the new application may be with the latency comparison.
+	HealthCheckCompare                 string
+	HealthCheckLatencyTolerancePercent int
+
+	// Abort is closed when the health check should stop because a health check on another foundation failed.
+	// It is nil when the health check cannot be aborted.
+	Abort <-chan struct{}
 }
 
 func (d PushFinishedEvent) Name() string {
@@ -206,6 +249,125 @@ func NewPushFinishedEventBinding(handler func(event PushFinishedEvent) error) in
 	}
 }
 
+// DeployCanceledEvent is emitted when a running deploy was canceled and rolled back.
+type DeployCanceledEvent struct {
+	CFContext   interfaces.CFContext
+	Auth        interfaces.Authorization
+	Environment structs.Environment
+	Response    io.ReadWriter
+	Data        map[string]interface{}
+	Log         interfaces.DeploymentLogger
+}
+
+func (d DeployCanceledEvent) Name() string {
+	return "DeployCanceledEvent"
+}
+
+func NewDeployCanceledEventBinding(handler func(event DeployCanceledEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(DeployCanceledEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(DeployCanceledEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+// DeployWarningEvent is emitted when a problem is found that does not fail the deploy,
+// such as a failed health check in an environment with the warn health check mode.
+type DeployWarningEvent struct {
+	CFContext       interfaces.CFContext
+	Auth            interfaces.Authorization
+	Response        io.ReadWriter
+	FoundationURL   string
+	TempAppWithUUID string
+	Warning         string
+	Data            map[string]interface{}
+	Log             interfaces.DeploymentLogger
+}
+
+func (d DeployWarningEvent) Name() string {
+	return "DeployWarningEvent"
+}
+
+func NewDeployWarningEventBinding(handler func(event DeployWarningEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(DeployWarningEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(DeployWarningEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+// DeploySLOBreachEvent is emitted when a deploy took longer than the deploy SLO of its environment.
+type DeploySLOBreachEvent struct {
+	CFContext   interfaces.CFContext
+	Auth        interfaces.Authorization
+	Environment structs.Environment
+	Response    io.ReadWriter
+	Duration    time.Duration
+	Target      time.Duration
+	Data        map[string]interface{}
+	Log         interfaces.DeploymentLogger
+}
+
+func (d DeploySLOBreachEvent) Name() string {
+	return "DeploySLOBreachEvent"
+}
+
+func NewDeploySLOBreachEventBinding(handler func(event DeploySLOBreachEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(DeploySLOBreachEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(DeploySLOBreachEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type SpaceCreatedEvent struct {
+	CFContext     interfaces.CFContext
+	Auth          interfaces.Authorization
+	Response      io.ReadWriter
+	FoundationURL string
+	Org           string
+	Space         string
+	OrgCreated    bool
+	Data          map[string]interface{}
+	Log           interfaces.DeploymentLogger
+}
+
+func (d SpaceCreatedEvent) Name() string {
+	return "SpaceCreatedEvent"
+}
+
+func NewSpaceCreatedEventBinding(handler func(event SpaceCreatedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(SpaceCreatedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(SpaceCreatedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
 type ArtifactRetrievalStartEvent struct {
 	CFContext   interfaces.CFContext
 	Auth        interfaces.Authorization
@@ -326,6 +326,217 @@ var _ = Describe("event binding", func() {
 		})
 	})
 
+	Describe("DeployCanceledEvent", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					binding := push.NewDeployCanceledEventBinding(nil)
+
+					event := push.DeployCanceledEvent{}
+					Expect(binding.Accepts(event)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					binding := push.NewDeployCanceledEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(binding.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					handler := func(event push.DeployCanceledEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := push.NewDeployCanceledEventBinding(handler)
+					event := push.DeployCanceledEvent{}
+					binding.Emit(event)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					handler := func(event push.DeployCanceledEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := push.NewDeployCanceledEventBinding(handler)
+					event := interfaces.Event{}
+					err := binding.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+	})
+	Describe("DeployWarningEvent", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					binding := push.NewDeployWarningEventBinding(nil)
+
+					event := push.DeployWarningEvent{}
+					Expect(binding.Accepts(event)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					binding := push.NewDeployWarningEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(binding.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					handler := func(event push.DeployWarningEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := push.NewDeployWarningEventBinding(handler)
+					event := push.DeployWarningEvent{}
+					binding.Emit(event)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					handler := func(event push.DeployWarningEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := push.NewDeployWarningEventBinding(handler)
+					event := interfaces.Event{}
+					err := binding.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+	})
+
+	Describe("DeploySLOBreachEvent", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					binding := push.NewDeploySLOBreachEventBinding(nil)
+
+					event := push.DeploySLOBreachEvent{}
+					Expect(binding.Accepts(event)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					binding := push.NewDeploySLOBreachEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(binding.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					handler := func(event push.DeploySLOBreachEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := push.NewDeploySLOBreachEventBinding(handler)
+					event := push.DeploySLOBreachEvent{}
+					binding.Emit(event)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					handler := func(event push.DeploySLOBreachEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := push.NewDeploySLOBreachEventBinding(handler)
+					event := interfaces.Event{}
+					err := binding.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+	})
+
+	Describe("SpaceCreatedEvent", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					binding := push.NewSpaceCreatedEventBinding(nil)
+
+					event := push.SpaceCreatedEvent{}
+					Expect(binding.Accepts(event)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					binding := push.NewSpaceCreatedEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(binding.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					handler := func(event push.SpaceCreatedEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := push.NewSpaceCreatedEventBinding(handler)
+					event := push.SpaceCreatedEvent{}
+					binding.Emit(event)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					handler := func(event push.SpaceCreatedEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := push.NewSpaceCreatedEventBinding(handler)
+					event := interfaces.Event{}
+					err := binding.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+	})
+
 	Describe("ArtifactRetrievalStartEvent", func() {
 		Describe("Accept", func() {
 			Context("when accept takes a correct event", func() {
@@ -0,0 +1,41 @@
+package push
+
+import (
+	"sort"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+)
+
+// SupportedFeatures are the Cloud Foundry app features a push can enable or disable.
+var SupportedFeatures = []string{"revisions", "ssh"}
+
+// validateFeatures checks that every feature is one of the SupportedFeatures.
+func validateFeatures(features map[string]bool) error {
+	for _, name := range sortedFeatureNames(features) {
+		if !isSupportedFeature(name) {
+			return deployer.UnsupportedFeatureError{Feature: name, Supported: SupportedFeatures}
+		}
+	}
+
+	return nil
+}
+
+func isSupportedFeature(name string) bool {
+	for _, supported := range SupportedFeatures {
+		if name == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedFeatureNames(features map[string]bool) []string {
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
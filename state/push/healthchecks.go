@@ -0,0 +1,130 @@
+package push
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/state"
+)
+
+// HealthCheckTiming is how long the health check of an application took on a foundation.
+type HealthCheckTiming struct {
+	FoundationURL string
+	AppName       string
+	Duration      time.Duration
+	Err           error
+}
+
+func (t HealthCheckTiming) String() string {
+	switch t.Err.(type) {
+	case nil:
+		return fmt.Sprintf("%s on %s: healthy in %s", t.AppName, t.FoundationURL, t.Duration.Round(time.Millisecond))
+	case state.HealthCheckAbortedError:
+		return fmt.Sprintf("%s on %s: aborted", t.AppName, t.FoundationURL)
+	default:
+		return fmt.Sprintf("%s on %s: failed in %s", t.AppName, t.FoundationURL, t.Duration.Round(time.Millisecond))
+	}
+}
+
+// HealthChecks bounds how many foundations of a deploy are health checked at the same time and records how long
+// each health check took. With fail fast, the first failed health check aborts the ones that are still waiting
+// for a slot or retrying.
+type HealthChecks struct {
+	slots    chan struct{}
+	failFast bool
+	abort    chan struct{}
+	once     sync.Once
+
+	timings []HealthCheckTiming
+	mutex   sync.Mutex
+}
+
+// NewHealthChecks returns HealthChecks that run up to concurrency health checks at the same time. The health
+// checks are not bounded when concurrency is below 1.
+func NewHealthChecks(concurrency int, failFast bool) *HealthChecks {
+	h := &HealthChecks{failFast: failFast, abort: make(chan struct{})}
+	if concurrency > 0 {
+		h.slots = make(chan struct{}, concurrency)
+	}
+	return h
+}
+
+// Run runs the health check of the application on the foundation once a slot is free. The check is given a
+// channel that is closed when it should stop. Run returns a HealthCheckAbortedError without running the check
+// when the health checks were aborted while it waited.
+//
+// The check is run right away when h is nil.
+func (h *HealthChecks) Run(foundationURL, appName string, check func(abort <-chan struct{}) error) error {
+	if h == nil {
+		return check(nil)
+	}
+
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+			defer func() { <-h.slots }()
+		case <-h.abort:
+		}
+	}
+
+	if h.aborted() {
+		err := state.HealthCheckAbortedError{foundationURL}
+		h.record(HealthCheckTiming{foundationURL, appName, 0, err})
+		return err
+	}
+
+	start := time.Now()
+	err := check(h.abort)
+
+	timing := HealthCheckTiming{foundationURL, appName, time.Since(start), err}
+	if err != nil && h.aborted() {
+		timing.Err = state.HealthCheckAbortedError{foundationURL}
+	}
+	h.record(timing)
+
+	if err != nil && h.failFast {
+		h.once.Do(func() { close(h.abort) })
+	}
+
+	return err
+}
+
+// Timings returns how long each health check took, in the order they finished.
+func (h *HealthChecks) Timings() []HealthCheckTiming {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return append([]HealthCheckTiming(nil), h.timings...)
+}
+
+// Write writes how long each health check took to the response. Nothing is written when no health check ran.
+func (h *HealthChecks) Write(response io.Writer) {
+	timings := h.Timings()
+	if len(timings) == 0 {
+		return
+	}
+
+	fmt.Fprintln(response, "Health check timings:")
+	for _, timing := range timings {
+		fmt.Fprintf(response, "  %s\n", timing)
+	}
+	fmt.Fprintln(response)
+}
+
+func (h *HealthChecks) aborted() bool {
+	select {
+	case <-h.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *HealthChecks) record(timing HealthCheckTiming) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.timings = append(h.timings, timing)
+}
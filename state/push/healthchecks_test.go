@@ -0,0 +1,126 @@
+package push_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/push"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("HealthChecks", func() {
+	It("runs up to the concurrency health checks at the same time", func() {
+		var (
+			healthChecks = NewHealthChecks(2, false)
+			mutex        sync.Mutex
+			running      int
+			most         int
+			wg           sync.WaitGroup
+		)
+
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				healthChecks.Run("https://foundation", "myApp", func(<-chan struct{}) error {
+					mutex.Lock()
+					running++
+					if running > most {
+						most = running
+					}
+					mutex.Unlock()
+
+					time.Sleep(10 * time.Millisecond)
+
+					mutex.Lock()
+					running--
+					mutex.Unlock()
+					return nil
+				})
+			}()
+		}
+		wg.Wait()
+
+		Expect(most).To(Equal(2))
+		Expect(healthChecks.Timings()).To(HaveLen(5))
+	})
+
+	It("aborts the waiting health checks after a failure when failing fast", func() {
+		healthChecks := NewHealthChecks(1, true)
+
+		err := healthChecks.Run("https://foundation-1", "myApp", func(<-chan struct{}) error {
+			return errors.New("unhealthy")
+		})
+		Expect(err).To(MatchError("unhealthy"))
+
+		ran := false
+		err = healthChecks.Run("https://foundation-2", "myApp", func(<-chan struct{}) error {
+			ran = true
+			return nil
+		})
+
+		Expect(err).To(MatchError(state.HealthCheckAbortedError{"https://foundation-2"}))
+		Expect(ran).To(BeFalse())
+	})
+
+	It("closes the abort of the running health checks after a failure when failing fast", func() {
+		healthChecks := NewHealthChecks(0, true)
+		started := make(chan struct{})
+		done := make(chan error)
+
+		go func() {
+			done <- healthChecks.Run("https://foundation-1", "myApp", func(abort <-chan struct{}) error {
+				close(started)
+				<-abort
+				return errors.New("aborted")
+			})
+		}()
+		<-started
+
+		healthChecks.Run("https://foundation-2", "myApp", func(<-chan struct{}) error {
+			return errors.New("unhealthy")
+		})
+
+		Eventually(done).Should(Receive(MatchError("aborted")))
+		Expect(healthChecks.Timings()[1].Err).To(Equal(state.HealthCheckAbortedError{"https://foundation-1"}))
+	})
+
+	It("does not abort the other health checks without failing fast", func() {
+		healthChecks := NewHealthChecks(1, false)
+
+		healthChecks.Run("https://foundation-1", "myApp", func(<-chan struct{}) error {
+			return errors.New("unhealthy")
+		})
+
+		Expect(healthChecks.Run("https://foundation-2", "myApp", func(<-chan struct{}) error { return nil })).To(Succeed())
+	})
+
+	It("writes how long each health check took", func() {
+		healthChecks := NewHealthChecks(0, true)
+		response := NewBuffer()
+
+		healthChecks.Run("https://foundation-1", "myApp", func(<-chan struct{}) error { return nil })
+		healthChecks.Run("https://foundation-2", "myApp", func(<-chan struct{}) error { return errors.New("unhealthy") })
+		healthChecks.Run("https://foundation-3", "myApp", func(<-chan struct{}) error { return nil })
+
+		healthChecks.Write(response)
+
+		Expect(response).To(Say("Health check timings:"))
+		Expect(response).To(Say(`myApp on https://foundation-1: healthy in \d+`))
+		Expect(response).To(Say(`myApp on https://foundation-2: failed in \d+`))
+		Expect(response).To(Say("myApp on https://foundation-3: aborted"))
+	})
+
+	It("runs the health check right away without HealthChecks", func() {
+		var healthChecks *HealthChecks
+
+		Expect(healthChecks.Run("https://foundation", "myApp", func(abort <-chan struct{}) error {
+			Expect(abort).To(BeNil())
+			return nil
+		})).To(Succeed())
+	})
+})
@@ -0,0 +1,162 @@
+package push
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	"github.com/spf13/afero"
+)
+
+const journalFileExtension = ".json"
+
+// Checkpoint is the progress of the push of an application to a foundation. It is written to a Journal when the push
+// enters its push, health check and swap phases. The credentials of the deploy are never kept.
+type Checkpoint struct {
+	UUID          string    `json:"uuid"`
+	Environment   string    `json:"environment"`
+	Org           string    `json:"org"`
+	Space         string    `json:"space"`
+	AppName       string    `json:"app_name"`
+	Domain        string    `json:"domain"`
+	SkipSSL       bool      `json:"skip_ssl"`
+	FoundationURL string    `json:"foundation_url"`
+	Phase         string    `json:"phase"`
+	CandidateApp  string    `json:"candidate_app"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// RecoveryAttempts is the number of times the recovery of the push failed after a restart.
+	RecoveryAttempts int `json:"recovery_attempts,omitempty"`
+}
+
+// Journal keeps the Checkpoints of the pushes in progress in Directory, so the deploys that were interrupted by a
+// restart of Deployadactyl can be recovered when it starts again.
+type Journal struct {
+	FileSystem *afero.Afero
+	Directory  string
+	Log        I.Logger
+
+	mutex sync.Mutex
+}
+
+// NewJournal returns a Journal that keeps its checkpoints in directory, creating it if needed.
+func NewJournal(fs *afero.Afero, directory string, log I.Logger) (*Journal, error) {
+	err := fs.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, state.JournalDirectoryError{directory, err}
+	}
+
+	return &Journal{FileSystem: fs, Directory: directory, Log: log}, nil
+}
+
+// Record replaces the checkpoint of the push of the application to the foundation. A checkpoint that cannot be
+// written is logged and does not fail the deploy.
+func (j *Journal) Record(checkpoint Checkpoint) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	checkpoint.UpdatedAt = time.Now().UTC()
+	filePath := path.Join(j.Directory, journalPrefix(checkpoint.UUID, checkpoint.FoundationURL)+journalKey(checkpoint.AppName)+journalFileExtension)
+
+	body, err := json.Marshal(checkpoint)
+	if err != nil {
+		j.Log.Error(state.JournalWriteError{filePath, err})
+		return
+	}
+
+	// The checkpoint is written through a temporary file, so a crash never leaves half a checkpoint behind.
+	temporaryPath := filePath + ".tmp"
+	err = j.FileSystem.WriteFile(temporaryPath, body, 0600)
+	if err == nil {
+		err = j.FileSystem.Rename(temporaryPath, filePath)
+	}
+	if err != nil {
+		j.Log.Error(state.JournalWriteError{filePath, err})
+		return
+	}
+
+	j.Log.Debugf("recorded the %s phase of %s on %s", checkpoint.Phase, checkpoint.CandidateApp, checkpoint.FoundationURL)
+}
+
+// Remove removes the checkpoints of the deploy with the UUID on the foundation, once its pushes there are finished.
+func (j *Journal) Remove(uuid, foundationURL string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	infos, err := j.FileSystem.ReadDir(j.Directory)
+	if err != nil {
+		j.Log.Errorf("cannot read deploy journal %s: %s", j.Directory, err)
+		return
+	}
+
+	prefix := journalPrefix(uuid, foundationURL)
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), prefix) {
+			continue
+		}
+
+		err = j.FileSystem.Remove(path.Join(j.Directory, info.Name()))
+		if err != nil {
+			j.Log.Errorf("cannot remove checkpoint %s: %s", info.Name(), err)
+		}
+	}
+}
+
+// Checkpoints returns the checkpoints in the journal, ordered by the UUID of their deploy. A checkpoint that cannot
+// be read is logged and skipped.
+func (j *Journal) Checkpoints() ([]Checkpoint, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	infos, err := j.FileSystem.ReadDir(j.Directory)
+	if err != nil {
+		return nil, state.JournalDirectoryError{j.Directory, err}
+	}
+
+	checkpoints := []Checkpoint{}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), journalFileExtension) {
+			continue
+		}
+
+		filePath := path.Join(j.Directory, info.Name())
+		body, err := j.FileSystem.ReadFile(filePath)
+		if err != nil {
+			j.Log.Errorf("cannot read checkpoint %s: %s", filePath, err)
+			continue
+		}
+
+		checkpoint := Checkpoint{}
+		err = json.Unmarshal(body, &checkpoint)
+		if err != nil {
+			j.Log.Errorf("cannot read checkpoint %s: %s", filePath, err)
+			continue
+		}
+
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	sort.SliceStable(checkpoints, func(i, k int) bool {
+		return checkpoints[i].UUID < checkpoints[k].UUID
+	})
+
+	return checkpoints, nil
+}
+
+// journalPrefix is the start of the file names of the checkpoints of a deploy on a foundation. The foundation URL
+// is hashed so it can be part of a file name.
+func journalPrefix(uuid, foundationURL string) string {
+	return uuid + "-" + journalKey(foundationURL) + "-"
+}
+
+func journalKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:6])
+}
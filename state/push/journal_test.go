@@ -0,0 +1,94 @@
+package push_test
+
+import (
+	"github.com/compozed/deployadactyl/interfaces"
+	. "github.com/compozed/deployadactyl/state/push"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Journal", func() {
+	var (
+		journal    *Journal
+		fileSystem *afero.Afero
+		logBuffer  *Buffer
+		checkpoint Checkpoint
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+		logBuffer = NewBuffer()
+		journal, err = NewJournal(fileSystem, "/journal", interfaces.DefaultLogger(logBuffer, logging.DEBUG, "journal_test"))
+		Expect(err).ToNot(HaveOccurred())
+
+		checkpoint = Checkpoint{
+			UUID:          "my-uuid",
+			Environment:   "production",
+			Org:           "my-org",
+			Space:         "my-space",
+			AppName:       "myApp",
+			FoundationURL: "https://foundation-1",
+			Phase:         PushPhase,
+			CandidateApp:  "myApp-new-build-my-uuid",
+		}
+	})
+
+	It("creates its directory", func() {
+		Expect(fileSystem.DirExists("/journal")).To(BeTrue())
+	})
+
+	It("keeps the latest checkpoint of each application on each foundation", func() {
+		journal.Record(checkpoint)
+		checkpoint.Phase = HealthCheckPhase
+		journal.Record(checkpoint)
+
+		other := checkpoint
+		other.FoundationURL = "https://foundation-2"
+		journal.Record(other)
+
+		checkpoints, err := journal.Checkpoints()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(checkpoints).To(HaveLen(2))
+		Expect(checkpoints[0].Phase).To(Equal(HealthCheckPhase))
+		Expect(checkpoints[0].CandidateApp).To(Equal("myApp-new-build-my-uuid"))
+		Expect(checkpoints[0].UpdatedAt).ToNot(BeZero())
+		Expect(logBuffer).To(Say("recorded the push phase of myApp-new-build-my-uuid on https://foundation-1"))
+	})
+
+	It("removes the checkpoints of a deploy on a foundation", func() {
+		journal.Record(checkpoint)
+
+		sibling := checkpoint
+		sibling.AppName = "mySibling"
+		journal.Record(sibling)
+
+		other := checkpoint
+		other.FoundationURL = "https://foundation-2"
+		journal.Record(other)
+
+		journal.Remove("my-uuid", "https://foundation-1")
+
+		checkpoints, err := journal.Checkpoints()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(checkpoints).To(HaveLen(1))
+		Expect(checkpoints[0].FoundationURL).To(Equal("https://foundation-2"))
+	})
+
+	It("skips checkpoints that cannot be read", func() {
+		journal.Record(checkpoint)
+		Expect(fileSystem.WriteFile("/journal/broken.json", []byte("{"), 0600)).To(Succeed())
+
+		checkpoints, err := journal.Checkpoints()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(checkpoints).To(HaveLen(1))
+		Expect(logBuffer).To(Say("cannot read checkpoint /journal/broken.json"))
+	})
+})
@@ -0,0 +1,40 @@
+package push
+
+import (
+	"encoding/json"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+)
+
+// renameJSONFields gives the fields of a JSON push body that clients send with a configured name the name
+// of the field. A field sent with both names takes the value of the configured name.
+func renameJSONFields(body []byte, names map[string]string) ([]byte, error) {
+	if len(names) == 0 {
+		return body, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	err := json.Unmarshal(body, &fields)
+	if err != nil {
+		return nil, deployer.InvalidRequestBodyError{Err: err}
+	}
+
+	for field, name := range names {
+		value, ok := fields[name]
+		if !ok || name == field {
+			continue
+		}
+		fields[field] = value
+		delete(fields, name)
+	}
+
+	return json.Marshal(fields)
+}
+
+// jsonFieldName returns the name clients send a field of a JSON push with.
+func (c *PushController) jsonFieldName(field string) string {
+	if name, ok := c.Config.JSONFields[field]; ok {
+		return name
+	}
+	return field
+}
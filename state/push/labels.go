@@ -0,0 +1,60 @@
+package push
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+)
+
+const (
+	maxLabelPrefixLength = 253
+	maxLabelNameLength   = 63
+	reservedLabelPrefix  = "cloudfoundry.org"
+)
+
+var (
+	labelNamePattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+	labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+)
+
+// validateLabels checks labels against the Cloud Foundry metadata constraints.
+// Keys are an optional DNS subdomain prefix followed by a slash and a name.
+func validateLabels(labels map[string]string) error {
+	for _, key := range sortedLabelKeys(labels) {
+		value := labels[key]
+		name := key
+		if i := strings.LastIndex(key, "/"); i != -1 {
+			prefix := key[:i]
+			name = key[i+1:]
+
+			if len(prefix) > maxLabelPrefixLength || !labelPrefixPattern.MatchString(prefix) {
+				return deployer.InvalidLabelError{Key: key, Reason: "prefix must be a DNS subdomain of at most 253 characters"}
+			}
+			if prefix == reservedLabelPrefix || strings.HasSuffix(prefix, "."+reservedLabelPrefix) {
+				return deployer.InvalidLabelError{Key: key, Reason: "prefix is reserved by Cloud Foundry"}
+			}
+		}
+
+		if len(name) > maxLabelNameLength || !labelNamePattern.MatchString(name) {
+			return deployer.InvalidLabelError{Key: key, Reason: "name must be at most 63 alphanumeric characters, '-', '_' or '.' and begin and end with an alphanumeric character"}
+		}
+
+		if value != "" && (len(value) > maxLabelNameLength || !labelNamePattern.MatchString(value)) {
+			return deployer.InvalidLabelError{Key: key, Reason: "value must be at most 63 alphanumeric characters, '-', '_' or '.' and begin and end with an alphanumeric character"}
+		}
+	}
+
+	return nil
+}
+
+func sortedLabelKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,202 @@
+package push
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// ApplicationManifestFile is the name of the manifest file written to the application directory for the
+// application at index in a multi-application manifest.
+const ApplicationManifestFile = "deployadactyl-manifest-%d.yml"
+
+// Statuses of the applications of a multi-application push.
+const (
+	ApplicationNotPushed  = "not pushed"
+	ApplicationPushed     = "pushed"
+	ApplicationFailed     = "failed"
+	ApplicationRolledBack = "rolled back"
+	ApplicationDeployed   = "deployed"
+)
+
+// ApplicationStatuses records what happened to each application of a multi-application push across every foundation.
+// An application that failed on one foundation stays failed.
+type ApplicationStatuses struct {
+	applications []string
+	statuses     map[string]string
+	mutex        sync.Mutex
+}
+
+// NewApplicationStatuses marks every application as not pushed.
+func NewApplicationStatuses(applications []string) *ApplicationStatuses {
+	statuses := make(map[string]string, len(applications))
+	for _, application := range applications {
+		statuses[application] = ApplicationNotPushed
+	}
+
+	return &ApplicationStatuses{applications: applications, statuses: statuses}
+}
+
+// Set records the status of the application.
+func (s *ApplicationStatuses) Set(application, status string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.statuses[application] == ApplicationFailed {
+		return
+	}
+	s.statuses[application] = status
+}
+
+// Get returns the status of the application.
+func (s *ApplicationStatuses) Get(application string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.statuses[application]
+}
+
+// Failed returns the applications that failed, in manifest order.
+func (s *ApplicationStatuses) Failed() []string {
+	var failed []string
+	for _, application := range s.applications {
+		if s.Get(application) == ApplicationFailed {
+			failed = append(failed, application)
+		}
+	}
+
+	return failed
+}
+
+// Write writes the status of every application to the response, in manifest order.
+func (s *ApplicationStatuses) Write(response io.Writer) {
+	fmt.Fprintln(response, "Applications:")
+	for _, application := range s.applications {
+		fmt.Fprintf(response, "  %s: %s\n", application, s.Get(application))
+	}
+}
+
+// MultiPusher pushes every application of a multi-application manifest to a single foundation.
+// Each application is pushed blue green by its own Pusher, one after the other. With the fail-fast
+// failure policy, when one of them fails, the applications already pushed to the foundation are rolled
+// back with it. With the continue failure policy, the other applications are still pushed and deployed.
+type MultiPusher struct {
+	Pushers       []Pusher
+	Statuses      *ApplicationStatuses
+	FailurePolicy string
+
+	executed int
+}
+
+// Initially logs into the foundation once for every application. The org and space names the first pusher
+// found for a space GUID are shared with the others.
+func (m *MultiPusher) Initially() error {
+	err := m.Pushers[0].Initially()
+	for i := range m.Pushers[1:] {
+		m.Pushers[i+1].DeploymentInfo.Org = m.Pushers[0].DeploymentInfo.Org
+		m.Pushers[i+1].DeploymentInfo.Space = m.Pushers[0].DeploymentInfo.Space
+	}
+
+	return err
+}
+
+func (m *MultiPusher) Verify() error {
+	return nil
+}
+
+// Execute pushes the applications in order. With the fail-fast failure policy it stops at the first one
+// that fails. With the continue failure policy it only fails when the deploy is canceled or every
+// application failed.
+func (m *MultiPusher) Execute() error {
+	var firstErr error
+
+	for _, pusher := range m.Pushers {
+		m.executed++
+
+		err := pusher.Execute()
+		if err == nil {
+			m.Statuses.Set(pusher.DeploymentInfo.AppName, ApplicationPushed)
+			continue
+		}
+
+		m.Statuses.Set(pusher.DeploymentInfo.AppName, ApplicationFailed)
+		err = state.ApplicationPushError{pusher.DeploymentInfo.AppName, err}
+
+		if m.FailurePolicy != S.FailurePolicyContinue {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		pusher.Log.Errorf("could not push %s, continuing with the other applications: %s", pusher.DeploymentInfo.AppName, err)
+		if !pusher.Cancellation.Done() {
+			return err
+		}
+	}
+
+	if len(m.Statuses.Failed()) == len(m.Pushers) {
+		return firstErr
+	}
+
+	return nil
+}
+
+// Success replaces the existing applications with the ones that were pushed. An application that failed,
+// on this or another foundation, is rolled back instead so it is the same on every foundation.
+func (m *MultiPusher) Success() error {
+	for _, pusher := range m.Pushers {
+		if m.Statuses.Get(pusher.DeploymentInfo.AppName) == ApplicationFailed {
+			err := pusher.Undo()
+			if err != nil {
+				pusher.Log.Errorf("could not roll back %s: %s", pusher.DeploymentInfo.AppName, err)
+			}
+			continue
+		}
+
+		err := pusher.Success()
+		if err != nil {
+			m.Statuses.Set(pusher.DeploymentInfo.AppName, ApplicationFailed)
+			return state.ApplicationPushError{pusher.DeploymentInfo.AppName, err}
+		}
+
+		m.Statuses.Set(pusher.DeploymentInfo.AppName, ApplicationDeployed)
+	}
+
+	return nil
+}
+
+// Undo rolls back the applications that were pushed, in reverse order. Every application is rolled back
+// even when one of them fails, and the first failure is returned.
+func (m *MultiPusher) Undo() error {
+	var undoErr error
+
+	for i := m.executed - 1; i >= 0; i-- {
+		pusher := m.Pushers[i]
+
+		err := pusher.Undo()
+		if err != nil {
+			pusher.Log.Errorf("could not roll back %s: %s", pusher.DeploymentInfo.AppName, err)
+			if undoErr == nil {
+				undoErr = state.ApplicationPushError{pusher.DeploymentInfo.AppName, err}
+			}
+			continue
+		}
+
+		if pusher.Environment.EnableRollback {
+			m.Statuses.Set(pusher.DeploymentInfo.AppName, ApplicationRolledBack)
+		} else {
+			m.Statuses.Set(pusher.DeploymentInfo.AppName, ApplicationDeployed)
+		}
+	}
+
+	return undoErr
+}
+
+// Finally cleans up the courier shared by the applications.
+func (m *MultiPusher) Finally() error {
+	return m.Pushers[0].Finally()
+}
@@ -0,0 +1,231 @@
+package push_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("MultiPusher", func() {
+	var (
+		courier      *mocks.Courier
+		eventManager *mocks.EventManager
+		statuses     *ApplicationStatuses
+		multiPusher  *MultiPusher
+		logBuffer    *Buffer
+		applications = []string{"frontend", "backend", "worker"}
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		eventManager = &mocks.EventManager{}
+		logBuffer = NewBuffer()
+		statuses = NewApplicationStatuses(applications)
+
+		multiPusher = &MultiPusher{Statuses: statuses}
+		for i, application := range applications {
+			multiPusher.Pushers = append(multiPusher.Pushers, Pusher{
+				Courier:        courier,
+				DeploymentInfo: S.DeploymentInfo{AppName: application, UUID: "uuid", Instances: uint16(i + 1)},
+				EventManager:   eventManager,
+				Response:       NewBuffer(),
+				Log:            interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "multipusher_test")},
+				FoundationURL:  "https://api.example.com",
+				AppPath:        "appPath",
+				Environment:    S.Environment{EnableRollback: true},
+				ManifestFile:   "manifest-" + application + ".yml",
+			})
+		}
+	})
+
+	Describe("Initially", func() {
+		It("logs into the foundation", func() {
+			Expect(multiPusher.Initially()).To(Succeed())
+
+			Expect(courier.LoginCall.Received.FoundationURL).To(Equal("https://api.example.com"))
+		})
+
+		It("shares the org and space found for a space GUID with every application", func() {
+			for i := range multiPusher.Pushers {
+				multiPusher.Pushers[i].DeploymentInfo.SpaceGUID = "5a1b2c3d-0000-4000-8000-000000000001"
+				multiPusher.Pushers[i].DeploymentInfo.Org = NameFromSpaceGUID
+				multiPusher.Pushers[i].DeploymentInfo.Space = NameFromSpaceGUID
+			}
+			courier.SpaceByGUIDCall.Returns.Space = S.Space{GUID: "5a1b2c3d-0000-4000-8000-000000000001", Name: "my-space", OrgName: "my-org"}
+
+			Expect(multiPusher.Initially()).To(Succeed())
+
+			for _, pusher := range multiPusher.Pushers {
+				Expect(pusher.DeploymentInfo.Org).To(Equal("my-org"))
+				Expect(pusher.DeploymentInfo.Space).To(Equal("my-space"))
+			}
+		})
+	})
+
+	Describe("Execute", func() {
+		It("pushes every application with its own manifest", func() {
+			Expect(multiPusher.Execute()).To(Succeed())
+
+			Expect(courier.PushWithManifestCall.Received.AppName).To(Equal([]string{
+				"frontend" + TemporaryNameSuffix + "uuid",
+				"backend" + TemporaryNameSuffix + "uuid",
+				"worker" + TemporaryNameSuffix + "uuid",
+			}))
+			Expect(courier.PushWithManifestCall.Received.Hostname).To(Equal(applications))
+			Expect(courier.PushWithManifestCall.Received.ManifestFile).To(Equal([]string{"manifest-frontend.yml", "manifest-backend.yml", "manifest-worker.yml"}))
+			Expect(courier.PushWithManifestCall.Received.Instances).To(Equal([]uint16{1, 2, 3}))
+
+			for _, application := range applications {
+				Expect(statuses.Get(application)).To(Equal(ApplicationPushed))
+			}
+		})
+
+		It("stops at the first application that fails", func() {
+			courier.PushWithManifestCall.Returns.Error = map[string]error{"backend": errors.New("push failed")}
+
+			err := multiPusher.Execute()
+
+			Expect(err).To(MatchError(state.ApplicationPushError{"backend", state.PushError{}}))
+			Expect(courier.PushWithManifestCall.TimesCalled).To(Equal(2))
+			Expect(statuses.Get("frontend")).To(Equal(ApplicationPushed))
+			Expect(statuses.Get("backend")).To(Equal(ApplicationFailed))
+			Expect(statuses.Get("worker")).To(Equal(ApplicationNotPushed))
+		})
+
+		Context("with the continue failure policy", func() {
+			BeforeEach(func() {
+				multiPusher.FailurePolicy = S.FailurePolicyContinue
+			})
+
+			It("pushes the other applications when one fails", func() {
+				courier.PushWithManifestCall.Returns.Error = map[string]error{"backend": errors.New("push failed")}
+
+				Expect(multiPusher.Execute()).To(Succeed())
+
+				Expect(courier.PushWithManifestCall.TimesCalled).To(Equal(3))
+				Expect(statuses.Get("frontend")).To(Equal(ApplicationPushed))
+				Expect(statuses.Get("backend")).To(Equal(ApplicationFailed))
+				Expect(statuses.Get("worker")).To(Equal(ApplicationPushed))
+				Expect(logBuffer).To(Say("could not push backend, continuing with the other applications"))
+			})
+
+			It("fails when every application fails", func() {
+				courier.PushWithManifestCall.Returns.Error = map[string]error{
+					"frontend": errors.New("push failed"),
+					"backend":  errors.New("push failed"),
+					"worker":   errors.New("push failed"),
+				}
+
+				err := multiPusher.Execute()
+
+				Expect(err).To(MatchError(state.ApplicationPushError{"frontend", state.PushError{}}))
+				Expect(statuses.Failed()).To(Equal(applications))
+			})
+		})
+	})
+
+	Describe("Success", func() {
+		It("replaces every application", func() {
+			Expect(multiPusher.Execute()).To(Succeed())
+			Expect(multiPusher.Success()).To(Succeed())
+
+			Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal("worker"))
+			for _, application := range applications {
+				Expect(statuses.Get(application)).To(Equal(ApplicationDeployed))
+			}
+		})
+
+		It("rolls back the applications that failed with the continue failure policy", func() {
+			multiPusher.FailurePolicy = S.FailurePolicyContinue
+			courier.ExistsCall.Returns.Bool = true
+			courier.PushWithManifestCall.Returns.Error = map[string]error{"backend": errors.New("push failed")}
+
+			Expect(multiPusher.Execute()).To(Succeed())
+			Expect(multiPusher.Success()).To(Succeed())
+
+			Expect(logBuffer).To(Say("rolling back deploy of backend" + TemporaryNameSuffix + "uuid"))
+			Expect(statuses.Get("frontend")).To(Equal(ApplicationDeployed))
+			Expect(statuses.Get("backend")).To(Equal(ApplicationFailed))
+			Expect(statuses.Get("worker")).To(Equal(ApplicationDeployed))
+		})
+	})
+
+	Describe("Undo", func() {
+		It("rolls back only the applications that were pushed", func() {
+			courier.ExistsCall.Returns.Bool = true
+			courier.PushWithManifestCall.Returns.Error = map[string]error{"backend": errors.New("push failed")}
+
+			Expect(multiPusher.Execute()).ToNot(Succeed())
+			Expect(multiPusher.Undo()).To(Succeed())
+
+			Expect(courier.DeleteCall.Received.AppName).To(Equal("frontend" + TemporaryNameSuffix + "uuid"))
+			Expect(statuses.Get("frontend")).To(Equal(ApplicationRolledBack))
+			Expect(statuses.Get("backend")).To(Equal(ApplicationFailed))
+			Expect(statuses.Get("worker")).To(Equal(ApplicationNotPushed))
+		})
+
+		It("rolls back every application when another foundation failed", func() {
+			courier.ExistsCall.Returns.Bool = true
+
+			Expect(multiPusher.Execute()).To(Succeed())
+			Expect(multiPusher.Undo()).To(Succeed())
+
+			for _, application := range applications {
+				Expect(statuses.Get(application)).To(Equal(ApplicationRolledBack))
+			}
+		})
+
+		It("keeps rolling back when an application cannot be rolled back", func() {
+			courier.ExistsCall.Returns.Bool = true
+			courier.DeleteCall.Returns.Error = errors.New("delete failed")
+
+			Expect(multiPusher.Execute()).To(Succeed())
+			err := multiPusher.Undo()
+
+			Expect(err).To(BeAssignableToTypeOf(state.ApplicationPushError{}))
+			Expect(err.Error()).To(HavePrefix("worker: "))
+			Expect(logBuffer).To(Say("could not roll back worker"))
+			Expect(logBuffer).To(Say("could not roll back backend"))
+			Expect(logBuffer).To(Say("could not roll back frontend"))
+		})
+	})
+})
+
+var _ = Describe("ApplicationStatuses", func() {
+	It("keeps an application failed", func() {
+		statuses := NewApplicationStatuses([]string{"frontend", "backend"})
+
+		statuses.Set("backend", ApplicationFailed)
+		statuses.Set("backend", ApplicationRolledBack)
+		statuses.Set("frontend", ApplicationDeployed)
+
+		Expect(statuses.Get("backend")).To(Equal(ApplicationFailed))
+		Expect(statuses.Get("frontend")).To(Equal(ApplicationDeployed))
+	})
+
+	It("returns the applications that failed in order", func() {
+		statuses := NewApplicationStatuses([]string{"frontend", "backend", "worker"})
+		statuses.Set("worker", ApplicationFailed)
+		statuses.Set("frontend", ApplicationFailed)
+
+		Expect(statuses.Failed()).To(Equal([]string{"frontend", "worker"}))
+	})
+
+	It("writes the status of every application in order", func() {
+		statuses := NewApplicationStatuses([]string{"frontend", "backend"})
+		statuses.Set("frontend", ApplicationDeployed)
+
+		response := NewBuffer()
+		statuses.Write(response)
+
+		Expect(string(response.Contents())).To(Equal("Applications:\n  frontend: deployed\n  backend: not pushed\n"))
+	})
+})
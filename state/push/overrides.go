@@ -0,0 +1,109 @@
+package push
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// overridablePushFlags are the manifest settings of the push flags a request may set.
+var overridablePushFlags = map[string]string{
+	"-m":                  "memory",
+	"-k":                  "disk_quota",
+	"-s":                  "stack",
+	"-t":                  "timeout",
+	"-u":                  "health-check-type",
+	"--health-check-type": "health-check-type",
+}
+
+// manifestOverride is a setting of the manifest that the request of a push replaces with another value.
+type manifestOverride struct {
+	setting  string
+	manifest string
+	request  string
+}
+
+func (o manifestOverride) String() string {
+	return fmt.Sprintf("%s %s instead of %s", o.setting, o.request, o.manifest)
+}
+
+// checkOverrides applies the override conflict policy of the environment to the settings of the manifest that the
+// command, buildpacks and push flags of the request override. The request wins unless the policy rejects the push
+// with a ManifestOverrideError. With the warn policy, each overridden setting is written to the response.
+func (a *PushManager) checkOverrides(manifest string) error {
+	info := a.DeployEventData.DeploymentInfo
+
+	overrides := findOverrides(manifest, info)
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	switch a.Environment.OverrideConflictPolicy {
+	case S.OverrideConflictReject:
+		descriptions := make([]string, 0, len(overrides))
+		for _, override := range overrides {
+			descriptions = append(descriptions, override.String())
+		}
+		return deployer.ManifestOverrideError{info.AppName, descriptions}
+	case S.OverrideConflictSilent:
+		for _, override := range overrides {
+			a.Logger.Debugf("the request overrides the manifest of %s: %s", info.AppName, override)
+		}
+	default:
+		for _, override := range overrides {
+			a.Logger.Errorf("WARNING: the request overrides the manifest of %s: %s", info.AppName, override)
+			fmt.Fprintf(a.DeployEventData.Response, "WARNING: the request overrides the manifest of %s: %s\n", info.AppName, override)
+		}
+	}
+
+	return nil
+}
+
+// findOverrides returns the settings of the manifest that the request sets to another value. Memory and disk sizes
+// are compared in megabytes, so 1G does not override 1024M.
+func findOverrides(manifest string, info *S.DeploymentInfo) []manifestOverride {
+	var overrides []manifestOverride
+
+	if info.Command != "" {
+		overrides = appendOverride(overrides, "command", manifestro.GetSetting(manifest, "command"), info.Command)
+	}
+
+	if len(info.Buildpacks) > 0 {
+		buildpacks := manifestro.GetSetting(manifest, "buildpacks")
+		if buildpacks == "" {
+			buildpacks = manifestro.GetSetting(manifest, "buildpack")
+		}
+		overrides = appendOverride(overrides, "buildpacks", buildpacks, strings.Join(info.Buildpacks, ", "))
+	}
+
+	for _, flag := range info.PushFlags {
+		name, value, ok := S.ParsePushFlag(flag)
+		setting, overridable := overridablePushFlags[name]
+		if !ok || !overridable {
+			continue
+		}
+		overrides = appendOverride(overrides, setting, manifestro.GetSetting(manifest, setting), value)
+	}
+
+	return overrides
+}
+
+// appendOverride appends the override of a setting that the manifest sets to another value than the request.
+func appendOverride(overrides []manifestOverride, setting, manifest, request string) []manifestOverride {
+	if manifest == "" || manifest == request {
+		return overrides
+	}
+
+	if setting == "memory" || setting == "disk_quota" {
+		manifestSize, manifestOK := S.ParseMegabytes(manifest)
+		requestSize, requestOK := S.ParseMegabytes(request)
+		if manifestOK && requestOK && manifestSize == requestSize {
+			return overrides
+		}
+	}
+
+	return append(overrides, manifestOverride{setting, manifest, request})
+}
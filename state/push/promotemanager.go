@@ -0,0 +1,109 @@
+package push
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+const successfulPromotion = `Your promotion was successful! (^_^)b
+%s now serves the candidate of deploy %s.
+
+`
+
+// PromoteManager creates a Promoter for each foundation to complete the manual cutover of a deploy.
+// The pending promotion is forgotten once every foundation has been promoted.
+type PromoteManager struct {
+	CourierCreator  courierCreator
+	EventManager    I.EventManager
+	Logger          I.DeploymentLogger
+	DeployEventData S.DeployEventData
+	Promotions      *Promotions
+	Retirements     *Retirements
+}
+
+func (a PromoteManager) SetUp() error {
+	return nil
+}
+
+func (a PromoteManager) OnStart() error {
+	info := a.DeployEventData.DeploymentInfo
+
+	a.Logger.Infof("promoting %s with UUID %s", info.AppName, info.UUID)
+	fmt.Fprintf(a.DeployEventData.Response, "Promoting %s%s%s to %s in environment %s\n", info.AppName, TemporaryNameSuffix, info.UUID, info.AppName, info.Environment)
+
+	return nil
+}
+
+func (a PromoteManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
+	if err != nil {
+		fmt.Fprintf(response, "\nYour application was not successfully promoted on all foundations: %s\n\n", err.Error())
+		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+		}
+		return I.DeployResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err,
+		}
+	}
+
+	info := a.DeployEventData.DeploymentInfo
+	a.Promotions.Remove(info.UUID)
+
+	a.Logger.Infof("successfully promoted application %s", info.AppName)
+	fmt.Fprintf(response, "\n"+successfulPromotion, info.AppName, info.UUID)
+
+	return I.DeployResponse{StatusCode: http.StatusOK}
+}
+
+func (a PromoteManager) CleanUp() {}
+
+func (a PromoteManager) Create(environment S.Environment, response io.ReadWriter, foundationURL string) (I.Action, error) {
+	courier, err := a.CourierCreator.CreateCourier()
+	if err != nil {
+		a.Logger.Error(err)
+		return &Promoter{}, state.CourierCreationError{Err: err}
+	}
+
+	deploymentInfo := *a.DeployEventData.DeploymentInfo
+	deploymentInfo.ManualCutover = false
+	environment.ManualCutover = false
+
+	return &Promoter{
+		Pusher: Pusher{
+			Courier:        courier,
+			DeploymentInfo: deploymentInfo,
+			EventManager:   a.EventManager,
+			Response:       response,
+			Log:            a.Logger,
+			FoundationURL:  foundationURL,
+			Environment:    environment,
+			Retirements:    a.Retirements,
+		},
+	}, nil
+}
+
+func (a PromoteManager) InitiallyError(initiallyErrors []error) error {
+	return bluegreen.LoginError{LoginErrors: initiallyErrors}
+}
+
+func (a PromoteManager) ExecuteError(executeErrors []error) error {
+	return bluegreen.PushError{PushErrors: executeErrors}
+}
+
+func (a PromoteManager) UndoError(executeErrors, undoErrors []error) error {
+	return bluegreen.RollbackError{PushErrors: executeErrors, RollbackErrors: undoErrors}
+}
+
+func (a PromoteManager) SuccessError(successErrors []error) error {
+	return bluegreen.FinishPushError{FinishPushError: successErrors}
+}
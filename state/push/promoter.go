@@ -0,0 +1,104 @@
+package push
+
+import (
+	"github.com/compozed/deployadactyl/state"
+)
+
+// Promoter completes the manual cutover of a deploy on a single foundation. The production routes are
+// mapped to the candidate application, the existing application is deleted and the candidate takes its name.
+type Promoter struct {
+	Pusher
+
+	// domain is the domain of the candidate's temporary route.
+	domain string
+}
+
+// Execute maps the production routes to the candidate application.
+func (p *Promoter) Execute() error {
+	candidate := p.candidate()
+
+	if !p.Courier.Exists(candidate) {
+		p.Log.Errorf("candidate %s does not exist on %s", candidate, p.FoundationURL)
+		return state.CandidateNotFoundError{candidate, p.FoundationURL}
+	}
+
+	domain, err := p.temporaryDomain(candidate)
+	if err != nil {
+		return err
+	}
+	p.domain = domain
+
+	p.Log.Debugf("mapping %s.%s to %s", p.DeploymentInfo.AppName, p.domain, candidate)
+	out, err := p.Courier.MapRoute(candidate, p.domain, p.DeploymentInfo.AppName)
+	p.Response.Write(out)
+	if err != nil {
+		p.Log.Errorf("could not map %s.%s to %s", p.DeploymentInfo.AppName, p.domain, candidate)
+		return state.MapRouteError{out}
+	}
+	p.Log.Infof("mapped %s.%s to %s", p.DeploymentInfo.AppName, p.domain, candidate)
+
+	if p.DeploymentInfo.Domain != "" {
+		return p.mapTempAppToLoadBalancedDomain(candidate)
+	}
+
+	return nil
+}
+
+// Success deletes the existing application, renames the candidate to the application name and
+// removes the candidate's temporary route. Failing to remove the temporary route does not fail the promotion.
+func (p *Promoter) Success() error {
+	err := p.Pusher.Success()
+	if err != nil {
+		return err
+	}
+
+	candidate := p.candidate()
+
+	out, err := p.Courier.UnmapRoute(p.DeploymentInfo.AppName, p.domain, candidate)
+	if err != nil {
+		p.Log.Errorf("could not unmap temporary route %s.%s: %s", candidate, p.domain, out)
+		return nil
+	}
+
+	out, err = p.Courier.DeleteRoute(p.domain, candidate)
+	if err != nil {
+		p.Log.Errorf("could not delete temporary route %s.%s: %s", candidate, p.domain, out)
+		return nil
+	}
+
+	p.Log.Infof("deleted temporary route %s.%s", candidate, p.domain)
+
+	return nil
+}
+
+// Undo unmaps the production routes from the candidate application, leaving both applications
+// as they were before the promotion.
+func (p *Promoter) Undo() error {
+	candidate := p.candidate()
+
+	if p.domain == "" {
+		return nil
+	}
+
+	p.Log.Errorf("rolling back promotion of %s", candidate)
+
+	out, err := p.Courier.UnmapRoute(candidate, p.domain, p.DeploymentInfo.AppName)
+	if err != nil {
+		p.Log.Errorf("could not unmap %s.%s from %s", p.DeploymentInfo.AppName, p.domain, candidate)
+		return state.UnmapRouteError{candidate, out}
+	}
+
+	if p.DeploymentInfo.Domain != "" {
+		out, err = p.Courier.UnmapRoute(candidate, p.DeploymentInfo.Domain, p.DeploymentInfo.AppName)
+		if err != nil {
+			p.Log.Errorf("could not unmap %s.%s from %s", p.DeploymentInfo.AppName, p.DeploymentInfo.Domain, candidate)
+			return state.UnmapRouteError{candidate, out}
+		}
+	}
+
+	return nil
+}
+
+func (p *Promoter) candidate() string {
+	return p.DeploymentInfo.AppName + TemporaryNameSuffix + p.DeploymentInfo.UUID
+}
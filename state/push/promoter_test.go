@@ -0,0 +1,199 @@
+package push_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Promoter", func() {
+	var (
+		promoter  *Promoter
+		courier   *mocks.Courier
+		response  *Buffer
+		logBuffer *Buffer
+		candidate string
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		response = NewBuffer()
+		logBuffer = NewBuffer()
+		candidate = "myApp" + TemporaryNameSuffix + "my-uuid"
+
+		courier.ExistsCall.Returns.Bool = true
+		courier.RoutesCall.Returns.Routes = []string{candidate + ".apps.example.com"}
+
+		promoter = &Promoter{
+			Pusher: Pusher{
+				Courier:        courier,
+				DeploymentInfo: S.DeploymentInfo{AppName: "myApp", UUID: "my-uuid"},
+				Response:       response,
+				Log:            interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "promoter_test")},
+				FoundationURL:  "https://foundation",
+			},
+		}
+	})
+
+	Describe("Execute", func() {
+		It("maps the production route to the candidate", func() {
+			Expect(promoter.Execute()).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{candidate}))
+			Expect(courier.MapRouteCall.Received.Domain).To(Equal([]string{"apps.example.com"}))
+			Expect(courier.MapRouteCall.Received.Hostname).To(Equal([]string{"myApp"}))
+
+			Eventually(logBuffer).Should(Say("mapped myApp.apps.example.com to %s", candidate))
+		})
+
+		It("maps the load balanced route when the environment has a domain", func() {
+			promoter.DeploymentInfo.Domain = "example.com"
+
+			Expect(promoter.Execute()).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.Domain).To(Equal([]string{"apps.example.com", "example.com"}))
+		})
+
+		It("returns an error when the candidate does not exist", func() {
+			courier.ExistsCall.Returns.Bool = false
+
+			Expect(promoter.Execute()).To(MatchError(state.CandidateNotFoundError{candidate, "https://foundation"}))
+			Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("returns an error when the routes cannot be read", func() {
+			courier.RoutesCall.Returns.Error = errors.New("routes error")
+
+			Expect(promoter.Execute()).To(MatchError(state.RoutesError{candidate, errors.New("routes error")}))
+		})
+
+		It("returns an error when the candidate has no temporary route", func() {
+			courier.RoutesCall.Returns.Routes = []string{"myApp.apps.example.com"}
+
+			Expect(promoter.Execute()).To(MatchError(state.NoTemporaryRouteError{candidate}))
+		})
+	})
+
+	Describe("Success", func() {
+		BeforeEach(func() {
+			Expect(promoter.Execute()).To(Succeed())
+		})
+
+		It("renames the candidate and removes its temporary route", func() {
+			Expect(promoter.Success()).To(Succeed())
+
+			Expect(courier.RenameCall.Received.AppName).To(Equal(candidate))
+			Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal("myApp"))
+			Expect(courier.UnmapRouteCall.Received.AppName).To(Equal("myApp"))
+			Expect(courier.UnmapRouteCall.Received.Hostname).To(Equal(candidate))
+			Expect(courier.DeleteRouteCall.Received.Domain).To(Equal("apps.example.com"))
+			Expect(courier.DeleteRouteCall.Received.Hostname).To(Equal(candidate))
+		})
+
+		It("does not fail when the temporary route cannot be deleted", func() {
+			courier.DeleteRouteCall.Returns.Error = errors.New("delete route error")
+
+			Expect(promoter.Success()).To(Succeed())
+
+			Eventually(logBuffer).Should(Say("could not delete temporary route %s.apps.example.com", candidate))
+		})
+	})
+
+	Describe("Undo", func() {
+		It("unmaps the production route from the candidate", func() {
+			Expect(promoter.Execute()).To(Succeed())
+
+			Expect(promoter.Undo()).To(Succeed())
+
+			Expect(courier.UnmapRouteCall.Received.AppName).To(Equal(candidate))
+			Expect(courier.UnmapRouteCall.Received.Domain).To(Equal("apps.example.com"))
+			Expect(courier.UnmapRouteCall.Received.Hostname).To(Equal("myApp"))
+		})
+
+		It("does nothing when no route was mapped", func() {
+			Expect(promoter.Undo()).To(Succeed())
+
+			Expect(courier.UnmapRouteCall.Received.AppName).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("Promotions", func() {
+	It("forgets a promotion once it is removed", func() {
+		promotions := NewPromotions()
+		promotions.Add(PendingPromotion{UUID: "my-uuid", AppName: "myApp", PushedAt: time.Now()})
+
+		promotion, ok := promotions.Get("my-uuid")
+		Expect(ok).To(BeTrue())
+		Expect(promotion.Candidate()).To(Equal("myApp" + TemporaryNameSuffix + "my-uuid"))
+
+		promotions.Remove("my-uuid")
+
+		_, ok = promotions.Get("my-uuid")
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("PromoteManager", func() {
+	var (
+		promoteManager PromoteManager
+		promotions     *Promotions
+		response       *Buffer
+	)
+
+	BeforeEach(func() {
+		response = NewBuffer()
+		promotions = NewPromotions()
+		promotions.Add(PendingPromotion{UUID: "my-uuid", AppName: "myApp"})
+
+		promoteManager = PromoteManager{
+			CourierCreator: courierCreator{},
+			Logger:         interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(NewBuffer(), logging.DEBUG, "promotemanager_test")},
+			DeployEventData: S.DeployEventData{
+				DeploymentInfo: &S.DeploymentInfo{AppName: "myApp", UUID: "my-uuid", ManualCutover: true},
+				Response:       response,
+			},
+			Promotions: promotions,
+		}
+	})
+
+	It("creates a Promoter that does not wait for another cutover", func() {
+		action, err := promoteManager.Create(S.Environment{ManualCutover: true}, response, "https://foundation")
+		Expect(err).ToNot(HaveOccurred())
+
+		promoter := action.(*Promoter)
+		Expect(promoter.DeploymentInfo.ManualCutover).To(BeFalse())
+		Expect(promoter.Environment.ManualCutover).To(BeFalse())
+		Expect(promoter.FoundationURL).To(Equal("https://foundation"))
+	})
+
+	It("forgets the pending promotion when it succeeds", func() {
+		deployResponse := promoteManager.OnFinish(S.Environment{}, response, nil)
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusOK))
+		Eventually(response).Should(Say("Your promotion was successful!"))
+
+		_, ok := promotions.Get("my-uuid")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps the pending promotion when it fails", func() {
+		deployResponse := promoteManager.OnFinish(S.Environment{}, response, errors.New("map route failed"))
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusInternalServerError))
+		Eventually(response).Should(Say("not successfully promoted"))
+
+		_, ok := promotions.Get("my-uuid")
+		Expect(ok).To(BeTrue())
+	})
+})
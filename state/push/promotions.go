@@ -0,0 +1,59 @@
+package push
+
+import (
+	"sync"
+	"time"
+)
+
+// PendingPromotion is a deploy whose candidate application was left next to the existing application
+// for a manual cutover.
+type PendingPromotion struct {
+	UUID        string
+	Environment string
+	Org         string
+	Space       string
+	AppName     string
+	PushedAt    time.Time
+}
+
+// Candidate returns the name of the candidate application.
+func (p PendingPromotion) Candidate() string {
+	return p.AppName + TemporaryNameSuffix + p.UUID
+}
+
+// Promotions tracks the deploys waiting to be promoted by their UUID.
+// They are only kept in memory and are lost when Deployadactyl restarts.
+type Promotions struct {
+	pending map[string]PendingPromotion
+	mutex   sync.Mutex
+}
+
+// NewPromotions returns Promotions without any pending promotion.
+func NewPromotions() *Promotions {
+	return &Promotions{pending: map[string]PendingPromotion{}}
+}
+
+// Add records a deploy as waiting to be promoted.
+func (p *Promotions) Add(promotion PendingPromotion) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pending[promotion.UUID] = promotion
+}
+
+// Get returns the pending promotion of the deploy with the UUID.
+func (p *Promotions) Get(uuid string) (PendingPromotion, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	promotion, ok := p.pending[uuid]
+	return promotion, ok
+}
+
+// Remove forgets the pending promotion of the deploy with the UUID.
+func (p *Promotions) Remove(uuid string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.pending, uuid)
+}
@@ -2,24 +2,46 @@ package push
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"github.com/compozed/deployadactyl/artifetcher/extractor"
 	"github.com/compozed/deployadactyl/config"
 	"github.com/compozed/deployadactyl/constants"
 	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/approval"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/controller/deployer/hostpolicy"
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	"github.com/compozed/deployadactyl/controller/deployer/validator"
+	"github.com/compozed/deployadactyl/eventmanager/retry"
 	"github.com/compozed/deployadactyl/geterrors"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/tracing"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 )
 
-type PushControllerConstructor func(log I.DeploymentLogger, deployer, silentDeployer I.Deployer, conf config.Config, eventManager I.EventManager, errorFinder I.ErrorFinder, pushManagerFactory I.PushManagerFactory) I.PushController
+// cancelRetryInterval is how often a deploy whose request timed out is canceled again until it is set up.
+const cancelRetryInterval = 100 * time.Millisecond
 
-func NewPushController(l I.DeploymentLogger, d, sd I.Deployer, c config.Config, em I.EventManager, ef I.ErrorFinder, pmf I.PushManagerFactory) I.PushController {
+// NameFromSpaceGUID is the org or space name of a push with a space_guid that takes the name from the space.
+const NameFromSpaceGUID = "-"
+
+// guidPattern matches a Cloud Foundry GUID.
+var guidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type PushControllerConstructor func(log I.DeploymentLogger, deployer, silentDeployer I.Deployer, conf config.Config, eventManager I.EventManager, errorFinder I.ErrorFinder, pushManagerFactory I.PushManagerFactory, validator I.DeployValidator) I.PushController
+
+func NewPushController(l I.DeploymentLogger, d, sd I.Deployer, c config.Config, em I.EventManager, ef I.ErrorFinder, pmf I.PushManagerFactory, v I.DeployValidator) I.PushController {
 	return &PushController{
 		Deployer:           d,
 		SilentDeployer:     sd,
@@ -28,6 +50,7 @@ func NewPushController(l I.DeploymentLogger, d, sd I.Deployer, c config.Config,
 		ErrorFinder:        ef,
 		PushManagerFactory: pmf,
 		Log:                l,
+		Validator:          v,
 	}
 }
 
@@ -39,20 +62,59 @@ type PushController struct {
 	EventManager       I.EventManager
 	ErrorFinder        I.ErrorFinder
 	PushManagerFactory I.PushManagerFactory
+
+	// Validator approves deploys before they start. Deploys are not validated when it is nil.
+	Validator I.DeployValidator
+
+	// Approver holds deploys to environments that require approval until they are approved. Deploys to those
+	// environments are rejected when it is nil.
+	Approver I.DeployApprover
+
+	// Tracer records the deploy events as a span. They are not traced when it is nil.
+	Tracer *tracing.Tracer
+
+	// EventRetries keeps success and failure events whose emission failed to emit them again later. They are not
+	// retried when it is nil.
+	EventRetries *retry.Queue
+
+	// ArtifactTypes are the content types, besides application/zip, of artifacts a push may send.
+	ArtifactTypes []string
+
+	// Now returns the current time, to time deploys against the deploy SLO of their environment. Defaults to time.Now.
+	Now func() time.Time
+
+	// Cancellations cancels the deploys whose Context passes its deadline. They are not canceled when it is nil.
+	Cancellations *deployer.Cancellations
 }
 
 // PUSH specific
 func (c *PushController) RunDeployment(deployment *I.Deployment, response *bytes.Buffer) (deployResponse I.DeployResponse) {
+	// The output of the deploy is also sent to the Output of the deployment as it is written.
+	var output io.ReadWriter = response
+	if deployment.Output != nil {
+		output = streamingResponse{response, deployment.Output}
+	}
+
 	cf := deployment.CFContext
 	deploymentInfo := &structs.DeploymentInfo{
-		Org:         cf.Organization,
-		Space:       cf.Space,
-		AppName:     cf.Application,
-		Environment: cf.Environment,
-		UUID:        c.Log.UUID,
+		Org:            cf.Organization,
+		Space:          cf.Space,
+		AppName:        cf.Application,
+		Environment:    cf.Environment,
+		UUID:           c.Log.UUID,
+		NoCache:        deployment.NoCache,
+		ClientIdentity: deployment.ClientIdentity,
+		ChangeTicket:   deployment.ChangeTicket,
+		Reason:         deployment.Reason,
+
+		AllApplications: cf.Application == "",
+		FailurePolicy:   deployment.FailurePolicy,
 	}
 
 	c.Log.Debugf("Starting deploy of %s with UUID %s", cf.Application, deploymentInfo.UUID)
+	if deployment.ClientIdentity != "" {
+		c.Log.Infof("deploy of %s triggered by client %s", cf.Application, deployment.ClientIdentity)
+	}
 	c.Log.Debug("building deploymentInfo")
 
 	body := ioutil.NopCloser(bytes.NewBuffer(*deployment.Body))
@@ -64,6 +126,12 @@ func (c *PushController) RunDeployment(deployment *I.Deployment, response *bytes
 		c.Log.Debug("deploying from zip request")
 		deploymentInfo.Body = body
 		deploymentInfo.ContentType = "ZIP"
+		deploymentInfo.ArtifactType = deployment.ArtifactType
+	} else if deployment.Type.Multipart {
+		c.Log.Debug("deploying from multipart request")
+		deploymentInfo.Body = body
+		deploymentInfo.Manifest = base64.StdEncoding.EncodeToString([]byte(deployment.Manifest))
+		deploymentInfo.ContentType = "MULTIPART"
 	} else {
 		return I.DeployResponse{
 			StatusCode: http.StatusBadRequest,
@@ -72,7 +140,7 @@ func (c *PushController) RunDeployment(deployment *I.Deployment, response *bytes
 	}
 	environment, err := c.resolveEnvironment(cf.Environment)
 	if err != nil {
-		fmt.Fprintln(response, err.Error())
+		fmt.Fprintln(output, err.Error())
 		return I.DeployResponse{
 			StatusCode: http.StatusInternalServerError,
 			Error:      err,
@@ -86,27 +154,287 @@ func (c *PushController) RunDeployment(deployment *I.Deployment, response *bytes
 		}
 	}
 
+	err = checkOrgAndSpace(cf, environment)
+	if err != nil {
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      err,
+		}
+	}
+
+	if deployment.SkipSSL != nil && *deployment.SkipSSL != environment.SkipSSL {
+		c.Log.Infof("skip ssl of environment %s is overridden from %t to %t for this deploy", cf.Environment, environment.SkipSSL, *deployment.SkipSSL)
+		environment.SkipSSL = *deployment.SkipSSL
+	}
+	c.Log.Infof("deploying with skip ssl %t", environment.SkipSSL)
+
 	deploymentInfo.Username = auth.Username
 	deploymentInfo.Password = auth.Password
 	deploymentInfo.Domain = environment.Domain
 	deploymentInfo.SkipSSL = environment.SkipSSL
-	deploymentInfo.CustomParams = environment.CustomParams
+	// The custom params of the request body are merged over a copy so they never change the environment.
+	deploymentInfo.CustomParams = copyCustomParams(environment.CustomParams)
 
 	if deployment.Type.JSON {
 		deploymentInfo, err = c.getDeploymentInfo(deployment.Body, deploymentInfo)
+		// Only the environment and the skipSSL query param decide whether TLS is verified, never the body.
+		deploymentInfo.SkipSSL = environment.SkipSSL
+		if err != nil {
+			c.Log.Error(err)
+
+			statusCode := http.StatusBadRequest
+			if _, ok := err.(deployer.AppSpecNotFoundError); ok {
+				statusCode = http.StatusNotFound
+			}
+			return I.DeployResponse{
+				StatusCode:     statusCode,
+				Error:          err,
+				DeploymentInfo: deploymentInfo,
+			}
+		}
+
+		err = c.checkArtifactHost(deploymentInfo.ArtifactURL)
+		if err != nil {
+			c.Log.Error(err)
+			return I.DeployResponse{
+				StatusCode:     http.StatusForbidden,
+				Error:          err,
+				DeploymentInfo: deploymentInfo,
+			}
+		}
+
+		if deploymentInfo.ProbeCommand != "" && !environment.AllowRequestProbe {
+			err = deployer.ProbeNotAllowedError{cf.Environment}
+			c.Log.Error(err)
+			return I.DeployResponse{
+				StatusCode:     http.StatusBadRequest,
+				Error:          err,
+				DeploymentInfo: deploymentInfo,
+			}
+		}
+
+		err = checkSpaceGUID(deploymentInfo, environment, cf.Environment)
 		if err != nil {
 			c.Log.Error(err)
 			return I.DeployResponse{
-				StatusCode:     http.StatusInternalServerError,
+				StatusCode:     http.StatusBadRequest,
 				Error:          err,
 				DeploymentInfo: deploymentInfo,
 			}
 		}
+
+		if !deploymentInfo.AllApplications && !deployment.AllowNameMismatch {
+			err = checkManifestAppName(deploymentInfo)
+			if err != nil {
+				c.Log.Error(err)
+				return I.DeployResponse{
+					StatusCode:     http.StatusBadRequest,
+					Error:          err,
+					DeploymentInfo: deploymentInfo,
+				}
+			}
+		}
+
+		if deploymentInfo.Profile != "" {
+			err = c.applyProfile(deploymentInfo)
+			if err != nil {
+				c.Log.Error(err)
+				return I.DeployResponse{
+					StatusCode:     http.StatusBadRequest,
+					Error:          err,
+					DeploymentInfo: deploymentInfo,
+				}
+			}
+		}
+	}
+
+	if deployment.Type.Multipart && !deploymentInfo.AllApplications && !deployment.AllowNameMismatch {
+		err = checkManifestAppName(deploymentInfo)
+		if err != nil {
+			c.Log.Error(err)
+			return I.DeployResponse{
+				StatusCode:     http.StatusBadRequest,
+				Error:          err,
+				DeploymentInfo: deploymentInfo,
+			}
+		}
+	}
+
+	err = checkCustomParams(deploymentInfo.CustomParams, environment.RequiredCustomParams, cf.Environment)
+	if err != nil {
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	strategy, err := resolveStrategy(deploymentInfo.Strategy, environment)
+	if err != nil {
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+	deploymentInfo.Strategy = strategy
+	c.Log.Infof("deploying with the %s strategy", deploymentInfo.Strategy)
+
+	err = checkInstances(deploymentInfo.AppName, deploymentInfo.Instances, environment)
+	if err != nil {
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	if environment.RequireChangeTicket && deploymentInfo.ChangeTicket == "" {
+		err = deployer.ChangeTicketRequiredError{cf.Environment}
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+	if deploymentInfo.ChangeTicket != "" {
+		c.Log.Infof("deploy of %s with UUID %s is for change ticket %s", cf.Application, deploymentInfo.UUID, deploymentInfo.ChangeTicket)
+	}
+
+	deploymentInfo.Reason = strings.TrimSpace(deploymentInfo.Reason)
+	if environment.RequireReason && deploymentInfo.Reason == "" {
+		err = deployer.ReasonRequiredError{cf.Environment}
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+	if deploymentInfo.Reason != "" {
+		c.Log.Infof("reason for the deploy of %s with UUID %s: %s", cf.Application, deploymentInfo.UUID, deploymentInfo.Reason)
 	}
 
-	deployEventData := structs.DeployEventData{Response: response, DeploymentInfo: deploymentInfo, RequestBody: body}
-	defer c.emitDeployFinish(&deployEventData, response, cf, auth, environment, &deployResponse, c.Log)
-	defer c.emitDeploySuccessOrFailure(&deployEventData, response, cf, auth, environment, &deployResponse, c.Log)
+	if deploymentInfo.AllApplications && deploymentInfo.Command != "" {
+		err = deployer.CommandNotSupportedError{}
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	if deploymentInfo.AllApplications && len(deploymentInfo.Buildpacks) > 0 {
+		err = deployer.BuildpacksNotSupportedError{}
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	err = checkBuildpacks(deploymentInfo.Buildpacks, environment.AllowedBuildpacks, cf.Environment)
+	if err != nil {
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusForbidden,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	if deploymentInfo.AllApplications && len(deploymentInfo.PushFlags) > 0 {
+		err = deployer.PushFlagsNotSupportedError{}
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	err = checkPushFlags(deploymentInfo.PushFlags, environment.AllowedPushFlags, cf.Environment)
+	if err != nil {
+		c.Log.Error(err)
+		statusCode := http.StatusForbidden
+		if _, ok := err.(deployer.InvalidPushFlagError); ok {
+			statusCode = http.StatusBadRequest
+		}
+		return I.DeployResponse{
+			StatusCode:     statusCode,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	// The manifest is only known once the artifact is fetched, so only the push flags are checked here.
+	err = checkResources(deploymentInfo.AppName, "", mergePushFlags(environment.PushFlags, deploymentInfo.PushFlags), environment)
+	if err != nil {
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	err = checkIsolationSegment(deploymentInfo.IsolationSegment, environment.AllowedIsolationSegments, cf.Environment)
+	if err != nil {
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	if deploymentInfo.AllApplications && (deploymentInfo.ManualCutover || environment.ManualCutover) {
+		err = deployer.ManualCutoverNotSupportedError{}
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode:     http.StatusBadRequest,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	deployEventData := structs.DeployEventData{Response: output, DeploymentInfo: deploymentInfo, RequestBody: body}
+
+	if c.Validator != nil {
+		c.Log.Debug("validating the deploy with the validation webhook")
+		err = c.Validator.Validate(deployEventData)
+		if err != nil {
+			c.Log.Error(err)
+			fmt.Fprintln(output, err)
+
+			statusCode := http.StatusInternalServerError
+			switch err.(type) {
+			case validator.DeniedError:
+				statusCode = http.StatusForbidden
+			case validator.WebhookError:
+				statusCode = http.StatusServiceUnavailable
+			}
+			return I.DeployResponse{
+				StatusCode:     statusCode,
+				Error:          err,
+				DeploymentInfo: deploymentInfo,
+			}
+		}
+	}
+
+	defer func() {
+		span := c.Tracer.StartPhase(c.Log.UUID, tracing.EventsPhase)
+		c.emitDeploySuccessOrFailure(&deployEventData, output, cf, auth, environment, &deployResponse, c.Log)
+		c.emitDeployFinish(&deployEventData, output, cf, auth, environment, &deployResponse, c.Log)
+		span.End(nil)
+	}()
 
 	c.Log.Debugf("emitting a %s event", constants.DeployStartEvent)
 
@@ -122,15 +450,18 @@ func (c *PushController) RunDeployment(deployment *I.Deployment, response *bytes
 	}
 
 	err = c.EventManager.EmitEvent(DeployStartedEvent{
-		CFContext:   cf,
-		Auth:        auth,
-		Body:        body,
-		ContentType: deploymentInfo.ContentType,
-		Environment: environment,
-		Response:    response,
-		ArtifactURL: deploymentInfo.ArtifactURL,
-		Data:        deploymentInfo.Data,
-		Log:         c.Log,
+		CFContext:      cf,
+		Auth:           auth,
+		Body:           body,
+		ContentType:    deploymentInfo.ContentType,
+		Environment:    environment,
+		Response:       output,
+		ArtifactURL:    deploymentInfo.ArtifactURL,
+		Data:           deploymentInfo.Data,
+		Log:            c.Log,
+		ClientIdentity: deploymentInfo.ClientIdentity,
+		ChangeTicket:   deploymentInfo.ChangeTicket,
+		Reason:         deploymentInfo.Reason,
 	})
 	if err != nil {
 		c.Log.Error(err)
@@ -142,63 +473,658 @@ func (c *PushController) RunDeployment(deployment *I.Deployment, response *bytes
 		}
 	}
 
+	if environment.RequireApproval {
+		// A push that waits for approval is streamed, so the client sees that it waits as it happens.
+		if deployment.Output == nil && deployment.StreamOutput != nil {
+			output = streamingResponse{response, deployment.StreamOutput()}
+			deployEventData.Response = output
+		}
+		err = c.awaitApproval(deployment.Context, deployEventData, output)
+		if err != nil {
+			return I.DeployResponse{
+				StatusCode:     approvalStatusCode(err),
+				Error:          err,
+				DeploymentInfo: deploymentInfo,
+			}
+		}
+	}
+
 	pusherCreator := c.PushManagerFactory.PushManager(c.Log, deployEventData, cf, auth, environment, deploymentInfo.EnvironmentVariables)
 
-	reqChannel1 := make(chan *I.DeployResponse)
-	reqChannel2 := make(chan *I.DeployResponse)
-	defer close(reqChannel1)
-	defer close(reqChannel2)
+	reqChannel := make(chan *I.DeployResponse)
+	defer close(reqChannel)
+
+	deployStart := c.now()
+
+	if deployment.Context != nil && c.Cancellations != nil {
+		stop := c.cancelOnDeadline(deployment.Context, deploymentInfo)
+		defer stop()
+	}
 
 	go func() {
-		reqChannel1 <- c.Deployer.Deploy(deploymentInfo, environment, pusherCreator, response)
+		reqChannel <- c.Deployer.Deploy(deploymentInfo, environment, pusherCreator, output)
 	}()
 
-	silentResponse := &bytes.Buffer{}
 	if cf.Environment == os.Getenv("SILENT_DEPLOY_ENVIRONMENT") {
-		go func() {
-			reqChannel2 <- c.SilentDeployer.Deploy(deploymentInfo, environment, pusherCreator, silentResponse)
-		}()
-		<-reqChannel2
+		c.silentDeploy(*deployment.Body, deploymentInfo, environment)
 	}
 
-	deployResponse = *<-reqChannel1
+	deployResponse = *<-reqChannel
+
+	c.checkDeploySLO(deployEventData, cf, auth, environment, output, c.now().Sub(deployStart))
 
 	return deployResponse
 }
 
+// cancelOnDeadline cancels the deploy when the deadline of ctx passes before the returned stop is called. The deploy
+// registers its cancellation once it is set up, so canceling is tried again until it is registered.
+func (c *PushController) cancelOnDeadline(ctx context.Context, info *structs.DeploymentInfo) (stop func()) {
+	stopped := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+			return
+		}
+		if ctx.Err() != context.DeadlineExceeded {
+			return
+		}
+
+		for {
+			err := c.Cancellations.Cancel(info.UUID, info.Environment, info.Org, info.Space, info.AppName)
+			switch err.(type) {
+			case nil:
+				c.Log.Infof("canceling deploy %s: its request timed out", info.UUID)
+				return
+			case deployer.DeployNotCancelableError:
+				c.Log.Infof("deploy %s keeps running after its request timed out: it can no longer be canceled", info.UUID)
+				return
+			}
+
+			select {
+			case <-time.After(cancelRetryInterval):
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	return func() { close(stopped) }
+}
+
+// silentDeploy starts the silent deploy of a copy of deploymentInfo with its own request body, so it shares no
+// state with the deploy it copies. The silent deployer sends the deploy on to another Deployadactyl, so it is
+// given no action creator.
+func (c *PushController) silentDeploy(body []byte, deploymentInfo *structs.DeploymentInfo, environment structs.Environment) {
+	silentInfo := deploymentInfo.Copy()
+	if deploymentInfo.Body != nil {
+		silentInfo.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	silentResponse := c.SilentDeployer.Deploy(silentInfo, environment, nil, &bytes.Buffer{})
+	if silentResponse.Error != nil {
+		c.Log.Errorf("could not start silent deploy: %s", silentResponse.Error)
+	}
+}
+
+// checkDeploySLO reports a deploy that took longer than the DeploySLOSeconds of its environment with a warning in
+// the response and a deploy.slo_breach event. The wait for an approval is not part of the duration. A breach never
+// fails the deploy, so an error emitting its event is only logged.
+func (c *PushController) checkDeploySLO(deployEventData structs.DeployEventData, cf I.CFContext, auth I.Authorization, environment structs.Environment, response io.ReadWriter, duration time.Duration) {
+	target := time.Duration(environment.DeploySLOSeconds) * time.Second
+	if target == 0 || duration <= target {
+		return
+	}
+
+	warning := fmt.Sprintf("the deploy took %s, longer than the deploy SLO of %s of environment %s", duration.Round(time.Second), target, cf.Environment)
+	c.Log.Errorf("WARNING: %s", warning)
+	fmt.Fprintf(response, "WARNING: %s\n", warning)
+
+	c.Log.Debugf("emitting a %s event", constants.DeploySLOBreachEvent)
+	data := &structs.DeploySLOBreach{DeploymentInfo: deployEventData.DeploymentInfo, Duration: duration, Target: target}
+	err := c.EventManager.Emit(I.Event{Type: constants.DeploySLOBreachEvent, Data: data})
+	if err != nil {
+		c.Log.Errorf("an error occurred when emitting a %s event: %s", constants.DeploySLOBreachEvent, err)
+		return
+	}
+
+	event := DeploySLOBreachEvent{
+		CFContext:   cf,
+		Auth:        auth,
+		Environment: environment,
+		Response:    response,
+		Duration:    duration,
+		Target:      target,
+		Data:        deployEventData.DeploymentInfo.Data,
+		Log:         c.Log,
+	}
+	err = c.EventManager.EmitEvent(event)
+	if err != nil {
+		c.Log.Errorf("an error occurred when emitting a %s event: %s", event.Name(), err)
+	}
+}
+
+func (c *PushController) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// awaitApproval holds the deploy until the Approver approves it. It stops waiting when ctx is done or when the
+// deploy is canceled through Cancellations, which it is registered in while it waits.
+func (c *PushController) awaitApproval(ctx context.Context, deployEventData structs.DeployEventData, response io.Writer) error {
+	info := deployEventData.DeploymentInfo
+	if c.Approver == nil {
+		err := deployer.ApprovalGateNotConfiguredError{info.Environment}
+		c.Log.Error(err)
+		fmt.Fprintln(response, err)
+		return err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cancellation := c.Cancellations.Register(info.UUID, info.Environment, info.Org, info.Space, info.AppName)
+	defer c.Cancellations.Remove(info.UUID, cancellation)
+	cancellation.OnCancel(cancel)
+
+	c.Log.Infof("awaiting approval of the deploy")
+	err := c.Approver.Approve(ctx, deployEventData, response)
+	if _, ok := err.(approval.CanceledError); ok && cancellation.Canceled() {
+		err = state.DeployCanceledError{info.AppName}
+	}
+	if err != nil {
+		c.Log.Error(err)
+		fmt.Fprintln(response, err)
+		return err
+	}
+
+	c.Log.Infof("the deploy was approved")
+	return nil
+}
+
+func approvalStatusCode(err error) int {
+	switch e := err.(type) {
+	case approval.DeniedError, approval.TimedOutError:
+		return http.StatusForbidden
+	case approval.CanceledError:
+		if e.Err == context.DeadlineExceeded {
+			return http.StatusGatewayTimeout
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// PromoteDeployment completes the manual cutover of the deploy with the UUID. The production routes are mapped to
+// its candidate, the existing application is deleted and the candidate takes the application name.
+func (c *PushController) PromoteDeployment(deployment *I.Deployment, uuid string, response *bytes.Buffer) I.DeployResponse {
+	return c.followUpDeployment(deployment, uuid, response, "promotion", c.PushManagerFactory.PromoteManager)
+}
+
+// RollbackDeployment brings back the application replaced by the deploy with the UUID while it waits for its
+// deletion. The replaced application is started, the deployed application is deleted and the replaced
+// application takes back the application name.
+func (c *PushController) RollbackDeployment(deployment *I.Deployment, uuid string, response *bytes.Buffer) I.DeployResponse {
+	return c.followUpDeployment(deployment, uuid, response, "rollback", c.PushManagerFactory.RollbackManager)
+}
+
+// followUpDeployment runs the manager for an earlier deploy with the UUID that is returned by createManager.
+func (c *PushController) followUpDeployment(deployment *I.Deployment, uuid string, response *bytes.Buffer, name string, createManager func(I.DeploymentLogger, structs.DeployEventData) (I.ActionCreator, error)) I.DeployResponse {
+	cf := deployment.CFContext
+
+	c.Log.Debugf("Starting %s of %s with UUID %s", name, cf.Application, uuid)
+
+	environment, err := c.resolveEnvironment(cf.Environment)
+	if err != nil {
+		fmt.Fprintln(response, err.Error())
+		return I.DeployResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err,
+		}
+	}
+	auth, err := c.resolveAuthorization(deployment.Authorization, environment, c.Log)
+	if err != nil {
+		return I.DeployResponse{
+			StatusCode: http.StatusUnauthorized,
+			Error:      err,
+		}
+	}
+
+	deploymentInfo := &structs.DeploymentInfo{
+		Org:         cf.Organization,
+		Space:       cf.Space,
+		AppName:     cf.Application,
+		Environment: cf.Environment,
+		UUID:        uuid,
+		Username:    auth.Username,
+		Password:    auth.Password,
+		Domain:      environment.Domain,
+		SkipSSL:     environment.SkipSSL,
+	}
+	deployEventData := structs.DeployEventData{Response: response, DeploymentInfo: deploymentInfo}
+
+	manager, err := createManager(c.Log, deployEventData)
+	if err != nil {
+		c.Log.Error(err)
+		fmt.Fprintln(response, err.Error())
+		return I.DeployResponse{
+			StatusCode:     http.StatusNotFound,
+			Error:          err,
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	return *c.Deployer.Deploy(deploymentInfo, environment, manager, response)
+}
+
 func (c *PushController) getDeploymentInfo(body *[]byte, deploymentInfo *structs.DeploymentInfo) (*structs.DeploymentInfo, error) {
-	reader := ioutil.NopCloser(bytes.NewBuffer(*body))
-	err := json.NewDecoder(reader).Decode(deploymentInfo)
+	renamed, err := renameJSONFields(*body, c.Config.JSONFields)
 	if err != nil {
 		return deploymentInfo, err
 	}
 
+	reader := ioutil.NopCloser(bytes.NewBuffer(renamed))
+	err = json.NewDecoder(reader).Decode(deploymentInfo)
+	if err != nil {
+		return deploymentInfo, deployer.InvalidRequestBodyError{Err: err}
+	}
+
+	// The artifact metadata is recorded by the fetch of the deploy, never taken from the request.
+	deploymentInfo.Artifact = nil
+
+	if deploymentInfo.Spec != "" {
+		err = c.applyAppSpec(deploymentInfo)
+		if err != nil {
+			return deploymentInfo, err
+		}
+	}
+
+	artifactURL := c.jsonFieldName("artifact_url")
 	getter := geterrors.WrapFunc(func(key string) string {
-		if key == "artifact_url" {
+		if key == artifactURL {
 			return deploymentInfo.ArtifactURL
 		}
 		return ""
 	})
 
-	getter.Get("artifact_url")
+	getter.Get(artifactURL)
 
 	err = getter.Err("The following properties are missing")
 	if err != nil {
-		return &structs.DeploymentInfo{}, err
+		return &structs.DeploymentInfo{}, deployer.MissingParameterError{Err: err}
+	}
+
+	err = validateLabels(deploymentInfo.Labels)
+	if err != nil {
+		return deploymentInfo, err
+	}
+
+	err = validateFeatures(deploymentInfo.Features)
+	if err != nil {
+		return deploymentInfo, err
+	}
+
+	err = validateEnv(deploymentInfo.Env)
+	if err != nil {
+		return deploymentInfo, err
+	}
+
+	if !extractor.Supported(c.ArtifactTypes, deploymentInfo.ArtifactType) {
+		return deploymentInfo, extractor.UnsupportedArtifactTypeError{extractor.MediaType(deploymentInfo.ArtifactType)}
 	}
 	return deploymentInfo, nil
 }
 
+// resolveStrategy returns the strategy the push chose, or the strategy of the environment when it did not choose one.
+// It returns an UnknownStrategyError for a strategy that is not implemented.
+func resolveStrategy(strategy string, environment structs.Environment) (string, error) {
+	switch strategy {
+	case "":
+		if environment.TrafficSplit {
+			return structs.StrategyTrafficSplit, nil
+		}
+		return structs.StrategyBlueGreen, nil
+	case structs.StrategyBlueGreen, structs.StrategyTrafficSplit:
+		return strategy, nil
+	default:
+		return "", deployer.UnknownStrategyError{strategy}
+	}
+}
+
+// copyCustomParams returns a copy of the custom params of an environment, or nil when it has none.
+func copyCustomParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+
+	copied := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		copied[key] = value
+	}
+	return copied
+}
+
+// checkCustomParams returns a CustomParamsRequiredError listing the required custom params of the environment that
+// are missing from the merged custom params of the deploy. A param that is null or an empty string is missing.
+func checkCustomParams(params map[string]interface{}, required []string, environment string) error {
+	var missing []string
+	for _, key := range required {
+		value, ok := params[key]
+		if !ok || value == nil || value == "" {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		return deployer.CustomParamsRequiredError{environment, missing}
+	}
+	return nil
+}
+
+// checkInstances returns a TooManyInstancesError when the application asks for more instances than the environment allows.
+func checkInstances(appName string, instances uint16, environment structs.Environment) error {
+	if environment.MaxInstances > 0 && instances > environment.MaxInstances {
+		return deployer.TooManyInstancesError{appName, instances, environment.MaxInstances}
+	}
+	return nil
+}
+
+// checkResources returns a ResourceLimitError when the application asks for more memory or disk per instance than
+// the environment allows. The push flags win over the manifest, like they do for cf push. A size that is not set,
+// or that Cloud Foundry would reject on its own, is not checked.
+func checkResources(appName, manifest string, flags []string, environment structs.Environment) error {
+	memory, disk := manifestro.GetResources(manifest)
+	for _, flag := range flags {
+		name, value, ok := structs.ParsePushFlag(flag)
+		switch {
+		case !ok:
+		case name == "-m":
+			memory = value
+		case name == "-k":
+			disk = value
+		}
+	}
+
+	limits := []struct {
+		resource  string
+		requested string
+		allowed   string
+	}{
+		{"memory", memory, environment.MaxMemoryPerInstance},
+		{"disk", disk, environment.MaxDiskPerInstance},
+	}
+	for _, limit := range limits {
+		allowed, ok := structs.ParseMegabytes(limit.allowed)
+		if !ok {
+			continue
+		}
+
+		requested, ok := structs.ParseMegabytes(limit.requested)
+		if ok && requested > allowed {
+			return deployer.ResourceLimitError{appName, limit.resource, limit.requested, limit.allowed}
+		}
+	}
+
+	return nil
+}
+
+// checkArtifactHost returns a HostNotAllowedError when the artifact of a JSON push is on a host the config does
+// not allow it to be fetched from. The fetcher checks every redirect and connection of the fetch with the same policy.
+func (c *PushController) checkArtifactHost(artifactURL string) error {
+	policy := hostpolicy.Policy{
+		AllowedHosts:   c.Config.ArtifactHosts.Allowed,
+		DeniedCIDRs:    c.Config.ArtifactHosts.DeniedCIDRs,
+		AllowLinkLocal: c.Config.ArtifactHosts.AllowLinkLocal,
+	}
+
+	return policy.Check(artifactURL)
+}
+
+// checkManifestAppName returns an AppNameMismatchError when the manifest of the push declares applications
+// and none of them is the application being deployed. A manifest that cannot be decoded is left for the push to report.
+func checkManifestAppName(deploymentInfo *structs.DeploymentInfo) error {
+	if deploymentInfo.Manifest == "" {
+		return nil
+	}
+
+	manifest, err := base64.StdEncoding.DecodeString(deploymentInfo.Manifest)
+	if err != nil {
+		return nil
+	}
+
+	names := manifestro.GetApplicationNames(string(manifest))
+	if len(names) == 0 {
+		return nil
+	}
+
+	for _, name := range names {
+		if name == deploymentInfo.AppName {
+			return nil
+		}
+	}
+
+	return deployer.AppNameMismatchError{deploymentInfo.AppName, names}
+}
+
+// checkSpaceGUID returns an error when the space or org GUID of the push is not well formed, when an org GUID or
+// an org or space named NameFromSpaceGUID is given without a space GUID, or when a space GUID is given for an
+// environment with more than one foundation. Whether the GUIDs agree with the org and space names of the push is
+// checked on the foundation.
+func checkSpaceGUID(deploymentInfo *structs.DeploymentInfo, environment structs.Environment, environmentName string) error {
+	if deploymentInfo.OrgGUID != "" && deploymentInfo.SpaceGUID == "" {
+		return deployer.OrgGUIDWithoutSpaceGUIDError{}
+	}
+	if deploymentInfo.SpaceGUID == "" {
+		if deploymentInfo.Org == NameFromSpaceGUID || deploymentInfo.Space == NameFromSpaceGUID {
+			return deployer.NameWithoutSpaceGUIDError{NameFromSpaceGUID}
+		}
+		return nil
+	}
+
+	if !guidPattern.MatchString(deploymentInfo.SpaceGUID) {
+		return deployer.InvalidGUIDError{"space_guid", deploymentInfo.SpaceGUID}
+	}
+	if deploymentInfo.OrgGUID != "" && !guidPattern.MatchString(deploymentInfo.OrgGUID) {
+		return deployer.InvalidGUIDError{"org_guid", deploymentInfo.OrgGUID}
+	}
+
+	if len(environment.Foundations) > 1 {
+		return deployer.SpaceGUIDNotSupportedError{environmentName}
+	}
+
+	return nil
+}
+
+// checkOrgAndSpace returns an OrgNotAllowedError or a SpaceNotAllowedError when the org or space of a push is not
+// one of the allowed orgs or spaces of the environment. Any org or space is allowed when the environment does not
+// list them. An org or space named NameFromSpaceGUID is checked on the foundation, once its name is found.
+func checkOrgAndSpace(cf I.CFContext, environment structs.Environment) error {
+	if cf.Organization != NameFromSpaceGUID && !isAllowed(cf.Organization, environment.AllowedOrgs) {
+		return deployer.OrgNotAllowedError{cf.Organization, cf.Environment}
+	}
+	if cf.Space != NameFromSpaceGUID && !isAllowed(cf.Space, environment.AllowedSpaces) {
+		return deployer.SpaceNotAllowedError{cf.Space, cf.Environment}
+	}
+
+	return nil
+}
+
+// isAllowed reports whether value is one of allowed, or allowed is empty.
+func isAllowed(value string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkBuildpacks returns a BuildpackNotAllowedError for the first buildpack that is not one of the allowed buildpacks
+// of the environment. Any buildpack is allowed when the environment does not list them.
+func checkBuildpacks(buildpacks, allowed []string, environment string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	for _, buildpack := range buildpacks {
+		found := false
+		for _, a := range allowed {
+			if buildpack == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return deployer.BuildpackNotAllowedError{buildpack, environment}
+		}
+	}
+
+	return nil
+}
+
+// checkPushFlags returns an InvalidPushFlagError for the first push flag that is not a passthrough push flag and its
+// value, and a PushFlagNotAllowedError for the first one that is not one of the allowed push flags of the
+// environment. No push flag is allowed when the environment does not list them.
+func checkPushFlags(flags, allowed []string, environment string) error {
+	for _, flag := range flags {
+		name, _, ok := structs.ParsePushFlag(flag)
+		if !ok {
+			return deployer.InvalidPushFlagError{flag}
+		}
+
+		found := false
+		for _, a := range allowed {
+			if name == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return deployer.PushFlagNotAllowedError{name, environment}
+		}
+	}
+
+	return nil
+}
+
+// checkIsolationSegment returns an IsolationSegmentNotAllowedError when the push chooses an isolation segment that is
+// not one of the allowed isolation segments of the environment.
+func checkIsolationSegment(segment string, allowed []string, environment string) error {
+	if segment == "" {
+		return nil
+	}
+
+	for _, a := range allowed {
+		if segment == a {
+			return nil
+		}
+	}
+
+	return deployer.IsolationSegmentNotAllowedError{segment, environment}
+}
+
+// applyProfile fills in the settings of the named profile that the push does not set itself.
+// Environment variables and labels are merged, with the ones from the push winning.
+func (c *PushController) applyProfile(deploymentInfo *structs.DeploymentInfo) error {
+	profile, ok := c.Config.Profiles[deploymentInfo.Profile]
+	if !ok {
+		return deployer.ProfileNotFoundError{deploymentInfo.Profile}
+	}
+
+	c.Log.Debugf("applying profile %s", deploymentInfo.Profile)
+
+	if deploymentInfo.HealthCheckEndpoint == "" {
+		deploymentInfo.HealthCheckEndpoint = profile.HealthCheckEndpoint
+	}
+	if deploymentInfo.PostDeployTask == "" {
+		deploymentInfo.PostDeployTask = profile.PostDeployTask
+	}
+	deploymentInfo.EnvironmentVariables = mergeSettings(profile.EnvironmentVariables, deploymentInfo.EnvironmentVariables)
+	deploymentInfo.Labels = mergeSettings(profile.Labels, deploymentInfo.Labels)
+	deploymentInfo.ProfileManifest = profile.Manifest()
+
+	return validateLabels(deploymentInfo.Labels)
+}
+
+// applyAppSpec merges the push over the named app spec. The artifact URL and instances of the spec are used when
+// the push does not set them, environment variables are merged with the ones from the push winning, and the
+// manifest of the push is merged over the manifest of the spec.
+func (c *PushController) applyAppSpec(deploymentInfo *structs.DeploymentInfo) error {
+	spec, ok := c.Config.AppSpecs[deploymentInfo.Spec]
+	if !ok {
+		return deployer.AppSpecNotFoundError{deploymentInfo.Spec}
+	}
+
+	c.Log.Debugf("merging the push over app spec %s", deploymentInfo.Spec)
+
+	if deploymentInfo.ArtifactURL == "" {
+		deploymentInfo.ArtifactURL = spec.ArtifactURL
+	}
+	if deploymentInfo.Instances == 0 {
+		deploymentInfo.Instances = spec.Instances
+	}
+	deploymentInfo.EnvironmentVariables = mergeSettings(spec.EnvironmentVariables, deploymentInfo.EnvironmentVariables)
+
+	if spec.Manifest == "" {
+		return nil
+	}
+
+	if deploymentInfo.Manifest == "" {
+		deploymentInfo.Manifest = base64.StdEncoding.EncodeToString([]byte(spec.Manifest))
+		return nil
+	}
+
+	manifest, err := base64.StdEncoding.DecodeString(deploymentInfo.Manifest)
+	if err != nil {
+		return deployer.AppSpecManifestError{deploymentInfo.Spec, err}
+	}
+
+	merged, err := manifestro.Merge(spec.Manifest, string(manifest))
+	if err != nil {
+		return deployer.AppSpecManifestError{deploymentInfo.Spec, err}
+	}
+	deploymentInfo.Manifest = base64.StdEncoding.EncodeToString([]byte(merged))
+
+	return nil
+}
+
+// mergeSettings returns the settings of base with the ones of override laid over them.
+func mergeSettings(base, override map[string]string) map[string]string {
+	if len(base) == 0 {
+		return override
+	}
+
+	merged := make(map[string]string, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+
+	return merged
+}
+
 func (c *PushController) resolveAuthorization(auth I.Authorization, envs structs.Environment, deploymentLogger I.DeploymentLogger) (I.Authorization, error) {
 	config := c.Config
 	deploymentLogger.Debug("checking for basic auth")
 	if auth.Username == "" && auth.Password == "" {
-		if envs.Authenticate {
+		username, password, ok := config.FallbackAuthorization(envs)
+		if !ok {
 			return I.Authorization{}, deployer.BasicAuthError{}
-
 		}
-		auth.Username = config.Username
-		auth.Password = config.Password
+		auth.Username = username
+		auth.Password = password
 	}
 
 	return auth, nil
@@ -224,14 +1150,17 @@ func (c *PushController) emitDeployFinish(deployEventData *structs.DeployEventDa
 	}
 
 	finishErr = c.EventManager.EmitEvent(DeployFinishedEvent{
-		CFContext:   cf,
-		Auth:        auth,
-		Body:        deployEventData.RequestBody,
-		ContentType: deployEventData.DeploymentInfo.ContentType,
-		Environment: environment,
-		Response:    deployEventData.Response,
-		Data:        deployEventData.DeploymentInfo.Data,
-		Log:         c.Log,
+		CFContext:      cf,
+		Auth:           auth,
+		Body:           deployEventData.RequestBody,
+		ContentType:    deployEventData.DeploymentInfo.ContentType,
+		Environment:    environment,
+		Response:       deployEventData.Response,
+		Data:           deployEventData.DeploymentInfo.Data,
+		Log:            c.Log,
+		ClientIdentity: deployEventData.DeploymentInfo.ClientIdentity,
+		ChangeTicket:   deployEventData.DeploymentInfo.ChangeTicket,
+		Reason:         deployEventData.DeploymentInfo.Reason,
 	})
 	if finishErr != nil {
 		fmt.Fprintln(response, finishErr)
@@ -257,21 +1186,26 @@ func (c PushController) emitDeploySuccessOrFailure(deployEventData *structs.Depl
 	if eventErr != nil {
 		deploymentLogger.Errorf("an error occurred when emitting a %s event: %s", deployEvent.Name(), eventErr)
 		fmt.Fprintln(response, eventErr)
+		if c.EventRetries != nil {
+			c.EventRetries.Add(deployEvent, eventErr)
+		}
 		return
 	}
 
 	var event I.IEvent
 	if deployResponse.Error != nil {
 		event = DeployFailureEvent{
-			CFContext:   cf,
-			Auth:        auth,
-			Body:        deployEventData.RequestBody,
-			ContentType: deployEventData.DeploymentInfo.ContentType,
-			Environment: environment,
-			Response:    deployEventData.Response,
-			Data:        deployEventData.DeploymentInfo.Data,
-			Error:       deployResponse.Error,
-			Log:         c.Log,
+			CFContext:    cf,
+			Auth:         auth,
+			Body:         deployEventData.RequestBody,
+			ContentType:  deployEventData.DeploymentInfo.ContentType,
+			Environment:  environment,
+			Response:     deployEventData.Response,
+			Data:         deployEventData.DeploymentInfo.Data,
+			Error:        deployResponse.Error,
+			Log:          c.Log,
+			ChangeTicket: deployEventData.DeploymentInfo.ChangeTicket,
+			Reason:       deployEventData.DeploymentInfo.Reason,
 		}
 	} else {
 		event = DeploySuccessEvent{
@@ -284,7 +1218,10 @@ func (c PushController) emitDeploySuccessOrFailure(deployEventData *structs.Depl
 			Data:                deployEventData.DeploymentInfo.Data,
 			HealthCheckEndpoint: deployEventData.DeploymentInfo.HealthCheckEndpoint,
 			ArtifactURL:         deployEventData.DeploymentInfo.ArtifactURL,
+			Artifact:            deployEventData.DeploymentInfo.Artifact,
 			Log:                 c.Log,
+			ChangeTicket:        deployEventData.DeploymentInfo.ChangeTicket,
+			Reason:              deployEventData.DeploymentInfo.Reason,
 		}
 	}
 	deploymentLogger.Debug(fmt.Sprintf("emitting a %s event", event.Name()))
@@ -299,7 +1236,13 @@ func (c PushController) emitDeploySuccessOrFailure(deployEventData *structs.Depl
 func (c PushController) printErrors(response io.ReadWriter, err *error) {
 	tempBuffer := bytes.Buffer{}
 	tempBuffer.ReadFrom(response)
-	fmt.Fprint(response, tempBuffer.String())
+
+	// A streamed output was already sent, so it is not sent again truncated.
+	var truncated io.Writer = response
+	if streamed, ok := response.(streamingResponse); ok {
+		truncated = streamed.buffer
+	}
+	fmt.Fprint(truncated, state.TruncateOutput(tempBuffer.String(), c.Config.ErrorOutput, c.Config.DeploymentLog, c.Log.UUID))
 
 	errors := c.ErrorFinder.FindErrors(tempBuffer.String())
 	if len(errors) > 0 {
@@ -318,3 +1261,18 @@ func (c PushController) printErrors(response io.ReadWriter, err *error) {
 		}
 	}
 }
+
+// streamingResponse is the response of a deploy that sends what is written to it to output as well.
+type streamingResponse struct {
+	buffer *bytes.Buffer
+	output io.Writer
+}
+
+func (r streamingResponse) Read(p []byte) (int, error) {
+	return r.buffer.Read(p)
+}
+
+func (r streamingResponse) Write(p []byte) (int, error) {
+	r.output.Write(p)
+	return r.buffer.Write(p)
+}
@@ -2,15 +2,23 @@ package push_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"fmt"
+	"github.com/compozed/deployadactyl/artifetcher/extractor"
 	"github.com/compozed/deployadactyl/config"
 	"github.com/compozed/deployadactyl/constants"
 	D "github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/approval"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	"github.com/compozed/deployadactyl/controller/deployer/error_finder"
+	"github.com/compozed/deployadactyl/controller/deployer/hostpolicy"
+	"github.com/compozed/deployadactyl/controller/deployer/validator"
+	"github.com/compozed/deployadactyl/eventmanager/retry"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/state/push"
 	"github.com/compozed/deployadactyl/structs"
 	"github.com/go-errors/errors"
@@ -18,11 +26,14 @@ import (
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"time"
 )
 
 var _ = Describe("RunDeployment", func() {
@@ -117,6 +128,53 @@ var _ = Describe("RunDeployment", func() {
 			Eventually(deployer.DeployCall.Received.DeploymentInfo.Password).Should(Equal(deployment.Authorization.Password))
 		})
 
+		It("sends the output of the deploy to the Output of the deployment as well", func() {
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+			deployer.DeployCall.Write.Output = "pushing little-timmy\n"
+
+			response := &bytes.Buffer{}
+			output := &bytes.Buffer{}
+
+			deployment := &I.Deployment{
+				Body:      &[]byte{},
+				CFContext: I.CFContext{Environment: environment, Organization: org, Space: space, Application: appName},
+				Output:    output,
+			}
+			deployment.Type.ZIP = true
+			controller.RunDeployment(deployment, response)
+
+			Expect(output.String()).To(ContainSubstring("pushing little-timmy\n"))
+			Expect(response.String()).To(ContainSubstring("pushing little-timmy\n"))
+		})
+
+		It("cancels the deploy when the deadline of the context of the deployment passes", func() {
+			cancellations := D.NewCancellations()
+			controller.Cancellations = cancellations
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			var cancellation *D.Cancellation
+			controller.Deployer = deployerFunc(func(info *structs.DeploymentInfo) *I.DeployResponse {
+				cancellation = cancellations.Register(info.UUID, info.Environment, info.Org, info.Space, info.AppName)
+				for start := time.Now(); !cancellation.Canceled() && time.Since(start) < time.Second; {
+					time.Sleep(5 * time.Millisecond)
+				}
+				return &I.DeployResponse{StatusCode: http.StatusInternalServerError}
+			})
+
+			deployment := &I.Deployment{
+				Body:      &[]byte{},
+				CFContext: I.CFContext{Environment: environment, Organization: org, Space: space, Application: appName},
+				Context:   ctx,
+			}
+			deployment.Type.ZIP = true
+			controller.RunDeployment(deployment, &bytes.Buffer{})
+
+			Expect(cancellation.Canceled()).To(BeTrue())
+			Eventually(logBuffer).Should(Say("its request timed out"))
+		})
+
 		It("deployer is provided the body", func() {
 
 			deployer.DeployCall.Returns.Error = nil
@@ -152,6 +210,34 @@ var _ = Describe("RunDeployment", func() {
 			Eventually(receivedBody).Should(Equal(*deployment.Body))
 		})
 
+		It("gives the silent deploy its own copy of the deploy", func() {
+			body := []byte("zip contents")
+			deployment.Body = &body
+			deployment.CFContext.Environment = environment
+			deployment.CFContext.Application = appName
+			deployment.Type.ZIP = true
+
+			os.Setenv("SILENT_DEPLOY_ENVIRONMENT", environment)
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+			controller.RunDeployment(&deployment, response)
+
+			primary := deployer.DeployCall.Received.DeploymentInfo
+			silent := silentDeployer.DeployCall.Received.DeploymentInfo
+			Expect(silent).ToNot(BeIdenticalTo(primary))
+			Expect(silent.AppName).To(Equal(appName))
+			Expect(silentDeployer.DeployCall.Received.ActionCreator).To(BeNil())
+			Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo).To(BeIdenticalTo(primary))
+
+			silentBody, err := ioutil.ReadAll(silent.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(silentBody)).To(Equal("zip contents"))
+
+			primaryBody, err := ioutil.ReadAll(primary.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(primaryBody)).To(Equal("zip contents"))
+		})
+
 		It("channel resolves when no errors occur", func() {
 			deployment.CFContext.Environment = environment
 			deployment.CFContext.Organization = org
@@ -427,6 +513,42 @@ var _ = Describe("RunDeployment", func() {
 
 							deploymentResponse := controller.RunDeployment(&deployment, response)
 
+							Eventually(deploymentResponse.Error).Should(HaveOccurred())
+							Eventually(deploymentResponse.Error.Error()).Should(Equal("basic auth header not found"))
+						})
+					})
+					Context("and the environment has an auth fallback", func() {
+						It("returns username and password from the environment", func() {
+							deployment.CFContext.Environment = environment
+							deployment.Type.ZIP = true
+
+							deployment.Authorization.Username = ""
+							deployment.Authorization.Password = ""
+
+							controller.Config.Environments[environment] = structs.Environment{
+								AuthFallback:     []string{structs.AuthFallbackEnv, structs.AuthFallbackDeny},
+								FallbackUsername: "username-" + randomizer.StringRunes(10),
+								FallbackPassword: "password-" + randomizer.StringRunes(10),
+							}
+
+							controller.RunDeployment(&deployment, response)
+
+							Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Username).Should(Equal(controller.Config.Environments[environment].FallbackUsername))
+							Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Password).Should(Equal(controller.Config.Environments[environment].FallbackPassword))
+						})
+						It("returns an error when the fallback denies", func() {
+							deployment.CFContext.Environment = environment
+							deployment.Type.ZIP = true
+
+							deployment.Authorization.Username = ""
+							deployment.Authorization.Password = ""
+
+							controller.Config.Environments[environment] = structs.Environment{
+								AuthFallback: []string{structs.AuthFallbackEnv, structs.AuthFallbackDeny},
+							}
+
+							deploymentResponse := controller.RunDeployment(&deployment, response)
+
 							Eventually(deploymentResponse.Error).Should(HaveOccurred())
 							Eventually(deploymentResponse.Error.Error()).Should(Equal("basic auth header not found"))
 						})
@@ -488,6 +610,38 @@ var _ = Describe("RunDeployment", func() {
 
 					Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ContentType).Should(Equal("ZIP"))
 				})
+				It("has the artifact type of a ZIP push", func() {
+					deployment.CFContext.Environment = environment
+					deployment.Type.ZIP = true
+					deployment.ArtifactType = "application/x-tar"
+
+					controller.RunDeployment(&deployment, response)
+
+					Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ArtifactType).To(Equal("application/x-tar"))
+				})
+				It("accepts the artifact type of a JSON push when it has an extractor", func() {
+					controller.ArtifactTypes = []string{"application/x-tar"}
+					deployment.CFContext.Environment = environment
+					deployment.Type.JSON = true
+					bodyByte := []byte(`{"artifact_url": "xyz", "artifact_type": "application/x-tar"}`)
+					deployment.Body = &bodyByte
+
+					controller.RunDeployment(&deployment, response)
+
+					Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ArtifactType).To(Equal("application/x-tar"))
+				})
+				It("returns http.StatusBadRequest when the artifact type of a JSON push has no extractor", func() {
+					deployment.CFContext.Environment = environment
+					deployment.Type.JSON = true
+					bodyByte := []byte(`{"artifact_url": "xyz", "artifact_type": "application/gzip"}`)
+					deployment.Body = &bodyByte
+
+					deploymentResponse := controller.RunDeployment(&deployment, response)
+
+					Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+					Expect(deploymentResponse.Error).To(MatchError(extractor.UnsupportedArtifactTypeError{"application/gzip"}))
+					Expect(deployer.DeployCall.Called).To(Equal(0))
+				})
 				It("has the correct body", func() {
 					deployment.CFContext.Environment = environment
 					deployment.Type.ZIP = true
@@ -538,6 +692,32 @@ var _ = Describe("RunDeployment", func() {
 					Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Domain).Should(Equal(domain))
 					Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.SkipSSL).Should(BeTrue())
 				})
+				It("overrides skipssl with the skip ssl of the deployment", func() {
+					deployment.CFContext.Environment = environment
+					deployment.Type.ZIP = true
+					skipSSL := false
+					deployment.SkipSSL = &skipSSL
+
+					controller.Config.Environments[environment] = structs.Environment{SkipSSL: true}
+
+					controller.RunDeployment(&deployment, response)
+
+					Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.SkipSSL).To(BeFalse())
+					Expect(pushManagerFactory.PushManagerCall.Received.Environment.SkipSSL).To(BeFalse())
+					Expect(logBuffer).To(Say(fmt.Sprintf("skip ssl of environment %s is overridden from true to false for this deploy", environment)))
+				})
+				It("does not let the body of a json push change skipssl", func() {
+					deployment.CFContext.Environment = environment
+					deployment.Type.JSON = true
+					bodyByte := []byte(`{"artifact_url": "xyz", "SkipSSL": true}`)
+					deployment.Body = &bodyByte
+
+					controller.Config.Environments[environment] = structs.Environment{}
+
+					controller.RunDeployment(&deployment, response)
+
+					Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.SkipSSL).To(BeFalse())
+				})
 				It("has correct custom parameters", func() {
 
 					deployment.CFContext.Environment = environment
@@ -569,6 +749,25 @@ var _ = Describe("RunDeployment", func() {
 					Eventually(pushManagerFactory.PushManagerCall.Received.Auth.Username).Should(Equal(deployment.Authorization.Username))
 					Expect(pushManagerFactory.PushManagerCall.Received.Environment).ToNot(BeNil())
 				})
+				It("records the client identity that triggered the deploy", func() {
+					deployment.CFContext.Environment = environment
+					deployment.ClientIdentity = "ci-pipeline"
+					deployment.Type.ZIP = true
+
+					controller.RunDeployment(&deployment, response)
+
+					Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ClientIdentity).Should(Equal("ci-pipeline"))
+					Eventually(logBuffer).Should(Say("triggered by client ci-pipeline"))
+				})
+				It("pushes every application in the manifest when the application is not named", func() {
+					deployment.CFContext.Environment = environment
+					deployment.CFContext.Application = ""
+					deployment.Type.ZIP = true
+
+					controller.RunDeployment(&deployment, response)
+
+					Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.AllApplications).Should(BeTrue())
+				})
 				It("correctly extracts artifact url from body", func() {
 					artifactURL := "artifactURL-" + randomizer.StringRunes(10)
 					bodyByte := []byte(fmt.Sprintf(`{"artifact_url": "%s"}`, artifactURL))
@@ -581,6 +780,18 @@ var _ = Describe("RunDeployment", func() {
 
 					Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ArtifactURL).Should(Equal(artifactURL))
 				})
+				It("does not take NoCache from the body", func() {
+					bodyByte := []byte(`{"artifact_url": "xyz", "NoCache": true}`)
+
+					deployment.CFContext.Environment = environment
+					deployment.Body = &bodyByte
+					deployment.Type.JSON = true
+
+					controller.RunDeployment(&deployment, response)
+
+					Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo).ShouldNot(BeNil())
+					Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.NoCache).To(BeFalse())
+				})
 				Context("if artifact url isn't provided in body", func() {
 					It("returns an error", func() {
 						bodyByte := []byte("{}")
@@ -594,10 +805,9 @@ var _ = Describe("RunDeployment", func() {
 						Eventually(deploymentResponse.Error).ShouldNot(BeNil())
 						Eventually(deploymentResponse.Error.Error()).Should(ContainSubstring("The following properties are missing: artifact_url"))
 					})
-				})
-				Context("if body is invalid", func() {
-					It("returns an error", func() {
-						bodyByte := []byte("")
+
+					It("returns http.StatusBadRequest", func() {
+						bodyByte := []byte("{}")
 
 						deployment.CFContext.Environment = environment
 						deployment.Body = &bodyByte
@@ -605,156 +815,1406 @@ var _ = Describe("RunDeployment", func() {
 
 						deploymentResponse := controller.RunDeployment(&deployment, response)
 
-						Eventually(deploymentResponse.Error).ShouldNot(BeNil())
-						Eventually(deploymentResponse.Error.Error()).Should(ContainSubstring("EOF"))
+						Eventually(deploymentResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+						Eventually(reflect.TypeOf(deploymentResponse.Error)).Should(Equal(reflect.TypeOf(D.MissingParameterError{})))
 					})
 				})
-				Context("deploy.start event", func() {
-					It("logs a start event", func() {
+				Context("if json fields are renamed", func() {
+					BeforeEach(func() {
+						controller.Config.JSONFields = map[string]string{"artifact_url": "artifactUrl", "manifest": "manifestYaml"}
 						deployment.CFContext.Environment = environment
-						deployment.Type.ZIP = true
+						deployment.Type.JSON = true
+					})
+
+					It("reads the fields with the configured names", func() {
+						bodyByte := []byte(`{"artifactUrl": "https://artifacts.example.com/app.zip", "manifestYaml": "bWFuaWZlc3Q=", "labels": {"build": "42"}}`)
+						deployment.Body = &bodyByte
 
 						controller.RunDeployment(&deployment, response)
 
-						Eventually(logBuffer).Should(Say("emitting a deploy.start event"))
+						deploymentInfo := pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo
+						Expect(deploymentInfo.ArtifactURL).To(Equal("https://artifacts.example.com/app.zip"))
+						Expect(deploymentInfo.Manifest).To(Equal("bWFuaWZlc3Q="))
+						Expect(deploymentInfo.Labels).To(Equal(map[string]string{"build": "42"}))
 					})
-					It("calls Emit", func() {
-						deployment.CFContext.Environment = environment
-						deployment.Type.ZIP = true
+
+					It("still reads the fields with their own names", func() {
+						bodyByte := []byte(`{"artifact_url": "https://artifacts.example.com/app.zip"}`)
+						deployment.Body = &bodyByte
 
 						controller.RunDeployment(&deployment, response)
 
-						Expect(eventManager.EmitCall.Received.Events[0].Type).Should(Equal(constants.DeployStartEvent))
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ArtifactURL).To(Equal("https://artifacts.example.com/app.zip"))
 					})
-					It("calls EmitEvent", func() {
-						deployment.CFContext.Environment = environment
-						deployment.Type.ZIP = true
+
+					It("prefers the configured name when both are sent", func() {
+						bodyByte := []byte(`{"artifact_url": "https://artifacts.example.com/old.zip", "artifactUrl": "https://artifacts.example.com/new.zip"}`)
+						deployment.Body = &bodyByte
 
 						controller.RunDeployment(&deployment, response)
 
-						Expect(eventManager.EmitEventCall.Received.Events[0].Name()).Should(Equal("DeployStartedEvent"))
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ArtifactURL).To(Equal("https://artifacts.example.com/new.zip"))
 					})
-					Context("when Emit fails", func() {
-						It("returns error", func() {
-							deployment.CFContext.Environment = environment
-							deployment.Type.ZIP = true
 
-							eventManager.EmitCall.Returns.Error = []error{errors.New("a test error")}
+					It("names the missing field with the configured name", func() {
+						bodyByte := []byte("{}")
+						deployment.Body = &bodyByte
 
-							deploymentResponse := controller.RunDeployment(&deployment, response)
+						deploymentResponse := controller.RunDeployment(&deployment, response)
 
-							Expect(reflect.TypeOf(deploymentResponse.Error)).Should(Equal(reflect.TypeOf(D.EventError{})))
-						})
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error.Error()).To(ContainSubstring("The following properties are missing: artifactUrl"))
 					})
-					Context("when EmitEvent fails", func() {
-						It("returns error", func() {
-							deployment.CFContext.Environment = environment
-							deployment.Type.ZIP = true
 
-							eventManager.EmitEventCall.Returns.Error = []error{errors.New("a test error")}
+					It("returns http.StatusBadRequest when the body is not a json object", func() {
+						bodyByte := []byte(`["artifactUrl"]`)
+						deployment.Body = &bodyByte
 
-							deploymentResponse := controller.RunDeployment(&deployment, response)
+						deploymentResponse := controller.RunDeployment(&deployment, response)
 
-							Expect(reflect.TypeOf(deploymentResponse.Error)).Should(Equal(reflect.TypeOf(D.EventError{})))
-						})
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(reflect.TypeOf(deploymentResponse.Error)).To(Equal(reflect.TypeOf(D.InvalidRequestBodyError{})))
 					})
-					It("passes populated deploymentInfo to DeployStartEvent event", func() {
+				})
+				Context("if labels are provided", func() {
+					It("passes the labels to the push manager", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "labels": {"example.com/git-sha": "abc123", "build": "42"}}`)
+
 						deployment.CFContext.Environment = environment
-						deployment.CFContext.Application = appName
-						deployment.CFContext.Space = space
-						deployment.CFContext.Organization = org
-						deployment.Type.ZIP = true
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
 
 						controller.RunDeployment(&deployment, response)
 
-						deploymentInfo := eventManager.EmitCall.Received.Events[0].Data.(*structs.DeployEventData).DeploymentInfo
-						Expect(deploymentInfo.AppName).To(Equal(appName))
-						Expect(deploymentInfo.Org).To(Equal(org))
-						Expect(deploymentInfo.Space).To(Equal(space))
-						Expect(deploymentInfo.UUID).ToNot(BeNil())
+						Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Labels).Should(Equal(map[string]string{"example.com/git-sha": "abc123", "build": "42"}))
 					})
-					It("passes CFContext to EmitEvent in the event", func() {
-						deployment.CFContext.Environment = environment
-						deployment.CFContext.Application = appName
-						deployment.CFContext.Space = space
-						deployment.CFContext.Organization = org
-
-						deployment.Type.ZIP = true
 
-						controller.RunDeployment(&deployment, response)
+					It("returns http.StatusBadRequest naming an invalid label key", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "labels": {"build": "42", "-git sha": "abc123"}}`)
 
-						event := eventManager.EmitEventCall.Received.Events[0].(push.DeployStartedEvent)
-						Expect(event.CFContext.Environment).To(Equal(environment))
-						Expect(event.CFContext.Application).To(Equal(appName))
-						Expect(event.CFContext.Space).To(Equal(space))
-						Expect(event.CFContext.Organization).To(Equal(org))
-					})
-					It("passes Auth to EmitEvent in the event", func() {
 						deployment.CFContext.Environment = environment
-						deployment.Authorization = I.Authorization{
-							Username: "myuser",
-							Password: "mypassword",
-						}
-
-						deployment.Type.ZIP = true
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
 
-						controller.RunDeployment(&deployment, response)
+						deploymentResponse := controller.RunDeployment(&deployment, response)
 
-						event := eventManager.EmitEventCall.Received.Events[0].(push.DeployStartedEvent)
-						Expect(event.Auth.Username).To(Equal("myuser"))
-						Expect(event.Auth.Password).To(Equal("mypassword"))
+						Eventually(deploymentResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+						Eventually(reflect.TypeOf(deploymentResponse.Error)).Should(Equal(reflect.TypeOf(D.InvalidLabelError{})))
+						Eventually(deploymentResponse.Error.Error()).Should(ContainSubstring(`invalid label "-git sha"`))
 					})
-					It("passes other info to EmitEvent", func() {
-						deployment.CFContext.Environment = environment
 
-						controller.Config.Environments[environment] = structs.Environment{
-							Name: environment,
-						}
+					It("returns http.StatusBadRequest for a reserved prefix", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "labels": {"cloudfoundry.org/build": "42"}}`)
 
-						deployment.Type.ZIP = true
+						deployment.CFContext.Environment = environment
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
 
-						controller.RunDeployment(&deployment, response)
+						deploymentResponse := controller.RunDeployment(&deployment, response)
 
-						event := eventManager.EmitEventCall.Received.Events[0].(push.DeployStartedEvent)
-						Expect(event.Body).ToNot(BeNil())
-						Expect(event.ContentType).To(Equal("ZIP"))
-						Expect(event.Environment.Name).To(Equal(environment))
-						Expect(event.Response).ToNot(BeNil())
+						Eventually(deploymentResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+						Eventually(deploymentResponse.Error).Should(MatchError(D.InvalidLabelError{Key: "cloudfoundry.org/build", Reason: "prefix is reserved by Cloud Foundry"}))
 					})
 				})
-				Context("deploy.finish event", func() {
+				Context("if features are provided", func() {
+					It("passes the features to the push manager", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "features": {"ssh": true, "revisions": false}}`)
 
-					It("calls Emit", func() {
 						deployment.CFContext.Environment = environment
-						deployment.Type.ZIP = true
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
 
 						controller.RunDeployment(&deployment, response)
-						Expect(eventManager.EmitCall.Received.Events[2].Type).Should(Equal(constants.DeployFinishEvent))
+
+						Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Features).Should(Equal(map[string]bool{"ssh": true, "revisions": false}))
 					})
-					It("calls EmitEvent", func() {
+
+					It("returns http.StatusBadRequest listing the supported features for an unknown feature", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "features": {"ssh": true, "service-binding-k8s": true}}`)
+
 						deployment.CFContext.Environment = environment
-						deployment.Type.ZIP = true
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
 
-						controller.RunDeployment(&deployment, response)
+						deploymentResponse := controller.RunDeployment(&deployment, response)
 
-						Expect(eventManager.EmitEventCall.Received.Events[2].Name()).To(Equal(push.DeployFinishedEvent{}.Name()))
+						Eventually(deploymentResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+						Eventually(deploymentResponse.Error).Should(MatchError(D.UnsupportedFeatureError{Feature: "service-binding-k8s", Supported: push.SupportedFeatures}))
+						Eventually(deploymentResponse.Error.Error()).Should(Equal(`unsupported app feature "service-binding-k8s", supported features are: revisions, ssh`))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
 					})
-					It("passes CFContext to Emit", func() {
+				})
+				Context("if environment variables are in the request", func() {
+					BeforeEach(func() {
 						deployment.CFContext.Environment = environment
-						deployment.CFContext.Application = appName
-						deployment.CFContext.Space = space
-						deployment.CFContext.Organization = org
-						deployment.Type.ZIP = true
+						deployment.Type.JSON = true
+					})
+
+					It("passes the environment variables to the push manager", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "env": {"API_TOKEN": "secret-token"}}`)
+						deployment.Body = &bodyByte
 
 						controller.RunDeployment(&deployment, response)
 
-						deploymentInfo := eventManager.EmitCall.Received.Events[2].Data.(*structs.DeployEventData).DeploymentInfo
-						Expect(deploymentInfo.AppName).To(Equal(appName))
-						Expect(deploymentInfo.Org).To(Equal(org))
-						Expect(deploymentInfo.Space).To(Equal(space))
-						Expect(deploymentInfo.UUID).ToNot(BeNil())
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Env).To(Equal(map[string]string{"API_TOKEN": "secret-token"}))
 					})
-					It("passes CFContext to EmitEvent in the event", func() {
+
+					It("returns http.StatusBadRequest for an invalid name", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "env": {"1API-TOKEN": "secret-token"}}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.InvalidEnvNameError{"1API-TOKEN"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if a validation webhook is configured", func() {
+					var deployValidator *mocks.DeployValidator
+
+					BeforeEach(func() {
+						deployValidator = &mocks.DeployValidator{}
+						controller.Validator = deployValidator
+
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.ZIP = true
+					})
+
+					It("validates the deploy before it starts", func() {
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployValidator.ValidateCall.TimesCalled).To(Equal(1))
+						Expect(deployValidator.ValidateCall.Received.DeployEventData.DeploymentInfo.AppName).To(Equal(appName))
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+
+					It("returns http.StatusForbidden with the webhook's message when the deploy is denied", func() {
+						deployValidator.ValidateCall.Returns.Error = validator.DeniedError{"no change ticket"}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(deploymentResponse.Error).To(MatchError(validator.DeniedError{"no change ticket"}))
+						Expect(response.String()).To(ContainSubstring("no change ticket"))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+						Expect(eventManager.EmitEventCall.Received.Events).To(BeEmpty())
+					})
+
+					It("returns http.StatusServiceUnavailable when the webhook cannot be reached", func() {
+						deployValidator.ValidateCall.Returns.Error = validator.WebhookError{errors.New("connection refused")}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusServiceUnavailable))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if a probe command is provided", func() {
+					It("passes the probe command to the push manager when the environment allows it", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "probe_command": "./smoke-test.sh"}`)
+
+						deployment.CFContext.Environment = environment
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
+
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowRequestProbe: true,
+						}
+
+						controller.RunDeployment(&deployment, response)
+
+						Eventually(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ProbeCommand).Should(Equal("./smoke-test.sh"))
+					})
+
+					It("returns http.StatusBadRequest when the environment does not allow it", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "probe_command": "./smoke-test.sh"}`)
+
+						deployment.CFContext.Environment = environment
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Eventually(deploymentResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+						Eventually(deploymentResponse.Error).Should(MatchError(D.ProbeNotAllowedError{environment}))
+					})
+				})
+				Context("if a profile is provided", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.JSON = true
+
+						controller.Config.Profiles = map[string]config.Profile{
+							"large": {
+								Instances:            4,
+								Memory:               "2G",
+								HealthCheckEndpoint:  "/health",
+								PostDeployTask:       "bin/migrate",
+								EnvironmentVariables: map[string]string{"LOG_LEVEL": "info", "REGION": "east"},
+								Labels:               map[string]string{"size": "large"},
+							},
+						}
+					})
+
+					It("applies the settings of the profile", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "profile": "large"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						deploymentInfo := pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo
+						Expect(deploymentInfo.HealthCheckEndpoint).To(Equal("/health"))
+						Expect(deploymentInfo.PostDeployTask).To(Equal("bin/migrate"))
+						Expect(deploymentInfo.EnvironmentVariables).To(Equal(map[string]string{"LOG_LEVEL": "info", "REGION": "east"}))
+						Expect(deploymentInfo.Labels).To(Equal(map[string]string{"size": "large"}))
+						Expect(deploymentInfo.ProfileManifest).To(MatchYAML("applications:\n- instances: 4\n  memory: 2G\n"))
+						Expect(pushManagerFactory.PushManagerCall.Received.EnvVars).To(HaveKeyWithValue("REGION", "east"))
+					})
+
+					It("lets the request override the profile", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "profile": "large", "health_check_endpoint": "/ready", "environment_variables": {"LOG_LEVEL": "debug"}}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						deploymentInfo := pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo
+						Expect(deploymentInfo.HealthCheckEndpoint).To(Equal("/ready"))
+						Expect(deploymentInfo.EnvironmentVariables).To(Equal(map[string]string{"LOG_LEVEL": "debug", "REGION": "east"}))
+					})
+
+					It("returns http.StatusBadRequest for an unknown profile", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "profile": "huge"}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.ProfileNotFoundError{"huge"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if the artifact host is checked", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.JSON = true
+					})
+
+					It("returns http.StatusForbidden for a link-local artifact url", func() {
+						bodyByte := []byte(`{"artifact_url": "http://169.254.169.254/latest/meta-data"}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(deploymentResponse.Error).To(BeAssignableToTypeOf(hostpolicy.HostNotAllowedError{}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusForbidden for an artifact on a host that is not allowed", func() {
+						controller.Config.ArtifactHosts = config.ArtifactHostsConfig{Allowed: []string{"artifacts.example.com"}, AllowLinkLocal: true}
+						bodyByte := []byte(`{"artifact_url": "https://evil.example.com/app.jar"}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(deploymentResponse.Error).To(MatchError(hostpolicy.HostNotAllowedError{"evil.example.com", "it is not an allowed artifact host"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("deploys an artifact on an allowed host", func() {
+						controller.Config.ArtifactHosts = config.ArtifactHostsConfig{Allowed: []string{"artifacts.example.com"}, AllowLinkLocal: true}
+						bodyByte := []byte(`{"artifact_url": "https://artifacts.example.com/app.jar"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+				})
+				Context("if an app spec is referenced", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.JSON = true
+
+						controller.Config.AppSpecs = map[string]config.AppSpec{
+							"canonical": {
+								ArtifactURL:          "https://example.com/app.jar",
+								Manifest:             "applications:\n- memory: 1G\n  instances: 2\n",
+								Instances:            2,
+								EnvironmentVariables: map[string]string{"LOG_LEVEL": "info", "REGION": "east"},
+							},
+						}
+					})
+
+					It("deploys the app spec", func() {
+						bodyByte := []byte(`{"spec": "canonical"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						deploymentInfo := pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo
+						Expect(deploymentInfo.ArtifactURL).To(Equal("https://example.com/app.jar"))
+						Expect(deploymentInfo.Instances).To(Equal(uint16(2)))
+						Expect(deploymentInfo.EnvironmentVariables).To(Equal(map[string]string{"LOG_LEVEL": "info", "REGION": "east"}))
+
+						manifest, err := base64.StdEncoding.DecodeString(deploymentInfo.Manifest)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(string(manifest)).To(MatchYAML("applications:\n- memory: 1G\n  instances: 2\n"))
+					})
+
+					It("merges the request over the app spec", func() {
+						manifest := base64.StdEncoding.EncodeToString([]byte("applications:\n- instances: 4\n"))
+						bodyByte := []byte(fmt.Sprintf(`{"spec": "canonical", "artifact_url": "xyz", "instances": 4, "manifest": "%s", "environment_variables": {"LOG_LEVEL": "debug"}}`, manifest))
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						deploymentInfo := pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo
+						Expect(deploymentInfo.ArtifactURL).To(Equal("xyz"))
+						Expect(deploymentInfo.Instances).To(Equal(uint16(4)))
+						Expect(deploymentInfo.EnvironmentVariables).To(Equal(map[string]string{"LOG_LEVEL": "debug", "REGION": "east"}))
+
+						merged, err := base64.StdEncoding.DecodeString(deploymentInfo.Manifest)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(string(merged)).To(MatchYAML("applications:\n- memory: 1G\n  instances: 4\n"))
+					})
+
+					It("returns http.StatusNotFound for an unknown app spec", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "spec": "unknown"}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusNotFound))
+						Expect(deploymentResponse.Error).To(MatchError(D.AppSpecNotFoundError{"unknown"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if the environment requires a change ticket", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+
+						controller.Config.Environments[environment] = structs.Environment{
+							RequireChangeTicket: true,
+						}
+					})
+
+					It("records the change ticket from the request body", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "change_ticket": "CHG0001"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ChangeTicket).To(Equal("CHG0001"))
+						Eventually(logBuffer).Should(Say("is for change ticket CHG0001"))
+					})
+
+					It("records the change ticket from the request header", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz"}`)
+						deployment.Body = &bodyByte
+						deployment.ChangeTicket = "CHG0002"
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.ChangeTicket).To(Equal("CHG0002"))
+					})
+
+					It("returns http.StatusBadRequest when there is no change ticket", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz"}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.ChangeTicketRequiredError{environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if the environment requires a reason", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+
+						controller.Config.Environments[environment] = structs.Environment{
+							RequireReason: true,
+						}
+					})
+
+					It("records the reason from the request body", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "reason": "hotfix for checkout errors"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Reason).To(Equal("hotfix for checkout errors"))
+						Eventually(logBuffer).Should(Say("reason for the deploy of %s with UUID %s: hotfix for checkout errors", appName, uuid))
+					})
+
+					It("records the reason from the request header", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz"}`)
+						deployment.Body = &bodyByte
+						deployment.Reason = "weekly release"
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Reason).To(Equal("weekly release"))
+					})
+
+					It("prefers the reason from the request body", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "reason": "hotfix for checkout errors"}`)
+						deployment.Body = &bodyByte
+						deployment.Reason = "weekly release"
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Reason).To(Equal("hotfix for checkout errors"))
+					})
+
+					It("returns http.StatusBadRequest when there is no reason", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "reason": "  "}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.ReasonRequiredError{environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if the environment requires approval", func() {
+					var approver *mocks.DeployApprover
+
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+						bodyByte := []byte(`{"artifact_url": "xyz"}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{
+							RequireApproval: true,
+						}
+
+						approver = &mocks.DeployApprover{}
+						controller.Approver = approver
+					})
+
+					It("deploys once the deploy is approved", func() {
+						approver.ApproveCall.Write.Output = "deploy approved: by jane"
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(approver.ApproveCall.TimesCalled).To(Equal(1))
+						Expect(approver.ApproveCall.Received.Context).ToNot(BeNil())
+						Expect(approver.ApproveCall.Received.DeployEventData.DeploymentInfo.AppName).To(Equal(appName))
+						Expect(pushManagerFactory.PushManagerCall.Called).To(BeTrue())
+						Expect(response.String()).To(ContainSubstring("deploy approved: by jane"))
+					})
+
+					It("streams the output of the deploy once it waits for the approval", func() {
+						approver.ApproveCall.Write.Output = "deploy approved: by jane\n"
+						deployer.DeployCall.Write.Output = "push output\n"
+						stream := &bytes.Buffer{}
+						deployment.StreamOutput = func() io.Writer {
+							return stream
+						}
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(stream.String()).To(HavePrefix("deploy approved: by jane\n"))
+						Expect(stream.String()).To(ContainSubstring("push output\n"))
+						Expect(response.String()).To(ContainSubstring("deploy approved: by jane\npush output\n"))
+					})
+
+					It("does not stream the output of a deploy that is already streamed", func() {
+						output := &bytes.Buffer{}
+						deployment.Output = output
+						deployment.StreamOutput = func() io.Writer {
+							Fail("StreamOutput was called")
+							return nil
+						}
+						approver.ApproveCall.Write.Output = "deploy approved: by jane\n"
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(output.String()).To(ContainSubstring("deploy approved: by jane\n"))
+					})
+
+					It("returns http.StatusForbidden when the deploy is denied", func() {
+						approver.ApproveCall.Returns.Error = approval.DeniedError{"by jane"}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(deploymentResponse.Error).To(MatchError(approval.DeniedError{"by jane"}))
+						Expect(pushManagerFactory.PushManagerCall.Called).To(BeFalse())
+						Expect(response.String()).To(ContainSubstring("deploy denied: by jane"))
+					})
+
+					It("returns http.StatusForbidden when the approval times out", func() {
+						approver.ApproveCall.Returns.Error = approval.TimedOutError{time.Hour}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(pushManagerFactory.PushManagerCall.Called).To(BeFalse())
+					})
+
+					It("returns http.StatusGatewayTimeout when the request times out while the approval is pending", func() {
+						ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+						defer cancel()
+						deployment.Context = ctx
+						controller.Approver = approverFunc(func(ctx context.Context, deployEventData structs.DeployEventData) error {
+							<-ctx.Done()
+							return approval.CanceledError{ctx.Err()}
+						})
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusGatewayTimeout))
+						Expect(deploymentResponse.Error).To(MatchError(approval.CanceledError{context.DeadlineExceeded}))
+						Expect(pushManagerFactory.PushManagerCall.Called).To(BeFalse())
+					})
+
+					It("stops waiting for the approval when the deploy is canceled", func() {
+						cancellations := D.NewCancellations()
+						controller.Cancellations = cancellations
+						canceled := make(chan error, 1)
+						var info *structs.DeploymentInfo
+						controller.Approver = approverFunc(func(ctx context.Context, deployEventData structs.DeployEventData) error {
+							info = deployEventData.DeploymentInfo
+							canceled <- cancellations.Cancel(info.UUID, info.Environment, info.Org, info.Space, info.AppName)
+							<-ctx.Done()
+							return approval.CanceledError{ctx.Err()}
+						})
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(<-canceled).To(Succeed())
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusInternalServerError))
+						Expect(deploymentResponse.Error).To(MatchError(state.DeployCanceledError{appName}))
+						Expect(pushManagerFactory.PushManagerCall.Called).To(BeFalse())
+						Expect(cancellations.Cancel(info.UUID, info.Environment, info.Org, info.Space, info.AppName)).To(MatchError(D.DeployNotFoundError{info.UUID, appName}))
+					})
+
+					It("returns http.StatusInternalServerError when the approval service fails", func() {
+						approver.ApproveCall.Returns.Error = approval.GateError{errors.New("connection refused")}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusInternalServerError))
+						Expect(pushManagerFactory.PushManagerCall.Called).To(BeFalse())
+					})
+
+					It("returns http.StatusInternalServerError when there is no approval gate", func() {
+						controller.Approver = nil
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusInternalServerError))
+						Expect(deploymentResponse.Error).To(MatchError(D.ApprovalGateNotConfiguredError{environment}))
+						Expect(pushManagerFactory.PushManagerCall.Called).To(BeFalse())
+					})
+				})
+				Context("if the environment has a deploy SLO", func() {
+					var step time.Duration
+
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+						bodyByte := []byte(`{"artifact_url": "xyz"}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{
+							DeploySLOSeconds: 600,
+						}
+
+						now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+						controller.Now = func() time.Time {
+							now = now.Add(step)
+							return now
+						}
+					})
+
+					It("warns about a deploy that took longer without failing it", func() {
+						step = 12 * time.Minute
+						deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusOK))
+						Expect(deploymentResponse.Error).ToNot(HaveOccurred())
+						Expect(response.String()).To(ContainSubstring("WARNING: the deploy took 12m0s, longer than the deploy SLO of 10m0s of environment " + environment))
+					})
+
+					It("emits a deploy.slo_breach event with the duration and the target", func() {
+						step = 12 * time.Minute
+
+						controller.RunDeployment(&deployment, response)
+
+						var breach *structs.DeploySLOBreach
+						for _, event := range eventManager.EmitCall.Received.Events {
+							if event.Type == constants.DeploySLOBreachEvent {
+								breach = event.Data.(*structs.DeploySLOBreach)
+							}
+						}
+						Expect(breach).ToNot(BeNil())
+						Expect(breach.DeploymentInfo.AppName).To(Equal(appName))
+						Expect(breach.Duration).To(Equal(12 * time.Minute))
+						Expect(breach.Target).To(Equal(10 * time.Minute))
+
+						var event push.DeploySLOBreachEvent
+						for _, emitted := range eventManager.EmitEventCall.Received.Events {
+							if e, ok := emitted.(push.DeploySLOBreachEvent); ok {
+								event = e
+							}
+						}
+						Expect(event.CFContext.Application).To(Equal(appName))
+						Expect(event.Duration).To(Equal(12 * time.Minute))
+					})
+
+					It("does not warn about a deploy within the SLO", func() {
+						step = 5 * time.Minute
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(response.String()).ToNot(ContainSubstring("deploy SLO"))
+						for _, event := range eventManager.EmitCall.Received.Events {
+							Expect(event.Type).ToNot(Equal(constants.DeploySLOBreachEvent))
+						}
+					})
+				})
+				Context("if the environment requires custom params", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+
+						controller.Config.Environments[environment] = structs.Environment{
+							CustomParams:         map[string]interface{}{"team_id": "t-rex"},
+							RequiredCustomParams: []string{"team_id", "cost_center"},
+						}
+					})
+
+					It("deploys when the request body sets the missing custom params", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "CustomParams": {"cost_center": "42"}}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.CustomParams).To(Equal(map[string]interface{}{"team_id": "t-rex", "cost_center": "42"}))
+						Expect(controller.Config.Environments[environment].CustomParams).To(Equal(map[string]interface{}{"team_id": "t-rex"}))
+					})
+
+					It("returns http.StatusBadRequest listing the missing custom params", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "CustomParams": {"team_id": ""}}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.CustomParamsRequiredError{environment, []string{"team_id", "cost_center"}}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+						Expect(eventManager.EmitCall.TimesCalled).To(Equal(0))
+					})
+				})
+				Context("if the manifest declares the application name", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+					})
+
+					It("deploys when the name matches the application", func() {
+						manifest := base64.StdEncoding.EncodeToString([]byte("applications:\n- name: " + appName + "\n"))
+						bodyByte := []byte(fmt.Sprintf(`{"artifact_url": "xyz", "manifest": "%s"}`, manifest))
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+
+					It("returns http.StatusBadRequest when the name does not match the application", func() {
+						manifest := base64.StdEncoding.EncodeToString([]byte("applications:\n- name: other-app\n"))
+						bodyByte := []byte(fmt.Sprintf(`{"artifact_url": "xyz", "manifest": "%s"}`, manifest))
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.AppNameMismatchError{appName, []string{"other-app"}}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("deploys a mismatched name when the request allows it", func() {
+						manifest := base64.StdEncoding.EncodeToString([]byte("applications:\n- name: other-app\n"))
+						bodyByte := []byte(fmt.Sprintf(`{"artifact_url": "xyz", "manifest": "%s"}`, manifest))
+						deployment.Body = &bodyByte
+						deployment.AllowNameMismatch = true
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+				})
+				Context("if the request is multipart", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.Multipart = true
+						bodyByte := []byte("some artifact")
+						deployment.Body = &bodyByte
+					})
+
+					It("deploys the artifact with the manifest part", func() {
+						deployment.Manifest = "applications:\n- name: " + appName + "\n"
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.ContentType).To(Equal("MULTIPART"))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.Manifest).To(Equal(base64.StdEncoding.EncodeToString([]byte(deployment.Manifest))))
+					})
+
+					It("returns http.StatusBadRequest when the manifest part names another application", func() {
+						deployment.Manifest = "applications:\n- name: other-app\n"
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.AppNameMismatchError{appName, []string{"other-app"}}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if a start command is provided", func() {
+					It("passes it to the push", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "command": "bin/start --worker"}`)
+						deployment.Body = &bodyByte
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Command).To(Equal("bin/start --worker"))
+					})
+
+					It("returns http.StatusBadRequest when every application of the manifest is pushed", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "command": "bin/start --worker"}`)
+						deployment.Body = &bodyByte
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = ""
+						deployment.Type.JSON = true
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.CommandNotSupportedError{}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if a space GUID is provided", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+
+						controller.Config.Environments[environment] = structs.Environment{Foundations: []string{"api1.example.com"}}
+					})
+
+					It("passes the GUIDs to the push", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "space_guid": "5a1b2c3d-0000-4000-8000-000000000001", "org_guid": "5A1B2C3D-0000-4000-8000-000000000002"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						deploymentInfo := pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo
+						Expect(deploymentInfo.SpaceGUID).To(Equal("5a1b2c3d-0000-4000-8000-000000000001"))
+						Expect(deploymentInfo.OrgGUID).To(Equal("5A1B2C3D-0000-4000-8000-000000000002"))
+					})
+
+					It("returns http.StatusBadRequest when a GUID is not well formed", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "space_guid": "5a1b2c3d-0000-4000-8000-000000000001", "org_guid": "my-org"}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.InvalidGUIDError{"org_guid", "my-org"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusBadRequest when an org GUID is given without a space GUID", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "org_guid": "5a1b2c3d-0000-4000-8000-000000000002"}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.OrgGUIDWithoutSpaceGUIDError{}))
+					})
+
+					It("does not check the allowed orgs and spaces of an org and space named after the space GUID", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "space_guid": "5a1b2c3d-0000-4000-8000-000000000001"}`)
+						deployment.Body = &bodyByte
+						deployment.CFContext.Organization = push.NameFromSpaceGUID
+						deployment.CFContext.Space = push.NameFromSpaceGUID
+
+						controller.Config.Environments[environment] = structs.Environment{
+							Foundations:   []string{"api1.example.com"},
+							AllowedOrgs:   []string{"my-org"},
+							AllowedSpaces: []string{"my-space"},
+						}
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(pushManagerFactory.PushManagerCall.Called).To(BeTrue())
+					})
+
+					It("returns http.StatusBadRequest when an org or space is named after a space GUID that is not given", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz"}`)
+						deployment.Body = &bodyByte
+						deployment.CFContext.Space = push.NameFromSpaceGUID
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.NameWithoutSpaceGUIDError{push.NameFromSpaceGUID}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusBadRequest when the environment has more than one foundation", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "space_guid": "5a1b2c3d-0000-4000-8000-000000000001"}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{Foundations: []string{"api1.example.com", "api2.example.com"}}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.SpaceGUIDNotSupportedError{environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if buildpacks are provided", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+					})
+
+					It("passes them to the push in order", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "buildpacks": ["nodejs_buildpack", "java_buildpack"]}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.Buildpacks).To(Equal([]string{"nodejs_buildpack", "java_buildpack"}))
+					})
+
+					It("passes them to the push when the environment allows them", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "buildpacks": ["java_buildpack"]}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedBuildpacks: []string{"nodejs_buildpack", "java_buildpack"},
+						}
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+
+					It("returns http.StatusForbidden when the environment does not allow the org", func() {
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedOrgs: []string{"other-org"},
+						}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(deploymentResponse.Error).To(MatchError(D.OrgNotAllowedError{deployment.CFContext.Organization, environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusForbidden when the environment does not allow the space", func() {
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedOrgs:   []string{deployment.CFContext.Organization},
+							AllowedSpaces: []string{"other-space"},
+						}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(deploymentResponse.Error).To(MatchError(D.SpaceNotAllowedError{deployment.CFContext.Space, environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusForbidden when the environment does not allow a buildpack", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "buildpacks": ["java_buildpack", "go_buildpack"]}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedBuildpacks: []string{"java_buildpack"},
+						}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(deploymentResponse.Error).To(MatchError(D.BuildpackNotAllowedError{"go_buildpack", environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusBadRequest when every application of the manifest is pushed", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "buildpacks": ["java_buildpack"]}`)
+						deployment.Body = &bodyByte
+						deployment.CFContext.Application = ""
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.BuildpacksNotSupportedError{}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if push flags are provided", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+					})
+
+					It("passes them to the push when the environment allows them", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "push_flags": ["-m 1G", "-t 180"]}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedPushFlags: []string{"-m", "-t"},
+						}
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+						Expect(pushManagerFactory.PushManagerCall.Received.DeployEventData.DeploymentInfo.PushFlags).To(Equal([]string{"-m 1G", "-t 180"}))
+					})
+
+					It("returns http.StatusForbidden when the environment does not allow a push flag", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "push_flags": ["-m 1G", "-k 2G"]}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedPushFlags: []string{"-m"},
+						}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusForbidden))
+						Expect(deploymentResponse.Error).To(MatchError(D.PushFlagNotAllowedError{"-k", environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusBadRequest for a flag that cannot be passed through", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "push_flags": ["-p /etc"]}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedPushFlags: []string{"-m"},
+						}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.InvalidPushFlagError{"-p /etc"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusBadRequest for a value that looks like another flag", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "push_flags": ["-m --no-start"]}`)
+						deployment.Body = &bodyByte
+
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedPushFlags: []string{"-m"},
+						}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.InvalidPushFlagError{"-m --no-start"}))
+					})
+
+					It("returns http.StatusBadRequest when every application of the manifest is pushed", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "push_flags": ["-m 1G"]}`)
+						deployment.Body = &bodyByte
+						deployment.CFContext.Application = ""
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.PushFlagsNotSupportedError{}))
+					})
+				})
+				Context("if an isolation segment is requested", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.JSON = true
+
+						bodyByte := []byte(`{"artifact_url": "xyz", "isolation_segment": "secure-segment"}`)
+						deployment.Body = &bodyByte
+					})
+
+					It("passes it to the push when the environment allows it", func() {
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedIsolationSegments: []string{"shared-segment", "secure-segment"},
+						}
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.IsolationSegment).To(Equal("secure-segment"))
+					})
+
+					It("returns http.StatusBadRequest when the environment does not allow it", func() {
+						controller.Config.Environments[environment] = structs.Environment{
+							AllowedIsolationSegments: []string{"shared-segment"},
+						}
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.IsolationSegmentNotAllowedError{"secure-segment", environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusBadRequest when the environment allows no isolation segments", func() {
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if a strategy is requested", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.JSON = true
+					})
+
+					It("records the requested strategy on the deployment info", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "strategy": "traffic-split"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Received.DeploymentInfo.Strategy).To(Equal(structs.StrategyTrafficSplit))
+					})
+
+					It("uses the strategy of the environment when none is requested", func() {
+						controller.Config.Environments[environment] = structs.Environment{TrafficSplit: true}
+						bodyByte := []byte(`{"artifact_url": "xyz"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Received.DeploymentInfo.Strategy).To(Equal(structs.StrategyTrafficSplit))
+					})
+
+					It("defaults to the bluegreen strategy", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz"}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Received.DeploymentInfo.Strategy).To(Equal(structs.StrategyBlueGreen))
+					})
+
+					It("returns http.StatusBadRequest for an unknown strategy", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "strategy": "canary"}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.UnknownStrategyError{"canary"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if the environment limits the instances", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.JSON = true
+						controller.Config.Environments[environment] = structs.Environment{MaxInstances: 10}
+					})
+
+					It("returns http.StatusBadRequest when the request asks for more instances", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "instances": 5000}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.TooManyInstancesError{deployment.CFContext.Application, 5000, 10}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("deploys when the request asks for the allowed instances", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "instances": 10}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+				})
+				Context("if the environment limits the memory and disk of each instance", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.Type.JSON = true
+						controller.Config.Environments[environment] = structs.Environment{
+							MaxMemoryPerInstance: "2G",
+							MaxDiskPerInstance:   "4G",
+							AllowedPushFlags:     []string{"-m", "-k"},
+						}
+					})
+
+					It("returns http.StatusBadRequest when the push flags ask for more memory", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "push_flags": ["-m 4096M"]}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.ResourceLimitError{deployment.CFContext.Application, "memory", "4096M", "2G"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns http.StatusBadRequest when the push flags ask for more disk", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "push_flags": ["-k 5G"]}`)
+						deployment.Body = &bodyByte
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.ResourceLimitError{deployment.CFContext.Application, "disk", "5G", "4G"}))
+					})
+
+					It("deploys when the push flags ask for the allowed memory and disk", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "push_flags": ["-m 2048M", "-k 4G"]}`)
+						deployment.Body = &bodyByte
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+				})
+				Context("if a manual cutover is requested", func() {
+					It("returns http.StatusBadRequest when every application of the manifest is pushed", func() {
+						bodyByte := []byte(`{"artifact_url": "xyz", "manual_cutover": true}`)
+						deployment.Body = &bodyByte
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = ""
+						deployment.Type.JSON = true
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(D.ManualCutoverNotSupportedError{}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+				Context("if body is invalid", func() {
+					It("returns an error", func() {
+						bodyByte := []byte("")
+
+						deployment.CFContext.Environment = environment
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Eventually(deploymentResponse.Error).ShouldNot(BeNil())
+						Eventually(deploymentResponse.Error.Error()).Should(ContainSubstring("EOF"))
+					})
+
+					It("returns http.StatusBadRequest for malformed json", func() {
+						bodyByte := []byte(`{"artifact_url": `)
+
+						deployment.CFContext.Environment = environment
+						deployment.Body = &bodyByte
+						deployment.Type.JSON = true
+
+						deploymentResponse := controller.RunDeployment(&deployment, response)
+
+						Eventually(deploymentResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+						Eventually(reflect.TypeOf(deploymentResponse.Error)).Should(Equal(reflect.TypeOf(D.InvalidRequestBodyError{})))
+						Eventually(deploymentResponse.Error.Error()).Should(ContainSubstring("invalid request body"))
+					})
+				})
+				Context("deploy.start event", func() {
+					It("logs a start event", func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						Eventually(logBuffer).Should(Say("emitting a deploy.start event"))
+					})
+					It("calls Emit", func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(eventManager.EmitCall.Received.Events[0].Type).Should(Equal(constants.DeployStartEvent))
+					})
+					It("calls EmitEvent", func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(eventManager.EmitEventCall.Received.Events[0].Name()).Should(Equal("DeployStartedEvent"))
+					})
+					Context("when Emit fails", func() {
+						It("returns error", func() {
+							deployment.CFContext.Environment = environment
+							deployment.Type.ZIP = true
+
+							eventManager.EmitCall.Returns.Error = []error{errors.New("a test error")}
+
+							deploymentResponse := controller.RunDeployment(&deployment, response)
+
+							Expect(reflect.TypeOf(deploymentResponse.Error)).Should(Equal(reflect.TypeOf(D.EventError{})))
+						})
+					})
+					Context("when EmitEvent fails", func() {
+						It("returns error", func() {
+							deployment.CFContext.Environment = environment
+							deployment.Type.ZIP = true
+
+							eventManager.EmitEventCall.Returns.Error = []error{errors.New("a test error")}
+
+							deploymentResponse := controller.RunDeployment(&deployment, response)
+
+							Expect(reflect.TypeOf(deploymentResponse.Error)).Should(Equal(reflect.TypeOf(D.EventError{})))
+						})
+					})
+					It("passes populated deploymentInfo to DeployStartEvent event", func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.CFContext.Space = space
+						deployment.CFContext.Organization = org
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						deploymentInfo := eventManager.EmitCall.Received.Events[0].Data.(*structs.DeployEventData).DeploymentInfo
+						Expect(deploymentInfo.AppName).To(Equal(appName))
+						Expect(deploymentInfo.Org).To(Equal(org))
+						Expect(deploymentInfo.Space).To(Equal(space))
+						Expect(deploymentInfo.UUID).ToNot(BeNil())
+					})
+					It("passes CFContext to EmitEvent in the event", func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.CFContext.Space = space
+						deployment.CFContext.Organization = org
+
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						event := eventManager.EmitEventCall.Received.Events[0].(push.DeployStartedEvent)
+						Expect(event.CFContext.Environment).To(Equal(environment))
+						Expect(event.CFContext.Application).To(Equal(appName))
+						Expect(event.CFContext.Space).To(Equal(space))
+						Expect(event.CFContext.Organization).To(Equal(org))
+					})
+					It("passes Auth to EmitEvent in the event", func() {
+						deployment.CFContext.Environment = environment
+						deployment.Authorization = I.Authorization{
+							Username: "myuser",
+							Password: "mypassword",
+						}
+
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						event := eventManager.EmitEventCall.Received.Events[0].(push.DeployStartedEvent)
+						Expect(event.Auth.Username).To(Equal("myuser"))
+						Expect(event.Auth.Password).To(Equal("mypassword"))
+					})
+					It("passes other info to EmitEvent", func() {
+						deployment.CFContext.Environment = environment
+
+						controller.Config.Environments[environment] = structs.Environment{
+							Name: environment,
+						}
+
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						event := eventManager.EmitEventCall.Received.Events[0].(push.DeployStartedEvent)
+						Expect(event.Body).ToNot(BeNil())
+						Expect(event.ContentType).To(Equal("ZIP"))
+						Expect(event.Environment.Name).To(Equal(environment))
+						Expect(event.Response).ToNot(BeNil())
+					})
+					It("passes the reason to EmitEvent", func() {
+						deployment.CFContext.Environment = environment
+						deployment.Reason = "hotfix for checkout errors"
+
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						event := eventManager.EmitEventCall.Received.Events[0].(push.DeployStartedEvent)
+						Expect(event.Reason).To(Equal("hotfix for checkout errors"))
+					})
+				})
+				Context("deploy.finish event", func() {
+
+					It("calls Emit", func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+						Expect(eventManager.EmitCall.Received.Events[2].Type).Should(Equal(constants.DeployFinishEvent))
+					})
+					It("calls EmitEvent", func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						Expect(eventManager.EmitEventCall.Received.Events[2].Name()).To(Equal(push.DeployFinishedEvent{}.Name()))
+					})
+					It("passes CFContext to Emit", func() {
+						deployment.CFContext.Environment = environment
+						deployment.CFContext.Application = appName
+						deployment.CFContext.Space = space
+						deployment.CFContext.Organization = org
+						deployment.Type.ZIP = true
+
+						controller.RunDeployment(&deployment, response)
+
+						deploymentInfo := eventManager.EmitCall.Received.Events[2].Data.(*structs.DeployEventData).DeploymentInfo
+						Expect(deploymentInfo.AppName).To(Equal(appName))
+						Expect(deploymentInfo.Org).To(Equal(org))
+						Expect(deploymentInfo.Space).To(Equal(space))
+						Expect(deploymentInfo.UUID).ToNot(BeNil())
+					})
+					It("passes CFContext to EmitEvent in the event", func() {
 						deployment.CFContext.Environment = environment
 						deployment.CFContext.Application = appName
 						deployment.CFContext.Space = space
@@ -923,6 +2383,25 @@ var _ = Describe("RunDeployment", func() {
 							controller.RunDeployment(&deployment, response)
 							Eventually(logBuffer).Should(Say("an error occurred when emitting a deploy.success event"))
 						})
+
+						It("keeps the event to be retried when event retries are on", func() {
+							deployment.CFContext.Environment = environment
+							deployment.Type.ZIP = true
+
+							af := &afero.Afero{Fs: afero.NewMemMapFs()}
+							queue, err := retry.NewQueue(af, "retries", eventManager, 3, time.Minute, I.DefaultLogger(logBuffer, logging.DEBUG, "api_test"))
+							Expect(err).ToNot(HaveOccurred())
+							controller.EventRetries = queue
+
+							eventManager.EmitCall.Returns.Error = []error{nil, errors.New("a test error"), nil}
+
+							controller.RunDeployment(&deployment, response)
+
+							entries, err := af.ReadDir("retries")
+							Expect(err).ToNot(HaveOccurred())
+							Expect(entries).To(HaveLen(1))
+							Eventually(logBuffer).Should(Say("the deploy.success event of deploy " + uuid + " will be retried in 1m0s"))
+						})
 					})
 					Context("when EmitEvent fails", func() {
 						It("returns error", func() {
@@ -1071,9 +2550,176 @@ var _ = Describe("RunDeployment", func() {
 					Eventually(string(responseBytes)).Should(ContainSubstring("Error: some details"))
 					Eventually(string(responseBytes)).Should(ContainSubstring("Potential solution: a solution"))
 				})
+
+				It("truncates the output but keeps found errors when error output is capped", func() {
+					deployment.CFContext.Environment = environment
+					deployment.Type.ZIP = true
+					controller.Config.ErrorOutput = config.ErrorOutputConfig{MaxLines: 2}
+
+					deployer.DeployCall.Write.Output = "line 1\nline 2\nline 3\nline 4\n"
+					deployer.DeployCall.Returns.Error = errors.New("push failed")
+					deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+
+					retError := error_finder.CreateLogMatchedError("a description", []string{"some details"}, "a solution", "a code")
+					errorFinder.FindErrorsCall.Returns.Errors = []I.LogMatchedError{retError}
+
+					controller.RunDeployment(&deployment, response)
+					responseBytes, _ := ioutil.ReadAll(response)
+
+					Expect(errorFinder.FindErrorsCall.Received.Response).To(ContainSubstring("line 4"))
+					Expect(string(responseBytes)).To(ContainSubstring("line 2\n... 2 more lines truncated\n"))
+					Expect(string(responseBytes)).ToNot(ContainSubstring("line 3"))
+					Expect(string(responseBytes)).To(ContainSubstring("The following error was found in the above logs: a description"))
+				})
 			})
 		})
 
 	})
 
 })
+
+var _ = Describe("PromoteDeployment", func() {
+	var (
+		deployer           *mocks.Deployer
+		pushManagerFactory *mocks.PushManagerFactory
+		controller         *push.PushController
+		deployment         I.Deployment
+		response           *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		deployer = &mocks.Deployer{}
+		pushManagerFactory = &mocks.PushManagerFactory{}
+		response = &bytes.Buffer{}
+
+		controller = &push.PushController{
+			Deployer:           deployer,
+			Log:                I.DeploymentLogger{Log: I.DefaultLogger(NewBuffer(), logging.DEBUG, "api_test"), UUID: "request-uuid"},
+			PushManagerFactory: pushManagerFactory,
+			EventManager:       &mocks.EventManager{},
+			Config: config.Config{
+				Environments: map[string]structs.Environment{"prod": {Domain: "example.com"}},
+			},
+		}
+
+		deployment = I.Deployment{
+			Authorization: I.Authorization{Username: "username", Password: "password"},
+			CFContext: I.CFContext{
+				Environment:  "prod",
+				Organization: "org",
+				Space:        "space",
+				Application:  "myApp",
+			},
+		}
+	})
+
+	It("deploys with the promote manager of the pending deploy", func() {
+		promoteManager := &mocks.PushManager{}
+		pushManagerFactory.PromoteManagerCall.Returns.ActionCreator = promoteManager
+		deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+		deployResponse := controller.PromoteDeployment(&deployment, "deploy-uuid", response)
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusOK))
+		deploymentInfo := pushManagerFactory.PromoteManagerCall.Received.DeployEventData.DeploymentInfo
+		Expect(deploymentInfo.UUID).To(Equal("deploy-uuid"))
+		Expect(deploymentInfo.AppName).To(Equal("myApp"))
+		Expect(deploymentInfo.Username).To(Equal("username"))
+		Expect(deploymentInfo.Domain).To(Equal("example.com"))
+		Expect(deployer.DeployCall.Received.ActionCreator).To(Equal(promoteManager))
+	})
+
+	It("returns http.StatusNotFound when no deploy is waiting for promotion", func() {
+		pushManagerFactory.PromoteManagerCall.Returns.Error = D.PendingPromotionNotFoundError{UUID: "deploy-uuid", AppName: "myApp"}
+
+		deployResponse := controller.PromoteDeployment(&deployment, "deploy-uuid", response)
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(response.String()).To(ContainSubstring("no deploy of myApp with UUID deploy-uuid is waiting for promotion"))
+		Expect(deployer.DeployCall.Called).To(Equal(0))
+	})
+
+	It("returns http.StatusInternalServerError when the environment does not exist", func() {
+		deployment.CFContext.Environment = "unknown"
+
+		deployResponse := controller.PromoteDeployment(&deployment, "deploy-uuid", response)
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusInternalServerError))
+		Expect(pushManagerFactory.PromoteManagerCall.Called).To(BeFalse())
+	})
+})
+
+var _ = Describe("RollbackDeployment", func() {
+	var (
+		deployer           *mocks.Deployer
+		pushManagerFactory *mocks.PushManagerFactory
+		controller         *push.PushController
+		deployment         I.Deployment
+		response           *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		deployer = &mocks.Deployer{}
+		pushManagerFactory = &mocks.PushManagerFactory{}
+		response = &bytes.Buffer{}
+
+		controller = &push.PushController{
+			Deployer:           deployer,
+			Log:                I.DeploymentLogger{Log: I.DefaultLogger(NewBuffer(), logging.DEBUG, "api_test"), UUID: "request-uuid"},
+			PushManagerFactory: pushManagerFactory,
+			EventManager:       &mocks.EventManager{},
+			Config: config.Config{
+				Environments: map[string]structs.Environment{"prod": {Domain: "example.com"}},
+			},
+		}
+
+		deployment = I.Deployment{
+			Authorization: I.Authorization{Username: "username", Password: "password"},
+			CFContext: I.CFContext{
+				Environment:  "prod",
+				Organization: "org",
+				Space:        "space",
+				Application:  "myApp",
+			},
+		}
+	})
+
+	It("deploys with the rollback manager of the deploy", func() {
+		rollbackManager := &mocks.PushManager{}
+		pushManagerFactory.RollbackManagerCall.Returns.ActionCreator = rollbackManager
+		deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+		deployResponse := controller.RollbackDeployment(&deployment, "deploy-uuid", response)
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusOK))
+		deploymentInfo := pushManagerFactory.RollbackManagerCall.Received.DeployEventData.DeploymentInfo
+		Expect(deploymentInfo.UUID).To(Equal("deploy-uuid"))
+		Expect(deploymentInfo.AppName).To(Equal("myApp"))
+		Expect(deployer.DeployCall.Received.ActionCreator).To(Equal(rollbackManager))
+		Expect(pushManagerFactory.PromoteManagerCall.Called).To(BeFalse())
+	})
+
+	It("returns http.StatusNotFound when no replaced application is waiting for deletion", func() {
+		pushManagerFactory.RollbackManagerCall.Returns.Error = D.RetirementNotFoundError{UUID: "deploy-uuid", AppName: "myApp"}
+
+		deployResponse := controller.RollbackDeployment(&deployment, "deploy-uuid", response)
+
+		Expect(deployResponse.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(response.String()).To(ContainSubstring("no application of myApp replaced by the deploy with UUID deploy-uuid is waiting for deletion"))
+		Expect(deployer.DeployCall.Called).To(Equal(0))
+	})
+})
+
+// approverFunc is a DeployApprover that runs the function.
+type approverFunc func(ctx context.Context, deployEventData structs.DeployEventData) error
+
+func (f approverFunc) Approve(ctx context.Context, deployEventData structs.DeployEventData, output io.Writer) error {
+	return f(ctx, deployEventData)
+}
+
+// deployerFunc is a Deployer that runs the function.
+type deployerFunc func(deploymentInfo *structs.DeploymentInfo) *I.DeployResponse
+
+func (f deployerFunc) Deploy(deploymentInfo *structs.DeploymentInfo, env structs.Environment, actionCreator I.ActionCreator, response io.ReadWriter) *I.DeployResponse {
+	return f(deploymentInfo)
+}
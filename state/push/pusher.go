@@ -4,17 +4,33 @@ package push
 import (
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	C "github.com/compozed/deployadactyl/constants"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/state"
 	S "github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/tracing"
 )
 
 // TemporaryNameSuffix is used when deploying the new application in order to
 // not overide the existing application name.
 const TemporaryNameSuffix = "-new-build-"
 
+const (
+	// PostDeployTaskNamePrefix is prepended to the deployment UUID to name the post deploy task.
+	PostDeployTaskNamePrefix = "post-deploy-"
+
+	// PostDeployTaskPollInterval is how often the state of the post deploy task is checked.
+	PostDeployTaskPollInterval = 5 * time.Second
+
+	// InstancePollInterval is how often the instance states are checked while waiting for instances to run.
+	InstancePollInterval = 5 * time.Second
+)
+
 // Pusher has a courier used to push applications to Cloud Foundry.
 // It represents logging into a single foundation to perform operations.
 type Pusher struct {
@@ -29,10 +45,56 @@ type Pusher struct {
 	Fetcher        I.Fetcher
 	CFContext      I.CFContext
 	Auth           I.Authorization
+	Prober         I.Prober
+
+	// ManifestFile is the manifest in AppPath used for the push. The manifest.yml of AppPath is used when empty.
+	ManifestFile string
+
+	// Sleep waits between checks of the post deploy task. Defaults to time.Sleep.
+	Sleep func(time.Duration)
+
+	// Cancellation is checked between the steps of the push. The push cannot be canceled when it is nil.
+	Cancellation *deployer.Cancellation
+
+	// Tracer records the push, health check and swap as spans. They are not traced when it is nil.
+	Tracer *tracing.Tracer
+
+	// Retirements keeps the replaced application stopped for the delete delay of the environment. The replaced
+	// application is deleted right away when it is nil.
+	Retirements *Retirements
+
+	// HealthChecks bounds how many foundations are health checked at the same time. The health check runs right
+	// away when it is nil.
+	HealthChecks *HealthChecks
+
+	// Journal records the phase of the push at each phase boundary, so it can be recovered if Deployadactyl
+	// restarts during the push. The push is not recorded when it is nil.
+	Journal *Journal
 }
 
 // Login will login to a Cloud Foundry instance.
-func (p Pusher) Initially() error {
+// If the environment allows it, the space (and optionally the org) is created first when missing.
+// A push with a space GUID targets the space with that GUID instead, and takes its org and space names from it.
+func (p *Pusher) Initially() error {
+	if p.DeploymentInfo.SpaceGUID != "" {
+		err := p.targetSpaceGUID()
+		if err != nil {
+			return err
+		}
+
+		if p.DeploymentInfo.IsolationSegment != "" {
+			return p.setIsolationSegment()
+		}
+		return nil
+	}
+
+	if p.Environment.AutoCreateSpace {
+		err := p.createSpace()
+		if err != nil {
+			return err
+		}
+	}
+
 	p.Log.Debugf(
 		`logging into cloud foundry with parameters:
 		foundation URL: %+v
@@ -58,6 +120,145 @@ func (p Pusher) Initially() error {
 
 	p.Log.Infof("logged into cloud foundry %s", p.FoundationURL)
 
+	if p.DeploymentInfo.IsolationSegment != "" {
+		return p.setIsolationSegment()
+	}
+
+	return nil
+}
+
+// setIsolationSegment runs the space of the push in its isolation segment, so the pushed application starts there.
+func (p Pusher) setIsolationSegment() error {
+	var (
+		space   = p.DeploymentInfo.Space
+		segment = p.DeploymentInfo.IsolationSegment
+	)
+
+	p.Log.Infof("setting the isolation segment of space %s to %s on %s", space, segment, p.FoundationURL)
+
+	output, err := p.Courier.SetSpaceIsolationSegment(space, segment)
+	p.Response.Write(output)
+	if err != nil {
+		p.Log.Errorf("could not set the isolation segment of space %s on %s", space, p.FoundationURL)
+		return state.SetIsolationSegmentError{space, segment, output}
+	}
+
+	return nil
+}
+
+// targetSpaceGUID looks up the space with the GUID of the push and targets it, without looking the org and space
+// up by name. The org and space names of the push are set to those of the space. Names given with the push must
+// match them, and so must its org GUID; a name of NameFromSpaceGUID matches any space. The org and space found must
+// be allowed in the environment.
+func (p *Pusher) targetSpaceGUID() error {
+	output, err := p.Courier.Authenticate(p.FoundationURL, p.DeploymentInfo.Username, p.DeploymentInfo.Password, p.DeploymentInfo.SkipSSL)
+	p.Response.Write(output)
+	if err != nil {
+		p.Log.Errorf("could not authenticate to %s", p.FoundationURL)
+		return state.LoginError{p.FoundationURL, output}
+	}
+
+	space, err := p.Courier.SpaceByGUID(p.DeploymentInfo.SpaceGUID)
+	if err != nil {
+		p.Log.Errorf("could not find space %s on %s", p.DeploymentInfo.SpaceGUID, p.FoundationURL)
+		return state.SpaceGUIDError{p.DeploymentInfo.SpaceGUID, err}
+	}
+
+	if !matchesName(p.DeploymentInfo.Space, space.Name) || !matchesName(p.DeploymentInfo.Org, space.OrgName) ||
+		(p.DeploymentInfo.OrgGUID != "" && !strings.EqualFold(space.OrgGUID, p.DeploymentInfo.OrgGUID)) {
+		err = state.SpaceGUIDConflictError{p.DeploymentInfo.SpaceGUID, p.DeploymentInfo.Org, p.DeploymentInfo.Space, space}
+		p.Log.Error(err)
+		return err
+	}
+
+	err = checkOrgAndSpace(I.CFContext{Environment: p.DeploymentInfo.Environment, Organization: space.OrgName, Space: space.Name}, p.Environment)
+	if err != nil {
+		p.Log.Error(err)
+		return err
+	}
+
+	p.Log.Infof("space %s is %s in org %s on %s", space.GUID, space.Name, space.OrgName, p.FoundationURL)
+	p.DeploymentInfo.Org = space.OrgName
+	p.DeploymentInfo.Space = space.Name
+
+	output, err = p.Courier.Target(space.OrgName, space.Name)
+	p.Response.Write(output)
+	if err != nil {
+		p.Log.Errorf("could not target space %s on %s", space.GUID, p.FoundationURL)
+		return state.TargetError{space.OrgName, space.Name, output}
+	}
+
+	p.Log.Infof("logged into cloud foundry %s", p.FoundationURL)
+
+	return nil
+}
+
+// matchesName reports whether the org or space name of a push with a space GUID matches the name found on the
+// foundation. The name matches any name when it is NameFromSpaceGUID.
+func matchesName(name, found string) bool {
+	return name == NameFromSpaceGUID || name == found
+}
+
+func (p Pusher) createSpace() error {
+	var (
+		org   = p.DeploymentInfo.Org
+		space = p.DeploymentInfo.Space
+	)
+
+	output, err := p.Courier.Authenticate(p.FoundationURL, p.DeploymentInfo.Username, p.DeploymentInfo.Password, p.DeploymentInfo.SkipSSL)
+	if err != nil {
+		p.Response.Write(output)
+		p.Log.Errorf("could not authenticate to %s", p.FoundationURL)
+		return state.LoginError{p.FoundationURL, output}
+	}
+
+	orgCreated := false
+	if p.Environment.AutoCreateOrg && !p.Courier.OrgExists(org) {
+		p.Log.Infof("creating org %s on %s", org, p.FoundationURL)
+
+		output, err = p.Courier.CreateOrg(org)
+		p.Response.Write(output)
+		if err != nil {
+			p.Log.Errorf("could not create org %s on %s", org, p.FoundationURL)
+			return state.CreateOrgError{org, output}
+		}
+
+		p.Log.Infof("created org %s on %s", org, p.FoundationURL)
+		orgCreated = true
+	}
+
+	if !orgCreated && p.Courier.SpaceExists(org, space) {
+		return nil
+	}
+
+	p.Log.Infof("creating space %s in org %s on %s", space, org, p.FoundationURL)
+
+	output, err = p.Courier.CreateSpace(org, space)
+	p.Response.Write(output)
+	if err != nil {
+		p.Log.Errorf("could not create space %s in org %s on %s", space, org, p.FoundationURL)
+		return state.CreateSpaceError{org, space, output}
+	}
+
+	p.Log.Infof("created space %s in org %s on %s", space, org, p.FoundationURL)
+
+	event := SpaceCreatedEvent{
+		CFContext:     p.CFContext,
+		Auth:          p.Auth,
+		Response:      p.Response,
+		FoundationURL: p.FoundationURL,
+		Org:           org,
+		Space:         space,
+		OrgCreated:    orgCreated,
+		Data:          p.DeploymentInfo.Data,
+		Log:           p.Log,
+	}
+	err = p.EventManager.EmitEvent(event)
+	if err != nil {
+		return err
+	}
+	p.Log.Infof("emitted a %s event", event.Name())
+
 	return nil
 }
 
@@ -79,18 +280,112 @@ func (p Pusher) Execute() error {
 		err             error
 	)
 
-	err = p.pushApplication(tempAppWithUUID, p.AppPath)
+	err = p.checkCanceled()
 	if err != nil {
 		return err
 	}
 
-	if p.DeploymentInfo.Domain != "" {
+	p.checkpoint(PushPhase)
+
+	err = runPhase(p.Tracer, p.DeploymentInfo.UUID, PushPhase, p.Environment.PushTimeoutSeconds, func() error {
+		return p.pushApplication(tempAppWithUUID, p.AppPath)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.checkCanceled()
+	if err != nil {
+		return err
+	}
+
+	if p.DeploymentInfo.Domain != "" && !p.manualCutover() && !p.trafficSplit() {
 		err = p.mapTempAppToLoadBalancedDomain(tempAppWithUUID)
 		if err != nil {
 			return err
 		}
 	}
 
+	if len(p.DeploymentInfo.Labels) > 0 {
+		err = p.setLabels(tempAppWithUUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(p.DeploymentInfo.Features) > 0 {
+		err = p.setFeatures(tempAppWithUUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	p.checkpoint(HealthCheckPhase)
+
+	err = runPhase(p.Tracer, p.DeploymentInfo.UUID, HealthCheckPhase, p.Environment.HealthCheckTimeoutSeconds, func() error {
+		return p.HealthChecks.Run(p.FoundationURL, p.DeploymentInfo.AppName, func(abort <-chan struct{}) error {
+			return p.emitPushFinished(tempAppWithUUID, abort)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.checkCanceled()
+	if err != nil {
+		return err
+	}
+
+	if p.Environment.WaitForInstances {
+		err = p.waitForInstances(tempAppWithUUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.DeploymentInfo.PostDeployTask != "" {
+		err = p.runPostDeployTask(tempAppWithUUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if command := p.probeCommand(); command != "" {
+		err = p.runProbe(tempAppWithUUID, command)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.trafficSplit() {
+		err = p.splitTraffic(tempAppWithUUID)
+		if err != nil {
+			return err
+		}
+
+		if p.DeploymentInfo.Domain != "" {
+			err = p.mapTempAppToLoadBalancedDomain(tempAppWithUUID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !p.Cancellation.Done() {
+		p.Log.Errorf("the deploy of %s was canceled", p.DeploymentInfo.AppName)
+		return state.DeployCanceledError{p.DeploymentInfo.AppName}
+	}
+
+	if p.manualCutover() {
+		p.reportCandidate(tempAppWithUUID)
+	}
+
+	return nil
+}
+
+// emitPushFinished emits the push finished events. The new application is health checked by their handlers,
+// which stop retrying once abort is closed.
+func (p Pusher) emitPushFinished(tempAppWithUUID string, abort <-chan struct{}) error {
 	p.Log.Debugf("emitting a %s event", C.PushFinishedEvent)
 	pushData := S.PushEventData{
 		AppPath:         p.AppPath,
@@ -101,7 +396,7 @@ func (p Pusher) Execute() error {
 		Response:        p.Response,
 	}
 
-	err = p.EventManager.Emit(I.Event{Type: C.PushFinishedEvent, Data: pushData})
+	err := p.EventManager.Emit(I.Event{Type: C.PushFinishedEvent, Data: pushData})
 	if err != nil {
 		return err
 	}
@@ -118,6 +413,18 @@ func (p Pusher) Execute() error {
 		Courier:             p.Courier,
 		Manifest:            p.DeploymentInfo.Manifest,
 		HealthCheckEndpoint: p.DeploymentInfo.HealthCheckEndpoint,
+		HealthCheckMode:     p.Environment.HealthCheckMode,
+		SkipSSL:             p.DeploymentInfo.SkipSSL,
+		Log:                 p.Log,
+
+		HealthCheckStablePolls:   p.Environment.HealthCheckStablePolls,
+		HealthCheckStableSeconds: p.Environment.HealthCheckStableSeconds,
+		MinHealthyPercent:        p.Environment.MinHealthyPercent,
+
+		HealthCheckCompare:                 p.Environment.HealthCheckCompare,
+		HealthCheckLatencyTolerancePercent: p.Environment.HealthCheckLatencyTolerancePercent,
+
+		Abort: abort,
 	}
 	err = p.EventManager.EmitEvent(event)
 	if err != nil {
@@ -130,14 +437,39 @@ func (p Pusher) Execute() error {
 
 // FinishPush will delete the original application if it existed. It will always
 // rename the the newly pushed application to the appName.
+//
+// With a manual cutover both applications are left as they are until the deploy is promoted.
 func (p Pusher) Success() error {
+	if p.manualCutover() {
+		p.Log.Infof("leaving %s and %s for a manual cutover", p.DeploymentInfo.AppName, p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID)
+		return nil
+	}
+
+	p.checkpoint(SwapPhase)
+
+	// The swap is never cut short: a swap that is stopped halfway leaves neither application serving the routes.
+	started := time.Now()
+	err := runPhase(p.Tracer, p.DeploymentInfo.UUID, SwapPhase, 0, p.swap)
+	took := time.Since(started)
+	if budget := time.Duration(p.Environment.SwapTimeoutSeconds) * time.Second; budget > 0 && took > budget {
+		p.Log.Errorf("the %s phase of %s took %s, longer than its budget of %s", SwapPhase, p.DeploymentInfo.AppName, took, budget)
+	}
+	return err
+}
+
+// swap replaces the original application with the newly pushed application.
+func (p Pusher) swap() error {
 	if p.Courier.Exists(p.DeploymentInfo.AppName) {
 		err := p.unMapLoadBalancedRoute()
 		if err != nil {
 			return err
 		}
 
-		err = p.deleteApplication(p.DeploymentInfo.AppName)
+		if p.retireApplication() {
+			err = p.stopReplacedApplication()
+		} else {
+			err = p.deleteApplication(p.DeploymentInfo.AppName)
+		}
 		if err != nil {
 			return err
 		}
@@ -154,9 +486,27 @@ func (p Pusher) Success() error {
 // UndoPush is only called when a Push fails. If it is not the first deployment, UndoPush will
 // delete the temporary application that was pushed.
 // If is the first deployment, UndoPush will rename the failed push to have the appName.
+//
+// With a traffic split, the traffic of the production route is returned to the existing application first.
 func (p Pusher) Undo() error {
 
 	tempAppWithUUID := p.DeploymentInfo.AppName + TemporaryNameSuffix + p.DeploymentInfo.UUID
+	if p.Cancellation.Canceled() {
+		if !p.Courier.Exists(tempAppWithUUID) {
+			return nil
+		}
+
+		if p.trafficSplit() && p.Courier.Exists(p.DeploymentInfo.AppName) {
+			err := p.restoreTraffic(tempAppWithUUID)
+			if err != nil {
+				return err
+			}
+		}
+
+		p.Log.Errorf("deploy was canceled, deleting %s", tempAppWithUUID)
+		return p.deleteApplication(tempAppWithUUID)
+	}
+
 	if !p.Environment.EnableRollback {
 		p.Log.Errorf("Failed to deploy, deployment not rolled back due to EnableRollback=false")
 
@@ -166,6 +516,13 @@ func (p Pusher) Undo() error {
 		if p.Courier.Exists(p.DeploymentInfo.AppName) {
 			p.Log.Errorf("rolling back deploy of %s", tempAppWithUUID)
 
+			if p.trafficSplit() {
+				err := p.restoreTraffic(tempAppWithUUID)
+				if err != nil {
+					return err
+				}
+			}
+
 			err := p.deleteApplication(tempAppWithUUID)
 			if err != nil {
 				return err
@@ -183,11 +540,266 @@ func (p Pusher) Undo() error {
 	return nil
 }
 
-// CleanUp removes the temporary directory created by the Executor.
+// CleanUp removes the temporary directory created by the Executor, and the checkpoints of the deploy on the
+// foundation now that its pushes there are finished.
 func (p Pusher) Finally() error {
+	if p.Journal != nil {
+		p.Journal.Remove(p.DeploymentInfo.UUID, p.FoundationURL)
+	}
+
 	return p.Courier.CleanUp()
 }
 
+// checkpoint records that the push of the application to the foundation entered the phase in the Journal.
+func (p Pusher) checkpoint(phase string) {
+	if p.Journal == nil {
+		return
+	}
+
+	p.Journal.Record(Checkpoint{
+		UUID:          p.DeploymentInfo.UUID,
+		Environment:   p.DeploymentInfo.Environment,
+		Org:           p.DeploymentInfo.Org,
+		Space:         p.DeploymentInfo.Space,
+		AppName:       p.DeploymentInfo.AppName,
+		Domain:        p.DeploymentInfo.Domain,
+		SkipSSL:       p.DeploymentInfo.SkipSSL,
+		FoundationURL: p.FoundationURL,
+		Phase:         phase,
+		CandidateApp:  p.DeploymentInfo.AppName + TemporaryNameSuffix + p.DeploymentInfo.UUID,
+	})
+}
+
+func (p Pusher) setLabels(appName string) error {
+	p.Log.Debugf("setting labels on %s", appName)
+
+	output, err := p.Courier.SetLabels(appName, p.DeploymentInfo.Labels)
+	p.Response.Write(output)
+	if err != nil {
+		p.Log.Errorf("could not set labels on %s", appName)
+		return state.SetLabelsError{appName, output}
+	}
+
+	p.Log.Infof("set %d labels on %s", len(p.DeploymentInfo.Labels), appName)
+
+	return nil
+}
+
+// setFeatures enables or disables the app features of the push on the application, in name order.
+func (p Pusher) setFeatures(appName string) error {
+	for _, feature := range sortedFeatureNames(p.DeploymentInfo.Features) {
+		enabled := p.DeploymentInfo.Features[feature]
+		p.Log.Debugf("setting app feature %s to %t on %s", feature, enabled, appName)
+
+		output, err := p.Courier.SetFeature(appName, feature, enabled)
+		p.Response.Write(output)
+		if err != nil {
+			p.Log.Errorf("could not set app feature %s on %s", feature, appName)
+			return state.SetFeatureError{appName, feature, output}
+		}
+
+		p.Log.Infof("set app feature %s to %t on %s", feature, enabled, appName)
+	}
+
+	return nil
+}
+
+// waitForInstances waits for the quorum of instances of the application to be running.
+// The number of running instances at the time of the decision is written to the response.
+func (p Pusher) waitForInstances(appName string) error {
+	expected := int(p.DeploymentInfo.Instances)
+	required := (expected*p.Environment.InstanceQuorumPercent + 99) / 100
+	timeout := time.Duration(p.Environment.InstanceTimeoutSeconds) * time.Second
+
+	p.Log.Infof("waiting for %d of %d instances of %s to be running", required, expected, appName)
+
+	var running int
+	for waited := time.Duration(0); ; waited += InstancePollInterval {
+		states, err := p.Courier.InstanceStates(appName)
+		if err != nil {
+			p.Log.Errorf("could not get the instance states of %s", appName)
+			return state.InstanceStateError{appName, err}
+		}
+
+		running = runningInstances(states)
+
+		if running >= required {
+			fmt.Fprintf(p.Response, "%d of %d instances of %s are running\n", running, expected, appName)
+			p.Log.Infof("%d of %d instances of %s are running", running, expected, appName)
+			return nil
+		}
+
+		if waited >= timeout {
+			break
+		}
+
+		p.sleep(InstancePollInterval)
+	}
+
+	fmt.Fprintf(p.Response, "%d of %d instances of %s are running, %d required\n", running, expected, appName, required)
+	p.Log.Errorf("%d of %d instances of %s are running, %d required", running, expected, appName, required)
+	return state.InstancesNotRunningError{appName, running, expected, required, timeout}
+}
+
+// runPostDeployTask runs the post deploy task against the temporary application and waits up to the
+// PostDeployTaskTimeoutSeconds of the environment for it to finish. The task output is written to the response
+// as it becomes available.
+func (p Pusher) runPostDeployTask(appName string) error {
+	taskName := PostDeployTaskNamePrefix + p.DeploymentInfo.UUID
+	timeout := time.Duration(p.Environment.PostDeployTaskTimeoutSeconds) * time.Second
+
+	p.Log.Infof("running post deploy task %s on %s", taskName, appName)
+
+	output, err := p.Courier.RunTask(appName, p.DeploymentInfo.PostDeployTask, taskName)
+	p.Response.Write(output)
+	if err != nil {
+		p.Log.Errorf("could not run post deploy task on %s", appName)
+		return state.RunTaskError{appName, output}
+	}
+
+	var written int
+	for waited := time.Duration(0); waited < timeout; waited += PostDeployTaskPollInterval {
+		taskState, err := p.Courier.TaskState(appName, taskName)
+		p.writeTaskLogs(appName, taskName, &written)
+		if err != nil {
+			p.Log.Errorf("could not get state of post deploy task %s", taskName)
+			return state.TaskStateError{taskName, err}
+		}
+
+		switch taskState {
+		case "SUCCEEDED":
+			p.Log.Infof("post deploy task %s succeeded", taskName)
+			return nil
+		case "FAILED":
+			p.Log.Errorf("post deploy task %s failed", taskName)
+			return state.TaskFailedError{taskName, taskState}
+		}
+
+		p.sleep(PostDeployTaskPollInterval)
+	}
+
+	p.Log.Errorf("post deploy task %s timed out", taskName)
+	return state.TaskTimeoutError{taskName, timeout}
+}
+
+// checkCanceled returns a DeployCanceledError when the deploy was canceled.
+func (p Pusher) checkCanceled() error {
+	if !p.Cancellation.Canceled() {
+		return nil
+	}
+
+	p.Log.Errorf("the deploy of %s was canceled", p.DeploymentInfo.AppName)
+	return state.DeployCanceledError{p.DeploymentInfo.AppName}
+}
+
+// manualCutover reports whether the candidate is left next to the existing application until the deploy is promoted.
+// The candidate is pushed with its own hostname so it does not receive production traffic.
+func (p Pusher) manualCutover() bool {
+	return p.DeploymentInfo.ManualCutover || p.Environment.ManualCutover
+}
+
+// reportCandidate writes the URL the candidate application can be inspected at to the response.
+func (p Pusher) reportCandidate(appName string) {
+	routes, err := p.Courier.Routes(appName)
+	if err != nil || len(routes) == 0 {
+		p.Log.Errorf("could not get the route of candidate %s", appName)
+		fmt.Fprintf(p.Response, "candidate %s is waiting for promotion\n", appName)
+		return
+	}
+
+	p.Log.Infof("candidate %s is available at https://%s", appName, routes[0])
+	fmt.Fprintf(p.Response, "candidate %s is waiting for promotion at https://%s\n", appName, routes[0])
+}
+
+// probeCommand returns the probe command from the request, or from the environment if the request has none.
+func (p Pusher) probeCommand() string {
+	if p.DeploymentInfo.ProbeCommand != "" {
+		return p.DeploymentInfo.ProbeCommand
+	}
+	return p.Environment.ProbeCommand
+}
+
+// runProbe runs the probe command against the new application. The probe receives the
+// application URL and deployment UUID as environment variables and its output is written to the response.
+func (p Pusher) runProbe(appName, command string) error {
+	routes, err := p.Courier.Routes(appName)
+	if err != nil {
+		p.Log.Errorf("could not get routes for %s", appName)
+		return state.ProbeError{appName, err}
+	}
+
+	var appURL string
+	if len(routes) > 0 {
+		appURL = "https://" + routes[0]
+	}
+
+	variables := map[string]string{
+		"DEPLOYADACTYL_APP_NAME":       appName,
+		"DEPLOYADACTYL_APP_URL":        appURL,
+		"DEPLOYADACTYL_FOUNDATION_URL": p.FoundationURL,
+		"DEPLOYADACTYL_UUID":           p.DeploymentInfo.UUID,
+	}
+	timeout := time.Duration(p.Environment.ProbeTimeoutSeconds) * time.Second
+
+	p.Log.Infof("running probe for %s with a timeout of %s", appName, timeout)
+
+	err = p.Prober.Probe(command, variables, timeout, p.Response)
+	if err != nil {
+		p.Log.Errorf("probe failed for %s: %s", appName, err)
+		return state.ProbeError{appName, err}
+	}
+
+	p.Log.Infof("probe succeeded for %s", appName)
+
+	return nil
+}
+
+// writeTaskLogs writes the task's log lines that have not been written to the response yet.
+func (p Pusher) writeTaskLogs(appName, taskName string, written *int) {
+	logs, err := p.Courier.Logs(appName)
+	if err != nil {
+		p.Log.Errorf("could not get logs for post deploy task %s: %s", taskName, err)
+		return
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(logs), "\n") {
+		if strings.Contains(line, "[APP/TASK/"+taskName+"/") {
+			lines = append(lines, line)
+		}
+	}
+
+	for ; *written < len(lines); *written++ {
+		fmt.Fprintln(p.Response, lines[*written])
+	}
+}
+
+func (p Pusher) sleep(d time.Duration) {
+	if p.Sleep != nil {
+		p.Sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// mergePushFlags returns the push flags of the environment followed by the push flags of the request. A flag of the
+// request replaces the flag of the environment with the same name.
+func mergePushFlags(environment, request []string) []string {
+	requested := map[string]bool{}
+	for _, flag := range request {
+		requested[strings.Fields(flag)[0]] = true
+	}
+
+	var flags []string
+	for _, flag := range environment {
+		if !requested[strings.Fields(flag)[0]] {
+			flags = append(flags, flag)
+		}
+	}
+
+	return append(flags, request...)
+}
+
 func (p Pusher) pushApplication(appName, appPath string) error {
 	p.Log.Debugf("pushing app %s to %s", appName, p.DeploymentInfo.Domain)
 	p.Log.Debugf("tempdir for app %s: %s", appName, appPath)
@@ -202,9 +814,45 @@ func (p Pusher) pushApplication(appName, appPath string) error {
 	defer func() { p.Response.Write(cloudFoundryLogs) }()
 	defer func() { p.Response.Write(pushOutput) }()
 
-	pushOutput, err = p.Courier.Push(appName, appPath, p.DeploymentInfo.AppName, p.DeploymentInfo.Instances)
+	hostname := p.DeploymentInfo.AppName
+	if p.manualCutover() || p.trafficSplit() {
+		hostname = appName
+	}
+
+	if p.DeploymentInfo.Command != "" {
+		p.Log.Infof("overriding the start command of %s with %s", appName, p.DeploymentInfo.Command)
+	}
+	if len(p.DeploymentInfo.Buildpacks) > 0 {
+		p.Log.Infof("overriding the buildpacks of %s with %s", appName, strings.Join(p.DeploymentInfo.Buildpacks, ", "))
+	}
+
+	flags := mergePushFlags(p.Environment.PushFlags, p.DeploymentInfo.PushFlags)
+	if len(flags) > 0 {
+		p.Log.Infof("adding the push flags %s to the push of %s", strings.Join(flags, ", "), appName)
+	}
+
+	// Cloud Foundry reads the manifest.yml next to the zip and pushes the files in the zip.
+	if p.DeploymentInfo.ArtifactArchive != "" {
+		p.Log.Debugf("pushing the zip %s of app %s without extracting it", p.DeploymentInfo.ArtifactArchive, appName)
+		flags = append(flags, "-p "+p.DeploymentInfo.ArtifactArchive)
+	}
+
+	pushOutput, err = p.withReauth(func() ([]byte, error) {
+		if p.ManifestFile != "" {
+			return p.Courier.PushWithManifest(appName, appPath, hostname, p.ManifestFile, p.DeploymentInfo.Instances, p.DeploymentInfo.Command, p.DeploymentInfo.Buildpacks, flags)
+		}
+		return p.Courier.Push(appName, appPath, hostname, p.DeploymentInfo.Instances, p.DeploymentInfo.Command, p.DeploymentInfo.Buildpacks, flags)
+	})
 	p.Log.Infof("output from Cloud Foundry: \n%s", pushOutput)
+	if isAuthExpired(err) {
+		return err
+	}
 	if err != nil {
+		if commandErr, ok := err.(executor.CFCommandError); ok {
+			p.Log.Error(commandErr)
+			pushOutput = append(pushOutput, fmt.Sprintf("\n%s\n", commandErr)...)
+		}
+
 		defer func() { p.Log.Errorf("logs from %s: \n%s", appName, cloudFoundryLogs) }()
 
 		cloudFoundryLogs, cloudFoundryLogsErr = p.Courier.Logs(appName)
@@ -223,7 +871,17 @@ func (p Pusher) pushApplication(appName, appPath string) error {
 func (p Pusher) mapTempAppToLoadBalancedDomain(appName string) error {
 	p.Log.Debugf("mapping route for %s to %s", p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
 
-	out, err := p.Courier.MapRoute(appName, p.DeploymentInfo.Domain, p.DeploymentInfo.AppName)
+	mapRoute, err := p.resolveRouteConflict(appName)
+	if err != nil || !mapRoute {
+		return err
+	}
+
+	out, err := p.withReauth(func() ([]byte, error) {
+		return p.Courier.MapRoute(appName, p.DeploymentInfo.Domain, p.DeploymentInfo.AppName)
+	})
+	if isAuthExpired(err) {
+		return err
+	}
 	if err != nil {
 		p.Log.Errorf("could not map %s to %s", p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
 		return state.MapRouteError{out}
@@ -240,7 +898,12 @@ func (p Pusher) unMapLoadBalancedRoute() error {
 	if p.DeploymentInfo.Domain != "" {
 		p.Log.Debugf("unmapping route %s", p.DeploymentInfo.AppName)
 
-		out, err := p.Courier.UnmapRoute(p.DeploymentInfo.AppName, p.DeploymentInfo.Domain, p.DeploymentInfo.AppName)
+		out, err := p.withReauth(func() ([]byte, error) {
+			return p.Courier.UnmapRoute(p.DeploymentInfo.AppName, p.DeploymentInfo.Domain, p.DeploymentInfo.AppName)
+		})
+		if isAuthExpired(err) {
+			return err
+		}
 		if err != nil {
 			p.Log.Errorf("could not unmap %s", p.DeploymentInfo.AppName)
 			return state.UnmapRouteError{p.DeploymentInfo.AppName, out}
@@ -255,7 +918,12 @@ func (p Pusher) unMapLoadBalancedRoute() error {
 func (p Pusher) deleteApplication(appName string) error {
 	p.Log.Debugf("deleting %s", appName)
 
-	out, err := p.Courier.Delete(appName)
+	out, err := p.withReauth(func() ([]byte, error) {
+		return p.Courier.Delete(appName)
+	})
+	if isAuthExpired(err) {
+		return err
+	}
 	if err != nil {
 		p.Log.Errorf("could not delete %s", appName)
 		p.Log.Errorf("deletion error %s", err.Error())
@@ -268,10 +936,67 @@ func (p Pusher) deleteApplication(appName string) error {
 	return nil
 }
 
+func (p Pusher) retireApplication() bool {
+	return p.Retirements != nil && p.Environment.DeleteDelaySeconds > 0
+}
+
+// stopReplacedApplication renames the replaced application out of the way and stops it instead of deleting it,
+// and leaves it to Retirements to delete once the delete delay is over.
+func (p Pusher) stopReplacedApplication() error {
+	venerable := p.DeploymentInfo.AppName + VenerableNameSuffix + p.DeploymentInfo.UUID
+	p.Log.Debugf("renaming %s to %s", p.DeploymentInfo.AppName, venerable)
+
+	out, err := p.withReauth(func() ([]byte, error) {
+		return p.Courier.Rename(p.DeploymentInfo.AppName, venerable)
+	})
+	if isAuthExpired(err) {
+		return err
+	}
+	if err != nil {
+		p.Log.Errorf("could not rename %s to %s", p.DeploymentInfo.AppName, venerable)
+		return state.RenameError{p.DeploymentInfo.AppName, out}
+	}
+
+	out, err = p.withReauth(func() ([]byte, error) {
+		return p.Courier.Stop(venerable)
+	})
+	if isAuthExpired(err) {
+		return err
+	}
+	if err != nil {
+		p.Log.Errorf("could not stop %s", venerable)
+		return state.StopError{venerable, out}
+	}
+
+	delay := time.Duration(p.Environment.DeleteDelaySeconds) * time.Second
+	p.Retirements.Add(Retirement{
+		UUID:        p.DeploymentInfo.UUID,
+		Environment: p.DeploymentInfo.Environment,
+		Org:         p.DeploymentInfo.Org,
+		Space:       p.DeploymentInfo.Space,
+		AppName:     p.DeploymentInfo.AppName,
+		Username:    p.DeploymentInfo.Username,
+		Password:    p.DeploymentInfo.Password,
+		SkipSSL:     p.DeploymentInfo.SkipSSL,
+		Delay:       delay,
+		RetiredAt:   time.Now(),
+	}, p.FoundationURL)
+
+	p.Log.Infof("stopped %s, it will be deleted in %s", venerable, delay)
+	fmt.Fprintf(p.Response, "stopped %s, it will be deleted in %s\n", venerable, delay)
+
+	return nil
+}
+
 func (p Pusher) renameNewBuildToOriginalAppName() error {
 	p.Log.Debugf("renaming %s to %s", p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
 
-	out, err := p.Courier.Rename(p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
+	out, err := p.withReauth(func() ([]byte, error) {
+		return p.Courier.Rename(p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
+	})
+	if isAuthExpired(err) {
+		return err
+	}
 	if err != nil {
 		p.Log.Errorf("could not rename %s to %s", p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
 		return state.RenameError{p.DeploymentInfo.AppName + TemporaryNameSuffix + p.DeploymentInfo.UUID, out}
@@ -4,13 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"os/exec"
+	"strings"
+	"time"
 
 	C "github.com/compozed/deployadactyl/constants"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
 	. "github.com/compozed/deployadactyl/state/push"
 	S "github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/tracing"
 	"github.com/op/go-logging"
+	"github.com/spf13/afero"
 
 	"encoding/base64"
 
@@ -28,6 +35,7 @@ var _ = Describe("Pusher", func() {
 		courier      *mocks.Courier
 		eventManager *mocks.EventManager
 		fetcher      *mocks.Fetcher
+		prober       *mocks.Prober
 
 		randomUsername      string
 		randomPassword      string
@@ -53,6 +61,7 @@ var _ = Describe("Pusher", func() {
 		courier = &mocks.Courier{}
 		eventManager = &mocks.EventManager{}
 		fetcher = &mocks.Fetcher{}
+		prober = &mocks.Prober{}
 
 		randomFoundationURL = "randomFoundationURL-" + randomizer.StringRunes(10)
 		randomUsername = "randomUsername-" + randomizer.StringRunes(10)
@@ -103,6 +112,7 @@ var _ = Describe("Pusher", func() {
 			Fetcher:        fetcher,
 			CFContext:      interfaces.CFContext{},
 			Auth:           interfaces.Authorization{},
+			Prober:         prober,
 		}
 	})
 
@@ -129,6 +139,210 @@ var _ = Describe("Pusher", func() {
 			})
 		})
 
+		Context("when the push has a space GUID", func() {
+			var space S.Space
+
+			BeforeEach(func() {
+				pusher.DeploymentInfo.SpaceGUID = "5a1b2c3d-0000-4000-8000-000000000001"
+				pusher.Environment.AutoCreateSpace = true
+
+				space = S.Space{
+					GUID:    pusher.DeploymentInfo.SpaceGUID,
+					Name:    randomSpace,
+					OrgGUID: "5a1b2c3d-0000-4000-8000-000000000002",
+					OrgName: randomOrg,
+				}
+				courier.SpaceByGUIDCall.Returns.Space = space
+			})
+
+			It("targets the space with the GUID instead of logging in by name or creating it", func() {
+				Expect(pusher.Initially()).To(Succeed())
+
+				Expect(courier.AuthenticateCall.Received.FoundationURL).To(Equal(randomFoundationURL))
+				Expect(courier.SpaceByGUIDCall.Received.SpaceGUID).To(Equal(space.GUID))
+				Expect(courier.CreateSpaceCall.TimesCalled).To(Equal(0))
+				Expect(courier.LoginCall.TimesCalled).To(Equal(0))
+				Expect(courier.TargetCall.Received.Org).To(Equal(randomOrg))
+				Expect(courier.TargetCall.Received.Space).To(Equal(randomSpace))
+			})
+
+			It("takes the org and space names from the space when they are not given", func() {
+				pusher.DeploymentInfo.Org = NameFromSpaceGUID
+				pusher.DeploymentInfo.Space = NameFromSpaceGUID
+
+				Expect(pusher.Initially()).To(Succeed())
+
+				Expect(courier.TargetCall.Received.Org).To(Equal(randomOrg))
+				Expect(courier.TargetCall.Received.Space).To(Equal(randomSpace))
+				Expect(pusher.DeploymentInfo.Org).To(Equal(randomOrg))
+				Expect(pusher.DeploymentInfo.Space).To(Equal(randomSpace))
+			})
+
+			It("returns an error when the org of the space is not allowed in the environment", func() {
+				pusher.DeploymentInfo.Org = NameFromSpaceGUID
+				pusher.DeploymentInfo.Environment = "production"
+				pusher.Environment.AllowedOrgs = []string{"other-org"}
+
+				err := pusher.Initially()
+
+				Expect(err).To(MatchError(deployer.OrgNotAllowedError{randomOrg, "production"}))
+				Expect(courier.TargetCall.TimesCalled).To(Equal(0))
+			})
+
+			It("returns an error when the space cannot be targeted", func() {
+				courier.TargetCall.Returns.Output = []byte("target failed")
+				courier.TargetCall.Returns.Error = errors.New("exit status 1")
+
+				err := pusher.Initially()
+
+				Expect(err).To(MatchError(state.TargetError{randomOrg, randomSpace, []byte("target failed")}))
+			})
+
+			It("accepts a matching org GUID", func() {
+				pusher.DeploymentInfo.OrgGUID = space.OrgGUID
+
+				Expect(pusher.Initially()).To(Succeed())
+			})
+
+			It("returns an error when the space has another name", func() {
+				courier.SpaceByGUIDCall.Returns.Space.Name = "other-space"
+
+				err := pusher.Initially()
+
+				Expect(err).To(BeAssignableToTypeOf(state.SpaceGUIDConflictError{}))
+				Expect(courier.TargetCall.TimesCalled).To(Equal(0))
+			})
+
+			It("returns an error when the org GUID does not match", func() {
+				pusher.DeploymentInfo.OrgGUID = "5a1b2c3d-0000-4000-8000-000000000003"
+
+				err := pusher.Initially()
+
+				Expect(err).To(MatchError(state.SpaceGUIDConflictError{space.GUID, randomOrg, randomSpace, space}))
+			})
+
+			It("returns an error when the space cannot be found", func() {
+				courier.SpaceByGUIDCall.Returns.Error = errors.New("not found")
+
+				err := pusher.Initially()
+
+				Expect(err).To(MatchError(state.SpaceGUIDError{space.GUID, errors.New("not found")}))
+				Expect(courier.TargetCall.TimesCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the environment auto-creates spaces", func() {
+			BeforeEach(func() {
+				pusher.Environment.AutoCreateSpace = true
+			})
+
+			It("does not create a space that already exists", func() {
+				courier.SpaceExistsCall.Returns.Bool = true
+
+				Expect(pusher.Initially()).To(Succeed())
+
+				Expect(courier.AuthenticateCall.Received.FoundationURL).To(Equal(randomFoundationURL))
+				Expect(courier.AuthenticateCall.Received.Username).To(Equal(randomUsername))
+				Expect(courier.AuthenticateCall.Received.Password).To(Equal(randomPassword))
+				Expect(courier.SpaceExistsCall.Received.Org).To(Equal(randomOrg))
+				Expect(courier.SpaceExistsCall.Received.Space).To(Equal(randomSpace))
+				Expect(courier.CreateSpaceCall.TimesCalled).To(Equal(0))
+				Expect(courier.LoginCall.Received.Space).To(Equal(randomSpace))
+				Expect(eventManager.EmitEventCall.TimesCalled).To(Equal(0))
+			})
+
+			It("creates a missing space before logging in", func() {
+				courier.CreateSpaceCall.Returns.Output = []byte("create space output")
+
+				Expect(pusher.Initially()).To(Succeed())
+
+				Expect(courier.CreateSpaceCall.Received.Org).To(Equal(randomOrg))
+				Expect(courier.CreateSpaceCall.Received.Space).To(Equal(randomSpace))
+				Expect(courier.CreateOrgCall.TimesCalled).To(Equal(0))
+				Expect(courier.LoginCall.Received.Space).To(Equal(randomSpace))
+
+				Eventually(response).Should(Say("create space output"))
+				Eventually(logBuffer).Should(Say(fmt.Sprintf("created space %s in org %s on %s", randomSpace, randomOrg, randomFoundationURL)))
+			})
+
+			It("emits a SpaceCreatedEvent", func() {
+				pusher.DeploymentInfo.Data = map[string]interface{}{"key": "value"}
+
+				Expect(pusher.Initially()).To(Succeed())
+
+				Expect(eventManager.EmitEventCall.Received.Events).To(HaveLen(1))
+				event := eventManager.EmitEventCall.Received.Events[0].(SpaceCreatedEvent)
+				Expect(event.FoundationURL).To(Equal(randomFoundationURL))
+				Expect(event.Org).To(Equal(randomOrg))
+				Expect(event.Space).To(Equal(randomSpace))
+				Expect(event.OrgCreated).To(BeFalse())
+				Expect(event.Data).To(Equal(map[string]interface{}{"key": "value"}))
+			})
+
+			It("returns an error when the space cannot be created", func() {
+				courier.CreateSpaceCall.Returns.Output = []byte("create space output")
+				courier.CreateSpaceCall.Returns.Error = errors.New("create space error")
+
+				err := pusher.Initially()
+				Expect(err).To(MatchError(state.CreateSpaceError{randomOrg, randomSpace, []byte("create space output")}))
+
+				Expect(courier.LoginCall.Received.FoundationURL).To(BeEmpty())
+			})
+
+			It("returns a login error when authentication fails", func() {
+				courier.AuthenticateCall.Returns.Output = []byte("auth output")
+				courier.AuthenticateCall.Returns.Error = errors.New("auth error")
+
+				err := pusher.Initially()
+				Expect(err).To(MatchError(state.LoginError{randomFoundationURL, []byte("auth output")}))
+
+				Expect(courier.CreateSpaceCall.TimesCalled).To(Equal(0))
+			})
+
+			It("does not create a missing org unless enabled", func() {
+				Expect(pusher.Initially()).To(Succeed())
+
+				Expect(courier.CreateOrgCall.TimesCalled).To(Equal(0))
+			})
+
+			Context("and orgs", func() {
+				BeforeEach(func() {
+					pusher.Environment.AutoCreateOrg = true
+				})
+
+				It("creates a missing org and its space", func() {
+					Expect(pusher.Initially()).To(Succeed())
+
+					Expect(courier.OrgExistsCall.Received.Org).To(Equal(randomOrg))
+					Expect(courier.CreateOrgCall.Received.Org).To(Equal(randomOrg))
+					Expect(courier.CreateSpaceCall.Received.Space).To(Equal(randomSpace))
+
+					event := eventManager.EmitEventCall.Received.Events[0].(SpaceCreatedEvent)
+					Expect(event.OrgCreated).To(BeTrue())
+				})
+
+				It("does not create an org that already exists", func() {
+					courier.OrgExistsCall.Returns.Bool = true
+					courier.SpaceExistsCall.Returns.Bool = true
+
+					Expect(pusher.Initially()).To(Succeed())
+
+					Expect(courier.CreateOrgCall.TimesCalled).To(Equal(0))
+					Expect(courier.CreateSpaceCall.TimesCalled).To(Equal(0))
+				})
+
+				It("returns an error when the org cannot be created", func() {
+					courier.CreateOrgCall.Returns.Output = []byte("create org output")
+					courier.CreateOrgCall.Returns.Error = errors.New("create org error")
+
+					err := pusher.Initially()
+					Expect(err).To(MatchError(state.CreateOrgError{randomOrg, []byte("create org output")}))
+
+					Expect(courier.CreateSpaceCall.TimesCalled).To(Equal(0))
+				})
+			})
+		})
+
 		Context("when login fails", func() {
 			It("returns an error", func() {
 				courier.LoginCall.Returns.Output = []byte("login output")
@@ -157,6 +371,46 @@ var _ = Describe("Pusher", func() {
 				Eventually(logBuffer).Should(Say(fmt.Sprintf("could not login to %s", randomFoundationURL)))
 			})
 		})
+
+		Context("when the push has an isolation segment", func() {
+			BeforeEach(func() {
+				pusher.DeploymentInfo.IsolationSegment = "secure-segment"
+			})
+
+			It("sets the isolation segment of the space after logging in", func() {
+				courier.SetSpaceIsolationSegmentCall.Returns.Output = []byte("segment set")
+
+				Expect(pusher.Initially()).To(Succeed())
+
+				Expect(courier.SetSpaceIsolationSegmentCall.Received.Space).To(Equal(randomSpace))
+				Expect(courier.SetSpaceIsolationSegmentCall.Received.Segment).To(Equal("secure-segment"))
+				Eventually(response).Should(Say("segment set"))
+				Eventually(logBuffer).Should(Say("setting the isolation segment of space " + randomSpace + " to secure-segment"))
+			})
+
+			It("returns an error when the isolation segment cannot be set", func() {
+				courier.SetSpaceIsolationSegmentCall.Returns.Output = []byte("segment output")
+				courier.SetSpaceIsolationSegmentCall.Returns.Error = errors.New("segment error")
+
+				err := pusher.Initially()
+
+				Expect(err).To(MatchError(state.SetIsolationSegmentError{randomSpace, "secure-segment", []byte("segment output")}))
+			})
+
+			It("does not set it when login fails", func() {
+				courier.LoginCall.Returns.Error = errors.New("login error")
+
+				Expect(pusher.Initially()).ToNot(Succeed())
+
+				Expect(courier.SetSpaceIsolationSegmentCall.TimesCalled).To(Equal(0))
+			})
+		})
+
+		It("does not set an isolation segment when the push has none", func() {
+			Expect(pusher.Initially()).To(Succeed())
+
+			Expect(courier.SetSpaceIsolationSegmentCall.TimesCalled).To(Equal(0))
+		})
 	})
 
 	Describe("Execute", func() {
@@ -172,158 +426,721 @@ var _ = Describe("Pusher", func() {
 					Expect(courier.PushCall.Received.Hostname).To(Equal(randomAppName))
 					Expect(courier.PushCall.Received.Instances).To(Equal(randomInstances))
 
-					Eventually(response).Should(Say("push succeeded"))
+					Eventually(response).Should(Say("push succeeded"))
+
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("pushing app %s to %s", tempAppWithUUID, randomDomain)))
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("tempdir for app %s: %s", tempAppWithUUID, randomAppPath)))
+					Eventually(logBuffer).Should(Say("output from Cloud Foundry"))
+					Eventually(logBuffer).Should(Say("successfully deployed new build"))
+				})
+			})
+
+			Context("when the push fails", func() {
+				It("returns an error", func() {
+					fetcher.FetchCall.Returns.AppPath = randomAppPath
+					courier.PushCall.Returns.Error = errors.New("push error")
+
+					err := pusher.Execute()
+
+					Expect(err).To(MatchError(state.PushError{}))
+				})
+
+				It("gets logs from the courier", func() {
+					fetcher.FetchCall.Returns.AppPath = randomAppPath
+					courier.PushCall.Returns.Output = []byte("push output")
+					courier.PushCall.Returns.Error = errors.New("push error")
+					courier.LogsCall.Returns.Output = []byte("cf logs")
+
+					Expect(pusher.Execute()).ToNot(Succeed())
+
+					Eventually(response).Should(Say("push output"))
+					Eventually(response).Should(Say("cf logs"))
+
+					Eventually(logBuffer).Should(Say("logs from"))
+				})
+
+				It("writes the exit code and standard error of the cf command after the push output", func() {
+					fetcher.FetchCall.Returns.AppPath = randomAppPath
+					courier.PushCall.Returns.Output = []byte("push output")
+					courier.PushCall.Returns.Error = executor.CFCommandError{
+						Command:  "push " + tempAppWithUUID,
+						ExitCode: 1,
+						Stderr:   "insufficient resources",
+						Err:      &exec.ExitError{},
+					}
+
+					Expect(pusher.Execute()).To(MatchError(state.PushError{}))
+
+					Eventually(response).Should(Say("push output"))
+					Eventually(response).Should(Say("cf push " + tempAppWithUUID + " failed with exit code 1: .*: insufficient resources"))
+					Eventually(logBuffer).Should(Say("failed with exit code 1"))
+				})
+
+				Context("when the courier log call fails", func() {
+					It("returns an error", func() {
+						fetcher.FetchCall.Returns.AppPath = randomAppPath
+						pushErr := errors.New("push error")
+						logsErr := errors.New("logs error")
+
+						courier.PushCall.Returns.Error = pushErr
+						courier.LogsCall.Returns.Error = logsErr
+
+						err := pusher.Execute()
+
+						Expect(err).To(MatchError(state.CloudFoundryGetLogsError{pushErr, logsErr}))
+					})
+				})
+			})
+		})
+
+		Context("with Zip request body", func() {
+			Context("when the push succeeds", func() {
+				It("pushes the new app", func() {
+					pusher.DeploymentInfo.ContentType = "ZIP"
+					courier.PushCall.Returns.Output = []byte("push succeeded")
+					fetcher.FetchFromZipCall.Returns.AppPath = randomAppPath
+
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.PushCall.Received.AppName).To(Equal(tempAppWithUUID))
+					Expect(courier.PushCall.Received.AppPath).To(Equal(randomAppPath))
+					Expect(courier.PushCall.Received.Hostname).To(Equal(randomAppName))
+					Expect(courier.PushCall.Received.Instances).To(Equal(randomInstances))
+
+					Eventually(response).Should(Say("push succeeded"))
+
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("pushing app %s to %s", tempAppWithUUID, randomDomain)))
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("tempdir for app %s: %s", tempAppWithUUID, randomAppPath)))
+					Eventually(logBuffer).Should(Say("output from Cloud Foundry"))
+					Eventually(logBuffer).Should(Say("successfully deployed new build"))
+				})
+			})
+		})
+
+		Context("with other besides zip and json request body type", func() {
+			Context("when the push succeeds", func() {
+				It("pushes the new app", func() {
+					pusher.DeploymentInfo.ContentType = "ZIP"
+					courier.PushCall.Returns.Output = []byte("push succeeded")
+					fetcher.FetchFromZipCall.Returns.AppPath = randomAppPath
+
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.PushCall.Received.AppName).To(Equal(tempAppWithUUID))
+					Expect(courier.PushCall.Received.AppPath).To(Equal(randomAppPath))
+					Expect(courier.PushCall.Received.Hostname).To(Equal(randomAppName))
+					Expect(courier.PushCall.Received.Instances).To(Equal(randomInstances))
+
+					Eventually(response).Should(Say("push succeeded"))
+
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("pushing app %s to %s", tempAppWithUUID, randomDomain)))
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("tempdir for app %s: %s", tempAppWithUUID, randomAppPath)))
+					Eventually(logBuffer).Should(Say("output from Cloud Foundry"))
+					Eventually(logBuffer).Should(Say("successfully deployed new build"))
+				})
+			})
+		})
+
+		Describe("logging in again when the token expires", func() {
+			It("logs in again and retries the push after a 401", func() {
+				courier.PushCall.Returns.Outputs = [][]byte{[]byte("Authentication has expired.  Please log back in to re-authenticate.")}
+				courier.PushCall.Returns.Errors = []error{errors.New("exit status 1")}
+				courier.PushCall.Returns.Output = []byte("push succeeded")
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushCall.TimesCalled).To(Equal(2))
+				Expect(courier.LoginCall.TimesCalled).To(Equal(1))
+				Expect(courier.LoginCall.Received.Username).To(Equal(pusher.DeploymentInfo.Username))
+				Expect(courier.LoginCall.Received.FoundationURL).To(Equal(pusher.FoundationURL))
+				Eventually(logBuffer).Should(Say("token for .* expired, logging in again"))
+			})
+
+			It("returns an AuthExpiredError when logging in again fails", func() {
+				courier.PushCall.Returns.Outputs = [][]byte{[]byte("Server error, status code: 401, error code: 1000, message: Invalid Auth Token")}
+				courier.PushCall.Returns.Errors = []error{errors.New("exit status 1")}
+				courier.LoginCall.Returns.Output = []byte("login failed")
+				courier.LoginCall.Returns.Error = errors.New("exit status 1")
+
+				err := pusher.Execute()
+
+				Expect(err).To(MatchError(state.AuthExpiredError{pusher.FoundationURL, []byte("login failed")}))
+				Expect(courier.PushCall.TimesCalled).To(Equal(1))
+			})
+
+			It("does not log in again when the push fails for another reason", func() {
+				courier.PushCall.Returns.Output = []byte("staging failed")
+				courier.PushCall.Returns.Error = errors.New("exit status 1")
+
+				Expect(pusher.Execute()).To(MatchError(state.PushError{}))
+
+				Expect(courier.LoginCall.TimesCalled).To(Equal(0))
+			})
+		})
+
+		Describe("limiting the time of the push phase", func() {
+			It("fails with a push phase timeout when the push takes too long", func() {
+				pusher.Environment.PushTimeoutSeconds = 1
+				courier.PushCall.Delay = 2 * time.Second
+
+				Expect(pusher.Execute()).To(MatchError(state.PhaseTimeoutError{PushPhase, time.Second}))
+			})
+
+			It("waits for the push to finish before it fails", func() {
+				pusher.Environment.PushTimeoutSeconds = 1
+				courier.PushCall.Delay = 2 * time.Second
+
+				Expect(pusher.Execute()).ToNot(Succeed())
+
+				Expect(courier.PushCall.TimesCalled).To(Equal(1))
+			})
+
+			It("succeeds when the push finishes in time", func() {
+				pusher.Environment.PushTimeoutSeconds = 1
+
+				Expect(pusher.Execute()).To(Succeed())
+			})
+		})
+
+		Describe("tracing the phases", func() {
+			var recorder *tracing.Recorder
+
+			BeforeEach(func() {
+				recorder = tracing.NewRecorder()
+				pusher.Tracer = tracing.NewTracer()
+				pusher.Tracer.Use(recorder)
+			})
+
+			It("records the push and health check under the deploy span", func() {
+				deploySpan := pusher.Tracer.StartDeploy(pusher.DeploymentInfo.UUID, interfaces.CFContext{Application: randomAppName})
+
+				Expect(pusher.Execute()).To(Succeed())
+				deploySpan.End(nil)
+
+				spans := recorder.Ended()
+				Expect(spans).To(HaveLen(3))
+				Expect(spans[0].Name).To(Equal(PushPhase))
+				Expect(spans[1].Name).To(Equal(HealthCheckPhase))
+				Expect(spans[0].ParentSpanID).To(Equal(spans[2].SpanID))
+			})
+
+			It("marks the phase failed when it fails", func() {
+				pusher.Tracer.StartDeploy(pusher.DeploymentInfo.UUID, interfaces.CFContext{})
+				courier.PushCall.Returns.Error = errors.New("push error")
+
+				Expect(pusher.Execute()).ToNot(Succeed())
+
+				Expect(recorder.Ended()).To(HaveLen(1))
+				Expect(recorder.Ended()[0].Status.Code).To(Equal(tracing.StatusError))
+			})
+		})
+
+		Describe("overriding the start command", func() {
+			It("pushes with the start command from the request", func() {
+				pusher.DeploymentInfo.Command = "bin/start --worker"
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushCall.Received.Command).To(Equal("bin/start --worker"))
+				Eventually(logBuffer).Should(Say("overriding the start command of %s with bin/start --worker", tempAppWithUUID))
+			})
+
+			It("keeps the default start command when none is provided", func() {
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushCall.Received.Command).To(BeEmpty())
+			})
+		})
+
+		Describe("overriding the buildpacks", func() {
+			It("pushes with the buildpacks from the request", func() {
+				pusher.DeploymentInfo.Buildpacks = []string{"nodejs_buildpack", "java_buildpack"}
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushCall.Received.Buildpacks).To(Equal([]string{"nodejs_buildpack", "java_buildpack"}))
+				Eventually(logBuffer).Should(Say("overriding the buildpacks of %s with nodejs_buildpack, java_buildpack", tempAppWithUUID))
+			})
+
+			It("keeps the buildpacks of the manifest when none are provided", func() {
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushCall.Received.Buildpacks).To(BeEmpty())
+			})
+		})
+
+		Describe("adding push flags", func() {
+			It("pushes with the push flags of the environment and the request", func() {
+				pusher.Environment.PushFlags = []string{"-m 512M", "-t 180"}
+				pusher.DeploymentInfo.PushFlags = []string{"-m 1G"}
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushCall.Received.Flags).To(Equal([]string{"-t 180", "-m 1G"}))
+				Eventually(logBuffer).Should(Say("adding the push flags -t 180, -m 1G to the push of %s", tempAppWithUUID))
+			})
+
+			It("pushes without push flags when none are set", func() {
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushCall.Received.Flags).To(BeEmpty())
+			})
+		})
+
+		Describe("pushing a zip that was not extracted", func() {
+			It("pushes the zip in the application directory", func() {
+				pusher.Environment.PushFlags = []string{"-t 180"}
+				pusher.DeploymentInfo.ArtifactArchive = "artifact.zip"
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushCall.Received.AppPath).To(Equal(pusher.AppPath))
+				Expect(courier.PushCall.Received.Flags).To(Equal([]string{"-t 180", "-p artifact.zip"}))
+			})
+		})
+
+		Describe("mapping the load balanced route to the temporary application", func() {
+			Context("when a domain is provided", func() {
+				It("maps the route to the app", func() {
+					fetcher.FetchCall.Returns.AppPath = randomAppPath
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.MapRouteCall.Received.AppName[0]).To(Equal(randomAppName + TemporaryNameSuffix + randomUUID))
+					Expect(courier.MapRouteCall.Received.Domain[0]).To(Equal(randomDomain))
+
+					Eventually(response).Should(Say(fmt.Sprintf("application route created: %s.%s", randomAppName, randomDomain)))
+
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("mapping route for %s to %s", randomAppName, randomDomain)))
+				})
+			})
+
+			Context("when a randomDomain is not provided", func() {
+				It("does not map the randomDomain", func() {
+					courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("mapped route"))
+					deploymentInfo.Domain = ""
+					fetcher.FetchCall.Returns.AppPath = randomAppPath
+
+					pusher = Pusher{
+						Courier:        courier,
+						DeploymentInfo: deploymentInfo,
+						EventManager:   eventManager,
+						Response:       response,
+						Log:            interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "pusher_test")},
+						Fetcher:        fetcher,
+					}
+
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+					Expect(courier.MapRouteCall.Received.Domain).To(BeEmpty())
+
+					Eventually(response).ShouldNot(Say("mapped route"))
+
+					Eventually(logBuffer).ShouldNot(Say(fmt.Sprintf("mapping route for %s to", randomAppName)))
+				})
+			})
+
+			Context("when MapRoute fails", func() {
+				It("returns an error", func() {
+					fetcher.FetchCall.Returns.AppPath = randomAppPath
+					courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("unable to map route"))
+					courier.MapRouteCall.Returns.Error = append(courier.MapRouteCall.Returns.Error, errors.New("map route error"))
+
+					err := pusher.Execute()
+					Expect(err).To(MatchError(state.MapRouteError{[]byte("unable to map route")}))
+
+					Expect(courier.MapRouteCall.Received.AppName[0]).To(Equal(randomAppName + TemporaryNameSuffix + randomUUID))
+					Expect(courier.MapRouteCall.Received.Domain[0]).To(Equal(randomDomain))
+
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("mapping route for %s to %s", randomAppName, randomDomain)))
+				})
+			})
+
+			Context("when the route is mapped to another application", func() {
+				var route string
+
+				BeforeEach(func() {
+					route = randomAppName + "." + randomDomain
+					fetcher.FetchCall.Returns.AppPath = randomAppPath
+					courier.RouteAppsCall.Returns.Apps = []string{randomAppName, randomAppName + TemporaryNameSuffix + "older-uuid", "other-app"}
+				})
+
+				It("fails by default", func() {
+					err := pusher.Execute()
+
+					Expect(err).To(MatchError(state.RouteConflictError{route, []string{"other-app"}}))
+					Expect(courier.RouteAppsCall.Received.Domain).To(Equal(randomDomain))
+					Expect(courier.RouteAppsCall.Received.Hostname).To(Equal(randomAppName))
+					Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+					Eventually(response).Should(Say(fmt.Sprintf("route %s is mapped to other-app", route)))
+				})
+
+				It("unmaps the route from the other application when the policy is steal", func() {
+					pusher.Environment.RouteConflictPolicy = S.RouteConflictSteal
+
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.UnmapRouteCall.Received.AppName).To(Equal("other-app"))
+					Expect(courier.UnmapRouteCall.Received.Domain).To(Equal(randomDomain))
+					Expect(courier.UnmapRouteCall.Received.Hostname).To(Equal(randomAppName))
+					Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{randomAppName + TemporaryNameSuffix + randomUUID}))
+					Eventually(response).Should(Say(fmt.Sprintf("unmapped route %s from other-app", route)))
+				})
+
+				It("leaves the route and warns when the policy is skip", func() {
+					pusher.Environment.RouteConflictPolicy = S.RouteConflictSkip
+
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+					Eventually(response).Should(Say(fmt.Sprintf("WARNING: route %s is mapped to other-app", route)))
+					Expect(eventManager.EmitCall.Received.Events).To(ContainElement(WithTransform(func(e interfaces.Event) string { return e.Type }, Equal(C.DeployWarningEvent))))
+				})
+
+				It("returns an error when the applications of the route cannot be found", func() {
+					courier.RouteAppsCall.Returns.Error = errors.New("curl failed")
+
+					err := pusher.Execute()
+
+					Expect(err).To(MatchError(state.RouteAppsError{route, errors.New("curl failed")}))
+				})
+			})
+		})
+
+		Describe("applying labels to the temporary application", func() {
+			Context("when labels are provided", func() {
+				BeforeEach(func() {
+					pusher.DeploymentInfo.Labels = map[string]string{"git-sha": "abc123", "build": "42"}
+				})
+
+				It("sets the labels on the app", func() {
+					courier.SetLabelsCall.Returns.Output = []byte("set labels output")
+
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.SetLabelsCall.Received.AppName).To(Equal(tempAppWithUUID))
+					Expect(courier.SetLabelsCall.Received.Labels).To(Equal(map[string]string{"git-sha": "abc123", "build": "42"}))
+
+					Eventually(response).Should(Say("set labels output"))
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("set 2 labels on %s", tempAppWithUUID)))
+				})
+
+				Context("when SetLabels fails", func() {
+					It("returns an error", func() {
+						courier.SetLabelsCall.Returns.Output = []byte("unable to set labels")
+						courier.SetLabelsCall.Returns.Error = errors.New("set labels error")
+
+						err := pusher.Execute()
+						Expect(err).To(MatchError(state.SetLabelsError{tempAppWithUUID, []byte("unable to set labels")}))
+
+						Expect(eventManager.EmitCall.Received.Events).To(BeEmpty())
+					})
+				})
+			})
+
+			Context("when labels are not provided", func() {
+				It("does not set labels", func() {
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.SetLabelsCall.TimesCalled).To(Equal(0))
+				})
+			})
+		})
+
+		Describe("setting app features on the temporary application", func() {
+			It("sets each feature in name order", func() {
+				pusher.DeploymentInfo.Features = map[string]bool{"ssh": false, "revisions": true}
+				courier.SetFeatureCall.Returns.Output = []byte("set feature output")
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.SetFeatureCall.Received.AppName).To(Equal([]string{tempAppWithUUID, tempAppWithUUID}))
+				Expect(courier.SetFeatureCall.Received.Features).To(Equal([]string{"revisions", "ssh"}))
+				Expect(courier.SetFeatureCall.Received.Enabled).To(Equal([]bool{true, false}))
+
+				Eventually(response).Should(Say("set feature output"))
+				Eventually(logBuffer).Should(Say(fmt.Sprintf("set app feature revisions to true on %s", tempAppWithUUID)))
+				Eventually(logBuffer).Should(Say(fmt.Sprintf("set app feature ssh to false on %s", tempAppWithUUID)))
+			})
+
+			It("returns an error when a feature cannot be set", func() {
+				pusher.DeploymentInfo.Features = map[string]bool{"ssh": true}
+				courier.SetFeatureCall.Returns.Output = []byte("unable to enable ssh")
+				courier.SetFeatureCall.Returns.Error = errors.New("enable-ssh error")
+
+				err := pusher.Execute()
+				Expect(err).To(MatchError(state.SetFeatureError{tempAppWithUUID, "ssh", []byte("unable to enable ssh")}))
+
+				Expect(eventManager.EmitCall.Received.Events).To(BeEmpty())
+			})
+
+			It("does not set features when none are provided", func() {
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.SetFeatureCall.TimesCalled).To(Equal(0))
+			})
+		})
+
+		Describe("running the post deploy task", func() {
+			var (
+				taskName string
+				sleeps   []time.Duration
+			)
+
+			BeforeEach(func() {
+				taskName = PostDeployTaskNamePrefix + randomUUID
+				sleeps = nil
+
+				pusher.DeploymentInfo.PostDeployTask = "rake db:migrate"
+				pusher.Environment.PostDeployTaskTimeoutSeconds = 60
+				pusher.Sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+			})
+
+			It("runs the task against the temporary application", func() {
+				courier.RunTaskCall.Returns.Output = []byte("run task output")
+				courier.TaskStateCall.Returns.States = []string{"SUCCEEDED"}
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.RunTaskCall.Received.AppName).To(Equal(tempAppWithUUID))
+				Expect(courier.RunTaskCall.Received.Command).To(Equal("rake db:migrate"))
+				Expect(courier.RunTaskCall.Received.TaskName).To(Equal(taskName))
+
+				Eventually(response).Should(Say("run task output"))
+				Eventually(logBuffer).Should(Say(fmt.Sprintf("post deploy task %s succeeded", taskName)))
+			})
+
+			It("runs the task after the push finished events", func() {
+				eventManager.EmitEventCall.Returns.Error = []error{errors.New("event manager error")}
+
+				Expect(pusher.Execute()).ToNot(Succeed())
+
+				Expect(courier.RunTaskCall.Received.AppName).To(BeEmpty())
+			})
+
+			It("waits for the task to finish", func() {
+				courier.TaskStateCall.Returns.States = []string{"PENDING", "RUNNING", "SUCCEEDED"}
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.TaskStateCall.TimesCalled).To(Equal(3))
+				Expect(sleeps).To(Equal([]time.Duration{PostDeployTaskPollInterval, PostDeployTaskPollInterval}))
+			})
+
+			It("writes the task output to the response once", func() {
+				courier.TaskStateCall.Returns.States = []string{"RUNNING", "SUCCEEDED"}
+				courier.LogsCall.Returns.Output = []byte(fmt.Sprintf("[APP/PROC/WEB/0] OUT web log\n[APP/TASK/%s/0] OUT migrating\n[APP/TASK/%s/0] OUT migrated\n", taskName, taskName))
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(response.Contents()).ToNot(ContainSubstring("web log"))
+				Expect(strings.Count(string(response.Contents()), "OUT migrating")).To(Equal(1))
+				Expect(strings.Count(string(response.Contents()), "OUT migrated")).To(Equal(1))
+			})
+
+			Context("when the task fails", func() {
+				It("returns an error", func() {
+					courier.TaskStateCall.Returns.States = []string{"RUNNING", "FAILED"}
+
+					err := pusher.Execute()
+					Expect(err).To(MatchError(state.TaskFailedError{taskName, "FAILED"}))
+
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("post deploy task %s failed", taskName)))
+				})
+			})
+
+			Context("when the task cannot be started", func() {
+				It("returns an error", func() {
+					courier.RunTaskCall.Returns.Output = []byte("unable to run task")
+					courier.RunTaskCall.Returns.Error = errors.New("run task error")
+
+					err := pusher.Execute()
+					Expect(err).To(MatchError(state.RunTaskError{tempAppWithUUID, []byte("unable to run task")}))
+
+					Expect(courier.TaskStateCall.TimesCalled).To(Equal(0))
+				})
+			})
+
+			Context("when the task state cannot be read", func() {
+				It("returns an error", func() {
+					courier.TaskStateCall.Returns.Error = errors.New("tasks error")
+
+					err := pusher.Execute()
+					Expect(err).To(MatchError(state.TaskStateError{taskName, errors.New("tasks error")}))
+				})
+			})
+
+			Context("when the task does not finish within the timeout of the environment", func() {
+				It("returns an error", func() {
+					courier.TaskStateCall.Returns.States = []string{"RUNNING"}
+
+					err := pusher.Execute()
+					Expect(err).To(MatchError(state.TaskTimeoutError{taskName, time.Minute}))
+
+					Expect(len(sleeps)).To(Equal(int(time.Minute / PostDeployTaskPollInterval)))
+				})
+			})
+
+			Context("when no post deploy task is provided", func() {
+				It("does not run a task", func() {
+					pusher.DeploymentInfo.PostDeployTask = ""
+
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(courier.RunTaskCall.Received.AppName).To(BeEmpty())
+				})
+			})
+		})
+
+		Describe("waiting for instances to be running", func() {
+			var sleeps []time.Duration
+
+			BeforeEach(func() {
+				sleeps = nil
+
+				pusher.DeploymentInfo.Instances = 4
+				pusher.Environment.WaitForInstances = true
+				pusher.Environment.InstanceQuorumPercent = 100
+				pusher.Environment.InstanceTimeoutSeconds = 20
+				pusher.Sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+			})
+
+			It("waits until every instance is running", func() {
+				courier.InstanceStatesCall.Returns.States = [][]string{
+					{"starting", "starting", "running", "crashed"},
+					{"running", "running", "running", "running"},
+				}
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.InstanceStatesCall.Received.AppName).To(Equal(tempAppWithUUID))
+				Expect(courier.InstanceStatesCall.TimesCalled).To(Equal(2))
+				Expect(sleeps).To(Equal([]time.Duration{InstancePollInterval}))
+				Eventually(response).Should(Say(fmt.Sprintf("4 of 4 instances of %s are running", tempAppWithUUID)))
+			})
+
+			It("accepts a quorum of running instances", func() {
+				pusher.Environment.InstanceQuorumPercent = 75
+				courier.InstanceStatesCall.Returns.States = [][]string{{"running", "running", "running", "crashed"}}
+
+				Expect(pusher.Execute()).To(Succeed())
 
-					Eventually(logBuffer).Should(Say(fmt.Sprintf("pushing app %s to %s", tempAppWithUUID, randomDomain)))
-					Eventually(logBuffer).Should(Say(fmt.Sprintf("tempdir for app %s: %s", tempAppWithUUID, randomAppPath)))
-					Eventually(logBuffer).Should(Say("output from Cloud Foundry"))
-					Eventually(logBuffer).Should(Say("successfully deployed new build"))
-				})
+				Eventually(response).Should(Say(fmt.Sprintf("3 of 4 instances of %s are running", tempAppWithUUID)))
 			})
 
-			Context("when the push fails", func() {
-				It("returns an error", func() {
-					fetcher.FetchCall.Returns.AppPath = randomAppPath
-					courier.PushCall.Returns.Error = errors.New("push error")
+			Context("when the instances are not running in time", func() {
+				It("returns an error reporting the running and expected instances", func() {
+					courier.InstanceStatesCall.Returns.States = [][]string{{"running", "crashed", "running", "starting"}}
 
 					err := pusher.Execute()
+					Expect(err).To(MatchError(state.InstancesNotRunningError{tempAppWithUUID, 2, 4, 4, 20 * time.Second}))
 
-					Expect(err).To(MatchError(state.PushError{}))
+					Expect(len(sleeps)).To(Equal(4))
+					Eventually(response).Should(Say(fmt.Sprintf("2 of 4 instances of %s are running, 4 required", tempAppWithUUID)))
 				})
 
-				It("gets logs from the courier", func() {
-					fetcher.FetchCall.Returns.AppPath = randomAppPath
-					courier.PushCall.Returns.Output = []byte("push output")
-					courier.PushCall.Returns.Error = errors.New("push error")
-					courier.LogsCall.Returns.Output = []byte("cf logs")
+				It("does not run the post deploy task", func() {
+					pusher.DeploymentInfo.PostDeployTask = "rake db:migrate"
+					courier.InstanceStatesCall.Returns.States = [][]string{{"crashed"}}
 
 					Expect(pusher.Execute()).ToNot(Succeed())
 
-					Eventually(response).Should(Say("push output"))
-					Eventually(response).Should(Say("cf logs"))
-
-					Eventually(logBuffer).Should(Say("logs from"))
+					Expect(courier.RunTaskCall.Received.AppName).To(BeEmpty())
 				})
+			})
 
-				Context("when the courier log call fails", func() {
-					It("returns an error", func() {
-						fetcher.FetchCall.Returns.AppPath = randomAppPath
-						pushErr := errors.New("push error")
-						logsErr := errors.New("logs error")
-
-						courier.PushCall.Returns.Error = pushErr
-						courier.LogsCall.Returns.Error = logsErr
-
-						err := pusher.Execute()
+			Context("when the instance states cannot be read", func() {
+				It("returns an error", func() {
+					courier.InstanceStatesCall.Returns.Error = errors.New("app error")
 
-						Expect(err).To(MatchError(state.CloudFoundryGetLogsError{pushErr, logsErr}))
-					})
+					err := pusher.Execute()
+					Expect(err).To(MatchError(state.InstanceStateError{tempAppWithUUID, errors.New("app error")}))
 				})
 			})
-		})
 
-		Context("with Zip request body", func() {
-			Context("when the push succeeds", func() {
-				It("pushes the new app", func() {
-					pusher.DeploymentInfo.ContentType = "ZIP"
-					courier.PushCall.Returns.Output = []byte("push succeeded")
-					fetcher.FetchFromZipCall.Returns.AppPath = randomAppPath
+			Context("when waiting for instances is disabled", func() {
+				It("does not check the instance states", func() {
+					pusher.Environment.WaitForInstances = false
 
 					Expect(pusher.Execute()).To(Succeed())
 
-					Expect(courier.PushCall.Received.AppName).To(Equal(tempAppWithUUID))
-					Expect(courier.PushCall.Received.AppPath).To(Equal(randomAppPath))
-					Expect(courier.PushCall.Received.Hostname).To(Equal(randomAppName))
-					Expect(courier.PushCall.Received.Instances).To(Equal(randomInstances))
-
-					Eventually(response).Should(Say("push succeeded"))
-
-					Eventually(logBuffer).Should(Say(fmt.Sprintf("pushing app %s to %s", tempAppWithUUID, randomDomain)))
-					Eventually(logBuffer).Should(Say(fmt.Sprintf("tempdir for app %s: %s", tempAppWithUUID, randomAppPath)))
-					Eventually(logBuffer).Should(Say("output from Cloud Foundry"))
-					Eventually(logBuffer).Should(Say("successfully deployed new build"))
+					Expect(courier.InstanceStatesCall.TimesCalled).To(Equal(0))
 				})
 			})
 		})
 
-		Context("with other besides zip and json request body type", func() {
-			Context("when the push succeeds", func() {
-				It("pushes the new app", func() {
-					pusher.DeploymentInfo.ContentType = "ZIP"
-					courier.PushCall.Returns.Output = []byte("push succeeded")
-					fetcher.FetchFromZipCall.Returns.AppPath = randomAppPath
+		Describe("running the probe", func() {
+			BeforeEach(func() {
+				pusher.Environment.ProbeCommand = "./smoke-test.sh"
+				pusher.Environment.ProbeTimeoutSeconds = 120
 
-					Expect(pusher.Execute()).To(Succeed())
+				courier.RoutesCall.Returns.Routes = []string{"app.example.com", "app.other.com"}
+			})
 
-					Expect(courier.PushCall.Received.AppName).To(Equal(tempAppWithUUID))
-					Expect(courier.PushCall.Received.AppPath).To(Equal(randomAppPath))
-					Expect(courier.PushCall.Received.Hostname).To(Equal(randomAppName))
-					Expect(courier.PushCall.Received.Instances).To(Equal(randomInstances))
+			It("runs the environment's probe command against the temporary application", func() {
+				prober.ProbeCall.Write.Output = "probe output"
 
-					Eventually(response).Should(Say("push succeeded"))
+				Expect(pusher.Execute()).To(Succeed())
 
-					Eventually(logBuffer).Should(Say(fmt.Sprintf("pushing app %s to %s", tempAppWithUUID, randomDomain)))
-					Eventually(logBuffer).Should(Say(fmt.Sprintf("tempdir for app %s: %s", tempAppWithUUID, randomAppPath)))
-					Eventually(logBuffer).Should(Say("output from Cloud Foundry"))
-					Eventually(logBuffer).Should(Say("successfully deployed new build"))
-				})
+				Expect(courier.RoutesCall.Received.AppName).To(Equal(tempAppWithUUID))
+				Expect(prober.ProbeCall.Received.Command).To(Equal("./smoke-test.sh"))
+				Expect(prober.ProbeCall.Received.Timeout).To(Equal(120 * time.Second))
+				Expect(prober.ProbeCall.Received.Variables).To(Equal(map[string]string{
+					"DEPLOYADACTYL_APP_NAME":       tempAppWithUUID,
+					"DEPLOYADACTYL_APP_URL":        "https://app.example.com",
+					"DEPLOYADACTYL_FOUNDATION_URL": randomFoundationURL,
+					"DEPLOYADACTYL_UUID":           randomUUID,
+				}))
+
+				Eventually(response).Should(Say("probe output"))
+				Eventually(logBuffer).Should(Say(fmt.Sprintf("probe succeeded for %s", tempAppWithUUID)))
 			})
-		})
-
-		Describe("mapping the load balanced route to the temporary application", func() {
-			Context("when a domain is provided", func() {
-				It("maps the route to the app", func() {
-					fetcher.FetchCall.Returns.AppPath = randomAppPath
-					Expect(pusher.Execute()).To(Succeed())
 
-					Expect(courier.MapRouteCall.Received.AppName[0]).To(Equal(randomAppName + TemporaryNameSuffix + randomUUID))
-					Expect(courier.MapRouteCall.Received.Domain[0]).To(Equal(randomDomain))
+			It("prefers the probe command from the request", func() {
+				pusher.DeploymentInfo.ProbeCommand = "./request-probe.sh"
 
-					Eventually(response).Should(Say(fmt.Sprintf("application route created: %s.%s", randomAppName, randomDomain)))
+				Expect(pusher.Execute()).To(Succeed())
 
-					Eventually(logBuffer).Should(Say(fmt.Sprintf("mapping route for %s to %s", randomAppName, randomDomain)))
-				})
+				Expect(prober.ProbeCall.Received.Command).To(Equal("./request-probe.sh"))
 			})
 
-			Context("when a randomDomain is not provided", func() {
-				It("does not map the randomDomain", func() {
-					courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("mapped route"))
-					deploymentInfo.Domain = ""
-					fetcher.FetchCall.Returns.AppPath = randomAppPath
+			It("passes an empty app url when the application has no routes", func() {
+				courier.RoutesCall.Returns.Routes = nil
 
-					pusher = Pusher{
-						Courier:        courier,
-						DeploymentInfo: deploymentInfo,
-						EventManager:   eventManager,
-						Response:       response,
-						Log:            interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "pusher_test")},
-						Fetcher:        fetcher,
-					}
+				Expect(pusher.Execute()).To(Succeed())
 
-					Expect(pusher.Execute()).To(Succeed())
+				Expect(prober.ProbeCall.Received.Variables["DEPLOYADACTYL_APP_URL"]).To(BeEmpty())
+			})
 
-					Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
-					Expect(courier.MapRouteCall.Received.Domain).To(BeEmpty())
+			Context("when the probe fails", func() {
+				It("returns an error", func() {
+					prober.ProbeCall.Returns.Error = errors.New("exit status 1")
 
-					Eventually(response).ShouldNot(Say("mapped route"))
+					err := pusher.Execute()
+					Expect(err).To(MatchError(state.ProbeError{tempAppWithUUID, errors.New("exit status 1")}))
 
-					Eventually(logBuffer).ShouldNot(Say(fmt.Sprintf("mapping route for %s to", randomAppName)))
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("probe failed for %s", tempAppWithUUID)))
 				})
 			})
 
-			Context("when MapRoute fails", func() {
-				It("returns an error", func() {
-					fetcher.FetchCall.Returns.AppPath = randomAppPath
-					courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("unable to map route"))
-					courier.MapRouteCall.Returns.Error = append(courier.MapRouteCall.Returns.Error, errors.New("map route error"))
+			Context("when the routes cannot be read", func() {
+				It("returns an error and does not run the probe", func() {
+					courier.RoutesCall.Returns.Error = errors.New("routes error")
 
 					err := pusher.Execute()
-					Expect(err).To(MatchError(state.MapRouteError{[]byte("unable to map route")}))
+					Expect(err).To(MatchError(state.ProbeError{tempAppWithUUID, errors.New("routes error")}))
 
-					Expect(courier.MapRouteCall.Received.AppName[0]).To(Equal(randomAppName + TemporaryNameSuffix + randomUUID))
-					Expect(courier.MapRouteCall.Received.Domain[0]).To(Equal(randomDomain))
+					Expect(prober.ProbeCall.TimesCalled).To(Equal(0))
+				})
+			})
 
-					Eventually(logBuffer).Should(Say(fmt.Sprintf("mapping route for %s to %s", randomAppName, randomDomain)))
+			Context("when no probe command is configured", func() {
+				It("does not run a probe", func() {
+					pusher.Environment.ProbeCommand = ""
+
+					Expect(pusher.Execute()).To(Succeed())
+
+					Expect(prober.ProbeCall.TimesCalled).To(Equal(0))
 				})
 			})
 		})
@@ -402,6 +1219,30 @@ var _ = Describe("Pusher", func() {
 				Expect(event.FoundationURL).To(Equal(pusher.FoundationURL))
 				Expect(event.TempAppWithUUID).ToNot(BeNil())
 			})
+			It("provides the health check mode of the environment", func() {
+				pusher.Environment.HealthCheckMode = S.HealthCheckWarn
+
+				pusher.Execute()
+
+				event := eventManager.EmitEventCall.Received.Events[0].(PushFinishedEvent)
+				Expect(event.HealthCheckMode).To(Equal(S.HealthCheckWarn))
+				Expect(event.Log).To(Equal(pusher.Log))
+			})
+			It("provides the min healthy percent of the environment, not its instance quorum", func() {
+				pusher.Environment.MinHealthyPercent = 60
+				pusher.Environment.InstanceQuorumPercent = 100
+
+				pusher.Execute()
+
+				event := eventManager.EmitEventCall.Received.Events[0].(PushFinishedEvent)
+				Expect(event.MinHealthyPercent).To(Equal(60))
+			})
+			It("provides whether the environment skips ssl", func() {
+				pusher.Execute()
+
+				event := eventManager.EmitEventCall.Received.Events[0].(PushFinishedEvent)
+				Expect(event.SkipSSL).To(Equal(skipSSL))
+			})
 			Context("when Emit fails", func() {
 				It("returns an error", func() {
 					fetcher.FetchCall.Returns.AppPath = randomAppPath
@@ -414,6 +1255,222 @@ var _ = Describe("Pusher", func() {
 		})
 	})
 
+	Describe("manual cutover", func() {
+		BeforeEach(func() {
+			pusher.DeploymentInfo.ManualCutover = true
+			fetcher.FetchCall.Returns.AppPath = randomAppPath
+			courier.RoutesCall.Returns.Routes = []string{tempAppWithUUID + ".apps.example.com"}
+		})
+
+		It("pushes the candidate with its own hostname", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.PushCall.Received.AppName).To(Equal(tempAppWithUUID))
+			Expect(courier.PushCall.Received.Hostname).To(Equal(tempAppWithUUID))
+		})
+
+		It("does not map the load balanced route to the candidate", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("reports where the candidate can be reached", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.RoutesCall.Received.AppName).To(Equal(tempAppWithUUID))
+			Eventually(response).Should(Say("candidate %s is waiting for promotion at https://%s.apps.example.com", tempAppWithUUID, tempAppWithUUID))
+		})
+
+		It("is turned on by the environment", func() {
+			pusher.DeploymentInfo.ManualCutover = false
+			pusher.Environment.ManualCutover = true
+
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.PushCall.Received.Hostname).To(Equal(tempAppWithUUID))
+		})
+
+		It("leaves both applications in place on success", func() {
+			courier.ExistsCall.Returns.Bool = true
+
+			Expect(pusher.Success()).To(Succeed())
+
+			Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+			Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+			Expect(courier.UnmapRouteCall.Received.AppName).To(BeEmpty())
+
+			Eventually(logBuffer).Should(Say("leaving %s and %s for a manual cutover", randomAppName, tempAppWithUUID))
+		})
+	})
+
+	Describe("traffic split", func() {
+		var sleeps []time.Duration
+
+		BeforeEach(func() {
+			sleeps = nil
+
+			pusher.DeploymentInfo.Instances = 2
+			pusher.Environment.TrafficSplit = true
+			pusher.Environment.TrafficSplitWeights = []int{10, 50}
+			pusher.Environment.TrafficSplitSoakSeconds = 20
+			pusher.Environment.InstanceQuorumPercent = 100
+			pusher.Sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+
+			fetcher.FetchCall.Returns.AppPath = randomAppPath
+			courier.ExistsCall.Returns.Bool = true
+			courier.RoutesCall.Returns.Routes = []string{tempAppWithUUID + ".apps.example.com"}
+			courier.RouteGUIDCall.Returns.GUID = "route-guid"
+			courier.AppGUIDCall.Returns.GUIDs = map[string]string{randomAppName: "existing-guid", tempAppWithUUID: "new-guid"}
+			courier.InstanceStatesCall.Returns.States = [][]string{{"running", "running"}}
+		})
+
+		It("pushes the new application with its own hostname", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.PushCall.Received.Hostname).To(Equal(tempAppWithUUID))
+		})
+
+		It("shifts the production route to the new application in steps", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.RouteGUIDCall.Received.Domain).To(Equal("apps.example.com"))
+			Expect(courier.RouteGUIDCall.Received.Hostname).To(Equal(randomAppName))
+			Expect(courier.SetRouteWeightsCall.Received.RouteGUID).To(ConsistOf("route-guid", "route-guid", "route-guid"))
+			Expect(courier.SetRouteWeightsCall.Received.Weights).To(Equal([]map[string]int{
+				{"existing-guid": 90, "new-guid": 10},
+				{"existing-guid": 50, "new-guid": 50},
+				{"new-guid": 100},
+			}))
+			Eventually(response).Should(Say("sending 10%% of %s.apps.example.com to the new application", randomAppName))
+		})
+
+		It("watches the new application for the soak time of each step", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(sleeps).To(Equal([]time.Duration{
+				TrafficSplitPollInterval, TrafficSplitPollInterval,
+				TrafficSplitPollInterval, TrafficSplitPollInterval,
+			}))
+			Expect(courier.InstanceStatesCall.Received.AppName).To(Equal(tempAppWithUUID))
+		})
+
+		It("maps the load balanced route once the new application has all of the traffic", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{tempAppWithUUID}))
+			Expect(courier.MapRouteCall.Received.Domain).To(Equal([]string{randomDomain}))
+		})
+
+		Context("when the new application degrades during the soak", func() {
+			It("returns the traffic to the existing application", func() {
+				courier.InstanceStatesCall.Returns.States = [][]string{{"running", "running"}, {"running", "crashed"}}
+
+				err := pusher.Execute()
+				Expect(err).To(MatchError(state.TrafficSplitDegradedError{tempAppWithUUID, 10, 1, 2}))
+
+				Expect(courier.SetRouteWeightsCall.Received.Weights).To(Equal([]map[string]int{
+					{"existing-guid": 90, "new-guid": 10},
+					{"existing-guid": 100},
+				}))
+			})
+		})
+
+		Context("when the application does not exist yet", func() {
+			It("maps the production route to the new application", func() {
+				courier.ExistsCall.Returns.Bool = false
+				pusher.DeploymentInfo.Domain = ""
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.SetRouteWeightsCall.TimesCalled).To(Equal(0))
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{tempAppWithUUID}))
+				Expect(courier.MapRouteCall.Received.Domain).To(Equal([]string{"apps.example.com"}))
+				Expect(courier.MapRouteCall.Received.Hostname).To(Equal([]string{randomAppName}))
+			})
+		})
+
+		Context("when the weights cannot be set", func() {
+			It("returns an error", func() {
+				courier.SetRouteWeightsCall.Returns.Error = errors.New("route error")
+
+				err := pusher.Execute()
+				Expect(err).To(BeAssignableToTypeOf(state.RouteWeightsError{}))
+			})
+		})
+
+		It("returns the traffic to the existing application on undo", func() {
+			Expect(pusher.Undo()).To(Succeed())
+
+			Expect(courier.SetRouteWeightsCall.Received.Weights).To(Equal([]map[string]int{{"existing-guid": 100}}))
+			Expect(courier.DeleteCall.Received.AppName).To(Equal(tempAppWithUUID))
+		})
+
+		It("is not used with a manual cutover", func() {
+			pusher.DeploymentInfo.ManualCutover = true
+
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.SetRouteWeightsCall.TimesCalled).To(Equal(0))
+		})
+
+		It("is not used when the push chooses the bluegreen strategy", func() {
+			pusher.DeploymentInfo.Strategy = S.StrategyBlueGreen
+
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.SetRouteWeightsCall.TimesCalled).To(Equal(0))
+			Expect(courier.PushCall.Received.Hostname).To(Equal(randomAppName))
+		})
+
+		It("is used when the push chooses the traffic-split strategy in another environment", func() {
+			pusher.Environment.TrafficSplit = false
+			pusher.DeploymentInfo.Strategy = S.StrategyTrafficSplit
+
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.SetRouteWeightsCall.TimesCalled).To(Equal(3))
+		})
+	})
+
+	Describe("canceling the deploy", func() {
+		var cancellation *deployer.Cancellation
+
+		BeforeEach(func() {
+			cancellations := deployer.NewCancellations()
+			cancellation = cancellations.Register(randomUUID, "", "", "", randomAppName)
+			cancellation.Add()
+			pusher.Cancellation = cancellation
+			fetcher.FetchCall.Returns.AppPath = randomAppPath
+		})
+
+		It("does not push when the deploy was canceled", func() {
+			Expect(cancellation.Cancel()).To(BeTrue())
+
+			Expect(pusher.Execute()).To(MatchError(state.DeployCanceledError{randomAppName}))
+
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("finishes the push when the deploy was not canceled", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(cancellation.Cancel()).To(BeFalse())
+		})
+
+		It("deletes the candidate on undo even when rollback is disabled", func() {
+			Expect(cancellation.Cancel()).To(BeTrue())
+			pusher.Environment.EnableRollback = false
+			courier.ExistsCall.Returns.Bool = true
+
+			Expect(pusher.Undo()).To(Succeed())
+
+			Expect(courier.ExistsCall.Received.AppName).To(Equal(tempAppWithUUID))
+			Expect(courier.DeleteCall.Received.AppName).To(Equal(tempAppWithUUID))
+			Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+		})
+	})
+
 	Describe("Success", func() {
 		It("renames the newly pushed app to the original name", func() {
 			Expect(pusher.Success()).To(Succeed())
@@ -424,6 +1481,30 @@ var _ = Describe("Pusher", func() {
 			Eventually(logBuffer).Should(Say("renamed %s to %s", tempAppWithUUID, randomAppName))
 		})
 
+		It("records the swap phase in the journal", func() {
+			journal, err := NewJournal(&afero.Afero{Fs: afero.NewMemMapFs()}, "/journal", interfaces.DefaultLogger(logBuffer, logging.DEBUG, "pusher_test"))
+			Expect(err).ToNot(HaveOccurred())
+			pusher.Journal = journal
+
+			Expect(pusher.Success()).To(Succeed())
+
+			checkpoints, err := journal.Checkpoints()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checkpoints).To(HaveLen(1))
+			Expect(checkpoints[0].Phase).To(Equal(SwapPhase))
+			Expect(checkpoints[0].CandidateApp).To(Equal(tempAppWithUUID))
+			Expect(checkpoints[0].FoundationURL).To(Equal(randomFoundationURL))
+		})
+
+		It("does not fail a swap that runs past its budget", func() {
+			pusher.Environment.SwapTimeoutSeconds = 1
+			courier.RenameCall.Delay = 1500 * time.Millisecond
+
+			Expect(pusher.Success()).To(Succeed())
+
+			Eventually(logBuffer).Should(Say("the swap phase of %s took .*, longer than its budget of 1s", randomAppName))
+		})
+
 		Context("when rename fails", func() {
 			It("returns an error", func() {
 				courier.RenameCall.Returns.Output = []byte("rename output")
@@ -512,6 +1593,51 @@ var _ = Describe("Pusher", func() {
 					Eventually(logBuffer).Should(Say(fmt.Sprintf("could not delete %s", randomAppName)))
 				})
 			})
+
+			Context("when the environment has a delete delay", func() {
+				var venerable string
+
+				BeforeEach(func() {
+					venerable = randomAppName + VenerableNameSuffix + randomUUID
+
+					pusher.Environment.DeleteDelaySeconds = 60
+					pusher.Retirements = NewRetirements(courierCreator{}, interfaces.DefaultLogger(logBuffer, logging.DEBUG, "pusher_test"))
+				})
+
+				AfterEach(func() {
+					pusher.Retirements.Remove(randomUUID, randomAppName)
+				})
+
+				It("stops the original application instead of deleting it", func() {
+					Expect(pusher.Success()).To(Succeed())
+
+					Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+					Expect(courier.StopCall.Received.AppName).To(Equal(venerable))
+
+					Eventually(logBuffer).Should(Say(fmt.Sprintf("renaming %s to %s", randomAppName, venerable)))
+					Eventually(response).Should(Say(fmt.Sprintf("stopped %s, it will be deleted in 1m0s", venerable)))
+				})
+
+				It("records the original application as waiting for its deletion", func() {
+					Expect(pusher.Success()).To(Succeed())
+
+					retirement, ok := pusher.Retirements.Get(randomUUID, randomAppName)
+					Expect(ok).To(BeTrue())
+					Expect(retirement.Venerable()).To(Equal(venerable))
+					Expect(retirement.Foundations).To(Equal([]string{pusher.FoundationURL}))
+					Expect(retirement.Delay).To(Equal(time.Minute))
+				})
+
+				It("returns an error when the original application cannot be stopped", func() {
+					courier.StopCall.Returns.Output = []byte("stop output")
+					courier.StopCall.Returns.Error = errors.New("stop error")
+
+					Expect(pusher.Success()).To(MatchError(state.StopError{venerable, []byte("stop output")}))
+
+					_, ok := pusher.Retirements.Get(randomUUID, randomAppName)
+					Expect(ok).To(BeFalse())
+				})
+			})
 		})
 
 		Context("when the application does not exist", func() {
@@ -592,6 +1718,21 @@ var _ = Describe("Pusher", func() {
 
 			Expect(pusher.Finally()).To(Succeed())
 		})
+
+		Context("when the push is recorded in a journal", func() {
+			It("removes the checkpoints of the push", func() {
+				journal, err := NewJournal(&afero.Afero{Fs: afero.NewMemMapFs()}, "/journal", interfaces.DefaultLogger(logBuffer, logging.DEBUG, "pusher_test"))
+				Expect(err).ToNot(HaveOccurred())
+				pusher.Journal = journal
+
+				Expect(pusher.Success()).To(Succeed())
+				Expect(journal.Checkpoints()).To(HaveLen(1))
+
+				Expect(pusher.Finally()).To(Succeed())
+
+				Expect(journal.Checkpoints()).To(BeEmpty())
+			})
+		})
 	})
 
 	Describe("Verify", func() {
@@ -3,6 +3,8 @@ package push
 import (
 	"encoding/base64"
 	"fmt"
+	"github.com/compozed/deployadactyl/artifetcher"
+	"github.com/compozed/deployadactyl/artifetcher/extractor"
 	"github.com/compozed/deployadactyl/constants"
 	"github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
@@ -10,9 +12,14 @@ import (
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/state"
 	S "github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/tracing"
 	"io"
 	"net/http"
+	"os"
+	"path"
 	"regexp"
+	"strings"
+	"time"
 )
 
 const deploymentOutput = `Deployment Parameters:
@@ -30,6 +37,11 @@ Thanks for using Deployadactyl! Please push down pull up on your lap bar and exi
 
 `
 
+const pendingPromotion = `Your candidate was pushed and is waiting for promotion. (^_^)b
+Production traffic still goes to %s. To complete the cutover, PUT {"state": "promoted", "uuid": "%s"} to this deploy's endpoint.
+
+`
+
 type courierCreator interface {
 	CreateCourier() (I.Courier, error)
 }
@@ -38,6 +50,10 @@ type fileSystemCleaner interface {
 	RemoveAll(path string) error
 }
 
+type manifestWriter interface {
+	WriteFile(filename string, data []byte, perm os.FileMode) error
+}
+
 type PushManager struct {
 	CourierCreator       courierCreator
 	EventManager         I.EventManager
@@ -45,15 +61,56 @@ type PushManager struct {
 	Fetcher              I.Fetcher
 	DeployEventData      S.DeployEventData
 	FileSystemCleaner    fileSystemCleaner
+	ManifestWriter       manifestWriter
 	CFContext            I.CFContext
 	Auth                 I.Authorization
 	Environment          S.Environment
 	EnvironmentVariables map[string]string
+	Prober               I.Prober
+
+	// ManifestTransformer changes the manifest before it is pushed. Manifests are pushed as they are when it is nil.
+	ManifestTransformer I.ManifestTransformer
+
+	// ArtifactScanner scans the extracted artifact before it is pushed. Artifacts are not scanned when it is nil.
+	ArtifactScanner I.ArtifactScanner
+
+	// Promotions records the deploys left for a manual cutover.
+	Promotions *Promotions
+
+	// Retirements keeps the replaced applications stopped for the delete delay of the environment.
+	Retirements *Retirements
+
+	// Cancellations lets the deploy be canceled while it is running.
+	Cancellations *deployer.Cancellations
+
+	// Tracer records the phases of the deploy as spans. Phases are not traced when it is nil.
+	Tracer *tracing.Tracer
+
+	// Journal records the phases of the pushes so they can be recovered after a restart. They are not recorded
+	// when it is nil.
+	Journal *Journal
+
+	// statuses is set when the applications of a multi-application manifest are pushed.
+	statuses *ApplicationStatuses
+
+	// cancellation is registered in Cancellations when the deploy is set up.
+	cancellation *deployer.Cancellation
+
+	// healthChecks is shared by the Pushers of every foundation when the deploy is set up.
+	healthChecks *HealthChecks
 }
 
 func (a *PushManager) SetUp() error {
+	info := a.DeployEventData.DeploymentInfo
+	a.cancellation = a.Cancellations.Register(info.UUID, info.Environment, info.Org, info.Space, info.AppName)
+	if a.cancellation == nil && a.Cancellations != nil {
+		a.Logger.Infof("deploy %s cannot be canceled: another deploy with the same UUID is running", info.UUID)
+	}
+	a.healthChecks = NewHealthChecks(a.Environment.HealthCheckConcurrency, a.Environment.HealthCheckFailFast)
+
 	var (
 		manifestString string
+		pushManifest   string
 		instances      *uint16
 		appPath        string
 		err            error
@@ -71,9 +128,38 @@ func (a *PushManager) SetUp() error {
 			manifestString = string(manifest)
 		}
 
+		manifestString, err = a.applyProfileManifest(manifestString)
+		if err != nil {
+			a.Logger.Error(err)
+			return err
+		}
+
+		manifestString, err = a.applyDefaultManifest(manifestString)
+		if err != nil {
+			a.Logger.Error(err)
+			return err
+		}
+
+		manifestString, err = a.transformManifest(manifestString)
+		if err != nil {
+			a.Logger.Error(err)
+			return err
+		}
+
+		// The manifest with the environment variables of the push is only written to the artifact, so their
+		// values are not in the events of the deploy.
+		pushManifest, err = a.withRequestEnv(manifestString)
+		if err != nil {
+			a.Logger.Error(err)
+			return err
+		}
+
 		fetchFn = func() (string, error) {
 			a.Logger.Debug("deploying from json request")
-			appPath, err = a.Fetcher.Fetch(a.DeployEventData.DeploymentInfo.ArtifactURL, manifestString)
+			appPath, err = a.Fetcher.Fetch(a.DeployEventData.DeploymentInfo.ArtifactURL, pushManifest)
+			if _, empty := err.(artifetcher.EmptyArtifactError); empty {
+				return "", err
+			}
 			if err != nil {
 				return "", state.AppPathError{Err: err}
 			}
@@ -81,12 +167,52 @@ func (a *PushManager) SetUp() error {
 		}
 	} else {
 		fetchFn = func() (string, error) {
-			a.Logger.Debug("deploying from zip request")
-			appPath, manifestString, err = a.Fetcher.FetchZipFromRequest(a.DeployEventData.DeploymentInfo.Body)
+			if a.streamZip() {
+				a.Logger.Debug("deploying from zip request without extracting it")
+				appPath, manifestString, err = a.Fetcher.StageZipFromRequest(a.DeployEventData.DeploymentInfo.Body)
+				if err == nil {
+					a.DeployEventData.DeploymentInfo.ArtifactArchive = artifetcher.StagedArtifactFile
+				}
+			} else {
+				a.Logger.Debug("deploying from zip request")
+				appPath, manifestString, err = a.Fetcher.FetchZipFromRequest(a.DeployEventData.DeploymentInfo.Body)
+			}
+			if _, empty := err.(artifetcher.EmptyArtifactError); empty {
+				return "", err
+			}
 			if err != nil {
 				return "", state.UnzippingError{Err: err}
 			}
 
+			original := manifestString
+
+			// The manifest part of a multipart push replaces any manifest in the artifact.
+			if a.DeployEventData.DeploymentInfo.ContentType == "MULTIPART" {
+				manifest, err := base64.StdEncoding.DecodeString(a.DeployEventData.DeploymentInfo.Manifest)
+				if err != nil {
+					return appPath, state.ManifestError{}
+				}
+				manifestString = string(manifest)
+			}
+
+			manifestString, err = a.applyDefaultManifest(manifestString)
+			if err != nil {
+				return appPath, err
+			}
+
+			manifestString, err = a.transformManifest(manifestString)
+			if err != nil {
+				return appPath, err
+			}
+
+			pushManifest = manifestString
+			if manifestString != original {
+				err = a.ManifestWriter.WriteFile(path.Join(appPath, "manifest.yml"), []byte(manifestString), 0600)
+				if err != nil {
+					return appPath, state.DefaultManifestError{Err: err}
+				}
+			}
+
 			return appPath, nil
 		}
 	}
@@ -111,13 +237,13 @@ func (a *PushManager) SetUp() error {
 		return deployer.EventError{Type: event.Name(), Err: err}
 	}
 
-	appPath, err = fetchFn()
-
-	instances = manifestro.GetInstances(manifestString)
-	if instances == nil {
-		instances = &a.Environment.Instances
-	}
-
+	err = runPhase(a.Tracer, info.UUID, FetchPhase, a.Environment.FetchTimeoutSeconds, func() error {
+		path, err := fetchFn()
+		appPath = path
+		return err
+	})
+	// The staged artifact is removed by CleanUp however the rest of the set up ends.
+	a.DeployEventData.DeploymentInfo.AppPath = appPath
 	if err != nil {
 		a.Logger.Error(err)
 		event = ArtifactRetrievalFailureEvent{
@@ -134,6 +260,50 @@ func (a *PushManager) SetUp() error {
 		return err
 	}
 
+	metadata := a.Fetcher.Metadata()
+	a.DeployEventData.DeploymentInfo.Artifact = &metadata
+
+	instances = manifestro.GetInstances(manifestString)
+	if instances == nil {
+		instances = &a.Environment.Instances
+	}
+
+	if !a.DeployEventData.DeploymentInfo.AllApplications {
+		err = checkInstances(info.AppName, *instances, a.Environment)
+		if err != nil {
+			a.Logger.Error(err)
+			return err
+		}
+
+		err = checkResources(info.AppName, manifestString, mergePushFlags(a.Environment.PushFlags, info.PushFlags), a.Environment)
+		if err != nil {
+			a.Logger.Error(err)
+			return err
+		}
+
+		err = a.checkOverrides(manifestString)
+		if err != nil {
+			a.Logger.Error(err)
+			return err
+		}
+	}
+
+	if a.DeployEventData.DeploymentInfo.AllApplications {
+		err = a.writeApplicationManifests(appPath, pushManifest)
+		if err != nil {
+			a.Logger.Error(err)
+			return err
+		}
+	}
+
+	err = a.scanArtifact(appPath)
+	if err != nil {
+		a.Logger.Error(err)
+		a.FileSystemCleaner.RemoveAll(appPath)
+		a.DeployEventData.DeploymentInfo.AppPath = ""
+		return err
+	}
+
 	event = ArtifactRetrievalSuccessEvent{
 		CFContext:            a.CFContext,
 		Auth:                 a.Auth,
@@ -143,7 +313,7 @@ func (a *PushManager) SetUp() error {
 		Manifest:             manifestString,
 		ArtifactURL:          a.DeployEventData.DeploymentInfo.ArtifactURL,
 		AppPath:              appPath,
-		EnvironmentVariables: a.EnvironmentVariables,
+		EnvironmentVariables: mergeSettings(a.EnvironmentVariables, a.DeployEventData.DeploymentInfo.Env),
 		Log:                  a.Logger,
 	}
 	a.Logger.Debugf("emitting a %s event", event.Name())
@@ -155,15 +325,148 @@ func (a *PushManager) SetUp() error {
 	}
 
 	a.DeployEventData.DeploymentInfo.Manifest = manifestString
-	a.DeployEventData.DeploymentInfo.AppPath = appPath
 	a.DeployEventData.DeploymentInfo.Instances = *instances
 
 	return nil
 }
 
+// streamZip reports whether the zip of the push can be pushed as is instead of being extracted. It has to be
+// extracted to push every application of its manifest, to be scanned, or when it is not a zip.
+func (a *PushManager) streamZip() bool {
+	info := a.DeployEventData.DeploymentInfo
+	if !a.Environment.StreamZipArtifacts || info.AllApplications || a.ArtifactScanner != nil {
+		return false
+	}
+
+	return extractor.MediaType(info.ArtifactType) == extractor.ZipContentType
+}
+
+// applyProfileManifest merges the manifest over the manifest of the profile the push applies.
+// The profile manifest is used as is when the push has no manifest.
+func (a *PushManager) applyProfileManifest(manifest string) (string, error) {
+	profileManifest := a.DeployEventData.DeploymentInfo.ProfileManifest
+	if profileManifest == "" {
+		return manifest, nil
+	}
+
+	if manifest == "" {
+		a.Logger.Debugf("using the manifest of profile %s", a.DeployEventData.DeploymentInfo.Profile)
+		return profileManifest, nil
+	}
+
+	a.Logger.Debugf("merging the manifest over the manifest of profile %s", a.DeployEventData.DeploymentInfo.Profile)
+	merged, err := manifestro.Merge(profileManifest, manifest)
+	if err != nil {
+		return "", state.ProfileManifestError{Err: err}
+	}
+
+	return merged, nil
+}
+
+// applyDefaultManifest merges the request manifest over the environment's default manifest.
+// The default manifest is used as is when the request has no manifest.
+func (a *PushManager) applyDefaultManifest(manifest string) (string, error) {
+	if a.Environment.DefaultManifest == "" {
+		return manifest, nil
+	}
+
+	if manifest == "" {
+		a.Logger.Debug("using the default manifest of the environment")
+		return a.Environment.DefaultManifest, nil
+	}
+
+	a.Logger.Debug("merging the manifest over the default manifest of the environment")
+	merged, err := manifestro.Merge(a.Environment.DefaultManifest, manifest)
+	if err != nil {
+		return "", state.DefaultManifestError{Err: err}
+	}
+
+	return merged, nil
+}
+
+// transformManifest passes the manifest through the ManifestTransformer.
+// The manifest is returned as it is when the transformer does not change it.
+func (a *PushManager) transformManifest(manifest string) (string, error) {
+	if a.ManifestTransformer == nil || manifest == "" {
+		return manifest, nil
+	}
+
+	content, err := manifestro.Parse(manifest)
+	if err != nil {
+		return "", state.ManifestTransformError{Err: err}
+	}
+
+	a.Logger.Debug("transforming the manifest")
+	content, err = a.ManifestTransformer.Transform(content, *a.DeployEventData.DeploymentInfo)
+	if err != nil {
+		return "", state.ManifestTransformError{Err: err}
+	}
+
+	transformed, err := manifestro.Format(content)
+	if err != nil {
+		return "", state.ManifestTransformError{Err: err}
+	}
+
+	// The manifests are compared formatted, so a transformer may return its numbers as another type of int.
+	original, _ := manifestro.Parse(manifest)
+	unchanged, _ := manifestro.Format(original)
+	if transformed == unchanged {
+		return manifest, nil
+	}
+
+	return transformed, nil
+}
+
+// writeApplicationManifests writes a manifest for each application of a multi-application manifest
+// to the application directory, so each application can be pushed on its own.
+func (a *PushManager) writeApplicationManifests(appPath, manifest string) error {
+	applications := manifestro.GetApplicationNames(manifest)
+	if len(applications) == 0 {
+		return state.NoApplicationsError{}
+	}
+
+	a.Logger.Infof("pushing %d applications from the manifest: %s", len(applications), strings.Join(applications, ", "))
+
+	for i, application := range applications {
+		applicationManifest, err := manifestro.GetApplication(manifest, application)
+		if err != nil {
+			return state.ApplicationManifestError{application, err}
+		}
+
+		instances := manifestro.GetInstances(applicationManifest)
+		if instances == nil {
+			instances = &a.Environment.Instances
+		}
+
+		err = checkInstances(application, *instances, a.Environment)
+		if err != nil {
+			return err
+		}
+
+		err = checkResources(application, applicationManifest, a.Environment.PushFlags, a.Environment)
+		if err != nil {
+			return err
+		}
+
+		err = a.ManifestWriter.WriteFile(path.Join(appPath, fmt.Sprintf(ApplicationManifestFile, i)), []byte(applicationManifest), 0600)
+		if err != nil {
+			return state.ApplicationManifestError{application, err}
+		}
+	}
+
+	a.DeployEventData.DeploymentInfo.Applications = applications
+	a.statuses = NewApplicationStatuses(applications)
+
+	return nil
+}
+
 func (a PushManager) OnStart() error {
 	info := a.DeployEventData.DeploymentInfo
-	deploymentMessage := fmt.Sprintf(deploymentOutput, info.ArtifactURL, info.Username, info.Environment, info.Org, info.Space, info.AppName)
+	appName := info.AppName
+	if len(info.Applications) > 0 {
+		appName = strings.Join(info.Applications, ", ")
+	}
+	deploymentMessage := fmt.Sprintf(deploymentOutput, info.ArtifactURL, info.Username, info.Environment, info.Org, info.Space, appName)
 
 	a.Logger.Info(deploymentMessage)
 	fmt.Fprintln(a.DeployEventData.Response, deploymentMessage)
@@ -196,6 +499,18 @@ func (a PushManager) OnStart() error {
 }
 
 func (a PushManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
+	if a.statuses != nil {
+		a.statuses.Write(response)
+	}
+
+	if a.healthChecks != nil {
+		a.healthChecks.Write(response)
+	}
+
+	if a.cancellation.Canceled() {
+		return a.onCancel(response)
+	}
+
 	if err != nil {
 		if !env.EnableRollback {
 			a.Logger.Errorf("EnableRollback %t, returning status %d and err %s", env.EnableRollback, http.StatusOK, err)
@@ -217,14 +532,91 @@ func (a PushManager) OnFinish(env S.Environment, response io.ReadWriter, err err
 			Error:      err,
 		}
 	}
-	a.Logger.Infof("successfully deployed application %s", a.DeployEventData.DeploymentInfo.AppName)
+	if a.statuses != nil {
+		if failed := a.statuses.Failed(); len(failed) > 0 {
+			err = state.ApplicationsFailedError{failed}
+			a.Logger.Errorf("deployed only some of the applications: %s", err)
+			fmt.Fprintf(response, "\nOnly some of the applications were deployed.\n\n")
+
+			return I.DeployResponse{
+				StatusCode: http.StatusMultiStatus,
+				Error:      err,
+			}
+		}
+	}
+
+	if len(a.DeployEventData.DeploymentInfo.Applications) > 0 {
+		a.Logger.Infof("successfully deployed applications %s", strings.Join(a.DeployEventData.DeploymentInfo.Applications, ", "))
+	} else {
+		a.Logger.Infof("successfully deployed application %s", a.DeployEventData.DeploymentInfo.AppName)
+	}
+
+	info := a.DeployEventData.DeploymentInfo
+	if (info.ManualCutover || env.ManualCutover) && a.Promotions != nil {
+		a.Promotions.Add(PendingPromotion{
+			UUID:        info.UUID,
+			Environment: info.Environment,
+			Org:         info.Org,
+			Space:       info.Space,
+			AppName:     info.AppName,
+			PushedAt:    time.Now(),
+		})
+		a.Logger.Infof("%s%s%s is waiting for promotion", info.AppName, TemporaryNameSuffix, info.UUID)
+		fmt.Fprintf(response, "\n"+pendingPromotion, info.AppName, info.UUID)
+
+		return I.DeployResponse{StatusCode: http.StatusOK}
+	}
+
 	fmt.Fprintf(response, "\n%s", successfulDeploy)
 
 	return I.DeployResponse{StatusCode: http.StatusOK}
 }
 
+// onCancel reports a canceled deploy and emits a deploy canceled event.
+func (a PushManager) onCancel(response io.ReadWriter) I.DeployResponse {
+	info := a.DeployEventData.DeploymentInfo
+	err := state.DeployCanceledError{info.AppName}
+
+	a.Logger.Errorf("deploy of %s with UUID %s was canceled", info.AppName, info.UUID)
+	fmt.Fprintf(response, "\nYour deploy was canceled and rolled back.\n\n")
+
+	emitErr := a.EventManager.Emit(I.Event{Type: constants.DeployCanceledEvent, Data: &a.DeployEventData})
+	if emitErr != nil {
+		a.Logger.Error(emitErr)
+	}
+
+	emitErr = a.EventManager.EmitEvent(DeployCanceledEvent{
+		CFContext:   a.CFContext,
+		Auth:        a.Auth,
+		Environment: a.Environment,
+		Response:    response,
+		Data:        info.Data,
+		Log:         a.Logger,
+	})
+	if emitErr != nil {
+		a.Logger.Error(emitErr)
+	}
+
+	return I.DeployResponse{
+		StatusCode: http.StatusInternalServerError,
+		Error:      err,
+	}
+}
+
+// CleanUp removes the staged artifact of the deploy. The deployer defers it, so it also runs when the deploy fails
+// or panics.
 func (a PushManager) CleanUp() {
-	a.FileSystemCleaner.RemoveAll(a.DeployEventData.DeploymentInfo.AppPath)
+	a.Cancellations.Remove(a.DeployEventData.DeploymentInfo.UUID, a.cancellation)
+
+	appPath := a.DeployEventData.DeploymentInfo.AppPath
+	err := a.FileSystemCleaner.RemoveAll(appPath)
+	if err != nil {
+		a.Logger.Errorf("cannot remove staging directory %s: %s", appPath, err)
+		return
+	}
+	if appPath != "" {
+		a.Logger.Infof("removed staging directory %s", appPath)
+	}
 }
 
 func (a PushManager) Create(environment S.Environment, response io.ReadWriter, foundationURL string) (I.Action, error) {
@@ -247,11 +639,52 @@ func (a PushManager) Create(environment S.Environment, response io.ReadWriter, f
 		Fetcher:        a.Fetcher,
 		CFContext:      a.CFContext,
 		Auth:           a.Auth,
+		Prober:         a.Prober,
+		Cancellation:   a.cancellation,
+		Tracer:         a.Tracer,
+		Retirements:    a.Retirements,
+		HealthChecks:   a.healthChecks,
+		Journal:        a.Journal,
+	}
+
+	if len(a.DeployEventData.DeploymentInfo.Applications) > 0 {
+		return a.createMultiPusher(*p)
 	}
 
+	a.cancellation.Add()
 	return p, nil
 }
 
+// createMultiPusher creates a Pusher for each application of a multi-application manifest from the given Pusher.
+// The Pushers share its courier.
+func (a PushManager) createMultiPusher(p Pusher) (I.Action, error) {
+	multiPusher := &MultiPusher{Statuses: a.statuses, FailurePolicy: a.DeployEventData.DeploymentInfo.FailurePolicy}
+
+	for i, application := range a.DeployEventData.DeploymentInfo.Applications {
+		manifest, err := manifestro.GetApplication(a.DeployEventData.DeploymentInfo.Manifest, application)
+		if err != nil {
+			a.Logger.Error(err)
+			return multiPusher, state.ApplicationManifestError{application, err}
+		}
+
+		instances := manifestro.GetInstances(manifest)
+		if instances == nil {
+			instances = &a.Environment.Instances
+		}
+
+		pusher := p
+		pusher.DeploymentInfo.AppName = application
+		pusher.DeploymentInfo.Manifest = manifest
+		pusher.DeploymentInfo.Instances = *instances
+		pusher.ManifestFile = fmt.Sprintf(ApplicationManifestFile, i)
+		a.cancellation.Add()
+
+		multiPusher.Pushers = append(multiPusher.Pushers, pusher)
+	}
+
+	return multiPusher, nil
+}
+
 func (a PushManager) InitiallyError(initiallyErrors []error) error {
 	return bluegreen.LoginError{LoginErrors: initiallyErrors}
 }
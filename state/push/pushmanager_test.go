@@ -3,11 +3,13 @@ package push_test
 import (
 	"bytes"
 	"encoding/base64"
+	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/constants"
 	"github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
 	. "github.com/compozed/deployadactyl/state/push"
 	"github.com/compozed/deployadactyl/structs"
 	"github.com/go-errors/errors"
@@ -20,8 +22,21 @@ import (
 	"io/ioutil"
 	"net/http"
 	"reflect"
+	"strings"
 )
 
+type courierCreator struct {
+	CourierCreatorFn func() (interfaces.Courier, error)
+}
+
+func (c courierCreator) CreateCourier() (interfaces.Courier, error) {
+	if c.CourierCreatorFn != nil {
+		return c.CourierCreatorFn()
+	}
+
+	return &mocks.Courier{}, nil
+}
+
 var _ = Describe("Actioncreator", func() {
 	var (
 		logBuffer         *bytes.Buffer
@@ -43,7 +58,7 @@ var _ = Describe("Actioncreator", func() {
 		response = NewBuffer()
 		pusherCreator = &PushManager{
 			Fetcher:      fetcher,
-			Logger:       interfaces.DeploymentLogger{log, randomizer.StringRunes(10)},
+			Logger:       interfaces.DeploymentLogger{Log: log, UUID: randomizer.StringRunes(10)},
 			EventManager: eventManager,
 			DeployEventData: structs.DeployEventData{
 				DeploymentInfo: &structs.DeploymentInfo{},
@@ -109,6 +124,41 @@ applications:
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("unzipped app path failed: fetch error"))
+				Expect(deploymentInfo.Artifact).To(BeNil())
+			})
+			It("returns the error of an empty artifact as it is", func() {
+				fetcher.FetchCall.Returns.Error = artifetcher.EmptyArtifactError{"https://artifacturl.com"}
+
+				deploymentInfo := structs.DeploymentInfo{
+					Manifest:    encodedManifest,
+					ArtifactURL: "https://artifacturl.com",
+					ContentType: "JSON",
+				}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				err := pusherCreator.SetUp()
+
+				Expect(err).To(MatchError(artifetcher.EmptyArtifactError{"https://artifacturl.com"}))
+			})
+			It("records the metadata of the fetched artifact in the deployment info", func() {
+				fetcher.FetchCall.Returns.AppPath = "newAppPath"
+				fetcher.MetadataCall.Returns.Metadata = structs.ArtifactMetadata{
+					URL:       "https://artifacturl.com",
+					Checksum:  "f6bdd0c3378276630679e1845601e166d30e7c3546d57059420e01373ee73f43",
+					SizeBytes: 2048,
+					GitSHA:    "3f2c1e0b9a8d7c6b5a4f3e2d1c0b9a8d7c6b5a4f",
+				}
+
+				deploymentInfo := structs.DeploymentInfo{
+					Manifest:    encodedManifest,
+					ArtifactURL: "https://artifacturl.com",
+					ContentType: "JSON",
+				}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(deploymentInfo.Artifact).To(Equal(&fetcher.MetadataCall.Returns.Metadata))
 			})
 			It("should retrieve instances from manifest", func() {
 				fetcher.FetchCall.Returns.AppPath = "newAppPath"
@@ -390,6 +440,160 @@ applications:
 			})
 		})
 
+		Context("when the environment limits the instances", func() {
+			BeforeEach(func() {
+				pusherCreator.Environment = structs.Environment{Instances: 2, MaxInstances: 10}
+				fetcher.FetchFromZipCall.Returns.AppPath = "newAppPath"
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{AppName: "blah", ContentType: "ZIP"}
+			})
+
+			It("returns an error when the manifest asks for more instances", func() {
+				fetcher.FetchFromZipCall.Returns.Manifest = `---
+applications:
+- name: "blah"
+  instances: 5000
+`
+
+				Expect(pusherCreator.SetUp()).To(MatchError(deployer.TooManyInstancesError{"blah", 5000, 10}))
+			})
+
+			It("returns an error when the manifest asks for more memory per instance", func() {
+				pusherCreator.Environment.MaxMemoryPerInstance = "1G"
+				fetcher.FetchFromZipCall.Returns.Manifest = `---
+applications:
+- name: "blah"
+  memory: 2G
+`
+
+				Expect(pusherCreator.SetUp()).To(MatchError(deployer.ResourceLimitError{"blah", "memory", "2G", "1G"}))
+			})
+
+			It("lets the push flags of the environment win over the manifest", func() {
+				pusherCreator.Environment.MaxDiskPerInstance = "1G"
+				pusherCreator.Environment.PushFlags = []string{"-k 512M"}
+				fetcher.FetchFromZipCall.Returns.Manifest = `---
+applications:
+- name: "blah"
+  disk_quota: 2G
+`
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+			})
+
+			It("succeeds when the manifest asks for the allowed instances", func() {
+				fetcher.FetchFromZipCall.Returns.Manifest = `---
+applications:
+- name: "blah"
+  instances: 10
+`
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Instances).To(Equal(uint16(10)))
+			})
+		})
+
+		Context("when the request overrides the manifest", func() {
+			var output *bytes.Buffer
+
+			BeforeEach(func() {
+				fetcher.FetchFromZipCall.Returns.AppPath = "newAppPath"
+				fetcher.FetchFromZipCall.Returns.Manifest = `---
+applications:
+- name: "blah"
+  memory: 512M
+  disk_quota: 1G
+  command: bin/start
+`
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+					AppName:     "blah",
+					ContentType: "ZIP",
+					Command:     "bin/start --worker",
+					PushFlags:   []string{"-m 1G", "-k 1024M"},
+				}
+				output = &bytes.Buffer{}
+				pusherCreator.DeployEventData.Response = output
+			})
+
+			It("warns about each overridden setting by default", func() {
+				pusherCreator.Environment.OverrideConflictPolicy = structs.OverrideConflictWarn
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(output.String()).To(ContainSubstring("WARNING: the request overrides the manifest of blah: command bin/start --worker instead of bin/start"))
+				Expect(output.String()).To(ContainSubstring("WARNING: the request overrides the manifest of blah: memory 1G instead of 512M"))
+				Expect(output.String()).ToNot(ContainSubstring("disk_quota"))
+			})
+
+			It("applies the request without a warning with the silent policy", func() {
+				pusherCreator.Environment.OverrideConflictPolicy = structs.OverrideConflictSilent
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(output.String()).ToNot(ContainSubstring("WARNING"))
+				Expect(logBuffer.String()).To(ContainSubstring("the request overrides the manifest of blah: memory 1G instead of 512M"))
+			})
+
+			It("returns an error with the reject policy", func() {
+				pusherCreator.Environment.OverrideConflictPolicy = structs.OverrideConflictReject
+
+				Expect(pusherCreator.SetUp()).To(MatchError(deployer.ManifestOverrideError{"blah", []string{
+					"command bin/start --worker instead of bin/start",
+					"memory 1G instead of 512M",
+				}}))
+			})
+
+			It("does not warn when the request sets the values of the manifest", func() {
+				pusherCreator.DeployEventData.DeploymentInfo.Command = "bin/start"
+				pusherCreator.DeployEventData.DeploymentInfo.PushFlags = []string{"-m 512M"}
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(output.String()).ToNot(ContainSubstring("WARNING"))
+			})
+		})
+
+		Context("when the environment streams zip artifacts", func() {
+			BeforeEach(func() {
+				pusherCreator.Environment.StreamZipArtifacts = true
+				fetcher.StageZipCall.Returns.AppPath = "stagedAppPath"
+				fetcher.StageZipCall.Returns.Manifest = `---
+applications:
+- name: "blah"
+`
+				fetcher.FetchFromZipCall.Returns.AppPath = "newAppPath"
+				fetcher.FetchFromZipCall.Returns.Manifest = fetcher.StageZipCall.Returns.Manifest
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{AppName: "blah", ContentType: "ZIP", Body: strings.NewReader("zip")}
+			})
+
+			It("stages the zip without extracting it", func() {
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.StageZipCall.Received.Request).To(Equal(strings.NewReader("zip")))
+				Expect(fetcher.FetchFromZipCall.Received.Request).To(BeNil())
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.AppPath).To(Equal("stagedAppPath"))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.ArtifactArchive).To(Equal(artifetcher.StagedArtifactFile))
+			})
+
+			It("extracts the zip when an artifact scanner is provided", func() {
+				pusherCreator.ArtifactScanner = &mocks.ArtifactScanner{}
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.StageZipCall.Received.Request).To(BeNil())
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.AppPath).To(Equal("newAppPath"))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.ArtifactArchive).To(BeEmpty())
+			})
+
+			It("extracts an artifact of another content type", func() {
+				pusherCreator.DeployEventData.DeploymentInfo.ArtifactType = "application/x-tar"
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.StageZipCall.Received.Request).To(BeNil())
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.ArtifactArchive).To(BeEmpty())
+			})
+		})
+
 		Context("contentType is ZIP", func() {
 
 			It("should extract manifest from the zip file", func() {
@@ -460,8 +664,425 @@ applications:
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("unzipping request body error: a test error"))
 			})
+
+			It("returns the error of an empty artifact as it is", func() {
+				fetcher.FetchFromZipCall.Returns.Error = artifetcher.EmptyArtifactError{}
+
+				deploymentInfo := structs.DeploymentInfo{
+					ContentType: "ZIP",
+				}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				err := pusherCreator.SetUp()
+				Expect(err).To(MatchError(artifetcher.EmptyArtifactError{}))
+			})
+		})
+
+		Context("contentType is MULTIPART", func() {
+			var fileSystem *afero.Afero
+
+			BeforeEach(func() {
+				fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+				pusherCreator.ManifestWriter = fileSystem
+
+				fetcher.FetchFromZipCall.Returns.AppPath = "newAppPath"
+				fetcher.FetchFromZipCall.Returns.Manifest = `---
+applications:
+- name: "blah"
+  instances: 2
+`
+			})
+
+			It("replaces the manifest of the artifact with the manifest part", func() {
+				manifest := `---
+applications:
+- name: "blah"
+  instances: 4
+`
+				deploymentInfo := structs.DeploymentInfo{
+					Manifest:    base64.StdEncoding.EncodeToString([]byte(manifest)),
+					ContentType: "MULTIPART",
+				}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				written, err := fileSystem.ReadFile("newAppPath/manifest.yml")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(written)).To(MatchYAML(manifest))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Instances).To(Equal(uint16(4)))
+			})
+
+			It("returns an error when the manifest part is not base64 encoded", func() {
+				deploymentInfo := structs.DeploymentInfo{
+					Manifest:    "not base64!",
+					ContentType: "MULTIPART",
+				}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				err := pusherCreator.SetUp()
+
+				Expect(err).To(MatchError(state.ManifestError{}))
+			})
+		})
+
+		Context("when the push sets environment variables", func() {
+			manifest := `---
+applications:
+- name: myApp
+  env:
+    LOG_LEVEL: debug
+`
+
+			BeforeEach(func() {
+				fetcher.FetchCall.Returns.AppPath = "newAppPath"
+				pusherCreator.EnvironmentVariables = map[string]string{"REGION": "east", "LOG_LEVEL": "warn"}
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+					AppName:     "myApp",
+					Manifest:    base64.StdEncoding.EncodeToString([]byte(manifest)),
+					ContentType: "JSON",
+					Env:         map[string]string{"LOG_LEVEL": "info", "API_TOKEN": "secret-token"},
+				}
+			})
+
+			It("pushes the manifest with the variables over the ones of the manifest", func() {
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.FetchCall.Received.Manifest).To(MatchYAML(`---
+applications:
+- name: myApp
+  env:
+    LOG_LEVEL: info
+    API_TOKEN: secret-token
+`))
+			})
+
+			It("lays the variables over the ones of the environment variable handler", func() {
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				event := eventManager.EmitEventCall.Received.Events[1].(ArtifactRetrievalSuccessEvent)
+				Expect(event.EnvironmentVariables).To(Equal(map[string]string{"REGION": "east", "LOG_LEVEL": "info", "API_TOKEN": "secret-token"}))
+			})
+
+			It("keeps the values out of the deployment info and the logs", func() {
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Manifest).To(MatchYAML(manifest))
+				Expect(logBuffer.String()).To(ContainSubstring("setting environment variables API_TOKEN, LOG_LEVEL from the push"))
+				Expect(logBuffer.String()).ToNot(ContainSubstring("secret-token"))
+			})
+
+			It("creates a manifest when the push has none", func() {
+				pusherCreator.DeployEventData.DeploymentInfo.Manifest = ""
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.FetchCall.Received.Manifest).To(MatchYAML(`---
+applications:
+- name: myApp
+  env:
+    LOG_LEVEL: info
+    API_TOKEN: secret-token
+`))
+			})
+		})
+
+		Context("when the environment has a default manifest", func() {
+			var fileSystem *afero.Afero
+
+			BeforeEach(func() {
+				fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+				pusherCreator.ManifestWriter = fileSystem
+
+				pusherCreator.Environment.DefaultManifest = `---
+applications:
+- memory: 1G
+  instances: 3
+  env:
+    LOG_LEVEL: info
+    REGION: east
+  services:
+  - logging
+`
+			})
+
+			It("uses the default manifest when the request has none", func() {
+				deploymentInfo := structs.DeploymentInfo{ContentType: "JSON"}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.FetchCall.Received.Manifest).To(Equal(pusherCreator.Environment.DefaultManifest))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Instances).To(Equal(uint16(3)))
+			})
+
+			It("merges the request manifest over the default manifest", func() {
+				manifest := `---
+applications:
+- name: my-app
+  instances: 2
+  env:
+    LOG_LEVEL: debug
+    FEATURE: "on"
+  services:
+  - database
+`
+				deploymentInfo := structs.DeploymentInfo{
+					Manifest:    base64.StdEncoding.EncodeToString([]byte(manifest)),
+					ContentType: "JSON",
+				}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.FetchCall.Received.Manifest).To(MatchYAML(`---
+applications:
+- name: my-app
+  memory: 1G
+  instances: 2
+  env:
+    LOG_LEVEL: debug
+    REGION: east
+    FEATURE: "on"
+  services:
+  - database
+`))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Instances).To(Equal(uint16(2)))
+			})
+
+			It("merges the zip manifest over the default manifest and writes it to the app path", func() {
+				fetcher.FetchFromZipCall.Returns.AppPath = "newAppPath"
+				fetcher.FetchFromZipCall.Returns.Manifest = `---
+applications:
+- name: my-app
+  env:
+    REGION: west
+`
+				deploymentInfo := structs.DeploymentInfo{ContentType: "ZIP"}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				expected := `---
+applications:
+- name: my-app
+  memory: 1G
+  instances: 3
+  env:
+    LOG_LEVEL: info
+    REGION: west
+  services:
+  - logging
+`
+				written, err := fileSystem.ReadFile("newAppPath/manifest.yml")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(written)).To(MatchYAML(expected))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Manifest).To(MatchYAML(expected))
+			})
+
+			It("returns an error when the request manifest cannot be merged", func() {
+				deploymentInfo := structs.DeploymentInfo{
+					Manifest:    base64.StdEncoding.EncodeToString([]byte("- not a map")),
+					ContentType: "JSON",
+				}
+				pusherCreator.DeployEventData.DeploymentInfo = &deploymentInfo
+
+				err := pusherCreator.SetUp()
+
+				Expect(err).To(BeAssignableToTypeOf(state.DefaultManifestError{}))
+				Expect(eventManager.EmitEventCall.Received.Events).To(BeEmpty())
+			})
+		})
+
+
+		Context("when the push applies a profile", func() {
+			profileManifest := `---
+applications:
+- instances: 4
+  memory: 2G
+`
+
+			It("uses the profile manifest when the request has none", func() {
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+					ContentType:     "JSON",
+					Profile:         "large",
+					ProfileManifest: profileManifest,
+				}
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.FetchCall.Received.Manifest).To(Equal(profileManifest))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Instances).To(Equal(uint16(4)))
+			})
+
+			It("merges the request manifest over the profile manifest and the profile over the default manifest", func() {
+				pusherCreator.Environment.DefaultManifest = `---
+applications:
+- instances: 1
+  memory: 512M
+  stack: cflinuxfs3
+`
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+					Manifest:        base64.StdEncoding.EncodeToString([]byte("---\napplications:\n- name: my-app\n  memory: 4G\n")),
+					ContentType:     "JSON",
+					Profile:         "large",
+					ProfileManifest: profileManifest,
+				}
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(fetcher.FetchCall.Received.Manifest).To(MatchYAML(`---
+applications:
+- name: my-app
+  instances: 4
+  memory: 4G
+  stack: cflinuxfs3
+`))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Instances).To(Equal(uint16(4)))
+			})
+		})
+
+		Context("when a manifest transformer is provided", func() {
+			var (
+				transformer *mocks.ManifestTransformer
+				fileSystem  *afero.Afero
+				manifest    = `---
+applications:
+- name: my-app
+  instances: 2
+`
+				transformed = map[interface{}]interface{}{
+					"applications": []interface{}{
+						map[interface{}]interface{}{
+							"name":      "my-app",
+							"instances": 2,
+							"sidecars":  []interface{}{map[interface{}]interface{}{"name": "proxy"}},
+						},
+					},
+				}
+			)
+
+			BeforeEach(func() {
+				transformer = &mocks.ManifestTransformer{}
+				transformer.TransformCall.Returns.Manifest = transformed
+				pusherCreator.ManifestTransformer = transformer
+
+				fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+				pusherCreator.ManifestWriter = fileSystem
+			})
+
+			It("pushes the transformed manifest of a JSON request", func() {
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+					AppName:     "my-app",
+					Manifest:    base64.StdEncoding.EncodeToString([]byte(manifest)),
+					ContentType: "JSON",
+				}
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(transformer.TransformCall.Received.Manifest["applications"]).To(HaveLen(1))
+				Expect(transformer.TransformCall.Received.DeploymentInfo.AppName).To(Equal("my-app"))
+				Expect(fetcher.FetchCall.Received.Manifest).To(ContainSubstring("proxy"))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Manifest).To(ContainSubstring("proxy"))
+			})
+
+			It("writes the transformed manifest of a zip request to the app path", func() {
+				fetcher.FetchFromZipCall.Returns.AppPath = "newAppPath"
+				fetcher.FetchFromZipCall.Returns.Manifest = manifest
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{ContentType: "ZIP"}
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				written, err := fileSystem.ReadFile("newAppPath/manifest.yml")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(written)).To(ContainSubstring("proxy"))
+			})
+
+			It("leaves the manifest as it is when the transformer does not change it", func() {
+				transformer.TransformCall.Returns.Manifest = map[interface{}]interface{}{
+					"applications": []interface{}{
+						map[interface{}]interface{}{"name": "my-app", "instances": 2},
+					},
+				}
+				fetcher.FetchFromZipCall.Returns.AppPath = "newAppPath"
+				fetcher.FetchFromZipCall.Returns.Manifest = manifest
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{ContentType: "ZIP"}
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.Manifest).To(Equal(manifest))
+				exists, _ := fileSystem.Exists("newAppPath/manifest.yml")
+				Expect(exists).To(BeFalse())
+			})
+
+			It("aborts the deploy when the transformer fails", func() {
+				transformer.TransformCall.Returns.Error = errors.New("sidecar not allowed")
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+					Manifest:    base64.StdEncoding.EncodeToString([]byte(manifest)),
+					ContentType: "JSON",
+				}
+
+				err := pusherCreator.SetUp()
+
+				Expect(err).To(BeAssignableToTypeOf(state.ManifestTransformError{}))
+				Expect(err.Error()).To(Equal("the manifest transformer failed: sidecar not allowed"))
+				Expect(fetcher.FetchCall.Received.ArtifactURL).To(BeEmpty())
+				Expect(eventManager.EmitEventCall.Received.Events).To(BeEmpty())
+			})
 		})
 
+		Context("when an artifact scanner is provided", func() {
+			var scanner *mocks.ArtifactScanner
+
+			BeforeEach(func() {
+				scanner = &mocks.ArtifactScanner{}
+				pusherCreator.ArtifactScanner = scanner
+
+				fetcher.FetchCall.Returns.AppPath = "newAppPath"
+				pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+					AppName:     "my-app",
+					ArtifactURL: "https://artifacts.example.com/app.zip",
+					ContentType: "JSON",
+				}
+			})
+
+			It("scans the extracted artifact before it is pushed", func() {
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(scanner.ScanCall.TimesCalled).To(Equal(1))
+				Expect(scanner.ScanCall.Received.Path).To(Equal("newAppPath"))
+				Expect(scanner.ScanCall.Received.DeploymentInfo.AppName).To(Equal("my-app"))
+			})
+
+			It("writes the warnings of the scan to the response", func() {
+				scanner.ScanCall.Returns.Result = interfaces.ScanResult{Warnings: []string{"lodash 4.17.4 has known vulnerabilities"}}
+
+				Expect(pusherCreator.SetUp()).To(Succeed())
+
+				Expect(response).To(Say("artifact scan warning: lodash 4.17.4 has known vulnerabilities"))
+			})
+
+			It("aborts the deploy with a ScanFailedError when the scan has findings", func() {
+				scanner.ScanCall.Returns.Result = interfaces.ScanResult{Findings: []string{"AWS secret key in config/app.yml", "EICAR test file in lib/eicar.com"}}
+
+				err := pusherCreator.SetUp()
+
+				Expect(err).To(MatchError(deployer.ScanFailedError{[]string{"AWS secret key in config/app.yml", "EICAR test file in lib/eicar.com"}}))
+				Expect(err.Error()).To(Equal("the artifact scan failed: AWS secret key in config/app.yml; EICAR test file in lib/eicar.com"))
+				Expect(fileSystemCleaner.RemoveAllCall.Received.Path).To(Equal("newAppPath"))
+				Expect(pusherCreator.DeployEventData.DeploymentInfo.AppPath).To(BeEmpty())
+			})
+
+			It("aborts the deploy when the scanner fails", func() {
+				scanErr := errors.New("scanner unavailable")
+				scanner.ScanCall.Returns.Error = scanErr
+
+				err := pusherCreator.SetUp()
+
+				Expect(err).To(MatchError(state.ArtifactScanError{scanErr}))
+				Expect(fileSystemCleaner.RemoveAllCall.Received.Path).To(Equal("newAppPath"))
+			})
+		})
 	})
 
 	Describe("OnStart", func() {
@@ -621,6 +1242,20 @@ applications:
 
 			Expect(exists).ToNot(BeTrue())
 		})
+		It("does not forget a running deploy that has the same UUID", func() {
+			cancellations := deployer.NewCancellations()
+			running := cancellations.Register("my-uuid", "", "", "", "myApp")
+
+			pusherCreator.Cancellations = cancellations
+			pusherCreator.DeployEventData.DeploymentInfo.UUID = "my-uuid"
+			pusherCreator.DeployEventData.DeploymentInfo.AppName = "myApp"
+			pusherCreator.DeployEventData.DeploymentInfo.ContentType = "ZIP"
+			pusherCreator.SetUp()
+			pusherCreator.CleanUp()
+
+			Expect(cancellations.Cancel("my-uuid", "", "", "", "myApp")).To(Succeed())
+			Expect(running.Canceled()).To(BeTrue())
+		})
 	})
 
 	Describe("OnFinish", func() {
@@ -688,5 +1323,155 @@ applications:
 				Eventually(string(logBytes)).Should(ContainSubstring("Your deploy was successful!"))
 			})
 		})
+		Context("when the deploy was canceled", func() {
+			It("reports the cancellation and emits a deploy canceled event", func() {
+				cancellations := deployer.NewCancellations()
+				pusherCreator.Cancellations = cancellations
+				pusherCreator.CourierCreator = courierCreator{}
+				pusherCreator.DeployEventData.DeploymentInfo.UUID = "my-uuid"
+				pusherCreator.DeployEventData.DeploymentInfo.AppName = "myApp"
+				pusherCreator.DeployEventData.DeploymentInfo.ContentType = "ZIP"
+				pusherCreator.SetUp()
+
+				action, err := pusherCreator.Create(structs.Environment{}, response, "https://foundation")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(action.(*Pusher).Cancellation).ToNot(BeNil())
+
+				Expect(cancellations.Cancel("my-uuid", "", "", "", "myApp")).To(Succeed())
+
+				resp := pusherCreator.OnFinish(structs.Environment{EnableRollback: false}, response, errors.New("push failed"))
+
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(resp.Error).To(MatchError(state.DeployCanceledError{"myApp"}))
+				Expect(eventManager.EmitCall.Received.Events[len(eventManager.EmitCall.Received.Events)-1].Type).To(Equal(constants.DeployCanceledEvent))
+				Expect(eventManager.EmitEventCall.Received.Events[len(eventManager.EmitEventCall.Received.Events)-1]).To(BeAssignableToTypeOf(DeployCanceledEvent{}))
+
+				output, _ := ioutil.ReadAll(response)
+				Expect(string(output)).To(ContainSubstring("Your deploy was canceled"))
+			})
+		})
+		Context("when the deploy waits for a manual cutover", func() {
+			It("records the pending promotion", func() {
+				promotions := NewPromotions()
+				pusherCreator.Promotions = promotions
+				pusherCreator.DeployEventData.DeploymentInfo.UUID = "my-uuid"
+				env := structs.Environment{EnableRollback: true, ManualCutover: true}
+
+				resp := pusherCreator.OnFinish(env, response, nil)
+
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				promotion, ok := promotions.Get("my-uuid")
+				Expect(ok).To(BeTrue())
+				Expect(promotion.AppName).To(Equal(pusherCreator.DeployEventData.DeploymentInfo.AppName))
+
+				output, _ := ioutil.ReadAll(response)
+				Expect(string(output)).To(ContainSubstring("waiting for promotion"))
+				Expect(string(output)).ToNot(ContainSubstring("Your deploy was successful!"))
+			})
+		})
+	})
+
+	Describe("multi-application manifests", func() {
+		var fileSystem *afero.Afero
+
+		manifest := `---
+buildpack: java_buildpack
+applications:
+- name: frontend
+  instances: 2
+- name: backend
+`
+
+		BeforeEach(func() {
+			fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+			pusherCreator.ManifestWriter = fileSystem
+			pusherCreator.Environment.Instances = 4
+
+			fetcher.FetchCall.Returns.AppPath = "newAppPath"
+			pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+				Manifest:        base64.StdEncoding.EncodeToString([]byte(manifest)),
+				ContentType:     "JSON",
+				AllApplications: true,
+			}
+		})
+
+		It("writes a manifest for each application", func() {
+			Expect(pusherCreator.SetUp()).To(Succeed())
+
+			Expect(pusherCreator.DeployEventData.DeploymentInfo.Applications).To(Equal([]string{"frontend", "backend"}))
+
+			written, err := fileSystem.ReadFile("newAppPath/deployadactyl-manifest-1.yml")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(written)).To(MatchYAML(`---
+buildpack: java_buildpack
+applications:
+- name: backend
+`))
+		})
+
+		It("returns an error when an application asks for more instances than the environment allows", func() {
+			pusherCreator.Environment.MaxInstances = 3
+
+			Expect(pusherCreator.SetUp()).To(MatchError(deployer.TooManyInstancesError{"backend", 4, 3}))
+		})
+
+		It("returns an error when the manifest has no named applications", func() {
+			pusherCreator.DeployEventData.DeploymentInfo.Manifest = base64.StdEncoding.EncodeToString([]byte("---\napplications:\n- instances: 2\n"))
+
+			Expect(pusherCreator.SetUp()).To(MatchError(state.NoApplicationsError{}))
+		})
+
+		It("creates a pusher for each application", func() {
+			Expect(pusherCreator.SetUp()).To(Succeed())
+			pusherCreator.CourierCreator = courierCreator{}
+
+			action, err := pusherCreator.Create(pusherCreator.Environment, response, "https://api.example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			multiPusher := action.(*MultiPusher)
+			Expect(multiPusher.Pushers).To(HaveLen(2))
+			Expect(multiPusher.Pushers[0].DeploymentInfo.AppName).To(Equal("frontend"))
+			Expect(multiPusher.Pushers[0].DeploymentInfo.Instances).To(Equal(uint16(2)))
+			Expect(multiPusher.Pushers[0].ManifestFile).To(Equal("deployadactyl-manifest-0.yml"))
+			Expect(multiPusher.Pushers[1].DeploymentInfo.AppName).To(Equal("backend"))
+			Expect(multiPusher.Pushers[1].DeploymentInfo.Instances).To(Equal(uint16(4)))
+			Expect(multiPusher.Pushers[1].ManifestFile).To(Equal("deployadactyl-manifest-1.yml"))
+		})
+
+		It("prints the applications when it starts", func() {
+			Expect(pusherCreator.SetUp()).To(Succeed())
+			Expect(pusherCreator.OnStart()).To(Succeed())
+
+			Expect(response).To(Say("AppName:      frontend, backend"))
+		})
+
+		It("writes the status of each application when it finishes", func() {
+			Expect(pusherCreator.SetUp()).To(Succeed())
+
+			pusherCreator.OnFinish(structs.Environment{EnableRollback: true}, response, errors.New("push failed"))
+
+			Expect(response).To(Say("Applications:\n  frontend: not pushed\n  backend: not pushed\n"))
+		})
+
+		It("reports a partial deploy when some applications failed with the continue failure policy", func() {
+			pusherCreator.DeployEventData.DeploymentInfo.FailurePolicy = structs.FailurePolicyContinue
+			Expect(pusherCreator.SetUp()).To(Succeed())
+			pusherCreator.CourierCreator = courierCreator{}
+
+			action, err := pusherCreator.Create(pusherCreator.Environment, response, "https://api.example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			multiPusher := action.(*MultiPusher)
+			Expect(multiPusher.FailurePolicy).To(Equal(structs.FailurePolicyContinue))
+			multiPusher.Statuses.Set("frontend", ApplicationDeployed)
+			multiPusher.Statuses.Set("backend", ApplicationFailed)
+
+			resp := pusherCreator.OnFinish(structs.Environment{EnableRollback: true}, response, nil)
+
+			Expect(resp.StatusCode).To(Equal(http.StatusMultiStatus))
+			Expect(resp.Error).To(MatchError(state.ApplicationsFailedError{[]string{"backend"}}))
+			Expect(response).To(Say("Applications:\n  frontend: deployed\n  backend: failed\n"))
+			Expect(response).To(Say("Only some of the applications were deployed."))
+		})
 	})
 })
@@ -0,0 +1,45 @@
+package push
+
+import (
+	"regexp"
+
+	"github.com/compozed/deployadactyl/state"
+)
+
+// authExpiredPattern matches the output of a cf command that failed because the token of the session expired.
+var authExpiredPattern = regexp.MustCompile(`(?i)authentication has expired|invalid auth token|not logged in|(error|status) code: 401`)
+
+// withReauth runs a Cloud Foundry command. When the command fails because the token of the session expired during
+// a long deploy, it logs into the foundation again with the credentials of the deploy and runs the command once more.
+// It returns an AuthExpiredError when the login fails.
+func (p Pusher) withReauth(command func() ([]byte, error)) ([]byte, error) {
+	output, err := command()
+	if err == nil || !authExpiredPattern.Match(output) {
+		return output, err
+	}
+
+	p.Log.Infof("cloud foundry token for %s expired, logging in again", p.FoundationURL)
+
+	loginOutput, err := p.Courier.Login(
+		p.FoundationURL,
+		p.DeploymentInfo.Username,
+		p.DeploymentInfo.Password,
+		p.DeploymentInfo.Org,
+		p.DeploymentInfo.Space,
+		p.DeploymentInfo.SkipSSL,
+	)
+	if err != nil {
+		p.Log.Errorf("could not login to %s again", p.FoundationURL)
+		return append(output, loginOutput...), state.AuthExpiredError{p.FoundationURL, loginOutput}
+	}
+
+	p.Log.Infof("logged into cloud foundry %s again", p.FoundationURL)
+
+	return command()
+}
+
+// isAuthExpired reports whether err is an AuthExpiredError, which is returned as is instead of the error of the step.
+func isAuthExpired(err error) bool {
+	_, ok := err.(state.AuthExpiredError)
+	return ok
+}
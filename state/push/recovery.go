@@ -0,0 +1,210 @@
+package push
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/compozed/deployadactyl/constants"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// MaxRecoveryAttempts is how many starts of Deployadactyl try to recover an interrupted push before its candidate
+// application is left for a manual clean up.
+const MaxRecoveryAttempts = 5
+
+// Recovery cleans up after the deploys that were interrupted by a restart of Deployadactyl, from the checkpoints left
+// in their Journal. A push interrupted before its swap has its candidate application deleted. A push interrupted
+// during its swap has the swap resumed, since its candidate application already passed its health check, and the
+// application it replaces is deleted right away.
+//
+// A deploy whose pushes all finished, by resuming their swap or because their swap had already finished, is marked
+// successful with a deploy.success event. Any other deploy is marked failed with a deploy.failure event. A deploy
+// whose recovery fails keeps its checkpoints and is recovered again on the next start, up to MaxRecoveryAttempts
+// times.
+type Recovery struct {
+	Journal        *Journal
+	CourierCreator courierCreator
+	EventManager   I.EventManager
+	Log            I.Logger
+
+	// Credentials returns the credentials Deployadactyl logs in to the foundations of an environment with. Pushes
+	// in environments without credentials are left for a manual clean up.
+	Credentials func(environment string) (username, password string, ok bool)
+}
+
+// Recover recovers every deploy with a checkpoint in the Journal. The checkpoints of a deploy are removed once every
+// push of the deploy is recovered or has run out of attempts.
+func (r Recovery) Recover() {
+	checkpoints, err := r.Journal.Checkpoints()
+	if err != nil {
+		r.Log.Error(err)
+		return
+	}
+
+	if len(checkpoints) == 0 {
+		return
+	}
+
+	r.Log.Infof("recovering %d pushes interrupted by a restart", len(checkpoints))
+
+	// The checkpoints are ordered by the UUID of their deploy.
+	for len(checkpoints) > 0 {
+		end := 1
+		for end < len(checkpoints) && checkpoints[end].UUID == checkpoints[0].UUID {
+			end++
+		}
+
+		r.recoverDeploy(checkpoints[:end])
+		checkpoints = checkpoints[end:]
+	}
+}
+
+// recoverDeploy recovers the pushes of a deploy. Recovering a push again is safe, so when the recovery of a push
+// fails, the checkpoints of the other pushes are kept as well and the outcome of the deploy is decided on a later
+// start.
+func (r Recovery) recoverDeploy(checkpoints []Checkpoint) {
+	var interrupted *Checkpoint
+	retry := false
+
+	for i := range checkpoints {
+		checkpoint := checkpoints[i]
+
+		finished, err := r.recover(checkpoint)
+		if err != nil {
+			checkpoint.RecoveryAttempts++
+			if checkpoint.RecoveryAttempts < MaxRecoveryAttempts {
+				r.Log.Errorf("%s, it is recovered again when Deployadactyl starts", err)
+				r.Journal.Record(checkpoint)
+				retry = true
+				continue
+			}
+
+			r.Log.Errorf("%s, giving up after %d attempts, %s is left for a manual clean up", err, checkpoint.RecoveryAttempts, checkpoint.CandidateApp)
+		}
+
+		if !finished && interrupted == nil {
+			interrupted = &checkpoints[i]
+		}
+	}
+
+	if retry {
+		return
+	}
+
+	for _, checkpoint := range checkpoints {
+		r.Journal.Remove(checkpoint.UUID, checkpoint.FoundationURL)
+	}
+
+	if interrupted != nil {
+		r.markFailed(*interrupted)
+		return
+	}
+
+	r.markSucceeded(checkpoints[0])
+}
+
+// recover cleans up after an interrupted push. It returns true when the push finished, either because its swap was
+// resumed or because its swap had already finished before the restart. It returns a RecoveryError when the push
+// could not be recovered.
+func (r Recovery) recover(checkpoint Checkpoint) (bool, error) {
+	candidate := checkpoint.CandidateApp
+
+	username, password, ok := r.Credentials(checkpoint.Environment)
+	if !ok {
+		return false, state.RecoveryError{checkpoint.UUID, checkpoint.FoundationURL, "there are no credentials for environment " + checkpoint.Environment}
+	}
+
+	courier, err := r.CourierCreator.CreateCourier()
+	if err != nil {
+		return false, state.RecoveryError{checkpoint.UUID, checkpoint.FoundationURL, err.Error()}
+	}
+	defer courier.CleanUp()
+
+	out, err := courier.Login(checkpoint.FoundationURL, username, password, checkpoint.Org, checkpoint.Space, checkpoint.SkipSSL)
+	if err != nil {
+		return false, state.RecoveryError{checkpoint.UUID, checkpoint.FoundationURL, "login failed: " + string(out)}
+	}
+
+	if !courier.Exists(candidate) {
+		if checkpoint.Phase == SwapPhase {
+			r.Log.Infof("recovered deploy %s on %s: %s was already renamed to %s", checkpoint.UUID, checkpoint.FoundationURL, candidate, checkpoint.AppName)
+			return true, nil
+		}
+
+		r.Log.Infof("recovered deploy %s on %s: %s does not exist, nothing to clean up", checkpoint.UUID, checkpoint.FoundationURL, candidate)
+		return false, nil
+	}
+
+	pusher := Pusher{
+		Courier: courier,
+		DeploymentInfo: S.DeploymentInfo{
+			UUID:        checkpoint.UUID,
+			Environment: checkpoint.Environment,
+			Org:         checkpoint.Org,
+			Space:       checkpoint.Space,
+			AppName:     checkpoint.AppName,
+			Domain:      checkpoint.Domain,
+			SkipSSL:     checkpoint.SkipSSL,
+			Username:    username,
+			Password:    password,
+		},
+		Response:      &bytes.Buffer{},
+		Log:           I.DeploymentLogger{Log: r.Log, UUID: checkpoint.UUID},
+		FoundationURL: checkpoint.FoundationURL,
+	}
+
+	if checkpoint.Phase == SwapPhase {
+		err = pusher.swap()
+		if err != nil {
+			return false, state.RecoveryError{checkpoint.UUID, checkpoint.FoundationURL, "cannot resume the swap: " + err.Error()}
+		}
+
+		r.Log.Infof("recovered deploy %s on %s: resumed the swap of %s to %s", checkpoint.UUID, checkpoint.FoundationURL, candidate, checkpoint.AppName)
+		return true, nil
+	}
+
+	err = pusher.deleteApplication(candidate)
+	if err != nil {
+		return false, state.RecoveryError{checkpoint.UUID, checkpoint.FoundationURL, fmt.Sprintf("cannot delete %s: %s", candidate, err)}
+	}
+
+	r.Log.Infof("recovered deploy %s on %s: deleted %s interrupted during the %s phase", checkpoint.UUID, checkpoint.FoundationURL, candidate, checkpoint.Phase)
+	return false, nil
+}
+
+// markFailed emits a deploy.failure event for the interrupted deploy. Its deploy output was lost with the restart,
+// so it has an empty Response.
+func (r Recovery) markFailed(checkpoint Checkpoint) {
+	r.emit(checkpoint, I.Event{
+		Type:  constants.DeployFailureEvent,
+		Data:  recoveredEventData(checkpoint),
+		Error: state.InterruptedDeployError{checkpoint.UUID, checkpoint.Phase},
+	})
+}
+
+// markSucceeded emits a deploy.success event for the interrupted deploy whose pushes all finished.
+func (r Recovery) markSucceeded(checkpoint Checkpoint) {
+	r.emit(checkpoint, I.Event{Type: constants.DeploySuccessEvent, Data: recoveredEventData(checkpoint)})
+}
+
+func (r Recovery) emit(checkpoint Checkpoint, event I.Event) {
+	err := r.EventManager.Emit(event)
+	if err != nil {
+		r.Log.Errorf("cannot emit the %s event of recovered deploy %s: %s", event.Type, checkpoint.UUID, err)
+	}
+}
+
+func recoveredEventData(checkpoint Checkpoint) *S.DeployEventData {
+	return &S.DeployEventData{
+		Response: &bytes.Buffer{},
+		DeploymentInfo: &S.DeploymentInfo{
+			UUID:        checkpoint.UUID,
+			Environment: checkpoint.Environment,
+			Org:         checkpoint.Org,
+			Space:       checkpoint.Space,
+			AppName:     checkpoint.AppName,
+		},
+	}
+}
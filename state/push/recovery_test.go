@@ -0,0 +1,194 @@
+package push_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/constants"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/push"
+	"github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Recovery", func() {
+	var (
+		recovery     Recovery
+		journal      *Journal
+		courier      *mocks.Courier
+		eventManager *mocks.EventManager
+		logBuffer    *Buffer
+		checkpoint   Checkpoint
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		courier = &mocks.Courier{}
+		eventManager = &mocks.EventManager{}
+		logBuffer = NewBuffer()
+		log := interfaces.DefaultLogger(logBuffer, logging.DEBUG, "recovery_test")
+
+		journal, err = NewJournal(&afero.Afero{Fs: afero.NewMemMapFs()}, "/journal", log)
+		Expect(err).ToNot(HaveOccurred())
+
+		recovery = Recovery{
+			Journal:        journal,
+			CourierCreator: courierCreator{CourierCreatorFn: func() (interfaces.Courier, error) { return courier, nil }},
+			EventManager:   eventManager,
+			Log:            log,
+			Credentials: func(environment string) (string, string, bool) {
+				return "my-user", "my-password", environment == "production"
+			},
+		}
+
+		checkpoint = Checkpoint{
+			UUID:          "my-uuid",
+			Environment:   "production",
+			Org:           "my-org",
+			Space:         "my-space",
+			AppName:       "myApp",
+			FoundationURL: "https://foundation",
+			Phase:         HealthCheckPhase,
+			CandidateApp:  "myApp-new-build-my-uuid",
+		}
+	})
+
+	It("deletes the candidate application of a push interrupted before its swap", func() {
+		courier.ExistsCall.Returns.Bool = true
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(courier.LoginCall.Received.FoundationURL).To(Equal("https://foundation"))
+		Expect(courier.LoginCall.Received.Username).To(Equal("my-user"))
+		Expect(courier.LoginCall.Received.Org).To(Equal("my-org"))
+		Expect(courier.LoginCall.Received.Space).To(Equal("my-space"))
+		Expect(courier.DeleteCall.Received.AppName).To(Equal("myApp-new-build-my-uuid"))
+		Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+		Expect(logBuffer).To(Say("recovered deploy my-uuid on https://foundation: deleted myApp-new-build-my-uuid interrupted during the healthcheck phase"))
+	})
+
+	It("resumes the swap of a push interrupted during its swap", func() {
+		courier.ExistsCall.Returns.Bool = true
+		checkpoint.Phase = SwapPhase
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(courier.DeleteCall.Received.AppName).To(Equal("myApp"))
+		Expect(courier.RenameCall.Received.AppName).To(Equal("myApp-new-build-my-uuid"))
+		Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal("myApp"))
+		Expect(logBuffer).To(Say("resumed the swap of myApp-new-build-my-uuid to myApp"))
+	})
+
+	It("marks a deploy successful when its swap is resumed", func() {
+		courier.ExistsCall.Returns.Bool = true
+		checkpoint.Phase = SwapPhase
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+		event := eventManager.EmitCall.Received.Events[0]
+		Expect(event.Type).To(Equal(constants.DeploySuccessEvent))
+		Expect(event.Error).ToNot(HaveOccurred())
+		Expect(event.Data.(*structs.DeployEventData).DeploymentInfo.UUID).To(Equal("my-uuid"))
+	})
+
+	It("marks a deploy successful when its swap finished before the restart", func() {
+		checkpoint.Phase = SwapPhase
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+		Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+		Expect(logBuffer).To(Say("myApp-new-build-my-uuid was already renamed to myApp"))
+		Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+		Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal(constants.DeploySuccessEvent))
+	})
+
+	It("leaves the foundation alone when the candidate application does not exist", func() {
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+		Expect(logBuffer).To(Say("myApp-new-build-my-uuid does not exist, nothing to clean up"))
+	})
+
+	It("marks each interrupted deploy failed once", func() {
+		journal.Record(checkpoint)
+		other := checkpoint
+		other.FoundationURL = "https://other-foundation"
+		journal.Record(other)
+
+		recovery.Recover()
+
+		Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+		event := eventManager.EmitCall.Received.Events[0]
+		Expect(event.Type).To(Equal(constants.DeployFailureEvent))
+		Expect(event.Error).To(MatchError(state.InterruptedDeployError{"my-uuid", HealthCheckPhase}))
+		Expect(event.Data.(*structs.DeployEventData).DeploymentInfo.AppName).To(Equal("myApp"))
+	})
+
+	It("removes the checkpoints it recovered", func() {
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(journal.Checkpoints()).To(BeEmpty())
+	})
+
+	It("keeps the checkpoint of a push without credentials for its environment", func() {
+		courier.ExistsCall.Returns.Bool = true
+		checkpoint.Environment = "staging"
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(courier.LoginCall.Received.FoundationURL).To(BeEmpty())
+		Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+		Expect(logBuffer).To(Say("cannot recover deploy my-uuid on https://foundation: there are no credentials for environment staging, it is recovered again when Deployadactyl starts"))
+		Expect(eventManager.EmitCall.Received.Events).To(BeEmpty())
+
+		checkpoints, _ := journal.Checkpoints()
+		Expect(checkpoints).To(HaveLen(1))
+		Expect(checkpoints[0].RecoveryAttempts).To(Equal(1))
+	})
+
+	It("does not clean up when it cannot log in", func() {
+		courier.ExistsCall.Returns.Bool = true
+		courier.LoginCall.Returns.Error = errors.New("login failed")
+		courier.LoginCall.Returns.Output = []byte("bad credentials")
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+		Expect(logBuffer).To(Say("cannot recover deploy my-uuid on https://foundation: login failed: bad credentials"))
+		Expect(eventManager.EmitCall.Received.Events).To(BeEmpty())
+		Expect(journal.Checkpoints()).To(HaveLen(1))
+	})
+
+	It("leaves the candidate application for a manual clean up after the last attempt", func() {
+		courier.ExistsCall.Returns.Bool = true
+		courier.LoginCall.Returns.Error = errors.New("login failed")
+		courier.LoginCall.Returns.Output = []byte("bad credentials")
+		checkpoint.RecoveryAttempts = MaxRecoveryAttempts - 1
+		journal.Record(checkpoint)
+
+		recovery.Recover()
+
+		Expect(logBuffer).To(Say("giving up after 5 attempts, myApp-new-build-my-uuid is left for a manual clean up"))
+		Expect(journal.Checkpoints()).To(BeEmpty())
+		Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+		Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal(constants.DeployFailureEvent))
+	})
+})
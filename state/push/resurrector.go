@@ -0,0 +1,86 @@
+package push
+
+import (
+	"github.com/compozed/deployadactyl/state"
+)
+
+// Resurrector rolls back a deploy on a single foundation. The application replaced by the deploy is started
+// again, the deployed application is deleted and the replaced application takes back its name.
+type Resurrector struct {
+	Pusher
+}
+
+// Execute starts the replaced application and maps the load balanced route to it.
+func (r *Resurrector) Execute() error {
+	venerable := r.venerable()
+
+	if !r.Courier.Exists(venerable) {
+		r.Log.Errorf("%s does not exist on %s", venerable, r.FoundationURL)
+		return state.VenerableNotFoundError{venerable, r.FoundationURL}
+	}
+
+	r.Log.Debugf("starting %s", venerable)
+	out, err := r.Courier.Start(venerable)
+	r.Response.Write(out)
+	if err != nil {
+		r.Log.Errorf("could not start %s", venerable)
+		return state.StartError{venerable, out}
+	}
+	r.Log.Infof("started %s", venerable)
+
+	if r.DeploymentInfo.Domain != "" {
+		return r.mapTempAppToLoadBalancedDomain(venerable)
+	}
+
+	return nil
+}
+
+// Success deletes the deployed application and renames the replaced application to the application name.
+func (r *Resurrector) Success() error {
+	venerable := r.venerable()
+
+	if r.Courier.Exists(r.DeploymentInfo.AppName) {
+		err := r.deleteApplication(r.DeploymentInfo.AppName)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.Log.Debugf("renaming %s to %s", venerable, r.DeploymentInfo.AppName)
+	out, err := r.Courier.Rename(venerable, r.DeploymentInfo.AppName)
+	if err != nil {
+		r.Log.Errorf("could not rename %s to %s", venerable, r.DeploymentInfo.AppName)
+		return state.RenameError{venerable, out}
+	}
+	r.Log.Infof("renamed %s to %s", venerable, r.DeploymentInfo.AppName)
+
+	return nil
+}
+
+// Undo unmaps the load balanced route from the replaced application and stops it again, leaving both
+// applications as they were before the rollback.
+func (r *Resurrector) Undo() error {
+	venerable := r.venerable()
+
+	r.Log.Errorf("undoing rollback to %s", venerable)
+
+	if r.DeploymentInfo.Domain != "" {
+		out, err := r.Courier.UnmapRoute(venerable, r.DeploymentInfo.Domain, r.DeploymentInfo.AppName)
+		if err != nil {
+			r.Log.Errorf("could not unmap %s.%s from %s", r.DeploymentInfo.AppName, r.DeploymentInfo.Domain, venerable)
+			return state.UnmapRouteError{venerable, out}
+		}
+	}
+
+	out, err := r.Courier.Stop(venerable)
+	if err != nil {
+		r.Log.Errorf("could not stop %s", venerable)
+		return state.StopError{venerable, out}
+	}
+
+	return nil
+}
+
+func (r *Resurrector) venerable() string {
+	return r.DeploymentInfo.AppName + VenerableNameSuffix + r.DeploymentInfo.UUID
+}
@@ -0,0 +1,106 @@
+package push_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Resurrector", func() {
+	var (
+		resurrector *Resurrector
+		courier     *mocks.Courier
+		logBuffer   *Buffer
+		venerable   string
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		logBuffer = NewBuffer()
+		venerable = "myApp" + VenerableNameSuffix + "my-uuid"
+
+		courier.ExistsCall.Returns.Bool = true
+
+		resurrector = &Resurrector{
+			Pusher: Pusher{
+				Courier:        courier,
+				DeploymentInfo: S.DeploymentInfo{AppName: "myApp", UUID: "my-uuid"},
+				Response:       NewBuffer(),
+				Log:            interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "resurrector_test")},
+				FoundationURL:  "https://foundation",
+			},
+		}
+	})
+
+	Describe("Execute", func() {
+		It("starts the replaced application", func() {
+			Expect(resurrector.Execute()).To(Succeed())
+
+			Expect(courier.StartCall.Received.AppName).To(Equal(venerable))
+			Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+
+			Eventually(logBuffer).Should(Say("started %s", venerable))
+		})
+
+		It("maps the load balanced route when the environment has a domain", func() {
+			resurrector.DeploymentInfo.Domain = "example.com"
+
+			Expect(resurrector.Execute()).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{venerable}))
+			Expect(courier.MapRouteCall.Received.Domain).To(Equal([]string{"example.com"}))
+			Expect(courier.MapRouteCall.Received.Hostname).To(Equal([]string{"myApp"}))
+		})
+
+		It("returns an error when the replaced application does not exist", func() {
+			courier.ExistsCall.Returns.Bool = false
+
+			Expect(resurrector.Execute()).To(MatchError(state.VenerableNotFoundError{venerable, "https://foundation"}))
+			Expect(courier.StartCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("returns an error when the replaced application cannot be started", func() {
+			courier.StartCall.Returns.Output = []byte("start output")
+			courier.StartCall.Returns.Error = errors.New("start error")
+
+			Expect(resurrector.Execute()).To(MatchError(state.StartError{venerable, []byte("start output")}))
+		})
+	})
+
+	Describe("Success", func() {
+		It("deletes the deployed application and renames the replaced application", func() {
+			Expect(resurrector.Success()).To(Succeed())
+
+			Expect(courier.DeleteCall.Received.AppName).To(Equal("myApp"))
+			Expect(courier.RenameCall.Received.AppName).To(Equal(venerable))
+			Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal("myApp"))
+		})
+
+		It("returns an error when the replaced application cannot be renamed", func() {
+			courier.RenameCall.Returns.Output = []byte("rename output")
+			courier.RenameCall.Returns.Error = errors.New("rename error")
+
+			Expect(resurrector.Success()).To(MatchError(state.RenameError{venerable, []byte("rename output")}))
+		})
+	})
+
+	Describe("Undo", func() {
+		It("stops the replaced application again", func() {
+			resurrector.DeploymentInfo.Domain = "example.com"
+
+			Expect(resurrector.Undo()).To(Succeed())
+
+			Expect(courier.UnmapRouteCall.Received.AppName).To(Equal(venerable))
+			Expect(courier.UnmapRouteCall.Received.Domain).To(Equal("example.com"))
+			Expect(courier.StopCall.Received.AppName).To(Equal(venerable))
+		})
+	})
+})
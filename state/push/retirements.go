@@ -0,0 +1,179 @@
+package push
+
+import (
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// VenerableNameSuffix is used to rename the replaced application while it waits for its deletion.
+const VenerableNameSuffix = "-venerable-"
+
+// Retirement is an application replaced by a deploy that is kept stopped until its delete delay is over,
+// so the deploy can be rolled back.
+type Retirement struct {
+	UUID        string
+	Environment string
+	Org         string
+	Space       string
+	AppName     string
+	Username    string
+	Password    string
+	SkipSSL     bool
+	Foundations []string
+	Delay       time.Duration
+	RetiredAt   time.Time
+}
+
+// Venerable returns the name of the replaced application.
+func (r Retirement) Venerable() string {
+	return r.AppName + VenerableNameSuffix + r.UUID
+}
+
+type retirement struct {
+	Retirement
+	timer   *time.Timer
+	claimed bool
+}
+
+// Retirements tracks the replaced applications waiting for their deletion by the UUID of the deploy and the
+// application name. The applications are deleted from every foundation they were retired on once their delay
+// is over, unless they are claimed by a rollback first.
+// They are only kept in memory. Applications pending deletion when Deployadactyl restarts are left stopped.
+type Retirements struct {
+	CourierCreator courierCreator
+	Log            I.Logger
+
+	pending map[string]*retirement
+	mutex   sync.Mutex
+}
+
+// NewRetirements returns Retirements without any pending deletion.
+func NewRetirements(courierCreator courierCreator, log I.Logger) *Retirements {
+	return &Retirements{
+		CourierCreator: courierCreator,
+		Log:            log,
+		pending:        map[string]*retirement{},
+	}
+}
+
+// Add records the application of the retirement as retired on the foundation. The delay starts with the
+// first foundation.
+func (r *Retirements) Add(ret Retirement, foundationURL string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := retirementKey(ret.UUID, ret.AppName)
+
+	pending, ok := r.pending[key]
+	if !ok {
+		ret.Foundations = nil
+		pending = &retirement{Retirement: ret}
+		pending.timer = time.AfterFunc(ret.Delay, func() { r.reap(key) })
+		r.pending[key] = pending
+	}
+
+	pending.Foundations = append(pending.Foundations, foundationURL)
+}
+
+// Get returns the retirement of the application replaced by the deploy with the UUID.
+func (r *Retirements) Get(uuid, appName string) (Retirement, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	pending, ok := r.pending[retirementKey(uuid, appName)]
+	if !ok {
+		return Retirement{}, false
+	}
+
+	return pending.Retirement, true
+}
+
+// Claim stops the deletion of the retired application so it can be rolled back. It returns false when the
+// application is not waiting for its deletion, is being deleted or is already claimed.
+func (r *Retirements) Claim(uuid, appName string) (Retirement, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	pending, ok := r.pending[retirementKey(uuid, appName)]
+	if !ok || pending.claimed || !pending.timer.Stop() {
+		return Retirement{}, false
+	}
+
+	pending.claimed = true
+	return pending.Retirement, true
+}
+
+// Release restarts the delay of a claimed retirement whose rollback did not complete.
+func (r *Retirements) Release(uuid, appName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := retirementKey(uuid, appName)
+
+	pending, ok := r.pending[key]
+	if !ok || !pending.claimed {
+		return
+	}
+
+	pending.claimed = false
+	pending.timer = time.AfterFunc(pending.Delay, func() { r.reap(key) })
+}
+
+// Remove forgets the retirement without deleting the application.
+func (r *Retirements) Remove(uuid, appName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := retirementKey(uuid, appName)
+
+	if pending, ok := r.pending[key]; ok {
+		pending.timer.Stop()
+		delete(r.pending, key)
+	}
+}
+
+func (r *Retirements) reap(key string) {
+	r.mutex.Lock()
+	pending, ok := r.pending[key]
+	delete(r.pending, key)
+	r.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	for _, foundationURL := range pending.Foundations {
+		r.delete(pending.Retirement, foundationURL)
+	}
+}
+
+func (r *Retirements) delete(ret Retirement, foundationURL string) {
+	venerable := ret.Venerable()
+
+	courier, err := r.CourierCreator.CreateCourier()
+	if err != nil {
+		r.Log.Errorf("could not delete %s on %s: %s", venerable, foundationURL, err)
+		return
+	}
+	defer courier.CleanUp()
+
+	out, err := courier.Login(foundationURL, ret.Username, ret.Password, ret.Org, ret.Space, ret.SkipSSL)
+	if err != nil {
+		r.Log.Errorf("could not delete %s: login to %s failed: %s", venerable, foundationURL, out)
+		return
+	}
+
+	out, err = courier.Delete(venerable)
+	if err != nil {
+		r.Log.Errorf("could not delete %s on %s: %s", venerable, foundationURL, out)
+		return
+	}
+
+	r.Log.Infof("deleted %s on %s after its delete delay", venerable, foundationURL)
+}
+
+func retirementKey(uuid, appName string) string {
+	return uuid + "/" + appName
+}
@@ -0,0 +1,113 @@
+package push_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	. "github.com/compozed/deployadactyl/state/push"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Retirements", func() {
+	var (
+		retirements *Retirements
+		courier     *mocks.Courier
+		logBuffer   *Buffer
+		retirement  Retirement
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		logBuffer = NewBuffer()
+
+		creator := courierCreator{CourierCreatorFn: func() (interfaces.Courier, error) { return courier, nil }}
+		retirements = NewRetirements(creator, interfaces.DefaultLogger(logBuffer, logging.DEBUG, "retirements_test"))
+
+		retirement = Retirement{
+			UUID:     "my-uuid",
+			Org:      "my-org",
+			Space:    "my-space",
+			AppName:  "myApp",
+			Username: "my-user",
+			Password: "my-password",
+			Delay:    10 * time.Millisecond,
+		}
+	})
+
+	It("deletes the retired application once its delay is over", func() {
+		retirements.Add(retirement, "https://foundation")
+
+		Eventually(logBuffer).Should(Say("deleted myApp-venerable-my-uuid on https://foundation after its delete delay"))
+
+		Expect(courier.LoginCall.Received.FoundationURL).To(Equal("https://foundation"))
+		Expect(courier.LoginCall.Received.Username).To(Equal("my-user"))
+		Expect(courier.LoginCall.Received.Org).To(Equal("my-org"))
+		Expect(courier.LoginCall.Received.Space).To(Equal("my-space"))
+		Expect(courier.DeleteCall.Received.AppName).To(Equal("myApp-venerable-my-uuid"))
+
+		_, ok := retirements.Get("my-uuid", "myApp")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("deletes the retired application from every foundation it was retired on", func() {
+		retirement.Delay = time.Minute
+		retirements.Add(retirement, "https://foundation-1")
+		retirements.Add(retirement, "https://foundation-2")
+
+		added, ok := retirements.Get("my-uuid", "myApp")
+		Expect(ok).To(BeTrue())
+		Expect(added.Foundations).To(Equal([]string{"https://foundation-1", "https://foundation-2"}))
+
+		retirements.Remove("my-uuid", "myApp")
+	})
+
+	It("does not delete a claimed application", func() {
+		retirement.Delay = 50 * time.Millisecond
+		retirements.Add(retirement, "https://foundation")
+
+		claimed, ok := retirements.Claim("my-uuid", "myApp")
+		Expect(ok).To(BeTrue())
+		Expect(claimed.Venerable()).To(Equal("myApp-venerable-my-uuid"))
+
+		Consistently(logBuffer, 100*time.Millisecond).ShouldNot(Say("deleted"))
+		Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+	})
+
+	It("does not claim an application twice", func() {
+		retirement.Delay = time.Minute
+		retirements.Add(retirement, "https://foundation")
+
+		_, ok := retirements.Claim("my-uuid", "myApp")
+		Expect(ok).To(BeTrue())
+
+		_, ok = retirements.Claim("my-uuid", "myApp")
+		Expect(ok).To(BeFalse())
+
+		retirements.Remove("my-uuid", "myApp")
+	})
+
+	It("deletes a released application once its delay is over again", func() {
+		retirements.Add(retirement, "https://foundation")
+
+		_, ok := retirements.Claim("my-uuid", "myApp")
+		Expect(ok).To(BeTrue())
+
+		retirements.Release("my-uuid", "myApp")
+
+		Eventually(logBuffer).Should(Say("deleted myApp-venerable-my-uuid"))
+	})
+
+	It("logs an error when the retired application cannot be deleted", func() {
+		courier.DeleteCall.Returns.Output = []byte("delete output")
+		courier.DeleteCall.Returns.Error = errors.New("delete error")
+
+		retirements.Add(retirement, "https://foundation")
+
+		Eventually(logBuffer).Should(Say("could not delete myApp-venerable-my-uuid on https://foundation: delete output"))
+	})
+})
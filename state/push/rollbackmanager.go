@@ -0,0 +1,122 @@
+package push
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+const successfulRollback = `Your rollback was successful! (^_^)b
+%s is back to the application replaced by deploy %s.
+
+`
+
+// RollbackManager creates a Resurrector for each foundation to bring back the application replaced by a deploy
+// while it waits for its deletion. The retirement is claimed while the rollback runs, so the application is not
+// deleted under it, and it is given its delete delay again when the rollback fails.
+type RollbackManager struct {
+	CourierCreator  courierCreator
+	EventManager    I.EventManager
+	Logger          I.DeploymentLogger
+	DeployEventData S.DeployEventData
+	Retirements     *Retirements
+
+	claimed bool
+}
+
+func (a *RollbackManager) SetUp() error {
+	info := a.DeployEventData.DeploymentInfo
+
+	_, ok := a.Retirements.Claim(info.UUID, info.AppName)
+	if !ok {
+		return deployer.RetirementNotFoundError{UUID: info.UUID, AppName: info.AppName}
+	}
+	a.claimed = true
+
+	return nil
+}
+
+func (a *RollbackManager) OnStart() error {
+	info := a.DeployEventData.DeploymentInfo
+
+	a.Logger.Infof("rolling back %s with UUID %s", info.AppName, info.UUID)
+	fmt.Fprintf(a.DeployEventData.Response, "Rolling back %s to %s%s%s in environment %s\n", info.AppName, info.AppName, VenerableNameSuffix, info.UUID, info.Environment)
+
+	return nil
+}
+
+func (a *RollbackManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
+	if err != nil {
+		fmt.Fprintf(response, "\nYour application was not successfully rolled back on all foundations: %s\n\n", err.Error())
+		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+		}
+		return I.DeployResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err,
+		}
+	}
+
+	info := a.DeployEventData.DeploymentInfo
+	a.Retirements.Remove(info.UUID, info.AppName)
+	a.claimed = false
+
+	a.Logger.Infof("successfully rolled back application %s", info.AppName)
+	fmt.Fprintf(response, "\n"+successfulRollback, info.AppName, info.UUID)
+
+	return I.DeployResponse{StatusCode: http.StatusOK}
+}
+
+// CleanUp gives a retirement that was not rolled back its delete delay again.
+func (a *RollbackManager) CleanUp() {
+	if a.claimed {
+		info := a.DeployEventData.DeploymentInfo
+		a.Retirements.Release(info.UUID, info.AppName)
+	}
+}
+
+func (a *RollbackManager) Create(environment S.Environment, response io.ReadWriter, foundationURL string) (I.Action, error) {
+	courier, err := a.CourierCreator.CreateCourier()
+	if err != nil {
+		a.Logger.Error(err)
+		return &Resurrector{}, state.CourierCreationError{Err: err}
+	}
+
+	return &Resurrector{
+		Pusher: Pusher{
+			Courier:        courier,
+			DeploymentInfo: *a.DeployEventData.DeploymentInfo,
+			EventManager:   a.EventManager,
+			Response:       response,
+			Log:            a.Logger,
+			FoundationURL:  foundationURL,
+			Environment:    environment,
+		},
+	}, nil
+}
+
+func (a *RollbackManager) InitiallyError(initiallyErrors []error) error {
+	return bluegreen.LoginError{LoginErrors: initiallyErrors}
+}
+
+func (a *RollbackManager) ExecuteError(executeErrors []error) error {
+	return bluegreen.PushError{PushErrors: executeErrors}
+}
+
+func (a *RollbackManager) UndoError(executeErrors, undoErrors []error) error {
+	return bluegreen.RollbackError{PushErrors: executeErrors, RollbackErrors: undoErrors}
+}
+
+func (a *RollbackManager) SuccessError(successErrors []error) error {
+	return bluegreen.FinishPushError{FinishPushError: successErrors}
+}
@@ -0,0 +1,95 @@
+package push
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compozed/deployadactyl/constants"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// resolveRouteConflict applies the route conflict policy of the environment when the production route is
+// mapped to applications other than the one being deployed and its candidates.
+// It returns false when the route must be left alone and not mapped to the candidate.
+func (p Pusher) resolveRouteConflict(candidate string) (bool, error) {
+	var (
+		domain   = p.DeploymentInfo.Domain
+		hostname = p.DeploymentInfo.AppName
+		route    = hostname + "." + domain
+	)
+
+	apps, err := p.Courier.RouteApps(domain, hostname)
+	if err != nil {
+		p.Log.Errorf("could not find the applications mapped to %s", route)
+		return false, state.RouteAppsError{route, err}
+	}
+
+	others := p.otherApps(apps, candidate)
+	if len(others) == 0 {
+		return true, nil
+	}
+
+	fmt.Fprintf(p.Response, "route %s is mapped to %s on %s\n", route, strings.Join(others, ", "), p.FoundationURL)
+
+	switch p.Environment.RouteConflictPolicy {
+	case S.RouteConflictSteal:
+		for _, app := range others {
+			p.Log.Infof("unmapping %s from %s", route, app)
+
+			out, err := p.Courier.UnmapRoute(app, domain, hostname)
+			p.Response.Write(out)
+			if err != nil {
+				p.Log.Errorf("could not unmap %s from %s", route, app)
+				return false, state.UnmapRouteError{app, out}
+			}
+
+			fmt.Fprintf(p.Response, "unmapped route %s from %s\n", route, app)
+		}
+		return true, nil
+	case S.RouteConflictSkip:
+		return false, p.warn(candidate, fmt.Sprintf("route %s is mapped to %s, not mapping it to %s", route, strings.Join(others, ", "), candidate))
+	default:
+		err = state.RouteConflictError{route, others}
+		p.Log.Error(err)
+		return false, err
+	}
+}
+
+// otherApps returns the applications that are neither the application being deployed nor one of its candidates.
+func (p Pusher) otherApps(apps []string, candidate string) []string {
+	var others []string
+	for _, app := range apps {
+		if app == p.DeploymentInfo.AppName || app == candidate || strings.HasPrefix(app, p.DeploymentInfo.AppName+TemporaryNameSuffix) {
+			continue
+		}
+		others = append(others, app)
+	}
+	return others
+}
+
+// warn reports a problem that does not fail the deploy in the response and emits a deploy warning.
+func (p Pusher) warn(candidate, warning string) error {
+	p.Log.Errorf("WARNING: %s", warning)
+	fmt.Fprintf(p.Response, "WARNING: %s\n", warning)
+
+	event := DeployWarningEvent{
+		CFContext:       p.CFContext,
+		Auth:            p.Auth,
+		Response:        p.Response,
+		FoundationURL:   p.FoundationURL,
+		TempAppWithUUID: candidate,
+		Warning:         warning,
+		Data:            p.DeploymentInfo.Data,
+		Log:             p.Log,
+	}
+
+	p.Log.Debugf("emitting a %s event", constants.DeployWarningEvent)
+	err := p.EventManager.Emit(I.Event{Type: constants.DeployWarningEvent, Data: event})
+	if err != nil {
+		return err
+	}
+
+	return p.EventManager.EmitEvent(event)
+}
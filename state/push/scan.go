@@ -0,0 +1,34 @@
+package push
+
+import (
+	"fmt"
+
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/state"
+)
+
+// scanArtifact passes the extracted artifact at appPath to the ArtifactScanner. The warnings of the scan are
+// written to the response. It returns a ScanFailedError when the scan has findings.
+func (a *PushManager) scanArtifact(appPath string) error {
+	if a.ArtifactScanner == nil {
+		return nil
+	}
+
+	a.Logger.Info("scanning the artifact")
+	result, err := a.ArtifactScanner.Scan(appPath, *a.DeployEventData.DeploymentInfo)
+	if err != nil {
+		return state.ArtifactScanError{Err: err}
+	}
+
+	for _, warning := range result.Warnings {
+		a.Logger.Infof("artifact scan warning: %s", warning)
+		fmt.Fprintf(a.DeployEventData.Response, "artifact scan warning: %s\n", warning)
+	}
+
+	if len(result.Findings) > 0 {
+		return deployer.ScanFailedError{result.Findings}
+	}
+
+	a.Logger.Info("the artifact passed the scan")
+	return nil
+}
@@ -0,0 +1,49 @@
+package push
+
+import (
+	"time"
+
+	"github.com/compozed/deployadactyl/state"
+	"github.com/compozed/deployadactyl/tracing"
+)
+
+// Phases of a push that an environment can give a time budget.
+const (
+	FetchPhase       = tracing.FetchPhase
+	PushPhase        = tracing.PushPhase
+	HealthCheckPhase = tracing.HealthCheckPhase
+	SwapPhase        = tracing.SwapPhase
+)
+
+// runPhase runs fn with the time budget of the phase, in a span of the deploy with the UUID when tracer is enabled.
+func runPhase(tracer *tracing.Tracer, uuid, phase string, seconds int, fn func() error) error {
+	span := tracer.StartPhase(uuid, phase)
+	err := withPhaseTimeout(phase, seconds, fn)
+	span.End(err)
+	return err
+}
+
+// withPhaseTimeout runs fn and returns a PhaseTimeoutError when it does not finish within seconds.
+// A phase without a budget, when seconds is below 1, is not limited.
+//
+// A phase that runs out of time is waited for before the PhaseTimeoutError is returned,
+// so the rollback of the deploy never changes Cloud Foundry while fn still does.
+func withPhaseTimeout(phase string, seconds int, fn func() error) error {
+	if seconds < 1 {
+		return fn()
+	}
+
+	timeout := time.Duration(seconds) * time.Second
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		<-done
+		return state.PhaseTimeoutError{phase, timeout}
+	}
+}
@@ -0,0 +1,209 @@
+package push
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// TrafficSplitPollInterval is how often the instances of the new application are checked while traffic is split.
+const TrafficSplitPollInterval = 10 * time.Second
+
+// splitRoute is the production route shared by the existing application and the candidate during a traffic split.
+type splitRoute struct {
+	url       string
+	guid      string
+	existing  string
+	candidate string
+}
+
+// trafficSplit reports whether the production route is shifted to the candidate in steps.
+// The candidate is pushed with its own hostname so it only receives the traffic it is given.
+// A manual cutover takes precedence over a traffic split.
+func (p Pusher) trafficSplit() bool {
+	if p.manualCutover() {
+		return false
+	}
+	if p.DeploymentInfo.Strategy != "" {
+		return p.DeploymentInfo.Strategy == S.StrategyTrafficSplit
+	}
+	return p.Environment.TrafficSplit
+}
+
+// splitTraffic maps the production route to the candidate alongside the existing application and sends it
+// each of the environment's traffic split weights in turn, watching its instances for the soak time.
+// When the candidate degrades, all of the traffic is returned to the existing application.
+// Once every step has passed, the candidate receives all of the traffic.
+//
+// The route is mapped to the candidate as it is when there is no existing application.
+func (p Pusher) splitTraffic(candidate string) error {
+	domain, err := p.temporaryDomain(candidate)
+	if err != nil {
+		return err
+	}
+
+	if !p.Courier.Exists(p.DeploymentInfo.AppName) {
+		p.Log.Infof("%s does not exist, not splitting traffic", p.DeploymentInfo.AppName)
+
+		out, err := p.Courier.MapRoute(candidate, domain, p.DeploymentInfo.AppName)
+		p.Response.Write(out)
+		if err != nil {
+			p.Log.Errorf("could not map %s.%s to %s", p.DeploymentInfo.AppName, domain, candidate)
+			return state.MapRouteError{out}
+		}
+
+		return nil
+	}
+
+	route, err := p.splitRoute(candidate, domain)
+	if err != nil {
+		return err
+	}
+
+	for _, weight := range p.Environment.TrafficSplitWeights {
+		err = p.setRouteWeights(route, weight)
+		if err != nil {
+			return err
+		}
+
+		err = p.soakTraffic(candidate, weight)
+		if err != nil {
+			restoreErr := p.setRouteWeights(route, 0)
+			if restoreErr != nil {
+				p.Log.Error(restoreErr)
+			}
+			return err
+		}
+	}
+
+	return p.setRouteWeights(route, 100)
+}
+
+// restoreTraffic returns all of the traffic of the production route to the existing application.
+func (p Pusher) restoreTraffic(candidate string) error {
+	domain, err := p.temporaryDomain(candidate)
+	if err != nil {
+		return err
+	}
+
+	route, err := p.splitRoute(candidate, domain)
+	if err != nil {
+		return err
+	}
+
+	return p.setRouteWeights(route, 0)
+}
+
+func (p Pusher) splitRoute(candidate, domain string) (splitRoute, error) {
+	route := splitRoute{url: p.DeploymentInfo.AppName + "." + domain}
+
+	var err error
+	route.guid, err = p.Courier.RouteGUID(domain, p.DeploymentInfo.AppName)
+	if err != nil {
+		p.Log.Errorf("could not get the guid of route %s", route.url)
+		return route, state.RouteWeightsError{route.url, err}
+	}
+
+	route.existing, err = p.Courier.AppGUID(p.DeploymentInfo.AppName)
+	if err != nil {
+		p.Log.Errorf("could not get the guid of %s", p.DeploymentInfo.AppName)
+		return route, state.RouteWeightsError{route.url, err}
+	}
+
+	route.candidate, err = p.Courier.AppGUID(candidate)
+	if err != nil {
+		p.Log.Errorf("could not get the guid of %s", candidate)
+		return route, state.RouteWeightsError{route.url, err}
+	}
+
+	return route, nil
+}
+
+// setRouteWeights sends weight percent of the route's traffic to the candidate and the rest to the existing application.
+// An application with no traffic is removed from the route.
+func (p Pusher) setRouteWeights(route splitRoute, weight int) error {
+	weights := map[string]int{}
+	if weight > 0 {
+		weights[route.candidate] = weight
+	}
+	if weight < 100 {
+		weights[route.existing] = 100 - weight
+	}
+
+	p.Log.Debugf("sending %d%% of %s to the new application", weight, route.url)
+
+	out, err := p.Courier.SetRouteWeights(route.guid, weights)
+	if err != nil {
+		p.Log.Errorf("could not set the weights of route %s: %s", route.url, out)
+		return state.RouteWeightsError{route.url, fmt.Errorf("%s: %s", err, out)}
+	}
+
+	fmt.Fprintf(p.Response, "sending %d%% of %s to the new application\n", weight, route.url)
+	p.Log.Infof("sending %d%% of %s to the new application", weight, route.url)
+
+	return nil
+}
+
+// soakTraffic watches the instances of the candidate for the soak time while it receives weight percent of the traffic.
+// The candidate is degraded when fewer than the quorum of its instances are running.
+func (p Pusher) soakTraffic(candidate string, weight int) error {
+	expected := int(p.DeploymentInfo.Instances)
+	required := (expected*p.Environment.InstanceQuorumPercent + 99) / 100
+	soak := time.Duration(p.Environment.TrafficSplitSoakSeconds) * time.Second
+
+	for waited := time.Duration(0); waited < soak; waited += TrafficSplitPollInterval {
+		p.sleep(TrafficSplitPollInterval)
+
+		err := p.checkCanceled()
+		if err != nil {
+			return err
+		}
+
+		states, err := p.Courier.InstanceStates(candidate)
+		if err != nil {
+			p.Log.Errorf("could not get the instance states of %s", candidate)
+			return state.InstanceStateError{candidate, err}
+		}
+
+		running := runningInstances(states)
+		if running < required {
+			fmt.Fprintf(p.Response, "%d of %d instances of %s are running, %d required\n", running, expected, candidate, required)
+			p.Log.Errorf("%s degraded while receiving %d%% of the traffic", candidate, weight)
+			return state.TrafficSplitDegradedError{candidate, weight, running, expected}
+		}
+	}
+
+	return nil
+}
+
+// temporaryDomain returns the domain of the candidate's temporary route.
+func (p Pusher) temporaryDomain(candidate string) (string, error) {
+	routes, err := p.Courier.Routes(candidate)
+	if err != nil {
+		p.Log.Errorf("could not get the routes of %s", candidate)
+		return "", state.RoutesError{candidate, err}
+	}
+
+	for _, route := range routes {
+		if strings.HasPrefix(route, candidate+".") {
+			return strings.TrimPrefix(route, candidate+"."), nil
+		}
+	}
+
+	p.Log.Errorf("candidate %s has no temporary route on %s", candidate, p.FoundationURL)
+	return "", state.NoTemporaryRouteError{candidate}
+}
+
+func runningInstances(states []string) int {
+	running := 0
+	for _, instanceState := range states {
+		if instanceState == "running" {
+			running++
+		}
+	}
+
+	return running
+}
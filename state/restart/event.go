@@ -0,0 +1,137 @@
+package restart
+
+import (
+	"reflect"
+
+	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/structs"
+	"github.com/go-errors/errors"
+	"io"
+)
+
+type eventBinding struct {
+	etype   reflect.Type
+	handler func(event interface{}) error
+}
+
+func (s eventBinding) Accepts(event interface{}) bool {
+	return reflect.TypeOf(event) == s.etype
+}
+
+func (b eventBinding) Emit(event interface{}) error {
+	return b.handler(event)
+}
+
+type RestartFailureEvent struct {
+	CFContext     interfaces.CFContext
+	Restage       bool
+	Data          map[string]interface{}
+	Environment   structs.Environment
+	Authorization interfaces.Authorization
+	Response      io.ReadWriter
+	Error         error
+	Log           interfaces.DeploymentLogger
+}
+
+func (e RestartFailureEvent) Name() string {
+	return "RestartFailureEvent"
+}
+
+func NewRestartFailureEventBinding(handler func(event RestartFailureEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(RestartFailureEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(RestartFailureEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{Err: errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type RestartSuccessEvent struct {
+	CFContext     interfaces.CFContext
+	Restage       bool
+	Data          map[string]interface{}
+	Environment   structs.Environment
+	Authorization interfaces.Authorization
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e RestartSuccessEvent) Name() string {
+	return "RestartSuccessEvent"
+}
+
+func NewRestartSuccessEventBinding(handler func(event RestartSuccessEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(RestartSuccessEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(RestartSuccessEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{Err: errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type RestartStartedEvent struct {
+	CFContext     interfaces.CFContext
+	Restage       bool
+	Data          map[string]interface{}
+	Environment   structs.Environment
+	Authorization interfaces.Authorization
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e RestartStartedEvent) Name() string {
+	return "RestartStartedEvent"
+}
+
+func NewRestartStartedEventBinding(handler func(event RestartStartedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(RestartStartedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(RestartStartedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{Err: errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type RestartFinishedEvent struct {
+	CFContext     interfaces.CFContext
+	Restage       bool
+	Data          map[string]interface{}
+	Authorization interfaces.Authorization
+	Response      io.ReadWriter
+	Environment   structs.Environment
+	Log           interfaces.DeploymentLogger
+}
+
+func (e RestartFinishedEvent) Name() string {
+	return "RestartFinishedEvent"
+}
+
+func NewRestartFinishedEventBinding(handler func(event RestartFinishedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(RestartFinishedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(RestartFinishedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{Err: errors.New("invalid event type")}
+			}
+		},
+	}
+}
@@ -0,0 +1,226 @@
+package restart
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"io"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type RestartControllerConstructor func(log I.DeploymentLogger, deployer I.Deployer, conf config.Config, eventManager I.EventManager, errorFinder I.ErrorFinder, restartManagerFactory I.RestartManagerFactory) I.RestartController
+
+func NewRestartController(l I.DeploymentLogger, d I.Deployer, c config.Config, em I.EventManager, ef I.ErrorFinder, rmf I.RestartManagerFactory) I.RestartController {
+	return &RestartController{
+		Deployer:              d,
+		Config:                c,
+		EventManager:          em,
+		ErrorFinder:           ef,
+		RestartManagerFactory: rmf,
+		Log:                   l,
+	}
+}
+
+// RestartController restages or restarts an application on every foundation in the environment.
+type RestartController struct {
+	Log                   I.DeploymentLogger
+	RestartManagerFactory I.RestartManagerFactory
+	Deployer              I.Deployer
+	Config                config.Config
+	EventManager          I.EventManager
+	ErrorFinder           I.ErrorFinder
+}
+
+// RestageDeployment rebuilds the droplet of an application from its existing bits.
+func (c *RestartController) RestageDeployment(deployment *I.Deployment, data map[string]interface{}, response *bytes.Buffer) (deployResponse I.DeployResponse) {
+	return c.restart(deployment, true, data, response)
+}
+
+// RestartDeployment restarts the instances of an application.
+func (c *RestartController) RestartDeployment(deployment *I.Deployment, data map[string]interface{}, response *bytes.Buffer) (deployResponse I.DeployResponse) {
+	return c.restart(deployment, false, data, response)
+}
+
+func (c *RestartController) restart(deployment *I.Deployment, restage bool, data map[string]interface{}, response *bytes.Buffer) (deployResponse I.DeployResponse) {
+	cf := deployment.CFContext
+	c.Log.Debugf("Preparing to %s %s with UUID %s", operation(restage), cf.Application, c.Log.UUID)
+
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	environment, err := c.resolveEnvironment(cf.Environment)
+	if err != nil {
+		fmt.Fprintln(response, err.Error())
+		return I.DeployResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err,
+		}
+	}
+	auth, err := c.resolveAuthorization(deployment.Authorization, environment, c.Log)
+	if err != nil {
+		return I.DeployResponse{
+			StatusCode: http.StatusUnauthorized,
+			Error:      err,
+		}
+	}
+
+	deploymentInfo := &structs.DeploymentInfo{
+		Org:          cf.Organization,
+		Space:        cf.Space,
+		AppName:      cf.Application,
+		Environment:  cf.Environment,
+		UUID:         c.Log.UUID,
+		Domain:       environment.Domain,
+		SkipSSL:      environment.SkipSSL,
+		CustomParams: environment.CustomParams,
+		Username:     auth.Username,
+		Password:     auth.Password,
+		Data:         data,
+	}
+
+	defer c.emitRestartFinish(response, c.Log, cf, restage, &auth, &environment, data, &deployResponse)
+	defer c.emitRestartSuccessOrFailure(response, c.Log, cf, restage, &auth, &environment, data, &deployResponse)
+
+	err = c.EventManager.EmitEvent(RestartStartedEvent{
+		CFContext:     cf,
+		Restage:       restage,
+		Authorization: auth,
+		Environment:   environment,
+		Data:          data,
+		Response:      response,
+		Log:           c.Log,
+	})
+	if err != nil {
+		c.Log.Error(err)
+		err = &bluegreen.InitializationError{err}
+		return I.DeployResponse{
+			StatusCode:     http.StatusInternalServerError,
+			Error:          deployer.EventError{Type: "RestartStartedEvent", Err: err},
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	deployEventData := structs.DeployEventData{Response: response, DeploymentInfo: deploymentInfo}
+
+	manager := c.RestartManagerFactory.RestartManager(c.Log, deployEventData, restage)
+	deployResponse = *c.Deployer.Deploy(deploymentInfo, environment, manager, response)
+	return deployResponse
+}
+
+func (c *RestartController) resolveAuthorization(auth I.Authorization, envs structs.Environment, deploymentLogger I.DeploymentLogger) (I.Authorization, error) {
+	config := c.Config
+	deploymentLogger.Debug("checking for basic auth")
+	if auth.Username == "" && auth.Password == "" {
+		username, password, ok := config.FallbackAuthorization(envs)
+		if !ok {
+			return I.Authorization{}, deployer.BasicAuthError{}
+		}
+		auth.Username = username
+		auth.Password = password
+	}
+
+	return auth, nil
+}
+
+func (c *RestartController) resolveEnvironment(env string) (structs.Environment, error) {
+	config := c.Config
+	environment, ok := config.Environments[env]
+	if !ok {
+		return structs.Environment{}, deployer.EnvironmentNotFoundError{env}
+	}
+	return environment, nil
+}
+
+func (c RestartController) emitRestartFinish(response io.ReadWriter, deploymentLogger I.DeploymentLogger, cfContext I.CFContext, restage bool, auth *I.Authorization, environment *structs.Environment, data map[string]interface{}, deployResponse *I.DeployResponse) {
+	var event I.IEvent
+	event = RestartFinishedEvent{
+		CFContext:     cfContext,
+		Restage:       restage,
+		Authorization: *auth,
+		Data:          data,
+		Environment:   *environment,
+		Log:           deploymentLogger,
+	}
+	deploymentLogger.Debugf("emitting a %s event", event.Name())
+	c.EventManager.EmitEvent(event)
+}
+
+func (c RestartController) emitRestartSuccessOrFailure(response io.ReadWriter, deploymentLogger I.DeploymentLogger, cfContext I.CFContext, restage bool, auth *I.Authorization, environment *structs.Environment, data map[string]interface{}, deployResponse *I.DeployResponse) {
+	var event I.IEvent
+
+	if deployResponse.Error != nil {
+		c.printErrors(response, &deployResponse.Error)
+		event = RestartFailureEvent{
+			CFContext:     cfContext,
+			Restage:       restage,
+			Authorization: *auth,
+			Environment:   *environment,
+			Data:          data,
+			Response:      response,
+			Error:         deployResponse.Error,
+			Log:           deploymentLogger,
+		}
+
+	} else {
+		event = RestartSuccessEvent{
+			CFContext:     cfContext,
+			Restage:       restage,
+			Authorization: *auth,
+			Environment:   *environment,
+			Data:          data,
+			Response:      response,
+			Log:           deploymentLogger,
+		}
+	}
+	deploymentLogger.Debugf("emitting a %s event", event.Name())
+	eventErr := c.EventManager.EmitEvent(event)
+	if eventErr != nil {
+		deploymentLogger.Errorf("an error occurred when emitting a %s event: %s", event.Name(), eventErr)
+		fmt.Fprintln(response, eventErr)
+	}
+}
+
+func (c RestartController) printErrors(response io.ReadWriter, err *error) {
+	tempBuffer := bytes.Buffer{}
+	tempBuffer.ReadFrom(response)
+	fmt.Fprint(response, state.TruncateOutput(tempBuffer.String(), c.Config.ErrorOutput, c.Config.DeploymentLog, c.Log.UUID))
+
+	errors := c.ErrorFinder.FindErrors(tempBuffer.String())
+	if len(errors) > 0 {
+		*err = errors[0]
+		for _, error := range errors {
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "*******************")
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "The following error was found in the above logs: "+error.Error())
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "Error: "+error.Details()[0])
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "Potential solution: "+error.Solution())
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "*******************")
+		}
+	}
+}
+
+func operation(restage bool) string {
+	if restage {
+		return "restage"
+	}
+	return "restart"
+}
+
+func past(restage bool) string {
+	if restage {
+		return "restaged"
+	}
+	return "restarted"
+}
@@ -0,0 +1,152 @@
+package restart_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/compozed/deployadactyl/config"
+	D "github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	. "github.com/compozed/deployadactyl/state/restart"
+	"github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("RestartController", func() {
+	var (
+		restartManagerFactory *mocks.RestartManagerFactory
+		eventManager          *mocks.EventManager
+		deployer              *mocks.Deployer
+		controller            *RestartController
+		deployment            *I.Deployment
+		environment           string
+		response              *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		environment = "environment-" + randomizer.StringRunes(10)
+
+		eventManager = &mocks.EventManager{}
+		deployer = &mocks.Deployer{}
+		restartManagerFactory = &mocks.RestartManagerFactory{}
+
+		controller = &RestartController{
+			Log:                   I.DeploymentLogger{Log: I.DefaultLogger(NewBuffer(), logging.DEBUG, "api_test"), UUID: randomizer.StringRunes(10)},
+			Deployer:              deployer,
+			RestartManagerFactory: restartManagerFactory,
+			EventManager:          eventManager,
+			Config:                config.Config{Environments: map[string]structs.Environment{environment: {}}},
+			ErrorFinder:           &mocks.ErrorFinder{},
+		}
+
+		deployment = &I.Deployment{
+			Authorization: I.Authorization{Username: "myUser"},
+			CFContext: I.CFContext{
+				Organization: "myOrg",
+				Space:        "mySpace",
+				Application:  "myApp",
+				Environment:  environment,
+			},
+		}
+		response = &bytes.Buffer{}
+	})
+
+	Describe("RestageDeployment", func() {
+		It("should emit a RestartStartedEvent for a restage", func() {
+			controller.RestageDeployment(deployment, nil, response)
+
+			event := eventManager.EmitEventCall.Received.Events[0].(RestartStartedEvent)
+			Expect(event.CFContext.Application).Should(Equal("myApp"))
+			Expect(event.Restage).Should(BeTrue())
+		})
+
+		It("should create a restart manager that restages", func() {
+			manager := &mocks.StartManager{}
+			restartManagerFactory.RestartManagerCall.Returns.ActionCreater = manager
+
+			controller.RestageDeployment(deployment, nil, response)
+
+			Expect(restartManagerFactory.RestartManagerCall.Received.Restage).Should(BeTrue())
+			Expect(restartManagerFactory.RestartManagerCall.Received.DeployEventData.DeploymentInfo.Username).Should(Equal("myUser"))
+			Expect(deployer.DeployCall.Received.ActionCreator).Should(Equal(manager))
+		})
+	})
+
+	Describe("RestartDeployment", func() {
+		It("should create a restart manager that restarts", func() {
+			controller.RestartDeployment(deployment, map[string]interface{}{"key": "value"}, response)
+
+			Expect(restartManagerFactory.RestartManagerCall.Called).Should(BeTrue())
+			Expect(restartManagerFactory.RestartManagerCall.Received.Restage).Should(BeFalse())
+			Expect(restartManagerFactory.RestartManagerCall.Received.DeployEventData.DeploymentInfo.Data).Should(HaveKeyWithValue("key", "value"))
+		})
+
+		Context("when the environment does not exist", func() {
+			It("should return an EnvironmentNotFoundError", func() {
+				deployment.CFContext.Environment = "not-an-environment"
+
+				deployResponse := controller.RestartDeployment(deployment, nil, response)
+
+				Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+				Expect(reflect.TypeOf(deployResponse.Error)).Should(Equal(reflect.TypeOf(D.EnvironmentNotFoundError{})))
+				Expect(deployer.DeployCall.Called).Should(Equal(0))
+			})
+		})
+
+		Context("when the environment requires authentication", func() {
+			It("should return a BasicAuthError without credentials", func() {
+				controller.Config.Environments[environment] = structs.Environment{Authenticate: true}
+				deployment.Authorization = I.Authorization{}
+
+				deployResponse := controller.RestartDeployment(deployment, nil, response)
+
+				Expect(deployResponse.StatusCode).Should(Equal(http.StatusUnauthorized))
+				Expect(deployResponse.Error).Should(MatchError(D.BasicAuthError{}))
+			})
+		})
+
+		Context("when RestartStartedEvent fails", func() {
+			It("should return an EventError", func() {
+				eventManager.EmitEventCall.Returns.Error = append(eventManager.EmitEventCall.Returns.Error, errors.New("anything"))
+
+				deployResponse := controller.RestartDeployment(deployment, nil, response)
+
+				Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+				Expect(reflect.TypeOf(deployResponse.Error)).Should(Equal(reflect.TypeOf(D.EventError{})))
+			})
+		})
+
+		Context("when restart succeeds", func() {
+			It("should emit RestartSuccessEvent and RestartFinishedEvent", func() {
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				controller.RestartDeployment(deployment, nil, response)
+
+				Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[1])).Should(Equal(reflect.TypeOf(RestartSuccessEvent{})))
+				Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[2])).Should(Equal(reflect.TypeOf(RestartFinishedEvent{})))
+			})
+		})
+
+		Context("when restart fails", func() {
+			It("should emit RestartFailureEvent and RestartFinishedEvent", func() {
+				deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+				deployer.DeployCall.Returns.Error = errors.New("restart failed")
+
+				deployResponse := controller.RestartDeployment(deployment, nil, response)
+
+				Expect(deployResponse.Error).Should(MatchError("restart failed"))
+				failure := eventManager.EmitEventCall.Received.Events[1].(RestartFailureEvent)
+				Expect(failure.Error).Should(MatchError("restart failed"))
+				Expect(failure.Restage).Should(BeFalse())
+				Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[2])).Should(Equal(reflect.TypeOf(RestartFinishedEvent{})))
+			})
+		})
+	})
+})
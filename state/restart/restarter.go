@@ -0,0 +1,100 @@
+package restart
+
+import (
+	"io"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+)
+
+type Restarter struct {
+	Courier       I.Courier
+	CFContext     I.CFContext
+	Authorization I.Authorization
+	EventManager  I.EventManager
+	Response      io.ReadWriter
+	Log           I.DeploymentLogger
+	FoundationURL string
+	AppName       string
+	Restage       bool
+	Data          map[string]interface{}
+}
+
+func (r Restarter) Verify() error {
+	return nil
+}
+
+func (r Restarter) Success() error {
+	return nil
+}
+
+func (r Restarter) Finally() error {
+	return nil
+}
+
+// Login will login to a Cloud Foundry instance.
+func (r Restarter) Initially() error {
+	r.Log.Debugf(
+		`logging into cloud foundry with parameters:
+		foundation URL: %+v
+		username: %+v
+		org: %+v
+		space: %+v`,
+		r.FoundationURL, r.Authorization.Username, r.CFContext.Organization, r.CFContext.Space,
+	)
+
+	output, err := r.Courier.Login(
+		r.FoundationURL,
+		r.Authorization.Username,
+		r.Authorization.Password,
+		r.CFContext.Organization,
+		r.CFContext.Space,
+		r.CFContext.SkipSSL,
+	)
+	r.Response.Write(output)
+	if err != nil {
+		r.Log.Errorf("could not login to %s", r.FoundationURL)
+		return state.LoginError{r.FoundationURL, output}
+	}
+
+	r.Log.Infof("logged into cloud foundry %s", r.FoundationURL)
+
+	return nil
+}
+
+// Execute restages or restarts the application and writes the cf output to the response.
+func (r Restarter) Execute() error {
+	if r.Courier.Exists(r.AppName) != true {
+		r.Log.Errorf("failed to %s app on foundation %s: application doesn't exist", operation(r.Restage), r.FoundationURL)
+		return state.ExistsError{ApplicationName: r.AppName}
+	}
+
+	r.Log.Infof("starting %s of app %s", operation(r.Restage), r.AppName)
+
+	if r.Restage {
+		output, err := r.Courier.Restage(r.AppName)
+		r.Response.Write(output)
+		if err != nil {
+			r.Log.Errorf("failed to restage app on foundation %s: %s", r.FoundationURL, err.Error())
+			return state.RestageError{ApplicationName: r.AppName, Out: output}
+		}
+	} else {
+		output, err := r.Courier.Restart(r.AppName)
+		r.Response.Write(output)
+		if err != nil {
+			r.Log.Errorf("failed to restart app on foundation %s: %s", r.FoundationURL, err.Error())
+			return state.RestartError{ApplicationName: r.AppName, Out: output}
+		}
+	}
+
+	r.Log.Infof("successfully %s app %s", past(r.Restage), r.AppName)
+
+	return nil
+}
+
+// Undo does not revert a restage or restart. The droplet and instances that were replaced are gone.
+func (r Restarter) Undo() error {
+	r.Log.Infof("%s of app %s on foundation %s will not be rolled back", operation(r.Restage), r.AppName, r.FoundationURL)
+
+	return nil
+}
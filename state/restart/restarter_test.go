@@ -0,0 +1,143 @@
+package restart_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/restart"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Restarter", func() {
+	var (
+		restarter     Restarter
+		courier       *mocks.Courier
+		appName       string
+		foundationURL string
+		response      *Buffer
+		logBuffer     *Buffer
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		appName = "appName-" + randomizer.StringRunes(10)
+		foundationURL = "foundationURL-" + randomizer.StringRunes(10)
+		response = NewBuffer()
+		logBuffer = NewBuffer()
+
+		courier.ExistsCall.Returns.Bool = true
+
+		restarter = Restarter{
+			Courier: courier,
+			CFContext: interfaces.CFContext{
+				Organization: "org-" + randomizer.StringRunes(10),
+				Space:        "space-" + randomizer.StringRunes(10),
+				Application:  appName,
+			},
+			Authorization: interfaces.Authorization{
+				Username: "username-" + randomizer.StringRunes(10),
+				Password: "password-" + randomizer.StringRunes(10),
+			},
+			Response:      response,
+			Log:           interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "restarter_test")},
+			FoundationURL: foundationURL,
+			AppName:       appName,
+		}
+	})
+
+	Describe("logging in", func() {
+		Context("when login fails", func() {
+			It("returns a LoginError", func() {
+				courier.LoginCall.Returns.Output = []byte("login output")
+				courier.LoginCall.Returns.Error = errors.New("login error")
+
+				err := restarter.Initially()
+
+				Expect(err).To(MatchError(state.LoginError{foundationURL, []byte("login output")}))
+				Eventually(response).Should(Say("login output"))
+			})
+		})
+
+		It("logs in with the cf context", func() {
+			Expect(restarter.Initially()).To(Succeed())
+
+			Expect(courier.LoginCall.Received.FoundationURL).To(Equal(foundationURL))
+			Expect(courier.LoginCall.Received.Org).To(Equal(restarter.CFContext.Organization))
+			Expect(courier.LoginCall.Received.Space).To(Equal(restarter.CFContext.Space))
+		})
+	})
+
+	Describe("executing", func() {
+		Context("when the app does not exist", func() {
+			It("returns an ExistsError", func() {
+				courier.ExistsCall.Returns.Bool = false
+
+				err := restarter.Execute()
+
+				Expect(err).To(MatchError(state.ExistsError{ApplicationName: appName}))
+				Expect(courier.RestartCall.Received.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when restaging", func() {
+			BeforeEach(func() {
+				restarter.Restage = true
+			})
+
+			It("restages the app and streams the output", func() {
+				courier.RestageCall.Returns.Output = []byte("restage output")
+
+				Expect(restarter.Execute()).To(Succeed())
+
+				Expect(courier.RestageCall.Received.AppName).To(Equal(appName))
+				Expect(courier.RestartCall.Received.AppName).To(BeEmpty())
+				Eventually(response).Should(Say("restage output"))
+			})
+
+			It("returns a RestageError when restage fails", func() {
+				courier.RestageCall.Returns.Output = []byte("restage output")
+				courier.RestageCall.Returns.Error = errors.New("restage error")
+
+				err := restarter.Execute()
+
+				Expect(err).To(MatchError(state.RestageError{ApplicationName: appName, Out: []byte("restage output")}))
+			})
+		})
+
+		Context("when restarting", func() {
+			It("restarts the app and streams the output", func() {
+				courier.RestartCall.Returns.Output = []byte("restart output")
+
+				Expect(restarter.Execute()).To(Succeed())
+
+				Expect(courier.RestartCall.Received.AppName).To(Equal(appName))
+				Expect(courier.RestageCall.Received.AppName).To(BeEmpty())
+				Eventually(response).Should(Say("restart output"))
+			})
+
+			It("returns a RestartError when restart fails", func() {
+				courier.RestartCall.Returns.Output = []byte("restart output")
+				courier.RestartCall.Returns.Error = errors.New("restart error")
+
+				err := restarter.Execute()
+
+				Expect(err).To(MatchError(state.RestartError{ApplicationName: appName, Out: []byte("restart output")}))
+			})
+		})
+	})
+
+	Describe("undo", func() {
+		It("does not change the app", func() {
+			Expect(restarter.Undo()).To(Succeed())
+
+			Expect(courier.RestartCall.Received.AppName).To(BeEmpty())
+			Eventually(logBuffer).Should(Say("will not be rolled back"))
+		})
+	})
+})
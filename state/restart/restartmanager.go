@@ -0,0 +1,105 @@
+package restart
+
+import (
+	"io"
+
+	"fmt"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+	"net/http"
+	"regexp"
+)
+
+type courierCreator interface {
+	CreateCourier() (I.Courier, error)
+}
+
+// RestartManager creates a Restarter for each foundation. When Restage is true the application is
+// restaged instead of restarted.
+type RestartManager struct {
+	CourierCreator  courierCreator
+	EventManager    I.EventManager
+	Logger          I.DeploymentLogger
+	DeployEventData S.DeployEventData
+	Restage         bool
+}
+
+func (a RestartManager) SetUp() error {
+	return nil
+}
+
+func (a RestartManager) OnStart() error {
+	return nil
+}
+
+func (a RestartManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
+	if err != nil {
+		fmt.Fprintf(response, "\nYour application was not successfully %s on all foundations: %s\n\n", past(a.Restage), err.Error())
+		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+		}
+		return I.DeployResponse{
+			Error:      err,
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	a.Logger.Infof("successfully %s application %s", past(a.Restage), a.DeployEventData.DeploymentInfo.AppName)
+	fmt.Fprintf(response, "\nYour %s was successful! (^_^)b\n\n", operation(a.Restage))
+
+	return I.DeployResponse{StatusCode: http.StatusOK}
+}
+
+func (a RestartManager) CleanUp() {}
+
+func (a RestartManager) Create(environment S.Environment, response io.ReadWriter, foundationURL string) (I.Action, error) {
+	courier, err := a.CourierCreator.CreateCourier()
+	if err != nil {
+		a.Logger.Error(err)
+		return &Restarter{}, state.CourierCreationError{Err: err}
+	}
+	r := &Restarter{
+		Courier: courier,
+		CFContext: I.CFContext{
+			Environment:  environment.Name,
+			Organization: a.DeployEventData.DeploymentInfo.Org,
+			Space:        a.DeployEventData.DeploymentInfo.Space,
+			Application:  a.DeployEventData.DeploymentInfo.AppName,
+			SkipSSL:      a.DeployEventData.DeploymentInfo.SkipSSL,
+		},
+		Authorization: I.Authorization{
+			Username: a.DeployEventData.DeploymentInfo.Username,
+			Password: a.DeployEventData.DeploymentInfo.Password,
+		},
+		EventManager:  a.EventManager,
+		Response:      response,
+		Log:           a.Logger,
+		FoundationURL: foundationURL,
+		AppName:       a.DeployEventData.DeploymentInfo.AppName,
+		Restage:       a.Restage,
+		Data:          a.DeployEventData.DeploymentInfo.Data,
+	}
+
+	return r, nil
+}
+
+func (a RestartManager) InitiallyError(initiallyErrors []error) error {
+	return bluegreen.LoginError{LoginErrors: initiallyErrors}
+}
+
+func (a RestartManager) ExecuteError(executeErrors []error) error {
+	return bluegreen.RestartError{Operation: operation(a.Restage), Errors: executeErrors}
+}
+
+func (a RestartManager) UndoError(executeErrors, undoErrors []error) error {
+	return bluegreen.RollbackRestartError{Operation: operation(a.Restage), RestartErrors: executeErrors, RollbackErrors: undoErrors}
+}
+
+func (a RestartManager) SuccessError(successErrors []error) error {
+	return bluegreen.FinishRestartError{Operation: operation(a.Restage), FinishRestartErrors: successErrors}
+}
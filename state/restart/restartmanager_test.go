@@ -0,0 +1,128 @@
+package restart_test
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state/restart"
+	"github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+type courierCreator struct {
+	CourierCreatorFn func() (interfaces.Courier, error)
+}
+
+func (c courierCreator) CreateCourier() (interfaces.Courier, error) {
+	if c.CourierCreatorFn != nil {
+		return c.CourierCreatorFn()
+	}
+
+	return &mocks.Courier{}, nil
+}
+
+var _ = Describe("Restartmanager", func() {
+	var (
+		response       *gbytes.Buffer
+		restartManager restart.RestartManager
+		creator        *courierCreator
+	)
+
+	BeforeEach(func() {
+		log := interfaces.DefaultLogger(gbytes.NewBuffer(), logging.DEBUG, "restartmanager tests")
+		response = gbytes.NewBuffer()
+		creator = &courierCreator{}
+
+		restartManager = restart.RestartManager{
+			CourierCreator: creator,
+			Logger:         interfaces.DeploymentLogger{Log: log, UUID: randomizer.StringRunes(10)},
+			DeployEventData: structs.DeployEventData{
+				DeploymentInfo: &structs.DeploymentInfo{
+					AppName:  "myApp",
+					Username: "bob",
+					Password: "password",
+				},
+				Response: response,
+			},
+			Restage: true,
+		}
+	})
+
+	Describe("Create", func() {
+		It("should return a Restarter object with correct data", func() {
+			env := structs.Environment{Name: "myEnv"}
+			foundationURL := "foundation url"
+
+			restarter, err := restartManager.Create(env, response, foundationURL)
+			Expect(err).ToNot(HaveOccurred())
+
+			restarterData := restarter.(*restart.Restarter)
+			Expect(restarterData.CFContext.Application).Should(Equal("myApp"))
+			Expect(restarterData.CFContext.Environment).Should(Equal("myEnv"))
+			Expect(restarterData.Authorization.Username).Should(Equal("bob"))
+			Expect(restarterData.Authorization.Password).Should(Equal("password"))
+			Expect(restarterData.FoundationURL).Should(Equal(foundationURL))
+			Expect(restarterData.Restage).Should(BeTrue())
+		})
+
+		Context("when courier build failed", func() {
+			It("should return an error", func() {
+				creator.CourierCreatorFn = func() (interfaces.Courier, error) {
+					return nil, errors.New("a test error")
+				}
+
+				_, err := restartManager.Create(structs.Environment{}, response, "foundation url")
+				Expect(err).Should(MatchError(ContainSubstring("a test error")))
+			})
+		})
+	})
+
+	Describe("errors", func() {
+		It("should return RestartError naming the operation on execute failure", func() {
+			err := restartManager.ExecuteError([]error{errors.New("first error")})
+
+			Expect(err).Should(MatchError(bluegreen.RestartError{Operation: "restage", Errors: []error{errors.New("first error")}}))
+			Expect(err.Error()).Should(HavePrefix("restage failed"))
+		})
+
+		It("should return RollbackRestartError on undo failure", func() {
+			restartManager.Restage = false
+
+			err := restartManager.UndoError([]error{errors.New("execute error")}, []error{errors.New("undo error")})
+
+			Expect(err.Error()).Should(HavePrefix("restart failed"))
+			Expect(err).Should(BeAssignableToTypeOf(bluegreen.RollbackRestartError{}))
+		})
+	})
+
+	Describe("OnFinish", func() {
+		It("returns http.StatusOK and prints a success message", func() {
+			deployResponse := restartManager.OnFinish(structs.Environment{}, response, nil)
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusOK))
+			Eventually(response).Should(gbytes.Say("Your restage was successful!"))
+		})
+
+		It("returns http.StatusBadRequest when login failed", func() {
+			deployResponse := restartManager.OnFinish(structs.Environment{}, response, errors.New("login failed"))
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+		})
+
+		It("returns http.StatusInternalServerError otherwise", func() {
+			restartManager.Restage = false
+
+			deployResponse := restartManager.OnFinish(structs.Environment{}, response, errors.New("restart failed"))
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+			Eventually(response).Should(gbytes.Say("not successfully restarted"))
+		})
+	})
+})
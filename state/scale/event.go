@@ -0,0 +1,134 @@
+package scale
+
+import (
+	"reflect"
+
+	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/structs"
+	"github.com/go-errors/errors"
+	"io"
+)
+
+type eventBinding struct {
+	etype   reflect.Type
+	handler func(event interface{}) error
+}
+
+func (s eventBinding) Accepts(event interface{}) bool {
+	return reflect.TypeOf(event) == s.etype
+}
+
+func (b eventBinding) Emit(event interface{}) error {
+	return b.handler(event)
+}
+
+type ScaleFailureEvent struct {
+	CFContext     interfaces.CFContext
+	Data          map[string]interface{}
+	Environment   structs.Environment
+	Authorization interfaces.Authorization
+	Response      io.ReadWriter
+	Error         error
+	Log           interfaces.DeploymentLogger
+}
+
+func (e ScaleFailureEvent) Name() string {
+	return "ScaleFailureEvent"
+}
+
+func NewScaleFailureEventBinding(handler func(event ScaleFailureEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(ScaleFailureEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(ScaleFailureEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{Err: errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type ScaleSuccessEvent struct {
+	CFContext     interfaces.CFContext
+	Data          map[string]interface{}
+	Environment   structs.Environment
+	Authorization interfaces.Authorization
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e ScaleSuccessEvent) Name() string {
+	return "ScaleSuccessEvent"
+}
+
+func NewScaleSuccessEventBinding(handler func(event ScaleSuccessEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(ScaleSuccessEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(ScaleSuccessEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{Err: errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type ScaleStartedEvent struct {
+	CFContext     interfaces.CFContext
+	Spec          structs.ScaleSpec
+	Data          map[string]interface{}
+	Environment   structs.Environment
+	Authorization interfaces.Authorization
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e ScaleStartedEvent) Name() string {
+	return "ScaleStartedEvent"
+}
+
+func NewScaleStartedEventBinding(handler func(event ScaleStartedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(ScaleStartedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(ScaleStartedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{Err: errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type ScaleFinishedEvent struct {
+	CFContext     interfaces.CFContext
+	Data          map[string]interface{}
+	Authorization interfaces.Authorization
+	Response      io.ReadWriter
+	Environment   structs.Environment
+	Log           interfaces.DeploymentLogger
+}
+
+func (e ScaleFinishedEvent) Name() string {
+	return "ScaleFinishedEvent"
+}
+
+func NewScaleFinishedEventBinding(handler func(event ScaleFinishedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(ScaleFinishedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(ScaleFinishedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{Err: errors.New("invalid event type")}
+			}
+		},
+	}
+}
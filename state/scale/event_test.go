@@ -0,0 +1,223 @@
+package scale_test
+
+import (
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state/scale"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("event binding", func() {
+	Describe("ScaleStartedEventBinding", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					stopBind := scale.NewScaleStartedEventBinding(nil)
+
+					stopEvent := scale.ScaleStartedEvent{}
+					Expect(stopBind.Accepts(stopEvent)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					stopBind := scale.NewScaleStartedEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(stopBind.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					scaleFunc := func(event scale.ScaleStartedEvent) error {
+						invoked = true
+						return nil
+					}
+					stopBind := scale.NewScaleStartedEventBinding(scaleFunc)
+					stopEvent := scale.ScaleStartedEvent{}
+					stopBind.Emit(stopEvent)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					scaleFunc := func(event scale.ScaleStartedEvent) error {
+						invoked = true
+						return nil
+					}
+					scaleBind := scale.NewScaleStartedEventBinding(scaleFunc)
+					event := interfaces.Event{}
+					err := scaleBind.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+
+	})
+	Describe("ScaleSuccessEventBinding", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					scaleSuccessBind := scale.NewScaleSuccessEventBinding(nil)
+
+					scaleEvent := scale.ScaleSuccessEvent{}
+					Expect(scaleSuccessBind.Accepts(scaleEvent)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					scaleSuccessBind := scale.NewScaleSuccessEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(scaleSuccessBind.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					scaleFunc := func(event scale.ScaleSuccessEvent) error {
+						invoked = true
+						return nil
+					}
+					stopSuccessBind := scale.NewScaleSuccessEventBinding(scaleFunc)
+					stopEvent := scale.ScaleSuccessEvent{}
+					stopSuccessBind.Emit(stopEvent)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					scaleFunc := func(event scale.ScaleSuccessEvent) error {
+						invoked = true
+						return nil
+					}
+					scaleSuccessBind := scale.NewScaleSuccessEventBinding(scaleFunc)
+					event := interfaces.Event{}
+					err := scaleSuccessBind.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+
+	})
+	Describe("ScaleFailureEventBinding", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					binding := scale.NewScaleFailureEventBinding(nil)
+
+					scaleEvent := scale.ScaleFailureEvent{}
+					Expect(binding.Accepts(scaleEvent)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					binding := scale.NewScaleFailureEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(binding.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					scaleFunc := func(event scale.ScaleFailureEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := scale.NewScaleFailureEventBinding(scaleFunc)
+					scaleEvent := scale.ScaleFailureEvent{}
+					binding.Emit(scaleEvent)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					scaleFunc := func(event scale.ScaleFailureEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := scale.NewScaleFailureEventBinding(scaleFunc)
+					event := interfaces.Event{}
+					err := binding.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+
+	})
+	Describe("StartFinishEventBinding", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					binding := scale.NewScaleFinishedEventBinding(nil)
+
+					event := scale.ScaleFinishedEvent{}
+					Expect(binding.Accepts(event)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					binding := scale.NewScaleFinishedEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(binding.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					scaleFunc := func(event scale.ScaleFinishedEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := scale.NewScaleFinishedEventBinding(scaleFunc)
+					stopEvent := scale.ScaleFinishedEvent{}
+					binding.Emit(stopEvent)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					scaleFunc := func(event scale.ScaleFinishedEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := scale.NewScaleFinishedEventBinding(scaleFunc)
+					event := interfaces.Event{}
+					err := binding.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+
+	})
+})
@@ -0,0 +1,199 @@
+package scale
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"io"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type ScaleControllerConstructor func(log I.DeploymentLogger, deployer I.Deployer, conf config.Config, eventManager I.EventManager, errorFinder I.ErrorFinder, scaleManagerFactory I.ScaleManagerFactory) I.ScaleController
+
+func NewScaleController(l I.DeploymentLogger, d I.Deployer, c config.Config, em I.EventManager, ef I.ErrorFinder, smf I.ScaleManagerFactory) I.ScaleController {
+	return &ScaleController{
+		Deployer:            d,
+		Config:              c,
+		EventManager:        em,
+		ErrorFinder:         ef,
+		ScaleManagerFactory: smf,
+		Log:                 l,
+	}
+}
+
+// ScaleController is used to determine the type of request and process it accordingly.
+type ScaleController struct {
+	Log                 I.DeploymentLogger
+	ScaleManagerFactory I.ScaleManagerFactory
+	Deployer            I.Deployer
+	Config              config.Config
+	EventManager        I.EventManager
+	ErrorFinder         I.ErrorFinder
+}
+
+func (c *ScaleController) ScaleDeployment(deployment *I.Deployment, spec structs.ScaleSpec, data map[string]interface{}, response *bytes.Buffer) (deployResponse I.DeployResponse) {
+	cf := deployment.CFContext
+	c.Log.Debugf("Preparing to scale %s with UUID %s", cf.Application, c.Log.UUID)
+
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	environment, err := c.resolveEnvironment(cf.Environment)
+	if err != nil {
+		fmt.Fprintln(response, err.Error())
+		return I.DeployResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err,
+		}
+	}
+	auth, err := c.resolveAuthorization(deployment.Authorization, environment, c.Log)
+	if err != nil {
+		return I.DeployResponse{
+			StatusCode: http.StatusUnauthorized,
+			Error:      err,
+		}
+	}
+
+	deploymentInfo := &structs.DeploymentInfo{
+		Org:          cf.Organization,
+		Space:        cf.Space,
+		AppName:      cf.Application,
+		Environment:  cf.Environment,
+		UUID:         c.Log.UUID,
+		Domain:       environment.Domain,
+		SkipSSL:      environment.SkipSSL,
+		CustomParams: environment.CustomParams,
+		Username:     auth.Username,
+		Password:     auth.Password,
+		Data:         data,
+	}
+
+	defer c.emitScaleFinish(response, c.Log, cf, &auth, &environment, data, &deployResponse)
+	defer c.emitScaleSuccessOrFailure(response, c.Log, cf, &auth, &environment, data, &deployResponse)
+
+	err = c.EventManager.EmitEvent(ScaleStartedEvent{
+		CFContext:     cf,
+		Authorization: auth,
+		Environment:   environment,
+		Spec:          spec,
+		Data:          data,
+		Response:      response,
+		Log:           c.Log,
+	})
+	if err != nil {
+		c.Log.Error(err)
+		err = &bluegreen.InitializationError{err}
+		return I.DeployResponse{
+			StatusCode:     http.StatusInternalServerError,
+			Error:          deployer.EventError{Type: "ScaleStartedEvent", Err: err},
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	deployEventData := structs.DeployEventData{Response: response, DeploymentInfo: deploymentInfo}
+
+	manager := c.ScaleManagerFactory.ScaleManager(c.Log, deployEventData, spec)
+	deployResponse = *c.Deployer.Deploy(deploymentInfo, environment, manager, response)
+	return deployResponse
+}
+
+func (c *ScaleController) resolveAuthorization(auth I.Authorization, envs structs.Environment, deploymentLogger I.DeploymentLogger) (I.Authorization, error) {
+	config := c.Config
+	deploymentLogger.Debug("checking for basic auth")
+	if auth.Username == "" && auth.Password == "" {
+		username, password, ok := config.FallbackAuthorization(envs)
+		if !ok {
+			return I.Authorization{}, deployer.BasicAuthError{}
+		}
+		auth.Username = username
+		auth.Password = password
+	}
+
+	return auth, nil
+}
+
+func (c *ScaleController) resolveEnvironment(env string) (structs.Environment, error) {
+	config := c.Config
+	environment, ok := config.Environments[env]
+	if !ok {
+		return structs.Environment{}, deployer.EnvironmentNotFoundError{env}
+	}
+	return environment, nil
+}
+
+func (c ScaleController) emitScaleFinish(response io.ReadWriter, deploymentLogger I.DeploymentLogger, cfContext I.CFContext, auth *I.Authorization, environment *structs.Environment, data map[string]interface{}, deployResponse *I.DeployResponse) {
+	var event I.IEvent
+	event = ScaleFinishedEvent{
+		CFContext:     cfContext,
+		Authorization: *auth,
+		Data:          data,
+		Environment:   *environment,
+		Log:           deploymentLogger,
+	}
+	deploymentLogger.Debugf("emitting a %s event", event.Name())
+	c.EventManager.EmitEvent(event)
+}
+
+func (c ScaleController) emitScaleSuccessOrFailure(response io.ReadWriter, deploymentLogger I.DeploymentLogger, cfContext I.CFContext, auth *I.Authorization, environment *structs.Environment, data map[string]interface{}, deployResponse *I.DeployResponse) {
+	var event I.IEvent
+
+	if deployResponse.Error != nil {
+		c.printErrors(response, &deployResponse.Error)
+		event = ScaleFailureEvent{
+			CFContext:     cfContext,
+			Authorization: *auth,
+			Environment:   *environment,
+			Data:          data,
+			Response:      response,
+			Error:         deployResponse.Error,
+			Log:           deploymentLogger,
+		}
+
+	} else {
+		event = ScaleSuccessEvent{
+			CFContext:     cfContext,
+			Authorization: *auth,
+			Environment:   *environment,
+			Data:          data,
+			Response:      response,
+			Log:           deploymentLogger,
+		}
+	}
+	deploymentLogger.Debugf("emitting a %s event", event.Name())
+	eventErr := c.EventManager.EmitEvent(event)
+	if eventErr != nil {
+		deploymentLogger.Errorf("an error occurred when emitting a %s event: %s", event.Name(), eventErr)
+		fmt.Fprintln(response, eventErr)
+	}
+}
+
+func (c ScaleController) printErrors(response io.ReadWriter, err *error) {
+	tempBuffer := bytes.Buffer{}
+	tempBuffer.ReadFrom(response)
+	fmt.Fprint(response, state.TruncateOutput(tempBuffer.String(), c.Config.ErrorOutput, c.Config.DeploymentLog, c.Log.UUID))
+
+	errors := c.ErrorFinder.FindErrors(tempBuffer.String())
+	if len(errors) > 0 {
+		*err = errors[0]
+		for _, error := range errors {
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "*******************")
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "The following error was found in the above logs: "+error.Error())
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "Error: "+error.Details()[0])
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "Potential solution: "+error.Solution())
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "*******************")
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package scale_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/compozed/deployadactyl/config"
+	D "github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	. "github.com/compozed/deployadactyl/state/scale"
+	"github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("ScaleDeployment", func() {
+	var (
+		scaleManagerFactory *mocks.ScaleManagerFactory
+		eventManager        *mocks.EventManager
+		deployer            *mocks.Deployer
+		controller          *ScaleController
+		deployment          *I.Deployment
+		spec                structs.ScaleSpec
+		environment         string
+		response            *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		environment = "environment-" + randomizer.StringRunes(10)
+		instances := uint16(4)
+		spec = structs.ScaleSpec{Instances: &instances}
+
+		eventManager = &mocks.EventManager{}
+		deployer = &mocks.Deployer{}
+		scaleManagerFactory = &mocks.ScaleManagerFactory{}
+
+		controller = &ScaleController{
+			Log:                 I.DeploymentLogger{Log: I.DefaultLogger(NewBuffer(), logging.DEBUG, "api_test"), UUID: randomizer.StringRunes(10)},
+			Deployer:            deployer,
+			ScaleManagerFactory: scaleManagerFactory,
+			EventManager:        eventManager,
+			Config:              config.Config{Environments: map[string]structs.Environment{environment: {}}},
+			ErrorFinder:         &mocks.ErrorFinder{},
+		}
+
+		deployment = &I.Deployment{
+			Authorization: I.Authorization{Username: "myUser"},
+			CFContext: I.CFContext{
+				Organization: "myOrg",
+				Space:        "mySpace",
+				Application:  "myApp",
+				Environment:  environment,
+			},
+		}
+		response = &bytes.Buffer{}
+	})
+
+	It("should emit a ScaleStartedEvent with the spec", func() {
+		controller.ScaleDeployment(deployment, spec, nil, response)
+
+		event := eventManager.EmitEventCall.Received.Events[0].(ScaleStartedEvent)
+		Expect(event.CFContext.Application).Should(Equal("myApp"))
+		Expect(event.Spec).Should(Equal(spec))
+	})
+
+	It("should create a scale manager with the spec", func() {
+		manager := &mocks.StartManager{}
+		scaleManagerFactory.ScaleManagerCall.Returns.ActionCreater = manager
+
+		controller.ScaleDeployment(deployment, spec, nil, response)
+
+		Expect(scaleManagerFactory.ScaleManagerCall.Received.Spec).Should(Equal(spec))
+		Expect(scaleManagerFactory.ScaleManagerCall.Received.DeployEventData.DeploymentInfo.Username).Should(Equal("myUser"))
+		Expect(deployer.DeployCall.Received.ActionCreator).Should(Equal(manager))
+	})
+
+	Context("when the environment does not exist", func() {
+		It("should return an EnvironmentNotFoundError", func() {
+			deployment.CFContext.Environment = "not-an-environment"
+
+			deployResponse := controller.ScaleDeployment(deployment, spec, nil, response)
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+			Expect(reflect.TypeOf(deployResponse.Error)).Should(Equal(reflect.TypeOf(D.EnvironmentNotFoundError{})))
+			Expect(deployer.DeployCall.Called).Should(Equal(0))
+		})
+	})
+
+	Context("when ScaleStartedEvent fails", func() {
+		It("should return an EventError", func() {
+			eventManager.EmitEventCall.Returns.Error = append(eventManager.EmitEventCall.Returns.Error, errors.New("anything"))
+
+			deployResponse := controller.ScaleDeployment(deployment, spec, nil, response)
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+			Expect(reflect.TypeOf(deployResponse.Error)).Should(Equal(reflect.TypeOf(D.EventError{})))
+		})
+	})
+
+	Context("when scale succeeds", func() {
+		It("should emit ScaleSuccessEvent and ScaleFinishedEvent", func() {
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+			controller.ScaleDeployment(deployment, spec, nil, response)
+
+			Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[1])).Should(Equal(reflect.TypeOf(ScaleSuccessEvent{})))
+			Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[2])).Should(Equal(reflect.TypeOf(ScaleFinishedEvent{})))
+		})
+	})
+
+	Context("when scale fails", func() {
+		It("should emit ScaleFailureEvent and ScaleFinishedEvent", func() {
+			deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+			deployer.DeployCall.Returns.Error = errors.New("scale failed")
+
+			deployResponse := controller.ScaleDeployment(deployment, spec, nil, response)
+
+			Expect(deployResponse.Error).Should(MatchError("scale failed"))
+			failure := eventManager.EmitEventCall.Received.Events[1].(ScaleFailureEvent)
+			Expect(failure.Error).Should(MatchError("scale failed"))
+			Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[2])).Should(Equal(reflect.TypeOf(ScaleFinishedEvent{})))
+		})
+	})
+})
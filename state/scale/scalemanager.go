@@ -0,0 +1,107 @@
+package scale
+
+import (
+	"io"
+
+	"fmt"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+	"net/http"
+	"regexp"
+)
+
+const successfulScale = `Your scale was successful! (^_^)b
+
+`
+
+type courierCreator interface {
+	CreateCourier() (I.Courier, error)
+}
+
+type ScaleManager struct {
+	CourierCreator  courierCreator
+	EventManager    I.EventManager
+	Logger          I.DeploymentLogger
+	DeployEventData S.DeployEventData
+	Spec            S.ScaleSpec
+}
+
+func (a ScaleManager) SetUp() error {
+	return nil
+}
+
+func (a ScaleManager) OnStart() error {
+	return nil
+}
+
+func (a ScaleManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
+	if err != nil {
+		fmt.Fprintf(response, "\nYour application was not successfully scaled on all foundations: %s\n\n", err.Error())
+		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+		}
+		return I.DeployResponse{
+			Error:      err,
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	a.Logger.Infof("successfully scaled application %s", a.DeployEventData.DeploymentInfo.AppName)
+	fmt.Fprintf(response, "\n%s", successfulScale)
+
+	return I.DeployResponse{StatusCode: http.StatusOK}
+}
+
+func (a ScaleManager) CleanUp() {}
+
+func (a ScaleManager) Create(environment S.Environment, response io.ReadWriter, foundationURL string) (I.Action, error) {
+	courier, err := a.CourierCreator.CreateCourier()
+	if err != nil {
+		a.Logger.Error(err)
+		return &Scaler{}, state.CourierCreationError{Err: err}
+	}
+	p := &Scaler{
+		Courier: courier,
+		CFContext: I.CFContext{
+			Environment:  environment.Name,
+			Organization: a.DeployEventData.DeploymentInfo.Org,
+			Space:        a.DeployEventData.DeploymentInfo.Space,
+			Application:  a.DeployEventData.DeploymentInfo.AppName,
+			SkipSSL:      a.DeployEventData.DeploymentInfo.SkipSSL,
+		},
+		Authorization: I.Authorization{
+			Username: a.DeployEventData.DeploymentInfo.Username,
+			Password: a.DeployEventData.DeploymentInfo.Password,
+		},
+		EventManager:  a.EventManager,
+		Response:      response,
+		Log:           a.Logger,
+		FoundationURL: foundationURL,
+		AppName:       a.DeployEventData.DeploymentInfo.AppName,
+		Spec:          a.Spec,
+		Data:          a.DeployEventData.DeploymentInfo.Data,
+	}
+
+	return p, nil
+}
+
+func (a ScaleManager) InitiallyError(initiallyErrors []error) error {
+	return bluegreen.LoginError{LoginErrors: initiallyErrors}
+}
+
+func (a ScaleManager) ExecuteError(executeErrors []error) error {
+	return bluegreen.ScaleError{Errors: executeErrors}
+}
+
+func (a ScaleManager) UndoError(executeErrors, undoErrors []error) error {
+	return bluegreen.RollbackScaleError{ScaleErrors: executeErrors, RollbackErrors: undoErrors}
+}
+
+func (a ScaleManager) SuccessError(successErrors []error) error {
+	return bluegreen.FinishScaleError{FinishScaleErrors: successErrors}
+}
@@ -0,0 +1,125 @@
+package scale_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state/scale"
+	"github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+type courierCreator struct {
+	CourierCreatorFn func() (interfaces.Courier, error)
+}
+
+func (c courierCreator) CreateCourier() (interfaces.Courier, error) {
+	if c.CourierCreatorFn != nil {
+		return c.CourierCreatorFn()
+	}
+
+	return &mocks.Courier{}, nil
+}
+
+var _ = Describe("Scalemanager", func() {
+	var (
+		response     *gbytes.Buffer
+		scaleManager scale.ScaleManager
+		creator      *courierCreator
+		instances    uint16
+	)
+
+	BeforeEach(func() {
+		log := interfaces.DefaultLogger(gbytes.NewBuffer(), logging.DEBUG, "scalemanager tests")
+		response = gbytes.NewBuffer()
+		creator = &courierCreator{}
+		instances = 2
+
+		scaleManager = scale.ScaleManager{
+			CourierCreator: creator,
+			Logger:         interfaces.DeploymentLogger{Log: log, UUID: randomizer.StringRunes(10)},
+			DeployEventData: structs.DeployEventData{
+				DeploymentInfo: &structs.DeploymentInfo{
+					AppName:  "myApp",
+					Username: "bob",
+					Password: "password",
+				},
+				Response: response,
+			},
+			Spec: structs.ScaleSpec{Instances: &instances},
+		}
+	})
+
+	Describe("Create", func() {
+		It("should return a Scaler object with correct data", func() {
+			env := structs.Environment{Name: "myEnv"}
+			foundationURL := "foundation url"
+
+			scaler, err := scaleManager.Create(env, response, foundationURL)
+			Expect(err).ToNot(HaveOccurred())
+
+			scalerData := scaler.(*scale.Scaler)
+			Expect(scalerData.CFContext.Application).Should(Equal("myApp"))
+			Expect(scalerData.CFContext.Environment).Should(Equal("myEnv"))
+			Expect(scalerData.Authorization.Username).Should(Equal("bob"))
+			Expect(scalerData.Authorization.Password).Should(Equal("password"))
+			Expect(scalerData.FoundationURL).Should(Equal(foundationURL))
+			Expect(*scalerData.Spec.Instances).Should(Equal(uint16(2)))
+		})
+
+		Context("when courier build failed", func() {
+			It("should return an error", func() {
+				creator.CourierCreatorFn = func() (interfaces.Courier, error) {
+					return nil, errors.New("a test error")
+				}
+
+				_, err := scaleManager.Create(structs.Environment{}, response, "foundation url")
+				Expect(err).Should(MatchError(ContainSubstring("a test error")))
+			})
+		})
+	})
+
+	Describe("errors", func() {
+		It("should return ScaleError on execute failure", func() {
+			err := scaleManager.ExecuteError([]error{errors.New("first error")})
+
+			Expect(reflect.TypeOf(err)).Should(Equal(reflect.TypeOf(bluegreen.ScaleError{})))
+		})
+
+		It("should return RollbackScaleError on undo failure", func() {
+			err := scaleManager.UndoError([]error{errors.New("execute error")}, []error{errors.New("undo error")})
+
+			Expect(reflect.TypeOf(err)).Should(Equal(reflect.TypeOf(bluegreen.RollbackScaleError{})))
+		})
+	})
+
+	Describe("OnFinish", func() {
+		It("returns http.StatusOK and prints a success message", func() {
+			deployResponse := scaleManager.OnFinish(structs.Environment{}, response, nil)
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusOK))
+			Eventually(response).Should(gbytes.Say("Your scale was successful!"))
+		})
+
+		It("returns http.StatusBadRequest when login failed", func() {
+			deployResponse := scaleManager.OnFinish(structs.Environment{}, response, errors.New("login failed"))
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+		})
+
+		It("returns http.StatusInternalServerError otherwise", func() {
+			deployResponse := scaleManager.OnFinish(structs.Environment{}, response, errors.New("scale failed"))
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+			Eventually(response).Should(gbytes.Say("not successfully scaled"))
+		})
+	})
+})
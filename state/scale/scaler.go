@@ -0,0 +1,134 @@
+package scale
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+type Scaler struct {
+	Courier       I.Courier
+	CFContext     I.CFContext
+	Authorization I.Authorization
+	EventManager  I.EventManager
+	Response      io.ReadWriter
+	Log           I.DeploymentLogger
+	FoundationURL string
+	AppName       string
+	Spec          S.ScaleSpec
+	Data          map[string]interface{}
+}
+
+func (s Scaler) Verify() error {
+	return nil
+}
+
+func (s Scaler) Success() error {
+	return nil
+}
+
+func (s Scaler) Finally() error {
+	return nil
+}
+
+// Login will login to a Cloud Foundry instance.
+func (s Scaler) Initially() error {
+	s.Log.Debugf(
+		`logging into cloud foundry with parameters:
+		foundation URL: %+v
+		username: %+v
+		org: %+v
+		space: %+v`,
+		s.FoundationURL, s.Authorization.Username, s.CFContext.Organization, s.CFContext.Space,
+	)
+
+	output, err := s.Courier.Login(
+		s.FoundationURL,
+		s.Authorization.Username,
+		s.Authorization.Password,
+		s.CFContext.Organization,
+		s.CFContext.Space,
+		s.CFContext.SkipSSL,
+	)
+	s.Response.Write(output)
+	if err != nil {
+		s.Log.Errorf("could not login to %s", s.FoundationURL)
+		return state.LoginError{s.FoundationURL, output}
+	}
+
+	s.Log.Infof("logged into cloud foundry %s", s.FoundationURL)
+
+	return nil
+}
+
+// Execute scales the application and sets its environment variables.
+// The application is restarted if any environment variables were changed.
+func (s Scaler) Execute() error {
+
+	if s.Courier.Exists(s.AppName) != true {
+		s.Log.Errorf("failed to scale app on foundation %s: application doesn't exist", s.FoundationURL)
+		return state.ExistsError{ApplicationName: s.AppName}
+	}
+
+	if s.Spec.Instances != nil || s.Spec.Memory != "" {
+		var instances string
+		if s.Spec.Instances != nil {
+			instances = strconv.Itoa(int(*s.Spec.Instances))
+		}
+
+		s.Log.Infof("scaling app %s", s.AppName)
+
+		output, err := s.Courier.Scale(s.AppName, instances, s.Spec.Memory)
+		if err != nil {
+			s.Log.Errorf("failed to scale app on foundation %s: %s", s.FoundationURL, err.Error())
+			return state.ScaleError{ApplicationName: s.AppName, Out: output}
+		}
+		s.Response.Write(output)
+	}
+
+	if len(s.Spec.EnvironmentVariables) == 0 {
+		s.Log.Infof("successfully scaled app %s", s.AppName)
+		return nil
+	}
+
+	names := make([]string, 0, len(s.Spec.EnvironmentVariables))
+	for name := range s.Spec.EnvironmentVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s.Log.Debugf("setting environment variable %s on app %s", name, s.AppName)
+
+		output, err := s.Courier.SetEnv(s.AppName, name, s.Spec.EnvironmentVariables[name])
+		if err != nil {
+			s.Log.Errorf("failed to set environment variable on foundation %s: %s", s.FoundationURL, err.Error())
+			return state.SetEnvError{ApplicationName: s.AppName, Name: name, Out: output}
+		}
+		s.Response.Write(output)
+	}
+
+	s.Log.Infof("restarting app %s", s.AppName)
+
+	output, err := s.Courier.Restart(s.AppName)
+	if err != nil {
+		s.Log.Errorf("failed to restart app on foundation %s: %s", s.FoundationURL, err.Error())
+		return state.RestartError{ApplicationName: s.AppName, Out: output}
+	}
+	s.Response.Write(output)
+
+	s.Log.Infof("successfully scaled app %s", s.AppName)
+
+	return nil
+}
+
+// Undo does not revert a scale. The previous configuration of the application is not known.
+func (s Scaler) Undo() error {
+	s.Log.Infof("scale of app %s on foundation %s will not be rolled back", s.AppName, s.FoundationURL)
+
+	return nil
+}
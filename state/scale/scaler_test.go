@@ -0,0 +1,156 @@
+package scale_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/scale"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Scaler", func() {
+	var (
+		scaler        Scaler
+		courier       *mocks.Courier
+		appName       string
+		foundationURL string
+		response      *Buffer
+		logBuffer     *Buffer
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		appName = "appName-" + randomizer.StringRunes(10)
+		foundationURL = "foundationURL-" + randomizer.StringRunes(10)
+		response = NewBuffer()
+		logBuffer = NewBuffer()
+
+		courier.ExistsCall.Returns.Bool = true
+
+		scaler = Scaler{
+			Courier: courier,
+			CFContext: interfaces.CFContext{
+				Organization: "org-" + randomizer.StringRunes(10),
+				Space:        "space-" + randomizer.StringRunes(10),
+				Application:  appName,
+			},
+			Authorization: interfaces.Authorization{
+				Username: "username-" + randomizer.StringRunes(10),
+				Password: "password-" + randomizer.StringRunes(10),
+			},
+			Response:      response,
+			Log:           interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "scaler_test")},
+			FoundationURL: foundationURL,
+			AppName:       appName,
+		}
+	})
+
+	Describe("logging in", func() {
+		Context("when login fails", func() {
+			It("returns a LoginError", func() {
+				courier.LoginCall.Returns.Output = []byte("login output")
+				courier.LoginCall.Returns.Error = errors.New("login error")
+
+				err := scaler.Initially()
+
+				Expect(err).To(MatchError(state.LoginError{foundationURL, []byte("login output")}))
+				Eventually(response).Should(Say("login output"))
+			})
+		})
+
+		It("logs in with the cf context", func() {
+			Expect(scaler.Initially()).To(Succeed())
+
+			Expect(courier.LoginCall.Received.FoundationURL).To(Equal(foundationURL))
+			Expect(courier.LoginCall.Received.Org).To(Equal(scaler.CFContext.Organization))
+			Expect(courier.LoginCall.Received.Space).To(Equal(scaler.CFContext.Space))
+		})
+	})
+
+	Describe("executing", func() {
+		Context("when the app does not exist", func() {
+			It("returns an ExistsError", func() {
+				courier.ExistsCall.Returns.Bool = false
+
+				err := scaler.Execute()
+
+				Expect(err).To(MatchError(state.ExistsError{ApplicationName: appName}))
+			})
+		})
+
+		Context("when instances and memory are given", func() {
+			It("scales the app without restarting it", func() {
+				instances := uint16(3)
+				scaler.Spec = S.ScaleSpec{Instances: &instances, Memory: "1G"}
+				courier.ScaleCall.Returns.Output = []byte("scale output")
+
+				Expect(scaler.Execute()).To(Succeed())
+
+				Expect(courier.ScaleCall.Received.AppName).To(Equal(appName))
+				Expect(courier.ScaleCall.Received.Instances).To(Equal("3"))
+				Expect(courier.ScaleCall.Received.Memory).To(Equal("1G"))
+				Expect(courier.RestartCall.Received.AppName).To(BeEmpty())
+				Eventually(response).Should(Say("scale output"))
+			})
+
+			It("returns a ScaleError when scale fails", func() {
+				scaler.Spec = S.ScaleSpec{Memory: "1G"}
+				courier.ScaleCall.Returns.Output = []byte("scale output")
+				courier.ScaleCall.Returns.Error = errors.New("scale error")
+
+				err := scaler.Execute()
+
+				Expect(err).To(MatchError(state.ScaleError{ApplicationName: appName, Out: []byte("scale output")}))
+			})
+		})
+
+		Context("when environment variables are given", func() {
+			BeforeEach(func() {
+				scaler.Spec = S.ScaleSpec{EnvironmentVariables: map[string]string{"B_VAR": "b", "A_VAR": "a"}}
+			})
+
+			It("sets each variable in order and restarts the app", func() {
+				Expect(scaler.Execute()).To(Succeed())
+
+				Expect(courier.ScaleCall.Received.AppName).To(BeEmpty())
+				Expect(courier.SetEnvCall.Received.Names).To(Equal([]string{"A_VAR", "B_VAR"}))
+				Expect(courier.SetEnvCall.Received.Values).To(Equal([]string{"a", "b"}))
+				Expect(courier.RestartCall.Received.AppName).To(Equal(appName))
+			})
+
+			It("returns a SetEnvError when set-env fails", func() {
+				courier.SetEnvCall.Returns.Error = errors.New("set-env error")
+
+				err := scaler.Execute()
+
+				Expect(err).To(MatchError(state.SetEnvError{ApplicationName: appName, Name: "A_VAR"}))
+				Expect(courier.RestartCall.Received.AppName).To(BeEmpty())
+			})
+
+			It("returns a RestartError when restart fails", func() {
+				courier.RestartCall.Returns.Output = []byte("restart output")
+				courier.RestartCall.Returns.Error = errors.New("restart error")
+
+				err := scaler.Execute()
+
+				Expect(err).To(MatchError(state.RestartError{ApplicationName: appName, Out: []byte("restart output")}))
+			})
+		})
+	})
+
+	Describe("undo", func() {
+		It("does not change the app", func() {
+			Expect(scaler.Undo()).To(Succeed())
+
+			Expect(courier.ScaleCall.Received.AppName).To(BeEmpty())
+			Eventually(logBuffer).Should(Say("will not be rolled back"))
+		})
+	})
+})
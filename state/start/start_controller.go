@@ -11,6 +11,7 @@ import (
 	"github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/structs"
 )
 
@@ -107,12 +108,12 @@ func (c *StartController) resolveAuthorization(auth I.Authorization, envs struct
 	config := c.Config
 	deploymentLogger.Debug("checking for basic auth")
 	if auth.Username == "" && auth.Password == "" {
-		if envs.Authenticate {
+		username, password, ok := config.FallbackAuthorization(envs)
+		if !ok {
 			return I.Authorization{}, deployer.BasicAuthError{}
-
 		}
-		auth.Username = config.Username
-		auth.Password = config.Password
+		auth.Username = username
+		auth.Password = password
 	}
 
 	return auth, nil
@@ -176,7 +177,7 @@ func (c StartController) emitStartSuccessOrFailure(response io.ReadWriter, deplo
 func (c StartController) printErrors(response io.ReadWriter, err *error) {
 	tempBuffer := bytes.Buffer{}
 	tempBuffer.ReadFrom(response)
-	fmt.Fprint(response, tempBuffer.String())
+	fmt.Fprint(response, state.TruncateOutput(tempBuffer.String(), c.Config.ErrorOutput, c.Config.DeploymentLog, c.Log.UUID))
 
 	errors := c.ErrorFinder.FindErrors(tempBuffer.String())
 	if len(errors) > 0 {
@@ -31,7 +31,7 @@ func (s Starter) Finally() error {
 	return nil
 }
 
-// Login will login to a Cloud Foundry instance.
+// Initially will login to a Cloud Foundry instance and check that the application exists.
 func (s Starter) Initially() error {
 	s.Log.Debugf(
 		`logging into cloud foundry with parameters:
@@ -58,6 +58,11 @@ func (s Starter) Initially() error {
 
 	s.Log.Infof("logged into cloud foundry %s", s.FoundationURL)
 
+	if s.Courier.Exists(s.AppName) != true {
+		s.Log.Errorf("cannot start app on foundation %s: application doesn't exist", s.FoundationURL)
+		return state.ExistsError{ApplicationName: s.AppName}
+	}
+
 	return nil
 }
 
@@ -109,6 +109,10 @@ var _ = Describe("Starter", func() {
 
 	Describe("Initially", func() {
 		Context("when login succeeds", func() {
+			BeforeEach(func() {
+				courier.ExistsCall.Returns.Bool = true
+			})
+
 			It("gives the correct info to the courier", func() {
 
 				Expect(starter.Initially()).To(Succeed())
@@ -158,6 +162,17 @@ var _ = Describe("Starter", func() {
 				Eventually(logBuffer).Should(Say(fmt.Sprintf("could not login to %s", randomFoundationURL)))
 			})
 		})
+
+		Context("when the app does not exist", func() {
+			It("returns an ExistsError", func() {
+				courier.ExistsCall.Returns.Bool = false
+
+				err := starter.Initially()
+
+				Expect(err).To(MatchError(state.ExistsError{ApplicationName: randomAppName}))
+				Expect(courier.ExistsCall.Received.AppName).To(Equal(randomAppName))
+			})
+		})
 	})
 
 	Describe("Execute", func() {
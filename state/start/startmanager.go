@@ -38,6 +38,12 @@ func (a StartManager) OnStart() error {
 func (a StartManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
 	if err != nil {
 		fmt.Fprintf(response, "\nYour application was not successfully started on all foundations: %s\n\n", err.Error())
+		if _, ok := err.(state.ExistsError); ok {
+			return I.DeployResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      err,
+			}
+		}
 		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
 			return I.DeployResponse{
 				StatusCode: http.StatusBadRequest,
@@ -88,7 +94,14 @@ func (a StartManager) Create(environment S.Environment, response io.ReadWriter,
 	return p, nil
 }
 
+// InitiallyError returns the ExistsError if the application is missing from any foundation
+// so the start can be refused with a 404.
 func (a StartManager) InitiallyError(initiallyErrors []error) error {
+	for _, err := range initiallyErrors {
+		if existsErr, ok := err.(state.ExistsError); ok {
+			return existsErr
+		}
+	}
 	return bluegreen.LoginError{LoginErrors: initiallyErrors}
 }
 
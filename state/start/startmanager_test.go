@@ -13,6 +13,7 @@ import (
 	"github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/go-errors/errors"
 	"github.com/onsi/gomega/gbytes"
 	"github.com/op/go-logging"
@@ -55,7 +56,7 @@ var _ = Describe("Startmanager", func() {
 		creator = &courierCreator{}
 		startManager = start.StartManager{
 			CourierCreator: creator,
-			Logger:         interfaces.DeploymentLogger{log, randomizer.StringRunes(10)},
+			Logger:         interfaces.DeploymentLogger{Log: log, UUID: randomizer.StringRunes(10)},
 			DeployEventData: structs.DeployEventData{
 				DeploymentInfo: &structs.DeploymentInfo{},
 				Response:       response,
@@ -120,6 +121,13 @@ var _ = Describe("Startmanager", func() {
 
 			Expect(reflect.TypeOf(err)).Should(Equal(reflect.TypeOf(bluegreen.LoginError{})))
 		})
+
+		It("should return the ExistsError when the app is missing", func() {
+			existsErr := state.ExistsError{ApplicationName: "myApp"}
+			err := startManager.InitiallyError([]error{errors.New("first error"), existsErr})
+
+			Expect(err).Should(Equal(existsErr))
+		})
 	})
 
 	Describe("ExecuteError", func() {
@@ -192,6 +200,12 @@ var _ = Describe("Startmanager", func() {
 					Expect(deployResponse.StatusCode).To(Equal(http.StatusBadRequest))
 				})
 			})
+			It("returns http.StatusNotFound when the app does not exist", func() {
+				deployResponse := startManager.OnFinish(structs.Environment{}, response, state.ExistsError{ApplicationName: "myApp"})
+
+				Expect(deployResponse.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(deployResponse.Error).To(MatchError("app myApp doesn't exist"))
+			})
 			It("returns a internal server error", func() {
 				deployResponse := startManager.OnFinish(structs.Environment{}, response, errors.New("a test error"))
 
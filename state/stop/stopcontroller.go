@@ -7,6 +7,7 @@ import (
 	"github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/structs"
 	"io"
 	"net/http"
@@ -149,7 +150,7 @@ func (c StopController) emitStopSuccessOrFailure(response io.ReadWriter, deploym
 func (c StopController) printErrors(response io.ReadWriter, err *error) {
 	tempBuffer := bytes.Buffer{}
 	tempBuffer.ReadFrom(response)
-	fmt.Fprint(response, tempBuffer.String())
+	fmt.Fprint(response, state.TruncateOutput(tempBuffer.String(), c.Config.ErrorOutput, c.Config.DeploymentLog, c.Log.UUID))
 
 	errors := c.ErrorFinder.FindErrors(tempBuffer.String())
 	if len(errors) > 0 {
@@ -173,11 +174,12 @@ func (c *StopController) resolveAuthorization(auth I.Authorization, envs structs
 	config := c.Config
 	deploymentLogger.Debug("checking for basic auth")
 	if auth.Username == "" && auth.Password == "" {
-		if envs.Authenticate {
+		username, password, ok := config.FallbackAuthorization(envs)
+		if !ok {
 			return I.Authorization{}, deployer.BasicAuthError{}
 		}
-		auth.Username = config.Username
-		auth.Password = config.Password
+		auth.Username = username
+		auth.Password = password
 	}
 
 	return auth, nil
@@ -41,6 +41,12 @@ func (a StopManager) OnStart() error {
 func (a StopManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
 	if err != nil {
 		fmt.Fprintf(response, "\nYour application was not successfully stopped on all foundations: %s\n\n", err.Error())
+		if _, ok := err.(state.ExistsError); ok {
+			return I.DeployResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      err,
+			}
+		}
 		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
 			return I.DeployResponse{
 				StatusCode: http.StatusBadRequest,
@@ -91,7 +97,14 @@ func (a StopManager) Create(environment S.Environment, response io.ReadWriter, f
 	return p, nil
 }
 
+// InitiallyError returns the ExistsError if the application is missing from any foundation
+// so the stop can be refused with a 404.
 func (a StopManager) InitiallyError(initiallyErrors []error) error {
+	for _, err := range initiallyErrors {
+		if existsErr, ok := err.(state.ExistsError); ok {
+			return existsErr
+		}
+	}
 	return bluegreen.LoginError{LoginErrors: initiallyErrors}
 }
 
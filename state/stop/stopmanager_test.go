@@ -10,6 +10,7 @@ import (
 	"github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/go-errors/errors"
 	"github.com/onsi/gomega/gbytes"
 	"github.com/op/go-logging"
@@ -53,7 +54,7 @@ var _ = Describe("Stopmanager", func() {
 		creator = &courierCreator{}
 		stopManager = stop.StopManager{
 			CourierCreator: creator,
-			Log:            interfaces.DeploymentLogger{log, randomizer.StringRunes(10)},
+			Log:            interfaces.DeploymentLogger{Log: log, UUID: randomizer.StringRunes(10)},
 			DeployEventData: structs.DeployEventData{
 				DeploymentInfo: &structs.DeploymentInfo{},
 				Response:       response,
@@ -137,6 +138,12 @@ var _ = Describe("Stopmanager", func() {
 					Expect(deployResponse.StatusCode).To(Equal(http.StatusBadRequest))
 				})
 			})
+			It("returns http.StatusNotFound when the app does not exist", func() {
+				deployResponse := stopManager.OnFinish(structs.Environment{}, response, state.ExistsError{ApplicationName: "myApp"})
+
+				Expect(deployResponse.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(deployResponse.Error).To(MatchError("app myApp doesn't exist"))
+			})
 			It("returns a internal server error", func() {
 				deployResponse := stopManager.OnFinish(structs.Environment{}, response, errors.New("a test error"))
 
@@ -151,6 +158,13 @@ var _ = Describe("Stopmanager", func() {
 
 			Expect(reflect.TypeOf(err)).Should(Equal(reflect.TypeOf(bluegreen.LoginError{})))
 		})
+
+		It("should return the ExistsError when the app is missing", func() {
+			existsErr := state.ExistsError{ApplicationName: "myApp"}
+			err := stopManager.InitiallyError([]error{errors.New("first error"), existsErr})
+
+			Expect(err).Should(Equal(existsErr))
+		})
 	})
 	Describe("ExecuteError", func() {
 		It("should return StopError", func() {
@@ -29,7 +29,7 @@ func (s Stopper) Finally() error {
 	return nil
 }
 
-// Login will login to a Cloud Foundry instance.
+// Initially will login to a Cloud Foundry instance and check that the application exists.
 func (s Stopper) Initially() error {
 	s.Log.Debugf(
 		`logging into cloud foundry with parameters:
@@ -56,6 +56,11 @@ func (s Stopper) Initially() error {
 
 	s.Log.Infof("logged into cloud foundry %s", s.FoundationURL)
 
+	if s.Courier.Exists(s.AppName) != true {
+		s.Log.Errorf("cannot stop app on foundation %s: application doesn't exist", s.FoundationURL)
+		return state.ExistsError{ApplicationName: s.AppName}
+	}
+
 	return nil
 }
 
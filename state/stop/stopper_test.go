@@ -108,6 +108,10 @@ var _ = Describe("Stopper", func() {
 
 	Describe("Initially", func() {
 		Context("when login succeeds", func() {
+			BeforeEach(func() {
+				courier.ExistsCall.Returns.Bool = true
+			})
+
 			It("gives the correct info to the courier", func() {
 
 				Expect(stopper.Initially()).To(Succeed())
@@ -157,6 +161,17 @@ var _ = Describe("Stopper", func() {
 				Eventually(logBuffer).Should(Say(fmt.Sprintf("could not login to %s", randomFoundationURL)))
 			})
 		})
+
+		Context("when the app does not exist", func() {
+			It("returns an ExistsError", func() {
+				courier.ExistsCall.Returns.Bool = false
+
+				err := stopper.Initially()
+
+				Expect(err).To(MatchError(state.ExistsError{ApplicationName: randomAppName}))
+				Expect(courier.ExistsCall.Received.AppName).To(Equal(randomAppName))
+			})
+		})
 	})
 
 	Describe("Execute", func() {
@@ -0,0 +1,211 @@
+package undeploy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"io"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/constants"
+	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type DeleteControllerConstructor func(log I.DeploymentLogger, deployer I.Deployer, conf config.Config, eventManager I.EventManager, errorFinder I.ErrorFinder, deleteManagerFactory I.DeleteManagerFactory) I.DeleteController
+
+func NewDeleteController(l I.DeploymentLogger, d I.Deployer, c config.Config, em I.EventManager, ef I.ErrorFinder, dmf I.DeleteManagerFactory) I.DeleteController {
+	return &DeleteController{
+		Deployer:             d,
+		Config:               c,
+		EventManager:         em,
+		ErrorFinder:          ef,
+		DeleteManagerFactory: dmf,
+		Log:                  l,
+	}
+}
+
+// DeleteController is used to determine the type of request and process it accordingly.
+type DeleteController struct {
+	Log                  I.DeploymentLogger
+	DeleteManagerFactory I.DeleteManagerFactory
+	Deployer             I.Deployer
+	Config               config.Config
+	EventManager         I.EventManager
+	ErrorFinder          I.ErrorFinder
+}
+
+func (c *DeleteController) DeleteDeployment(deployment *I.Deployment, options structs.DeleteOptions, data map[string]interface{}, response *bytes.Buffer) (deployResponse I.DeployResponse) {
+	cf := deployment.CFContext
+	c.Log.Debugf("Preparing to delete %s with UUID %s", cf.Application, c.Log.UUID)
+
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	environment, err := c.resolveEnvironment(cf.Environment)
+	if err != nil {
+		fmt.Fprintln(response, err.Error())
+		return I.DeployResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err,
+		}
+	}
+	auth, err := c.resolveAuthorization(deployment.Authorization, environment, c.Log)
+	if err != nil {
+		return I.DeployResponse{
+			StatusCode: http.StatusUnauthorized,
+			Error:      err,
+		}
+	}
+
+	deploymentInfo := &structs.DeploymentInfo{
+		Org:          cf.Organization,
+		Space:        cf.Space,
+		AppName:      cf.Application,
+		Environment:  cf.Environment,
+		UUID:         c.Log.UUID,
+		Domain:       environment.Domain,
+		SkipSSL:      environment.SkipSSL,
+		CustomParams: environment.CustomParams,
+		Username:     auth.Username,
+		Password:     auth.Password,
+		Data:         data,
+	}
+
+	defer c.emitDeleteFinish(response, c.Log, cf, &auth, &environment, data, &deployResponse)
+	defer c.emitDeleteSuccessOrFailure(response, c.Log, cf, &auth, &environment, data, &deployResponse)
+
+	deployEventData := structs.DeployEventData{Response: response, DeploymentInfo: deploymentInfo}
+
+	c.Log.Debugf("emitting a %s event", constants.DeployDeleteEvent)
+	err = c.EventManager.Emit(I.Event{Type: constants.DeployDeleteEvent, Data: &deployEventData})
+	if err != nil {
+		c.Log.Error(err)
+		err = &bluegreen.InitializationError{err}
+		return I.DeployResponse{
+			StatusCode:     http.StatusInternalServerError,
+			Error:          deployer.EventError{Type: constants.DeployDeleteEvent, Err: err},
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	err = c.EventManager.EmitEvent(DeleteStartedEvent{
+		CFContext:     cf,
+		Authorization: auth,
+		Environment:   environment,
+		Data:          data,
+		Response:      response,
+		Log:           c.Log,
+	})
+	if err != nil {
+		c.Log.Error(err)
+		err = &bluegreen.InitializationError{err}
+		return I.DeployResponse{
+			StatusCode:     http.StatusInternalServerError,
+			Error:          deployer.EventError{Type: "DeleteStartedEvent", Err: err},
+			DeploymentInfo: deploymentInfo,
+		}
+	}
+
+	manager := c.DeleteManagerFactory.DeleteManager(c.Log, deployEventData, options)
+	deployResponse = *c.Deployer.Deploy(deploymentInfo, environment, manager, response)
+	return deployResponse
+}
+
+func (c *DeleteController) resolveAuthorization(auth I.Authorization, envs structs.Environment, deploymentLogger I.DeploymentLogger) (I.Authorization, error) {
+	config := c.Config
+	deploymentLogger.Debug("checking for basic auth")
+	if auth.Username == "" && auth.Password == "" {
+		username, password, ok := config.FallbackAuthorization(envs)
+		if !ok {
+			return I.Authorization{}, deployer.BasicAuthError{}
+		}
+		auth.Username = username
+		auth.Password = password
+	}
+
+	return auth, nil
+}
+
+func (c *DeleteController) resolveEnvironment(env string) (structs.Environment, error) {
+	config := c.Config
+	environment, ok := config.Environments[env]
+	if !ok {
+		return structs.Environment{}, deployer.EnvironmentNotFoundError{env}
+	}
+	return environment, nil
+}
+
+func (c DeleteController) emitDeleteFinish(response io.ReadWriter, deploymentLogger I.DeploymentLogger, cfContext I.CFContext, auth *I.Authorization, environment *structs.Environment, data map[string]interface{}, deployResponse *I.DeployResponse) {
+	var event I.IEvent
+	event = DeleteFinishedEvent{
+		CFContext:     cfContext,
+		Authorization: *auth,
+		Data:          data,
+		Environment:   *environment,
+		Log:           deploymentLogger,
+	}
+	deploymentLogger.Debugf("emitting a %s event", event.Name())
+	c.EventManager.EmitEvent(event)
+}
+
+func (c DeleteController) emitDeleteSuccessOrFailure(response io.ReadWriter, deploymentLogger I.DeploymentLogger, cfContext I.CFContext, auth *I.Authorization, environment *structs.Environment, data map[string]interface{}, deployResponse *I.DeployResponse) {
+	var event I.IEvent
+
+	if deployResponse.Error != nil {
+		c.printErrors(response, &deployResponse.Error)
+		event = DeleteFailureEvent{
+			CFContext:     cfContext,
+			Authorization: *auth,
+			Environment:   *environment,
+			Data:          data,
+			Response:      response,
+			Error:         deployResponse.Error,
+			Log:           deploymentLogger,
+		}
+
+	} else {
+		event = DeleteSuccessEvent{
+			CFContext:     cfContext,
+			Authorization: *auth,
+			Environment:   *environment,
+			Data:          data,
+			Response:      response,
+			Log:           deploymentLogger,
+		}
+	}
+	deploymentLogger.Debugf("emitting a %s event", event.Name())
+	eventErr := c.EventManager.EmitEvent(event)
+	if eventErr != nil {
+		deploymentLogger.Errorf("an error occurred when emitting a %s event: %s", event.Name(), eventErr)
+		fmt.Fprintln(response, eventErr)
+	}
+}
+
+func (c DeleteController) printErrors(response io.ReadWriter, err *error) {
+	tempBuffer := bytes.Buffer{}
+	tempBuffer.ReadFrom(response)
+	fmt.Fprint(response, state.TruncateOutput(tempBuffer.String(), c.Config.ErrorOutput, c.Config.DeploymentLog, c.Log.UUID))
+
+	errors := c.ErrorFinder.FindErrors(tempBuffer.String())
+	if len(errors) > 0 {
+		*err = errors[0]
+		for _, error := range errors {
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "*******************")
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "The following error was found in the above logs: "+error.Error())
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "Error: "+error.Details()[0])
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "Potential solution: "+error.Solution())
+			fmt.Fprintln(response)
+			fmt.Fprintln(response, "*******************")
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package undeploy_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/compozed/deployadactyl/config"
+	D "github.com/compozed/deployadactyl/controller/deployer"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	. "github.com/compozed/deployadactyl/state/undeploy"
+	"github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("DeleteDeployment", func() {
+	var (
+		deleteManagerFactory *mocks.DeleteManagerFactory
+		eventManager         *mocks.EventManager
+		deployer             *mocks.Deployer
+		controller           *DeleteController
+		deployment           *I.Deployment
+		options              structs.DeleteOptions
+		environment          string
+		response             *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		environment = "environment-" + randomizer.StringRunes(10)
+		options = structs.DeleteOptions{Routes: true}
+
+		eventManager = &mocks.EventManager{}
+		deployer = &mocks.Deployer{}
+		deleteManagerFactory = &mocks.DeleteManagerFactory{}
+
+		controller = &DeleteController{
+			Log:                  I.DeploymentLogger{Log: I.DefaultLogger(NewBuffer(), logging.DEBUG, "api_test"), UUID: randomizer.StringRunes(10)},
+			Deployer:             deployer,
+			DeleteManagerFactory: deleteManagerFactory,
+			EventManager:         eventManager,
+			Config:               config.Config{Environments: map[string]structs.Environment{environment: {}}},
+			ErrorFinder:          &mocks.ErrorFinder{},
+		}
+
+		deployment = &I.Deployment{
+			Authorization: I.Authorization{Username: "myUser"},
+			CFContext: I.CFContext{
+				Organization: "myOrg",
+				Space:        "mySpace",
+				Application:  "myApp",
+				Environment:  environment,
+			},
+		}
+		response = &bytes.Buffer{}
+	})
+
+	It("should emit a deploy.delete event", func() {
+		controller.DeleteDeployment(deployment, options, nil, response)
+
+		Expect(eventManager.EmitCall.Received.Events[0].Type).Should(Equal("deploy.delete"))
+		eventData := eventManager.EmitCall.Received.Events[0].Data.(*structs.DeployEventData)
+		Expect(eventData.DeploymentInfo.AppName).Should(Equal("myApp"))
+	})
+
+	It("should emit a DeleteStartedEvent", func() {
+		controller.DeleteDeployment(deployment, options, nil, response)
+
+		event := eventManager.EmitEventCall.Received.Events[0].(DeleteStartedEvent)
+		Expect(event.CFContext.Application).Should(Equal("myApp"))
+		Expect(event.CFContext.Environment).Should(Equal(environment))
+	})
+
+	Context("when deploy.delete event fails", func() {
+		It("should return an EventError", func() {
+			eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, errors.New("anything"))
+
+			deployResponse := controller.DeleteDeployment(deployment, options, nil, response)
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+			Expect(reflect.TypeOf(deployResponse.Error)).Should(Equal(reflect.TypeOf(D.EventError{})))
+			Expect(deployer.DeployCall.Called).Should(Equal(0))
+		})
+	})
+
+	It("should create a delete manager with the options", func() {
+		manager := &mocks.StartManager{}
+		deleteManagerFactory.DeleteManagerCall.Returns.ActionCreater = manager
+
+		controller.DeleteDeployment(deployment, options, nil, response)
+
+		Expect(deleteManagerFactory.DeleteManagerCall.Received.Options).Should(Equal(options))
+		Expect(deleteManagerFactory.DeleteManagerCall.Received.DeployEventData.DeploymentInfo.Username).Should(Equal("myUser"))
+		Expect(deployer.DeployCall.Received.ActionCreator).Should(Equal(manager))
+	})
+
+	Context("when the environment does not exist", func() {
+		It("should return an EnvironmentNotFoundError", func() {
+			deployment.CFContext.Environment = "not-an-environment"
+
+			deployResponse := controller.DeleteDeployment(deployment, options, nil, response)
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+			Expect(reflect.TypeOf(deployResponse.Error)).Should(Equal(reflect.TypeOf(D.EnvironmentNotFoundError{})))
+			Expect(deployer.DeployCall.Called).Should(Equal(0))
+		})
+	})
+
+	Context("when DeleteStartedEvent fails", func() {
+		It("should return an EventError", func() {
+			eventManager.EmitEventCall.Returns.Error = append(eventManager.EmitEventCall.Returns.Error, errors.New("anything"))
+
+			deployResponse := controller.DeleteDeployment(deployment, options, nil, response)
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+			Expect(reflect.TypeOf(deployResponse.Error)).Should(Equal(reflect.TypeOf(D.EventError{})))
+		})
+	})
+
+	Context("when delete succeeds", func() {
+		It("should emit DeleteSuccessEvent and DeleteFinishedEvent", func() {
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+			controller.DeleteDeployment(deployment, options, nil, response)
+
+			Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[1])).Should(Equal(reflect.TypeOf(DeleteSuccessEvent{})))
+			Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[2])).Should(Equal(reflect.TypeOf(DeleteFinishedEvent{})))
+		})
+	})
+
+	Context("when delete fails", func() {
+		It("should emit DeleteFailureEvent and DeleteFinishedEvent", func() {
+			deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+			deployer.DeployCall.Returns.Error = errors.New("delete failed")
+
+			deployResponse := controller.DeleteDeployment(deployment, options, nil, response)
+
+			Expect(deployResponse.Error).Should(MatchError("delete failed"))
+			failure := eventManager.EmitEventCall.Received.Events[1].(DeleteFailureEvent)
+			Expect(failure.Error).Should(MatchError("delete failed"))
+			Expect(reflect.TypeOf(eventManager.EmitEventCall.Received.Events[2])).Should(Equal(reflect.TypeOf(DeleteFinishedEvent{})))
+		})
+	})
+})
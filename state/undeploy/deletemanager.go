@@ -0,0 +1,120 @@
+package undeploy
+
+import (
+	"io"
+
+	"fmt"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+	"net/http"
+	"regexp"
+)
+
+const successfulDelete = `Your delete was successful! (^_^)b
+
+`
+
+type courierCreator interface {
+	CreateCourier() (I.Courier, error)
+}
+
+type DeleteManager struct {
+	CourierCreator  courierCreator
+	EventManager    I.EventManager
+	Logger          I.DeploymentLogger
+	DeployEventData S.DeployEventData
+	Options         S.DeleteOptions
+}
+
+func (a DeleteManager) SetUp() error {
+	return nil
+}
+
+func (a DeleteManager) OnStart() error {
+	return nil
+}
+
+func (a DeleteManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
+	if err != nil {
+		fmt.Fprintf(response, "\nYour application was not successfully deleted on all foundations: %s\n\n", err.Error())
+		if _, ok := err.(state.ExistsError); ok {
+			return I.DeployResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      err,
+			}
+		}
+		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+		}
+		return I.DeployResponse{
+			Error:      err,
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	a.Logger.Infof("successfully deleted application %s", a.DeployEventData.DeploymentInfo.AppName)
+	fmt.Fprintf(response, "\n%s", successfulDelete)
+
+	return I.DeployResponse{StatusCode: http.StatusOK}
+}
+
+func (a DeleteManager) CleanUp() {}
+
+func (a DeleteManager) Create(environment S.Environment, response io.ReadWriter, foundationURL string) (I.Action, error) {
+	courier, err := a.CourierCreator.CreateCourier()
+	if err != nil {
+		a.Logger.Error(err)
+		return &Deleter{}, state.CourierCreationError{Err: err}
+	}
+	p := &Deleter{
+		Courier: courier,
+		CFContext: I.CFContext{
+			Environment:  environment.Name,
+			Organization: a.DeployEventData.DeploymentInfo.Org,
+			Space:        a.DeployEventData.DeploymentInfo.Space,
+			Application:  a.DeployEventData.DeploymentInfo.AppName,
+			SkipSSL:      a.DeployEventData.DeploymentInfo.SkipSSL,
+		},
+		Authorization: I.Authorization{
+			Username: a.DeployEventData.DeploymentInfo.Username,
+			Password: a.DeployEventData.DeploymentInfo.Password,
+		},
+		EventManager:  a.EventManager,
+		Response:      response,
+		Log:           a.Logger,
+		FoundationURL: foundationURL,
+		AppName:       a.DeployEventData.DeploymentInfo.AppName,
+		Options:       a.Options,
+		Data:          a.DeployEventData.DeploymentInfo.Data,
+	}
+
+	return p, nil
+}
+
+// InitiallyError returns the ExistsError if the application is missing from any foundation
+// so the delete can be refused with a 404.
+func (a DeleteManager) InitiallyError(initiallyErrors []error) error {
+	for _, err := range initiallyErrors {
+		if existsErr, ok := err.(state.ExistsError); ok {
+			return existsErr
+		}
+	}
+	return bluegreen.LoginError{LoginErrors: initiallyErrors}
+}
+
+func (a DeleteManager) ExecuteError(executeErrors []error) error {
+	return bluegreen.DeleteError{Errors: executeErrors}
+}
+
+func (a DeleteManager) UndoError(executeErrors, undoErrors []error) error {
+	return bluegreen.RollbackDeleteError{DeleteErrors: executeErrors, RollbackErrors: undoErrors}
+}
+
+func (a DeleteManager) SuccessError(successErrors []error) error {
+	return bluegreen.FinishDeleteError{FinishDeleteErrors: successErrors}
+}
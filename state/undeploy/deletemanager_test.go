@@ -0,0 +1,145 @@
+package undeploy_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
+	"github.com/compozed/deployadactyl/state/undeploy"
+	"github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+type courierCreator struct {
+	CourierCreatorFn func() (interfaces.Courier, error)
+}
+
+func (c courierCreator) CreateCourier() (interfaces.Courier, error) {
+	if c.CourierCreatorFn != nil {
+		return c.CourierCreatorFn()
+	}
+
+	return &mocks.Courier{}, nil
+}
+
+var _ = Describe("Deletemanager", func() {
+	var (
+		response      *gbytes.Buffer
+		deleteManager undeploy.DeleteManager
+		creator       *courierCreator
+	)
+
+	BeforeEach(func() {
+		log := interfaces.DefaultLogger(gbytes.NewBuffer(), logging.DEBUG, "deletemanager tests")
+		response = gbytes.NewBuffer()
+		creator = &courierCreator{}
+
+		deleteManager = undeploy.DeleteManager{
+			CourierCreator: creator,
+			Logger:         interfaces.DeploymentLogger{Log: log, UUID: randomizer.StringRunes(10)},
+			DeployEventData: structs.DeployEventData{
+				DeploymentInfo: &structs.DeploymentInfo{
+					AppName:  "myApp",
+					Username: "bob",
+					Password: "password",
+				},
+				Response: response,
+			},
+			Options: structs.DeleteOptions{Routes: true},
+		}
+	})
+
+	Describe("Create", func() {
+		It("should return a Deleter object with correct data", func() {
+			env := structs.Environment{Name: "myEnv"}
+			foundationURL := "foundation url"
+
+			deleter, err := deleteManager.Create(env, response, foundationURL)
+			Expect(err).ToNot(HaveOccurred())
+
+			deleterData := deleter.(*undeploy.Deleter)
+			Expect(deleterData.CFContext.Application).Should(Equal("myApp"))
+			Expect(deleterData.CFContext.Environment).Should(Equal("myEnv"))
+			Expect(deleterData.Authorization.Username).Should(Equal("bob"))
+			Expect(deleterData.Authorization.Password).Should(Equal("password"))
+			Expect(deleterData.FoundationURL).Should(Equal(foundationURL))
+			Expect(deleterData.Options.Routes).Should(BeTrue())
+		})
+
+		Context("when courier build failed", func() {
+			It("should return an error", func() {
+				creator.CourierCreatorFn = func() (interfaces.Courier, error) {
+					return nil, errors.New("a test error")
+				}
+
+				_, err := deleteManager.Create(structs.Environment{}, response, "foundation url")
+				Expect(err).Should(MatchError(ContainSubstring("a test error")))
+			})
+		})
+	})
+
+	Describe("InitiallyError", func() {
+		It("should return the ExistsError when the app is missing", func() {
+			existsErr := state.ExistsError{ApplicationName: "myApp"}
+			err := deleteManager.InitiallyError([]error{errors.New("first error"), existsErr})
+
+			Expect(err).Should(Equal(existsErr))
+		})
+
+		It("should return LoginError otherwise", func() {
+			err := deleteManager.InitiallyError([]error{errors.New("first error")})
+
+			Expect(reflect.TypeOf(err)).Should(Equal(reflect.TypeOf(bluegreen.LoginError{})))
+		})
+	})
+
+	Describe("errors", func() {
+		It("should return DeleteError on execute failure", func() {
+			err := deleteManager.ExecuteError([]error{errors.New("first error")})
+
+			Expect(reflect.TypeOf(err)).Should(Equal(reflect.TypeOf(bluegreen.DeleteError{})))
+		})
+
+		It("should return RollbackDeleteError on undo failure", func() {
+			err := deleteManager.UndoError([]error{errors.New("execute error")}, []error{errors.New("undo error")})
+
+			Expect(reflect.TypeOf(err)).Should(Equal(reflect.TypeOf(bluegreen.RollbackDeleteError{})))
+		})
+	})
+
+	Describe("OnFinish", func() {
+		It("returns http.StatusOK and prints a success message", func() {
+			deployResponse := deleteManager.OnFinish(structs.Environment{}, response, nil)
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusOK))
+			Eventually(response).Should(gbytes.Say("Your delete was successful!"))
+		})
+
+		It("returns http.StatusNotFound when the app does not exist", func() {
+			deployResponse := deleteManager.OnFinish(structs.Environment{}, response, state.ExistsError{ApplicationName: "myApp"})
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusNotFound))
+		})
+
+		It("returns http.StatusBadRequest when login failed", func() {
+			deployResponse := deleteManager.OnFinish(structs.Environment{}, response, errors.New("login failed"))
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusBadRequest))
+		})
+
+		It("returns http.StatusInternalServerError otherwise", func() {
+			deployResponse := deleteManager.OnFinish(structs.Environment{}, response, errors.New("delete failed"))
+
+			Expect(deployResponse.StatusCode).Should(Equal(http.StatusInternalServerError))
+			Eventually(response).Should(gbytes.Say("not successfully deleted"))
+		})
+	})
+})
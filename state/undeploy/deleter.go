@@ -0,0 +1,122 @@
+package undeploy
+
+import (
+	"io"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+type Deleter struct {
+	Courier       I.Courier
+	CFContext     I.CFContext
+	Authorization I.Authorization
+	EventManager  I.EventManager
+	Response      io.ReadWriter
+	Log           I.DeploymentLogger
+	FoundationURL string
+	AppName       string
+	Options       S.DeleteOptions
+	Data          map[string]interface{}
+}
+
+func (d Deleter) Verify() error {
+	return nil
+}
+
+func (d Deleter) Success() error {
+	return nil
+}
+
+func (d Deleter) Finally() error {
+	return nil
+}
+
+// Initially will login to a Cloud Foundry instance and check that the application exists.
+func (d Deleter) Initially() error {
+	d.Log.Debugf(
+		`logging into cloud foundry with parameters:
+		foundation URL: %+v
+		username: %+v
+		org: %+v
+		space: %+v`,
+		d.FoundationURL, d.Authorization.Username, d.CFContext.Organization, d.CFContext.Space,
+	)
+
+	output, err := d.Courier.Login(
+		d.FoundationURL,
+		d.Authorization.Username,
+		d.Authorization.Password,
+		d.CFContext.Organization,
+		d.CFContext.Space,
+		d.CFContext.SkipSSL,
+	)
+	d.Response.Write(output)
+	if err != nil {
+		d.Log.Errorf("could not login to %s", d.FoundationURL)
+		return state.LoginError{d.FoundationURL, output}
+	}
+
+	d.Log.Infof("logged into cloud foundry %s", d.FoundationURL)
+
+	if d.Courier.Exists(d.AppName) != true {
+		d.Log.Errorf("cannot delete app on foundation %s: application doesn't exist", d.FoundationURL)
+		return state.ExistsError{ApplicationName: d.AppName}
+	}
+
+	return nil
+}
+
+// Execute deletes the application. Routes are deleted with the application if Options.Routes is set.
+// Services bound only to the application are deleted afterwards if Options.Services is set.
+func (d Deleter) Execute() error {
+	var (
+		services []string
+		err      error
+	)
+
+	if d.Options.Services {
+		services, err = d.Courier.OrphanedServices(d.AppName)
+		if err != nil {
+			d.Log.Errorf("failed to find services bound to app on foundation %s: %s", d.FoundationURL, err.Error())
+			return state.OrphanedServicesError{ApplicationName: d.AppName, Err: err}
+		}
+	}
+
+	d.Log.Infof("deleting app %s", d.AppName)
+
+	var output []byte
+	if d.Options.Routes {
+		output, err = d.Courier.DeleteWithRoutes(d.AppName)
+	} else {
+		output, err = d.Courier.Delete(d.AppName)
+	}
+	d.Response.Write(output)
+	if err != nil {
+		d.Log.Errorf("failed to delete app on foundation %s: %s", d.FoundationURL, err.Error())
+		return state.DeleteApplicationError{ApplicationName: d.AppName, Out: output}
+	}
+
+	for _, service := range services {
+		d.Log.Infof("deleting orphaned service %s", service)
+
+		output, err = d.Courier.DeleteService(service)
+		d.Response.Write(output)
+		if err != nil {
+			d.Log.Errorf("failed to delete service on foundation %s: %s", d.FoundationURL, err.Error())
+			return state.DeleteServiceError{ServiceName: service, Out: output}
+		}
+	}
+
+	d.Log.Infof("successfully deleted app %s", d.AppName)
+
+	return nil
+}
+
+// Undo does not restore a deleted application.
+func (d Deleter) Undo() error {
+	d.Log.Infof("delete of app %s on foundation %s cannot be rolled back", d.AppName, d.FoundationURL)
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package undeploy_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/undeploy"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Deleter", func() {
+	var (
+		deleter       Deleter
+		courier       *mocks.Courier
+		appName       string
+		foundationURL string
+		response      *Buffer
+		logBuffer     *Buffer
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		appName = "appName-" + randomizer.StringRunes(10)
+		foundationURL = "foundationURL-" + randomizer.StringRunes(10)
+		response = NewBuffer()
+		logBuffer = NewBuffer()
+
+		courier.ExistsCall.Returns.Bool = true
+
+		deleter = Deleter{
+			Courier: courier,
+			CFContext: interfaces.CFContext{
+				Organization: "org-" + randomizer.StringRunes(10),
+				Space:        "space-" + randomizer.StringRunes(10),
+				Application:  appName,
+			},
+			Authorization: interfaces.Authorization{
+				Username: "username-" + randomizer.StringRunes(10),
+				Password: "password-" + randomizer.StringRunes(10),
+			},
+			Response:      response,
+			Log:           interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "deleter_test")},
+			FoundationURL: foundationURL,
+			AppName:       appName,
+		}
+	})
+
+	Describe("initially", func() {
+		It("logs in with the cf context", func() {
+			Expect(deleter.Initially()).To(Succeed())
+
+			Expect(courier.LoginCall.Received.FoundationURL).To(Equal(foundationURL))
+			Expect(courier.LoginCall.Received.Org).To(Equal(deleter.CFContext.Organization))
+			Expect(courier.LoginCall.Received.Space).To(Equal(deleter.CFContext.Space))
+		})
+
+		Context("when login fails", func() {
+			It("returns a LoginError", func() {
+				courier.LoginCall.Returns.Output = []byte("login output")
+				courier.LoginCall.Returns.Error = errors.New("login error")
+
+				err := deleter.Initially()
+
+				Expect(err).To(MatchError(state.LoginError{foundationURL, []byte("login output")}))
+				Eventually(response).Should(Say("login output"))
+			})
+		})
+
+		Context("when the app does not exist", func() {
+			It("returns an ExistsError", func() {
+				courier.ExistsCall.Returns.Bool = false
+
+				err := deleter.Initially()
+
+				Expect(err).To(MatchError(state.ExistsError{ApplicationName: appName}))
+			})
+		})
+	})
+
+	Describe("executing", func() {
+		It("deletes the app and writes the output to the response", func() {
+			courier.DeleteCall.Returns.Output = []byte("delete output")
+
+			Expect(deleter.Execute()).To(Succeed())
+
+			Expect(courier.DeleteCall.Received.AppName).To(Equal(appName))
+			Expect(courier.DeleteWithRoutesCall.Received.AppName).To(BeEmpty())
+			Expect(courier.OrphanedServicesCall.Received.AppName).To(BeEmpty())
+			Eventually(response).Should(Say("delete output"))
+		})
+
+		It("returns a DeleteApplicationError when delete fails", func() {
+			courier.DeleteCall.Returns.Output = []byte("delete output")
+			courier.DeleteCall.Returns.Error = errors.New("delete error")
+
+			err := deleter.Execute()
+
+			Expect(err).To(MatchError(state.DeleteApplicationError{ApplicationName: appName, Out: []byte("delete output")}))
+		})
+
+		Context("when routes are deleted", func() {
+			It("deletes the app with its routes", func() {
+				deleter.Options = S.DeleteOptions{Routes: true}
+
+				Expect(deleter.Execute()).To(Succeed())
+
+				Expect(courier.DeleteWithRoutesCall.Received.AppName).To(Equal(appName))
+				Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when services are deleted", func() {
+			BeforeEach(func() {
+				deleter.Options = S.DeleteOptions{Services: true}
+				courier.OrphanedServicesCall.Returns.Services = []string{"service-one", "service-two"}
+			})
+
+			It("deletes the services only bound to the app", func() {
+				Expect(deleter.Execute()).To(Succeed())
+
+				Expect(courier.OrphanedServicesCall.Received.AppName).To(Equal(appName))
+				Expect(courier.DeleteServiceCall.Received.ServiceNames).To(Equal([]string{"service-one", "service-two"}))
+			})
+
+			It("does not delete the app when the services cannot be found", func() {
+				courier.OrphanedServicesCall.Returns.Error = errors.New("services error")
+
+				err := deleter.Execute()
+
+				Expect(err).To(MatchError(state.OrphanedServicesError{ApplicationName: appName, Err: errors.New("services error")}))
+				Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+			})
+
+			It("returns a DeleteServiceError when a service cannot be deleted", func() {
+				courier.DeleteServiceCall.Returns.Error = errors.New("delete-service error")
+
+				err := deleter.Execute()
+
+				Expect(err).To(MatchError(state.DeleteServiceError{ServiceName: "service-one"}))
+			})
+		})
+	})
+
+	Describe("undo", func() {
+		It("does not change the app", func() {
+			Expect(deleter.Undo()).To(Succeed())
+
+			Eventually(logBuffer).Should(Say("cannot be rolled back"))
+		})
+	})
+})
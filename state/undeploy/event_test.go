@@ -0,0 +1,223 @@
+package undeploy_test
+
+import (
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state/undeploy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("event binding", func() {
+	Describe("DeleteStartedEventBinding", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					stopBind := undeploy.NewDeleteStartedEventBinding(nil)
+
+					stopEvent := undeploy.DeleteStartedEvent{}
+					Expect(stopBind.Accepts(stopEvent)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					stopBind := undeploy.NewDeleteStartedEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(stopBind.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					deleteFunc := func(event undeploy.DeleteStartedEvent) error {
+						invoked = true
+						return nil
+					}
+					stopBind := undeploy.NewDeleteStartedEventBinding(deleteFunc)
+					stopEvent := undeploy.DeleteStartedEvent{}
+					stopBind.Emit(stopEvent)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					deleteFunc := func(event undeploy.DeleteStartedEvent) error {
+						invoked = true
+						return nil
+					}
+					deleteBind := undeploy.NewDeleteStartedEventBinding(deleteFunc)
+					event := interfaces.Event{}
+					err := deleteBind.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+
+	})
+	Describe("DeleteSuccessEventBinding", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					deleteSuccessBind := undeploy.NewDeleteSuccessEventBinding(nil)
+
+					deleteEvent := undeploy.DeleteSuccessEvent{}
+					Expect(deleteSuccessBind.Accepts(deleteEvent)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					deleteSuccessBind := undeploy.NewDeleteSuccessEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(deleteSuccessBind.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					deleteFunc := func(event undeploy.DeleteSuccessEvent) error {
+						invoked = true
+						return nil
+					}
+					stopSuccessBind := undeploy.NewDeleteSuccessEventBinding(deleteFunc)
+					stopEvent := undeploy.DeleteSuccessEvent{}
+					stopSuccessBind.Emit(stopEvent)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					deleteFunc := func(event undeploy.DeleteSuccessEvent) error {
+						invoked = true
+						return nil
+					}
+					deleteSuccessBind := undeploy.NewDeleteSuccessEventBinding(deleteFunc)
+					event := interfaces.Event{}
+					err := deleteSuccessBind.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+
+	})
+	Describe("DeleteFailureEventBinding", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					binding := undeploy.NewDeleteFailureEventBinding(nil)
+
+					deleteEvent := undeploy.DeleteFailureEvent{}
+					Expect(binding.Accepts(deleteEvent)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					binding := undeploy.NewDeleteFailureEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(binding.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					deleteFunc := func(event undeploy.DeleteFailureEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := undeploy.NewDeleteFailureEventBinding(deleteFunc)
+					deleteEvent := undeploy.DeleteFailureEvent{}
+					binding.Emit(deleteEvent)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					deleteFunc := func(event undeploy.DeleteFailureEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := undeploy.NewDeleteFailureEventBinding(deleteFunc)
+					event := interfaces.Event{}
+					err := binding.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+
+	})
+	Describe("StartFinishEventBinding", func() {
+		Describe("Accept", func() {
+			Context("when accept takes a correct event", func() {
+				It("should return true", func() {
+					binding := undeploy.NewDeleteFinishedEventBinding(nil)
+
+					event := undeploy.DeleteFinishedEvent{}
+					Expect(binding.Accepts(event)).Should(Equal(true))
+				})
+			})
+			Context("when accept takes incorrect event", func() {
+				It("should return false", func() {
+					binding := undeploy.NewDeleteFinishedEventBinding(nil)
+
+					event := interfaces.Event{}
+					Expect(binding.Accepts(event)).Should(Equal(false))
+				})
+			})
+		})
+		Describe("Emit", func() {
+			Context("when emit takes a correct event", func() {
+				It("should invoke handler", func() {
+					invoked := false
+					deleteFunc := func(event undeploy.DeleteFinishedEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := undeploy.NewDeleteFinishedEventBinding(deleteFunc)
+					stopEvent := undeploy.DeleteFinishedEvent{}
+					binding.Emit(stopEvent)
+
+					Expect(invoked).Should(Equal(true))
+				})
+			})
+			Context("when emit takes incorrect event", func() {
+				It("should return error", func() {
+					invoked := false
+					deleteFunc := func(event undeploy.DeleteFinishedEvent) error {
+						invoked = true
+						return nil
+					}
+					binding := undeploy.NewDeleteFinishedEventBinding(deleteFunc)
+					event := interfaces.Event{}
+					err := binding.Emit(event)
+
+					Expect(invoked).Should(Equal(false))
+					Expect(err).ShouldNot(BeNil())
+					Expect(err.Error()).Should(Equal("invalid event type"))
+				})
+			})
+		})
+
+	})
+})
@@ -0,0 +1,13 @@
+package undeploy_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestUndeploy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Undeploy Suite")
+}
@@ -0,0 +1,13 @@
+package structs
+
+import "time"
+
+// ArtifactMetadata describes the artifact a deploy fetched. URL is empty for artifacts sent in the request body.
+// GitSHA is the commit the artifact was built from when it has a git.properties file.
+type ArtifactMetadata struct {
+	URL       string    `json:"url,omitempty"`
+	Checksum  string    `json:"checksum"`
+	SizeBytes int64     `json:"size_bytes"`
+	FetchedAt time.Time `json:"fetched_at"`
+	GitSHA    string    `json:"git_sha,omitempty"`
+}
@@ -0,0 +1,7 @@
+package structs
+
+// DeleteOptions controls what is removed along with an application.
+type DeleteOptions struct {
+	Routes   bool
+	Services bool
+}
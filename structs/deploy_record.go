@@ -0,0 +1,30 @@
+package structs
+
+// DeployRecord is the record of a deploy event that is sent to the consumers of the deploy events.
+// The credentials of the deploy and the values of its Env are never included.
+type DeployRecord struct {
+	Type           string         `json:"type"`
+	Replay         bool           `json:"replay"`
+	Error          string         `json:"error,omitempty"`
+	DeploymentInfo DeploymentInfo `json:"deployment_info"`
+}
+
+// NewDeployRecord returns the record of an event of the deploy with the info, without its credentials, body and
+// environment variable values.
+func NewDeployRecord(eventType string, replay bool, err error, info DeploymentInfo) DeployRecord {
+	info.Username = ""
+	info.Password = ""
+	info.Body = nil
+	info.Env = RedactEnv(info.Env)
+
+	record := DeployRecord{
+		Type:           eventType,
+		Replay:         replay,
+		DeploymentInfo: info,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	return record
+}
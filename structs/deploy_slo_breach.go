@@ -0,0 +1,11 @@
+package structs
+
+import "time"
+
+// DeploySLOBreach is the data of a deploy.slo_breach event, emitted when a deploy took longer than the
+// DeploySLOSeconds of its environment.
+type DeploySLOBreach struct {
+	DeploymentInfo *DeploymentInfo
+	Duration       time.Duration
+	Target         time.Duration
+}
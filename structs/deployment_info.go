@@ -8,6 +8,8 @@ import (
 // DeploymentInfo is a collection of properties necessary for a deployment.
 type DeploymentInfo struct {
 	ArtifactURL          string `json:"artifact_url"`
+	ArtifactChecksum     string `json:"artifact_checksum"`
+	ArtifactType         string `json:"artifact_type"`
 	Manifest             string `json:"manifest"`
 	Username             string
 	Password             string
@@ -24,8 +26,147 @@ type DeploymentInfo struct {
 	Body                 io.Reader
 	EnvironmentVariables map[string]string `json:"environment_variables"`
 	HealthCheckEndpoint  string            `json:"health_check_endpoint"`
+	Labels               map[string]string `json:"labels"`
+	Features             map[string]bool   `json:"features"`
+	PostDeployTask       string            `json:"post_deploy_task"`
+	Command              string            `json:"command"`
+	Buildpacks           []string          `json:"buildpacks"`
+	PushFlags            []string          `json:"push_flags"`
+	ProbeCommand         string            `json:"probe_command"`
+	Profile              string            `json:"profile"`
+	Spec                 string            `json:"spec"`
+	ManualCutover        bool              `json:"manual_cutover"`
+	Strategy             string            `json:"strategy"`
+	ChangeTicket         string            `json:"change_ticket"`
+	Reason               string            `json:"reason"`
+	SpaceGUID            string            `json:"space_guid"`
+	OrgGUID              string            `json:"org_guid"`
+	IsolationSegment     string            `json:"isolation_segment"`
 	CustomParams         map[string]interface{}
+	NoCache              bool   `json:"-"`
+	ClientIdentity       string `json:"-"`
+
+	// AllApplications pushes every application in the manifest instead of AppName.
+	AllApplications bool `json:"-"`
+
+	// ProfileManifest is a manifest with the instances, memory and stack of the profile.
+	// The manifest of the push is merged over it.
+	ProfileManifest string `json:"-"`
+
+	// Applications are the names of the applications pushed when AllApplications is set.
+	Applications []string `json:"-"`
+
+	// FailurePolicy decides whether the other applications are still pushed when one of the Applications fails.
+	FailurePolicy string `json:"-"`
+
+	// Env are environment variables set on the pushed applications before they start, over the ones of the
+	// manifest and the environment variable handler. Their values are never logged or published.
+	Env map[string]string `json:"env"`
+
+	// ArtifactArchive is the zip in AppPath that is pushed instead of AppPath when the artifact was staged without
+	// being extracted. It is empty when the artifact was extracted.
+	ArtifactArchive string `json:"-"`
+
+	// Artifact is the metadata of the artifact the deploy fetched. It is nil until the artifact is fetched.
+	Artifact *ArtifactMetadata `json:"artifact,omitempty"`
 
 	// Generic map used for users to provide their own deployment properties in JSON format.
 	Data map[string]interface{} `json:"data"`
 }
+
+// RedactedValue replaces the values of Env wherever a deploy is published.
+const RedactedValue = "[REDACTED]"
+
+// RedactEnv returns the names of env with their values replaced by RedactedValue.
+func RedactEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(env))
+	for name := range env {
+		redacted[name] = RedactedValue
+	}
+
+	return redacted
+}
+
+// Copy returns a deep copy of the deployment info, so another deploy can use it without sharing its maps and
+// slices. Body is not copied: the copy reads the same Body unless it is given its own.
+func (d *DeploymentInfo) Copy() *DeploymentInfo {
+	info := *d
+
+	info.EnvironmentVariables = copyStringMap(d.EnvironmentVariables)
+	info.Labels = copyStringMap(d.Labels)
+	info.Env = copyStringMap(d.Env)
+	info.Buildpacks = copyStrings(d.Buildpacks)
+	info.PushFlags = copyStrings(d.PushFlags)
+	info.Applications = copyStrings(d.Applications)
+
+	if d.Features != nil {
+		info.Features = make(map[string]bool, len(d.Features))
+		for name, enabled := range d.Features {
+			info.Features[name] = enabled
+		}
+	}
+
+	if d.CustomParams != nil {
+		info.CustomParams = copyValue(d.CustomParams).(map[string]interface{})
+	}
+	if d.Data != nil {
+		info.Data = copyValue(d.Data).(map[string]interface{})
+	}
+
+	if d.Artifact != nil {
+		artifact := *d.Artifact
+		info.Artifact = &artifact
+	}
+
+	return &info
+}
+
+func copyStringMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+
+	copied := make(map[string]string, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
+
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	return append([]string{}, values...)
+}
+
+// copyValue deep copies the maps and slices decoded from json or yaml.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			copied[key] = copyValue(nested)
+		}
+		return copied
+	case map[interface{}]interface{}:
+		copied := make(map[interface{}]interface{}, len(v))
+		for key, nested := range v {
+			copied[key] = copyValue(nested)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, nested := range v {
+			copied[i] = copyValue(nested)
+		}
+		return copied
+	}
+
+	return value
+}
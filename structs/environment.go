@@ -1,13 +1,246 @@
 package structs
 
+import (
+	"strconv"
+	"strings"
+)
+
+// Health check modes of an environment.
+const (
+	// HealthCheckEnforce fails the deploy when the health check fails.
+	HealthCheckEnforce = "enforce"
+	// HealthCheckWarn reports a failed health check as a warning and lets the deploy succeed.
+	HealthCheckWarn = "warn"
+	// HealthCheckOff skips the health check.
+	HealthCheckOff = "off"
+)
+
+// Health check comparisons of an environment, between a new application and the application it replaces.
+const (
+	// HealthCheckCompareOff only health checks the new application.
+	HealthCheckCompareOff = "off"
+	// HealthCheckCompareHealthy fails the health check when a smaller share of the instances of the new application
+	// is healthy than of the replaced application.
+	HealthCheckCompareHealthy = "healthy"
+	// HealthCheckCompareLatency also fails the health check when the median latency of the new application is over
+	// the latency tolerance of the replaced application.
+	HealthCheckCompareLatency = "latency"
+)
+
+// Deploy strategies. A push chooses one with its strategy field, otherwise the environment decides.
+const (
+	// StrategyBlueGreen pushes the new application next to the existing one and moves all of the traffic at once.
+	StrategyBlueGreen = "bluegreen"
+	// StrategyTrafficSplit moves the traffic to the new application in the traffic split steps of the environment.
+	StrategyTrafficSplit = "traffic-split"
+)
+
+// Route conflict policies of an environment, for a production route that is already mapped to another application.
+const (
+	// RouteConflictFail fails the deploy.
+	RouteConflictFail = "fail"
+	// RouteConflictSteal unmaps the route from the other application.
+	RouteConflictSteal = "steal"
+	// RouteConflictSkip leaves the route with the other application and warns.
+	RouteConflictSkip = "skip"
+)
+
+// Override conflict policies of an environment, for a push request that overrides a setting of its manifest, such
+// as its memory with a push flag or its command.
+const (
+	// OverrideConflictSilent applies the request over the manifest.
+	OverrideConflictSilent = "silent"
+	// OverrideConflictWarn applies the request over the manifest and writes each overridden setting to the response.
+	OverrideConflictWarn = "warn"
+	// OverrideConflictReject fails the deploy.
+	OverrideConflictReject = "reject"
+)
+
+// Sources of the credentials for a request without basic auth. An environment tries them in its auth fallback order.
+const (
+	// AuthFallbackConfig uses the CF_USERNAME and CF_PASSWORD of Deployadactyl.
+	AuthFallbackConfig = "config"
+	// AuthFallbackEnv uses the CF_USERNAME_<ENVIRONMENT> and CF_PASSWORD_<ENVIRONMENT> of the environment, when both are set.
+	AuthFallbackEnv = "env"
+	// AuthFallbackDeny rejects the request.
+	AuthFallbackDeny = "deny"
+)
+
+// Failure policies of a batch deploy or a multi-application push, for an application that fails.
+const (
+	// FailurePolicyFailFast stops pushing the remaining applications.
+	FailurePolicyFailFast = "fail-fast"
+	// FailurePolicyContinue pushes the remaining applications and deploys the ones that succeed.
+	FailurePolicyContinue = "continue"
+)
+
+// Names of the event handlers an environment can turn on with its event handlers.
+const (
+	// EventHandlerHealthCheck checks the health of a newly pushed application.
+	EventHandlerHealthCheck = "healthcheck"
+	// EventHandlerEnvVar adds the environment variables of a push to its manifest.
+	EventHandlerEnvVar = "envvar"
+	// EventHandlerRouteMapper maps the additional routes of a manifest.
+	EventHandlerRouteMapper = "routemapper"
+)
+
+// PassthroughPushFlags are the cf push flags an environment or a push may add to the push command. The flags
+// Deployadactyl sets itself, and the ones that push another artifact or leave the new application stopped or
+// without its route, are left out.
+var PassthroughPushFlags = []string{"-k", "-m", "-s", "-t", "-u", "--health-check-type"}
+
+// ParsePushFlag splits a push flag like "-m 512M" into its name and value. It returns false when the flag is not
+// one of the PassthroughPushFlags or does not have exactly one value, or when the value looks like another flag.
+func ParsePushFlag(flag string) (string, string, bool) {
+	fields := strings.Fields(flag)
+	if len(fields) != 2 || strings.HasPrefix(fields[1], "-") || !IsPassthroughPushFlag(fields[0]) {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+// IsPassthroughPushFlag returns true when name is one of the PassthroughPushFlags.
+func IsPassthroughPushFlag(name string) bool {
+	for _, passthrough := range PassthroughPushFlags {
+		if name == passthrough {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMegabytes returns the megabytes of a Cloud Foundry memory or disk size like "512M" or "2G". The unit is one of
+// M, MB, G, GB, T or TB in any case. It returns false for a size without a unit or with another unit.
+func ParseMegabytes(size string) (int, bool) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+
+	units := []struct {
+		suffix    string
+		megabytes int
+	}{
+		{"TB", 1024 * 1024}, {"GB", 1024}, {"MB", 1}, {"T", 1024 * 1024}, {"G", 1024}, {"M", 1},
+	}
+	for _, unit := range units {
+		if !strings.HasSuffix(size, unit.suffix) {
+			continue
+		}
+
+		value, err := strconv.Atoi(strings.TrimSuffix(size, unit.suffix))
+		if err != nil || value < 0 {
+			return 0, false
+		}
+		return value * unit.megabytes, true
+	}
+
+	return 0, false
+}
+
 // Environment is representation of a single environment configuration.
 type Environment struct {
-	Name           string
-	Domain         string
-	Foundations    []string `yaml:",flow"`
-	Authenticate   bool
-	SkipSSL        bool `yaml:"skip_ssl"`
-	Instances      uint16
-	EnableRollback bool                   `yaml:"rollback_enabled"`
-	CustomParams   map[string]interface{} `yaml:"custom_params"`
+	Name                   string
+	Domain                 string
+	Foundations            []string `yaml:",flow"`
+	Authenticate           bool
+	SkipSSL                bool `yaml:"skip_ssl"`
+	Instances              uint16
+	EnableRollback         bool                   `yaml:"rollback_enabled"`
+	CustomParams           map[string]interface{} `yaml:"custom_params"`
+	RequiredCustomParams   []string               `yaml:"required_custom_params,flow"`
+	AutoCreateSpace        bool                   `yaml:"auto_create_space"`
+	AutoCreateOrg          bool                   `yaml:"auto_create_org"`
+	ProbeCommand           string                 `yaml:"probe_command"`
+	ProbeTimeoutSeconds    int                    `yaml:"probe_timeout_seconds"`
+	AllowRequestProbe      bool                   `yaml:"allow_request_probe"`
+	DefaultManifest        string                 `yaml:"default_manifest"`
+	WaitForInstances       bool                   `yaml:"wait_for_instances"`
+	InstanceQuorumPercent  int                    `yaml:"instance_quorum_percent"`
+	InstanceTimeoutSeconds int                    `yaml:"instance_timeout_seconds"`
+	MaxOutputKB            int                    `yaml:"max_output_kb"`
+	ManualCutover          bool                   `yaml:"manual_cutover"`
+	HealthCheckMode        string                 `yaml:"health_check_mode"`
+	RouteConflictPolicy    string                 `yaml:"route_conflict_policy"`
+	OverrideConflictPolicy string                 `yaml:"override_conflict_policy"`
+	RequireChangeTicket    bool                   `yaml:"require_change_ticket"`
+	RequireReason          bool                   `yaml:"require_reason"`
+	// RequireApproval holds each deploy to the environment until the approval gate approves it.
+	RequireApproval bool `yaml:"require_approval"`
+	// ReadOnly environments can only be observed. Deploys and changes to the state of their applications are rejected.
+	ReadOnly bool `yaml:"read_only"`
+	// AuthFallback is the order of the sources tried for the credentials of a request without basic auth. The request
+	// is rejected when none of them has credentials. It defaults to deny when Authenticate is set, otherwise config.
+	AuthFallback []string `yaml:"auth_fallback,flow"`
+	// FallbackUsername and FallbackPassword are the credentials of the env auth fallback. They are read from the
+	// environment variables of Deployadactyl, not the config yaml.
+	FallbackUsername string `yaml:"-"`
+	FallbackPassword string `yaml:"-"`
+	// EventHandlers are the names of the event handlers that run for deploys to the environment. Every registered
+	// handler runs when it is not set.
+	EventHandlers []string `yaml:"event_handlers,flow"`
+	// AllowedBuildpacks are the buildpacks a push may choose. A push may choose any buildpack when it is empty.
+	AllowedBuildpacks []string `yaml:"allowed_buildpacks,flow"`
+	// AllowedOrgs and AllowedSpaces are the orgs and spaces a push may deploy to. A push may deploy to any org or
+	// space when they are empty.
+	AllowedOrgs   []string `yaml:"allowed_orgs,flow"`
+	AllowedSpaces []string `yaml:"allowed_spaces,flow"`
+	// AllowedIsolationSegments are the isolation segments a push may run its space in. A push may not choose an
+	// isolation segment when it is empty.
+	AllowedIsolationSegments []string `yaml:"allowed_isolation_segments,flow"`
+	// PushFlags are added to every push command of the environment, eg: "-m 512M". AllowedPushFlags are the names
+	// of the flags a push may add with its own push flags, eg: "-m". A push may not add flags when it is empty.
+	// Both only take PassthroughPushFlags.
+	PushFlags        []string `yaml:"push_flags,flow"`
+	AllowedPushFlags []string `yaml:"allowed_push_flags,flow"`
+	// The phase timeouts limit how long each phase of a push may take. A phase without a timeout is not limited.
+	FetchTimeoutSeconds       int `yaml:"fetch_timeout_seconds"`
+	PushTimeoutSeconds        int `yaml:"push_timeout_seconds"`
+	HealthCheckTimeoutSeconds int `yaml:"health_check_timeout_seconds"`
+	SwapTimeoutSeconds        int `yaml:"swap_timeout_seconds"`
+	// TrafficSplit shifts the production route to the new application in steps. TrafficSplitWeights are the
+	// percentages of traffic sent to the new application at each step, each held for TrafficSplitSoakSeconds.
+	TrafficSplit            bool  `yaml:"traffic_split"`
+	TrafficSplitWeights     []int `yaml:"traffic_split_weights,flow"`
+	TrafficSplitSoakSeconds int   `yaml:"traffic_split_soak_seconds"`
+	// HealthCheckStablePolls is how many consecutive health checks, spread over HealthCheckStableSeconds, must succeed
+	// before a new application is healthy, so one that crashes while warming up does not pass on a single success.
+	HealthCheckStablePolls   int `yaml:"health_check_stable_polls"`
+	HealthCheckStableSeconds int `yaml:"health_check_stable_seconds"`
+	// MinHealthyPercent is the percentage of the instances of a new application that must pass the health check
+	// for it to be healthy, so a few instances restarting do not fail a large application.
+	MinHealthyPercent int `yaml:"min_healthy_percent"`
+	// HealthCheckCompare health checks the application replaced by a deploy along with the new application, so a
+	// new application that passes the health check is not swapped in for a healthier one. With the latency
+	// comparison the new application may be up to HealthCheckLatencyTolerancePercent slower.
+	HealthCheckCompare                 string `yaml:"health_check_compare"`
+	HealthCheckLatencyTolerancePercent int    `yaml:"health_check_latency_tolerance_percent"`
+	// HealthCheckConcurrency is how many foundations of a deploy are health checked at the same time. Every
+	// foundation is health checked at once when it is zero. With HealthCheckFailFast, the first failed health
+	// check aborts the health checks of the other foundations that are still waiting or retrying.
+	HealthCheckConcurrency int  `yaml:"health_check_concurrency"`
+	HealthCheckFailFast    bool `yaml:"health_check_fail_fast"`
+	// APIURLPrefix is the part of a foundation URL that AppsURLPrefix replaces to build the URL of a newly pushed
+	// application, eg: api.cf in https://api.cf.example.com with apps gives https://apps.example.com.
+	APIURLPrefix  string `yaml:"api_url_prefix"`
+	AppsURLPrefix string `yaml:"apps_url_prefix"`
+	// MinTimeBetweenDeploysSeconds holds off a deploy of an application until this long after its last successful
+	// deploy. Deploys are not held off when it is zero.
+	MinTimeBetweenDeploysSeconds int `yaml:"min_time_between_deploys_seconds"`
+	// MaxInstances is the most instances a push may ask for, from its manifest or its request. Pushes are not
+	// limited when it is zero.
+	MaxInstances uint16 `yaml:"max_instances"`
+	// MaxMemoryPerInstance and MaxDiskPerInstance are the most memory and disk a push may ask for each instance, as
+	// Cloud Foundry sizes like "2G", from its push flags or its manifest. Pushes are not limited when they are empty.
+	MaxMemoryPerInstance string `yaml:"max_memory_per_instance"`
+	MaxDiskPerInstance   string `yaml:"max_disk_per_instance"`
+	// DeleteDelaySeconds keeps the application replaced by a push stopped for this long before deleting it, so the
+	// push can be rolled back. The replaced application is deleted right away when it is zero.
+	DeleteDelaySeconds int `yaml:"delete_delay_seconds"`
+	// DeploySLOSeconds is how long a deploy of the environment should take at most. A deploy that takes longer
+	// is reported with a warning and a deploy.slo_breach event, but does not fail. It is not checked when zero.
+	DeploySLOSeconds int `yaml:"deploy_slo_seconds"`
+	// StreamZipArtifacts pushes the zip of a zip or multipart push to Cloud Foundry as is instead of extracting it
+	// first. A push whose artifact has to be extracted is still extracted.
+	StreamZipArtifacts bool `yaml:"stream_zip_artifacts"`
+	// PostDeployTaskTimeoutSeconds is how long the post deploy task of a push may run before the deploy fails and
+	// is rolled back.
+	PostDeployTaskTimeoutSeconds int `yaml:"post_deploy_task_timeout_seconds"`
 }
@@ -0,0 +1,14 @@
+package structs
+
+// ScaleSpec is a partial update to a running application.
+// Fields left empty are not changed.
+type ScaleSpec struct {
+	Instances            *uint16
+	Memory               string
+	EnvironmentVariables map[string]string
+}
+
+// IsEmpty returns true if the spec does not change anything.
+func (s ScaleSpec) IsEmpty() bool {
+	return s.Instances == nil && s.Memory == "" && len(s.EnvironmentVariables) == 0
+}
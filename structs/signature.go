@@ -0,0 +1,9 @@
+package structs
+
+// Signature is the signature of a deploy record, so its consumers can verify that it was sent by Deployadactyl and
+// not changed since. Value is the base64 encoded signature made with the key named KeyID.
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id,omitempty"`
+	Value     string `json:"value"`
+}
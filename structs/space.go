@@ -0,0 +1,9 @@
+package structs
+
+// Space is a Cloud Foundry space and the org it belongs to.
+type Space struct {
+	GUID    string
+	Name    string
+	OrgGUID string
+	OrgName string
+}
@@ -0,0 +1,12 @@
+package tracing
+
+import "fmt"
+
+type ExporterError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e ExporterError) Error() string {
+	return fmt.Sprintf("could not create the otlp exporter for %s: %s", e.Endpoint, e.Err)
+}
@@ -0,0 +1,260 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+const (
+	// TracesPath is the path the OTLP/HTTP collector receives spans on.
+	TracesPath = "/v1/traces"
+
+	exportBatchSize     = 512
+	exportQueueSize     = 2048
+	exportInterval      = 5 * time.Second
+	exportClientTimeout = 10 * time.Second
+)
+
+// Exporter is a SpanProcessor that sends spans to an OTLP collector over HTTP, encoded as OTLP/JSON. Spans are
+// queued and sent in batches in the background. Spans that do not fit in the queue, or that the collector does
+// not accept, are dropped and logged.
+//
+// It is used in place of the OpenTelemetry Go OTLP exporter, which needs a much newer Go than the one this
+// repository builds with and brings gRPC and protobuf with it. It encodes only the fields of trace.proto that
+// the Tracer sets, following the OTLP/JSON mapping of the OTLP specification.
+type Exporter struct {
+	URL    string
+	Client *http.Client
+	Log    I.Logger
+
+	mutex  sync.Mutex
+	closed bool
+	spans  chan SpanData
+	done   chan struct{}
+}
+
+// NewExporter returns an Exporter for the collector at endpoint, a host and port, and starts sending it spans.
+func NewExporter(endpoint string, insecure bool, log I.Logger) (*Exporter, error) {
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+
+	u, err := url.Parse(scheme + "://" + endpoint + TracesPath)
+	if err != nil {
+		return nil, ExporterError{endpoint, err}
+	}
+	if u.Host == "" || u.Path != TracesPath {
+		return nil, ExporterError{endpoint, fmt.Errorf("the endpoint must be a host and port")}
+	}
+
+	e := &Exporter{
+		URL:    u.String(),
+		Client: &http.Client{Timeout: exportClientTimeout},
+		Log:    log,
+		spans:  make(chan SpanData, exportQueueSize),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+
+	return e, nil
+}
+
+// OnEnd queues the span to be exported. Spans that end after Shutdown are dropped.
+func (e *Exporter) OnEnd(span SpanData) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.closed {
+		return
+	}
+
+	select {
+	case e.spans <- span:
+	default:
+		e.Log.Errorf("dropped the %s span of trace %s: the export queue is full", span.Name, span.TraceID)
+	}
+}
+
+// Shutdown exports the queued spans and stops the Exporter.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.mutex.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.spans)
+	}
+	e.mutex.Unlock()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := []SpanData{}
+	for {
+		select {
+		case span, ok := <-e.spans:
+			if !ok {
+				e.export(batch)
+				return
+			}
+
+			batch = append(batch, span)
+			if len(batch) >= exportBatchSize {
+				e.export(batch)
+				batch = []SpanData{}
+			}
+		case <-ticker.C:
+			e.export(batch)
+			batch = []SpanData{}
+		}
+	}
+}
+
+func (e *Exporter) export(batch []SpanData) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(encodeSpans(batch))
+	if err != nil {
+		e.Log.Errorf("could not export %d spans: %s", len(batch), err)
+		return
+	}
+
+	response, err := e.Client.Post(e.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		e.Log.Errorf("could not export %d spans to %s: %s", len(batch), e.URL, err)
+		return
+	}
+	defer response.Body.Close()
+	ioutil.ReadAll(response.Body)
+
+	if response.StatusCode != http.StatusOK {
+		e.Log.Errorf("could not export %d spans to %s: %s", len(batch), e.URL, response.Status)
+	}
+}
+
+// The OTLP/JSON encoding of an export request. See
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+// Span kinds, numbered as in OTLP. The root span of a deploy serves its request.
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+)
+
+func encodeSpans(spans []SpanData) otlpExportRequest {
+	encoded := []otlpSpan{}
+	for _, span := range spans {
+		kind := otlpSpanKindInternal
+		if span.ParentSpanID == "" {
+			kind = otlpSpanKindServer
+		}
+
+		encoded = append(encoded, otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.Attributes),
+			Status:            otlpStatus{int(span.Status.Code), span.Status.Description},
+		})
+	}
+
+	return otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   otlpResource{encodeAttributes([]Attribute{String("service.name", ServiceName)})},
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{ServiceName}, Spans: encoded}},
+		}},
+	}
+}
+
+func encodeAttributes(attributes []Attribute) []otlpAttribute {
+	encoded := []otlpAttribute{}
+	for _, attribute := range attributes {
+		value := otlpValue{}
+		switch v := attribute.Value.(type) {
+		case int:
+			// OTLP/JSON encodes 64 bit integers as strings.
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+
+		encoded = append(encoded, otlpAttribute{attribute.Key, value})
+	}
+	return encoded
+}
@@ -0,0 +1,204 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	. "github.com/compozed/deployadactyl/tracing"
+	"github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Exporter", func() {
+	var (
+		collector *httptest.Server
+		requests  chan *http.Request
+		bodies    chan []byte
+		status    int
+		logBuffer *Buffer
+		log       I.Logger
+	)
+
+	BeforeEach(func() {
+		requests = make(chan *http.Request, 10)
+		bodies = make(chan []byte, 10)
+		status = http.StatusOK
+		logBuffer = NewBuffer()
+		log = I.DefaultLogger(logBuffer, logging.DEBUG, "otlp_test")
+
+		collector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests <- r
+			bodies <- body
+			w.WriteHeader(status)
+		}))
+	})
+
+	AfterEach(func() {
+		collector.Close()
+	})
+
+	endpoint := func() string {
+		return strings.TrimPrefix(collector.URL, "http://")
+	}
+
+	It("sends the spans as OTLP/JSON when it shuts down", func() {
+		tracer := NewTracer()
+		Expect(tracer.Enable(endpoint(), true, log)).To(Succeed())
+
+		root := tracer.StartDeploy("uuid-1", I.CFContext{Application: "app"})
+		tracer.StartPhase("uuid-1", PushPhase).End(nil)
+		root.EndWithStatus(http.StatusInternalServerError)
+
+		Expect(tracer.Shutdown(context.Background())).To(Succeed())
+
+		var request *http.Request
+		Eventually(requests).Should(Receive(&request))
+		Expect(request.URL.Path).To(Equal(TracesPath))
+		Expect(request.Header.Get("Content-Type")).To(Equal("application/json"))
+
+		var body []byte
+		Eventually(bodies).Should(Receive(&body))
+
+		var export struct {
+			ResourceSpans []struct {
+				Resource struct {
+					Attributes []map[string]interface{}
+				}
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID      string
+						SpanID       string
+						ParentSpanID string
+						Name         string
+						Kind         int
+						Attributes   []struct {
+							Key   string
+							Value map[string]string
+						}
+						Status struct {
+							Code    int
+							Message string
+						}
+					}
+				}
+			}
+		}
+		Expect(json.Unmarshal(body, &export)).To(Succeed())
+
+		Expect(export.ResourceSpans).To(HaveLen(1))
+		Expect(export.ResourceSpans[0].Resource.Attributes[0]["key"]).To(Equal("service.name"))
+
+		spans := export.ResourceSpans[0].ScopeSpans[0].Spans
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal(PushPhase))
+		Expect(spans[0].Kind).To(Equal(1))
+		Expect(spans[0].ParentSpanID).To(Equal(spans[1].SpanID))
+		Expect(spans[1].Name).To(Equal(DeploySpan))
+		Expect(spans[1].Kind).To(Equal(2))
+		Expect(spans[1].Status.Code).To(Equal(2))
+		Expect(spans[1].Status.Message).To(Equal("500 Internal Server Error"))
+		Expect(spans[1].Attributes[5].Key).To(Equal(StatusCodeAttribute))
+		Expect(spans[1].Attributes[5].Value).To(Equal(map[string]string{"intValue": "500"}))
+	})
+
+	It("encodes the spans as in the OTLP/JSON mapping of trace.proto", func() {
+		exporter, err := NewExporter(endpoint(), true, log)
+		Expect(err).ToNot(HaveOccurred())
+
+		start := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+		exporter.OnEnd(SpanData{
+			TraceID:    "5b8efff798038103d269b633813fc60c",
+			SpanID:     "eee19b7ec3c1b174",
+			Name:       DeploySpan,
+			StartTime:  start,
+			EndTime:    start.Add(1500 * time.Millisecond),
+			Attributes: []Attribute{String(AppAttribute, "my-app"), Int(StatusCodeAttribute, 200)},
+		})
+		exporter.OnEnd(SpanData{
+			TraceID:      "5b8efff798038103d269b633813fc60c",
+			SpanID:       "eee19b7ec3c1b175",
+			ParentSpanID: "eee19b7ec3c1b174",
+			Name:         PushPhase,
+			StartTime:    start,
+			EndTime:      start.Add(time.Second),
+			Attributes:   []Attribute{},
+			Status:       Status{Code: StatusError, Description: "push failed"},
+		})
+		Expect(exporter.Shutdown(context.Background())).To(Succeed())
+
+		var body []byte
+		Eventually(bodies).Should(Receive(&body))
+
+		Expect(body).To(MatchJSON(`{
+			"resourceSpans": [{
+				"resource": {
+					"attributes": [{"key": "service.name", "value": {"stringValue": "deployadactyl"}}]
+				},
+				"scopeSpans": [{
+					"scope": {"name": "deployadactyl"},
+					"spans": [{
+						"traceId": "5b8efff798038103d269b633813fc60c",
+						"spanId": "eee19b7ec3c1b174",
+						"name": "deploy",
+						"kind": 2,
+						"startTimeUnixNano": "1792229400000000000",
+						"endTimeUnixNano": "1792229401500000000",
+						"attributes": [
+							{"key": "deployadactyl.app", "value": {"stringValue": "my-app"}},
+							{"key": "http.status_code", "value": {"intValue": "200"}}
+						],
+						"status": {"code": 0}
+					}, {
+						"traceId": "5b8efff798038103d269b633813fc60c",
+						"spanId": "eee19b7ec3c1b175",
+						"parentSpanId": "eee19b7ec3c1b174",
+						"name": "push",
+						"kind": 1,
+						"startTimeUnixNano": "1792229400000000000",
+						"endTimeUnixNano": "1792229401000000000",
+						"attributes": [],
+						"status": {"code": 2, "message": "push failed"}
+					}]
+				}]
+			}]
+		}`))
+	})
+
+	It("logs the spans the collector does not accept", func() {
+		status = http.StatusBadRequest
+
+		exporter, err := NewExporter(endpoint(), true, log)
+		Expect(err).ToNot(HaveOccurred())
+
+		exporter.OnEnd(SpanData{Name: DeploySpan})
+		Expect(exporter.Shutdown(context.Background())).To(Succeed())
+
+		Eventually(logBuffer).Should(Say("could not export 1 spans to http://.*/v1/traces: 400 Bad Request"))
+	})
+
+	It("drops spans that end after it shut down", func() {
+		exporter, err := NewExporter(endpoint(), true, log)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(exporter.Shutdown(context.Background())).To(Succeed())
+		exporter.OnEnd(SpanData{Name: DeploySpan})
+
+		Consistently(requests).ShouldNot(Receive())
+	})
+
+	It("rejects an endpoint that is not a host and port", func() {
+		_, err := NewExporter("collector:4318/v1/traces", false, log)
+
+		Expect(err).To(MatchError(ContainSubstring("could not create the otlp exporter for collector:4318/v1/traces")))
+	})
+})
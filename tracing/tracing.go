@@ -0,0 +1,309 @@
+// Package tracing emits OpenTelemetry spans for deploys: a root span for each deploy request and a child span for
+// each phase of the deploy, exported to an OTLP collector.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// ServiceName is the service the spans are reported under.
+const ServiceName = "deployadactyl"
+
+// DeploySpan is the name of the root span of a deploy.
+const DeploySpan = "deploy"
+
+// Phases of a deploy that get their own span.
+const (
+	FetchPhase       = "fetch"
+	PushPhase        = "push"
+	HealthCheckPhase = "healthcheck"
+	SwapPhase        = "swap"
+	EventsPhase      = "events"
+)
+
+// Attributes set on every span of a deploy.
+const (
+	EnvironmentAttribute = "deployadactyl.environment"
+	OrgAttribute         = "deployadactyl.org"
+	SpaceAttribute       = "deployadactyl.space"
+	AppAttribute         = "deployadactyl.app"
+	UUIDAttribute        = "deployadactyl.uuid"
+	StatusCodeAttribute  = "http.status_code"
+)
+
+// StatusCode is the OpenTelemetry status of a span.
+type StatusCode int
+
+// The status codes of a span, numbered as in OTLP.
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// Status is the status of a span and, for an error, its description.
+type Status struct {
+	Code        StatusCode
+	Description string
+}
+
+// Attribute is a key and a string or int value set on a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{key, value}
+}
+
+// Int returns an int attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{key, value}
+}
+
+// SpanData is a span that ended, as it is handed to a SpanProcessor. ParentSpanID is empty for a root span.
+type SpanData struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   []Attribute
+	Status       Status
+}
+
+// SpanProcessor receives the spans of a Tracer as they end.
+type SpanProcessor interface {
+	OnEnd(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+type deploy struct {
+	traceID    string
+	spanID     string
+	attributes []Attribute
+}
+
+// Tracer starts the spans of the deploys that are running. It keeps the root span of each deploy by UUID so
+// the phases, which only know the UUID, can start their spans under it.
+//
+// A Tracer does nothing until it is enabled, and a nil Tracer does nothing at all.
+type Tracer struct {
+	mutex     sync.Mutex
+	processor SpanProcessor
+	deploys   map[string]deploy
+}
+
+// NewTracer returns a Tracer that is not enabled.
+func NewTracer() *Tracer {
+	return &Tracer{deploys: map[string]deploy{}}
+}
+
+// Enable exports the spans to the OTLP collector at endpoint over HTTP, without TLS when insecure is set.
+// Spans that cannot be exported are logged and dropped.
+func (t *Tracer) Enable(endpoint string, insecure bool, log I.Logger) error {
+	exporter, err := NewExporter(endpoint, insecure, log)
+	if err != nil {
+		return err
+	}
+
+	t.Use(exporter)
+	return nil
+}
+
+// Use sends the spans to processor instead of a collector.
+func (t *Tracer) Use(processor SpanProcessor) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.processor = processor
+}
+
+// Enabled returns whether spans are emitted.
+func (t *Tracer) Enabled() bool {
+	if t == nil {
+		return false
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.processor != nil
+}
+
+// StartDeploy starts the root span of the deploy with the UUID. It returns nil when the Tracer is not enabled.
+func (t *Tracer) StartDeploy(uuid string, cf I.CFContext) *Span {
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.processor == nil {
+		return nil
+	}
+
+	attributes := []Attribute{
+		String(EnvironmentAttribute, cf.Environment),
+		String(OrgAttribute, cf.Organization),
+		String(SpaceAttribute, cf.Space),
+		String(AppAttribute, cf.Application),
+		String(UUIDAttribute, uuid),
+	}
+
+	span := t.start(newID(16), "", DeploySpan, attributes)
+	span.uuid = uuid
+	span.root = true
+	t.deploys[uuid] = deploy{span.data.TraceID, span.data.SpanID, attributes}
+
+	return span
+}
+
+// StartPhase starts the span of a phase under the root span of the deploy with the UUID. It returns nil when
+// the Tracer is not enabled or the deploy has no root span, such as a silent deploy that outlived its request.
+func (t *Tracer) StartPhase(uuid, phase string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	d, ok := t.deploys[uuid]
+	if t.processor == nil || !ok {
+		return nil
+	}
+
+	return t.start(d.traceID, d.spanID, phase, d.attributes)
+}
+
+// Shutdown exports the spans that have not been sent yet and stops the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	processor := t.processor
+	t.mutex.Unlock()
+
+	if processor == nil {
+		return nil
+	}
+	return processor.Shutdown(ctx)
+}
+
+func (t *Tracer) start(traceID, parentSpanID, name string, attributes []Attribute) *Span {
+	return &Span{
+		tracer:    t,
+		processor: t.processor,
+		data: SpanData{
+			TraceID:      traceID,
+			SpanID:       newID(8),
+			ParentSpanID: parentSpanID,
+			Name:         name,
+			StartTime:    time.Now(),
+			Attributes:   append([]Attribute{}, attributes...),
+		},
+	}
+}
+
+func (t *Tracer) forget(uuid string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.deploys, uuid)
+}
+
+// Span is a span started by a Tracer. Ending a nil Span does nothing.
+type Span struct {
+	tracer    *Tracer
+	processor SpanProcessor
+	data      SpanData
+	uuid      string
+	root      bool
+}
+
+// End ends the span, marking it failed when err is not nil. Ending the root span of a deploy stops new phase
+// spans from being started under it.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	if err != nil {
+		s.data.Status = Status{StatusError, err.Error()}
+	}
+	s.data.EndTime = time.Now()
+	s.processor.OnEnd(s.data)
+
+	if s.root {
+		s.tracer.forget(s.uuid)
+	}
+}
+
+// EndWithStatus ends the span with the status code of the response, marking it failed when the status code is an error.
+func (s *Span) EndWithStatus(statusCode int) {
+	if s == nil {
+		return
+	}
+
+	s.data.Attributes = append(s.data.Attributes, Int(StatusCodeAttribute, statusCode))
+
+	var err error
+	if statusCode >= http.StatusBadRequest {
+		err = fmt.Errorf("%d %s", statusCode, http.StatusText(statusCode))
+	}
+	s.End(err)
+}
+
+// Recorder is a SpanProcessor that keeps the spans in memory, in the order they ended.
+type Recorder struct {
+	mutex sync.Mutex
+	ended []SpanData
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// OnEnd records the span.
+func (r *Recorder) OnEnd(span SpanData) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.ended = append(r.ended, span)
+}
+
+// Shutdown does nothing.
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Ended returns the spans that ended.
+func (r *Recorder) Ended() []SpanData {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]SpanData{}, r.ended...)
+}
+
+// newID returns a random trace or span ID of size bytes, hex encoded.
+func newID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
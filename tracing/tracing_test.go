@@ -0,0 +1,114 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	. "github.com/compozed/deployadactyl/tracing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracer", func() {
+	var (
+		tracer   *Tracer
+		recorder *Recorder
+		cf       I.CFContext
+	)
+
+	BeforeEach(func() {
+		recorder = NewRecorder()
+		tracer = NewTracer()
+		tracer.Use(recorder)
+
+		cf = I.CFContext{
+			Environment:  "production",
+			Organization: "org",
+			Space:        "space",
+			Application:  "app",
+		}
+	})
+
+	It("tags the root span of a deploy with the deploy", func() {
+		tracer.StartDeploy("uuid-1", cf).End(nil)
+
+		Expect(recorder.Ended()).To(HaveLen(1))
+		span := recorder.Ended()[0]
+		Expect(span.Name).To(Equal(DeploySpan))
+		Expect(span.Attributes).To(ConsistOf(
+			String(EnvironmentAttribute, "production"),
+			String(OrgAttribute, "org"),
+			String(SpaceAttribute, "space"),
+			String(AppAttribute, "app"),
+			String(UUIDAttribute, "uuid-1"),
+		))
+		Expect(span.TraceID).To(HaveLen(32))
+		Expect(span.SpanID).To(HaveLen(16))
+		Expect(span.ParentSpanID).To(BeEmpty())
+		Expect(span.Status.Code).To(Equal(StatusUnset))
+	})
+
+	It("starts phase spans under the root span of the deploy", func() {
+		root := tracer.StartDeploy("uuid-1", cf)
+		tracer.StartPhase("uuid-1", PushPhase).End(nil)
+		root.End(nil)
+
+		Expect(recorder.Ended()).To(HaveLen(2))
+		phase, deploy := recorder.Ended()[0], recorder.Ended()[1]
+		Expect(phase.Name).To(Equal(PushPhase))
+		Expect(phase.TraceID).To(Equal(deploy.TraceID))
+		Expect(phase.ParentSpanID).To(Equal(deploy.SpanID))
+		Expect(phase.Attributes).To(ContainElement(String(AppAttribute, "app")))
+	})
+
+	It("marks a span failed when it ends with an error", func() {
+		tracer.StartDeploy("uuid-1", cf)
+		tracer.StartPhase("uuid-1", FetchPhase).End(errors.New("fetch failed"))
+
+		span := recorder.Ended()[0]
+		Expect(span.Status.Code).To(Equal(StatusError))
+		Expect(span.Status.Description).To(Equal("fetch failed"))
+	})
+
+	It("marks the root span failed when the response is an error", func() {
+		tracer.StartDeploy("uuid-1", cf).EndWithStatus(500)
+
+		span := recorder.Ended()[0]
+		Expect(span.Status.Code).To(Equal(StatusError))
+		Expect(span.Attributes).To(ContainElement(Int(StatusCodeAttribute, 500)))
+	})
+
+	It("does not start phase spans once the deploy has ended", func() {
+		tracer.StartDeploy("uuid-1", cf).End(nil)
+
+		Expect(tracer.StartPhase("uuid-1", SwapPhase)).To(BeNil())
+	})
+
+	It("does not start phase spans for unknown deploys", func() {
+		Expect(tracer.StartPhase("uuid-2", SwapPhase)).To(BeNil())
+	})
+
+	Context("when the tracer is not enabled", func() {
+		It("does not start spans", func() {
+			tracer = NewTracer()
+
+			Expect(tracer.Enabled()).To(BeFalse())
+			Expect(tracer.StartDeploy("uuid-1", cf)).To(BeNil())
+			Expect(tracer.StartPhase("uuid-1", PushPhase)).To(BeNil())
+		})
+	})
+
+	Context("when the tracer is nil", func() {
+		It("does nothing", func() {
+			tracer = nil
+
+			span := tracer.StartDeploy("uuid-1", cf)
+			Expect(span).To(BeNil())
+			span.End(errors.New("ignored"))
+			span.EndWithStatus(500)
+			Expect(tracer.Shutdown(context.Background())).To(Succeed())
+		})
+	})
+})